@@ -0,0 +1,153 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	storeerr "github.com/pingcap/tidb/store/driver/error"
+	"go.uber.org/atomic"
+)
+
+// Clock is the source of time used by Policy. It's replaced by a fake clock in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Policy is the retry budget and backoff policy shared by the range operations of a DDL job.
+// The backoff of the n-th retry is base * 2^n, and it's capped by cap.
+type Policy struct {
+	maxAttempts int
+	base        time.Duration
+	cap         time.Duration
+	isRetryable func(error) bool
+	clock       Clock
+	onRetry     func(attempt int, err error, backoff time.Duration)
+
+	// retries is the number of retries consumed by the job, it's shared by all the operations of the job.
+	retries atomic.Int64
+}
+
+// NewPolicy creates a Policy. Every operation run by Do is attempted at most maxAttempts times.
+// If isRetryable is nil, IsRetryableError is used.
+func NewPolicy(maxAttempts int, base, cap time.Duration, isRetryable func(error) bool) *Policy {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if isRetryable == nil {
+		isRetryable = IsRetryableError
+	}
+	return &Policy{
+		maxAttempts: maxAttempts,
+		base:        base,
+		cap:         cap,
+		isRetryable: isRetryable,
+		clock:       realClock{},
+	}
+}
+
+// SetOnRetry sets the function called before backing off for a retry, with the failed attempt and its error.
+func (p *Policy) SetOnRetry(onRetry func(attempt int, err error, backoff time.Duration)) {
+	p.onRetry = onRetry
+}
+
+// SetClock sets the clock of the policy.
+func (p *Policy) SetClock(clock Clock) {
+	p.clock = clock
+}
+
+// Retries returns the number of retries consumed by the policy.
+func (p *Policy) Retries() int64 {
+	return p.retries.Load()
+}
+
+// AddRetries adds n to the consumed retries, it's used to restore the retries recorded in the job.
+func (p *Policy) AddRetries(n int64) {
+	p.retries.Add(n)
+}
+
+// Backoff returns the duration to wait before the attempt-th retry, attempt starts from 0.
+func (p *Policy) Backoff(attempt int) time.Duration {
+	if attempt >= 62 {
+		return p.cap
+	}
+	d := p.base << uint(attempt)
+	if d <= 0 || d > p.cap {
+		return p.cap
+	}
+	return d
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, or the attempts are used up.
+// It returns the context error if ctx is done while backing off.
+func (p *Policy) Do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < p.maxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !p.isRetryable(err) || attempt == p.maxAttempts-1 {
+			return err
+		}
+		p.retries.Inc()
+		backoff := p.Backoff(attempt)
+		if p.onRetry != nil {
+			p.onRetry(attempt, err, backoff)
+		}
+		select {
+		case <-p.clock.After(backoff):
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		}
+	}
+	return err
+}
+
+// IsRetryableError checks whether the error of a range operation is caused by the pressure of the storage
+// and is worth retrying.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if kv.IsTxnRetryableError(err) {
+		return true
+	}
+	for _, e := range []*errors.Error{
+		storeerr.ErrTiKVServerBusy,
+		storeerr.ErrTiKVServerTimeout,
+		storeerr.ErrTiFlashServerBusy,
+		storeerr.ErrTiFlashServerTimeout,
+		storeerr.ErrPDServerTimeout,
+		storeerr.ErrRegionUnavailable,
+		storeerr.ErrResolveLockTimeout,
+	} {
+		if e.Equal(err) {
+			return true
+		}
+	}
+	return false
+}
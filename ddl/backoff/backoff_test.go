@@ -0,0 +1,179 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pingcaperrors "github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	storeerr "github.com/pingcap/tidb/store/driver/error"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock fires the timers immediately and records the durations waited.
+type fakeClock struct {
+	now    time.Time
+	waited []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waited = append(c.waited, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// blockingClock never fires the timers.
+type blockingClock struct{}
+
+func (blockingClock) Now() time.Time {
+	return time.Time{}
+}
+
+func (blockingClock) After(time.Duration) <-chan time.Time {
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	p := NewPolicy(10, 100*time.Millisecond, time.Second, nil)
+	require.Equal(t, 100*time.Millisecond, p.Backoff(0))
+	require.Equal(t, 200*time.Millisecond, p.Backoff(1))
+	require.Equal(t, 800*time.Millisecond, p.Backoff(3))
+	require.Equal(t, time.Second, p.Backoff(4))
+	require.Equal(t, time.Second, p.Backoff(100))
+}
+
+func TestDoRetryUntilSuccess(t *testing.T) {
+	clock := &fakeClock{}
+	p := NewPolicy(10, 100*time.Millisecond, 300*time.Millisecond, nil)
+	p.SetClock(clock)
+	var retried []int
+	p.SetOnRetry(func(attempt int, err error, backoff time.Duration) {
+		require.True(t, storeerr.ErrTiKVServerBusy.Equal(err))
+		require.Equal(t, p.Backoff(attempt), backoff)
+		retried = append(retried, attempt)
+	})
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		if calls <= 3 {
+			return pingcaperrors.Trace(storeerr.ErrTiKVServerBusy)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, calls)
+	require.Equal(t, int64(3), p.Retries())
+	require.Equal(t, []int{0, 1, 2}, retried)
+	require.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, clock.waited)
+	require.Equal(t, 600*time.Millisecond, clock.Now().Sub(time.Time{}))
+
+	// The retries are accumulated among the operations.
+	p.SetOnRetry(nil)
+	err = p.Do(context.Background(), func() error {
+		calls++
+		if calls == 5 {
+			return kv.ErrWriteConflict
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), p.Retries())
+}
+
+func TestDoExhaustAttempts(t *testing.T) {
+	clock := &fakeClock{}
+	p := NewPolicy(3, time.Millisecond, time.Second, nil)
+	p.SetClock(clock)
+	p.AddRetries(40)
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return storeerr.ErrPDServerTimeout
+	})
+	require.True(t, storeerr.ErrPDServerTimeout.Equal(err))
+	require.Equal(t, 3, calls)
+	require.Equal(t, int64(42), p.Retries())
+	require.Len(t, clock.waited, 2)
+}
+
+func TestDoNonRetryableError(t *testing.T) {
+	clock := &fakeClock{}
+	p := NewPolicy(10, time.Millisecond, time.Second, nil)
+	p.SetClock(clock)
+
+	mockErr := errors.New("mock error")
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return mockErr
+	})
+	require.Equal(t, mockErr, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, int64(0), p.Retries())
+	require.Len(t, clock.waited, 0)
+
+	// Use the customized classifier.
+	p = NewPolicy(10, time.Millisecond, time.Second, func(err error) bool { return err == mockErr })
+	p.SetClock(clock)
+	calls = 0
+	err = p.Do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return mockErr
+		}
+		return storeerr.ErrTiKVServerBusy
+	})
+	require.True(t, storeerr.ErrTiKVServerBusy.Equal(err))
+	require.Equal(t, 2, calls)
+	require.Equal(t, int64(1), p.Retries())
+}
+
+func TestDoCanceled(t *testing.T) {
+	p := NewPolicy(10, time.Hour, time.Hour, nil)
+	p.SetClock(blockingClock{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := p.Do(ctx, func() error {
+		calls++
+		cancel()
+		return storeerr.ErrRegionUnavailable
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, calls)
+	require.Equal(t, int64(1), p.Retries())
+}
+
+func TestIsRetryableError(t *testing.T) {
+	require.False(t, IsRetryableError(nil))
+	require.False(t, IsRetryableError(errors.New("mock error")))
+	require.False(t, IsRetryableError(storeerr.ErrGCTooEarly))
+	require.True(t, IsRetryableError(kv.ErrTxnRetryable))
+	require.True(t, IsRetryableError(pingcaperrors.Trace(storeerr.ErrTiKVServerTimeout)))
+	require.True(t, IsRetryableError(storeerr.ErrTiFlashServerBusy))
+	require.True(t, IsRetryableError(storeerr.ErrResolveLockTimeout))
+}
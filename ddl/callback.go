@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -57,7 +58,10 @@ type Callback interface {
 	OnWatched(ctx context.Context)
 	// OnGetJobBefore is called before getting job.
 	OnGetJobBefore(jobType string)
-	// OnGetJobAfter is called after getting job.
+	// OnJobPicked is called when the owner picks the job from the queue of the job type, before the job is claimed.
+	// queueLen is the number of the candidate jobs in the snapshot of the queue the job is picked from.
+	OnJobPicked(jobType string, job *model.Job, queueLen int)
+	// OnGetJobAfter is called after getting job, i.e. the job is claimed by the owner.
 	OnGetJobAfter(jobType string, job *model.Job)
 }
 
@@ -95,11 +99,108 @@ func (c *BaseCallback) OnGetJobBefore(jobType string) {
 	// Nothing to do.
 }
 
+// OnJobPicked implements Callback.OnJobPicked interface.
+func (*BaseCallback) OnJobPicked(_ string, _ *model.Job, _ int) {
+	// Nothing to do.
+}
+
 // OnGetJobAfter implements Callback.OnGetJobAfter interface.
 func (c *BaseCallback) OnGetJobAfter(jobType string, job *model.Job) {
 	// Nothing to do.
 }
 
+// HookJobContext is the storage of a DDL job shared by the hook invocations of the job, so the hooks can accumulate
+// the state of the job without racing with the DDL workers. It's released when the job is finished.
+type HookJobContext struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	states []model.SchemaState
+}
+
+// Put puts the value of the key.
+func (c *HookJobContext) Put(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// Get gets the value of the key.
+func (c *HookJobContext) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// RecordState records the schema state of the job if it's changed since the last record.
+func (c *HookJobContext) RecordState(job *model.Job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.states) > 0 && c.states[len(c.states)-1] == job.SchemaState {
+		return
+	}
+	c.states = append(c.states, job.SchemaState)
+}
+
+// States returns the schema state transitions recorded by RecordState.
+func (c *HookJobContext) States() []model.SchemaState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]model.SchemaState(nil), c.states...)
+}
+
+// HookJobContexts keeps the HookJobContexts of the running jobs. It's embedded by the callbacks which provide the
+// job-scoped storage to their hooks.
+type HookJobContexts struct {
+	mu   sync.Mutex
+	ctxs map[int64]*HookJobContext
+}
+
+// JobContext returns the HookJobContext of the job, it's created on the first access.
+func (c *HookJobContexts) JobContext(jobID int64) *HookJobContext {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctxs == nil {
+		c.ctxs = make(map[int64]*HookJobContext)
+	}
+	ctx, ok := c.ctxs[jobID]
+	if !ok {
+		ctx = &HookJobContext{}
+		c.ctxs[jobID] = ctx
+	}
+	return ctx
+}
+
+// ReleaseJobContext releases the HookJobContext of the job and returns it.
+func (c *HookJobContexts) ReleaseJobContext(jobID int64) *HookJobContext {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctx, ok := c.ctxs[jobID]
+	if !ok {
+		ctx = &HookJobContext{}
+	}
+	delete(c.ctxs, jobID)
+	return ctx
+}
+
+// JobContextCount returns the number of the HookJobContexts which aren't released.
+func (c *HookJobContexts) JobContextCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.ctxs)
+}
+
+// JobFinishedCallback is implemented by the callbacks which need to know when a job is finished, e.g. to release
+// the HookJobContext of the job.
+type JobFinishedCallback interface {
+	// OnJobFinished is called when the job is finished, i.e. it's done, rolled back or cancelled, before it's moved
+	// to the history queue. It may be called again for the job if moving it fails.
+	OnJobFinished(job *model.Job)
+}
+
 // DomainReloader is used to avoid import loop.
 type DomainReloader interface {
 	Reload() error
@@ -43,6 +43,9 @@ func (ti *TestInterceptor) OnGetInfoSchema(ctx sessionctx.Context, is infoschema
 // TestDDLCallback is used to customize user callback themselves.
 type TestDDLCallback struct {
 	*BaseCallback
+	// HookJobContexts provides the job-scoped storage passed to the *WithContext hooks, the storage of a job is released when the job
+	// is finished.
+	HookJobContexts
 	// We recommended to pass the domain parameter to the test ddl callback, it will ensure
 	// domain to reload schema before your ddl stepping into the next state change.
 	Do DomainReloader
@@ -53,7 +56,12 @@ type TestDDLCallback struct {
 	OnJobUpdatedExported   func(*model.Job)
 	onWatched              func(ctx context.Context)
 	OnGetJobBeforeExported func(string)
+	OnJobPickedExported    func(string, *model.Job, int)
 	OnGetJobAfterExported  func(string, *model.Job)
+
+	OnJobRunBeforeWithContext func(*model.Job, *HookJobContext)
+	OnJobUpdatedWithContext   func(*model.Job, *HookJobContext)
+	OnJobFinishedWithContext  func(*model.Job, *HookJobContext)
 }
 
 // OnChanged mock the same behavior with the main DDL hook.
@@ -83,6 +91,10 @@ func (tc *TestDDLCallback) OnSchemaStateChanged() {
 // OnJobRunBefore is used to run the user customized logic of `onJobRunBefore` first.
 func (tc *TestDDLCallback) OnJobRunBefore(job *model.Job) {
 	logutil.BgLogger().Info("on job run before", zap.String("job", job.String()))
+	if tc.OnJobRunBeforeWithContext != nil {
+		tc.OnJobRunBeforeWithContext(job, tc.JobContext(job.ID))
+		return
+	}
 	if tc.OnJobRunBeforeExported != nil {
 		tc.OnJobRunBeforeExported(job)
 		return
@@ -98,6 +110,10 @@ func (tc *TestDDLCallback) OnJobRunBefore(job *model.Job) {
 // OnJobUpdated is used to run the user customized logic of `OnJobUpdated` first.
 func (tc *TestDDLCallback) OnJobUpdated(job *model.Job) {
 	logutil.BgLogger().Info("on job updated", zap.String("job", job.String()))
+	if tc.OnJobUpdatedWithContext != nil {
+		tc.OnJobUpdatedWithContext(job, tc.JobContext(job.ID))
+		return
+	}
 	if tc.OnJobUpdatedExported != nil {
 		tc.OnJobUpdatedExported(job)
 		return
@@ -110,6 +126,14 @@ func (tc *TestDDLCallback) OnJobUpdated(job *model.Job) {
 	tc.BaseCallback.OnJobUpdated(job)
 }
 
+// OnJobFinished implements JobFinishedCallback.OnJobFinished interface, it releases the HookJobContext of the job.
+func (tc *TestDDLCallback) OnJobFinished(job *model.Job) {
+	jobCtx := tc.ReleaseJobContext(job.ID)
+	if tc.OnJobFinishedWithContext != nil {
+		tc.OnJobFinishedWithContext(job, jobCtx)
+	}
+}
+
 // OnWatched is used to run the user customized logic of `OnWatched` first.
 func (tc *TestDDLCallback) OnWatched(ctx context.Context) {
 	if tc.onWatched != nil {
@@ -129,6 +153,15 @@ func (tc *TestDDLCallback) OnGetJobBefore(jobType string) {
 	tc.BaseCallback.OnGetJobBefore(jobType)
 }
 
+// OnJobPicked implements Callback.OnJobPicked interface.
+func (tc *TestDDLCallback) OnJobPicked(jobType string, job *model.Job, queueLen int) {
+	if tc.OnJobPickedExported != nil {
+		tc.OnJobPickedExported(jobType, job, queueLen)
+		return
+	}
+	tc.BaseCallback.OnJobPicked(jobType, job, queueLen)
+}
+
 // OnGetJobAfter implements Callback.OnGetJobAfter interface.
 func (tc *TestDDLCallback) OnGetJobAfter(jobType string, job *model.Job) {
 	if tc.OnGetJobAfterExported != nil {
@@ -145,3 +178,37 @@ func TestCallback(t *testing.T) {
 	cb.OnJobUpdated(nil)
 	cb.OnWatched(context.TODO())
 }
+
+func TestHookJobContext(t *testing.T) {
+	tc := &TestDDLCallback{}
+	job := &model.Job{ID: 1}
+	var finished *HookJobContext
+	tc.OnJobUpdatedWithContext = func(job *model.Job, jobCtx *HookJobContext) {
+		jobCtx.RecordState(job)
+		cnt, _ := jobCtx.Get("count")
+		n, _ := cnt.(int)
+		jobCtx.Put("count", n+1)
+	}
+	tc.OnJobFinishedWithContext = func(_ *model.Job, jobCtx *HookJobContext) {
+		finished = jobCtx
+	}
+	for _, state := range []model.SchemaState{model.StateDeleteOnly, model.StateDeleteOnly, model.StateWriteOnly, model.StatePublic} {
+		job.SchemaState = state
+		tc.OnJobUpdated(job)
+	}
+	require.Equal(t, 1, tc.JobContextCount())
+	_, ok := tc.JobContext(2).Get("count")
+	require.False(t, ok)
+	require.Equal(t, 2, tc.JobContextCount())
+
+	tc.OnJobFinished(job)
+	require.Equal(t, 1, tc.JobContextCount())
+	require.NotNil(t, finished)
+	cnt, ok := finished.Get("count")
+	require.True(t, ok)
+	require.Equal(t, 4, cnt)
+	require.Equal(t, []model.SchemaState{model.StateDeleteOnly, model.StateWriteOnly, model.StatePublic}, finished.States())
+	// The context is created again after it's released.
+	_, ok = tc.JobContext(job.ID).Get("count")
+	require.False(t, ok)
+}
@@ -16,20 +16,27 @@ package ddl
 
 import (
 	"context"
-	"strings"
+	"fmt"
+	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
 	"github.com/pingcap/tidb/domain/infosync"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/metrics"
 	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	storeerr "github.com/pingcap/tidb/store/driver/error"
 	"github.com/pingcap/tidb/tablecodec"
-	"github.com/pingcap/tidb/util/filter"
+	"github.com/pingcap/tidb/util/dbterror"
 	"github.com/pingcap/tidb/util/gcutil"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
 )
 
@@ -41,6 +48,80 @@ var pdScheduleKey = []string{
 	"replica-schedule-limit",
 }
 
+// The external toggles of the cluster changed by the flashback job. They're recorded as a bitmask in the job args
+// before being changed, and restored by restoreFlashbackExternals when the job finishes.
+const (
+	flashbackChangedGC uint64 = 1 << iota
+	flashbackChangedPDSchedule
+	// flashbackChangedScheduleDenyRule is set by the flashback tables job, which fences the key ranges of the tables by
+	// a label rule instead of closing the PD schedule of the whole cluster.
+	flashbackChangedScheduleDenyRule
+
+	// flashbackChangedAll is used by the jobs created by the older versions, which don't record the bitmask.
+	flashbackChangedAll = flashbackChangedGC | flashbackChangedPDSchedule
+)
+
+// flashbackPhaseBudgets are the cumulative shares of the flashback cluster timeout by which each phase must finish.
+// Most of the timeout is left to the last phase, which flashes back the key ranges.
+var flashbackPhaseBudgets = []struct {
+	state  model.SchemaState
+	budget float64
+}{
+	{model.StateNone, 0.1},
+	{model.StateWriteOnly, 0.3},
+	{model.StateWriteReorganization, 1},
+}
+
+// flashbackPhaseDeadline returns the deadline of the current phase of the job and the number of the phases completed,
+// it returns a zero deadline if the job has no timeout.
+func flashbackPhaseDeadline(job *model.Job, timeout time.Duration) (deadline time.Time, completed int) {
+	for i, phase := range flashbackPhaseBudgets {
+		if phase.state == job.SchemaState {
+			completed = i
+			if timeout > 0 {
+				deadline = oracle.GetTimeFromTS(job.RealStartTS).Add(time.Duration(float64(timeout) * phase.budget))
+			}
+			break
+		}
+	}
+	return
+}
+
+// checkFlashbackTimeout returns the error if the current phase of the job has run out of its share of the timeout.
+func checkFlashbackTimeout(job *model.Job, timeout time.Duration) error {
+	deadline, _ := flashbackPhaseDeadline(job, timeout)
+	if deadline.IsZero() || time.Now().Before(deadline) {
+		return nil
+	}
+	return errFlashbackTimeout(job, timeout)
+}
+
+func errFlashbackTimeout(job *model.Job, timeout time.Duration) error {
+	_, completed := flashbackPhaseDeadline(job, timeout)
+	elapsed := time.Since(oracle.GetTimeFromTS(job.RealStartTS)).Round(time.Millisecond)
+	return errors.Errorf("flashback cluster timed out in phase %s after %s (timeout: %s), %d of %d phases completed",
+		job.SchemaState, elapsed, timeout, completed, len(flashbackPhaseBudgets))
+}
+
+// FlashbackJobDetachedError is returned to the killed flashback statement whose job can't be cancelled any more.
+type FlashbackJobDetachedError struct {
+	JobID int64
+}
+
+// Error implements the error interface.
+func (e *FlashbackJobDetachedError) Error() string {
+	return fmt.Sprintf("the flashback job %d can't be cancelled in the current state, it continues in the background, check it by ADMIN SHOW DDL JOBS", e.JobID)
+}
+
+// withFlashbackDeadline returns the context which is done at the deadline of the current phase of the job.
+func withFlashbackDeadline(ctx context.Context, job *model.Job, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline, _ := flashbackPhaseDeadline(job, timeout)
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
 func closePDSchedule() error {
 	closeMap := make(map[string]interface{})
 	for _, key := range pdScheduleKey {
@@ -49,7 +130,9 @@ func closePDSchedule() error {
 	return infosync.SetPDScheduleConfig(context.Background(), closeMap)
 }
 
-func savePDSchedule(job *model.Job) error {
+// savePDSchedule saves the PD schedule in the job args, and records it in the meta, so it can be restored by
+// CleanupOrphanFlashbackPDSchedule if the job is lost.
+func savePDSchedule(w *worker, job *model.Job) error {
 	retValue, err := infosync.GetPDScheduleConfig(context.Background())
 	if err != nil {
 		return err
@@ -59,9 +142,78 @@ func savePDSchedule(job *model.Job) error {
 		saveValue[key] = retValue[key]
 	}
 	job.Args[1] = &saveValue
+	return kv.RunInNewTxn(w.ctx, w.store, true, func(ctx context.Context, txn kv.Transaction) error {
+		return meta.NewMeta(txn).SetFlashbackPDSchedule(&meta.FlashbackPDSchedule{JobID: job.ID, PDSchedule: saveValue})
+	})
+}
+
+// clearFlashbackPDSchedule removes the PD schedule recorded by the job, after it's restored.
+func clearFlashbackPDSchedule(t *meta.Meta, jobID int64) error {
+	s, err := t.GetFlashbackPDSchedule()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if s != nil && s.JobID == jobID {
+		return errors.Trace(t.SetFlashbackPDSchedule(nil))
+	}
 	return nil
 }
 
+// CleanupOrphanFlashbackPDSchedule restores the PD schedule recorded by the flashback job which is neither running
+// nor pending, e.g. the job is removed from the queue after all the TiDB instances exit during the flashback. It
+// returns the ID of the orphaned job, or 0 if there is no orphaned record.
+func CleanupOrphanFlashbackPDSchedule(ctx context.Context, sctx sessionctx.Context) (int64, error) {
+	var s *meta.FlashbackPDSchedule
+	var jobs []*model.Job
+	err := kv.RunInNewTxn(ctx, sctx.GetStore(), true, func(ctx context.Context, txn kv.Transaction) (err error) {
+		t := meta.NewMeta(txn)
+		if s, err = t.GetFlashbackPDSchedule(); err != nil || s == nil {
+			return err
+		}
+		jobs, err = getAllDDLJobs(ctx, sctx, t)
+		return err
+	})
+	if err != nil || s == nil {
+		return 0, errors.Trace(err)
+	}
+	for _, job := range jobs {
+		if job.ID == s.JobID {
+			return 0, nil
+		}
+	}
+
+	logutil.BgLogger().Warn("[ddl] found the PD schedule saved by the orphaned flashback job, restore it",
+		zap.Int64("jobID", s.JobID), zap.Any("pdSchedule", s.PDSchedule))
+	if err = recoverPDSchedule(s.PDSchedule); err != nil {
+		return 0, errors.Trace(err)
+	}
+	err = kv.RunInNewTxn(ctx, sctx.GetStore(), true, func(ctx context.Context, txn kv.Transaction) error {
+		return clearFlashbackPDSchedule(meta.NewMeta(txn), s.JobID)
+	})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return s.JobID, nil
+}
+
+func setFlashbackChangedExternals(job *model.Job, changed uint64) {
+	if len(job.Args) < 3 {
+		job.Args = append(job.Args, changed)
+		return
+	}
+	job.Args[2] = changed
+}
+
+func getFlashbackClusterArgs(job *model.Job) (flashbackTS uint64, pdScheduleValue map[string]interface{},
+	changedExternals uint64, batchSize int, force bool, timeout time.Duration, killConflictingQueries, scatterRegions bool,
+	err error) {
+	changedExternals = flashbackChangedAll
+	batchSize = variable.DefTiDBFlashbackBatchSize
+	err = job.DecodeArgs(&flashbackTS, &pdScheduleValue, &changedExternals, &batchSize, &force, &timeout,
+		&killConflictingQueries, &scatterRegions)
+	return
+}
+
 func recoverPDSchedule(pdScheduleParam map[string]interface{}) error {
 	if pdScheduleParam == nil {
 		return nil
@@ -69,71 +221,179 @@ func recoverPDSchedule(pdScheduleParam map[string]interface{}) error {
 	return infosync.SetPDScheduleConfig(context.Background(), pdScheduleParam)
 }
 
-// ValidateFlashbackTS validates that flashBackTS in range [gcSafePoint, currentTS).
+// ValidateFlashbackTS validates that flashBackTS in range [gcSafePoint, currentTS), the range has no lower bound if
+// the GC safe point is missing.
 func ValidateFlashbackTS(ctx context.Context, sctx sessionctx.Context, flashBackTS uint64) error {
+	if err := checkFlashbackTSNotFuture(ctx, sctx, flashBackTS); err != nil {
+		return err
+	}
+	_, err := checkFlashbackGCSafePoint(sctx, flashBackTS)
+	return err
+}
+
+func getFlashbackCurrentTS(ctx context.Context, sctx sessionctx.Context) (uint64, error) {
 	currentTS, err := sctx.GetStore().GetOracle().GetStaleTimestamp(ctx, oracle.GlobalTxnScope, 0)
 	// If we fail to calculate currentTS from local time, fallback to get a timestamp from PD.
 	if err != nil {
 		metrics.ValidateReadTSFromPDCount.Inc()
 		currentVer, err := sctx.GetStore().CurrentVersion(oracle.GlobalTxnScope)
 		if err != nil {
-			return errors.Errorf("fail to validate flashback timestamp: %v", err)
+			return 0, errors.Errorf("fail to validate flashback timestamp: %v", err)
 		}
 		currentTS = currentVer.Ver
 	}
+	return currentTS, nil
+}
+
+func checkFlashbackTSNotFuture(ctx context.Context, sctx sessionctx.Context, flashBackTS uint64) error {
+	currentTS, err := getFlashbackCurrentTS(ctx, sctx)
+	if err != nil {
+		return err
+	}
 	if oracle.GetTimeFromTS(flashBackTS).After(oracle.GetTimeFromTS(currentTS)) {
 		return errors.Errorf("cannot set flashback timestamp to future time")
 	}
-	gcSafePoint, err := gcutil.GetGCSafePoint(sctx)
+	return nil
+}
+
+// FlashbackClusterMinLag is how much earlier than the current TSO the flashback cluster timestamp must be at least.
+// The cluster flashed back to a later timestamp is almost unchanged, while its GC, PD schedule and read-only mode are
+// still toggled, so such a flashback is rejected. If it's 0, only the timestamps not earlier than the current TSO
+// are rejected.
+var FlashbackClusterMinLag = time.Second
+
+// checkFlashbackClusterTSNotRecent checks that the flashback cluster timestamp is at least FlashbackClusterMinLag
+// earlier than the current TSO. It's checked before the job is submitted, so the statement fails fast before any
+// external toggle is changed.
+func checkFlashbackClusterTSNotRecent(ctx context.Context, sctx sessionctx.Context, flashbackTS uint64) error {
+	currentTS, err := sctx.GetStore().GetOracle().GetTimestamp(ctx, &oracle.Option{TxnScope: oracle.GlobalTxnScope})
 	if err != nil {
-		return err
+		return errors.Errorf("fail to validate flashback timestamp: %v", err)
 	}
-
-	return gcutil.ValidateSnapshotWithGCSafePoint(flashBackTS, gcSafePoint)
+	limitTS := currentTS
+	if lag := FlashbackClusterMinLag.Milliseconds(); lag > 0 {
+		limitTS = oracle.ComposeTS(oracle.ExtractPhysical(currentTS)-lag, 0)
+	}
+	if flashbackTS >= limitTS {
+		return dbterror.ErrFlashbackTSTooRecent.GenWithStackByArgs(oracle.GetTimeFromTS(flashbackTS),
+			FlashbackClusterMinLag, oracle.GetTimeFromTS(currentTS))
+	}
+	return nil
 }
 
-func checkAndSetFlashbackClusterInfo(sess sessionctx.Context, d *ddlCtx, t *meta.Meta, job *model.Job, flashbackTS uint64) (err error) {
-	if err = ValidateFlashbackTS(d.ctx, sess, flashbackTS); err != nil {
-		return err
+// checkFlashbackGCSafePoint checks that flashBackTS isn't older than the GC safe point, and returns the safe point.
+// The safe point is missing if GC has never run, e.g. it's disabled since the cluster is bootstrapped, then nothing
+// has been collected and flashBackTS has no lower bound, so 0 is returned.
+func checkFlashbackGCSafePoint(sctx sessionctx.Context, flashBackTS uint64) (uint64, error) {
+	gcSafePoint, ok, err := gcutil.GetGCSafePointIfExists(sctx)
+	if err != nil || !ok {
+		return 0, err
 	}
-
-	if err = gcutil.DisableGC(sess); err != nil {
-		return err
-	}
-	if err = closePDSchedule(); err != nil {
-		return err
+	if flashBackTS < gcSafePoint {
+		return gcSafePoint, storeerr.NewErrGCTooEarlyForRead(flashBackTS, gcSafePoint)
 	}
+	return gcSafePoint, nil
+}
 
+// checkFlashbackSchemaVersion checks that no DDL has been done during [flashbackTS, now).
+func checkFlashbackSchemaVersion(store kv.Storage, t *meta.Meta, flashbackTS uint64) error {
 	nowSchemaVersion, err := t.GetSchemaVersion()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	flashbackSchemaVersion, err := meta.NewSnapshotMeta(d.store.GetSnapshot(kv.NewVersion(flashbackTS))).GetSchemaVersion()
+	flashbackSchemaVersion, err := getSnapshotSchemaVersion(store, flashbackTS)
 	if err != nil {
-		return errors.Trace(err)
+		return err
 	}
 	// If flashbackSchemaVersion not same as nowSchemaVersion, we've done ddl during [flashbackTs, now).
 	if flashbackSchemaVersion != nowSchemaVersion {
 		return errors.Errorf("schema version not same, have done ddl during [flashbackTS, now)")
 	}
+	return nil
+}
+
+func getSnapshotSchemaVersion(store kv.Storage, ts uint64) (int64, error) {
+	schemaVersion, err := meta.NewSnapshotMeta(store.GetSnapshot(kv.NewVersion(ts))).GetSchemaVersion()
+	return schemaVersion, errors.Trace(err)
+}
+
+// checkFlashbackNoOtherJobs checks that there is no DDL job other than the flashback job with jobID in the queue.
+func checkFlashbackNoOtherJobs(jobs []*model.Job, jobID int64) error {
+	for _, j := range jobs {
+		if j.ID != jobID {
+			return errors.Errorf("have other ddl jobs(jobID: %d) in queue, can't do flashback", j.ID)
+		}
+	}
+	return nil
+}
+
+// checkFlashbackKeyspaceCodec checks that the codec of the store is keyspace-aware if the store runs with a keyspace,
+// or the flashback may touch the data of other keyspaces.
+func checkFlashbackKeyspaceCodec(store kv.Storage) error {
+	s, ok := store.(kv.KeyspaceStore)
+	if !ok {
+		return nil
+	}
+	if codec := s.GetKeyspaceCodec(); codec == nil || !codec.KeyspaceAware() {
+		return errors.Errorf("the store runs with a keyspace but its codec isn't keyspace-aware, can't do flashback")
+	}
+	return nil
+}
+
+// encodeFlashbackKeyRanges prepends the keyspace prefix to the key ranges if the store runs with a keyspace. The key
+// ranges built by GetFlashbackKeyRanges don't carry the prefix, which is added by the transaction layer for the other
+// requests, but the flashback RPCs are sent to TiKV with the keys as they are.
+func encodeFlashbackKeyRanges(store kv.Storage, keyRanges []kv.KeyRange) ([]kv.KeyRange, error) {
+	if err := checkFlashbackKeyspaceCodec(store); err != nil {
+		return nil, err
+	}
+	s, ok := store.(kv.KeyspaceStore)
+	if !ok {
+		return keyRanges, nil
+	}
+	codec := s.GetKeyspaceCodec()
+	encoded := make([]kv.KeyRange, 0, len(keyRanges))
+	for _, r := range keyRanges {
+		encoded = append(encoded, kv.KeyRange{StartKey: codec.EncodeKey(r.StartKey), EndKey: codec.EncodeKey(r.EndKey)})
+	}
+	return encoded, nil
+}
+
+func checkAndSetFlashbackClusterInfo(ctx context.Context, sess sessionctx.Context, d *ddlCtx, t *meta.Meta, job *model.Job, flashbackTS uint64) (err error) {
+	if err = ValidateFlashbackTS(ctx, sess, flashbackTS); err != nil {
+		return err
+	}
+	if err = checkFlashbackKeyspaceCodec(d.store); err != nil {
+		return err
+	}
+
+	if err = gcutil.DisableGC(sess); err != nil {
+		return err
+	}
+	if err = closePDSchedule(); err != nil {
+		return err
+	}
+
+	if err = checkFlashbackSchemaVersion(d.store, t, flashbackTS); err != nil {
+		return err
+	}
+	if err = checkFlashbackCollations(d.store, t, flashbackTS); err != nil {
+		return err
+	}
+
+	failpoint.Inject("mockSlowFlashbackInternalSQL", func(val failpoint.Value) {
+		if _, err = newSession(sess).execute(ctx, fmt.Sprintf("select sleep(%d)", val.(int)), "mock_slow_sql"); err != nil {
+			failpoint.Return(err)
+		}
+	})
 
-	jobs, err := GetAllDDLJobs(sess, t)
+	jobs, err := getAllDDLJobs(ctx, sess, t)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	// Other ddl jobs in queue, return error.
-	if len(jobs) != 1 {
-		var otherJob *model.Job
-		for _, j := range jobs {
-			if j.ID != job.ID {
-				otherJob = j
-				break
-			}
-		}
-		return errors.Errorf("have other ddl jobs(jobID: %d) in queue, can't do flashback", otherJob.ID)
-	}
-	return nil
+	return checkFlashbackNoOtherJobs(jobs, job.ID)
 }
 
 type flashbackID struct {
@@ -142,21 +402,59 @@ type flashbackID struct {
 }
 
 func addToSlice(schema string, tableName string, tableID int64, flashbackIDs []flashbackID) []flashbackID {
-	var excluded bool
-	if filter.IsSystemSchema(schema) && !strings.HasPrefix(tableName, "stats_") {
-		excluded = true
-	}
 	flashbackIDs = append(flashbackIDs, flashbackID{
 		id:       tableID,
-		excluded: excluded,
+		excluded: isFlashbackExcluded(schema, tableName),
 	})
 	return flashbackIDs
 }
 
+func addTableToFlashbackIDs(dbName string, table *model.TableInfo, flashbackIDs []flashbackID) []flashbackID {
+	if !table.IsBaseTable() || table.ID > meta.MaxGlobalID {
+		return flashbackIDs
+	}
+	flashbackIDs = addToSlice(dbName, table.Name.L, table.ID, flashbackIDs)
+	if table.Partition != nil {
+		for _, partition := range table.Partition.Definitions {
+			flashbackIDs = addToSlice(dbName, table.Name.L, partition.ID, flashbackIDs)
+		}
+	}
+	return flashbackIDs
+}
+
+func addTableMetaToFlashbackIDs(dbName string, table *meta.TableMeta, flashbackIDs []flashbackID) []flashbackID {
+	if !table.IsBaseTable() || table.ID > meta.MaxGlobalID {
+		return flashbackIDs
+	}
+	for _, id := range table.PhysicalIDs() {
+		flashbackIDs = addToSlice(dbName, table.Name.L, id, flashbackIDs)
+	}
+	return flashbackIDs
+}
+
+// getSnapshotFlashbackIDs returns the physical IDs of the tables at flashbackTS. The partitions exchanged with the
+// tables keep their physical IDs, so the data at flashbackTS may be in the physical tables which don't belong to the
+// tables now. The tables are walked by meta.IterTables, the snapshot infoschema isn't loaded.
+func getSnapshotFlashbackIDs(store kv.Storage, flashbackTS uint64, flashbackIDs []flashbackID) ([]flashbackID, error) {
+	snapMeta := meta.NewSnapshotMeta(store.GetSnapshot(kv.NewVersion(flashbackTS)))
+	err := meta.IterTables(snapMeta, func(dbInfo *model.DBInfo, tbl *meta.TableMeta) error {
+		flashbackIDs = addTableMetaToFlashbackIDs(dbInfo.Name.L, tbl, flashbackIDs)
+		return nil
+	})
+	return flashbackIDs, errors.Trace(err)
+}
+
 // GetFlashbackKeyRanges make keyRanges efficiently for flashback cluster when many tables in cluster,
 // The time complexity is O(nlogn).
-func GetFlashbackKeyRanges(sess sessionctx.Context, startKey kv.Key) ([]kv.KeyRange, error) {
-	schemas := sess.GetDomainInfoSchema().(infoschema.InfoSchema).AllSchemas()
+// If flashbackTS isn't 0, the physical IDs of the tables at flashbackTS are also covered by the key ranges.
+// The key ranges don't carry the keyspace prefix, it's prepended by encodeFlashbackKeyRanges before sending the RPCs.
+// The system tables are flashed back or not by their policies in systemTableFlashbackPolicies.
+func GetFlashbackKeyRanges(sess sessionctx.Context, startKey kv.Key, flashbackTS uint64) ([]kv.KeyRange, error) {
+	is := sess.GetDomainInfoSchema().(infoschema.InfoSchema)
+	if err := checkFlashbackForbiddenTables(is); err != nil {
+		return nil, err
+	}
+	schemas := is.AllSchemas()
 
 	// The semantic of keyRanges(output).
 	var keyRanges []kv.KeyRange
@@ -164,21 +462,29 @@ func GetFlashbackKeyRanges(sess sessionctx.Context, startKey kv.Key) ([]kv.KeyRa
 	var flashbackIDs []flashbackID
 	for _, db := range schemas {
 		for _, table := range db.Tables {
-			if !table.IsBaseTable() || table.ID > meta.MaxGlobalID {
-				continue
-			}
-			flashbackIDs = addToSlice(db.Name.L, table.Name.L, table.ID, flashbackIDs)
-			if table.Partition != nil {
-				for _, partition := range table.Partition.Definitions {
-					flashbackIDs = addToSlice(db.Name.L, table.Name.L, partition.ID, flashbackIDs)
-				}
-			}
+			flashbackIDs = addTableToFlashbackIDs(db.Name.L, table, flashbackIDs)
+		}
+	}
+	if flashbackTS != 0 {
+		var err error
+		if flashbackIDs, err = getSnapshotFlashbackIDs(sess.GetStore(), flashbackTS, flashbackIDs); err != nil {
+			return nil, err
 		}
 	}
 
 	slices.SortFunc(flashbackIDs, func(a, b flashbackID) bool {
 		return a.id < b.id
 	})
+	// Merge the same IDs from the current tables and the tables at flashbackTS, the ID is excluded if either is.
+	merged := flashbackIDs[:0]
+	for _, id := range flashbackIDs {
+		if len(merged) > 0 && merged[len(merged)-1].id == id.id {
+			merged[len(merged)-1].excluded = merged[len(merged)-1].excluded || id.excluded
+			continue
+		}
+		merged = append(merged, id)
+	}
+	flashbackIDs = merged
 
 	lastExcludeIdx := -1
 	for i, id := range flashbackIDs {
@@ -221,24 +527,50 @@ func GetFlashbackKeyRanges(sess sessionctx.Context, startKey kv.Key) ([]kv.KeyRa
 
 // A Flashback has 3 different stages.
 // 1. before lock flashbackClusterJobID, check clusterJobID and lock it.
-// 2. before flashback start, check timestamp, disable GC and close PD schedule.
+// 2. before flashback start, handle the conflicting queries, scatter the heavy ranges if required, check timestamp,
+// disable GC, close PD schedule and resolve the locks.
 // 3. before flashback done, get key ranges, send flashback RPC.
+// If the job has a timeout, each stage must finish within its share of the timeout, or the job is cancelled.
 func (w *worker) onFlashbackCluster(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64, err error) {
-	var flashbackTS uint64
-	var pdScheduleValue map[string]interface{}
-	if err := job.DecodeArgs(&flashbackTS, &pdScheduleValue); err != nil {
+	flashbackTS, _, _, batchSize, force, timeout, killConflictingQueries, scatterRegions, err := getFlashbackClusterArgs(job)
+	if err != nil {
 		job.State = model.JobStateCancelled
 		return ver, errors.Trace(err)
 	}
+	if err = checkFlashbackTimeout(job, timeout); err != nil {
+		job.State = model.JobStateCancelled
+		return ver, err
+	}
+	ctx, cancel := withFlashbackDeadline(w.jobContext(job).ctx, job, timeout)
+	defer cancel()
+
+	state := job.SchemaState
+	defer func() {
+		failpoint.Inject("mockFlashbackClusterErr", func(val failpoint.Value) {
+			if err == nil && val.(string) == state.String() {
+				job.State = model.JobStateCancelled
+				err = errors.New("mock flashback cluster error")
+			}
+		})
+	}()
 
-	switch job.SchemaState {
-	// Stage 1, check and set FlashbackClusterJobID, and save the PD schedule.
+	switch state {
+	// Stage 1, check and set FlashbackClusterJobID, save the PD schedule and record the toggles to change.
 	case model.StateNone:
 		flashbackJobID, err := t.GetFlashbackClusterJobID()
 		if err != nil {
 			job.State = model.JobStateCancelled
 			return ver, err
 		}
+		barrier, err := t.GetFlashbackTablesBarrier()
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		if barrier != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Errorf("Other flashback job(ID: %d) is running", barrier.JobID)
+		}
 		if flashbackJobID == 0 || flashbackJobID == job.ID {
 			err = kv.RunInNewTxn(w.ctx, w.store, true, func(ctx context.Context, txn kv.Transaction) error {
 				return meta.NewMeta(txn).SetFlashbackClusterJobID(job.ID)
@@ -247,42 +579,135 @@ func (w *worker) onFlashbackCluster(d *ddlCtx, t *meta.Meta, job *model.Job) (ve
 				job.State = model.JobStateCancelled
 				return ver, errors.Trace(err)
 			}
-			if err = savePDSchedule(job); err != nil {
+			if err = savePDSchedule(w, job); err != nil {
 				job.State = model.JobStateCancelled
 				return ver, errors.Trace(err)
 			}
+			// Record the toggles before changing them, so that they can be restored even if the owner changes.
+			gcEnabled, err := checkGCEnable(w)
+			if err != nil {
+				job.State = model.JobStateCancelled
+				return ver, errors.Trace(err)
+			}
+			changed := flashbackChangedPDSchedule
+			if gcEnabled {
+				changed |= flashbackChangedGC
+			}
+			setFlashbackChangedExternals(job, changed)
+			w.jobLogger(job).Info("record the external toggles to change by flashback cluster",
+				zap.Bool("gcEnabled", gcEnabled), zap.Uint64("changedExternals", changed))
 		} else {
 			job.State = model.JobStateCancelled
 			return ver, errors.Errorf("Other flashback job(ID: %d) is running", job.ID)
 		}
 		job.SchemaState = model.StateWriteOnly
 		return ver, nil
-	// Stage 2, check flashbackTS, close GC and PD schedule.
+	// Stage 2, handle the conflicting queries, scatter the heavy ranges, check flashbackTS, close GC and PD schedule,
+	// resolve the locks in the flashback ranges.
 	case model.StateWriteOnly:
-		sess, err := w.sessPool.get()
+		sess, err := w.sessPool.getForJob(job.ID, "flashback cluster check")
 		if err != nil {
 			job.State = model.JobStateCancelled
 			return ver, errors.Trace(err)
 		}
-		defer w.sessPool.put(sess)
-		if err = checkAndSetFlashbackClusterInfo(sess, d, t, job, flashbackTS); err != nil {
+		defer func() {
+			failpoint.Inject("mockLeakFlashbackSession", func(val failpoint.Value) {
+				if val.(bool) {
+					failpoint.Return()
+				}
+			})
+			w.sessPool.put(sess)
+		}()
+		untrack := w.trackJobSession(job, sess)
+		defer untrack()
+		// The queries reading the snapshot across the flashback are handled before the cluster becomes read-only.
+		if err = handleFlashbackConflictingQueries(ctx, job, killConflictingQueries); err != nil {
+			job.State = model.JobStateCancelled
+			if ctx.Err() == context.DeadlineExceeded {
+				return ver, errFlashbackTimeout(job, timeout)
+			}
+			return ver, errors.Trace(err)
+		}
+		// The regions are scattered before the PD schedule is closed, the rewrite traffic stays where it is after that.
+		if scatterRegions {
+			keyRanges, err := GetFlashbackKeyRanges(sess, tablecodec.EncodeTablePrefix(0), flashbackTS)
+			if err != nil {
+				job.State = model.JobStateCancelled
+				return ver, errors.Trace(err)
+			}
+			scatterFlashbackRanges(ctx, d.store, job, keyRanges)
+		}
+		if err = checkAndSetFlashbackClusterInfo(w.jobContext(job).ctx, sess, d, t, job, flashbackTS); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		w.jobLogger(job).Info("disable GC and close PD schedule for flashback cluster", zap.Uint64("flashbackTS", flashbackTS))
+		// Resolve the locks before flashing back, or the flashback fails late or waits for the live transactions.
+		keyRanges, err := GetFlashbackKeyRanges(sess, tablecodec.EncodeTablePrefix(0), flashbackTS)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		if err = resolveFlashbackLocks(ctx, d.store, keyRanges, job.StartTS, force); err != nil {
+			job.State = model.JobStateCancelled
+			if ctx.Err() == context.DeadlineExceeded {
+				return ver, errFlashbackTimeout(job, timeout)
+			}
+			return ver, errors.Trace(err)
+		}
+		// The stats tables are rewritten range by range, the snapshot reads of them are rejected until the job
+		// finishes, or they may see the torn stats.
+		statsRanges := getFlashbackStatsRanges(sess.GetDomainInfoSchema().(infoschema.InfoSchema), keyRanges)
+		if err = t.SetFlashbackRewritingRanges(&meta.FlashbackRewritingRanges{JobID: job.ID, Ranges: statsRanges}); err != nil {
 			job.State = model.JobStateCancelled
 			return ver, errors.Trace(err)
 		}
 		job.SchemaState = model.StateWriteReorganization
 		return ver, nil
-	// Stage 3, get key ranges.
+	// Stage 3, get key ranges and flash back the data in them.
 	case model.StateWriteReorganization:
-		sess, err := w.sessPool.get()
+		sess, err := w.sessPool.getForJob(job.ID, "flashback cluster get key ranges")
 		if err != nil {
 			job.State = model.JobStateCancelled
 			return ver, errors.Trace(err)
 		}
 		defer w.sessPool.put(sess)
-		_, err = GetFlashbackKeyRanges(sess, tablecodec.EncodeTablePrefix(0))
+		// The TiDB instances reload the rewriting ranges within a lease, wait for them to reject the snapshot reads.
+		if err = waitFlashbackRewritingRangesSeen(ctx, d.lease); err != nil {
+			return ver, errors.Trace(err)
+		}
+		policy := newRangeOpPolicy(job)
+		logger := w.jobLogger(job)
+		checkpoints, err := loadFlashbackCheckpoints(ctx, sess, logger, job)
 		if err != nil {
 			return ver, errors.Trace(err)
 		}
+		batch := newFlashbackBatch(logger, job, batchSize, checkpoints)
+		policy.SetOnRetry(func(attempt int, err error, backoff time.Duration) {
+			logger.Warn("retry flashback range operations", zap.Int("attempt", attempt+1),
+				zap.Duration("backoff", backoff), zap.Error(err))
+		})
+		err = policy.Do(ctx, func() error {
+			failpoint.Inject("mockFlashbackRangeOpErr", func(val failpoint.Value) {
+				if val.(bool) {
+					failpoint.Return(errors.Trace(storeerr.ErrTiKVServerBusy))
+				}
+			})
+			keyRanges, err := GetFlashbackKeyRanges(sess, tablecodec.EncodeTablePrefix(0), flashbackTS)
+			if err != nil {
+				return err
+			}
+			return flashbackToVersion(ctx, d.store, keyRanges, flashbackTS, batch)
+		})
+		recordRangeOpRetries(job, policy)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				job.State = model.JobStateCancelled
+				return ver, errFlashbackTimeout(job, timeout)
+			}
+			return ver, errors.Trace(err)
+		}
+		logFlashbackStats(logger, job)
 
 		job.State = model.JobStateDone
 		job.SchemaState = model.StatePublic
@@ -291,38 +716,249 @@ func (w *worker) onFlashbackCluster(d *ddlCtx, t *meta.Meta, job *model.Job) (ve
 	return ver, nil
 }
 
-func finishFlashbackCluster(w *worker, job *model.Job) error {
-	var flashbackTS uint64
-	var pdScheduleValue map[string]interface{}
-	if err := job.DecodeArgs(&flashbackTS, &pdScheduleValue); err != nil {
+// flashbackToVersion flashes back the data in the key ranges to flashbackTS if the store supports it. It can be retried
+// from the beginning, the ranges flashed back by the last attempt are the same as at flashbackTS, they're left unchanged.
+// The ranges are flashed back by the batches of the regions, see flashbackBatch, and a checkpoint is recorded after
+// each batch, so the retried step resumes from it.
+func flashbackToVersion(ctx context.Context, store kv.Storage, keyRanges []kv.KeyRange, flashbackTS uint64,
+	batch *flashbackBatch) error {
+	s, ok := store.(kv.FlashbackableStore)
+	if !ok {
+		return nil
+	}
+	if err := checkFlashbackKeyspaceCodec(store); err != nil {
+		return err
+	}
+	err := batch.forEach(ctx, store, keyRanges, func(_ int, r kv.KeyRange, _ int) error {
+		encoded, err := encodeFlashbackKeyRanges(store, []kv.KeyRange{r})
+		if err != nil {
+			return err
+		}
+		return errors.Trace(s.PrepareFlashbackToVersion(ctx, encoded[0].StartKey, encoded[0].EndKey))
+	})
+	if err != nil {
+		return err
+	}
+	startTS, err := store.CurrentVersion(kv.GlobalTxnScope)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	commitTS, err := store.CurrentVersion(kv.GlobalTxnScope)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return batch.forEach(ctx, store, keyRanges, func(idx int, r kv.KeyRange, regionCount int) error {
+		encoded, err := encodeFlashbackKeyRanges(store, []kv.KeyRange{r})
+		if err != nil {
+			return err
+		}
+		stats, err := s.FlashbackToVersion(ctx, encoded[0].StartKey, encoded[0].EndKey, flashbackTS, startTS.Ver, commitTS.Ver)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		recordFlashbackRangeStats(batch.job, idx, stats)
+		if err = batch.checkpoints.save(ctx, idx, r.EndKey, regionCount); err != nil {
+			return err
+		}
+		failpoint.Inject("pauseAfterFlashbackRange", nil)
+		failpoint.Inject("mockFlashbackCrashAfterCheckpoint", func(val failpoint.Value) {
+			if val.(bool) {
+				failpoint.Return(errors.New("mock the DDL owner crashes after the checkpoint"))
+			}
+		})
+		return nil
+	})
+}
+
+// getFlashbackStatsRanges returns the ranges of the stats tables which are flashed back in keyRanges.
+func getFlashbackStatsRanges(is infoschema.InfoSchema, keyRanges []kv.KeyRange) []kv.KeyRange {
+	db, ok := is.SchemaByName(model.NewCIStr(mysql.SystemDB))
+	if !ok {
+		return nil
+	}
+	var statsRanges []kv.KeyRange
+	for _, tbl := range db.Tables {
+		if isFlashbackExcluded(mysql.SystemDB, tbl.Name.L) {
+			continue
+		}
+		r := kv.KeyRange{StartKey: tablecodec.EncodeTablePrefix(tbl.ID), EndKey: tablecodec.EncodeTablePrefix(tbl.ID + 1)}
+		for _, keyRange := range keyRanges {
+			if r.StartKey.Cmp(keyRange.EndKey) < 0 && keyRange.StartKey.Cmp(r.EndKey) < 0 {
+				statsRanges = append(statsRanges, r)
+				break
+			}
+		}
+	}
+	return statsRanges
+}
+
+// waitFlashbackRewritingRangesSeen waits a lease for the TiDB instances to reload the ranges being rewritten by the
+// flashback cluster job.
+func waitFlashbackRewritingRangesSeen(ctx context.Context, lease time.Duration) error {
+	if lease == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(lease):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finishFlashbackCluster restores the external toggles and releases the flashback cluster job ID. If the job succeeds,
+// the marker with the flashback TS is written and the coprocessor cache epoch is bumped in t, which is the final
+// commit of the job, and the erased timeline is recorded as the flashback watermark.
+func finishFlashbackCluster(w *worker, t *meta.Meta, job *model.Job) error {
+	flashbackTS, pdScheduleValue, changedExternals, _, _, _, _, _, err := getFlashbackClusterArgs(job)
+	if err != nil {
 		return errors.Trace(err)
 	}
 
-	err := kv.RunInNewTxn(w.ctx, w.store, true, func(ctx context.Context, txn kv.Transaction) error {
+	restored := false
+	err = kv.RunInNewTxn(w.ctx, w.store, true, func(ctx context.Context, txn kv.Transaction) error {
 		t := meta.NewMeta(txn)
 		jobID, err := t.GetFlashbackClusterJobID()
 		if err != nil {
 			return err
 		}
+		restored = false
 		if jobID == job.ID {
-			if pdScheduleValue != nil {
-				if err = recoverPDSchedule(pdScheduleValue); err != nil {
-					return err
-				}
-			}
-			if err = enableGC(w); err != nil {
+			if err = restoreFlashbackExternals(w, job.ID, changedExternals, pdScheduleValue); err != nil {
 				return err
 			}
+			restored = true
 			err = t.SetFlashbackClusterJobID(0)
 			if err != nil {
 				return err
 			}
+			if err = clearFlashbackPDSchedule(t, job.ID); err != nil {
+				return err
+			}
+			if err = t.SetFlashbackRewritingRanges(nil); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+	if restored {
+		w.jobLogger(job).Info("restore the external toggles changed by flashback cluster", zap.Uint64("changedExternals", changedExternals))
+	}
+	purgeFlashbackCheckpoints(w, job)
+	if job.IsSynced() {
+		// The data is rewritten, the coprocessor caches populated before can't be used anymore.
+		if err = t.BumpCoprCacheEpoch(); err != nil {
+			return err
+		}
+		// The data is rewritten before the final commit, the reads after its start TS see the rewound timeline.
+		recordFlashbackWatermark(w, job, flashbackTS, t.StartTS)
+		return errors.Trace(t.SetLastFlashbackClusterTS(flashbackTS))
+	}
+	return nil
+}
+
+// recordFlashbackWatermark records the timeline erased by the flashback cluster job, i.e. (targetTS, endTS), into
+// mysql.flashback_watermark. With the concurrent DDL, it's written in the final commit of the job. Otherwise, it's
+// written right before the final commit. The failure is only logged, because the watermark is used to check the
+// stale reads, it shouldn't block the job.
+func recordFlashbackWatermark(w *worker, job *model.Job, targetTS, endTS uint64) {
+	sql := fmt.Sprintf("insert ignore into mysql.flashback_watermark(job_id, table_id, target_ts, end_ts) values (%d, 0, %d, %d)",
+		job.ID, targetTS, endTS)
+	var err error
+	if w.concurrentDDL {
+		_, err = w.sess.execute(w.ctx, sql, "record_flashback_watermark")
+	} else {
+		var sctx sessionctx.Context
+		if sctx, err = w.sessPool.get(); err == nil {
+			_, err = newSession(sctx).execute(w.ctx, sql, "record_flashback_watermark")
+			w.sessPool.put(sctx)
+		}
+	}
+	if err != nil {
+		logutil.BgLogger().Warn("[ddl] record the flashback watermark failed", zap.Int64("jobID", job.ID),
+			zap.Uint64("targetTS", targetTS), zap.Uint64("endTS", endTS), zap.Error(err))
+	}
+}
 
+// GetLastFlashbackTSO returns the target TSO of the last finished flashback cluster job, it returns 0 if no flashback
+// cluster job has finished. The tools like BR can use it to refuse restoring the log backup across a flashback.
+func GetLastFlashbackTSO(sctx sessionctx.Context) (uint64, error) {
+	store := sctx.GetStore()
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return meta.NewSnapshotMeta(store.GetSnapshot(ver)).GetLastFlashbackClusterTS()
+}
+
+// GetFlashbackUndoTS returns the TS to flash the cluster back to for undoing the flashback cluster job, i.e. the
+// start TS of the job. Unless force is true, the job can only be undone in its read-only window: tidb_super_read_only
+// is ON, and no DDL or other flashback cluster job has finished after it, so that nothing written after the job is
+// lost by the undo.
+func GetFlashbackUndoTS(ctx context.Context, store kv.Storage, jobID int64, force bool) (uint64, error) {
+	job, err := getHistoryJob(ctx, store, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if job == nil {
+		return 0, dbterror.ErrDDLJobNotFound.GenWithStackByArgs(jobID)
+	}
+	if job.Type != model.ActionFlashbackCluster {
+		return 0, errors.Errorf("the job %d isn't a flashback cluster job, can't undo it", jobID)
+	}
+	if !job.IsSynced() {
+		return 0, errors.Errorf("the flashback cluster job %d isn't done successfully, there is nothing to undo", jobID)
+	}
+	if force {
+		return job.StartTS, nil
+	}
+	if !variable.VarTiDBSuperReadOnly.Load() {
+		return 0, errors.Errorf("the cluster isn't read-only after the flashback cluster job %d, set tidb_super_read_only to ON before undoing it, or undo it with FORCE", jobID)
+	}
+	flashbackTS, _, _, _, _, _, _, _, err := getFlashbackClusterArgs(job)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	t := meta.NewSnapshotMeta(store.GetSnapshot(ver))
+	lastFlashbackTS, err := t.GetLastFlashbackClusterTS()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if lastFlashbackTS != flashbackTS {
+		return 0, errors.Errorf("other flashback cluster job has finished after the flashback cluster job %d, can't undo it", jobID)
+	}
+	// The schema version isn't changed by the final commit of the job, so it can be compared with the one read at
+	// the finished TS, which is the start TS of the final commit.
+	if err = checkFlashbackSchemaVersion(store, t, job.BinlogInfo.FinishedTS); err != nil {
+		return 0, errors.Errorf("have done ddl after the flashback cluster job %d, can't undo it", jobID)
+	}
+	return job.StartTS, nil
+}
+
+// restoreFlashbackExternals restores the external toggles changed by the flashback job. It's called on every
+// terminal path of the job, and is idempotent so that it can be retried by the next owner.
+func restoreFlashbackExternals(w *worker, jobID int64, changed uint64, pdScheduleValue map[string]interface{}) error {
+	if changed&flashbackChangedPDSchedule != 0 && pdScheduleValue != nil {
+		if err := recoverPDSchedule(pdScheduleValue); err != nil {
+			return err
+		}
+	}
+	if changed&flashbackChangedScheduleDenyRule != 0 {
+		if err := infosync.RemoveKeyRangeScheduleDenyRule(w.ctx, flashbackScheduleDenyRuleID(jobID)); err != nil {
+			return err
+		}
+	}
+	if changed&flashbackChangedGC != 0 {
+		if err := enableGC(w); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -15,21 +15,37 @@
 package ddl_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/domain/infosync"
 	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/session"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/store/mockstore"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/external"
 	"github.com/pingcap/tidb/util/dbterror"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/atomic"
 )
 
 func TestGetFlashbackKeyRanges(t *testing.T) {
@@ -38,7 +54,7 @@ func TestGetFlashbackKeyRanges(t *testing.T) {
 	se, err := session.CreateSession4Test(store)
 	require.NoError(t, err)
 
-	kvRanges, err := ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0))
+	kvRanges, err := ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), 0)
 	require.NoError(t, err)
 	// The results are 6 key ranges
 	// 0: (stats_meta,stats_histograms,stats_buckets)
@@ -57,14 +73,14 @@ func TestGetFlashbackKeyRanges(t *testing.T) {
 
 	// The original table ID for range is [60, 63)
 	// startKey is 61, so return [61, 63)
-	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(61))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(61), 0)
 	require.NoError(t, err)
 	require.Len(t, kvRanges, 1)
 	require.Equal(t, kvRanges[0].StartKey, tablecodec.EncodeTablePrefix(61))
 
 	// The original ranges are [48, 49), [60, 63)
 	// startKey is 59, so return [60, 63)
-	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(59))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(59), 0)
 	require.NoError(t, err)
 	require.Len(t, kvRanges, 1)
 	require.Equal(t, kvRanges[0].StartKey, tablecodec.EncodeTablePrefix(60))
@@ -79,7 +95,7 @@ func TestGetFlashbackKeyRanges(t *testing.T) {
 		"    PARTITION p2 VALUES LESS THAN (16)," +
 		"    PARTITION p3 VALUES LESS THAN (21)" +
 		");")
-	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(63))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(63), 0)
 	require.NoError(t, err)
 	// start from table ID is 63, so only 1 kv range.
 	require.Len(t, kvRanges, 1)
@@ -98,16 +114,197 @@ func TestGetFlashbackKeyRanges(t *testing.T) {
 	tk.MustExec("truncate table mysql.stats_fm_sketch")
 	tk.MustExec("truncate table mysql.stats_history")
 	tk.MustExec("truncate table mysql.stats_meta_history")
-	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), 0)
 	require.NoError(t, err)
 	require.Len(t, kvRanges, 2)
 
 	tk.MustExec("truncate table test.employees")
-	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), 0)
 	require.NoError(t, err)
 	require.Len(t, kvRanges, 1)
 }
 
+func TestSystemTableFlashbackPolicies(t *testing.T) {
+	_, dom := testkit.CreateMockStoreAndDomain(t)
+
+	// Every table created by the bootstrap must decide how it's flashed back.
+	db, ok := dom.InfoSchema().SchemaByName(model.NewCIStr(mysql.SystemDB))
+	require.True(t, ok)
+	require.NotEmpty(t, db.Tables)
+	for _, tbl := range db.Tables {
+		_, ok := ddl.GetSystemTableFlashbackPolicy(tbl.Name.L)
+		require.Truef(t, ok, "the flashback policy of mysql.%s isn't registered", tbl.Name.O)
+	}
+
+	policy, ok := ddl.GetSystemTableFlashbackPolicy("STATS_META")
+	require.True(t, ok)
+	require.Equal(t, ddl.FlashbackPolicyFlashback, policy)
+	policy, ok = ddl.GetSystemTableFlashbackPolicy("tidb_ddl_job")
+	require.True(t, ok)
+	require.Equal(t, ddl.FlashbackPolicySkip, policy)
+}
+
+func TestGetFlashbackKeyRangesWithExchangePartition(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	se, err := session.CreateSession4Test(store)
+	require.NoError(t, err)
+	tk.MustExec("use test")
+	// Create nt first, so its original physical ID is the smallest one of the user tables.
+	tk.MustExec("create table nt (a int)")
+	tk.MustExec("create table pt (a int) partition by range (a) (partition p0 values less than (10), partition p1 values less than (20))")
+	tk.MustExec("insert into pt values (1), (11)")
+	tk.MustExec("insert into nt values (2)")
+	tk.MustExec("set @@tidb_enable_exchange_partition=1")
+	tk.MustExec("alter table pt exchange partition p0 with table nt")
+	ptInfo := external.GetTableByName(t, tk, "test", "pt").Meta()
+	physicalIDs := []int64{ptInfo.ID, external.GetTableByName(t, tk, "test", "nt").Meta().ID}
+	for _, def := range ptInfo.Partition.Definitions {
+		physicalIDs = append(physicalIDs, def.ID)
+	}
+	// The original physical ID of nt holds the data of p0 now.
+	ntOriginID := ptInfo.Partition.Definitions[0].ID
+	covered := func(ranges []kv.KeyRange, id int64) bool {
+		key := tablecodec.EncodeTablePrefix(id)
+		for _, r := range ranges {
+			if key.Cmp(r.StartKey) >= 0 && key.Cmp(r.EndKey) < 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	kvRanges, err := ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), ts)
+	require.NoError(t, err)
+	for _, id := range physicalIDs {
+		require.True(t, covered(kvRanges, id))
+	}
+	// The data exchanged before flashbackTS stays in the exchanged tables.
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")))
+	tk.MustQuery("select * from pt order by a").Check(testkit.Rows("2", "11"))
+	tk.MustQuery("select * from pt partition (p0)").Check(testkit.Rows("2"))
+	tk.MustQuery("select * from nt").Check(testkit.Rows("1"))
+
+	// The physical IDs at flashbackTS are covered even if they don't belong to any table now.
+	tk.MustExec("truncate table nt")
+	tk.MustExec("alter table pt truncate partition p0")
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), 0)
+	require.NoError(t, err)
+	require.False(t, covered(kvRanges, ntOriginID))
+	kvRanges, err = ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), ts)
+	require.NoError(t, err)
+	for _, id := range physicalIDs {
+		require.True(t, covered(kvRanges, id))
+	}
+	require.True(t, covered(kvRanges, external.GetTableByName(t, tk, "test", "nt").Meta().ID))
+}
+
+// mockKeyspaceCodec prepends the prefix to the keys, it isn't keyspace-aware if the prefix is empty.
+type mockKeyspaceCodec struct {
+	prefix []byte
+}
+
+func (c *mockKeyspaceCodec) KeyspaceAware() bool {
+	return len(c.prefix) > 0
+}
+
+func (c *mockKeyspaceCodec) EncodeKey(key kv.Key) kv.Key {
+	return append(append(kv.Key{}, c.prefix...), key...)
+}
+
+func (c *mockKeyspaceCodec) DecodeKey(key kv.Key) (kv.Key, error) {
+	if !bytes.HasPrefix(key, c.prefix) {
+		return nil, errors.Errorf("key %s isn't in the keyspace", key)
+	}
+	return key[len(c.prefix):], nil
+}
+
+// mockKeyspaceStore runs with a keyspace, it records the ranges of the flashback RPCs, and strips the keyspace prefix
+// before flashing back the data of the store.
+type mockKeyspaceStore struct {
+	kv.Storage
+	codec  *mockKeyspaceCodec
+	ranges []kv.KeyRange
+}
+
+func (s *mockKeyspaceStore) GetKeyspaceCodec() kv.KeyspaceCodec {
+	return s.codec
+}
+
+func (s *mockKeyspaceStore) decodeRange(startKey, endKey []byte) (kv.Key, kv.Key, error) {
+	start, err := s.codec.DecodeKey(startKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := s.codec.DecodeKey(endKey)
+	return start, end, err
+}
+
+func (s *mockKeyspaceStore) PrepareFlashbackToVersion(ctx context.Context, startKey, endKey []byte) error {
+	start, end, err := s.decodeRange(startKey, endKey)
+	if err != nil {
+		return err
+	}
+	return s.Storage.(kv.FlashbackableStore).PrepareFlashbackToVersion(ctx, start, end)
+}
+
+func (s *mockKeyspaceStore) FlashbackToVersion(ctx context.Context, startKey, endKey []byte, version, startTS, commitTS uint64) (*kv.FlashbackStats, error) {
+	s.ranges = append(s.ranges, kv.KeyRange{StartKey: startKey, EndKey: endKey})
+	start, end, err := s.decodeRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.Storage.(kv.FlashbackableStore).FlashbackToVersion(ctx, start, end, version, startTS, commitTS)
+}
+
+func TestFlashbackKeyRangesWithKeyspace(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+	tk.MustExec("insert into t values (1), (2)")
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec("insert into t values (3)")
+	tblID := external.GetTableByName(t, tk, "test", "t").Meta().ID
+
+	se, err := session.CreateSession4Test(store)
+	require.NoError(t, err)
+	keyRanges, err := ddl.GetFlashbackKeyRanges(se, tablecodec.EncodeTablePrefix(0), 0)
+	require.NoError(t, err)
+
+	// The store isn't keyspace-aware, the flashback is refused before any RPC is sent.
+	ks := &mockKeyspaceStore{Storage: store, codec: &mockKeyspaceCodec{}}
+	require.ErrorContains(t, ddl.CheckFlashbackKeyspaceCodec(ks), "isn't keyspace-aware")
+	require.ErrorContains(t, ddl.FlashbackToVersion(context.Background(), ks, &model.Job{}, keyRanges, ts), "isn't keyspace-aware")
+	require.Empty(t, ks.ranges)
+
+	// The ranges sent to the store carry the keyspace prefix, and decode back to the tables.
+	ks.codec.prefix = []byte{'x', 0, 0, 1}
+	require.NoError(t, ddl.CheckFlashbackKeyspaceCodec(ks))
+	require.NoError(t, ddl.FlashbackToVersion(context.Background(), ks, &model.Job{}, keyRanges, ts))
+	require.Len(t, ks.ranges, len(keyRanges))
+	var tblCovered bool
+	for i, r := range ks.ranges {
+		require.True(t, bytes.HasPrefix(r.StartKey, ks.codec.prefix))
+		require.True(t, bytes.HasPrefix(r.EndKey, ks.codec.prefix))
+		start, end, err := ks.decodeRange(r.StartKey, r.EndKey)
+		require.NoError(t, err)
+		require.Equal(t, keyRanges[i].StartKey, start)
+		require.Equal(t, keyRanges[i].EndKey, end)
+		startID, endID := tablecodec.DecodeTableID(start), tablecodec.DecodeTableID(end)
+		tblCovered = tblCovered || (startID <= tblID && tblID < endID)
+	}
+	require.True(t, tblCovered)
+	tk.MustQuery("select * from t").Check(testkit.Rows("1", "2"))
+}
+
 func TestFlashbackCloseAndResetPDSchedule(t *testing.T) {
 	store, dom := testkit.CreateMockStoreAndDomain(t)
 	originHook := dom.DDL().GetHook()
@@ -176,3 +373,1134 @@ func TestCancelFlashbackCluster(t *testing.T) {
 
 	dom.DDL().SetHook(originHook)
 }
+
+func TestFlashbackClusterSessionLeak(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	defer tk.MustExec(fmt.Sprintf("set global tidb_ddl_session_leak_threshold = %d", variable.DefTiDBDDLSessionLeakThreshold))
+
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockLeakFlashbackSession", `return(true)`))
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockLeakFlashbackSession"))
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0]
+	// The DDL workers hold their sessions all the time, which aren't leaks.
+	tk.MustQuery("select count(*) > 0 from information_schema.internal_session_pool where resident = 1 and leaked = 0").Check(testkit.Rows("1"))
+
+	const leakedSQL = "select job_id, purpose, leaked from information_schema.internal_session_pool where job_id > 0"
+	tk.MustQuery(leakedSQL).Check(testkit.Rows(fmt.Sprintf("%s flashback cluster check 0", jobID)))
+	tk.MustExec("set global tidb_ddl_session_leak_threshold = 1")
+	require.Eventually(t, func() bool {
+		return len(tk.MustQuery(leakedSQL+" and leaked = 1").Rows()) == 1
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// The leaked session is reported, but kept by its holder unless it's recycled.
+	require.GreaterOrEqual(t, ddl.CheckSessionLeaks(dom.DDL(), time.Second, false), 1)
+	tk.MustQuery(leakedSQL).Check(testkit.Rows(fmt.Sprintf("%s flashback cluster check 1", jobID)))
+	require.GreaterOrEqual(t, ddl.CheckSessionLeaks(dom.DDL(), time.Second, true), 1)
+	tk.MustQuery(leakedSQL).Check(testkit.Rows())
+
+	// The recycled session is reused by the later DDL.
+	tk.MustExec("create table test.t_leak (a int)")
+	tk.MustExec("drop table test.t_leak")
+}
+
+func TestFlashbackClusterMarker(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	originHook := dom.DDL().GetHook()
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	lastTS, err := ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	require.Zero(t, lastTS)
+
+	// The cancelled job doesn't write the marker.
+	hook := newCancelJobHook(t, store, dom, func(job *model.Job) bool {
+		return job.SchemaState == model.StateWriteOnly
+	})
+	dom.DDL().SetHook(hook)
+	tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)), errno.ErrCancelledDDLJob)
+	hook.MustCancelDone(t)
+	dom.DDL().SetHook(originHook)
+	lastTS, err = ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	require.Zero(t, lastTS)
+
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	lastTS, err = ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	// The timestamp is parsed from the time string, so it loses the logical part.
+	require.Equal(t, oracle.ExtractPhysical(ts), oracle.ExtractPhysical(lastTS))
+}
+
+func TestInterruptFlashbackClusterInternalSQL(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk2 := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockSlowFlashbackInternalSQL", "return(100)"))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockSlowFlashbackInternalSQL"))
+	}()
+
+	runFlashback := func() (<-chan error, uint64, int64) {
+		done := make(chan error, 1)
+		go func() {
+			done <- tk.ExecToErr(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+		}()
+		// The internal session running the slow query is shown in the process list.
+		var procID uint64
+		var jobID int64
+		require.Eventually(t, func() bool {
+			for id, pi := range dom.SysProcTracker().GetSysProcessList() {
+				if strings.HasPrefix(pi.User, "ddl_job_") && strings.Contains(pi.Info, "sleep") {
+					procID = id
+					_, err := fmt.Sscanf(pi.User, "ddl_job_%d", &jobID)
+					return err == nil
+				}
+			}
+			return false
+		}, 10*time.Second, 10*time.Millisecond)
+		return done, procID, jobID
+	}
+	waitDone := func(done <-chan error) error {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "the flashback job isn't interrupted in time")
+		}
+		return nil
+	}
+
+	// Cancelling the job interrupts the in-flight internal SQL.
+	done, _, jobID := runFlashback()
+	tk2.MustQuery(fmt.Sprintf("admin cancel ddl jobs %d", jobID)).Check(testkit.Rows(fmt.Sprintf("%d successful", jobID)))
+	err = waitDone(done)
+	require.True(t, dbterror.ErrCancelledDDLJob.Equal(err), "err %v", err)
+	require.Len(t, dom.SysProcTracker().GetSysProcessList(), 0)
+
+	// Killing the internal session only interrupts the current query, the job goes on.
+	done, procID, _ := runFlashback()
+	dom.SysProcTracker().KillSysProcess(procID)
+	require.NoError(t, waitDone(done))
+}
+
+func TestFlashbackClusterRetryRangeOp(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackRangeOpErr", "3*return(true)"))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockFlashbackRangeOpErr"))
+	}()
+
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	rows := tk.MustQuery("admin show ddl jobs 1").Rows()
+	require.Len(t, rows, 1)
+	require.Equal(t, "flashback cluster /* retries: 3 */", rows[0][3])
+	require.Equal(t, "synced", rows[0][11])
+
+	// The retries are recorded in the log of the job.
+	var retries []string
+	for _, row := range tk.MustQuery(fmt.Sprintf("admin show ddl job log %s", rows[0][0])).Rows() {
+		if row[3] == "retry flashback range operations" {
+			require.Equal(t, "warn", row[2])
+			require.Contains(t, row[4], "TiKV server is busy")
+			retries = append(retries, row[4].(string))
+		}
+	}
+	require.Len(t, retries, 3)
+	for i, fields := range retries {
+		require.Contains(t, fields, fmt.Sprintf(`"attempt":%d`, i+1))
+	}
+}
+
+func TestFlashbackBatchHalveSize(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustQuery("split table t between (0) and (80000) regions 8").Check(testkit.Rows("7 1"))
+	tblID := external.GetTableByName(t, tk, "test", "t").Meta().ID
+	keyRanges := []kv.KeyRange{{StartKey: tablecodec.EncodeTablePrefix(tblID), EndKey: tablecodec.EncodeTablePrefix(tblID + 1)}}
+	ctx := context.Background()
+
+	// The whole table is flashed back by one request.
+	job := &model.Job{}
+	batches, err := ddl.GetFlashbackBatches(ctx, store, job, 64, keyRanges)
+	require.NoError(t, err)
+	require.Equal(t, keyRanges, batches)
+	require.Nil(t, job.ReorgMeta)
+
+	// The requests covering more than 2 regions time out, the batch size is halved until they succeed.
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackBatchTimeout", "return(2)"))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockFlashbackBatchTimeout"))
+	}()
+	batches, err = ddl.GetFlashbackBatches(ctx, store, job, 8, keyRanges)
+	require.NoError(t, err)
+	require.Equal(t, 2, job.ReorgMeta.FlashbackBatchSize)
+	require.Len(t, batches, 4)
+	require.Equal(t, keyRanges[0].StartKey, batches[0].StartKey)
+	for i := 1; i < len(batches); i++ {
+		require.Equal(t, batches[i-1].EndKey, batches[i].StartKey)
+	}
+	require.Equal(t, keyRanges[0].EndKey, batches[len(batches)-1].EndKey)
+
+	// The retried step starts with the halved size.
+	batches, err = ddl.GetFlashbackBatches(ctx, store, job, 64, keyRanges)
+	require.NoError(t, err)
+	require.Len(t, batches, 4)
+
+	// The size isn't halved below 1.
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackBatchTimeout", "return(0)"))
+	_, err = ddl.GetFlashbackBatches(ctx, store, job, 64, keyRanges)
+	require.Error(t, err)
+	require.Equal(t, 1, job.ReorgMeta.FlashbackBatchSize)
+}
+
+func TestFlashbackClusterBatchSizeArg(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("set @@tidb_flashback_batch_size = 8")
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	var batchSize []interface{}
+	for _, row := range tk.MustQuery(fmt.Sprintf("admin show ddl job args %s", jobID)).Rows() {
+		if row[2] == "batch_size" {
+			batchSize = row[3:]
+		}
+	}
+	require.Equal(t, []interface{}{"8"}, batchSize)
+}
+
+// flashbackRecordStore records the key ranges flashed back in it, and returns the stats for each of them.
+type flashbackRecordStore struct {
+	kv.Storage
+	ranges []kv.KeyRange
+	stats  *kv.FlashbackStats
+}
+
+func (s *flashbackRecordStore) PrepareFlashbackToVersion(context.Context, []byte, []byte) error {
+	return nil
+}
+
+func (s *flashbackRecordStore) FlashbackToVersion(_ context.Context, startKey, endKey []byte, _, _, _ uint64) (*kv.FlashbackStats, error) {
+	s.ranges = append(s.ranges, kv.KeyRange{StartKey: startKey, EndKey: endKey})
+	return s.stats, nil
+}
+
+func TestFlashbackCheckpoints(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	keyRanges := []kv.KeyRange{
+		{StartKey: kv.Key("t1"), EndKey: kv.Key("t2")},
+		{StartKey: kv.Key("t2"), EndKey: kv.Key("t3")},
+		{StartKey: kv.Key("t3"), EndKey: kv.Key("t4")},
+	}
+	recorder := &flashbackRecordStore{Storage: store}
+	job := &model.Job{ID: 1000001}
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+
+	// The DDL owner crashes after the first range is flashed back and its checkpoint is recorded.
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackCrashAfterCheckpoint", "1*return(true)"))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockFlashbackCrashAfterCheckpoint"))
+	}()
+	require.Error(t, ddl.FlashbackToVersionWithCheckpoints(ctx, tk.Session(), recorder, job, keyRanges, 1))
+	require.Equal(t, keyRanges[:1], recorder.ranges)
+	tk.MustQuery("select job_id, range_index, hex(end_key), region_count from mysql.tidb_flashback_checkpoint").
+		Check(testkit.Rows("1000001 0 7432 1"))
+
+	// The retried step resumes from the checkpoint.
+	recorder.ranges = nil
+	require.NoError(t, ddl.FlashbackToVersionWithCheckpoints(ctx, tk.Session(), recorder, job, keyRanges, 1))
+	require.Equal(t, keyRanges[1:], recorder.ranges)
+	checkpoints, err := ddl.GetFlashbackCheckpoints(ctx, tk.Session(), job.ID)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 3)
+	for i, cp := range checkpoints {
+		require.Equal(t, i, cp.RangeIndex)
+		require.Equal(t, keyRanges[i].EndKey, cp.EndKey)
+	}
+	// The checkpoints of the other jobs aren't shown with the jobs.
+	tk.MustQuery("admin show ddl jobs 1").CheckAt([]int{13}, testkit.Rows("<nil>"))
+
+	// The checkpoints left by the lost jobs are purged after the retention.
+	tk.MustExec("insert into mysql.tidb_flashback_checkpoint values (1000002, 0, x'74', 3, '2000-01-01 00:00:00'), " +
+		"(1000002, 1, x'75', 1, '2000-01-01 00:00:00'), (1000003, 0, x'74', 3, now(6))")
+	orphans, err := ddl.CleanupOrphanFlashbackCheckpoints(ctx, tk.Session())
+	require.NoError(t, err)
+	require.Equal(t, []int64{1000002}, orphans)
+	tk.MustQuery("select distinct job_id from mysql.tidb_flashback_checkpoint order by job_id").Check(testkit.Rows("1000001", "1000003"))
+	defer func(retention time.Duration) {
+		ddl.FlashbackCheckpointRetention = retention
+	}(ddl.FlashbackCheckpointRetention)
+	ddl.FlashbackCheckpointRetention = 0
+	orphans, err = ddl.CleanupOrphanFlashbackCheckpoints(ctx, tk.Session())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{1000001, 1000003}, orphans)
+	tk.MustQuery("select count(*) from mysql.tidb_flashback_checkpoint").Check(testkit.Rows("0"))
+}
+
+func TestFlashbackRangeStats(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	keyRanges := []kv.KeyRange{
+		{StartKey: kv.Key("t1"), EndKey: kv.Key("t2")},
+		{StartKey: kv.Key("t2"), EndKey: kv.Key("t3")},
+	}
+	recorder := &flashbackRecordStore{Storage: store, stats: &kv.FlashbackStats{KeysProcessed: 3, VersionsWritten: 2}}
+	ctx := context.Background()
+
+	// The counters of the ranges are added up.
+	job := &model.Job{}
+	require.NoError(t, ddl.FlashbackToVersion(ctx, recorder, job, keyRanges, 1))
+	require.Len(t, job.ReorgMeta.FlashbackRanges, 2)
+	require.Equal(t, uint64(3), *job.ReorgMeta.FlashbackRanges[1].KeysProcessed)
+	require.Equal(t, "Keys processed: 6  Versions written: 4", ddl.FlashbackStatsMessage(job))
+
+	// The counters are NULL once the store doesn't return them for any request, e.g. the retried step runs with
+	// the older TiKV.
+	recorder.stats = nil
+	require.NoError(t, ddl.FlashbackToVersion(ctx, recorder, job, keyRanges[1:], 1))
+	require.Nil(t, job.ReorgMeta.FlashbackRanges[0].KeysProcessed)
+	require.Equal(t, "Keys processed: NULL  Versions written: NULL", ddl.FlashbackStatsMessage(job))
+	require.Equal(t, "Keys processed: NULL  Versions written: NULL", ddl.FlashbackStatsMessage(&model.Job{}))
+}
+
+func TestFlashbackClusterRestoreExternals(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	oldValue := map[string]interface{}{
+		"hot-region-schedule-limit": 1,
+	}
+	require.NoError(t, infosync.SetPDScheduleConfig(context.Background(), oldValue))
+	flashbackSQL := fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts))
+
+	for _, state := range []model.SchemaState{model.StateNone, model.StateWriteOnly, model.StateWriteReorganization} {
+		for _, gcEnabled := range []bool{true, false} {
+			tk.MustExec(fmt.Sprintf("set @@global.tidb_gc_enable = %v", gcEnabled))
+			require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackClusterErr", fmt.Sprintf(`return("%s")`, state)))
+			err := tk.ExecToErr(flashbackSQL)
+			require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockFlashbackClusterErr"))
+			require.ErrorContains(t, err, "mock flashback cluster error", "state %s", state)
+
+			// The GC enable status is restored to the one before the flashback.
+			tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows(fmt.Sprintf("%d", map[bool]int{true: 1, false: 0}[gcEnabled])))
+			value, err := infosync.GetPDScheduleConfig(context.Background())
+			require.NoError(t, err)
+			require.EqualValues(t, 1, value["hot-region-schedule-limit"], "state %s", state)
+		}
+	}
+
+	// The flashback cluster job ID is reset, so a new flashback can run.
+	tk.MustExec("set @@global.tidb_gc_enable = true")
+	tk.MustExec(flashbackSQL)
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+}
+
+func TestFlashbackClusterWithoutGC(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	getFlashbackSQL := func() (string, uint64) {
+		ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+		require.NoError(t, err)
+		return fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)), ts
+	}
+
+	// GC has never run, the GC safe point is missing and the flashback TS has no lower bound.
+	_, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	for _, gcEnabled := range []bool{true, false} {
+		tk.MustExec(fmt.Sprintf("set @@global.tidb_gc_enable = %v", gcEnabled))
+		flashbackSQL, ts := getFlashbackSQL()
+		tk.MustExec(fmt.Sprintf("set @@tidb_flashback_readiness_ts = %d", ts))
+		tk.MustQuery("select result, detail from information_schema.flashback_readiness where check_name = 'gc_safe_point'").
+			Check(testkit.Rows("PASS the GC safe point is unknown, GC has never run"))
+		tk.MustExec(flashbackSQL)
+		// GC isn't turned on if it's off before the flashback.
+		tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows(fmt.Sprintf("%d", map[bool]int{true: 1, false: 0}[gcEnabled])))
+	}
+
+	// The schema history is still checked.
+	flashbackSQL, _ := getFlashbackSQL()
+	tk.MustExec("create table t (a int)")
+	require.ErrorContains(t, tk.ExecToErr(flashbackSQL), "schema version not same, have done ddl during [flashbackTS, now)")
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("0"))
+
+	// GC is off with the safe point saved, e.g. by a backup holding the data, the safe point is still the lower bound.
+	tk.MustExec(fmt.Sprintf(safePointSQL, time.Now().Add(-time.Minute).Format("20060102-15:04:05 -0700 MST")))
+	tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(-time.Hour).Format("2006-01-02 15:04:05")), errno.ErrGCTooEarlyForRead)
+	flashbackSQL, _ = getFlashbackSQL()
+	tk.MustExec(flashbackSQL)
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("0"))
+}
+
+func TestFlashbackClusterRaceWithCreateTable(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+		require.NoError(t, err)
+		var wg sync.WaitGroup
+		stop := atomic.NewBool(false)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; !stop.Load(); j++ {
+				// The DDL may be cancelled by the flashback, ignore the error.
+				_ = tk2.ExecToErr(fmt.Sprintf("create table t%d_%d (a int)", i, j))
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		err = tk.ExecToErr(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")))
+		stop.Store(true)
+		wg.Wait()
+		if err != nil {
+			continue
+		}
+		// The flashback succeeds, so every table either exists before flashbackTS or is created after the flashback.
+		requireNoDDLDuringFlashback(t, store, model.ActionFlashbackCluster, ts, func(job *model.Job) bool {
+			return job.Type == model.ActionCreateTable
+		})
+	}
+	// The barrier is released.
+	tk.MustExec("create table t (a int)")
+}
+
+// requireNoDDLDuringFlashback checks that no synced DDL job matched by fn finishes during [ts, the end of the last
+// flashback job of the type).
+func requireNoDDLDuringFlashback(t *testing.T, store kv.Storage, tp model.ActionType, ts uint64, fn func(job *model.Job) bool) {
+	var jobs []*model.Job
+	err := kv.RunInNewTxn(kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL), store, false, func(ctx context.Context, txn kv.Transaction) (err error) {
+		jobs, err = ddl.GetAllHistoryDDLJobs(meta.NewMeta(txn))
+		return err
+	})
+	require.NoError(t, err)
+	var flashbackJob *model.Job
+	for _, job := range jobs {
+		if job.Type == tp && (flashbackJob == nil || job.ID > flashbackJob.ID) {
+			flashbackJob = job
+		}
+	}
+	require.NotNil(t, flashbackJob)
+	require.True(t, flashbackJob.IsSynced())
+	for _, job := range jobs {
+		if job.ID != flashbackJob.ID && job.IsSynced() && fn(job) {
+			finishedTS := job.BinlogInfo.FinishedTS
+			require.False(t, finishedTS >= ts && finishedTS <= flashbackJob.BinlogInfo.FinishedTS,
+				"job %d finishes during the flashback job %d", job.ID, flashbackJob.ID)
+		}
+	}
+}
+
+func TestCleanupOrphanFlashbackPDSchedule(t *testing.T) {
+	store, err := mockstore.NewMockStore()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+	dom, err := session.BootstrapSession(store)
+	require.NoError(t, err)
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	getRecord := func() (s *meta.FlashbackPDSchedule) {
+		err := kv.RunInNewTxn(ctx, store, true, func(ctx context.Context, txn kv.Transaction) (err error) {
+			s, err = meta.NewMeta(txn).GetFlashbackPDSchedule()
+			return err
+		})
+		require.NoError(t, err)
+		return s
+	}
+	// Simulate the flashback job which is lost after closing the PD schedule.
+	mockOrphan := func(jobID int64) {
+		err := kv.RunInNewTxn(ctx, store, true, func(ctx context.Context, txn kv.Transaction) error {
+			return meta.NewMeta(txn).SetFlashbackPDSchedule(&meta.FlashbackPDSchedule{
+				JobID:      jobID,
+				PDSchedule: map[string]interface{}{"hot-region-schedule-limit": 1},
+			})
+		})
+		require.NoError(t, err)
+		require.NoError(t, infosync.SetPDScheduleConfig(context.Background(), map[string]interface{}{"hot-region-schedule-limit": 0}))
+	}
+	requirePDSchedule := func(expected int) {
+		value, err := infosync.GetPDScheduleConfig(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, expected, value["hot-region-schedule-limit"])
+	}
+
+	// The orphaned record is cleaned up when the domain starts.
+	mockOrphan(10000)
+	dom.Close()
+	dom, err = session.BootstrapSession(store)
+	require.NoError(t, err)
+	defer dom.Close()
+	requirePDSchedule(1)
+	require.Nil(t, getRecord())
+
+	// The orphaned record is cleaned up by `ADMIN CLEANUP FLASHBACK`.
+	tk := testkit.NewTestKit(t, store)
+	mockOrphan(10001)
+	tk.MustExec("admin cleanup flashback")
+	tk.MustQuery("show warnings").Check(testkit.Rows("Note 1105 the PD schedule saved by the orphaned flashback job 10001 is restored"))
+	requirePDSchedule(1)
+	require.Nil(t, getRecord())
+	tk.MustExec("admin cleanup flashback")
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+
+	// The record of the running flashback job isn't cleaned up, and it's removed when the job finishes.
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tk2 := testkit.NewTestKit(t, store)
+	var records []*meta.FlashbackPDSchedule
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type == model.ActionFlashbackCluster && job.SchemaState == model.StateWriteReorganization {
+			tk2.MustExec("admin cleanup flashback")
+			tk2.MustQuery("show warnings").Check(testkit.Rows())
+			records = append(records, getRecord())
+		}
+	}
+	dom.DDL().SetHook(hook)
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	require.Len(t, records, 1)
+	require.NotNil(t, records[0])
+	require.EqualValues(t, 1, records[0].PDSchedule["hot-region-schedule-limit"])
+	require.Nil(t, getRecord())
+	requirePDSchedule(1)
+}
+
+func TestFailStaleFlashbackJob(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	originHook := dom.DDL().GetHook()
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tk.MustExec("set global tidb_gc_enable = on")
+	require.NoError(t, infosync.SetPDScheduleConfig(context.Background(), map[string]interface{}{"hot-region-schedule-limit": 1}))
+
+	// Simulate the job submitted by an older version, which has locked the cluster, saved the PD schedule and
+	// disabled the GC before the upgrade.
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	tk2 := testkit.NewTestKit(t, store)
+	jobIDCh := make(chan int64, 1)
+	var once sync.Once
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type != model.ActionFlashbackCluster {
+			return
+		}
+		once.Do(func() {
+			err := kv.RunInNewTxn(ctx, store, true, func(ctx context.Context, txn kv.Transaction) error {
+				m := meta.NewMeta(txn)
+				if err := m.SetFlashbackClusterJobID(job.ID); err != nil {
+					return err
+				}
+				return m.SetFlashbackPDSchedule(&meta.FlashbackPDSchedule{
+					JobID:      job.ID,
+					PDSchedule: map[string]interface{}{"hot-region-schedule-limit": 1},
+				})
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, infosync.SetPDScheduleConfig(context.Background(), map[string]interface{}{"hot-region-schedule-limit": 0}))
+			tk2.MustExec("set global tidb_gc_enable = off")
+			jobIDCh <- job.ID
+		})
+	}
+	dom.DDL().SetHook(hook)
+	defer dom.DDL().SetHook(originHook)
+
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockLegacyFlashbackClusterArgs", `return(true)`))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockLegacyFlashbackClusterArgs"))
+	}()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := tk.Exec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+		errCh <- err
+	}()
+	jobID := <-jobIDCh
+
+	// The job can't be finished by the workers, it's retried until it's failed.
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case err := <-errCh:
+		require.FailNow(t, "the stale flashback job is finished by the workers", "err: %v", err)
+	default:
+	}
+	failedIDs, err := ddl.FailStaleFlashbackJobs(dom.DDL())
+	require.NoError(t, err)
+	require.Equal(t, []int64{jobID}, failedIDs)
+	select {
+	case err := <-errCh:
+		require.ErrorContains(t, err, fmt.Sprintf("the args of the flashback cluster job %d can't be used by this version of TiDB", jobID))
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "the stale flashback job isn't finished after it's failed")
+	}
+
+	// The toggles recorded by the job are restored, and the cluster is released.
+	value, err := infosync.GetPDScheduleConfig(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, value["hot-region-schedule-limit"])
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+	err = kv.RunInNewTxn(ctx, store, true, func(ctx context.Context, txn kv.Transaction) error {
+		m := meta.NewMeta(txn)
+		flashbackJobID, err := m.GetFlashbackClusterJobID()
+		require.NoError(t, err)
+		require.Zero(t, flashbackJobID)
+		s, err := m.GetFlashbackPDSchedule()
+		require.NoError(t, err)
+		require.Nil(t, s)
+		return nil
+	})
+	require.NoError(t, err)
+	tk.MustExec("create table t (a int)")
+	failedIDs, err = ddl.FailStaleFlashbackJobs(dom.DDL())
+	require.NoError(t, err)
+	require.Empty(t, failedIDs)
+}
+
+func TestFlashbackClusterTimeout(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	originHook := dom.DDL().GetHook()
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	oldValue := map[string]interface{}{
+		"hot-region-schedule-limit": 1,
+	}
+	require.NoError(t, infosync.SetPDScheduleConfig(context.Background(), oldValue))
+	flashbackSQL := fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts))
+	tk.MustContainErrMsg(flashbackSQL+" timeout '10'", "invalid flashback cluster timeout '10'")
+	tk.MustContainErrMsg(flashbackSQL+" timeout '-1s'", "invalid flashback cluster timeout '-1s'")
+
+	// The phases before the last one only have a small share of the timeout.
+	for _, c := range []struct {
+		state    model.SchemaState
+		stall    time.Duration
+		errPhase string
+	}{
+		{model.StateWriteOnly, 400 * time.Millisecond, "phase write only after"},
+		{model.StateWriteReorganization, 1100 * time.Millisecond, "phase write reorganization after"},
+	} {
+		var stalled bool
+		hook := &ddl.TestDDLCallback{Do: dom}
+		hook.OnJobRunBeforeExported = func(job *model.Job) {
+			if job.Type == model.ActionFlashbackCluster && job.SchemaState == c.state && !stalled {
+				stalled = true
+				time.Sleep(c.stall)
+			}
+		}
+		dom.DDL().SetHook(hook)
+		err := tk.ExecToErr(flashbackSQL + " timeout '1s'")
+		dom.DDL().SetHook(originHook)
+		require.ErrorContains(t, err, "flashback cluster timed out in "+c.errPhase)
+		completed := map[model.SchemaState]int{model.StateWriteOnly: 1, model.StateWriteReorganization: 2}[c.state]
+		require.ErrorContains(t, err, fmt.Sprintf("(timeout: 1s), %d of 3 phases completed", completed))
+
+		// The job is cancelled, and the external toggles are restored.
+		tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+		value, err := infosync.GetPDScheduleConfig(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, 1, value["hot-region-schedule-limit"], "state %s", c.state)
+	}
+
+	// The job doesn't time out within the timeout, and the timeout is shown in the job args.
+	tk.MustExec(flashbackSQL + " timeout '10m'")
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job args %s", jobID)).Rows()
+	require.Equal(t, []interface{}{"timeout", "10m0s"}, rows[len(rows)-1][2:])
+}
+
+func TestFlashbackSchemaRollbackListener(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	originHook := dom.DDL().GetHook()
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tsStr := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	type notification struct {
+		oldVer, newVer int64
+		tso            uint64
+	}
+	var mu sync.Mutex
+	var notifications []notification
+	dom.RegisterSchemaRollbackListener(func(oldVer, newVer int64, tso uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		notifications = append(notifications, notification{oldVer, newVer, tso})
+	})
+	// The panics of the listeners don't affect the others.
+	dom.RegisterSchemaRollbackListener(func(int64, int64, uint64) {
+		panic("mock listener panic")
+	})
+
+	// The owner retires in the middle of the job, and the job is retried after the owner is elected again.
+	var retired bool
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type == model.ActionFlashbackTables && job.SchemaState == model.StateWriteOnly && !retired {
+			retired = true
+			dom.DDL().OwnerManager().RetireOwner()
+			go func() {
+				time.Sleep(200 * time.Millisecond)
+				require.NoError(t, dom.DDL().OwnerManager().CampaignOwner())
+			}()
+		}
+	}
+	dom.DDL().SetHook(hook)
+	oldVer := dom.InfoSchema().SchemaMetaVersion()
+	tk.MustExec(fmt.Sprintf("flashback table t to timestamp '%s'", tsStr))
+	dom.DDL().SetHook(originHook)
+	require.True(t, retired)
+
+	// The listeners are notified before the statement returns.
+	mu.Lock()
+	require.Equal(t, []notification{{oldVer, dom.InfoSchema().SchemaMetaVersion(), ts}}, notifications)
+	mu.Unlock()
+	tk.MustQuery("select old_schema_version, new_schema_version, flashback_ts from mysql.schema_rollback_log").Check(testkit.Rows(
+		fmt.Sprintf("%d %d %d", oldVer, notifications[0].newVer, ts)))
+
+	// The failed flashback doesn't notify the listeners.
+	tk.MustExec("alter table t add column b int")
+	require.Error(t, tk.ExecToErr(fmt.Sprintf("flashback cluster as of timestamp '%s'", tsStr)))
+	tk.MustQuery("select count(*) from mysql.schema_rollback_log").Check(testkit.Rows("1"))
+	mu.Lock()
+	require.Len(t, notifications, 1)
+	mu.Unlock()
+}
+
+func TestFlashbackClusterInvalidateCoprCache(t *testing.T) {
+	originConfig := config.GetGlobalConfig()
+	config.StoreGlobalConfig(config.NewConfig())
+	defer config.StoreGlobalConfig(originConfig)
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+	tk.MustExec("insert into t values (1), (2), (3)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	// TiKV always tells the cached items are valid. It's only enabled for the queries on t, or the DDL framework may
+	// read the stale jobs from the cache.
+	enableMockCopCache := func() {
+		require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/store/mockstore/unistore/cophandler/mockCopCacheInUnistore", `return(123)`))
+	}
+	disableMockCopCache := func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/store/mockstore/unistore/cophandler/mockCopCacheInUnistore"))
+	}
+	mustHitCache := func(hit bool) {
+		enableMockCopCache()
+		defer disableMockCopCache()
+		rows := tk.MustQuery("explain analyze select * from t").Rows()
+		expected := "copr_cache_hit_ratio: 0.00"
+		if hit {
+			expected = "copr_cache_hit_ratio: 1.00"
+		}
+		require.Contains(t, rows[0][5], expected)
+	}
+	mustHitCache(false)
+	mustHitCache(true)
+
+	// The items populated before the flashback aren't used after it.
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	mustHitCache(false)
+	mustHitCache(true)
+}
+
+func TestUndoFlashbackCluster(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+	createJobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	tk.MustExec("insert into t values (1), (2)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec("insert into t values (3)")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	defer tk.MustExec("set global tidb_super_read_only = 0")
+
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+	lastTS, err := ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	require.Equal(t, ts, lastTS)
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	id, err := strconv.ParseInt(jobID, 10, 64)
+	require.NoError(t, err)
+	job, err := ddl.GetHistoryJobByID(tk.Session(), id)
+	require.NoError(t, err)
+
+	// Only the finished flashback cluster jobs can be undone.
+	tk.MustGetErrCode("admin undo flashback 12345678", errno.ErrDDLJobNotFound)
+	tk.MustContainErrMsg("admin undo flashback "+createJobID, "isn't a flashback cluster job")
+	// The flashback can only be undone in the read-only window after it without FORCE.
+	tk.MustContainErrMsg("admin undo flashback "+jobID, "set tidb_super_read_only to ON before undoing it")
+
+	// The cluster is flashed back to the start TS of the job, i.e. the data written before the flashback.
+	tk.MustExec("set global tidb_super_read_only = 1")
+	tk.MustExec("admin undo flashback " + jobID)
+	lastTS, err = ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	require.Equal(t, job.StartTS, lastTS)
+	tk.MustQuery("select * from t").Check(testkit.Rows("1", "2", "3"))
+	// The flashback is undone by another flashback, it can't be undone again.
+	tk.MustContainErrMsg("admin undo flashback "+jobID, "other flashback cluster job has finished after")
+
+	// FORCE skips the read-only window check.
+	tk.MustExec("set global tidb_super_read_only = 0")
+	tk.MustExec("admin undo flashback " + jobID + " force")
+	lastTS, err = ddl.GetLastFlashbackTSO(tk.Session())
+	require.NoError(t, err)
+	require.Equal(t, job.StartTS, lastTS)
+
+	// The DDL done after the flashback closes the read-only window.
+	forceJobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	tk.MustExec("create table t2 (a int)")
+	tk.MustExec("set global tidb_super_read_only = 1")
+	tk.MustContainErrMsg("admin undo flashback "+forceJobID, "have done ddl after the flashback cluster job")
+}
+
+func TestFlashbackClusterData(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int, c varchar(10), index idx_b(b), unique index idx_c(c))")
+	tk.MustExec("create table tp (a int, b int, index idx_b(b)) partition by range (a) " +
+		"(partition p0 values less than (10), partition p1 values less than (20), partition p2 values less than maxvalue)")
+	tk.MustExec("insert into t values (1, 10, 'a'), (2, 20, 'b'), (3, 30, 'c')")
+	tk.MustExec("insert into tp values (1, 10), (11, 20), (21, 30)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	tk.MustExec("update t set b = b + 1, c = concat(c, 'x') where a = 1")
+	tk.MustExec("delete from t where a = 2")
+	tk.MustExec("insert into t values (4, 40, 'd')")
+	// The rows are moved across the partitions.
+	tk.MustExec("update tp set a = a + 10 where a = 1")
+	tk.MustExec("delete from tp where a = 21")
+	tk.MustExec("insert into tp values (31, 40)")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	mutatedTS, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+
+	tk.MustQuery("select * from t order by a").Check(testkit.Rows("1 10 a", "2 20 b", "3 30 c"))
+	tk.MustQuery("select b from t use index(idx_b) order by b").Check(testkit.Rows("10", "20", "30"))
+	tk.MustQuery("select c from t use index(idx_c) where c in ('a', 'ax', 'b', 'd')").Sort().Check(testkit.Rows("a", "b"))
+	tk.MustQuery("select * from tp order by a").Check(testkit.Rows("1 10", "11 20", "21 30"))
+	tk.MustQuery("select * from tp partition (p0)").Check(testkit.Rows("1 10"))
+	tk.MustQuery("select * from tp partition (p1)").Check(testkit.Rows("11 20"))
+	tk.MustQuery("select b from tp use index(idx_b) where b >= 10 order by b").Check(testkit.Rows("10", "20", "30"))
+	tk.MustExec("admin check table t")
+	tk.MustExec("admin check table tp")
+
+	// The data before the flashback is kept by the older versions.
+	tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", mutatedTS))
+	tk.MustQuery("select * from t order by a").Check(testkit.Rows("1 11 ax", "3 30 c", "4 40 d"))
+	tk.MustQuery("select * from tp order by a, b").Check(testkit.Rows("11 10", "11 20", "31 40"))
+	tk.MustExec("set @@tidb_snapshot = ''")
+
+	// The tables can still be written after the flashback.
+	tk.MustExec("insert into t values (4, 40, 'd')")
+	tk.MustExec("insert into tp values (31, 40)")
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("4"))
+	tk.MustQuery("select count(*) from tp").Check(testkit.Rows("4"))
+	tk.MustExec("admin check table t")
+	tk.MustExec("admin check table tp")
+}
+
+func TestFlashbackClusterCommitConflict(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("create table t2 (a int primary key)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec("update t set b = 10 where a = 1")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	// The transactions start before the flashback and commit after it, one writes the key rewound by the flashback,
+	// which meets a write conflict in TiKV, the other writes the key untouched by the flashback.
+	tk1 := testkit.NewTestKit(t, store)
+	tk1.MustExec("use test")
+	tk1.MustExec("begin optimistic")
+	tk1.MustExec("update t set b = 100 where a = 1")
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	tk2.MustExec("begin optimistic")
+	tk2.MustExec("insert into t2 values (1)")
+	tk3 := testkit.NewTestKit(t, store)
+	tk3.MustExec("use test")
+	tk3.MustExec("begin")
+	tk3.MustQuery("select * from t where a = 2").Check(testkit.Rows("2 2"))
+
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+
+	err = tk1.ExecToErr("commit")
+	require.True(t, kv.ErrFlashbackCommitConflict.Equal(err), "%v", err)
+	require.Contains(t, err.Error(), fmt.Sprintf("before the flashback job %s finished", jobID))
+	require.Contains(t, err.Error(), fmt.Sprintf("on top of the data flashed back to %d", ts))
+	err = tk2.ExecToErr("commit")
+	require.True(t, kv.ErrFlashbackCommitConflict.Equal(err), "%v", err)
+	// The read-only transaction isn't affected.
+	tk3.MustExec("commit")
+	tk.MustQuery("select * from t order by a").Check(testkit.Rows("1 1", "2 2"))
+	tk.MustQuery("select * from t2").Check(testkit.Rows())
+
+	// The transactions starting after the flashback commit as usual.
+	tk1.MustExec("begin optimistic")
+	tk1.MustExec("update t set b = 100 where a = 1")
+	tk1.MustExec("commit")
+	tk2.MustExec("insert into t2 values (1)")
+	tk.MustQuery("select * from t order by a").Check(testkit.Rows("1 100", "2 2"))
+	tk.MustQuery("select * from t2").Check(testkit.Rows("1"))
+}
+
+func TestFlashbackClusterRejectStatsSnapshotRead(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+	tk.MustExec("insert into t values (1), (2)")
+	tk.MustExec("analyze table t")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	snapshotTS, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/pauseAfterFlashbackRange", "pause"))
+	done := make(chan error, 1)
+	go func() {
+		tk1 := testkit.NewTestKit(t, store)
+		done <- tk1.ExecToErr(fmt.Sprintf("flashback cluster to tso %d", ts))
+	}()
+
+	// The snapshot reads of the stats tables are rejected while they're being rewritten.
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", snapshotTS))
+	require.Eventually(t, func() bool {
+		err := tk2.ExecToErr("select count(*) from mysql.stats_meta")
+		return err != nil && strings.Contains(err.Error(), "is rewriting it")
+	}, 10*time.Second, 50*time.Millisecond)
+	tk2.MustGetErrCode("select count(*) from mysql.stats_histograms", errno.ErrSnapshotReadFlashbackRewriting)
+	// The other tables aren't affected.
+	tk2.MustQuery("select * from test.t order by a").Check(testkit.Rows("1", "2"))
+
+	require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/pauseAfterFlashbackRange"))
+	require.NoError(t, <-done)
+	tk2.MustQuery("select count(*) > 0 from mysql.stats_meta").Check(testkit.Rows("1"))
+}
+
+func TestFlashbackClusterTSTooRecent(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	originLag := ddl.FlashbackClusterMinLag
+	ddl.FlashbackClusterMinLag = time.Second
+	defer func() {
+		ddl.FlashbackClusterMinLag = originLag
+	}()
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	// The timestamps not earlier than a second ago are rejected before the job is submitted.
+	now := time.Now()
+	for _, flashbackTime := range []time.Time{now, now.Add(time.Second)} {
+		tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", flashbackTime.Format("2006-01-02 15:04:05.000")),
+			errno.ErrFlashbackTSTooRecent)
+	}
+	tk.MustGetErrCode(fmt.Sprintf("flashback cluster to tso %d", ts), errno.ErrFlashbackTSTooRecent)
+	tk.MustQuery("admin show ddl jobs where job_type = 'flashback cluster'").Check(testkit.Rows())
+
+	// The timestamp a second ago can be flashed back.
+	time.Sleep(time.Second)
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+	require.Len(t, tk.MustQuery("admin show ddl jobs where job_type = 'flashback cluster'").Rows(), 1)
+}
+
+func TestFlashbackClusterStateDurations(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key)")
+	tk.MustExec("insert into t values (1)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec("insert into t values (2)")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+
+	type stateDuration struct {
+		State       string  `json:"state"`
+		SchemaState string  `json:"schema_state"`
+		Duration    float64 `json:"duration"`
+	}
+	rows := tk.MustQuery("admin show ddl jobs 1").Rows()
+	require.Equal(t, "flashback cluster", rows[0][3])
+	var durations []stateDuration
+	require.NoError(t, json.Unmarshal([]byte(rows[0][14].(string)), &durations))
+	schemaStates := make([]string, 0, len(durations))
+	for _, d := range durations {
+		require.GreaterOrEqual(t, d.Duration, float64(0))
+		schemaStates = append(schemaStates, d.SchemaState)
+	}
+	require.Contains(t, schemaStates, model.StateWriteOnly.String())
+	require.Contains(t, schemaStates, model.StateWriteReorganization.String())
+	tk.MustQuery("select state_durations from information_schema.ddl_jobs where job_id = ?", rows[0][0]).
+		Check(testkit.Rows(rows[0][14].(string)))
+
+	// The durations are recorded for the other jobs too.
+	tk.MustExec("create table t1 (a int)")
+	rows = tk.MustQuery("admin show ddl jobs 1").Rows()
+	require.Equal(t, "create table", rows[0][3])
+	require.NoError(t, json.Unmarshal([]byte(rows[0][14].(string)), &durations))
+	require.NotEmpty(t, durations)
+}
+
+func TestFlashbackClusterCollationMismatch(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a varchar(10), b int, key(a)) collate utf8mb4_bin")
+	tk.MustExec("create table t2 (a varchar(10) primary key clustered) collate utf8mb4_bin")
+	tk.MustExec("create table t3 (a int, b varchar(10), key(a)) collate utf8mb4_bin")
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	db, ok := dom.InfoSchema().SchemaByName(model.NewCIStr("test"))
+	require.True(t, ok)
+	var originTbls, changedTbls []*model.TableInfo
+	for _, name := range []string{"t1", "t2", "t3"} {
+		tbl, err := dom.InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr(name))
+		require.NoError(t, err)
+		originTbls = append(originTbls, tbl.Meta().Clone())
+		changed := tbl.Meta().Clone()
+		for _, col := range changed.Columns {
+			if col.GetType() == mysql.TypeVarchar {
+				col.SetCollate("utf8mb4_general_ci")
+			}
+		}
+		changedTbls = append(changedTbls, changed)
+	}
+	updateTables := func(tbls []*model.TableInfo) {
+		ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+		require.NoError(t, kv.RunInNewTxn(ctx, store, true, func(ctx context.Context, txn kv.Transaction) error {
+			m := meta.NewMeta(txn)
+			for _, tbl := range tbls {
+				if err := m.UpdateTable(db.ID, tbl); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+
+	// The collations of the columns are changed in the meta without a schema change, the flashback is refused for
+	// the tables whose index keys are encoded in the changed collations.
+	updateTables(changedTbls)
+	err = tk.ExecToErr(fmt.Sprintf("flashback cluster to tso %d", ts))
+	require.True(t, dbterror.ErrFlashbackCollationMismatch.Equal(err), "%v", err)
+	require.ErrorContains(t, err, "tables test.t1, test.t2 are")
+
+	// The flashback goes on once the collations are restored.
+	updateTables(originTbls)
+	tk.MustExec(fmt.Sprintf("flashback cluster to tso %d", ts))
+}
@@ -82,8 +82,9 @@ const (
 
 	batchAddingJobs = 10
 
-	reorgWorkerCnt   = 10
-	generalWorkerCnt = 1
+	reorgWorkerCnt    = 10
+	generalWorkerCnt  = 1
+	metadataWorkerCnt = 1
 )
 
 // OnExist specifies what to do when a new object has a name collision.
@@ -135,7 +136,8 @@ type DDL interface {
 	CreatePlacementPolicy(ctx sessionctx.Context, stmt *ast.CreatePlacementPolicyStmt) error
 	DropPlacementPolicy(ctx sessionctx.Context, stmt *ast.DropPlacementPolicyStmt) error
 	AlterPlacementPolicy(ctx sessionctx.Context, stmt *ast.AlterPlacementPolicyStmt) error
-	FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64) error
+	FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64, force, killConflictingQueries bool, timeout time.Duration) error
+	FlashbackTables(ctx sessionctx.Context, tables []ast.Ident, flashbackTS uint64, force bool) error
 
 	// CreateSchemaWithInfo creates a database (schema) given its database info.
 	//
@@ -193,6 +195,8 @@ type DDL interface {
 	SetBinlogClient(*pumpcli.PumpsClient)
 	// GetHook gets the hook. It's exported for testing.
 	GetHook() Callback
+	// SessionPoolHolders returns the holders of the sessions of the DDL session pool which aren't put back.
+	SessionPoolHolders() []SessionPoolHolder
 	// SetHook sets the hook.
 	SetHook(h Callback)
 	// GetInfoSchemaWithInterceptor gets the infoschema binding to d. It's exported for testing.
@@ -222,8 +226,9 @@ type ddl struct {
 	delRangeMgr       delRangeManager
 	enableTiFlashPoll *atomicutil.Bool
 	// used in the concurrency ddl.
-	reorgWorkerPool      *workerPool
-	generalDDLWorkerPool *workerPool
+	reorgWorkerPool       *workerPool
+	generalDDLWorkerPool  *workerPool
+	metadataDDLWorkerPool *workerPool
 	// get notification if any DDL coming.
 	ddlJobCh chan struct{}
 }
@@ -279,6 +284,9 @@ type ddlCtx struct {
 	statsHandle  *handle.Handle
 	tableLockCkr util.DeadTableLockChecker
 	etcdCli      *clientv3.Client
+	// sysProcTracker and serverIDGetter are used to track the internal sessions of jobs as system processes.
+	sysProcTracker sessionctx.SysProcTracker
+	serverIDGetter func() uint64
 
 	*waitSchemaSyncedController
 	*schemaVersionManager
@@ -551,6 +559,8 @@ func newDDL(ctx context.Context, options ...Option) *ddl {
 		schemaVersionManager:       newSchemaVersionManager(),
 		waitSchemaSyncedController: newWaitSchemaSyncedController(),
 		runningJobIDs:              make([]string, 0, jobRecordCapacity),
+		sysProcTracker:             opt.SysProcTracker,
+		serverIDGetter:             opt.ServerIDGetter,
 	}
 	ddlCtx.reorgCtx.reorgCtxMap = make(map[int64]*reorgCtx)
 	ddlCtx.jobCtx.jobCtxMap = make(map[int64]*JobContext)
@@ -603,7 +613,7 @@ func (d *ddl) prepareWorkers4ConcurrencyDDL() {
 	workerFactory := func(tp workerType) func() (pools.Resource, error) {
 		return func() (pools.Resource, error) {
 			wk := newWorker(d.ctx, tp, d.sessPool, d.delRangeMgr, d.ddlCtx, true)
-			sessForJob, err := d.sessPool.get()
+			sessForJob, err := d.sessPool.getWithOwner(sessionOwner{purpose: wk.String(), resident: true})
 			if err != nil {
 				return nil, err
 			}
@@ -617,6 +627,7 @@ func (d *ddl) prepareWorkers4ConcurrencyDDL() {
 	reorgCnt := mathutil.Min(mathutil.Max(runtime.GOMAXPROCS(0)/4, 1), reorgWorkerCnt)
 	d.reorgWorkerPool = newDDLWorkerPool(pools.NewResourcePool(workerFactory(addIdxWorker), reorgCnt, reorgCnt, 0), reorg)
 	d.generalDDLWorkerPool = newDDLWorkerPool(pools.NewResourcePool(workerFactory(generalWorker), generalWorkerCnt, generalWorkerCnt, 0), general)
+	d.metadataDDLWorkerPool = newDDLWorkerPool(pools.NewResourcePool(workerFactory(generalWorker), metadataWorkerCnt, metadataWorkerCnt, 0), metadata)
 	failpoint.Inject("NoDDLDispatchLoop", func(val failpoint.Value) {
 		if val.(bool) {
 			failpoint.Return()
@@ -654,6 +665,12 @@ func (d *ddl) Start(ctxPool *pools.ResourcePool) error {
 		if err != nil {
 			logutil.BgLogger().Error("error when getting the ddl history count", zap.Error(err))
 		}
+		// The flashback jobs submitted by the older versions may block all the DDL jobs after the upgrade.
+		d.wg.Run(func() {
+			if _, err := d.failStaleFlashbackJobs(); err != nil {
+				logutil.BgLogger().Warn("[ddl] fail the stale flashback jobs failed", zap.Error(err))
+			}
+		})
 	})
 
 	d.delRangeMgr = d.newDeleteRangeManager(ctxPool == nil)
@@ -665,6 +682,7 @@ func (d *ddl) Start(ctxPool *pools.ResourcePool) error {
 		d.wg.Add(1)
 		go d.startCleanDeadTableLock()
 	}
+	d.wg.Run(d.startSessionLeakDetector)
 
 	// If tidb_enable_ddl is true, we need campaign owner and do DDL job.
 	// Otherwise, we needn't do that.
@@ -742,6 +760,9 @@ func (d *ddl) close() {
 	if d.generalDDLWorkerPool != nil {
 		d.generalDDLWorkerPool.close()
 	}
+	if d.metadataDDLWorkerPool != nil {
+		d.metadataDDLWorkerPool.close()
+	}
 
 	for _, worker := range d.workers {
 		worker.Close()
@@ -1009,6 +1030,13 @@ func (d *ddl) DoDDLJob(ctx sessionctx.Context, job *model.Job) error {
 					logutil.BgLogger().Warn("Kill command could not cancel DDL job", zap.Error(err))
 					continue
 				}
+				// The flashback job can't be cancelled after it starts to flashback the data, don't keep the killed
+				// statement waiting for it.
+				isFlashback := job.Type == model.ActionFlashbackCluster || job.Type == model.ActionFlashbackTables
+				if isFlashback && len(errs) > 0 && dbterror.ErrCannotCancelDDLJob.Equal(errs[0]) {
+					logutil.BgLogger().Info("[ddl] detach the killed statement from the flashback job", zap.Int64("jobID", jobID))
+					return &FlashbackJobDetachedError{JobID: jobID}
+				}
 			}
 		}
 
@@ -1045,6 +1073,7 @@ func (d *ddl) DoDDLJob(ctx sessionctx.Context, job *model.Job) error {
 				}
 			}
 			appendMultiChangeWarningsToOwnerCtx(ctx, historyJob)
+			appendFlashbackTablesNotesToOwnerCtx(ctx, historyJob)
 
 			logutil.BgLogger().Info("[ddl] DDL job is finished", zap.Int64("jobID", jobID))
 			return nil
@@ -1070,6 +1099,14 @@ func (d *ddl) callHookOnChanged(job *model.Job, err error) error {
 	return errors.Trace(err)
 }
 
+func (dc *ddlCtx) callHookOnJobFinished(job *model.Job) {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+	if hook, ok := dc.mu.hook.(JobFinishedCallback); ok {
+		hook.OnJobFinished(job)
+	}
+}
+
 // SetBinlogClient implements DDL.SetBinlogClient interface.
 func (d *ddl) SetBinlogClient(binlogCli *pumpcli.PumpsClient) {
 	d.binlogCli = binlogCli
@@ -1083,6 +1120,11 @@ func (d *ddl) GetHook() Callback {
 	return d.mu.hook
 }
 
+// SessionPoolHolders implements DDL.SessionPoolHolders interface.
+func (d *ddl) SessionPoolHolders() []SessionPoolHolder {
+	return d.sessPool.holders()
+}
+
 // SetHook set the customized hook.
 func (d *ddl) SetHook(h Callback) {
 	d.mu.Lock()
@@ -1091,6 +1133,30 @@ func (d *ddl) SetHook(h Callback) {
 	d.mu.hook = h
 }
 
+// sessionLeakCheckInterval is the interval of checking the leaked sessions of the DDL session pool.
+var sessionLeakCheckInterval = time.Minute
+
+// startSessionLeakDetector checks the sessions held longer than tidb_ddl_session_leak_threshold periodically, and
+// recycles them if tidb_ddl_recycle_leaked_session is on.
+func (d *ddl) startSessionLeakDetector() {
+	defer tidbutil.Recover(metrics.LabelDDL, "startSessionLeakDetector", nil, false)
+
+	ticker := time.NewTicker(sessionLeakCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			threshold := variable.DDLSessionLeakThreshold.Load()
+			if threshold <= 0 {
+				continue
+			}
+			d.sessPool.checkLeaks(time.Duration(threshold)*time.Second, variable.DDLRecycleLeakedSession.Load())
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
 func (d *ddl) startCleanDeadTableLock() {
 	defer func() {
 		tidbutil.Recover(metrics.LabelDDL, "startCleanDeadTableLock", nil, false)
@@ -1327,7 +1393,7 @@ func get2JobsFromQueue(t *meta.Meta) (*model.Job, *model.Job, error) {
 
 func get2JobsFromTable(sess *session) (*model.Job, *model.Job, error) {
 	var generalJob, reorgJob *model.Job
-	jobs, err := getJobsBySQL(sess, JobTable, "not reorg order by job_id limit 1")
+	jobs, err := getJobsBySQL(context.Background(), sess, JobTable, "not reorg order by job_id limit 1")
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -1335,7 +1401,7 @@ func get2JobsFromTable(sess *session) (*model.Job, *model.Job, error) {
 	if len(jobs) != 0 {
 		generalJob = jobs[0]
 	}
-	jobs, err = getJobsBySQL(sess, JobTable, "reorg order by job_id limit 1")
+	jobs, err = getJobsBySQL(context.Background(), sess, JobTable, "reorg order by job_id limit 1")
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -1448,13 +1514,14 @@ func cancelConcurrencyJobs(se sessionctx.Context, ids []int64) ([]error, error)
 		idsStr = append(idsStr, strconv.FormatInt(id, 10))
 	}
 
-	jobs, err := getJobsBySQL(sess, JobTable, fmt.Sprintf("job_id in (%s) order by job_id", strings.Join(idsStr, ", ")))
+	jobs, err := getJobsBySQL(context.Background(), sess, JobTable, fmt.Sprintf("job_id in (%s) order by job_id", strings.Join(idsStr, ", ")))
 	if err != nil {
 		sess.rollback()
 		return nil, err
 	}
 
 	errs := make([]error, len(ids))
+	cancelledIDs := make([]int64, 0, len(ids))
 
 	for _, job := range jobs {
 		i, ok := jobMap[job.ID]
@@ -1488,12 +1555,15 @@ func cancelConcurrencyJobs(se sessionctx.Context, ids []int64) ([]error, error)
 		err = updateDDLJob2Table(sess, job, true)
 		if err != nil {
 			errs[i] = errors.Trace(err)
+			continue
 		}
+		cancelledIDs = append(cancelledIDs, job.ID)
 	}
 	err = sess.commit()
 	if err != nil {
 		return nil, err
 	}
+	interruptRunningJobs(se.GetStore(), cancelledIDs)
 	for id, idx := range jobMap {
 		errs[idx] = dbterror.ErrDDLJobNotFound.GenWithStackByArgs(id)
 	}
@@ -1517,8 +1587,12 @@ func getDDLJobsInQueue(t *meta.Meta, jobListKey meta.JobListKeyType) ([]*model.J
 
 // GetAllDDLJobs get all DDL jobs and sorts jobs by job.ID.
 func GetAllDDLJobs(sess sessionctx.Context, t *meta.Meta) ([]*model.Job, error) {
+	return getAllDDLJobs(context.Background(), sess, t)
+}
+
+func getAllDDLJobs(ctx context.Context, sess sessionctx.Context, t *meta.Meta) ([]*model.Job, error) {
 	if variable.EnableConcurrentDDL.Load() {
-		return getJobsBySQL(newSession(sess), JobTable, "1 order by job_id")
+		return getJobsBySQL(ctx, newSession(sess), JobTable, "1 order by job_id")
 	}
 
 	return getDDLJobs(t)
@@ -1654,6 +1728,10 @@ func (s *session) execute(ctx context.Context, query string, label string) ([]ch
 	if rows, err = sqlexec.DrainRecordSet(ctx, rs, 8); err != nil {
 		return nil, errors.Trace(err)
 	}
+	// The rows may be incomplete if the query is interrupted because the job is cancelled.
+	if err = ctx.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
 	return rows, nil
 }
 
@@ -37,6 +37,7 @@ import (
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/charset"
@@ -2594,15 +2595,93 @@ func (d *ddl) preSplitAndScatter(ctx sessionctx.Context, tbInfo *model.TableInfo
 	}
 }
 
-func (d *ddl) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64) error {
-	logutil.BgLogger().Info("[ddl] get flashback cluster job", zap.String("flashbackTS", oracle.GetTimeFromTS(flashbackTS).String()))
+func (d *ddl) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64, force, killConflictingQueries bool, timeout time.Duration) error {
+	if err := checkFlashbackClusterTSNotRecent(context.Background(), ctx, flashbackTS); err != nil {
+		return err
+	}
+	scatterRegions := ctx.GetSessionVars().FlashbackScatterRegions
+	logutil.BgLogger().Info("[ddl] get flashback cluster job", zap.String("flashbackTS", oracle.GetTimeFromTS(flashbackTS).String()),
+		zap.Bool("force", force), zap.Bool("killConflictingQueries", killConflictingQueries), zap.Duration("timeout", timeout),
+		zap.Bool("scatterRegions", scatterRegions))
 	job := &model.Job{
 		Type:       model.ActionFlashbackCluster,
 		BinlogInfo: &model.HistoryInfo{},
-		Args:       []interface{}{flashbackTS, map[string]interface{}{}},
+		Args: []interface{}{flashbackTS, map[string]interface{}{}, uint64(0),
+			ctx.GetSessionVars().FlashbackBatchSize, force, timeout, killConflictingQueries, scatterRegions},
 	}
+	failpoint.Inject("mockLegacyFlashbackClusterArgs", func(val failpoint.Value) {
+		if val.(bool) {
+			// The older versions record whether the GC is enabled as the third arg.
+			job.Args = []interface{}{flashbackTS, map[string]interface{}{}, true}
+		}
+	})
 	err := d.DoDDLJob(ctx, job)
 	err = d.callHookOnChanged(job, err)
+	if err == nil {
+		d.setFlashbackStatsMessage(ctx, job.ID)
+	}
+	return errors.Trace(err)
+}
+
+// FlashbackTables flashes back the tables to flashbackTS in one job. The tables are validated before submitting the
+// job, so the statement fails as a whole if any of them can't be flashed back.
+func (d *ddl) FlashbackTables(ctx sessionctx.Context, tables []ast.Ident, flashbackTS uint64, force bool) error {
+	is := d.GetInfoSchemaWithInterceptor(ctx)
+	flashbackTables := make([]*FlashbackTable, 0, len(tables))
+	schemaIDs := make([]int64, 0, len(tables))
+	tableIDs := make([]int64, 0, len(tables))
+	seen := make(map[int64]struct{}, len(tables))
+	for _, ident := range tables {
+		schema, ok := is.SchemaByName(ident.Schema)
+		if !ok {
+			return infoschema.ErrDatabaseNotExists.GenWithStackByArgs(ident.Schema)
+		}
+		tbl, err := is.TableByName(ident.Schema, ident.Name)
+		if err != nil {
+			return infoschema.ErrTableNotExists.GenWithStackByArgs(ident.Schema, ident.Name)
+		}
+		tblInfo := tbl.Meta()
+		if _, ok := seen[tblInfo.ID]; ok {
+			return infoschema.ErrNonuniqTable.GenWithStackByArgs(ident.Name)
+		}
+		seen[tblInfo.ID] = struct{}{}
+		if err = checkFlashbackTable(tblInfo); err != nil {
+			return err
+		}
+		flashbackTables = append(flashbackTables, &FlashbackTable{
+			SchemaID:   schema.ID,
+			TableID:    tblInfo.ID,
+			SchemaName: schema.Name.O,
+			TableName:  tblInfo.Name.O,
+		})
+		schemaIDs = append(schemaIDs, schema.ID)
+		tableIDs = append(tableIDs, tblInfo.ID)
+	}
+	if err := ValidateFlashbackTS(context.Background(), ctx, flashbackTS); err != nil {
+		return err
+	}
+	err := kv.RunInNewTxn(kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL), d.store, false,
+		func(ctx context.Context, txn kv.Transaction) error {
+			return checkFlashbackTablesUnchanged(d.store, meta.NewMeta(txn), flashbackTables, flashbackTS)
+		})
+	if err != nil {
+		return err
+	}
+
+	logutil.BgLogger().Info("[ddl] get flashback tables job", zap.Int("tables", len(flashbackTables)),
+		zap.String("flashbackTS", oracle.GetTimeFromTS(flashbackTS).String()), zap.Bool("force", force))
+	job := &model.Job{
+		SchemaID:   schemaIDs[0],
+		TableID:    tableIDs[0],
+		SchemaName: flashbackTables[0].SchemaName,
+		TableName:  flashbackTables[0].TableName,
+		Type:       model.ActionFlashbackTables,
+		BinlogInfo: &model.HistoryInfo{},
+		Args:       []interface{}{flashbackTS, map[string]interface{}{}, uint64(0), flashbackTables, force},
+		CtxVars:    []interface{}{schemaIDs, tableIDs},
+	}
+	err = d.DoDDLJob(ctx, job)
+	err = d.callHookOnChanged(job, err)
 	return errors.Trace(err)
 }
 
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	ddlbackoff "github.com/pingcap/tidb/ddl/backoff"
 	"github.com/pingcap/tidb/ddl/util"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
@@ -58,6 +59,14 @@ var (
 	mockDDLErrOnce = int64(0)
 )
 
+const (
+	// rangeOpMaxAttempts is the max attempts of a range operation of a DDL job, such as getting the flashback ranges
+	// and deleting the ranges in the delete-range emulator.
+	rangeOpMaxAttempts = 10
+	rangeOpBaseBackoff = 100 * time.Millisecond
+	rangeOpMaxBackoff  = 5 * time.Second
+)
+
 // GetWaitTimeWhenErrorOccurred return waiting interval when processing DDL jobs encounter errors.
 func GetWaitTimeWhenErrorOccurred() time.Duration {
 	return time.Duration(atomic.LoadInt64(&WaitTimeWhenErrorOccurred))
@@ -104,6 +113,8 @@ type worker struct {
 
 // JobContext is the ddl job execution context.
 type JobContext struct {
+	// ctx is used by the internal SQL of the job, it's done when the job is cancelled or the DDL is closed.
+	ctx context.Context
 	// below fields are cache for top sql
 	ddlJobCtx          context.Context
 	cacheSQL           string
@@ -115,6 +126,7 @@ type JobContext struct {
 // NewJobContext returns a new ddl job context.
 func NewJobContext() *JobContext {
 	return &JobContext{
+		ctx:                context.Background(),
 		ddlJobCtx:          context.Background(),
 		cacheSQL:           "",
 		cacheNormalizedSQL: "",
@@ -556,12 +568,15 @@ func (w *worker) finishDDLJob(t *meta.Meta, job *model.Job) (err error) {
 	case model.ActionRecoverTable:
 		err = finishRecoverTable(w, job)
 	case model.ActionFlashbackCluster:
-		err = finishFlashbackCluster(w, job)
+		err = finishFlashbackCluster(w, t, job)
+	case model.ActionFlashbackTables:
+		err = finishFlashbackTables(w, t, job)
 	case model.ActionCreateTables:
 		if job.IsCancelled() {
 			// it may be too large that it can not be added to the history queue, too
 			// delete its arguments
 			job.Args = nil
+			job.RawArgs = nil
 		}
 	}
 	if err != nil {
@@ -584,8 +599,16 @@ func (w *worker) finishDDLJob(t *meta.Meta, job *model.Job) (err error) {
 		// Notice: warnings is used to support non-strict mode.
 		updateRawArgs = false
 	}
+	if job.Args == nil {
+		// The args of most jobs aren't decoded when finishing them, keep the raw args in the history so that they
+		// can be shown by `ADMIN SHOW DDL JOB ARGS`.
+		updateRawArgs = false
+	}
+	// Record the final state, so the time spent in the last state before it is known.
+	job.RecordStateTransition(time.Now())
 	w.writeDDLSeqNum(job)
 	w.removeJobCtx(job)
+	w.callHookOnJobFinished(job)
 	err = AddHistoryDDLJob(w.sess, t, job, updateRawArgs, w.concurrentDDL)
 	return errors.Trace(err)
 }
@@ -1042,6 +1065,28 @@ func (w *worker) waitDependencyJobFinished(job *model.Job, cnt *int) {
 	}
 }
 
+// newRangeOpPolicy creates the retry policy for the range operations of the job. The retries consumed by the
+// previous steps of the job are restored from its reorg meta.
+func newRangeOpPolicy(job *model.Job) *ddlbackoff.Policy {
+	p := ddlbackoff.NewPolicy(rangeOpMaxAttempts, rangeOpBaseBackoff, rangeOpMaxBackoff, nil)
+	if job != nil && job.ReorgMeta != nil {
+		p.AddRetries(job.ReorgMeta.RetryCount)
+	}
+	return p
+}
+
+// recordRangeOpRetries records the retries consumed by the policy into the reorg meta of the job,
+// so that they're shown by `ADMIN SHOW DDL JOBS`.
+func recordRangeOpRetries(job *model.Job, p *ddlbackoff.Policy) {
+	if p.Retries() == 0 {
+		return
+	}
+	if job.ReorgMeta == nil {
+		job.ReorgMeta = &model.DDLReorgMeta{}
+	}
+	job.ReorgMeta.RetryCount = p.Retries()
+}
+
 func chooseLeaseTime(t, max time.Duration) time.Duration {
 	if t == 0 || t > max {
 		return max
@@ -1080,10 +1125,10 @@ func (w *worker) countForError(err error, job *model.Job) error {
 
 	// If job is cancelled, we shouldn't return an error and shouldn't load DDL variables.
 	if job.State == model.JobStateCancelled {
-		logutil.Logger(w.logCtx).Info("[ddl] DDL job is cancelled normally", zap.Error(err))
+		w.jobLogger(job).Info("DDL job is cancelled normally", zap.Error(err))
 		return nil
 	}
-	logutil.Logger(w.logCtx).Error("[ddl] run DDL job error", zap.Error(err))
+	w.jobLogger(job).Error("run DDL job error", zap.Error(err))
 
 	// Load global DDL variables.
 	if err1 := loadDDLVars(w); err1 != nil {
@@ -1091,7 +1136,7 @@ func (w *worker) countForError(err error, job *model.Job) error {
 	}
 	// Check error limit to avoid falling into an infinite loop.
 	if job.ErrorCount > variable.GetDDLErrorCountLimit() && job.State == model.JobStateRunning && job.IsRollbackable() {
-		logutil.Logger(w.logCtx).Warn("[ddl] DDL job error count exceed the limit, cancelling it now", zap.Int64("jobID", job.ID), zap.Int64("errorCountLimit", variable.GetDDLErrorCountLimit()))
+		w.jobLogger(job).Warn("DDL job error count exceed the limit, cancelling it now", zap.Int64("errorCountLimit", variable.GetDDLErrorCountLimit()))
 		job.State = model.JobStateCancelling
 	}
 	return err
@@ -1111,6 +1156,9 @@ func (w *worker) runDDLJob(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64,
 		logutil.Logger(w.logCtx).Info("[ddl] run DDL job", zap.String("job", job.String()))
 	}
 
+	finish := w.startInterruptibleJob(w.jobContext(job), job)
+	defer finish()
+
 	// Should check flashbackClusterJobID.
 	// Some ddl jobs maybe added between check and insert into ddl job table.
 	flashbackJobID, err := t.GetFlashbackClusterJobID()
@@ -1120,7 +1168,12 @@ func (w *worker) runDDLJob(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64,
 	}
 	if flashbackJobID != 0 && flashbackJobID != job.ID {
 		job.State = model.JobStateCancelled
-		return ver, errors.Errorf("Can't do ddl job, cluster is flashing back now")
+		// Record the error in the job, or the cancelled job is reported without the cause.
+		return ver, w.countForError(errors.Errorf("Can't do ddl job, cluster is flashing back now"), job)
+	}
+	if err = checkFlashbackTablesBarrier(t, job); err != nil {
+		job.State = model.JobStateCancelled
+		return ver, w.countForError(err, job)
 	}
 
 	timeStart := time.Now()
@@ -1130,6 +1183,15 @@ func (w *worker) runDDLJob(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64,
 	defer func() {
 		metrics.DDLWorkerHistogram.WithLabelValues(metrics.WorkerRunDDLJob, job.Type.String(), metrics.RetLabel(err)).Observe(time.Since(timeStart).Seconds())
 	}()
+	state, schemaState := job.State, job.SchemaState
+	defer func() {
+		if job.State != state || job.SchemaState != schemaState {
+			job.RecordStateTransition(time.Now())
+			w.jobLogger(job).Info("DDL job state changed",
+				zap.Stringer("fromState", state), zap.Stringer("toState", job.State),
+				zap.Stringer("fromSchemaState", schemaState), zap.Stringer("toSchemaState", job.SchemaState))
+		}
+	}()
 	if job.IsFinished() {
 		logutil.Logger(w.logCtx).Debug("[ddl] finish DDL job", zap.String("job", job.String()))
 		return
@@ -1143,6 +1205,10 @@ func (w *worker) runDDLJob(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64,
 	if !job.IsRollingback() && !job.IsCancelling() {
 		job.State = model.JobStateRunning
 	}
+	if len(job.StateTransitions) == 0 {
+		// The job starts running, its following states are recorded when they're changed.
+		job.RecordStateTransition(timeStart)
+	}
 
 	// For every type, `schema/table` modification and `job` modification are conducted
 	// in the one kv transaction. The `schema/table` modification can be always discarded
@@ -1250,6 +1316,8 @@ func (w *worker) runDDLJob(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64,
 		ver, err = onAlterNoCacheTable(d, t, job)
 	case model.ActionFlashbackCluster:
 		ver, err = w.onFlashbackCluster(d, t, job)
+	case model.ActionFlashbackTables:
+		ver, err = w.onFlashbackTables(d, t, job)
 	case model.ActionMultiSchemaChange:
 		ver, err = onMultiSchemaChange(w, d, t, job)
 	default:
@@ -265,6 +265,60 @@ func TestParallelDDL(t *testing.T) {
 	require.Less(t, seqIDs[7], seqIDs[9])
 }
 
+func TestHookJobContextConcurrentJobs(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int)")
+
+	// The add index job and the add column job are run by different workers concurrently, and share the hook.
+	sqls := []string{"alter table t1 add index idx(a)", "alter table t2 add column b int"}
+	var mu sync.Mutex
+	states := make(map[string][]model.SchemaState)
+	runs := make(map[string]int)
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeWithContext = func(job *model.Job, jobCtx *ddl.HookJobContext) {
+		cnt, _ := jobCtx.Get("runs")
+		n, _ := cnt.(int)
+		jobCtx.Put("runs", n+1)
+	}
+	hook.OnJobUpdatedWithContext = func(job *model.Job, jobCtx *ddl.HookJobContext) {
+		jobCtx.RecordState(job)
+	}
+	hook.OnJobFinishedWithContext = func(job *model.Job, jobCtx *ddl.HookJobContext) {
+		cnt, _ := jobCtx.Get("runs")
+		mu.Lock()
+		defer mu.Unlock()
+		states[job.Query] = jobCtx.States()
+		runs[job.Query], _ = cnt.(int)
+	}
+	originHook := dom.DDL().GetHook()
+	dom.DDL().SetHook(hook)
+	defer dom.DDL().SetHook(originHook)
+
+	var wg util.WaitGroupWrapper
+	for _, sql := range sqls {
+		sql := sql
+		tk1 := testkit.NewTestKit(t, store)
+		tk1.MustExec("use test")
+		wg.Run(func() {
+			tk1.MustExec(sql)
+		})
+	}
+	wg.Wait()
+
+	expected := []model.SchemaState{model.StateDeleteOnly, model.StateWriteOnly, model.StateWriteReorganization, model.StatePublic}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, sql := range sqls {
+		require.Equal(t, expected, states[sql], sql)
+		require.GreaterOrEqual(t, runs[sql], len(expected), sql)
+	}
+	// The storage of the jobs is released when they're finished.
+	require.Zero(t, hook.JobContextCount())
+}
+
 func TestJobNeedGC(t *testing.T) {
 	job := &model.Job{Type: model.ActionAddIndex, State: model.JobStateCancelled}
 	require.False(t, ddl.JobNeedGCForTest(job))
@@ -196,42 +196,46 @@ func (dr *delRange) doDelRangeWork() error {
 func (dr *delRange) doTask(sctx sessionctx.Context, r util.DelRangeTask) error {
 	var oldStartKey, newStartKey kv.Key
 	oldStartKey = r.StartKey
+	policy := newRangeOpPolicy(nil)
 	for {
 		finish := true
-		dr.keys = dr.keys[:0]
 		ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
-		err := kv.RunInNewTxn(ctx, dr.store, false, func(ctx context.Context, txn kv.Transaction) error {
-			if topsqlstate.TopSQLEnabled() {
-				// Only when TiDB run without PD(use unistore as storage for test) will run into here, so just set a mock internal resource tagger.
-				txn.SetOption(kv.ResourceGroupTagger, util.GetInternalResourceGroupTaggerForTopSQL())
-			}
-			iter, err := txn.Iter(oldStartKey, r.EndKey)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			defer iter.Close()
-
-			txn.SetDiskFullOpt(kvrpcpb.DiskFullOpt_AllowedOnAlmostFull)
-			for i := 0; i < delBatchSize; i++ {
-				if !iter.Valid() {
-					break
+		err := policy.Do(ctx, func() error {
+			finish = true
+			dr.keys = dr.keys[:0]
+			return kv.RunInNewTxn(ctx, dr.store, false, func(ctx context.Context, txn kv.Transaction) error {
+				if topsqlstate.TopSQLEnabled() {
+					// Only when TiDB run without PD(use unistore as storage for test) will run into here, so just set a mock internal resource tagger.
+					txn.SetOption(kv.ResourceGroupTagger, util.GetInternalResourceGroupTaggerForTopSQL())
 				}
-				finish = false
-				dr.keys = append(dr.keys, iter.Key().Clone())
-				newStartKey = iter.Key().Next()
-
-				if err := iter.Next(); err != nil {
+				iter, err := txn.Iter(oldStartKey, r.EndKey)
+				if err != nil {
 					return errors.Trace(err)
 				}
-			}
+				defer iter.Close()
 
-			for _, key := range dr.keys {
-				err := txn.Delete(key)
-				if err != nil && !kv.ErrNotExist.Equal(err) {
-					return errors.Trace(err)
+				txn.SetDiskFullOpt(kvrpcpb.DiskFullOpt_AllowedOnAlmostFull)
+				for i := 0; i < delBatchSize; i++ {
+					if !iter.Valid() {
+						break
+					}
+					finish = false
+					dr.keys = append(dr.keys, iter.Key().Clone())
+					newStartKey = iter.Key().Next()
+
+					if err := iter.Next(); err != nil {
+						return errors.Trace(err)
+					}
 				}
-			}
-			return nil
+
+				for _, key := range dr.keys {
+					err := txn.Delete(key)
+					if err != nil && !kv.ErrNotExist.Equal(err) {
+						return errors.Trace(err)
+					}
+				}
+				return nil
+			})
 		})
 		if err != nil {
 			return errors.Trace(err)
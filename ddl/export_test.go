@@ -14,6 +14,80 @@
 
 package ddl
 
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
 func SetBatchInsertDeleteRangeSize(i int) {
 	batchInsertDeleteRangeSize = i
 }
+
+// GetFlashbackBatches returns the batches of the key ranges flashed back by the job with the batch size.
+func GetFlashbackBatches(ctx context.Context, store kv.Storage, job *model.Job, size int, keyRanges []kv.KeyRange) ([]kv.KeyRange, error) {
+	var batches []kv.KeyRange
+	err := newFlashbackBatch(newJobLogger(job), job, size, nil).forEach(ctx, store, keyRanges, func(_ int, r kv.KeyRange, _ int) error {
+		batches = append(batches, r)
+		return nil
+	})
+	return batches, err
+}
+
+// FlashbackToVersion flashes back the key ranges in the store as the step of the job.
+func FlashbackToVersion(ctx context.Context, store kv.Storage, job *model.Job, keyRanges []kv.KeyRange, flashbackTS uint64) error {
+	batch := newFlashbackBatch(newJobLogger(job), job, variable.DefTiDBFlashbackBatchSize, nil)
+	return flashbackToVersion(ctx, store, keyRanges, flashbackTS, batch)
+}
+
+// FlashbackToVersionWithCheckpoints flashes back the key ranges in the store as the step of the job, which resumes
+// from the checkpoints of the job.
+func FlashbackToVersionWithCheckpoints(ctx context.Context, sctx sessionctx.Context, store kv.Storage, job *model.Job,
+	keyRanges []kv.KeyRange, flashbackTS uint64) error {
+	checkpoints, err := loadFlashbackCheckpoints(ctx, sctx, newJobLogger(job), job)
+	if err != nil {
+		return err
+	}
+	batch := newFlashbackBatch(newJobLogger(job), job, variable.DefTiDBFlashbackBatchSize, checkpoints)
+	return flashbackToVersion(ctx, store, keyRanges, flashbackTS, batch)
+}
+
+func SetFlashbackResolveLockMaxBackoff(backoff int) (restore func()) {
+	origin := flashbackResolveLockMaxBackoff
+	flashbackResolveLockMaxBackoff = backoff
+	return func() {
+		flashbackResolveLockMaxBackoff = origin
+	}
+}
+
+func CheckSessionLeaks(d DDL, threshold time.Duration, recycle bool) int {
+	return d.(*ddl).sessPool.checkLeaks(threshold, recycle)
+}
+
+func FailStaleFlashbackJobs(d DDL) ([]int64, error) {
+	return d.(*ddl).failStaleFlashbackJobs()
+}
+
+func SetFlashbackConflictingQueriesWaitTimeout(timeout time.Duration) (restore func()) {
+	origin := flashbackConflictingQueriesWaitTimeout
+	flashbackConflictingQueriesWaitTimeout = timeout
+	return func() {
+		flashbackConflictingQueriesWaitTimeout = origin
+	}
+}
+
+func SetFlashbackScatterThresholds(minStores int, sizeMiB int64) (restore func()) {
+	originStores, originSize := flashbackScatterMinStores, flashbackScatterSizeThreshold
+	flashbackScatterMinStores, flashbackScatterSizeThreshold = minStores, sizeMiB
+	return func() {
+		flashbackScatterMinStores, flashbackScatterSizeThreshold = originStores, originSize
+	}
+}
+
+func CheckFlashbackKeyspaceCodec(store kv.Storage) error {
+	return checkFlashbackKeyspaceCodec(store)
+}
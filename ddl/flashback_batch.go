@@ -0,0 +1,142 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	storeerr "github.com/pingcap/tidb/store/driver/error"
+	"github.com/tikv/client-go/v2/tikv"
+	"go.uber.org/zap"
+)
+
+const (
+	// minFlashbackBatchSize is the floor of the flashback batch size, which isn't halved any more on the timeouts.
+	minFlashbackBatchSize = 1
+	// flashbackBatchLocateMaxBackoff is the max backoff in milliseconds to locate the regions of a batch.
+	flashbackBatchLocateMaxBackoff = 20000
+)
+
+// flashbackBatch splits the flashback key ranges into the batches of at most size regions, each batch is flashed back
+// by one request. The size is halved when a request times out or TiKV is busy, so that the large clusters are flashed
+// back by the smaller requests instead of failing the step over and over. The effective size is recorded in the reorg
+// meta of the job, and the retries of the step start with it. The batches start from the checkpoints of the job.
+type flashbackBatch struct {
+	size        int
+	job         *model.Job
+	logger      *jobLogger
+	checkpoints *flashbackCheckpoints
+}
+
+func newFlashbackBatch(logger *jobLogger, job *model.Job, size int, checkpoints *flashbackCheckpoints) *flashbackBatch {
+	if job.ReorgMeta != nil && job.ReorgMeta.FlashbackBatchSize > 0 {
+		size = job.ReorgMeta.FlashbackBatchSize
+	}
+	if size < minFlashbackBatchSize {
+		size = minFlashbackBatchSize
+	}
+	return &flashbackBatch{size: size, job: job, logger: logger, checkpoints: checkpoints}
+}
+
+// adapt halves the batch size if the request failed with err because it was too large. It returns false if the
+// request can't be retried with a smaller batch.
+func (b *flashbackBatch) adapt(err error) bool {
+	if b.size <= minFlashbackBatchSize {
+		return false
+	}
+	if !storeerr.ErrTiKVServerTimeout.Equal(err) && !storeerr.ErrTiKVServerBusy.Equal(err) {
+		return false
+	}
+	from := b.size
+	b.size = from / 2
+	if b.size < minFlashbackBatchSize {
+		b.size = minFlashbackBatchSize
+	}
+	if b.job.ReorgMeta == nil {
+		b.job.ReorgMeta = &model.DDLReorgMeta{}
+	}
+	b.job.ReorgMeta.FlashbackBatchSize = b.size
+	b.logger.Warn("halve the flashback batch size", zap.Int("from", from), zap.Int("to", b.size), zap.Error(err))
+	return true
+}
+
+// forEach calls fn with the batches of the key ranges in order, and the number of the regions in the range up to the
+// end of the batch. The batch failed with a timeout is retried with the halved size. The stores without the region
+// cache flash back each key range as a whole.
+func (b *flashbackBatch) forEach(ctx context.Context, store kv.Storage, keyRanges []kv.KeyRange,
+	fn func(idx int, r kv.KeyRange, regionCount int) error) error {
+	s, ok := store.(tikv.Storage)
+	for i, r := range keyRanges {
+		key, regionCount := b.checkpoints.resumeKey(i, r)
+		if regionCount > 0 && bytes.Equal(key, r.EndKey) {
+			continue
+		}
+		for {
+			end, regions := r.EndKey, 1
+			var err error
+			if ok {
+				end, regions, err = locateFlashbackBatch(ctx, s, key, r.EndKey, b.size)
+				if err != nil {
+					return err
+				}
+			}
+			failpoint.Inject("mockFlashbackBatchTimeout", func(val failpoint.Value) {
+				if regions > val.(int) {
+					err = errors.Trace(storeerr.ErrTiKVServerTimeout)
+				}
+			})
+			if err == nil {
+				err = fn(i, kv.KeyRange{StartKey: key, EndKey: end}, regionCount+regions)
+			}
+			if err != nil {
+				if b.adapt(errors.Cause(err)) {
+					continue
+				}
+				return err
+			}
+			regionCount += regions
+			if bytes.Equal(end, r.EndKey) {
+				break
+			}
+			key = end
+		}
+	}
+	return nil
+}
+
+// locateFlashbackBatch returns the end key and the number of the regions of the batch starting at startKey, which
+// covers at most size regions before endKey.
+func locateFlashbackBatch(ctx context.Context, s tikv.Storage, startKey, endKey kv.Key, size int) (kv.Key, int, error) {
+	bo := tikv.NewBackofferWithVars(ctx, flashbackBatchLocateMaxBackoff, nil)
+	key := startKey
+	for regions := 1; ; regions++ {
+		loc, err := s.GetRegionCache().LocateKey(bo, key)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		if len(loc.EndKey) == 0 || (len(endKey) != 0 && bytes.Compare(loc.EndKey, endKey) >= 0) {
+			return endKey, regions, nil
+		}
+		if regions >= size {
+			return loc.EndKey, regions, nil
+		}
+		key = loc.EndKey
+	}
+}
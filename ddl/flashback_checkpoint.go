@@ -0,0 +1,187 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// FlashbackCheckpointRetention is how long the checkpoints of the flashback job, which is neither running nor
+// pending, are kept in mysql.tidb_flashback_checkpoint before they're purged by CleanupOrphanFlashbackCheckpoints.
+// The checkpoints of the finished jobs are purged when they finish, only the ones of the lost jobs are left.
+// It's a variable to be changed in the tests.
+var FlashbackCheckpointRetention = 24 * time.Hour
+
+// FlashbackCheckpoint is the progress of the flashback job in one of its key ranges, it's recorded in
+// mysql.tidb_flashback_checkpoint after each batch of the range is flashed back, and the job resumes from it after
+// the step is retried, e.g. by the new DDL owner.
+type FlashbackCheckpoint struct {
+	RangeIndex int
+	// EndKey is the key before which the range is flashed back.
+	EndKey      kv.Key
+	RegionCount int
+	UpdatedAt   time.Time
+}
+
+// flashbackCheckpoints records the checkpoints of the flashback job. The nil flashbackCheckpoints records nothing.
+type flashbackCheckpoints struct {
+	sess   *session
+	jobID  int64
+	ranges map[int]*FlashbackCheckpoint
+}
+
+// loadFlashbackCheckpoints loads the checkpoints recorded by the job, the job resumes from them.
+func loadFlashbackCheckpoints(ctx context.Context, sctx sessionctx.Context, logger *jobLogger, job *model.Job) (*flashbackCheckpoints, error) {
+	checkpoints, err := GetFlashbackCheckpoints(ctx, sctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	c := &flashbackCheckpoints{sess: newSession(sctx), jobID: job.ID, ranges: make(map[int]*FlashbackCheckpoint, len(checkpoints))}
+	regionCount := 0
+	for _, cp := range checkpoints {
+		c.ranges[cp.RangeIndex] = cp
+		regionCount += cp.RegionCount
+	}
+	if len(checkpoints) > 0 {
+		logger.Info("resume flashback from the checkpoints", zap.Int("ranges", len(checkpoints)), zap.Int("regions", regionCount))
+	}
+	return c, nil
+}
+
+// resumeKey returns the key to resume flashing back the range from, and the number of the regions flashed back
+// before it. The checkpoint outside the range is ignored, e.g. the key ranges are changed.
+func (c *flashbackCheckpoints) resumeKey(idx int, r kv.KeyRange) (kv.Key, int) {
+	if c == nil {
+		return r.StartKey, 0
+	}
+	cp, ok := c.ranges[idx]
+	if !ok || bytes.Compare(cp.EndKey, r.StartKey) < 0 || (len(r.EndKey) != 0 && bytes.Compare(cp.EndKey, r.EndKey) > 0) {
+		return r.StartKey, 0
+	}
+	return cp.EndKey, cp.RegionCount
+}
+
+// save records that the range is flashed back before endKey.
+func (c *flashbackCheckpoints) save(ctx context.Context, idx int, endKey kv.Key, regionCount int) error {
+	if c == nil {
+		return nil
+	}
+	sql := fmt.Sprintf("replace into mysql.tidb_flashback_checkpoint(job_id, range_index, end_key, region_count) values (%d, %d, x'%x', %d)",
+		c.jobID, idx, []byte(endKey), regionCount)
+	if _, err := c.sess.execute(ctx, sql, "save_flashback_checkpoint"); err != nil {
+		return errors.Trace(err)
+	}
+	c.ranges[idx] = &FlashbackCheckpoint{RangeIndex: idx, EndKey: endKey, RegionCount: regionCount}
+	return nil
+}
+
+// GetFlashbackCheckpoints returns the checkpoints recorded by the flashback job in the order of the ranges.
+func GetFlashbackCheckpoints(ctx context.Context, sctx sessionctx.Context, jobID int64) ([]*FlashbackCheckpoint, error) {
+	rows, err := newSession(sctx).execute(ctx, fmt.Sprintf(
+		"select range_index, end_key, region_count, updated_at from mysql.tidb_flashback_checkpoint where job_id = %d order by range_index", jobID),
+		"get_flashback_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]*FlashbackCheckpoint, 0, len(rows))
+	for _, row := range rows {
+		updatedAt, err := row.GetTime(3).GoTime(time.Local)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		checkpoints = append(checkpoints, &FlashbackCheckpoint{
+			RangeIndex:  int(row.GetInt64(0)),
+			EndKey:      kv.Key(row.GetBytes(1)),
+			RegionCount: int(row.GetInt64(2)),
+			UpdatedAt:   updatedAt,
+		})
+	}
+	return checkpoints, nil
+}
+
+// purgeFlashbackCheckpoints removes the checkpoints of the job when it finishes. The failure is only logged, the
+// checkpoints left are purged by CleanupOrphanFlashbackCheckpoints after the retention.
+func purgeFlashbackCheckpoints(w *worker, job *model.Job) {
+	sql := fmt.Sprintf("delete from mysql.tidb_flashback_checkpoint where job_id = %d", job.ID)
+	var err error
+	if w.concurrentDDL {
+		_, err = w.sess.execute(w.ctx, sql, "purge_flashback_checkpoints")
+	} else {
+		var sctx sessionctx.Context
+		if sctx, err = w.sessPool.get(); err == nil {
+			_, err = newSession(sctx).execute(w.ctx, sql, "purge_flashback_checkpoints")
+			w.sessPool.put(sctx)
+		}
+	}
+	if err != nil {
+		logutil.BgLogger().Warn("[ddl] purge the flashback checkpoints failed", zap.Int64("jobID", job.ID), zap.Error(err))
+	}
+}
+
+// CleanupOrphanFlashbackCheckpoints purges the checkpoints of the flashback jobs which are neither running nor
+// pending and haven't been updated for FlashbackCheckpointRetention, e.g. the job is lost or its purge failed. It
+// returns the IDs of the jobs whose checkpoints are purged.
+func CleanupOrphanFlashbackCheckpoints(ctx context.Context, sctx sessionctx.Context) ([]int64, error) {
+	rows, err := newSession(sctx).execute(ctx, fmt.Sprintf(
+		"select distinct job_id from mysql.tidb_flashback_checkpoint where updated_at < date_sub(now(6), interval %d microsecond)",
+		FlashbackCheckpointRetention.Microseconds()), "get_orphan_flashback_checkpoints")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	var jobs []*model.Job
+	err = kv.RunInNewTxn(ctx, sctx.GetStore(), true, func(ctx context.Context, txn kv.Transaction) (err error) {
+		jobs, err = getAllDDLJobs(ctx, sctx, meta.NewMeta(txn))
+		return err
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	running := make(map[int64]struct{}, len(jobs))
+	for _, job := range jobs {
+		running[job.ID] = struct{}{}
+	}
+	var orphans []int64
+	var ids []string
+	for _, row := range rows {
+		jobID := row.GetInt64(0)
+		if _, ok := running[jobID]; ok {
+			continue
+		}
+		orphans = append(orphans, jobID)
+		ids = append(ids, fmt.Sprintf("%d", jobID))
+	}
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+	logutil.BgLogger().Warn("[ddl] purge the checkpoints of the orphaned flashback jobs", zap.Int64s("jobIDs", orphans))
+	_, err = newSession(sctx).execute(ctx, fmt.Sprintf("delete from mysql.tidb_flashback_checkpoint where job_id in (%s)",
+		strings.Join(ids, ", ")), "purge_orphan_flashback_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
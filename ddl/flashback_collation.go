@@ -0,0 +1,96 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/dbterror"
+)
+
+// tableKeyCollations returns the collations of the string columns encoded in the index keys of the table, including
+// the ones of the clustered primary key. The index keys written in one collation can't be read in another one, so
+// the tables whose key collations are changed can't be flashed back across the change.
+func tableKeyCollations(tblInfo *model.TableInfo) string {
+	var sb strings.Builder
+	for _, idx := range tblInfo.Indices {
+		for _, idxCol := range idx.Columns {
+			if idxCol.Offset >= len(tblInfo.Columns) {
+				continue
+			}
+			col := tblInfo.Columns[idxCol.Offset]
+			if !types.IsString(col.GetType()) {
+				continue
+			}
+			fmt.Fprintf(&sb, "%d.%d:%s;", idx.ID, col.ID, col.GetCollate())
+		}
+	}
+	return sb.String()
+}
+
+// listTableKeyCollations returns the key collations of the tables by their IDs, and the names of the tables.
+func listTableKeyCollations(m *meta.Meta) (map[int64]string, map[int64]string, error) {
+	dbs, err := m.ListDatabases()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	collations, names := make(map[int64]string), make(map[int64]string)
+	for _, db := range dbs {
+		tbls, err := m.ListTables(db.ID)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		for _, tbl := range tbls {
+			if !tbl.IsBaseTable() {
+				continue
+			}
+			collations[tbl.ID] = tableKeyCollations(tbl)
+			names[tbl.ID] = fmt.Sprintf("%s.%s", db.Name.O, tbl.Name.O)
+		}
+	}
+	return collations, names, nil
+}
+
+// checkFlashbackCollations checks that the index keys of the tables are encoded in the same collations at
+// flashbackTS and now, or the indexes are unreadable after the flashback, since the keys written before and after
+// the change are mixed. The tables existing only at one side are left to checkFlashbackSchemaVersion.
+func checkFlashbackCollations(store kv.Storage, t *meta.Meta, flashbackTS uint64) error {
+	snapCollations, _, err := listTableKeyCollations(meta.NewSnapshotMeta(store.GetSnapshot(kv.NewVersion(flashbackTS))))
+	if err != nil {
+		return err
+	}
+	collations, names, err := listTableKeyCollations(t)
+	if err != nil {
+		return err
+	}
+	var mismatched []string
+	for id, snapCollation := range snapCollations {
+		if collation, ok := collations[id]; ok && collation != snapCollation {
+			mismatched = append(mismatched, names[id])
+		}
+	}
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return dbterror.ErrFlashbackCollationMismatch.GenWithStackByArgs(strings.Join(mismatched, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/domain/infosync"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	// flashbackConflictingQueriesWaitTimeout is the max time the flashback job waits for the conflicting queries
+	// before flashing back the data, if they aren't killed.
+	flashbackConflictingQueriesWaitTimeout = 30 * time.Second
+	// flashbackConflictingQueriesCheckInterval is the interval of checking whether the conflicting queries finish.
+	flashbackConflictingQueriesCheckInterval = 100 * time.Millisecond
+)
+
+// flashbackConflictingQuery is a query running in the cluster since before the flashback job starts, which reads the
+// snapshot across the flashback.
+type flashbackConflictingQuery struct {
+	server *infosync.ServerInfo
+	*infosync.ProcessListItem
+}
+
+// getFlashbackConflictingQueries gets the queries running in all the TiDB instances which start before the job. The
+// statement waiting for the job isn't counted. The instances failing to list the queries are skipped.
+func getFlashbackConflictingQueries(ctx context.Context, job *model.Job) ([]flashbackConflictingQuery, error) {
+	servers, err := infosync.GetAllServerInfo(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	startTime := oracle.GetTimeFromTS(job.StartTS)
+	var queries []flashbackConflictingQuery
+	for _, server := range servers {
+		items, err := infosync.GetProcessList(ctx, server)
+		if err != nil {
+			logutil.BgLogger().Warn("[ddl] get the processlist of the TiDB instance failed, skip it",
+				zap.Int64("jobID", job.ID), zap.String("server", server.ID), zap.Error(err))
+			continue
+		}
+		for _, item := range items {
+			if item.Command == mysql.Command2Str[mysql.ComSleep] || item.DDLJobID == job.ID || !item.StartTime.Before(startTime) {
+				continue
+			}
+			queries = append(queries, flashbackConflictingQuery{server: server, ProcessListItem: item})
+		}
+	}
+	slices.SortFunc(queries, func(i, j flashbackConflictingQuery) bool {
+		if i.server.ID != j.server.ID {
+			return i.server.ID < j.server.ID
+		}
+		return i.ConnectionID < j.ConnectionID
+	})
+	return queries, nil
+}
+
+// handleFlashbackConflictingQueries handles the queries conflicting with the flashback job before the cluster enters
+// the read-only window. If kill is true, the conflicting queries are killed. Otherwise, they're reported, and the job
+// waits for them to finish for at most flashbackConflictingQueriesWaitTimeout before going on.
+func handleFlashbackConflictingQueries(ctx context.Context, job *model.Job, kill bool) error {
+	queries, err := getFlashbackConflictingQueries(ctx, job)
+	if err != nil {
+		return err
+	}
+	if kill {
+		for _, q := range queries {
+			if err := infosync.KillQuery(ctx, q.server, q.ConnectionID); err != nil {
+				logutil.BgLogger().Warn("[ddl] kill the conflicting query of the flashback failed", zap.Int64("jobID", job.ID),
+					zap.String("server", q.server.ID), zap.Uint64("connID", q.ConnectionID), zap.Error(err))
+				continue
+			}
+			logutil.BgLogger().Info("[ddl] kill the conflicting query of the flashback", zap.Int64("jobID", job.ID),
+				zap.String("server", q.server.ID), zap.Uint64("connID", q.ConnectionID), zap.String("user", q.User),
+				zap.String("host", q.Host), zap.Time("startTime", q.StartTime))
+		}
+		return nil
+	}
+
+	deadline := time.Now().Add(flashbackConflictingQueriesWaitTimeout)
+	for _, q := range queries {
+		logutil.BgLogger().Warn("[ddl] wait for the conflicting query of the flashback, kill it or use KILL CONFLICTING QUERIES",
+			zap.Int64("jobID", job.ID), zap.String("server", q.server.ID), zap.Uint64("connID", q.ConnectionID),
+			zap.String("user", q.User), zap.String("host", q.Host), zap.Time("startTime", q.StartTime))
+	}
+	for len(queries) > 0 {
+		if time.Now().After(deadline) {
+			logutil.BgLogger().Warn("[ddl] the conflicting queries of the flashback are still running after the timeout, go on",
+				zap.Int64("jobID", job.ID), zap.Int("count", len(queries)), zap.Duration("timeout", flashbackConflictingQueriesWaitTimeout))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(flashbackConflictingQueriesCheckInterval):
+		}
+		if queries, err = getFlashbackConflictingQueries(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/testutil"
+	"github.com/pingcap/tidb/util"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// runningQuerySessionManager shows the query running in the session of tk as the only connection of the instance.
+type runningQuerySessionManager struct {
+	testutil.MockSessionManager
+	tk      *testkit.TestKit
+	running int32
+
+	mu     sync.Mutex
+	killed []uint64
+}
+
+func (sm *runningQuerySessionManager) ShowProcessList() map[uint64]*util.ProcessInfo {
+	if atomic.LoadInt32(&sm.running) == 0 {
+		return nil
+	}
+	pi := *sm.tk.Session().ShowProcess()
+	// The statements of the test kit are executed without the command.
+	pi.Command = mysql.ComQuery
+	return map[uint64]*util.ProcessInfo{pi.ID: &pi}
+}
+
+func (sm *runningQuerySessionManager) Kill(connID uint64, query bool) {
+	sm.mu.Lock()
+	sm.killed = append(sm.killed, connID)
+	sm.mu.Unlock()
+	atomic.StoreUint32(&sm.tk.Session().GetSessionVars().Killed, 1)
+}
+
+func (sm *runningQuerySessionManager) runQuery(sql string) <-chan string {
+	atomic.StoreInt32(&sm.running, 1)
+	result := make(chan string, 1)
+	go func() {
+		rows := sm.tk.MustQuery(sql).Rows()
+		atomic.StoreInt32(&sm.running, 0)
+		result <- rows[0][0].(string)
+	}()
+	// The query must start before the flashback job.
+	time.Sleep(100 * time.Millisecond)
+	return result
+}
+
+func TestFlashbackClusterKillConflictingQueries(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	flashbackSQL := func() string {
+		ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+		require.NoError(t, err)
+		return fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts))
+	}
+
+	sm := &runningQuerySessionManager{tk: testkit.NewTestKit(t, store)}
+	dom.InfoSyncer().SetSessionManager(sm)
+	defer dom.InfoSyncer().SetSessionManager(nil)
+
+	// Without KILL CONFLICTING QUERIES, the job waits for the query to finish.
+	result := sm.runQuery("select sleep(1)")
+	tk.MustExec(flashbackSQL())
+	select {
+	case res := <-result:
+		require.Equal(t, "0", res)
+	default:
+		require.FailNow(t, "the flashback doesn't wait for the conflicting query")
+	}
+
+	// The job goes on if the query is still running after the timeout.
+	restore := ddl.SetFlashbackConflictingQueriesWaitTimeout(100 * time.Millisecond)
+	result = sm.runQuery("select sleep(2)")
+	tk.MustExec(flashbackSQL())
+	select {
+	case <-result:
+		require.FailNow(t, "the flashback waits for the conflicting query after the timeout")
+	default:
+	}
+	require.Equal(t, "0", <-result)
+	restore()
+	require.Empty(t, sm.killed)
+
+	// With KILL CONFLICTING QUERIES, the query is killed.
+	result = sm.runQuery("select sleep(30)")
+	start := time.Now()
+	tk.MustExec(flashbackSQL() + " kill conflicting queries")
+	require.Equal(t, "1", <-result)
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.Equal(t, []uint64{sm.tk.Session().ShowProcess().ID}, sm.killed)
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job args %s", jobID)).Rows()
+	require.Equal(t, []interface{}{"kill_conflicting_queries", "true"}, rows[len(rows)-1][2:])
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func TestKillFlashbackCluster(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	originHook := dom.DDL().GetHook()
+	defer dom.DDL().SetHook(originHook)
+	tk := testkit.NewTestKit(t, store)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	flashbackSQL := fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts))
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	// Killed in StateWriteOnly, the job is cancelled.
+	var killed bool
+	tk2 := testkit.NewTestKit(t, store)
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type == model.ActionFlashbackCluster && job.SchemaState == model.StateWriteOnly && !killed {
+			killed = true
+			atomic.StoreUint32(&tk.Session().GetSessionVars().Killed, 1)
+			// Wait for the killed statement to cancel the job.
+			assert.Eventually(t, func() bool {
+				return tk2.MustQuery("admin show ddl jobs 1").Rows()[0][11] == "cancelling"
+			}, 10*time.Second, 10*time.Millisecond)
+		}
+	}
+	dom.DDL().SetHook(hook)
+	tk.MustGetErrCode(flashbackSQL, errno.ErrCancelledDDLJob)
+	require.True(t, killed)
+	atomic.StoreUint32(&tk.Session().GetSessionVars().Killed, 0)
+	require.Equal(t, "cancelled", tk.MustQuery("admin show ddl jobs 1").Rows()[0][11])
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+
+	// Killed in StateWriteReorganization, the statement returns and the job continues in the background. The schema
+	// rollback listeners are notified after the job is done.
+	notified := make(chan int64, 1)
+	dom.RegisterSchemaRollbackListener(func(oldVer, newVer int64, tso uint64) {
+		notified <- newVer
+	})
+	killed = false
+	detached := make(chan struct{})
+	hook = &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type == model.ActionFlashbackCluster && job.SchemaState == model.StateWriteReorganization && !killed {
+			killed = true
+			atomic.StoreUint32(&tk.Session().GetSessionVars().Killed, 1)
+			<-detached
+		}
+	}
+	dom.DDL().SetHook(hook)
+	err = tk.ExecToErr(flashbackSQL)
+	close(detached)
+	atomic.StoreUint32(&tk.Session().GetSessionVars().Killed, 0)
+	jobID := tk2.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	require.EqualError(t, err, fmt.Sprintf("the flashback job %s can't be cancelled in the current state, it continues in the background, check it by ADMIN SHOW DDL JOBS", jobID))
+	id, err := strconv.ParseInt(jobID, 10, 64)
+	require.NoError(t, err)
+	tk2.MustWaitDDLJob(id)
+	select {
+	case <-notified:
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "the schema rollback listener isn't notified")
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/memory"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+	"github.com/tikv/client-go/v2/txnkv/txnlock"
+	tikvutil "github.com/tikv/client-go/v2/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// flashbackScanLockLimit is the max number of locks scanned by a scan lock request.
+	flashbackScanLockLimit = 1024
+	// flashbackMaxReportedTxns is the max number of live transactions reported in the error.
+	flashbackMaxReportedTxns = 10
+)
+
+// FlashbackMemTracker tracks the memory of the locks buffered by the flashback jobs. It's attached to the global
+// memory tracker, so the memory is counted in the server memory quota.
+var FlashbackMemTracker = memory.NewTracker(memory.LabelForFlashback, -1)
+
+// The estimated sizes of a lock besides its keys, and of a transaction recorded in flashbackLiveTxns.
+const (
+	flashbackLockSize = int64(unsafe.Sizeof(txnlock.Lock{}))
+	flashbackTxnSize  = int64(unsafe.Sizeof(uint64(0))) * 2
+)
+
+func flashbackLocksMemUsage(locks []*txnlock.Lock) int64 {
+	size := int64(0)
+	for _, l := range locks {
+		size += flashbackLockSize + int64(len(l.Key)+len(l.Primary))
+	}
+	return size
+}
+
+// flashbackLiveTxns collects the live transactions in the flashback ranges. Only the locks of the first
+// flashbackMaxReportedTxns transactions are kept to be reported, the others are only counted.
+type flashbackLiveTxns struct {
+	locks      []*txnlock.Lock
+	txns       map[uint64]struct{}
+	memTracker *memory.Tracker
+}
+
+func (t *flashbackLiveTxns) add(locks []*txnlock.Lock) {
+	for _, l := range locks {
+		if _, ok := t.txns[l.TxnID]; ok {
+			continue
+		}
+		t.txns[l.TxnID] = struct{}{}
+		t.memTracker.Consume(flashbackTxnSize)
+		if len(t.locks) < flashbackMaxReportedTxns {
+			t.locks = append(t.locks, l)
+			t.memTracker.Consume(flashbackLockSize + int64(len(l.Key)+len(l.Primary)))
+		}
+	}
+}
+
+// flashbackResolveLockMaxBackoff is the max backoff in milliseconds to wait for the live transactions in a region
+// to finish before reporting them. It's a variable for testing.
+var flashbackResolveLockMaxBackoff = 10000
+
+// resolveFlashbackLocks scans the locks older than maxVersion in the key ranges, and resolves the expired ones. The
+// live transactions which still hold the locks are rolled back if force is true, otherwise they're reported in the
+// error, so the operator can kill them before flashing back. The scanned locks are tracked by FlashbackMemTracker.
+func resolveFlashbackLocks(ctx context.Context, store kv.Storage, ranges []kv.KeyRange, maxVersion uint64, force bool) error {
+	s, ok := store.(tikv.Storage)
+	if !ok {
+		// Only support resolving locks in tikv.Storage now.
+		return nil
+	}
+	memTracker := memory.NewTracker(memory.LabelForFlashback, -1)
+	memTracker.AttachTo(FlashbackMemTracker)
+	defer memTracker.Detach()
+	live := &flashbackLiveTxns{txns: make(map[uint64]struct{}), memTracker: memTracker}
+	for _, r := range ranges {
+		if err := resolveFlashbackLocksInRange(ctx, s, r, maxVersion, force, live); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(live.locks) == 0 {
+		return nil
+	}
+	return errLiveTxnsInFlashbackRanges(live.locks, len(live.txns))
+}
+
+func resolveFlashbackLocksInRange(ctx context.Context, s tikv.Storage, r kv.KeyRange, maxVersion uint64, force bool,
+	live *flashbackLiveTxns) error {
+	key := r.StartKey
+	bo := tikv.NewBackofferWithVars(ctx, flashbackResolveLockMaxBackoff, nil)
+	for {
+		loc, err := s.GetRegionCache().LocateKey(bo, key)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		endKey := loc.EndKey
+		if len(endKey) == 0 || (len(r.EndKey) != 0 && bytes.Compare(endKey, r.EndKey) > 0) {
+			endKey = r.EndKey
+		}
+		locks, err := scanFlashbackLocks(bo, s, loc, key, endKey, maxVersion)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// The batch of the locks is at most flashbackScanLockLimit, and it's released after the region is resolved.
+		locksMemUsage := flashbackLocksMemUsage(locks)
+		live.memTracker.Consume(locksMemUsage)
+		retry, err := resolveFlashbackLocksInRegion(bo, s, loc, locks, force, live)
+		live.memTracker.Consume(-locksMemUsage)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if retry {
+			continue
+		}
+
+		if len(locks) < flashbackScanLockLimit {
+			key = loc.EndKey
+		} else {
+			key = kv.Key(locks[len(locks)-1].Key).Next()
+		}
+		if len(key) == 0 || (len(r.EndKey) != 0 && bytes.Compare(key, r.EndKey) >= 0) {
+			break
+		}
+		bo = tikv.NewBackofferWithVars(ctx, flashbackResolveLockMaxBackoff, nil)
+	}
+	return nil
+}
+
+// resolveFlashbackLocksInRegion resolves the locks scanned in the region, and returns whether the region should be
+// scanned again to wait for the live transactions to finish.
+func resolveFlashbackLocksInRegion(bo *tikv.Backoffer, s tikv.Storage, loc *tikv.KeyLocation, locks []*txnlock.Lock,
+	force bool, live *flashbackLiveTxns) (retry bool, err error) {
+	var resolved bool
+	if force {
+		resolved, err = s.GetLockResolver().BatchResolveLocks(bo, locks, loc.Region)
+	} else {
+		var msBeforeTxnExpired int64
+		msBeforeTxnExpired, err = s.GetLockResolver().ResolveLocks(bo, 0, locks)
+		resolved = msBeforeTxnExpired <= 0
+	}
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if !resolved {
+		// Wait for the live transactions to finish, and scan the region again.
+		if err = bo.Backoff(tikv.BoTxnLock(), errors.Errorf("remain locks: %d", len(locks))); err == nil {
+			return true, nil
+		}
+		if force {
+			return false, errors.Trace(err)
+		}
+		liveLocks, err := filterLiveFlashbackLocks(s, locks)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		live.add(liveLocks)
+	} else if force && len(locks) > 0 {
+		logutil.BgLogger().Info("[ddl] roll back the transactions holding the locks in the flashback ranges",
+			zap.Uint64("region", loc.Region.GetID()), zap.Int("locks", len(locks)))
+	}
+	return false, nil
+}
+
+func scanFlashbackLocks(bo *tikv.Backoffer, s tikv.Storage, loc *tikv.KeyLocation, startKey, endKey []byte,
+	maxVersion uint64) ([]*txnlock.Lock, error) {
+	req := tikvrpc.NewRequest(tikvrpc.CmdScanLock, &kvrpcpb.ScanLockRequest{
+		MaxVersion: maxVersion,
+		Limit:      flashbackScanLockLimit,
+		StartKey:   startKey,
+		EndKey:     endKey,
+	}, kvrpcpb.Context{
+		RequestSource: tikvutil.RequestSourceFromCtx(bo.GetCtx()),
+	})
+	for {
+		resp, err := s.SendReq(bo, req, loc.Region, tikv.ReadTimeoutMedium)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if regionErr != nil {
+			if err = bo.Backoff(tikv.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if loc, err = s.GetRegionCache().LocateKey(bo, startKey); err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		if resp.Resp == nil {
+			return nil, errors.Trace(tikverr.ErrBodyMissing)
+		}
+		locksResp := resp.Resp.(*kvrpcpb.ScanLockResponse)
+		if locksResp.GetError() != nil {
+			return nil, errors.Errorf("unexpected scanlock error: %s", locksResp)
+		}
+		locks := make([]*txnlock.Lock, 0, len(locksResp.GetLocks()))
+		for _, li := range locksResp.GetLocks() {
+			locks = append(locks, txnlock.NewLock(li))
+		}
+		return locks, nil
+	}
+}
+
+// filterLiveFlashbackLocks returns the locks whose transactions are still alive, one lock for each transaction.
+func filterLiveFlashbackLocks(s tikv.Storage, locks []*txnlock.Lock) ([]*txnlock.Lock, error) {
+	checked := make(map[uint64]struct{}, len(locks))
+	var live []*txnlock.Lock
+	for _, l := range locks {
+		if _, ok := checked[l.TxnID]; ok {
+			continue
+		}
+		checked[l.TxnID] = struct{}{}
+		status, err := s.GetLockResolver().GetTxnStatus(l.TxnID, 0, l.Primary)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if status.TTL() > 0 {
+			live = append(live, l)
+		}
+	}
+	return live, nil
+}
+
+// errLiveTxnsInFlashbackRanges reports the locks of the live transactions, total is the number of all the live
+// transactions.
+func errLiveTxnsInFlashbackRanges(locks []*txnlock.Lock, total int) error {
+	txns := make([]string, 0, flashbackMaxReportedTxns+1)
+	for i, l := range locks {
+		if i == flashbackMaxReportedTxns {
+			break
+		}
+		txns = append(txns, fmt.Sprintf("(start_ts: %d, primary: %s, ttl: %dms)", l.TxnID, kv.Key(l.Primary), l.TTL))
+	}
+	if total > len(txns) {
+		txns = append(txns, fmt.Sprintf("and %d more", total-len(txns)))
+	}
+	return errors.Errorf("the flashback ranges are locked by the live transactions %s, kill them or flashback with FORCE to roll them back",
+		strings.Join(txns, ", "))
+}
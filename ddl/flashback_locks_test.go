@@ -0,0 +1,72 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func TestFlashbackResolveLocks(t *testing.T) {
+	defer ddl.SetFlashbackResolveLockMaxBackoff(100)()
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tsStr := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	for _, c := range []struct {
+		flashbackSQL string
+		lockedSQL    string
+	}{
+		{fmt.Sprintf("flashback table t to timestamp '%s'", tsStr), "update t set b = 10 where a = 1"},
+		{fmt.Sprintf("flashback cluster as of timestamp '%s'", tsStr), "update t set b = 20 where a = 2"},
+	} {
+		// The live transaction is reported, and the job is cancelled without changing anything.
+		tk2.MustExec("begin pessimistic")
+		tk2.MustExec(c.lockedSQL)
+		startTS := tk2.Session().GetSessionVars().TxnCtx.StartTS
+		err = tk.ExecToErr(c.flashbackSQL)
+		require.ErrorContains(t, err, fmt.Sprintf("the flashback ranges are locked by the live transactions (start_ts: %d, primary: ", startTS))
+		require.ErrorContains(t, err, "kill them or flashback with FORCE to roll them back")
+		// The scanned locks are tracked, and released after the job.
+		require.Greater(t, ddl.FlashbackMemTracker.MaxConsumed(), int64(0))
+		require.Equal(t, int64(0), ddl.FlashbackMemTracker.BytesConsumed())
+		tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+		tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "2 2"))
+
+		// The live transaction is rolled back with FORCE.
+		tk.MustExec(c.flashbackSQL + " force")
+		require.Error(t, tk2.ExecToErr("commit"))
+		tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "2 2"))
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/util/filter"
+)
+
+// FlashbackPolicy decides how a system table is handled by the flashback cluster job.
+type FlashbackPolicy int
+
+const (
+	// FlashbackPolicySkip means the table keeps its current data, its key range isn't flashed back.
+	FlashbackPolicySkip FlashbackPolicy = iota
+	// FlashbackPolicyFlashback means the table is flashed back with the user tables.
+	FlashbackPolicyFlashback
+	// FlashbackPolicyForbid means the cluster can't be flashed back at all while the table exists.
+	FlashbackPolicyForbid
+)
+
+// String implements fmt.Stringer interface.
+func (p FlashbackPolicy) String() string {
+	switch p {
+	case FlashbackPolicySkip:
+		return "skip"
+	case FlashbackPolicyFlashback:
+		return "flashback"
+	case FlashbackPolicyForbid:
+		return "forbid"
+	}
+	return "unknown"
+}
+
+// systemTableFlashbackPolicies is the flashback policy of the tables in the mysql schema. Every table created by the
+// bootstrap must be registered here, so adding a system table forces an explicit decision on how it's flashed back.
+// The unregistered tables are skipped.
+var systemTableFlashbackPolicies = map[string]FlashbackPolicy{
+	// The privilege tables.
+	"user":             FlashbackPolicySkip,
+	"global_priv":      FlashbackPolicySkip,
+	"db":               FlashbackPolicySkip,
+	"tables_priv":      FlashbackPolicySkip,
+	"columns_priv":     FlashbackPolicySkip,
+	"role_edges":       FlashbackPolicySkip,
+	"default_roles":    FlashbackPolicySkip,
+	"global_grants":    FlashbackPolicySkip,
+	"global_variables": FlashbackPolicySkip,
+	"tidb":             FlashbackPolicySkip,
+	"help_topic":       FlashbackPolicySkip,
+	// The stats tables are flashed back to keep them consistent with the data of the user tables.
+	"stats_meta":         FlashbackPolicyFlashback,
+	"stats_histograms":   FlashbackPolicyFlashback,
+	"stats_buckets":      FlashbackPolicyFlashback,
+	"stats_feedback":     FlashbackPolicyFlashback,
+	"stats_top_n":        FlashbackPolicyFlashback,
+	"stats_fm_sketch":    FlashbackPolicyFlashback,
+	"stats_extended":     FlashbackPolicyFlashback,
+	"stats_history":      FlashbackPolicyFlashback,
+	"stats_meta_history": FlashbackPolicyFlashback,
+	"column_stats_usage": FlashbackPolicySkip,
+	"analyze_options":    FlashbackPolicySkip,
+	"analyze_jobs":       FlashbackPolicySkip,
+	// The GC meta tables.
+	"gc_delete_range":      FlashbackPolicySkip,
+	"gc_delete_range_done": FlashbackPolicySkip,
+	// The DDL job tables.
+	"tidb_ddl_job":     FlashbackPolicySkip,
+	"tidb_ddl_reorg":   FlashbackPolicySkip,
+	"tidb_ddl_history": FlashbackPolicySkip,
+	// The tables written by the flashback jobs themselves.
+	"tidb_flashback_checkpoint": FlashbackPolicySkip,
+	"flashback_watermark":       FlashbackPolicySkip,
+	"schema_rollback_log":       FlashbackPolicySkip,
+	"admin_operation_log":       FlashbackPolicySkip,
+	// The others.
+	"bind_info":                        FlashbackPolicySkip,
+	"expr_pushdown_blacklist":          FlashbackPolicySkip,
+	"opt_rule_blacklist":               FlashbackPolicySkip,
+	"capture_plan_baselines_blacklist": FlashbackPolicySkip,
+	"schema_index_usage":               FlashbackPolicySkip,
+	"table_cache_meta":                 FlashbackPolicySkip,
+	"advisory_locks":                   FlashbackPolicySkip,
+}
+
+// GetSystemTableFlashbackPolicy returns the flashback policy of the table in the mysql schema, the second return value
+// is false if the table isn't registered.
+func GetSystemTableFlashbackPolicy(tableName string) (FlashbackPolicy, bool) {
+	policy, ok := systemTableFlashbackPolicies[strings.ToLower(tableName)]
+	return policy, ok
+}
+
+// isFlashbackExcluded returns whether the key range of the table isn't flashed back. The tables in the system schemas
+// are excluded unless they're registered with FlashbackPolicyFlashback.
+func isFlashbackExcluded(schema string, tableName string) bool {
+	if !filter.IsSystemSchema(schema) {
+		return false
+	}
+	if !strings.EqualFold(schema, mysql.SystemDB) {
+		return true
+	}
+	policy, _ := GetSystemTableFlashbackPolicy(tableName)
+	return policy != FlashbackPolicyFlashback
+}
+
+// checkFlashbackForbiddenTables returns an error if a table registered with FlashbackPolicyForbid exists.
+func checkFlashbackForbiddenTables(is infoschema.InfoSchema) error {
+	db, ok := is.SchemaByName(model.NewCIStr(mysql.SystemDB))
+	if !ok {
+		return nil
+	}
+	for _, tbl := range db.Tables {
+		if policy, _ := GetSystemTableFlashbackPolicy(tbl.Name.L); policy == FlashbackPolicyForbid {
+			return errors.Errorf("the system table %s.%s forbids flashback, can't do flashback", mysql.SystemDB, tbl.Name.O)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/util/gcutil"
+	"github.com/pingcap/tidb/util/mathutil"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// The names of the prerequisite checks of flashback cluster.
+const (
+	FlashbackCheckGCSafePoint      = "gc_safe_point"
+	FlashbackCheckMinResolvedTS    = "min_resolved_ts"
+	FlashbackCheckDDLHistory       = "ddl_history"
+	FlashbackCheckDDLJobs          = "ddl_jobs"
+	FlashbackCheckStoreVersions    = "store_versions"
+	FlashbackCheckLongTransactions = "long_transactions"
+)
+
+// FlashbackReadinessCheck is the result of a prerequisite check of flashback cluster.
+type FlashbackReadinessCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// CheckFlashbackClusterStores returns the stores of the cluster, and returns the error if flashback cluster isn't
+// supported by the stores.
+func CheckFlashbackClusterStores(sctx sessionctx.Context) ([]infoschema.ServerInfo, error) {
+	stores, err := infoschema.GetStoreServerInfo(sctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		if store.ServerType == kv.TiFlash.Name() {
+			return stores, errors.Errorf("not support flash back cluster with TiFlash stores")
+		}
+	}
+	return stores, nil
+}
+
+// CheckFlashbackClusterReadiness runs the prerequisite checks of flashing back the cluster to flashbackTS without
+// changing anything. The checks which are also done by the flashback cluster statement and job share the code with
+// them, so a failed check here means the flashback fails. The min resolved TS and the long transactions checks are
+// advisory, the flashback waits for them or fails late instead. sysSess runs the internal SQL, and sctx is the
+// session querying the checks, whose own transaction isn't counted as a long transaction.
+func CheckFlashbackClusterReadiness(ctx context.Context, sctx, sysSess sessionctx.Context, flashbackTS uint64) ([]FlashbackReadinessCheck, error) {
+	checks := make([]FlashbackReadinessCheck, 0, 6)
+	addCheck := func(name string, err error, detail string) {
+		if err != nil {
+			detail = err.Error()
+		}
+		checks = append(checks, FlashbackReadinessCheck{Name: name, Passed: err == nil, Detail: detail})
+	}
+
+	gcSafePoint, err := checkFlashbackGCSafePoint(sysSess, flashbackTS)
+	detail := "the GC safe point is unknown, GC has never run"
+	if gcSafePoint != 0 {
+		detail = fmt.Sprintf("the flashback TS is %s after the GC safe point %d",
+			oracle.GetTimeFromTS(flashbackTS).Sub(oracle.GetTimeFromTS(gcSafePoint)), gcSafePoint)
+	}
+	addCheck(FlashbackCheckGCSafePoint, err, detail)
+
+	err = checkFlashbackTSNotFuture(ctx, sysSess, flashbackTS)
+	minResolvedTS := getFlashbackMinResolvedTS(sctx.GetStore())
+	detail = "the min resolved TS is unknown"
+	if minResolvedTS != 0 {
+		detail = fmt.Sprintf("the flashback TS is not after the min resolved TS %d", minResolvedTS)
+		if err == nil && flashbackTS > minResolvedTS {
+			err = errors.Errorf("the flashback TS is after the min resolved TS %d", minResolvedTS)
+		}
+	}
+	addCheck(FlashbackCheckMinResolvedTS, err, detail)
+
+	var jobsErr error
+	err = kv.RunInNewTxn(ctx, sysSess.GetStore(), false, func(ctx context.Context, txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		addCheck(FlashbackCheckDDLHistory, checkFlashbackSchemaVersion(sysSess.GetStore(), t, flashbackTS),
+			"no DDL has been done since the flashback TS")
+		jobs, err := getAllDDLJobs(ctx, sysSess, t)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		jobsErr = checkFlashbackNoOtherJobs(jobs, 0)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	addCheck(FlashbackCheckDDLJobs, jobsErr, "no DDL job is in queue")
+
+	stores, err := CheckFlashbackClusterStores(sysSess)
+	versions := make([]string, 0, len(stores))
+	for _, store := range stores {
+		versions = append(versions, fmt.Sprintf("%s %s %s", store.ServerType, store.Address, store.Version))
+	}
+	addCheck(FlashbackCheckStoreVersions, err, strings.Join(versions, ", "))
+
+	addCheck(FlashbackCheckLongTransactions, checkFlashbackLongTxns(sctx), "no transaction is running")
+	return checks, nil
+}
+
+// checkFlashbackLongTxns returns the error if there are the transactions running in the other sessions of this
+// instance, which hold the locks and block the flashback.
+func checkFlashbackLongTxns(sctx sessionctx.Context) error {
+	sm := sctx.GetSessionManager()
+	if sm == nil {
+		return nil
+	}
+	var count int
+	var oldestStartTS, oldestConnID uint64
+	for _, txn := range sm.ShowTxnList() {
+		if txn.ConnectionID == sctx.GetSessionVars().ConnectionID {
+			continue
+		}
+		count++
+		if oldestStartTS == 0 || txn.StartTS < oldestStartTS {
+			oldestStartTS, oldestConnID = txn.StartTS, txn.ConnectionID
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return errors.Errorf("%d transactions are running, the oldest one (conn: %d, start_ts: %d) has run %s", count,
+		oldestConnID, oldestStartTS, time.Since(oracle.GetTimeFromTS(oldestStartTS)).Round(time.Second))
+}
+
+// getFlashbackMinResolvedTS returns the min resolved TS of the cluster, it's 0 if it's unknown.
+func getFlashbackMinResolvedTS(store kv.Storage) uint64 {
+	minResolvedTS := store.GetMinSafeTS(oracle.GlobalTxnScope)
+	failpoint.Inject("mockFlashbackMinResolvedTS", func(val failpoint.Value) {
+		minResolvedTS = uint64(val.(int))
+	})
+	return minResolvedTS
+}
+
+// GetFlashbackWindow returns the oldest timestamp which flashback cluster accepts now, i.e. it isn't before the GC
+// safe point, and no DDL has been done since it. ok is false if flashback cluster is impossible now, e.g. there are
+// DDL jobs in queue, or the last DDL is done after the min resolved TS.
+func GetFlashbackWindow(ctx context.Context, sess sessionctx.Context) (oldestTS uint64, ok bool, err error) {
+	store := sess.GetStore()
+	currentVer, err := store.CurrentVersion(oracle.GlobalTxnScope)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	upperTS := currentVer.Ver
+	if minResolvedTS := getFlashbackMinResolvedTS(store); minResolvedTS != 0 && minResolvedTS < upperTS {
+		upperTS = minResolvedTS
+	}
+	gcSafePoint, _, err := gcutil.GetGCSafePointIfExists(sess)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	err = kv.RunInNewTxn(ctx, store, false, func(ctx context.Context, txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		jobs, err := getAllDDLJobs(ctx, sess, t)
+		if err != nil || len(jobs) > 0 {
+			return errors.Trace(err)
+		}
+		if gcSafePoint > upperTS {
+			return nil
+		}
+		// The schema version is usually bumped by the last DDL job, so the search starts from its finished TS.
+		hintTS := gcSafePoint
+		historyJobs, err := GetLastNHistoryDDLJobs(t, 1)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(historyJobs) > 0 && historyJobs[0].BinlogInfo != nil {
+			hintTS = mathutil.Max(historyJobs[0].BinlogInfo.FinishedTS, gcSafePoint)
+		}
+		oldestTS, ok, err = searchFlashbackSchemaVersionTS(store, t, gcSafePoint, mathutil.Min(hintTS, upperTS), upperTS)
+		return err
+	})
+	return oldestTS, ok, err
+}
+
+// searchFlashbackSchemaVersionTS returns the oldest timestamp in [lowerTS, upperTS] which passes
+// checkFlashbackSchemaVersion. The schema version at a timestamp only grows with it, so the timestamp is searched by
+// galloping from hintTS and then the binary search.
+func searchFlashbackSchemaVersionTS(store kv.Storage, t *meta.Meta, lowerTS, hintTS, upperTS uint64) (uint64, bool, error) {
+	nowSchemaVersion, err := t.GetSchemaVersion()
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	passed := func(ts uint64) (bool, error) {
+		schemaVersion, err := getSnapshotSchemaVersion(store, ts)
+		return schemaVersion == nowSchemaVersion, err
+	}
+	if ok, err := passed(upperTS); err != nil || !ok {
+		return 0, false, err
+	}
+	if ok, err := passed(lowerTS); err != nil || ok {
+		return lowerTS, ok, err
+	}
+	// The oldest timestamp is in (failedTS, passedTS].
+	failedTS, passedTS := lowerTS, upperTS
+	ok, err := passed(hintTS)
+	if err != nil {
+		return 0, false, err
+	}
+	// The steps start from a millisecond.
+	step := oracle.ComposeTS(1, 0)
+	if ok {
+		passedTS = hintTS
+		for ; hintTS-failedTS > step; step *= 2 {
+			if ok, err = passed(hintTS - step); err != nil {
+				return 0, false, err
+			}
+			if !ok {
+				failedTS = hintTS - step
+				break
+			}
+			passedTS = hintTS - step
+		}
+	} else {
+		failedTS = mathutil.Max(hintTS, failedTS)
+		for ; passedTS-hintTS > step; step *= 2 {
+			if ok, err = passed(hintTS + step); err != nil {
+				return 0, false, err
+			}
+			if ok {
+				passedTS = hintTS + step
+				break
+			}
+			failedTS = hintTS + step
+		}
+	}
+	for passedTS-failedTS > 1 {
+		mid := failedTS + (passedTS-failedTS)/2
+		if ok, err = passed(mid); err != nil {
+			return 0, false, err
+		}
+		if ok {
+			passedTS = mid
+		} else {
+			failedTS = mid
+		}
+	}
+	return passedTS, true, nil
+}
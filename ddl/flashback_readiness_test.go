@@ -0,0 +1,125 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/session/txninfo"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func TestFlashbackReadiness(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	query := "select check_name, result from information_schema.flashback_readiness"
+	tk.MustQuery(query).Check(testkit.Rows())
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 set tidb_flashback_readiness_ts to the flashback timestamp before reading flashback_readiness"))
+
+	// All the checks pass before any DDL is done after the timestamp.
+	tk.MustExec(fmt.Sprintf("set @@tidb_flashback_readiness_ts = %d", ts))
+	tk.MustQuery(query).Check(testkit.Rows(
+		"gc_safe_point PASS",
+		"min_resolved_ts PASS",
+		"ddl_history PASS",
+		"ddl_jobs PASS",
+		"store_versions PASS",
+		"long_transactions PASS",
+	))
+	tk.MustQuery("select flashback_ts from information_schema.flashback_readiness where check_name = 'gc_safe_point'").
+		Check(testkit.Rows(fmt.Sprintf("%d", ts)))
+
+	// The DDL after the timestamp and the transactions of other sessions fail the checks.
+	tk.MustExec("alter table t add column b int")
+	tk.Session().SetSessionManager(&testutil.MockSessionManager{TxnInfo: []*txninfo.TxnInfo{
+		{StartTS: ts, ConnectionID: tk.Session().GetSessionVars().ConnectionID},
+		{StartTS: ts, ConnectionID: 100},
+	}})
+	tk.MustQuery(query + " where result = 'FAIL'").Check(testkit.Rows("ddl_history FAIL", "long_transactions FAIL"))
+	tk.MustQuery("select detail from information_schema.flashback_readiness where check_name = 'ddl_history'").
+		Check(testkit.Rows("schema version not same, have done ddl during [flashbackTS, now)"))
+	rows := tk.MustQuery("select detail from information_schema.flashback_readiness where check_name = 'long_transactions'").Rows()
+	require.Contains(t, rows[0][0], fmt.Sprintf("1 transactions are running, the oldest one (conn: 100, start_ts: %d)", ts))
+
+	// The timestamps before the GC safe point or in the future fail the checks.
+	tk.MustExec(fmt.Sprintf("set @@tidb_flashback_readiness_ts = '%s'", time.Now().Add(-72*time.Hour).Format("2006-01-02 15:04:05")))
+	tk.MustQuery(query + " where check_name = 'gc_safe_point'").Check(testkit.Rows("gc_safe_point FAIL"))
+	tk.MustExec(fmt.Sprintf("set @@tidb_flashback_readiness_ts = '%s'", time.Now().Add(time.Hour).Format("2006-01-02 15:04:05")))
+	tk.MustQuery(query + " where check_name = 'min_resolved_ts'").Check(testkit.Rows("min_resolved_ts FAIL"))
+}
+
+func TestFlashbackWindow(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	getWindow := func() string {
+		rows := tk.MustQuery("show status like 'tidb_flashback_window'").Rows()
+		require.Len(t, rows, 1)
+		return rows[0][1].(string)
+	}
+	checkDDLHistory := func(ts uint64) string {
+		tk.MustExec(fmt.Sprintf("set @@tidb_flashback_readiness_ts = %d", ts))
+		return tk.MustQuery("select result from information_schema.flashback_readiness where check_name = 'ddl_history'").Rows()[0][0].(string)
+	}
+
+	// The window of the fresh cluster starts after the DDLs of the bootstrap, since GC has never run.
+	window, err := strconv.ParseUint(getWindow(), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, "PASS", checkDDLHistory(window))
+	require.Equal(t, "FAIL", checkDDLHistory(window-1))
+
+	// The window starts after the recent DDL.
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	tk.MustExec("create table t (a int)")
+	window, err = strconv.ParseUint(getWindow(), 10, 64)
+	require.NoError(t, err)
+	require.Greater(t, window, ts)
+	require.Equal(t, "PASS", checkDDLHistory(window))
+	require.Equal(t, "FAIL", checkDDLHistory(window-1))
+
+	// The window is bounded by the GC safe point.
+	timeBeforeDrop, timeAfterDrop, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	require.Equal(t, strconv.FormatUint(window, 10), getWindow())
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeAfterDrop))
+	require.Equal(t, "<nil>", getWindow())
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	// Flashback is impossible if the min resolved TS is before the recent DDL.
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockFlashbackMinResolvedTS", fmt.Sprintf("return(%d)", ts)))
+	require.Equal(t, "<nil>", getWindow())
+	require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockFlashbackMinResolvedTS"))
+	require.Equal(t, strconv.FormatUint(window, 10), getWindow())
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/tidb/ddl/placement"
+	"github.com/pingcap/tidb/domain/infosync"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/store/helper"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	// flashbackScatterMinStores is the min number of the TiKV stores for the flashback to scatter the regions, the
+	// rewrite traffic can't be distributed much in the smaller clusters.
+	flashbackScatterMinStores = 3
+	// flashbackScatterSizeThreshold is the approximate size in MiB of a flashback range, above which the regions of
+	// the range are scattered.
+	flashbackScatterSizeThreshold int64 = 1024
+	// flashbackScatterTimeBudget is the max time spent on scattering the regions, the flashback goes on with the
+	// ranges left unscattered when it runs out.
+	flashbackScatterTimeBudget = 30 * time.Second
+)
+
+// flashbackHeavyRange is a flashback range whose approximate size is above flashbackScatterSizeThreshold.
+type flashbackHeavyRange struct {
+	kv.KeyRange
+	size int64
+}
+
+// countUpTiKVStores returns the number of the TiKV stores which are up, the TiFlash stores aren't counted.
+func countUpTiKVStores(stores *helper.StoresStat) int {
+	count := 0
+	for _, store := range stores.Stores {
+		if store.Store.StateName != "Up" {
+			continue
+		}
+		isTiFlash := false
+		for _, label := range store.Store.Labels {
+			if label.Key == placement.EngineLabelKey && label.Value == placement.EngineLabelTiFlash {
+				isTiFlash = true
+				break
+			}
+		}
+		if !isTiFlash {
+			count++
+		}
+	}
+	return count
+}
+
+func getFlashbackRangeRegionsInfo(ctx context.Context, r kv.KeyRange) (*helper.RegionsInfo, error) {
+	return infosync.GetRegionsInfoByRange(ctx, codec.EncodeBytes(nil, r.StartKey), codec.EncodeBytes(nil, r.EndKey))
+}
+
+func getFlashbackRangeRegions(ctx context.Context, r kv.KeyRange) ([]uint64, error) {
+	regionsInfo, err := getFlashbackRangeRegionsInfo(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	regionIDs := make([]uint64, 0, len(regionsInfo.Regions))
+	for _, region := range regionsInfo.Regions {
+		regionIDs = append(regionIDs, uint64(region.ID))
+	}
+	return regionIDs, nil
+}
+
+// getFlashbackHeavyRanges returns the flashback ranges above flashbackScatterSizeThreshold with their sizes, the
+// heaviest range comes first. The ranges whose regions can't be got from PD are skipped.
+func getFlashbackHeavyRanges(ctx context.Context, job *model.Job, keyRanges []kv.KeyRange) []flashbackHeavyRange {
+	var heavyRanges []flashbackHeavyRange
+	for _, r := range keyRanges {
+		if ctx.Err() != nil {
+			break
+		}
+		regionsInfo, err := getFlashbackRangeRegionsInfo(ctx, r)
+		if err != nil {
+			logutil.BgLogger().Warn("[ddl] get the regions of the flashback range failed, skip it", zap.Int64("jobID", job.ID),
+				zap.Stringer("startKey", r.StartKey), zap.Stringer("endKey", r.EndKey), zap.Error(err))
+			continue
+		}
+		heavy := flashbackHeavyRange{KeyRange: r}
+		for _, region := range regionsInfo.Regions {
+			heavy.size += region.ApproximateSize
+		}
+		if heavy.size >= flashbackScatterSizeThreshold {
+			heavyRanges = append(heavyRanges, heavy)
+		}
+	}
+	slices.SortStableFunc(heavyRanges, func(i, j flashbackHeavyRange) bool {
+		return i.size > j.size
+	})
+	return heavyRanges
+}
+
+// scatterFlashbackRanges splits and scatters the regions of the heavy flashback ranges while the PD schedule is still
+// on, so that the rewrite traffic of the flashback is distributed to the stores. It's best-effort, the failures are
+// only logged, and it returns after flashbackScatterTimeBudget even if some heavy ranges are left.
+func scatterFlashbackRanges(ctx context.Context, store kv.Storage, job *model.Job, keyRanges []kv.KeyRange) {
+	ctx, cancel := context.WithTimeout(ctx, flashbackScatterTimeBudget)
+	defer cancel()
+
+	stores, err := infosync.GetStoresStat(ctx)
+	if err != nil {
+		logutil.BgLogger().Warn("[ddl] get the stores failed, skip scattering the flashback ranges", zap.Int64("jobID", job.ID), zap.Error(err))
+		return
+	}
+	if count := countUpTiKVStores(stores); count < flashbackScatterMinStores {
+		logutil.BgLogger().Info("[ddl] too few TiKV stores, skip scattering the flashback ranges", zap.Int64("jobID", job.ID),
+			zap.Int("stores", count), zap.Int("minStores", flashbackScatterMinStores))
+		return
+	}
+
+	heavyRanges := getFlashbackHeavyRanges(ctx, job, keyRanges)
+	scattered := 0
+	for _, r := range heavyRanges {
+		if ctx.Err() != nil {
+			logutil.BgLogger().Warn("[ddl] scattering the flashback ranges runs out of the time budget, go on", zap.Int64("jobID", job.ID),
+				zap.Int("scattered", scattered), zap.Int("heavyRanges", len(heavyRanges)), zap.Duration("budget", flashbackScatterTimeBudget))
+			return
+		}
+		// Split at the bounds of the range, so the regions shared with the neighbour ranges aren't moved with it.
+		if s, ok := store.(kv.SplittableStore); ok {
+			if _, err := s.SplitRegions(ctx, [][]byte{r.StartKey, r.EndKey}, false, nil); err != nil {
+				logutil.BgLogger().Warn("[ddl] split the bounds of the flashback range failed", zap.Int64("jobID", job.ID),
+					zap.Stringer("startKey", r.StartKey), zap.Stringer("endKey", r.EndKey), zap.Error(err))
+			}
+		}
+		// The regions are got again since the split changes them.
+		regionIDs, err := getFlashbackRangeRegions(ctx, r.KeyRange)
+		if err == nil {
+			err = infosync.ScatterRegions(ctx, regionIDs)
+		}
+		if err != nil {
+			logutil.BgLogger().Warn("[ddl] scatter the regions of the flashback range failed", zap.Int64("jobID", job.ID),
+				zap.Stringer("startKey", r.StartKey), zap.Stringer("endKey", r.EndKey), zap.Error(err))
+			continue
+		}
+		scattered++
+		logutil.BgLogger().Info("[ddl] scatter the regions of the flashback range", zap.Int64("jobID", job.ID),
+			zap.Stringer("startKey", r.StartKey), zap.Stringer("endKey", r.EndKey), zap.Int64("sizeMiB", r.size),
+			zap.Int("regions", len(regionIDs)))
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/domain/infosync"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/store/helper"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func mockRegionKey(tableID int64) string {
+	return strings.ToUpper(hex.EncodeToString(codec.EncodeBytes(nil, tablecodec.EncodeTablePrefix(tableID))))
+}
+
+func mockStores(tikv, tiflash int) []helper.StoreStat {
+	var stores []helper.StoreStat
+	for i := 0; i < tikv+tiflash; i++ {
+		store := helper.StoreStat{Store: helper.StoreBaseStat{ID: int64(i + 1), StateName: "Up"}}
+		if i >= tikv {
+			store.Store.Labels = []helper.StoreLabel{{Key: "engine", Value: "tiflash"}}
+		}
+		stores = append(stores, store)
+	}
+	return stores
+}
+
+func TestFlashbackClusterScatterRegions(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int)")
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	flashbackSQL := func() string {
+		ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+		require.NoError(t, err)
+		return fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts))
+	}
+	restore := ddl.SetFlashbackScatterThresholds(3, 100)
+	defer restore()
+	defer infosync.SetMockRegions(nil, nil)
+
+	is := dom.InfoSchema()
+	t1, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t1"))
+	require.NoError(t, err)
+	t2, err := is.TableByName(model.NewCIStr("test"), model.NewCIStr("t2"))
+	require.NoError(t, err)
+	mockRegions := func(size1, size2 int64) []helper.RegionInfo {
+		return []helper.RegionInfo{
+			{ID: 1, StartKey: mockRegionKey(t1.Meta().ID), EndKey: mockRegionKey(t2.Meta().ID), ApproximateSize: size1},
+			{ID: 2, StartKey: mockRegionKey(t2.Meta().ID), EndKey: mockRegionKey(t2.Meta().ID + 1), ApproximateSize: size2},
+		}
+	}
+
+	// The regions aren't scattered by default.
+	infosync.SetMockRegions(mockRegions(2000, 10), mockStores(3, 0))
+	tk.MustExec(flashbackSQL())
+	require.Empty(t, infosync.GetMockScatteredRegions())
+
+	// The regions of the range above the threshold are scattered.
+	tk.MustExec("set @@tidb_flashback_scatter_regions = on")
+	tk.MustExec(flashbackSQL())
+	require.ElementsMatch(t, []uint64{1, 2}, infosync.GetMockScatteredRegions())
+	jobID := tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string)
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job args %s", jobID)).Rows()
+	require.Equal(t, []interface{}{"scatter_regions", "true"}, rows[len(rows)-1][2:])
+
+	// The ranges below the threshold aren't scattered.
+	infosync.SetMockRegions(mockRegions(20, 10), mockStores(3, 0))
+	tk.MustExec(flashbackSQL())
+	require.Empty(t, infosync.GetMockScatteredRegions())
+
+	// The small clusters are skipped, the TiFlash stores aren't counted.
+	infosync.SetMockRegions(mockRegions(2000, 10), mockStores(2, 1))
+	tk.MustExec(flashbackSQL())
+	require.Empty(t, infosync.GetMockScatteredRegions())
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/gcutil"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// checkFlashbackClusterJobArgs returns the error if the args of the flashback cluster job can't be used by this
+// version, e.g. the job is submitted by an older version before the rolling upgrade. Such a job is retried forever,
+// because it's cancelled by onFlashbackCluster, but can't be finished by finishFlashbackCluster.
+func checkFlashbackClusterJobArgs(job *model.Job) error {
+	// Decode a copy, because DecodeArgs overwrites the args of the job.
+	flashbackTS, _, _, _, _, _, _, _, err := getFlashbackClusterArgs(&model.Job{RawArgs: job.RawArgs})
+	if err != nil {
+		return err
+	}
+	if flashbackTS == 0 && !job.IsCancelled() {
+		return errors.New("the flashback TS is missing")
+	}
+	return nil
+}
+
+// failStaleFlashbackJobs fails the flashback cluster jobs in the queue whose args can't be used by this version, and
+// returns their IDs. The external toggles recorded by the jobs are restored first if they have locked the cluster,
+// then the jobs are cancelled with the normalized args, so that the worker moves them to the history queue and
+// releases the cluster. It's called when the TiDB instance becomes the DDL owner.
+func (d *ddl) failStaleFlashbackJobs() ([]int64, error) {
+	se, err := d.sessPool.get()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer d.sessPool.put(se)
+
+	ctx := kv.WithInternalSourceType(d.ctx, kv.InternalTxnDDL)
+	var (
+		jobs           []*model.Job
+		flashbackJobID int64
+		pdSchedule     *meta.FlashbackPDSchedule
+	)
+	err = kv.RunInNewTxn(ctx, d.store, true, func(ctx context.Context, txn kv.Transaction) (err error) {
+		t := meta.NewMeta(txn)
+		if flashbackJobID, err = t.GetFlashbackClusterJobID(); err != nil {
+			return err
+		}
+		if pdSchedule, err = t.GetFlashbackPDSchedule(); err != nil {
+			return err
+		}
+		jobs, err = getAllDDLJobs(ctx, se, t)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var failedIDs []int64
+	for _, job := range jobs {
+		if job.Type != model.ActionFlashbackCluster {
+			continue
+		}
+		cause := checkFlashbackClusterJobArgs(job)
+		if cause == nil {
+			continue
+		}
+		logutil.BgLogger().Warn("[ddl] found the flashback cluster job with the incompatible args, fail it",
+			zap.Int64("jobID", job.ID), zap.String("state", job.State.String()),
+			zap.ByteString("rawArgs", job.RawArgs), zap.Error(cause))
+		metrics.DDLCounter.WithLabelValues(metrics.StaleFlashbackJob).Inc()
+		if job.ID == flashbackJobID {
+			if err = restoreStaleFlashbackExternals(se, job, pdSchedule); err != nil {
+				return failedIDs, errors.Trace(err)
+			}
+		}
+		// The externals are restored, nothing is left for finishFlashbackCluster to restore.
+		job.Args = []interface{}{getStaleFlashbackTS(job), nil, uint64(0), variable.DefTiDBFlashbackBatchSize, false,
+			time.Duration(0), false, false}
+		job.State = model.JobStateCancelled
+		job.Error = toTError(errors.Errorf("the args of the flashback cluster job %d can't be used by this version of TiDB, it may be submitted before the upgrade: %v", job.ID, cause))
+		if err = updateStaleFlashbackJob(ctx, se, job); err != nil {
+			return failedIDs, errors.Trace(err)
+		}
+		failedIDs = append(failedIDs, job.ID)
+	}
+	if len(failedIDs) > 0 {
+		interruptRunningJobs(d.store, failedIDs)
+		asyncNotify(d.ddlJobCh)
+	}
+	return failedIDs, nil
+}
+
+// restoreStaleFlashbackExternals restores the external toggles recorded by the stale flashback job which has locked
+// the cluster. The PD schedule is restored from the record in the meta, or from the args if the older version doesn't
+// record it in the meta. The GC is enabled if the args record that it's changed by the job.
+func restoreStaleFlashbackExternals(sctx sessionctx.Context, job *model.Job, record *meta.FlashbackPDSchedule) error {
+	var rawArgs []json.RawMessage
+	// The args may be partially decodable, restore what can be decoded.
+	_ = json.Unmarshal(job.RawArgs, &rawArgs)
+	var pdScheduleValue map[string]interface{}
+	if record != nil && record.JobID == job.ID {
+		pdScheduleValue = record.PDSchedule
+	} else if len(rawArgs) > 1 {
+		_ = json.Unmarshal(rawArgs[1], &pdScheduleValue)
+	}
+	if len(pdScheduleValue) > 0 {
+		if err := recoverPDSchedule(pdScheduleValue); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(rawArgs) > 2 && isGCChangedByFlashbackArg(rawArgs[2]) {
+		if err := gcutil.EnableGC(sctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// isGCChangedByFlashbackArg returns whether the third arg of the flashback cluster job records that the GC is changed
+// by the job. The older versions record whether the GC is enabled before the job as a bool, and this version records
+// the changed externals as a bitmask.
+func isGCChangedByFlashbackArg(arg json.RawMessage) bool {
+	var gcEnabled bool
+	if json.Unmarshal(arg, &gcEnabled) == nil {
+		return gcEnabled
+	}
+	var changed uint64
+	if json.Unmarshal(arg, &changed) == nil {
+		return changed&flashbackChangedGC != 0
+	}
+	return false
+}
+
+// getStaleFlashbackTS returns the flashback TS in the args of the stale flashback job, or 0 if it can't be decoded.
+func getStaleFlashbackTS(job *model.Job) (flashbackTS uint64) {
+	var rawArgs []json.RawMessage
+	if json.Unmarshal(job.RawArgs, &rawArgs) == nil && len(rawArgs) > 0 {
+		_ = json.Unmarshal(rawArgs[0], &flashbackTS)
+	}
+	return flashbackTS
+}
+
+// updateStaleFlashbackJob updates the stale flashback job in the queue.
+func updateStaleFlashbackJob(ctx context.Context, se sessionctx.Context, job *model.Job) error {
+	if variable.EnableConcurrentDDL.Load() {
+		sess := newSession(se)
+		if err := sess.begin(); err != nil {
+			return err
+		}
+		if err := updateDDLJob2Table(sess, job, true); err != nil {
+			sess.rollback()
+			return err
+		}
+		return sess.commit()
+	}
+
+	return kv.RunInNewTxn(ctx, se.GetStore(), true, func(ctx context.Context, txn kv.Transaction) error {
+		t := meta.NewMeta(txn)
+		for _, key := range []meta.JobListKeyType{meta.DefaultJobListKey, meta.AddIndexJobListKey} {
+			jobs, err := getDDLJobsInQueue(t, key)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			for i, j := range jobs {
+				if j.ID == job.ID {
+					return t.UpdateDDLJob(int64(i), job, true, key)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// recordFlashbackRangeStats adds the counters returned by the flashback request of the range to the reorg meta of
+// the job. The counters of the range are missing once any request of it doesn't return them.
+func recordFlashbackRangeStats(job *model.Job, idx int, stats *kv.FlashbackStats) {
+	if job.ReorgMeta == nil {
+		job.ReorgMeta = &model.DDLReorgMeta{}
+	}
+	for len(job.ReorgMeta.FlashbackRanges) <= idx {
+		job.ReorgMeta.FlashbackRanges = append(job.ReorgMeta.FlashbackRanges, nil)
+	}
+	r := job.ReorgMeta.FlashbackRanges[idx]
+	if r == nil {
+		r = &model.FlashbackRangeStats{}
+		if stats != nil {
+			r.KeysProcessed, r.VersionsWritten = new(uint64), new(uint64)
+		}
+		job.ReorgMeta.FlashbackRanges[idx] = r
+	}
+	if stats == nil {
+		r.KeysProcessed, r.VersionsWritten = nil, nil
+		return
+	}
+	if r.KeysProcessed != nil && r.VersionsWritten != nil {
+		*r.KeysProcessed += stats.KeysProcessed
+		*r.VersionsWritten += stats.VersionsWritten
+	}
+}
+
+// flashbackTotals returns the total counters of the key ranges flashed back by the job, see
+// model.DDLReorgMeta.FlashbackTotals.
+func flashbackTotals(job *model.Job) (keysProcessed, versionsWritten *uint64) {
+	if job.ReorgMeta == nil {
+		return nil, nil
+	}
+	return job.ReorgMeta.FlashbackTotals()
+}
+
+// logFlashbackStats logs the summary of the counters of the key ranges flashed back by the job.
+func logFlashbackStats(logger *jobLogger, job *model.Job) {
+	keys, versions := flashbackTotals(job)
+	var ranges int
+	if job.ReorgMeta != nil {
+		ranges = len(job.ReorgMeta.FlashbackRanges)
+	}
+	logger.Info("flashback ranges summary", zap.Int("ranges", ranges),
+		zap.String("keysProcessed", formatFlashbackCounter(keys)), zap.String("versionsWritten", formatFlashbackCounter(versions)))
+}
+
+// setFlashbackStatsMessage sets the total counters of the finished flashback job as the info of the OK packet of the
+// statement. The failure to read the job is only logged.
+func (d *ddl) setFlashbackStatsMessage(sctx sessionctx.Context, jobID int64) {
+	se, err := d.sessPool.get()
+	if err != nil {
+		logutil.BgLogger().Warn("[ddl] get the flashback stats failed", zap.Int64("jobID", jobID), zap.Error(err))
+		return
+	}
+	job, err := GetHistoryJobByID(se, jobID)
+	d.sessPool.put(se)
+	if err != nil || job == nil {
+		logutil.BgLogger().Warn("[ddl] get the flashback stats failed", zap.Int64("jobID", jobID), zap.Error(err))
+		return
+	}
+	sctx.GetSessionVars().StmtCtx.SetMessage(FlashbackStatsMessage(job))
+}
+
+// FlashbackStatsMessage returns the total counters of the flashback job, the missing counters are shown as NULL.
+func FlashbackStatsMessage(job *model.Job) string {
+	keys, versions := flashbackTotals(job)
+	return fmt.Sprintf("Keys processed: %s  Versions written: %s", formatFlashbackCounter(keys), formatFlashbackCounter(versions))
+}
+
+func formatFlashbackCounter(counter *uint64) string {
+	if counter == nil {
+		return "NULL"
+	}
+	return strconv.FormatUint(*counter, 10)
+}
@@ -0,0 +1,411 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/domain/infosync"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/gcutil"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+)
+
+// FlashbackTable is a table of the flashback tables job. The tables are recorded in the job args, and the physical
+// IDs are filled as the result of the table when the job is done.
+type FlashbackTable struct {
+	SchemaID   int64  `json:"schema_id"`
+	TableID    int64  `json:"table_id"`
+	SchemaName string `json:"schema_name"`
+	TableName  string `json:"table_name"`
+	// PhysicalIDs are the IDs of the table and its partitions which are flashed back.
+	PhysicalIDs []int64 `json:"physical_ids,omitempty"`
+}
+
+func (t *FlashbackTable) String() string {
+	return fmt.Sprintf("`%s`.`%s`", t.SchemaName, t.TableName)
+}
+
+func getFlashbackTablesArgs(job *model.Job) (flashbackTS uint64, pdScheduleValue map[string]interface{},
+	changedExternals uint64, tables []*FlashbackTable, force bool, err error) {
+	changedExternals = flashbackChangedAll
+	err = job.DecodeArgs(&flashbackTS, &pdScheduleValue, &changedExternals, &tables, &force)
+	return
+}
+
+// checkFlashbackTable checks whether the table can be flashed back.
+func checkFlashbackTable(tblInfo *model.TableInfo) error {
+	switch {
+	case !tblInfo.IsBaseTable():
+		return errors.Errorf("can't flashback the view or the sequence %s", tblInfo.Name)
+	case tblInfo.TempTableType != model.TempTableNone:
+		return errors.Errorf("can't flashback the temporary table %s", tblInfo.Name)
+	case tblInfo.TiFlashReplica != nil && tblInfo.TiFlashReplica.Count > 0:
+		return errors.Errorf("can't flashback the table %s with TiFlash replicas", tblInfo.Name)
+	}
+	return nil
+}
+
+// checkFlashbackTablesUnchanged checks that the tables exist at flashbackTS and no DDL has changed them since then.
+func checkFlashbackTablesUnchanged(store kv.Storage, t *meta.Meta, tables []*FlashbackTable, flashbackTS uint64) error {
+	snapMeta := meta.NewSnapshotMeta(store.GetSnapshot(kv.NewVersion(flashbackTS)))
+	for _, tbl := range tables {
+		tblInfo, err := getTableInfo(t, tbl.TableID, tbl.SchemaID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = checkFlashbackTable(tblInfo); err != nil {
+			return err
+		}
+		oldTblInfo, err := snapMeta.GetTable(tbl.SchemaID, tbl.TableID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if oldTblInfo == nil {
+			return errors.Errorf("table %s doesn't exist at the flashback timestamp", tbl)
+		}
+		if oldTblInfo.UpdateTS != tblInfo.UpdateTS {
+			return errors.Errorf("table %s has been changed by DDL during [flashbackTS, now)", tbl)
+		}
+	}
+	return nil
+}
+
+// checkOtherFlashbackJobs checks that there is neither a running flashback job nor a queueing DDL job on the
+// tables before the job.
+func checkOtherFlashbackJobs(jobs []*model.Job, job *model.Job, tables []*FlashbackTable) error {
+	tableIDs := make(map[int64]struct{}, len(tables))
+	schemaIDs := make(map[int64]struct{}, len(tables))
+	for _, tbl := range tables {
+		tableIDs[tbl.TableID] = struct{}{}
+		schemaIDs[tbl.SchemaID] = struct{}{}
+	}
+	for _, j := range jobs {
+		if j.ID >= job.ID {
+			continue
+		}
+		if j.Type == model.ActionFlashbackCluster || j.Type == model.ActionFlashbackTables {
+			return errors.Errorf("Other flashback job(ID: %d) is running", j.ID)
+		}
+		_, tableConflict := tableIDs[j.TableID]
+		_, schemaConflict := schemaIDs[j.SchemaID]
+		if tableConflict || (schemaConflict && j.Type == model.ActionDropSchema) {
+			return errors.Errorf("have other ddl jobs(jobID: %d) on the tables in queue, can't do flashback", j.ID)
+		}
+	}
+	return nil
+}
+
+// checkFlashbackTablesStarted checks that no other DDL job on the tables has started, the jobs which are started before
+// acquiring the barrier of the flashback tables job aren't blocked by it.
+func checkFlashbackTablesStarted(jobs []*model.Job, job *model.Job, tables []*FlashbackTable) error {
+	tableIDs := make(map[int64]struct{}, len(tables))
+	schemaIDs := make(map[int64]struct{}, len(tables))
+	for _, tbl := range tables {
+		tableIDs[tbl.TableID] = struct{}{}
+		schemaIDs[tbl.SchemaID] = struct{}{}
+	}
+	for _, j := range jobs {
+		if j.ID == job.ID || j.State == model.JobStateQueueing {
+			continue
+		}
+		_, tableConflict := tableIDs[j.TableID]
+		_, schemaConflict := schemaIDs[j.SchemaID]
+		if tableConflict || (schemaConflict && j.Type == model.ActionDropSchema) {
+			return errors.Errorf("have other running ddl jobs(jobID: %d) on the tables, can't do flashback", j.ID)
+		}
+	}
+	return nil
+}
+
+// acquireFlashbackTablesBarrier acquires the barrier of the flashback tables job in a new transaction, so the DDL jobs
+// on the tables are blocked since then, even if the transaction of the job is retried.
+func acquireFlashbackTablesBarrier(w *worker, t *meta.Meta, job *model.Job, tables []*FlashbackTable) error {
+	barrier, err := t.GetFlashbackTablesBarrier()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if barrier != nil && barrier.JobID != job.ID {
+		return errors.Errorf("Other flashback job(ID: %d) is running", barrier.JobID)
+	}
+	flashbackJobID, err := t.GetFlashbackClusterJobID()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if flashbackJobID != 0 {
+		return errors.Errorf("Other flashback job(ID: %d) is running", flashbackJobID)
+	}
+	barrier = &meta.FlashbackTablesBarrier{JobID: job.ID}
+	for _, tbl := range tables {
+		barrier.SchemaIDs = append(barrier.SchemaIDs, tbl.SchemaID)
+		barrier.TableIDs = append(barrier.TableIDs, tbl.TableID)
+	}
+	return kv.RunInNewTxn(w.ctx, w.store, true, func(ctx context.Context, txn kv.Transaction) error {
+		return meta.NewMeta(txn).SetFlashbackTablesBarrier(barrier)
+	})
+}
+
+// checkFlashbackTablesBarrier cancels the DDL job which hasn't started if it's on the tables being flashed back.
+func checkFlashbackTablesBarrier(t *meta.Meta, job *model.Job) error {
+	if job.State != model.JobStateQueueing {
+		return nil
+	}
+	barrier, err := t.GetFlashbackTablesBarrier()
+	if err != nil || barrier == nil || barrier.JobID == job.ID {
+		return errors.Trace(err)
+	}
+	if job.Type == model.ActionFlashbackCluster || job.Type == model.ActionFlashbackTables {
+		return errors.Errorf("Other flashback job(ID: %d) is running", barrier.JobID)
+	}
+	if slices.Contains(barrier.TableIDs, job.TableID) ||
+		(job.Type == model.ActionDropSchema && slices.Contains(barrier.SchemaIDs, job.SchemaID)) {
+		return errors.Errorf("Can't do ddl job, table is flashing back now")
+	}
+	return nil
+}
+
+// GetFlashbackTablesKeyRanges returns the key ranges of the physical tables to flashback, the ranges of the adjacent
+// IDs are merged into one range.
+func GetFlashbackTablesKeyRanges(physicalIDs []int64) []kv.KeyRange {
+	ids := slices.Clone(physicalIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	var keyRanges []kv.KeyRange
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		keyRanges = append(keyRanges, kv.KeyRange{
+			StartKey: tablecodec.EncodeTablePrefix(ids[i]),
+			EndKey:   tablecodec.EncodeTablePrefix(ids[j] + 1),
+		})
+		i = j + 1
+	}
+	return keyRanges
+}
+
+// fillFlashbackTablesPhysicalIDs fills the physical IDs of the tables, and returns the physical IDs of all the tables.
+func fillFlashbackTablesPhysicalIDs(t *meta.Meta, tables []*FlashbackTable) ([]int64, error) {
+	var physicalIDs []int64
+	for _, tbl := range tables {
+		tblInfo, err := getTableInfo(t, tbl.TableID, tbl.SchemaID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tbl.PhysicalIDs = append(tbl.PhysicalIDs[:0], tblInfo.ID)
+		if tblInfo.Partition != nil {
+			for _, def := range tblInfo.Partition.Definitions {
+				tbl.PhysicalIDs = append(tbl.PhysicalIDs, def.ID)
+			}
+		}
+		physicalIDs = append(physicalIDs, tbl.PhysicalIDs...)
+	}
+	return physicalIDs, nil
+}
+
+// flashbackScheduleDenyRuleTTL is the TTL of the label rule which fences the flashback ranges from the PD schedule.
+// The rule is removed when the job finishes, the TTL only makes sure that the ranges are scheduled again if the rule
+// is left behind, e.g. the job is lost.
+var flashbackScheduleDenyRuleTTL = time.Hour
+
+// flashbackScheduleDenyRuleID returns the ID of the label rule put by the flashback tables job.
+func flashbackScheduleDenyRuleID(jobID int64) string {
+	return fmt.Sprintf("flashback/%d", jobID)
+}
+
+// addFlashbackScheduleDenyRule puts the label rule which denies PD to schedule the regions in the flashback ranges,
+// so only the regions of the tables stop being scheduled, and the rest of the cluster is balanced as usual.
+func addFlashbackScheduleDenyRule(w *worker, job *model.Job, keyRanges []kv.KeyRange) error {
+	ruleID := flashbackScheduleDenyRuleID(job.ID)
+	if err := infosync.AddKeyRangeScheduleDenyRule(w.jobContext(job).ctx, ruleID, keyRanges, flashbackScheduleDenyRuleTTL); err != nil {
+		return err
+	}
+	w.jobLogger(job).Info("fence the flashback ranges from the PD schedule",
+		zap.String("rule", ruleID), zap.Int("ranges", len(keyRanges)), zap.Duration("ttl", flashbackScheduleDenyRuleTTL))
+	return nil
+}
+
+// onFlashbackTables flashes back the tables in one job, so the PD schedule and the GC are changed only once.
+// It has the same stages as onFlashbackCluster, and all the tables are validated in the first stage before
+// changing anything, so the job fails as a whole if any table can't be flashed back.
+func (w *worker) onFlashbackTables(d *ddlCtx, t *meta.Meta, job *model.Job) (ver int64, err error) {
+	flashbackTS, _, _, tables, force, err := getFlashbackTablesArgs(job)
+	if err != nil {
+		job.State = model.JobStateCancelled
+		return ver, errors.Trace(err)
+	}
+
+	switch job.SchemaState {
+	// Stage 1, acquire the barrier, validate the tables and record the toggles to change.
+	case model.StateNone:
+		// Acquire the barrier before validating the tables, or the DDL jobs on the tables may run after the validation.
+		if err = acquireFlashbackTablesBarrier(w, t, job, tables); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, err
+		}
+		sess, err := w.sessPool.getForJob(job.ID, "flashback tables check")
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		defer w.sessPool.put(sess)
+		jobs, err := getAllDDLJobs(w.jobContext(job).ctx, sess, t)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		if err = checkOtherFlashbackJobs(jobs, job, tables); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, err
+		}
+		if err = checkFlashbackTablesUnchanged(d.store, t, tables, flashbackTS); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, err
+		}
+		gcEnabled, err := checkGCEnable(w)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		changed := flashbackChangedScheduleDenyRule
+		if gcEnabled {
+			changed |= flashbackChangedGC
+		}
+		setFlashbackChangedExternals(job, changed)
+		job.SchemaState = model.StateWriteOnly
+		return ver, nil
+	// Stage 2, recheck the tables, check flashbackTS, close GC, fence the flashback ranges from the PD schedule and
+	// resolve the locks in them.
+	case model.StateWriteOnly:
+		sess, err := w.sessPool.getForJob(job.ID, "flashback tables check")
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		defer w.sessPool.put(sess)
+		jobs, err := getAllDDLJobs(w.jobContext(job).ctx, sess, t)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		if err = checkFlashbackTablesStarted(jobs, job, tables); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, err
+		}
+		if err = checkFlashbackTablesUnchanged(d.store, t, tables, flashbackTS); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, err
+		}
+		if err = ValidateFlashbackTS(w.jobContext(job).ctx, sess, flashbackTS); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		if err = gcutil.DisableGC(sess); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		physicalIDs, err := fillFlashbackTablesPhysicalIDs(t, tables)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		keyRanges := GetFlashbackTablesKeyRanges(physicalIDs)
+		if err = addFlashbackScheduleDenyRule(w, job, keyRanges); err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		err = resolveFlashbackLocks(w.jobContext(job).ctx, d.store, keyRanges, job.StartTS, force)
+		if err != nil {
+			job.State = model.JobStateCancelled
+			return ver, errors.Trace(err)
+		}
+		job.SchemaState = model.StateWriteReorganization
+		return ver, nil
+	// Stage 3, get the merged key ranges of the tables and record the results.
+	case model.StateWriteReorganization:
+		physicalIDs, err := fillFlashbackTablesPhysicalIDs(t, tables)
+		if err != nil {
+			return ver, errors.Trace(err)
+		}
+		keyRanges := GetFlashbackTablesKeyRanges(physicalIDs)
+		// Renew the TTL of the rule, the stages may be delayed by the owner changes.
+		if err = addFlashbackScheduleDenyRule(w, job, keyRanges); err != nil {
+			return ver, errors.Trace(err)
+		}
+		logutil.Logger(w.logCtx).Info("[ddl] get the key ranges of the flashback tables",
+			zap.Int64("jobID", job.ID), zap.Int("tables", len(tables)), zap.Int("ranges", len(keyRanges)))
+
+		job.Args[3] = tables
+		job.State = model.JobStateDone
+		job.SchemaState = model.StatePublic
+		return ver, nil
+	}
+	return ver, nil
+}
+
+// finishFlashbackTables restores the external toggles changed by the flashback tables job and releases its barrier.
+// If the job succeeds, the coprocessor cache epoch is bumped.
+func finishFlashbackTables(w *worker, t *meta.Meta, job *model.Job) error {
+	_, pdScheduleValue, changedExternals, _, _, err := getFlashbackTablesArgs(job)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = restoreFlashbackExternals(w, job.ID, changedExternals, pdScheduleValue); err != nil {
+		return err
+	}
+	if err = clearFlashbackPDSchedule(t, job.ID); err != nil {
+		return err
+	}
+	if job.IsSynced() {
+		// The data is rewritten, the coprocessor caches populated before can't be used anymore.
+		if err = t.BumpCoprCacheEpoch(); err != nil {
+			return err
+		}
+	}
+	barrier, err := t.GetFlashbackTablesBarrier()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if barrier != nil && barrier.JobID == job.ID {
+		return errors.Trace(t.SetFlashbackTablesBarrier(nil))
+	}
+	return nil
+}
+
+// appendFlashbackTablesNotesToOwnerCtx reports the results of the flashback tables job as the notes of the statement.
+func appendFlashbackTablesNotesToOwnerCtx(ctx sessionctx.Context, job *model.Job) {
+	if job.Type != model.ActionFlashbackTables {
+		return
+	}
+	flashbackTS, _, _, tables, _, err := getFlashbackTablesArgs(job)
+	if err != nil {
+		return
+	}
+	for _, tbl := range tables {
+		ctx.GetSessionVars().StmtCtx.AppendNote(errors.Errorf("table %s is flashed back to '%s', %d physical tables in total",
+			tbl, oracle.GetTimeFromTS(flashbackTS), len(tbl.PhysicalIDs)))
+	}
+}
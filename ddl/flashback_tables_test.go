@@ -0,0 +1,285 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/ddl/label"
+	"github.com/pingcap/tidb/domain/infosync"
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/external"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/atomic"
+)
+
+func TestGetFlashbackTablesKeyRanges(t *testing.T) {
+	require.Len(t, ddl.GetFlashbackTablesKeyRanges(nil), 0)
+	require.Equal(t, []kv.KeyRange{
+		{StartKey: tablecodec.EncodeTablePrefix(3), EndKey: tablecodec.EncodeTablePrefix(6)},
+		{StartKey: tablecodec.EncodeTablePrefix(8), EndKey: tablecodec.EncodeTablePrefix(9)},
+		{StartKey: tablecodec.EncodeTablePrefix(10), EndKey: tablecodec.EncodeTablePrefix(12)},
+	}, ddl.GetFlashbackTablesKeyRanges([]int64{10, 5, 3, 8, 4, 11, 5}))
+}
+
+func TestFlashbackTables(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int) partition by hash(a) partitions 4")
+	tk.MustExec("create table t3 (a int)")
+	tk.MustExec("create view v as select * from t1")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tsStr := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+	// The timestamp is parsed from the string, so it's truncated to milliseconds.
+	flashbackTime := oracle.GetTimeFromTS(oracle.GoTimeToTS(oracle.GetTimeFromTS(ts).Truncate(time.Millisecond)))
+
+	tk.MustExec(fmt.Sprintf("flashback table t1, test.t2 to timestamp '%s'", tsStr))
+	tk.MustQuery("show warnings").Check(testkit.Rows(
+		fmt.Sprintf("Note 1105 table `test`.`t1` is flashed back to '%s', 1 physical tables in total", flashbackTime),
+		fmt.Sprintf("Note 1105 table `test`.`t2` is flashed back to '%s', 5 physical tables in total", flashbackTime),
+	))
+	rows := tk.MustQuery("admin show ddl jobs 1").Rows()
+	require.Len(t, rows, 1)
+	require.Equal(t, "flashback tables", rows[0][3])
+	require.Equal(t, "synced", rows[0][11])
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+
+	// The whole statement fails if any of the tables can't be flashed back, and no job is submitted.
+	tk.MustExec("alter table t3 add column b int")
+	for _, c := range []struct {
+		tables string
+		err    string
+	}{
+		{"t1, t2, t3", "table `test`.`t3` has been changed by DDL during [flashbackTS, now)"},
+		{"t1, t4", "[schema:1146]Table 'test.t4' doesn't exist"},
+		{"t1, v", "can't flashback the view or the sequence v"},
+		{"t1, t2, test.t1", "[schema:1066]Not unique table/alias: 't1'"},
+	} {
+		tk.MustGetErrMsg(fmt.Sprintf("flashback table %s to timestamp '%s'", c.tables, tsStr), c.err)
+		rows := tk.MustQuery("admin show ddl jobs 1").Rows()
+		require.Equal(t, "add column", rows[0][3])
+	}
+	tk.MustGetErrMsg(fmt.Sprintf("flashback table t1 to timestamp '%s'", time.Now().Add(time.Hour).Format("2006-01-02 15:04:05")),
+		"cannot set flashback timestamp to future time")
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+}
+
+func TestFlashbackTablesBarrier(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int)")
+	tbl1 := external.GetTableByName(t, tk, "test", "t1").Meta()
+	db, ok := dom.InfoSchema().SchemaByName(model.NewCIStr("test"))
+	require.True(t, ok)
+	setBarrier := func(b *meta.FlashbackTablesBarrier) {
+		err := kv.RunInNewTxn(kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL), store, true, func(ctx context.Context, txn kv.Transaction) error {
+			return meta.NewMeta(txn).SetFlashbackTablesBarrier(b)
+		})
+		require.NoError(t, err)
+	}
+	getBarrier := func() (b *meta.FlashbackTablesBarrier) {
+		err := kv.RunInNewTxn(kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL), store, true, func(ctx context.Context, txn kv.Transaction) (err error) {
+			b, err = meta.NewMeta(txn).GetFlashbackTablesBarrier()
+			return err
+		})
+		require.NoError(t, err)
+		return b
+	}
+
+	// The DDL jobs on the tables held by the barrier are cancelled, the others aren't affected.
+	setBarrier(&meta.FlashbackTablesBarrier{JobID: -1, SchemaIDs: []int64{db.ID}, TableIDs: []int64{tbl1.ID}})
+	tk.MustContainErrMsg("alter table t1 add column b int", "Can't do ddl job, table is flashing back now")
+	tk.MustContainErrMsg("drop database test", "Can't do ddl job, table is flashing back now")
+	tk.MustExec("alter table t2 add column b int")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tsStr := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+	tk.MustContainErrMsg(fmt.Sprintf("flashback table t2 to timestamp '%s'", tsStr), "Other flashback job(ID: -1) is running")
+	setBarrier(nil)
+
+	// The barrier is held by the job since the first stage, and released when the job finishes.
+	originHook := dom.DDL().GetHook()
+	defer dom.DDL().SetHook(originHook)
+	var heldBarriers []*meta.FlashbackTablesBarrier
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type == model.ActionFlashbackTables && job.SchemaState != model.StateNone {
+			heldBarriers = append(heldBarriers, getBarrier())
+		}
+	}
+	dom.DDL().SetHook(hook)
+	tk.MustExec(fmt.Sprintf("flashback table t1, t2 to timestamp '%s'", tsStr))
+	require.Len(t, heldBarriers, 2)
+	for _, b := range heldBarriers {
+		require.NotNil(t, b)
+		require.Equal(t, []int64{tbl1.ID, external.GetTableByName(t, tk, "test", "t2").Meta().ID}, b.TableIDs)
+	}
+	require.Nil(t, getBarrier())
+
+	// The barrier is released if the job is cancelled.
+	cancelHook := newCancelJobHook(t, store, dom, func(job *model.Job) bool {
+		return job.Type == model.ActionFlashbackTables && job.SchemaState == model.StateWriteOnly
+	})
+	dom.DDL().SetHook(cancelHook)
+	tk.MustGetErrCode(fmt.Sprintf("flashback table t1, t2 to timestamp '%s'", tsStr), errno.ErrCancelledDDLJob)
+	cancelHook.MustCancelDone(t)
+	require.Nil(t, getBarrier())
+	tk.MustExec("alter table t1 add column b int")
+}
+
+func TestFlashbackTablesRaceWithDDL(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tblID := external.GetTableByName(t, tk, "test", "t").Meta().ID
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+		require.NoError(t, err)
+		var wg sync.WaitGroup
+		stop := atomic.NewBool(false)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; !stop.Load(); j++ {
+				// The DDL may be cancelled by the flashback, ignore the error.
+				_ = tk2.ExecToErr(fmt.Sprintf("alter table t add column c%d_%d int", i, j))
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		err = tk.ExecToErr(fmt.Sprintf("flashback table t to timestamp '%s'", oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")))
+		stop.Store(true)
+		wg.Wait()
+		if err != nil {
+			continue
+		}
+		// The flashback succeeds, so no DDL on the table has finished during [flashbackTS, the end of the flashback).
+		requireNoDDLDuringFlashback(t, store, model.ActionFlashbackTables, ts, func(job *model.Job) bool {
+			return job.TableID == tblID
+		})
+	}
+}
+
+func TestFlashbackTablesScheduleDenyRule(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int) partition by hash(a) partitions 2")
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tsStr := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+	pdSchedule, err := infosync.GetPDScheduleConfig(context.Background())
+	require.NoError(t, err)
+
+	var physicalIDs []int64
+	for _, name := range []string{"t1", "t2"} {
+		tblInfo := external.GetTableByName(t, tk, "test", name).Meta()
+		physicalIDs = append(physicalIDs, tblInfo.ID)
+		if tblInfo.Partition != nil {
+			for _, def := range tblInfo.Partition.Definitions {
+				physicalIDs = append(physicalIDs, def.ID)
+			}
+		}
+	}
+	expected := label.NewScheduleDenyRule("", ddl.GetFlashbackTablesKeyRanges(physicalIDs), time.Hour)
+
+	getRule := func(jobID int64) *label.Rule {
+		ruleID := fmt.Sprintf("flashback/%d", jobID)
+		rules, err := infosync.GetLabelRules(context.Background(), []string{ruleID})
+		require.NoError(t, err)
+		return rules[ruleID]
+	}
+	originHook := dom.DDL().GetHook()
+	defer dom.DDL().SetHook(originHook)
+	var jobID int64
+	rules := make(map[model.SchemaState]*label.Rule)
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobRunBeforeExported = func(job *model.Job) {
+		if job.Type != model.ActionFlashbackTables {
+			return
+		}
+		jobID = job.ID
+		rules[job.SchemaState] = getRule(job.ID)
+		// The PD schedule of the cluster isn't changed.
+		s, err := infosync.GetPDScheduleConfig(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, pdSchedule, s)
+	}
+	dom.DDL().SetHook(hook)
+
+	// The rule is put before resolving the locks in the flashback ranges, and removed when the job is done.
+	tk.MustExec(fmt.Sprintf("flashback table t1, t2 to timestamp '%s'", tsStr))
+	require.Len(t, rules, 3)
+	require.Nil(t, rules[model.StateNone])
+	require.Nil(t, rules[model.StateWriteOnly])
+	rule := rules[model.StateWriteReorganization]
+	require.NotNil(t, rule)
+	require.Equal(t, fmt.Sprintf("flashback/%d", jobID), rule.ID)
+	require.Equal(t, expected.Labels, rule.Labels)
+	require.Equal(t, "1h0m0s", rule.Labels[0].TTL)
+	require.Len(t, rule.Data, 2)
+	require.Len(t, expected.Data, 2)
+	for i := range expected.Data {
+		data := rule.Data[i].(map[string]interface{})
+		for k, v := range expected.Data[i].(map[string]string) {
+			require.Equal(t, v, data[k])
+		}
+	}
+	require.Nil(t, getRule(jobID))
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job log %d", jobID)).Rows()
+	found := false
+	for _, row := range rows {
+		found = found || row[3] == "fence the flashback ranges from the PD schedule"
+	}
+	require.True(t, found)
+	tk.MustQuery("select @@global.tidb_gc_enable").Check(testkit.Rows("1"))
+}
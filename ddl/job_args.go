@@ -0,0 +1,183 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// JobArg is a decoded arg of a DDL job.
+type JobArg struct {
+	Name  string
+	Value string
+}
+
+// JobArgsDecoder decodes the args of a DDL job into human-readable args, it's used by `ADMIN SHOW DDL JOB ARGS`.
+type JobArgsDecoder func(job *model.Job) ([]JobArg, error)
+
+// rawJobArgsName is the name of the arg returned for the jobs which don't have a decoder.
+const rawJobArgsName = "raw_args"
+
+var jobArgsDecoders = struct {
+	sync.RWMutex
+	m map[model.ActionType]JobArgsDecoder
+}{
+	m: map[model.ActionType]JobArgsDecoder{
+		model.ActionFlashbackCluster: decodeFlashbackClusterArgs,
+		model.ActionFlashbackTables:  decodeFlashbackTablesArgs,
+		model.ActionAddIndex:         decodeAddIndexArgs,
+		model.ActionAddPrimaryKey:    decodeAddIndexArgs,
+	},
+}
+
+// RegisterJobArgsDecoder registers the args decoder of the DDL jobs of the action type.
+func RegisterJobArgsDecoder(tp model.ActionType, decoder JobArgsDecoder) {
+	jobArgsDecoders.Lock()
+	defer jobArgsDecoders.Unlock()
+	jobArgsDecoders.m[tp] = decoder
+}
+
+// DecodeJobArgs decodes the args of the job by the decoder registered for its action type. The raw JSON args are
+// returned if there is no decoder for the action type, or the args can't be decoded, e.g. they're rewritten when
+// the job is rolled back.
+func DecodeJobArgs(job *model.Job) []JobArg {
+	jobArgsDecoders.RLock()
+	decoder := jobArgsDecoders.m[job.Type]
+	jobArgsDecoders.RUnlock()
+	if decoder != nil {
+		// Decode a copy, because DecodeArgs overwrites the args of the job.
+		args, err := decoder(&model.Job{Type: job.Type, State: job.State, RawArgs: job.RawArgs, RowCount: job.GetRowCount()})
+		if err == nil {
+			return args
+		}
+	}
+	return []JobArg{{Name: rawJobArgsName, Value: string(job.RawArgs)}}
+}
+
+func formatFlashbackTS(ts uint64) []JobArg {
+	return []JobArg{
+		{Name: "flashback_ts", Value: strconv.FormatUint(ts, 10)},
+		{Name: "flashback_time", Value: oracle.GetTimeFromTS(ts).String()},
+	}
+}
+
+func formatFlashbackExternals(pdScheduleValue map[string]interface{}, changedExternals uint64, force bool) ([]JobArg, error) {
+	// The map is marshaled with the sorted keys.
+	pdSchedule, err := json.Marshal(pdScheduleValue)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var changed []string
+	if changedExternals&flashbackChangedGC != 0 {
+		changed = append(changed, "gc")
+	}
+	if changedExternals&flashbackChangedPDSchedule != 0 {
+		changed = append(changed, "pd_schedule")
+	}
+	if changedExternals&flashbackChangedScheduleDenyRule != 0 {
+		changed = append(changed, "schedule_deny_rule")
+	}
+	return []JobArg{
+		{Name: "saved_pd_schedule", Value: string(pdSchedule)},
+		{Name: "changed_externals", Value: strings.Join(changed, ",")},
+		{Name: "force", Value: strconv.FormatBool(force)},
+	}, nil
+}
+
+func decodeFlashbackClusterArgs(job *model.Job) ([]JobArg, error) {
+	flashbackTS, pdScheduleValue, changedExternals, batchSize, force, timeout, killConflictingQueries, scatterRegions,
+		err := getFlashbackClusterArgs(job)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	externals, err := formatFlashbackExternals(pdScheduleValue, changedExternals, force)
+	if err != nil {
+		return nil, err
+	}
+	args := append(formatFlashbackTS(flashbackTS), externals...)
+	if timeout > 0 {
+		args = append(args, JobArg{Name: "timeout", Value: timeout.String()})
+	}
+	if batchSize != variable.DefTiDBFlashbackBatchSize {
+		args = append(args, JobArg{Name: "batch_size", Value: strconv.Itoa(batchSize)})
+	}
+	if killConflictingQueries {
+		args = append(args, JobArg{Name: "kill_conflicting_queries", Value: "true"})
+	}
+	if scatterRegions {
+		args = append(args, JobArg{Name: "scatter_regions", Value: "true"})
+	}
+	return args, nil
+}
+
+func decodeFlashbackTablesArgs(job *model.Job) ([]JobArg, error) {
+	flashbackTS, pdScheduleValue, changedExternals, tables, force, err := getFlashbackTablesArgs(job)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	externals, err := formatFlashbackExternals(pdScheduleValue, changedExternals, force)
+	if err != nil {
+		return nil, err
+	}
+	args := append(formatFlashbackTS(flashbackTS), externals...)
+	for _, tbl := range tables {
+		physicalIDs, err := json.Marshal(tbl.PhysicalIDs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		args = append(args, JobArg{Name: "table", Value: tbl.String() + " physical_ids: " + string(physicalIDs)})
+	}
+	return args, nil
+}
+
+func decodeAddIndexArgs(job *model.Job) ([]JobArg, error) {
+	var (
+		unique                  bool
+		indexName               model.CIStr
+		indexPartSpecifications []*ast.IndexPartSpecification
+	)
+	// The args after the index columns differ between adding the primary key and adding the index, skip them.
+	if err := job.DecodeArgs(&unique, &indexName, &indexPartSpecifications); err != nil {
+		return nil, errors.Trace(err)
+	}
+	cols := make([]string, 0, len(indexPartSpecifications))
+	for _, spec := range indexPartSpecifications {
+		if spec.Column == nil {
+			// The expression index parts are restored from the hidden columns, only show the marks of them.
+			cols = append(cols, "<expression>")
+			continue
+		}
+		col := spec.Column.Name.O
+		if spec.Length != 0 && spec.Length != -1 {
+			col += "(" + strconv.Itoa(spec.Length) + ")"
+		}
+		cols = append(cols, col)
+	}
+	return []JobArg{
+		{Name: "index_name", Value: indexName.O},
+		{Name: "unique", Value: strconv.FormatBool(unique)},
+		{Name: "columns", Value: strings.Join(cols, ", ")},
+		{Name: "row_count", Value: strconv.FormatInt(job.GetRowCount(), 10)},
+	}, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/external"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func TestShowDDLJobArgs(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b varchar(32))")
+	lastJobID := func() int64 {
+		id, err := strconv.ParseInt(tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string), 10, 64)
+		require.NoError(t, err)
+		return id
+	}
+	createJobID := lastJobID()
+
+	tk.MustExec("insert into t values (1, 'a'), (2, 'b')")
+	tk.MustExec("alter table t add unique index idx(a, b(10))")
+	addIndexJobID := lastJobID()
+	tk.MustQuery(fmt.Sprintf("admin show ddl job args %d", addIndexJobID)).Check(testkit.Rows(
+		fmt.Sprintf("%d add index index_name idx", addIndexJobID),
+		fmt.Sprintf("%d add index unique true", addIndexJobID),
+		fmt.Sprintf("%d add index columns a, b(10)", addIndexJobID),
+		fmt.Sprintf("%d add index row_count 2", addIndexJobID),
+	))
+
+	time.Sleep(10 * time.Millisecond)
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	tk.MustExec(fmt.Sprintf("flashback table t to timestamp '%s'", oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")))
+	flashbackJobID := lastJobID()
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job args %d", flashbackJobID)).Rows()
+	require.Len(t, rows, 6)
+	for _, row := range rows {
+		require.Equal(t, strconv.FormatInt(flashbackJobID, 10), row[0])
+		require.Equal(t, "flashback tables", row[1])
+	}
+	require.Equal(t, "flashback_ts", rows[0][2])
+	flashbackTS, err := strconv.ParseUint(rows[0][3].(string), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, oracle.GetTimeFromTS(ts).Truncate(time.Millisecond), oracle.GetTimeFromTS(flashbackTS))
+	require.Equal(t, []interface{}{"flashback_time", oracle.GetTimeFromTS(flashbackTS).String()}, rows[1][2:])
+	require.Equal(t, "saved_pd_schedule", rows[2][2])
+	require.Equal(t, []interface{}{"changed_externals", "gc,schedule_deny_rule"}, rows[3][2:])
+	require.Equal(t, []interface{}{"force", "false"}, rows[4][2:])
+	tblID := external.GetTableByName(t, tk, "test", "t").Meta().ID
+	require.Equal(t, []interface{}{"table", fmt.Sprintf("`test`.`t` physical_ids: [%d]", tblID)}, rows[5][2:])
+
+	// The jobs without decoders show the raw args, and the args of different jobs are shown in the given order.
+	rows = tk.MustQuery(fmt.Sprintf("admin show ddl job args %d, %d", createJobID, addIndexJobID)).Rows()
+	require.Len(t, rows, 5)
+	require.Equal(t, []interface{}{strconv.FormatInt(createJobID, 10), "create table", "raw_args"}, rows[0][:3])
+	require.Contains(t, rows[0][3], `"name":{"O":"t","L":"t"}`)
+	tk.MustQuery("admin show ddl job args 0").Check(testkit.Rows())
+}
+
+func TestRegisterJobArgsDecoder(t *testing.T) {
+	job := &model.Job{Type: model.ActionTruncateTable, RawArgs: []byte(`[100]`)}
+	require.Equal(t, []ddl.JobArg{{Name: "raw_args", Value: "[100]"}}, ddl.DecodeJobArgs(job))
+
+	ddl.RegisterJobArgsDecoder(model.ActionTruncateTable, func(job *model.Job) ([]ddl.JobArg, error) {
+		var newTableID int64
+		if err := job.DecodeArgs(&newTableID); err != nil {
+			return nil, err
+		}
+		return []ddl.JobArg{{Name: "new_table_id", Value: strconv.FormatInt(newTableID, 10)}}, nil
+	})
+	defer ddl.RegisterJobArgsDecoder(model.ActionTruncateTable, nil)
+	require.Equal(t, []ddl.JobArg{{Name: "new_table_id", Value: "100"}}, ddl.DecodeJobArgs(job))
+	// The raw args are shown if the args can't be decoded.
+	job.RawArgs = []byte(`["a"]`)
+	require.Equal(t, []ddl.JobArg{{Name: "raw_args", Value: `["a"]`}}, ddl.DecodeJobArgs(job))
+	require.Nil(t, job.Args)
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	tidbutil "github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// jobInterrupter interrupts the in-flight internal SQL of a running job.
+type jobInterrupter struct {
+	cancel  context.CancelFunc
+	tracker sessionctx.SysProcTracker
+
+	mu struct {
+		sync.Mutex
+		interrupted bool
+		// procIDs are the IDs of the internal sessions which are tracked as the system processes of the job.
+		procIDs map[uint64]struct{}
+	}
+}
+
+func (i *jobInterrupter) interrupt() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.mu.interrupted = true
+	i.cancel()
+	for procID := range i.mu.procIDs {
+		i.tracker.KillSysProcess(procID)
+	}
+}
+
+// runningJobInterrupters records the interrupters of the jobs running on this TiDB instance, keyed by
+// the store UUID and the job ID. Cancelling a job on the instance running it interrupts its internal SQL
+// in time, otherwise the job sees the cancelling state after its current step finishes.
+var runningJobInterrupters = struct {
+	sync.Mutex
+	m map[string]map[int64]*jobInterrupter
+}{m: make(map[string]map[int64]*jobInterrupter)}
+
+func getJobInterrupter(store kv.Storage, jobID int64) *jobInterrupter {
+	runningJobInterrupters.Lock()
+	defer runningJobInterrupters.Unlock()
+	return runningJobInterrupters.m[store.UUID()][jobID]
+}
+
+// interruptRunningJobs interrupts the internal SQL of the jobs if they're running on this instance.
+func interruptRunningJobs(store kv.Storage, ids []int64) {
+	for _, id := range ids {
+		if itr := getJobInterrupter(store, id); itr != nil {
+			logutil.BgLogger().Info("[ddl] interrupt the internal SQL of the cancelled job", zap.Int64("jobID", id))
+			itr.interrupt()
+		}
+	}
+}
+
+// startInterruptibleJob derives the context for the internal SQL of the job from the worker context, and registers
+// the job so that cancelling it interrupts the internal SQL. The returned function must be called when the current
+// step of the job finishes.
+func (w *worker) startInterruptibleJob(jobCtx *JobContext, job *model.Job) func() {
+	ctx, cancel := context.WithCancel(w.ctx)
+	itr := &jobInterrupter{cancel: cancel, tracker: w.sysProcTracker}
+	itr.mu.procIDs = make(map[uint64]struct{})
+	uuid := w.store.UUID()
+	runningJobInterrupters.Lock()
+	if runningJobInterrupters.m[uuid] == nil {
+		runningJobInterrupters.m[uuid] = make(map[int64]*jobInterrupter)
+	}
+	runningJobInterrupters.m[uuid][job.ID] = itr
+	runningJobInterrupters.Unlock()
+	jobCtx.ctx = ctx
+
+	return func() {
+		runningJobInterrupters.Lock()
+		if runningJobInterrupters.m[uuid][job.ID] == itr {
+			delete(runningJobInterrupters.m[uuid], job.ID)
+		}
+		runningJobInterrupters.Unlock()
+		cancel()
+		jobCtx.ctx = context.Background()
+	}
+}
+
+// trackJobSession tracks the internal session as a system process of the job, so that it's shown in the process
+// list as `ddl_job_<jobID>` and can be killed by `KILL`. The returned function must be called before the session
+// is put back to the pool.
+func (w *worker) trackJobSession(job *model.Job, sctx sessionctx.Context) func() {
+	if w.sysProcTracker == nil || w.serverIDGetter == nil {
+		return func() {}
+	}
+	procID := tidbutil.GetDDLJobProcID(w.serverIDGetter, job.ID)
+	if err := w.sysProcTracker.Track(procID, sctx); err != nil {
+		logutil.Logger(w.logCtx).Warn("[ddl] track the internal session of the job failed", zap.Error(err))
+		return func() {}
+	}
+	sctx.GetSessionVars().SysProcessName = fmt.Sprintf("ddl_job_%d", job.ID)
+	itr := getJobInterrupter(w.store, job.ID)
+	if itr != nil {
+		itr.mu.Lock()
+		itr.mu.procIDs[procID] = struct{}{}
+		interrupted := itr.mu.interrupted
+		itr.mu.Unlock()
+		if interrupted {
+			w.sysProcTracker.KillSysProcess(procID)
+		}
+	}
+	return func() {
+		if itr != nil {
+			itr.mu.Lock()
+			delete(itr.mu.procIDs, procID)
+			itr.mu.Unlock()
+		}
+		sctx.GetSessionVars().SysProcessName = ""
+		w.sysProcTracker.UnTrack(procID)
+	}
+}
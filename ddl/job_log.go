@@ -0,0 +1,94 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxJobLogFieldsLen is the max length of the fields of an event recorded in the log of a job, the longer ones are
+// truncated, so that the log doesn't bloat the job meta.
+const maxJobLogFieldsLen = 512
+
+// jobLogger logs the events of a DDL job, e.g. the state changes, the external toggles, the retries and the errors.
+// The events are written to the TiDB log with the job ID, and recorded in the log of the job, which is persisted
+// with the job, so that they can be shown by `ADMIN SHOW DDL JOB LOG` on any TiDB after the job is done.
+// It writes the job, so it must be used in the goroutine running the job.
+type jobLogger struct {
+	logger *zap.Logger
+	job    *model.Job
+}
+
+// newJobLogger returns the logger of the job, which logs with logutil.BgLogger.
+func newJobLogger(job *model.Job) *jobLogger {
+	return &jobLogger{logger: logutil.BgLogger(), job: job}
+}
+
+// jobLogger returns the logger of the job, which logs with the worker.
+func (w *worker) jobLogger(job *model.Job) *jobLogger {
+	return &jobLogger{logger: logutil.Logger(w.logCtx), job: job}
+}
+
+// Info logs the event at the info level.
+func (l *jobLogger) Info(msg string, fields ...zap.Field) {
+	l.log(zapcore.InfoLevel, msg, fields)
+}
+
+// Warn logs the event at the warn level.
+func (l *jobLogger) Warn(msg string, fields ...zap.Field) {
+	l.log(zapcore.WarnLevel, msg, fields)
+}
+
+// Error logs the event at the error level.
+func (l *jobLogger) Error(msg string, fields ...zap.Field) {
+	l.log(zapcore.ErrorLevel, msg, fields)
+}
+
+func (l *jobLogger) log(level zapcore.Level, msg string, fields []zap.Field) {
+	if ce := l.logger.Check(level, "[ddl] "+msg); ce != nil {
+		ce.Write(append([]zap.Field{zap.Int64("jobID", l.job.ID)}, fields...)...)
+	}
+	l.job.AppendLog(&model.JobLogEntry{
+		Time:   time.Now(),
+		Level:  level.String(),
+		Msg:    msg,
+		Fields: encodeJobLogFields(fields),
+	})
+}
+
+// encodeJobLogFields encodes the fields into a JSON object, it's truncated if it's longer than maxJobLogFieldsLen.
+func encodeJobLogFields(fields []zap.Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	b, err := json.Marshal(enc.Fields)
+	if err != nil {
+		return ""
+	}
+	if len(b) > maxJobLogFieldsLen {
+		return string(b[:maxJobLogFieldsLen]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/pingcap/tidb/errno"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowDDLJobLog(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert into t values (1), (1)")
+	lastJobID := func() int64 {
+		id, err := strconv.ParseInt(tk.MustQuery("admin show ddl jobs 1").Rows()[0][0].(string), 10, 64)
+		require.NoError(t, err)
+		return id
+	}
+
+	tk.MustExec("alter table t add index idx(a)")
+	addIndexJobID := lastJobID()
+	rows := tk.MustQuery(fmt.Sprintf("admin show ddl job log %d", addIndexJobID)).Rows()
+	var msgs []string
+	for _, row := range rows {
+		require.Equal(t, strconv.FormatInt(addIndexJobID, 10), row[0])
+		require.Equal(t, "info", row[2])
+		msgs = append(msgs, row[3].(string))
+	}
+	require.Equal(t, []string{
+		"DDL job state changed",
+		"DDL job state changed",
+		"DDL job state changed",
+		"start reorg job",
+		"run reorg job done",
+		"DDL job state changed",
+	}, msgs)
+	require.Equal(t, `{"fromSchemaState":"none","fromState":"queueing","toSchemaState":"delete only","toState":"running"}`, rows[0][4])
+	require.Equal(t, `{"handled rows":2}`, rows[4][4])
+	require.Equal(t, `{"fromSchemaState":"write reorganization","fromState":"running","toSchemaState":"public","toState":"done"}`, rows[5][4])
+
+	// The errors of the job are recorded, and the logs of different jobs are shown in the given order.
+	tk.MustGetErrCode("alter table t add unique index uidx(a)", errno.ErrDupEntry)
+	uniqueJobID := lastJobID()
+	rows = tk.MustQuery(fmt.Sprintf("admin show ddl job log %d, %d", uniqueJobID, addIndexJobID)).Rows()
+	require.Len(t, rows, 15)
+	var hasErr bool
+	for _, row := range rows[:9] {
+		require.Equal(t, strconv.FormatInt(uniqueJobID, 10), row[0])
+		if row[2] == "error" {
+			require.Equal(t, "run DDL job error", row[3])
+			require.Contains(t, row[4], "Duplicate entry")
+			hasErr = true
+		}
+	}
+	require.True(t, hasErr)
+	require.Equal(t, `{"fromSchemaState":"delete reorganization","fromState":"rollingback","toSchemaState":"none","toState":"rollback done"}`, rows[8][4])
+	tk.MustQuery("admin show ddl job log 0").Check(testkit.Rows())
+}
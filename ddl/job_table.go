@@ -67,9 +67,31 @@ func (dc *ddlCtx) excludeJobIDs() string {
 }
 
 const (
-	getJobSQL = "select job_meta, processing from mysql.tidb_ddl_job where job_id in (select min(job_id) from mysql.tidb_ddl_job group by schema_ids, table_ids) and %s reorg %s order by processing desc, job_id"
+	getJobSQL = "select job_meta, processing from mysql.tidb_ddl_job where job_id in (select min(job_id) from mysql.tidb_ddl_job group by schema_ids, table_ids) and %s %s order by processing desc, job_id"
+	// getConflictingJobSQL gets a processing job conflicting with the job on a single table, which is either on the
+	// same table, or drops the schema of the table, or flashes back the cluster. A flashback queued before the job
+	// conflicts with it as well, since the flashback is a barrier of the jobs queued after it.
+	getConflictingJobSQL = "select job_id from mysql.tidb_ddl_job where (processing and (find_in_set(%s, table_ids) != 0 or (find_in_set(%s, schema_ids) != 0 and type = %d) or type = %d)) or (type = %d and job_id < %d) limit 1"
 )
 
+// metadataOnlyActions are the actions which only change the table meta, they are quick and don't conflict with the
+// jobs on the other tables, so they are handled by the metadata workers and aren't blocked by the long general jobs.
+var metadataOnlyActions = []model.ActionType{
+	model.ActionSetDefaultValue,
+	model.ActionModifyTableComment,
+	model.ActionRenameIndex,
+	model.ActionModifyTableCharsetAndCollate,
+	model.ActionAlterIndexVisibility,
+}
+
+func metadataOnlyActionList() string {
+	s := make([]string, 0, len(metadataOnlyActions))
+	for _, action := range metadataOnlyActions {
+		s = append(s, strconv.Itoa(int(action)))
+	}
+	return strings.Join(s, ",")
+}
+
 type jobType int
 
 func (t jobType) String() string {
@@ -78,6 +100,8 @@ func (t jobType) String() string {
 		return "general"
 	case reorg:
 		return "reorg"
+	case metadata:
+		return "metadata"
 	}
 	return "unknown job type: " + strconv.Itoa(int(t))
 }
@@ -85,16 +109,20 @@ func (t jobType) String() string {
 const (
 	general jobType = iota
 	reorg
+	metadata
 )
 
 func (d *ddl) getJob(sess *session, tp jobType, filter func(*model.Job) (bool, error)) (*model.Job, error) {
-	not := "not"
-	label := "get_job_general"
-	if tp == reorg {
-		not = ""
-		label = "get_job_reorg"
+	var cond, label string
+	switch tp {
+	case reorg:
+		cond, label = "reorg", "get_job_reorg"
+	case metadata:
+		cond, label = fmt.Sprintf("not reorg and type in (%s)", metadataOnlyActionList()), "get_job_metadata"
+	default:
+		cond, label = fmt.Sprintf("not reorg and type not in (%s)", metadataOnlyActionList()), "get_job_general"
 	}
-	sql := fmt.Sprintf(getJobSQL, not, d.excludeJobIDs())
+	sql := fmt.Sprintf(getJobSQL, cond, d.excludeJobIDs())
 	rows, err := sess.execute(context.Background(), sql, label)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -107,6 +135,7 @@ func (d *ddl) getJob(sess *session, tp jobType, filter func(*model.Job) (bool, e
 			return nil, errors.Trace(err)
 		}
 		if row.GetInt64(1) == 1 {
+			d.onJobPicked(tp, &runJob, len(rows))
 			return &runJob, nil
 		}
 		b, err := filter(&runJob)
@@ -114,6 +143,7 @@ func (d *ddl) getJob(sess *session, tp jobType, filter func(*model.Job) (bool, e
 			return nil, errors.Trace(err)
 		}
 		if b {
+			d.onJobPicked(tp, &runJob, len(rows))
 			if err := d.markJobProcessing(sess, &runJob); err != nil {
 				logutil.BgLogger().Warn("[ddl] handle ddl job failed: mark job is processing meet error", zap.Error(err), zap.String("job", runJob.String()))
 				return nil, errors.Trace(err)
@@ -124,15 +154,28 @@ func (d *ddl) getJob(sess *session, tp jobType, filter func(*model.Job) (bool, e
 	return nil, nil
 }
 
+func (d *ddl) onJobPicked(tp jobType, job *model.Job, queueLen int) {
+	d.mu.RLock()
+	d.mu.hook.OnJobPicked(tp.String(), job, queueLen)
+	d.mu.RUnlock()
+}
+
 func (d *ddl) getGeneralJob(sess *session) (*model.Job, error) {
 	return d.getJob(sess, general, func(job *model.Job) (bool, error) {
+		// The flashback conflicts with all the jobs, it waits for the processing ones.
+		if job.Type == model.ActionFlashbackCluster {
+			return d.checkJobIsRunnable(sess, "select job_id from mysql.tidb_ddl_job where processing limit 1")
+		}
+		// The jobs queued after a flashback wait for it, even if the flashback waits for the processing jobs.
 		if job.Type == model.ActionDropSchema {
-			sql := fmt.Sprintf("select job_id from mysql.tidb_ddl_job where find_in_set(%s, schema_ids) != 0 and processing limit 1", strconv.Quote(strconv.FormatInt(job.SchemaID, 10)))
+			sql := fmt.Sprintf("select job_id from mysql.tidb_ddl_job where (find_in_set(%s, schema_ids) != 0 and processing) or (type = %d and job_id < %d) limit 1",
+				strconv.Quote(strconv.FormatInt(job.SchemaID, 10)), model.ActionFlashbackCluster, job.ID)
 			return d.checkJobIsRunnable(sess, sql)
 		}
-		// For general job, there is only 1 general worker to handle it, so at this moment the processing job must be reorg job and the reorg job must only contain one table id.
+		// For general job, there is only 1 general worker to handle it, so at this moment the processing job must be reorg job or metadata-only job, which must only contain one table id.
 		// So it's not possible the find_in_set("1,2", "1,2,3") occurs.
-		sql := fmt.Sprintf("select job_id from mysql.tidb_ddl_job t1, (select table_ids from mysql.tidb_ddl_job where job_id = %d) t2 where processing and find_in_set(t1.table_ids, t2.table_ids) != 0", job.ID)
+		sql := fmt.Sprintf("select job_id from mysql.tidb_ddl_job t1, (select table_ids from mysql.tidb_ddl_job where job_id = %d) t2 where (processing and find_in_set(t1.table_ids, t2.table_ids) != 0) or (t1.type = %d and t1.job_id < %d)",
+			job.ID, model.ActionFlashbackCluster, job.ID)
 		return d.checkJobIsRunnable(sess, sql)
 	})
 }
@@ -144,12 +187,24 @@ func (d *ddl) checkJobIsRunnable(sess *session, sql string) (bool, error) {
 
 func (d *ddl) getReorgJob(sess *session) (*model.Job, error) {
 	return d.getJob(sess, reorg, func(job *model.Job) (bool, error) {
-		sql := fmt.Sprintf("select job_id from mysql.tidb_ddl_job where (find_in_set(%s, schema_ids) != 0 and type = %d and processing) or (find_in_set(%s, table_ids) != 0 and processing) limit 1",
-			strconv.Quote(strconv.FormatInt(job.SchemaID, 10)), model.ActionDropSchema, strconv.Quote(strconv.FormatInt(job.TableID, 10)))
-		return d.checkJobIsRunnable(sess, sql)
+		return d.checkJobIsRunnable(sess, conflictingJobSQL(job))
+	})
+}
+
+// getMetadataJob gets the metadata-only job which doesn't conflict with the processing jobs, so it isn't blocked by the
+// long general jobs or reorg jobs on the other tables.
+func (d *ddl) getMetadataJob(sess *session) (*model.Job, error) {
+	return d.getJob(sess, metadata, func(job *model.Job) (bool, error) {
+		return d.checkJobIsRunnable(sess, conflictingJobSQL(job))
 	})
 }
 
+func conflictingJobSQL(job *model.Job) string {
+	return fmt.Sprintf(getConflictingJobSQL, strconv.Quote(strconv.FormatInt(job.TableID, 10)),
+		strconv.Quote(strconv.FormatInt(job.SchemaID, 10)), model.ActionDropSchema, model.ActionFlashbackCluster,
+		model.ActionFlashbackCluster, job.ID)
+}
+
 func (d *ddl) startDispatchLoop() {
 	se, err := d.sessPool.get()
 	if err != nil {
@@ -186,6 +241,7 @@ func (d *ddl) startDispatchLoop() {
 			return
 		}
 		d.loadDDLJobAndRun(sess, d.generalDDLWorkerPool, d.getGeneralJob)
+		d.loadDDLJobAndRun(sess, d.metadataDDLWorkerPool, d.getMetadataJob)
 		d.loadDDLJobAndRun(sess, d.reorgWorkerPool, d.getReorgJob)
 	}
 }
@@ -294,7 +350,7 @@ func job2TableIDs(job *model.Job) string {
 
 func job2UniqueIDs(job *model.Job, schema bool) string {
 	switch job.Type {
-	case model.ActionExchangeTablePartition, model.ActionRenameTables, model.ActionRenameTable:
+	case model.ActionExchangeTablePartition, model.ActionRenameTables, model.ActionRenameTable, model.ActionFlashbackTables:
 		var ids []int64
 		if schema {
 			ids = job.CtxVars[0].([]int64)
@@ -419,8 +475,8 @@ func wrapKey2String(key []byte) string {
 	return fmt.Sprintf("0x%x", key)
 }
 
-func getJobsBySQL(sess *session, tbl, condition string) ([]*model.Job, error) {
-	rows, err := sess.execute(context.Background(), fmt.Sprintf("select job_meta from mysql.%s where %s", tbl, condition), "get_job")
+func getJobsBySQL(ctx context.Context, sess *session, tbl, condition string) ([]*model.Job, error) {
+	rows, err := sess.execute(ctx, fmt.Sprintf("select job_meta from mysql.%s where %s", tbl, condition), "get_job")
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -518,7 +574,7 @@ func (d *ddl) MoveJobFromTable2Queue() error {
 		if !isConcurrentDDL || err != nil {
 			return errors.Trace(err)
 		}
-		jobs, err := getJobsBySQL(se, "tidb_ddl_job", "1 order by job_id")
+		jobs, err := getJobsBySQL(context.Background(), se, "tidb_ddl_job", "1 order by job_id")
 		if err != nil {
 			return errors.Trace(err)
 		}
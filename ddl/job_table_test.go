@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/util"
 	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
 	"go.uber.org/atomic"
 	"golang.org/x/exp/slices"
 )
@@ -291,3 +292,181 @@ func TestConcurrentDDLSwitch(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadataOnlyJobNotBlocked(t *testing.T) {
+	if !variable.EnableConcurrentDDL.Load() {
+		t.Skipf("test requires concurrent ddl")
+	}
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int)")
+	d := dom.DDL()
+	originHook := d.GetHook()
+	defer d.SetHook(originHook)
+
+	// blockJob blocks the first job matched until the returned release is called.
+	blockJob := func(match func(*model.Job) bool) (blocked <-chan struct{}, release func()) {
+		blockedCh, releaseCh := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		hook := &ddl.TestDDLCallback{Do: dom}
+		hook.OnJobRunBeforeExported = func(job *model.Job) {
+			if match(job) {
+				once.Do(func() {
+					close(blockedCh)
+					<-releaseCh
+				})
+			}
+		}
+		d.SetHook(hook)
+		return blockedCh, func() { close(releaseCh) }
+	}
+
+	// The metadata-only job on the other table isn't blocked by the reorg job or the general job.
+	for i, c := range []struct {
+		sql   string
+		match func(*model.Job) bool
+	}{
+		{"alter table t1 add index idx(a)", func(job *model.Job) bool {
+			return job.Type == model.ActionAddIndex && job.SchemaState == model.StateWriteReorganization
+		}},
+		{"truncate table t1", func(job *model.Job) bool {
+			return job.Type == model.ActionTruncateTable
+		}},
+	} {
+		blocked, release := blockJob(c.match)
+		var wg util.WaitGroupWrapper
+		wg.Run(func() {
+			tk1 := testkit.NewTestKit(t, store)
+			tk1.MustExec("use test")
+			tk1.MustExec(c.sql)
+		})
+		<-blocked
+		tk.MustExec(fmt.Sprintf("alter table t2 comment 'c%d'", i))
+		tk.MustQuery("select table_comment from information_schema.tables where table_schema = 'test' and table_name = 't2'").
+			Check(testkit.Rows(fmt.Sprintf("c%d", i)))
+		release()
+		wg.Wait()
+	}
+
+	// The flashback conflicts with all the jobs, the metadata-only job queues until the flashback is done.
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	blocked, release := blockJob(func(job *model.Job) bool {
+		return job.Type == model.ActionFlashbackCluster && job.SchemaState == model.StateNone
+	})
+	var wg util.WaitGroupWrapper
+	var flashbackErr error
+	wg.Run(func() {
+		tk1 := testkit.NewTestKit(t, store)
+		flashbackErr = tk1.ExecToErr(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	})
+	<-blocked
+	commentDone := atomic.NewBool(false)
+	wg.Run(func() {
+		tk2 := testkit.NewTestKit(t, store)
+		tk2.MustExec("use test")
+		tk2.MustExec("alter table t2 comment 'queued'")
+		commentDone.Store(true)
+	})
+	require.Eventually(t, func() bool {
+		jobs, err := ddl.GetAllDDLJobs(testkit.NewTestKit(t, store).Session(), nil)
+		require.NoError(t, err)
+		return len(jobs) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(500 * time.Millisecond)
+	require.False(t, commentDone.Load())
+	tk.MustQuery(fmt.Sprintf("select processing from mysql.tidb_ddl_job where type = %d", model.ActionModifyTableComment)).Check(testkit.Rows("0"))
+	release()
+	wg.Wait()
+	// The flashback refuses to go on with the queueing job, the job runs after it.
+	require.ErrorContains(t, flashbackErr, "have other ddl jobs")
+	require.True(t, commentDone.Load())
+	tk.MustQuery("select table_comment from information_schema.tables where table_schema = 'test' and table_name = 't2'").
+		Check(testkit.Rows("queued"))
+}
+
+func TestFlashbackClusterPickedBeforeLaterJobs(t *testing.T) {
+	if !variable.EnableConcurrentDDL.Load() {
+		t.Skipf("test requires concurrent ddl")
+	}
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("create table t2 (a int)")
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	d := dom.DDL()
+	originHook := d.GetHook()
+	defer d.SetHook(originHook)
+
+	sqls := []string{
+		fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)),
+		"create table t3 (a int)",
+		"alter table t1 add index idx(a)",
+		"alter table t2 comment 'c'",
+	}
+	hook := &ddl.TestDDLCallback{Do: dom}
+	var wg util.WaitGroupWrapper
+	wg.Add(1)
+	var once sync.Once
+	hook.OnGetJobBeforeExported = func(string) {
+		// The owner is blocked until all the jobs are queued, so they're queued before any of them is picked.
+		once.Do(func() {
+			for i, sql := range sqls {
+				sql := sql
+				wg.Run(func() {
+					tk := testkit.NewTestKit(t, store)
+					tk.MustExec("use test")
+					// The flashback refuses to go on with the queueing jobs, ignore the error.
+					_ = tk.ExecToErr(sql)
+				})
+				require.Eventually(t, func() bool {
+					jobs, err := ddl.GetAllDDLJobs(testkit.NewTestKit(t, store).Session(), nil)
+					require.NoError(t, err)
+					return len(jobs) == i+1
+				}, 5*time.Second, 10*time.Millisecond)
+			}
+			wg.Done()
+		})
+	}
+	type pick struct {
+		jobType  string
+		action   model.ActionType
+		queueLen int
+	}
+	var mu sync.Mutex
+	var picks []pick
+	hook.OnJobPickedExported = func(jobType string, job *model.Job, queueLen int) {
+		mu.Lock()
+		defer mu.Unlock()
+		picks = append(picks, pick{jobType, job.Type, queueLen})
+	}
+	d.SetHook(hook)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Greater(t, len(picks), len(sqls))
+	// The flashback is picked first, it sees the create table job queueing behind it.
+	require.Equal(t, "general", picks[0].jobType)
+	require.Equal(t, model.ActionFlashbackCluster, picks[0].action)
+	require.GreaterOrEqual(t, picks[0].queueLen, 2)
+	// The flashback is a barrier, no later job is picked until the flashback is finished.
+	flashbackDone := false
+	for _, p := range picks {
+		if p.action != model.ActionFlashbackCluster {
+			flashbackDone = true
+			continue
+		}
+		require.False(t, flashbackDone, "the flashback is picked after the later jobs: %v", picks)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/dbterror"
+	"github.com/pingcap/tidb/util/mathutil"
+)
+
+// The bounds of the backoff of polling the watched job.
+const (
+	jobWatchMinInterval = 10 * time.Millisecond
+	jobWatchMaxInterval = time.Second
+)
+
+// jobWatchBackoff is the backoff of polling the watched job, it doubles the interval up to jobWatchMaxInterval.
+type jobWatchBackoff struct {
+	interval time.Duration
+}
+
+func (b *jobWatchBackoff) wait(ctx context.Context) error {
+	if b.interval == 0 {
+		b.interval = jobWatchMinInterval
+	} else {
+		b.interval = mathutil.Min(2*b.interval, jobWatchMaxInterval)
+	}
+	select {
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	case <-time.After(b.interval):
+		return nil
+	}
+}
+
+// WaitJobDone waits for the DDL job to be done, i.e. moved into the history, and returns the history job. The error
+// of the job is returned with the job if it's cancelled or rolled back.
+func WaitJobDone(ctx context.Context, store kv.Storage, jobID int64) (*model.Job, error) {
+	var bo jobWatchBackoff
+	for {
+		job, err := getHistoryJob(ctx, store, jobID)
+		if err != nil {
+			return nil, err
+		}
+		if job != nil {
+			if job.IsSynced() {
+				return job, nil
+			}
+			return job, errors.Trace(job.Error)
+		}
+		if err = bo.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// JobWatcher watches the state changes of a DDL job.
+type JobWatcher struct {
+	sctx  sessionctx.Context
+	jobID int64
+	last  *model.Job
+	done  bool
+}
+
+// NewJobWatcher creates a JobWatcher of the job. The job is read by sctx, which shouldn't be used by others until
+// the watching is done.
+func NewJobWatcher(sctx sessionctx.Context, jobID int64) *JobWatcher {
+	return &JobWatcher{sctx: sctx, jobID: jobID}
+}
+
+// Next waits for the next state change of the job, i.e. the change of its state or schema state, and returns the
+// job. The first call returns the current job. The last job returned is the history job, after that io.EOF is
+// returned.
+func (w *JobWatcher) Next(ctx context.Context) (*model.Job, error) {
+	if w.done {
+		return nil, io.EOF
+	}
+	var bo jobWatchBackoff
+	for {
+		job, done, err := w.getJob(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if w.last == nil || done || job.State != w.last.State || job.SchemaState != w.last.SchemaState {
+			w.last, w.done = job, done
+			return job, nil
+		}
+		if err = bo.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getJob returns the job, and whether it's a history job.
+func (w *JobWatcher) getJob(ctx context.Context) (*model.Job, bool, error) {
+	ctx = kv.WithInternalSourceType(ctx, kv.InternalTxnDDL)
+	var jobs []*model.Job
+	var err error
+	if variable.EnableConcurrentDDL.Load() {
+		jobs, err = getJobsBySQL(ctx, newSession(w.sctx), JobTable, fmt.Sprintf("job_id = %d", w.jobID))
+	} else {
+		err = kv.RunInNewTxn(ctx, w.sctx.GetStore(), false, func(ctx context.Context, txn kv.Transaction) (err error) {
+			jobs, err = getDDLJobs(meta.NewMeta(txn))
+			return err
+		})
+	}
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	for _, job := range jobs {
+		if job.ID == w.jobID {
+			return job, false, nil
+		}
+	}
+	// The job is moved into the history before it's removed from the running jobs, so it's found in the history if
+	// it isn't running.
+	job, err := getHistoryJob(ctx, w.sctx.GetStore(), w.jobID)
+	if err != nil {
+		return nil, false, errors.Trace(err)
+	}
+	if job == nil {
+		return nil, false, dbterror.ErrDDLJobNotFound.GenWithStackByArgs(w.jobID)
+	}
+	return job, true, nil
+}
+
+func getHistoryJob(ctx context.Context, store kv.Storage, jobID int64) (job *model.Job, err error) {
+	err = kv.RunInNewTxn(kv.WithInternalSourceType(ctx, kv.InternalTxnDDL), store, false, func(ctx context.Context, txn kv.Transaction) error {
+		job, err = meta.NewMeta(txn).GetHistoryDDLJob(jobID)
+		return err
+	})
+	return job, errors.Trace(err)
+}
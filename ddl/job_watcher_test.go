@@ -0,0 +1,126 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/terror"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/dbterror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobWatcher(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	tkWatch := testkit.NewTestKit(t, store)
+
+	// The job is held in a state until the test lets it go.
+	runDDLHeld := func(sql string, hold model.SchemaState, cancel bool) (jobID int64, release func(), wait func()) {
+		jobIDCh, releaseCh := make(chan int64, 1), make(chan struct{})
+		held := false
+		hook := &ddl.TestDDLCallback{Do: dom}
+		hook.OnJobRunBeforeExported = func(job *model.Job) {
+			if job.SchemaState == hold && !held {
+				held = true
+				jobIDCh <- job.ID
+				<-releaseCh
+				if cancel {
+					tkCancel := testkit.NewTestKit(t, store)
+					tkCancel.MustQuery(fmt.Sprintf("admin cancel ddl jobs %d", job.ID))
+				}
+			}
+		}
+		dom.DDL().SetHook(hook)
+		var wg util.WaitGroupWrapper
+		wg.Run(func() {
+			err := tk2.ExecToErr(sql)
+			if cancel {
+				assert.True(t, terror.ErrorEqual(err, dbterror.ErrCancelledDDLJob), "%v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+		return <-jobIDCh, func() { close(releaseCh) }, wg.Wait
+	}
+
+	// The state changes are streamed until the job is done.
+	jobID, release, wait := runDDLHeld("alter table t add index idx(b)", model.StateNone, false)
+	w := ddl.NewJobWatcher(tkWatch.Session(), jobID)
+	job, err := w.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, model.JobStateQueueing, job.State)
+	require.Equal(t, model.StateNone, job.SchemaState)
+	release()
+	var last *model.Job
+	for {
+		job, err = w.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, jobID, job.ID)
+		if last != nil {
+			require.False(t, job.State == last.State && job.SchemaState == last.SchemaState)
+		}
+		last = job
+	}
+	require.Equal(t, model.JobStateSynced, last.State)
+	require.Equal(t, model.StatePublic, last.SchemaState)
+	job, err = ddl.WaitJobDone(context.Background(), store, jobID)
+	require.NoError(t, err)
+	require.Equal(t, last.State, job.State)
+	require.Equal(t, jobID, tk.MustWaitDDLJob(jobID).ID)
+	wait()
+
+	// The error of the cancelled job is returned.
+	jobID, release, wait = runDDLHeld("alter table t add index idx2(b)", model.StateWriteOnly, true)
+	release()
+	job, err = ddl.WaitJobDone(context.Background(), store, jobID)
+	require.True(t, terror.ErrorEqual(err, dbterror.ErrCancelledDDLJob), "%v", err)
+	require.Equal(t, model.JobStateRollbackDone, job.State)
+	wait()
+
+	// The context times out before the job is done.
+	jobID, release, wait = runDDLHeld("alter table t add index idx3(b)", model.StateWriteOnly, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	_, err = ddl.WaitJobDone(ctx, store, jobID)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	w = ddl.NewJobWatcher(tkWatch.Session(), jobID)
+	job, err = w.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, model.StateWriteOnly, job.SchemaState)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	_, err = w.Next(ctx)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+	wait()
+	require.Equal(t, model.JobStateSynced, tk.MustWaitDDLJob(jobID).State)
+}
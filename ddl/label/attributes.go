@@ -41,6 +41,8 @@ const (
 type Label struct {
 	Key   string `json:"key,omitempty"`
 	Value string `json:"value,omitempty"`
+	// TTL is the duration after which PD removes the label, e.g. "1h". The label never expires if it's empty.
+	TTL string `json:"ttl,omitempty"`
 }
 
 // NewLabel creates a new label for a given string.
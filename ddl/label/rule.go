@@ -18,7 +18,9 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/codec"
@@ -30,6 +32,11 @@ const (
 	// IDPrefix is the prefix for label rule ID.
 	IDPrefix = "schema"
 	ruleType = "key-range"
+
+	// ScheduleKey is the key of the label which controls the PD schedule of the regions.
+	ScheduleKey = "schedule"
+	// ScheduleDeny is the value of the ScheduleKey label which denies PD to schedule the regions.
+	ScheduleDeny = "deny"
 )
 
 const (
@@ -157,6 +164,25 @@ func (r *Rule) Reset(dbName, tableName, partName string, ids ...int64) *Rule {
 	return r
 }
 
+// NewScheduleDenyRule creates a rule which denies PD to schedule the regions in the key ranges. The label expires
+// after the ttl, so the ranges aren't fenced forever if the rule is left behind, e.g. the TiDB crashes.
+func NewScheduleDenyRule(id string, keyRanges []kv.KeyRange, ttl time.Duration) *Rule {
+	r := &Rule{
+		ID:       id,
+		Index:    RuleIndexDefault,
+		Labels:   []Label{{Key: ScheduleKey, Value: ScheduleDeny, TTL: ttl.String()}},
+		RuleType: ruleType,
+		Data:     make([]interface{}, 0, len(keyRanges)),
+	}
+	for _, kr := range keyRanges {
+		r.Data = append(r.Data, map[string]string{
+			"start_key": hex.EncodeToString(codec.EncodeBytes(nil, kr.StartKey)),
+			"end_key":   hex.EncodeToString(codec.EncodeBytes(nil, kr.EndKey)),
+		})
+	}
+	return r
+}
+
 // RulePatch is the patch to update the label rules.
 type RulePatch struct {
 	SetRules    []*Rule  `json:"sets"`
@@ -16,8 +16,11 @@ package label
 
 import (
 	"testing"
+	"time"
 
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/stretchr/testify/require"
 )
 
@@ -108,3 +111,26 @@ func TestReset(t *testing.T) {
 	r3 := rule.Reset("db3", "t3", "p3", 3)
 	require.Equal(t, r3, expected)
 }
+
+func TestNewScheduleDenyRule(t *testing.T) {
+	keyRanges := []kv.KeyRange{
+		{StartKey: tablecodec.EncodeTablePrefix(1), EndKey: tablecodec.EncodeTablePrefix(3)},
+		{StartKey: tablecodec.EncodeTablePrefix(5), EndKey: tablecodec.EncodeTablePrefix(6)},
+	}
+	rule := NewScheduleDenyRule("flashback/1", keyRanges, time.Hour)
+	require.Equal(t, "flashback/1", rule.ID)
+	require.Equal(t, ruleType, rule.RuleType)
+	require.Equal(t, RuleIndexDefault, rule.Index)
+	require.Equal(t, Labels{{Key: ScheduleKey, Value: ScheduleDeny, TTL: "1h0m0s"}}, rule.Labels)
+
+	require.Len(t, rule.Data, 2)
+	r := rule.Data[0].(map[string]string)
+	require.Equal(t, "7480000000000000ff0100000000000000f8", r["start_key"])
+	require.Equal(t, "7480000000000000ff0300000000000000f8", r["end_key"])
+	r = rule.Data[1].(map[string]string)
+	require.Equal(t, "7480000000000000ff0500000000000000f8", r["start_key"])
+	require.Equal(t, "7480000000000000ff0600000000000000f8", r["end_key"])
+
+	// The TTL isn't restored as an attribute.
+	require.Equal(t, `"schedule=deny"`, rule.Labels.Restore())
+}
@@ -42,6 +42,8 @@ func TestMain(m *testing.M) {
 	ddl.ReorgWaitTimeout = 30 * time.Millisecond
 	ddl.RunInGoTest = true
 	ddl.SetBatchInsertDeleteRangeSize(2)
+	// The tests flash back the cluster to the timestamps got just before.
+	ddl.FlashbackClusterMinLag = 0
 
 	config.UpdateGlobal(func(conf *config.Config) {
 		// Test for table lock.
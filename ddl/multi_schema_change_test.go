@@ -28,6 +28,7 @@ import (
 	"github.com/pingcap/tidb/testkit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
 func TestMultiSchemaChangeAddColumns(t *testing.T) {
@@ -998,11 +999,11 @@ func TestMultiSchemaChangeAdminShowDDLJobs(t *testing.T) {
 			assert.Equal(t, rows[1][2], "t")
 			assert.Equal(t, rows[1][3], "add index /* subjob */")
 			assert.Equal(t, rows[1][4], "delete only")
-			assert.Equal(t, rows[1][len(rows[1])-1], "running")
+			assert.Equal(t, rows[1][11], "running")
 
 			assert.Equal(t, rows[2][3], "add index /* subjob */")
 			assert.Equal(t, rows[2][4], "none")
-			assert.Equal(t, rows[2][len(rows[2])-1], "queueing")
+			assert.Equal(t, rows[2][11], "queueing")
 		}
 	}
 
@@ -1165,49 +1166,62 @@ func TestMultiSchemaChangeMixedWithUpdate(t *testing.T) {
 	dom.DDL().SetHook(originHook)
 }
 
+// cancelOnceHook cancels the job once when pred is satisfied.
 type cancelOnceHook struct {
 	store     kv.Storage
-	triggered bool
-	cancelErr error
+	triggered atomic.Bool
+	cancelErr atomic.Error
 	pred      func(job *model.Job) bool
 	s         sessionctx.Context
 
 	ddl.TestDDLCallback
 }
 
-func (c *cancelOnceHook) OnJobUpdated(job *model.Job) {
-	if c.triggered || !c.pred(job) {
+// cancelErrKey is the key of the cancel result in the HookJobContext.
+const cancelErrKey = "cancelErr"
+
+func (c *cancelOnceHook) onJobUpdated(job *model.Job, jobCtx *ddl.HookJobContext) {
+	if _, triggered := jobCtx.Get(cancelErrKey); triggered || !c.pred(job) {
 		return
 	}
-	c.triggered = true
 	errs, err := ddl.CancelJobs(c.s, c.store, []int64{job.ID})
 	if errs[0] != nil {
-		c.cancelErr = errs[0]
-		return
+		err = errs[0]
+	}
+	jobCtx.Put(cancelErrKey, err)
+}
+
+func (c *cancelOnceHook) onJobFinished(_ *model.Job, jobCtx *ddl.HookJobContext) {
+	if v, triggered := jobCtx.Get(cancelErrKey); triggered {
+		err, _ := v.(error)
+		c.cancelErr.Store(err)
+		c.triggered.Store(true)
 	}
-	c.cancelErr = err
 }
 
 func (c *cancelOnceHook) MustCancelDone(t *testing.T) {
-	require.True(t, c.triggered)
-	require.NoError(t, c.cancelErr)
+	require.True(t, c.triggered.Load())
+	require.NoError(t, c.cancelErr.Load())
 }
 
 func (c *cancelOnceHook) MustCancelFailed(t *testing.T) {
-	require.True(t, c.triggered)
-	require.Contains(t, c.cancelErr.Error(), strconv.Itoa(errno.ErrCannotCancelDDLJob))
+	require.True(t, c.triggered.Load())
+	require.Contains(t, c.cancelErr.Load().Error(), strconv.Itoa(errno.ErrCannotCancelDDLJob))
 }
 
 func newCancelJobHook(t *testing.T, store kv.Storage, dom *domain.Domain,
 	pred func(job *model.Job) bool) *cancelOnceHook {
 	tk := testkit.NewTestKit(t, store)
 	tk.MustExec("use test")
-	return &cancelOnceHook{
+	hook := &cancelOnceHook{
 		store:           store,
 		pred:            pred,
 		TestDDLCallback: ddl.TestDDLCallback{Do: dom},
 		s:               tk.Session(),
 	}
+	hook.OnJobUpdatedWithContext = hook.onJobUpdated
+	hook.OnJobFinishedWithContext = hook.onJobFinished
+	return hook
 }
 
 func putTheSameDDLJobTwice(t *testing.T, fn func()) {
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/sessionctx"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -32,6 +33,9 @@ type Options struct {
 	InfoCache *infoschema.InfoCache
 	Hook      Callback
 	Lease     time.Duration
+
+	SysProcTracker sessionctx.SysProcTracker
+	ServerIDGetter func() uint64
 }
 
 // WithEtcdClient specifies the `clientv3.Client` of DDL used to request the etcd service
@@ -68,3 +72,12 @@ func WithLease(lease time.Duration) Option {
 		options.Lease = lease
 	}
 }
+
+// WithSysProcTracker specifies the tracker and the server ID getter used to track the internal sessions of DDL jobs
+// as system processes
+func WithSysProcTracker(tracker sessionctx.SysProcTracker, serverIDGetter func() uint64) Option {
+	return func(options *Options) {
+		options.SysProcTracker = tracker
+		options.ServerIDGetter = serverIDGetter
+	}
+}
@@ -212,6 +212,9 @@ func (w *worker) runReorgJob(rh *reorgHandler, reorgInfo *reorgInfo, tblInfo *mo
 			return dbterror.ErrCancelledDDLJob
 		}
 		rc = w.newReorgCtx(reorgInfo)
+		// The rate sampled before the job is taken over by this owner or restarted is stale.
+		job.ReorgMeta.Progress = nil
+		w.jobLogger(job).Info("start reorg job", zap.Int64("physicalTableID", reorgInfo.PhysicalTableID))
 		w.wg.Add(1)
 		go func() {
 			defer w.wg.Done()
@@ -238,7 +241,7 @@ func (w *worker) runReorgJob(rh *reorgHandler, reorgInfo *reorgInfo, tblInfo *mo
 			return dbterror.ErrCancelledDDLJob
 		}
 		rowCount, _, _ := rc.getRowCountAndKey()
-		logutil.BgLogger().Info("[ddl] run reorg job done", zap.Int64("handled rows", rowCount))
+		w.jobLogger(job).Info("run reorg job done", zap.Int64("handled rows", rowCount), zap.Error(err))
 		job.SetRowCount(rowCount)
 
 		// Update a job's warnings.
@@ -258,7 +261,7 @@ func (w *worker) runReorgJob(rh *reorgHandler, reorgInfo *reorgInfo, tblInfo *mo
 			metrics.GetBackfillProgressByLabel(metrics.LblModifyColumn, job.SchemaName, tblInfo.Name.String()).Set(0)
 		}
 		if err1 := rh.RemoveDDLReorgHandle(job, reorgInfo.elements); err1 != nil {
-			logutil.BgLogger().Warn("[ddl] run reorg job done, removeDDLReorgHandle failed", zap.Error(err1))
+			w.jobLogger(job).Warn("run reorg job done, removeDDLReorgHandle failed", zap.Error(err1))
 			return errors.Trace(err1)
 		}
 	case <-w.ctx.Done():
@@ -270,7 +273,11 @@ func (w *worker) runReorgJob(rh *reorgHandler, reorgInfo *reorgInfo, tblInfo *mo
 		rowCount, doneKey, currentElement := rc.getRowCountAndKey()
 		// Update a job's RowCount.
 		job.SetRowCount(rowCount)
-		updateBackfillProgress(w, reorgInfo, tblInfo, rowCount)
+		totalCount := updateBackfillProgress(w, reorgInfo, tblInfo, rowCount)
+		if job.ReorgMeta.Progress == nil {
+			job.ReorgMeta.Progress = &model.ReorgProgress{}
+		}
+		job.ReorgMeta.Progress.AddSample(time.Now(), rowCount, totalCount)
 
 		// Update a job's warnings.
 		w.mergeWarningsIntoJob(job)
@@ -304,10 +311,12 @@ func (w *worker) mergeWarningsIntoJob(job *model.Job) {
 	job.SetWarnings(mergeWarningsAndWarningsCount(partWarnings, job.ReorgMeta.Warnings, partWarningsCount, job.ReorgMeta.WarningsCount))
 }
 
+// updateBackfillProgress updates the backfill progress metrics, and returns the estimated total row count of the
+// table, 0 means unknown.
 func updateBackfillProgress(w *worker, reorgInfo *reorgInfo, tblInfo *model.TableInfo,
-	addedRowCount int64) {
+	addedRowCount int64) int64 {
 	if tblInfo == nil || addedRowCount == 0 {
-		return
+		return 0
 	}
 	totalCount := getTableTotalCount(w, tblInfo)
 	progress := float64(0)
@@ -325,6 +334,16 @@ func updateBackfillProgress(w *worker, reorgInfo *reorgInfo, tblInfo *model.Tabl
 	case model.ActionModifyColumn:
 		metrics.GetBackfillProgressByLabel(metrics.LblModifyColumn, reorgInfo.SchemaName, tblInfo.Name.String()).Set(progress * 100)
 	}
+	return totalCount
+}
+
+// EstimateJobRemaining estimates the remaining time of the reorg job by its sampled progress. It returns false if
+// the job isn't in reorg, or the remaining time is unknown.
+func EstimateJobRemaining(job *model.Job) (time.Duration, bool) {
+	if job.ReorgMeta == nil || !job.IsRunning() || job.SchemaState != model.StateWriteReorganization {
+		return 0, false
+	}
+	return job.ReorgMeta.Progress.EstimateRemaining(time.Now())
 }
 
 func getTableTotalCount(w *worker, tblInfo *model.TableInfo) int64 {
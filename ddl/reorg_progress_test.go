@@ -0,0 +1,114 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorgJobEstimatedRemaining(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomainWithSchemaLease(t, 100*time.Millisecond)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	const totalRows = 20000
+	values := make([]string, 0, 1000)
+	for i := 0; i < totalRows; i++ {
+		values = append(values, fmt.Sprintf("(%d, %d)", i, i))
+		if len(values) == cap(values) {
+			tk.MustExec("insert into t values " + strings.Join(values, ","))
+			values = values[:0]
+		}
+	}
+	tk.MustExec("analyze table t")
+	tk.MustQuery("split table t between (0) and (20000) regions 20").Check(testkit.Rows("19 1"))
+	tk.MustQuery("select table_rows from information_schema.tables where table_schema = 'test' and table_name = 't'").Check(testkit.Rows("20000"))
+
+	// Each region of 1000 rows takes about 100ms.
+	tk.MustExec("set @@global.tidb_ddl_reorg_worker_cnt = 1")
+	defer tk.MustExec("set @@global.tidb_ddl_reorg_worker_cnt = default")
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/ddl/mockBackfillSlow", "return"))
+	defer func() {
+		require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/ddl/mockBackfillSlow"))
+	}()
+
+	tk2 := testkit.NewTestKit(t, store)
+	var (
+		checkErr    error
+		checked     int
+		startTime   time.Time
+		startCount  int64
+		jobID       int64
+		showETA     string
+		ddlJobsETA  string
+		expectedETA time.Duration
+	)
+	hook := &ddl.TestDDLCallback{Do: dom}
+	hook.OnJobUpdatedExported = func(job *model.Job) {
+		if checkErr != nil || job.Type != model.ActionAddIndex || job.SchemaState != model.StateWriteReorganization ||
+			job.RowCount == 0 {
+			return
+		}
+		if startTime.IsZero() {
+			startTime, startCount, jobID = time.Now(), job.RowCount, job.ID
+			return
+		}
+		rate := float64(job.RowCount-startCount) / float64(time.Since(startTime))
+		if rate <= 0 || job.RowCount >= totalRows {
+			return
+		}
+		rows := tk2.MustQuery("admin show ddl jobs 1").Rows()
+		if rows[0][12] == "<nil>" {
+			// Too few samples in the window.
+			return
+		}
+		showETA = rows[0][12].(string)
+		ddlJobsETA = tk2.MustQuery("select estimated_remaining from information_schema.ddl_jobs where job_id = ?", jobID).Rows()[0][0].(string)
+		expectedETA = time.Duration(float64(totalRows-job.RowCount) / rate)
+		eta, err := time.ParseDuration(showETA)
+		if err != nil {
+			checkErr = err
+			return
+		}
+		// The rate is estimated in the sliding window, allow it to drift from the average rate.
+		tolerance := expectedETA / 2
+		if tolerance < time.Second {
+			tolerance = time.Second
+		}
+		if eta < expectedETA-tolerance || eta > expectedETA+tolerance {
+			checkErr = fmt.Errorf("the estimated remaining time %s is too far from %s", showETA, expectedETA)
+			return
+		}
+		checked++
+	}
+	dom.DDL().SetHook(hook)
+	tk.MustExec("alter table t add index idx(b)")
+	require.NoError(t, checkErr)
+	require.Greater(t, checked, 0)
+	require.Equal(t, showETA, ddlJobsETA)
+
+	// The job isn't in reorg any more.
+	rows := tk.MustQuery("admin show ddl jobs 1").Rows()
+	require.Equal(t, "<nil>", rows[0][12])
+	tk.MustQuery("select estimated_remaining from information_schema.ddl_jobs where job_id = ?", jobID).Check(testkit.Rows("<nil>"))
+}
@@ -235,6 +235,14 @@ func (d *ddl) checkHistoryJobInTest(ctx sessionctx.Context, historyJob *model.Jo
 			if !isCreateTable && !isCreateSeq && !isCreateView {
 				panic(fmt.Sprintf("job ID %d, parse ddl job failed, query %s", historyJob.ID, historyJob.Query))
 			}
+		case model.ActionFlashbackCluster:
+			// The flashback cluster job is also submitted by ADMIN UNDO FLASHBACK.
+			if admin, ok := st.(*ast.AdminStmt); ok && admin.Tp == ast.AdminUndoFlashback {
+				continue
+			}
+			if _, ok := st.(*ast.FlashBackClusterStmt); !ok {
+				panic(fmt.Sprintf("job ID %d, parse ddl job failed, query %s", historyJob.ID, historyJob.Query))
+			}
 		default:
 			if _, ok := st.(ast.DDLNode); !ok {
 				panic(fmt.Sprintf("job ID %d, parse ddl job failed, query %s", historyJob.ID, historyJob.Query))
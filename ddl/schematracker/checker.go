@@ -269,7 +269,13 @@ func (d Checker) RecoverTable(ctx sessionctx.Context, recoverInfo *ddl.RecoverIn
 }
 
 // FlashbackCluster implements the DDL interface.
-func (d Checker) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64) (err error) {
+func (d Checker) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64, force, killConflictingQueries bool, timeout time.Duration) (err error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+// FlashbackTables implements the DDL interface.
+func (d Checker) FlashbackTables(ctx sessionctx.Context, tables []ast.Ident, flashbackTS uint64, force bool) (err error) {
 	//TODO implement me
 	panic("implement me")
 }
@@ -520,6 +526,11 @@ func (d Checker) GetHook() ddl.Callback {
 	return d.realDDL.GetHook()
 }
 
+// SessionPoolHolders implements the DDL interface.
+func (d Checker) SessionPoolHolders() []ddl.SessionPoolHolder {
+	return d.realDDL.SessionPoolHolders()
+}
+
 // SetHook implements the DDL interface.
 func (d Checker) SetHook(h ddl.Callback) {
 	d.realDDL.SetHook(h)
@@ -307,7 +307,12 @@ func (d SchemaTracker) RecoverTable(ctx sessionctx.Context, recoverInfo *ddl.Rec
 }
 
 // FlashbackCluster implements the DDL interface, which is no-op in DM's case.
-func (d SchemaTracker) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64) (err error) {
+func (d SchemaTracker) FlashbackCluster(ctx sessionctx.Context, flashbackTS uint64, force, killConflictingQueries bool, timeout time.Duration) (err error) {
+	return nil
+}
+
+// FlashbackTables implements the DDL interface, which is no-op in DM's case.
+func (d SchemaTracker) FlashbackTables(ctx sessionctx.Context, tables []ast.Ident, flashbackTS uint64, force bool) (err error) {
 	return nil
 }
 
@@ -1180,6 +1185,11 @@ func (SchemaTracker) GetTableMaxHandle(ctx *ddl.JobContext, startTS uint64, tbl
 // SetBinlogClient implements the DDL interface, it's no-op in DM's case.
 func (SchemaTracker) SetBinlogClient(client *pumpcli.PumpsClient) {}
 
+// SessionPoolHolders implements the DDL interface, it's no-op in DM's case.
+func (SchemaTracker) SessionPoolHolders() []ddl.SessionPoolHolder {
+	return nil
+}
+
 // GetHook implements the DDL interface, it's no-op in DM's case.
 func (d SchemaTracker) GetHook() ddl.Callback {
 	return nil
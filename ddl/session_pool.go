@@ -15,8 +15,13 @@
 package ddl
 
 import (
+	"context"
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ngaut/pools"
 	"github.com/pingcap/errors"
@@ -25,25 +30,64 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/mock"
+	"go.uber.org/zap"
 )
 
+// sessionOwner tells who holds a session of the sessionPool.
+type sessionOwner struct {
+	// jobID is the ID of the DDL job the session is used for, it's 0 if the session isn't used for a job.
+	jobID int64
+	// purpose is what the session is used for, it's the caller of get if it's not specified.
+	purpose string
+	// resident is true if the session is held by its owner all the time, e.g. a DDL worker, so it's never a leak.
+	resident bool
+}
+
+type sessionHolder struct {
+	sessionOwner
+	since    time.Time
+	reported bool
+}
+
+// SessionPoolHolder is a holder of the sessions of the DDL session pool, it's shown in
+// information_schema.internal_session_pool.
+type SessionPoolHolder struct {
+	JobID     int64
+	Purpose   string
+	Resident  bool
+	StartTime time.Time
+}
+
 // sessionPool is used to new session.
 type sessionPool struct {
 	mu struct {
 		sync.Mutex
 		closed bool
+		// holders are the owners of the sessions which aren't put back.
+		holders map[sessionctx.Context]*sessionHolder
 	}
 	resPool *pools.ResourcePool
 	store   kv.Storage
 }
 
 func newSessionPool(resPool *pools.ResourcePool, store kv.Storage) *sessionPool {
-	return &sessionPool{resPool: resPool, store: store}
+	sg := &sessionPool{resPool: resPool, store: store}
+	sg.mu.holders = make(map[sessionctx.Context]*sessionHolder)
+	return sg
 }
 
 // get gets sessionctx from context resource pool.
 // Please remember to call put after you finished using sessionctx.
 func (sg *sessionPool) get() (sessionctx.Context, error) {
+	return sg.getWithOwner(sessionOwner{purpose: callerName(2)})
+}
+
+// getForJob is like get, and the session is tagged with the job and the purpose, which are shown if it's leaked.
+func (sg *sessionPool) getForJob(jobID int64, purpose string) (sessionctx.Context, error) {
+	return sg.getWithOwner(sessionOwner{jobID: jobID, purpose: purpose})
+}
+
+func (sg *sessionPool) getWithOwner(owner sessionOwner) (sessionctx.Context, error) {
 	if sg.resPool == nil {
 		ctx := mock.NewContext()
 		ctx.Store = sg.store
@@ -69,14 +113,37 @@ func (sg *sessionPool) get() (sessionctx.Context, error) {
 	}
 	ctx.GetSessionVars().SetStatusFlag(mysql.ServerStatusAutocommit, true)
 	ctx.GetSessionVars().InRestrictedSQL = true
+	sg.mu.Lock()
+	sg.mu.holders[ctx] = &sessionHolder{sessionOwner: owner, since: time.Now()}
+	sg.mu.Unlock()
 	return ctx, nil
 }
 
+// callerName returns the name of the function skip frames above, e.g. "ddl.(*worker).Close".
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
+
 // put returns sessionctx to context resource pool.
 func (sg *sessionPool) put(ctx sessionctx.Context) {
 	if sg.resPool == nil {
 		return
 	}
+	sg.mu.Lock()
+	_, held := sg.mu.holders[ctx]
+	delete(sg.mu.holders, ctx)
+	sg.mu.Unlock()
+	if !held {
+		// The session is recycled by checkLeaks, and may be held by another owner now.
+		logutil.BgLogger().Warn("[ddl] put the recycled session back to the sessionPool, ignore it",
+			zap.String("caller", callerName(2)))
+		return
+	}
 
 	// no need to protect sg.resPool, even the sg.resPool is closed, the ctx still need to
 	// put into resPool, because when resPool is closing, it will wait all the ctx returns, then resPool finish closing.
@@ -95,3 +162,56 @@ func (sg *sessionPool) close() {
 	sg.resPool.Close()
 	sg.mu.closed = true
 }
+
+// holders returns the holders of the sessions which aren't put back, ordered by the time they get the sessions.
+func (sg *sessionPool) holders() []SessionPoolHolder {
+	sg.mu.Lock()
+	holders := make([]SessionPoolHolder, 0, len(sg.mu.holders))
+	for _, holder := range sg.mu.holders {
+		holders = append(holders, SessionPoolHolder{
+			JobID:     holder.jobID,
+			Purpose:   holder.purpose,
+			Resident:  holder.resident,
+			StartTime: holder.since,
+		})
+	}
+	sg.mu.Unlock()
+	sort.Slice(holders, func(i, j int) bool {
+		return holders[i].StartTime.Before(holders[j].StartTime)
+	})
+	return holders
+}
+
+// checkLeaks logs the sessions which aren't resident and are held longer than threshold, and returns them to the
+// pool if recycle is true. Recycling is dangerous if the holder is still using the session, but it keeps the DDL
+// from hanging on an exhausted pool. It returns the number of the leaked sessions.
+func (sg *sessionPool) checkLeaks(threshold time.Duration, recycle bool) int {
+	if sg.resPool == nil {
+		return 0
+	}
+	var recycled []sessionctx.Context
+	leaked := 0
+	now := time.Now()
+	sg.mu.Lock()
+	for ctx, holder := range sg.mu.holders {
+		if holder.resident || now.Sub(holder.since) < threshold {
+			continue
+		}
+		leaked++
+		if !holder.reported || recycle {
+			logutil.BgLogger().Warn("[ddl] session of the sessionPool may be leaked", zap.Int64("jobID", holder.jobID),
+				zap.String("purpose", holder.purpose), zap.Time("since", holder.since), zap.Bool("recycle", recycle))
+			holder.reported = true
+		}
+		if recycle {
+			delete(sg.mu.holders, ctx)
+			recycled = append(recycled, ctx)
+		}
+	}
+	sg.mu.Unlock()
+	for _, ctx := range recycled {
+		ctx.RollbackTxn(context.Background())
+		sg.resPool.Put(ctx.(pools.Resource))
+	}
+	return leaked
+}
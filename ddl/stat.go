@@ -15,7 +15,10 @@
 package ddl
 
 import (
+	"context"
+
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/sessionctx/variable"
 )
 
@@ -34,6 +37,9 @@ var (
 	ddlJobSnapshotVer = "ddl_job_snapshot_ver"
 	ddlJobReorgHandle = "ddl_job_reorg_handle"
 	ddlJobArgs        = "ddl_job_args"
+	// flashbackWindow is the oldest TSO which flashback cluster accepts now, it's NULL if flashback cluster is
+	// impossible now.
+	flashbackWindow = "tidb_flashback_window"
 )
 
 // GetScope gets the status variables scope.
@@ -59,6 +65,15 @@ func (d *ddl) Stats(vars *variable.SessionVars) (map[string]interface{}, error)
 	}
 
 	m[ddlSchemaVersion] = ddlInfo.SchemaVer
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	oldestTS, ok, err := GetFlashbackWindow(ctx, s)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	m[flashbackWindow] = nil
+	if ok {
+		m[flashbackWindow] = oldestTS
+	}
 	// TODO: Get the owner information.
 	if len(ddlInfo.Jobs) == 0 {
 		return m, nil
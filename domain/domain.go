@@ -114,6 +114,21 @@ type Domain struct {
 	sysExecutorFactory   func(*Domain) (pools.Resource, error)
 
 	sysProcesses SysProcesses
+
+	schemaRollbackListeners schemaRollbackListeners
+	// schemaRollbackEpoch4PC is increased by each schema rollback, the cached plans built before it are stale.
+	schemaRollbackEpoch4PC atomicutil.Uint64
+	// coprCacheEpoch is the last coprocessor cache epoch seen by Reload, it's protected by m.
+	coprCacheEpoch int64
+	// statsReloadNeeded is set when a new coprocessor cache epoch is seen, i.e. the stats tables may be rewound by a
+	// flashback, so the stats cache is reloaded by the loadStatsWorker.
+	statsReloadNeeded atomicutil.Bool
+	// flashbackWatermarks caches the rows of mysql.flashback_watermark, they're reloaded after a new coprocessor cache
+	// epoch is seen.
+	flashbackWatermarks flashbackWatermarks
+	// flashbackRewritingRanges are the ranges of the stats tables being rewritten by the flashback cluster job, which
+	// are seen by the last Reload.
+	flashbackRewritingRanges atomic.Pointer[meta.FlashbackRewritingRanges]
 }
 
 // InfoCache export for test.
@@ -438,6 +453,8 @@ func (do *Domain) Reload() error {
 		return err
 	}
 	metrics.LoadSchemaCounter.WithLabelValues("succ").Inc()
+	do.checkCoprCacheEpoch(ver.Ver)
+	do.checkFlashbackRewritingRanges(ver.Ver)
 
 	// only update if it is not from cache
 	if !hitCache {
@@ -762,6 +779,7 @@ func NewDomain(store kv.Storage, ddlLease time.Duration, statsLease time.Duratio
 	do.SchemaValidator = NewSchemaValidator(ddlLease, do)
 	do.expensiveQueryHandle = expensivequery.NewExpensiveQueryHandle(do.exit)
 	do.sysProcesses = SysProcesses{mu: &sync.RWMutex{}, procMap: make(map[uint64]sessionctx.Context)}
+	do.RegisterSchemaRollbackListener(do.logSchemaRollback)
 	variable.SetStatsCacheCapacity.Store(do.SetStatsCacheCapacity)
 	return do
 }
@@ -835,6 +853,7 @@ func (do *Domain) Init(
 		ddl.WithInfoCache(do.infoCache),
 		ddl.WithHook(callback),
 		ddl.WithLease(ddlLease),
+		ddl.WithSysProcTracker(&do.sysProcesses, do.ServerID),
 	)
 	failpoint.Inject("MockReplaceDDL", func(val failpoint.Value) {
 		if val.(bool) {
@@ -1437,7 +1456,11 @@ func (do *Domain) loadStatsWorker() {
 			if err != nil {
 				logutil.BgLogger().Debug("refresh variables failed", zap.Error(err))
 			}
-			err = statsHandle.Update(do.InfoSchema())
+			if do.statsReloadNeeded.CAS(true, false) {
+				err = statsHandle.ReloadStatsCache(do.InfoSchema())
+			} else {
+				err = statsHandle.Update(do.InfoSchema())
+			}
 			if err != nil {
 				logutil.BgLogger().Debug("update stats info failed", zap.Error(err))
 			}
@@ -1602,6 +1625,7 @@ func (do *Domain) ExpensiveQueryHandle() *expensivequery.Handle {
 const (
 	privilegeKey   = "/tidb/privilege"
 	sysVarCacheKey = "/tidb/sysvars"
+	planCacheKey   = "/tidb/plancache"
 )
 
 // NotifyUpdatePrivilege updates privilege key in etcd, TiDB client that watches
@@ -1652,6 +1676,111 @@ func (do *Domain) NotifyUpdateSysVarCache() {
 	}
 }
 
+// FlushPlanCache flushes the plan caches of all the sessions on this TiDB instance, and returns the number of
+// evicted entries. The plans cached in the prepared statements are flushed when they're executed next time.
+func (do *Domain) FlushPlanCache(now types.Time) int {
+	do.SetExpiredTimeStamp4PC(now)
+	if do.info == nil {
+		return 0
+	}
+	if sm := do.info.GetSessionManager(); sm != nil {
+		return sm.FlushPlanCache()
+	}
+	return 0
+}
+
+// NotifyFlushPlanCache updates the plan cache key in etcd, so that every TiDB instance watching the key flushes
+// its plan caches. The caller should flush the plan caches of the current instance by itself.
+func (do *Domain) NotifyFlushPlanCache() {
+	if do.etcdClient != nil {
+		row := do.etcdClient.KV
+		_, err := row.Put(context.Background(), planCacheKey, "")
+		if err != nil {
+			logutil.BgLogger().Warn("notify flush plan cache failed", zap.Error(err))
+		}
+	}
+}
+
+// FlushPlanCacheLoop creates a goroutine which flushes the plan caches of this instance when it's notified by
+// NotifyFlushPlanCache.
+func (do *Domain) FlushPlanCacheLoop() {
+	if do.etcdClient == nil {
+		return
+	}
+	watchCh := do.etcdClient.Watch(context.Background(), planCacheKey)
+	do.wg.Add(1)
+	go func() {
+		defer func() {
+			do.wg.Done()
+			logutil.BgLogger().Info("FlushPlanCacheLoop exited.")
+			util.Recover(metrics.LabelDomain, "FlushPlanCacheLoop", nil, false)
+		}()
+		var count int
+		for {
+			ok := true
+			select {
+			case <-do.exit:
+				return
+			case _, ok = <-watchCh:
+			}
+			if !ok {
+				logutil.BgLogger().Error("FlushPlanCacheLoop loop watch channel closed")
+				watchCh = do.etcdClient.Watch(context.Background(), planCacheKey)
+				count++
+				if count > 10 {
+					time.Sleep(time.Duration(count) * time.Second)
+				}
+				continue
+			}
+			count = 0
+			now := types.NewTime(types.FromGoTime(time.Now()), mysql.TypeTimestamp, 3)
+			evicted := do.FlushPlanCache(now)
+			logutil.BgLogger().Info("flush plan cache from etcd watch event", zap.Int("evicted", evicted))
+		}
+	}()
+}
+
+// orphanFlashbackCheckInterval is the interval to check the PD schedule saved by the orphaned flashback job.
+var orphanFlashbackCheckInterval = 10 * time.Minute
+
+// CleanupOrphanFlashbackLoop restores the PD schedule saved by the orphaned flashback job when the domain starts, and
+// checks it periodically, because the PD schedule is never restored if the flashback job is lost. The checkpoints
+// left by the orphaned flashback jobs are purged after their retention too.
+// It should be called only once in BootstrapSession.
+func (do *Domain) CleanupOrphanFlashbackLoop(ctx sessionctx.Context) {
+	ctx.GetSessionVars().InRestrictedSQL = true
+	do.cleanupOrphanFlashback(ctx)
+	do.wg.Add(1)
+	go func() {
+		ticker := time.NewTicker(orphanFlashbackCheckInterval)
+		defer func() {
+			ticker.Stop()
+			do.wg.Done()
+			logutil.BgLogger().Info("CleanupOrphanFlashbackLoop exited.")
+			util.Recover(metrics.LabelDomain, "CleanupOrphanFlashbackLoop", nil, false)
+		}()
+		for {
+			select {
+			case <-do.exit:
+				return
+			case <-ticker.C:
+				do.cleanupOrphanFlashback(ctx)
+			}
+		}
+	}()
+}
+
+func (do *Domain) cleanupOrphanFlashback(ctx sessionctx.Context) {
+	internalCtx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	_, err := ddl.CleanupOrphanFlashbackPDSchedule(internalCtx, ctx)
+	if err != nil {
+		logutil.BgLogger().Warn("cleanup the orphaned flashback failed", zap.Error(err))
+	}
+	if _, err = ddl.CleanupOrphanFlashbackCheckpoints(internalCtx, ctx); err != nil {
+		logutil.BgLogger().Warn("cleanup the orphaned flashback checkpoints failed", zap.Error(err))
+	}
+}
+
 // LoadSigningCertLoop loads the signing cert periodically to make sure it's fresh new.
 func (do *Domain) LoadSigningCertLoop() {
 	do.wg.Add(1)
@@ -0,0 +1,119 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"go.uber.org/zap"
+)
+
+// FlashbackWatermark is the timeline erased by a finished flashback job, which is recorded in
+// mysql.flashback_watermark. The data read at a timestamp in (TargetTS, EndTS) doesn't exist in the rewound timeline.
+type FlashbackWatermark struct {
+	JobID int64
+	// TableID is 0 if the whole cluster is flashed back.
+	TableID  int64
+	TargetTS uint64
+	EndTS    uint64
+}
+
+// Contains returns whether ts is inside the erased timeline.
+func (w *FlashbackWatermark) Contains(ts uint64) bool {
+	return ts > w.TargetTS && ts < w.EndTS
+}
+
+type flashbackWatermarks struct {
+	sync.Mutex
+	loaded     bool
+	watermarks []FlashbackWatermark
+}
+
+// invalidate makes the watermarks reloaded the next time they're got.
+func (w *flashbackWatermarks) invalidate() {
+	w.Lock()
+	w.loaded = false
+	w.Unlock()
+}
+
+// GetFlashbackWatermarks returns the flashback watermarks. They're loaded when they're got the first time, and
+// reloaded after a new coprocessor cache epoch is seen by Reload, i.e. after a flashback job finishes.
+func (do *Domain) GetFlashbackWatermarks() ([]FlashbackWatermark, error) {
+	do.flashbackWatermarks.Lock()
+	defer do.flashbackWatermarks.Unlock()
+	if do.flashbackWatermarks.loaded {
+		return do.flashbackWatermarks.watermarks, nil
+	}
+
+	res, err := do.SysSessionPool().Get()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer do.SysSessionPool().Put(res)
+	exec := res.(sqlexec.RestrictedSQLExecutor)
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnOthers)
+	rows, _, err := exec.ExecRestrictedSQL(ctx, nil, "SELECT job_id, table_id, target_ts, end_ts FROM mysql.flashback_watermark")
+	if infoschema.ErrTableNotExists.Equal(err) {
+		// The table isn't created yet during the bootstrap or the upgrade, so no flashback job has finished.
+		rows, err = nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	watermarks := make([]FlashbackWatermark, 0, len(rows))
+	for _, row := range rows {
+		watermarks = append(watermarks, FlashbackWatermark{
+			JobID:    row.GetInt64(0),
+			TableID:  row.GetInt64(1),
+			TargetTS: row.GetUint64(2),
+			EndTS:    row.GetUint64(3),
+		})
+	}
+	do.flashbackWatermarks.watermarks = watermarks
+	do.flashbackWatermarks.loaded = true
+	return watermarks, nil
+}
+
+// FlashbackRewritingRanges returns the ranges of the stats tables being rewritten by the flashback cluster job, which
+// are seen by the last Reload. It returns nil if no flashback job is rewriting them. It's cheap enough to be called
+// for each snapshot read.
+func (do *Domain) FlashbackRewritingRanges() *meta.FlashbackRewritingRanges {
+	return do.flashbackRewritingRanges.Load()
+}
+
+// checkFlashbackRewritingRanges loads the ranges being rewritten by the flashback cluster job at ts.
+func (do *Domain) checkFlashbackRewritingRanges(ts uint64) {
+	r, err := meta.NewSnapshotMeta(do.store.GetSnapshot(kv.NewVersion(ts))).GetFlashbackRewritingRanges()
+	if err != nil {
+		logutil.BgLogger().Warn("get the flashback rewriting ranges failed", zap.Error(err))
+		return
+	}
+	if old := do.flashbackRewritingRanges.Swap(r); (old == nil) != (r == nil) {
+		if r != nil {
+			logutil.BgLogger().Info("reject the snapshot reads of the ranges rewritten by flashback",
+				zap.Int64("jobID", r.JobID), zap.Int("ranges", len(r.Ranges)))
+		} else {
+			logutil.BgLogger().Info("allow the snapshot reads of the ranges rewritten by flashback",
+				zap.Int64("jobID", old.JobID))
+		}
+	}
+}
@@ -113,6 +113,7 @@ type InfoSyncer struct {
 	labelRuleManager        LabelRuleManager
 	placementManager        PlacementManager
 	scheduleManager         ScheduleManager
+	regionManager           RegionManager
 	tiflashPlacementManager TiFlashPlacementManager
 }
 
@@ -195,6 +196,7 @@ func GlobalInfoSyncerInit(ctx context.Context, id string, serverIDGetter func()
 	is.labelRuleManager = initLabelRuleManager(etcdCli)
 	is.placementManager = initPlacementManager(etcdCli)
 	is.scheduleManager = initScheduleManager(etcdCli)
+	is.regionManager = initRegionManager(etcdCli)
 	is.tiflashPlacementManager = initTiFlashPlacementManager(etcdCli)
 	setGlobalInfoSyncer(is)
 	return is, nil
@@ -256,6 +258,13 @@ func initScheduleManager(etcdCli *clientv3.Client) ScheduleManager {
 	return &PDScheduleManager{etcdCli: etcdCli}
 }
 
+func initRegionManager(etcdCli *clientv3.Client) RegionManager {
+	if etcdCli == nil {
+		return &mockRegionManager{}
+	}
+	return &PDRegionManager{etcdCli: etcdCli}
+}
+
 // GetMockTiFlash can only be used in tests to get MockTiFlash
 func GetMockTiFlash() *MockTiFlash {
 	is, err := getGlobalInfoSyncer()
@@ -1035,6 +1044,22 @@ func GetLabelRules(ctx context.Context, ruleIDs []string) (map[string]*label.Rul
 	return is.labelRuleManager.GetLabelRules(ctx, ruleIDs)
 }
 
+// AddKeyRangeScheduleDenyRule puts the label rule with the given ID to PD, which denies PD to schedule the regions in
+// the key ranges, while the other regions are scheduled as usual. The rule expires after the ttl, it's put again to
+// renew the ttl.
+func AddKeyRangeScheduleDenyRule(ctx context.Context, ruleID string, keyRanges []kv.KeyRange, ttl time.Duration) error {
+	if len(keyRanges) == 0 {
+		return nil
+	}
+	return PutLabelRule(ctx, label.NewScheduleDenyRule(ruleID, keyRanges, ttl))
+}
+
+// RemoveKeyRangeScheduleDenyRule removes the label rule put by AddKeyRangeScheduleDenyRule from PD. It's a no-op if
+// the rule doesn't exist.
+func RemoveKeyRangeScheduleDenyRule(ctx context.Context, ruleID string) error {
+	return UpdateLabelRules(ctx, label.NewRulePatch(nil, []string{ruleID}))
+}
+
 // SetTiFlashGroupConfig is a helper function to set tiflash rule group config
 func SetTiFlashGroupConfig(ctx context.Context) error {
 	is, err := getGlobalInfoSyncer()
@@ -1194,3 +1219,30 @@ func SetPDScheduleConfig(ctx context.Context, config map[string]interface{}) err
 	}
 	return is.scheduleManager.SetPDScheduleConfig(ctx, config)
 }
+
+// GetRegionsInfoByRange gets the regions in the range [startKey, endKey) of the encoded keys from pd
+func GetRegionsInfoByRange(ctx context.Context, startKey, endKey []byte) (*helper.RegionsInfo, error) {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return is.regionManager.GetRegionsInfoByRange(ctx, startKey, endKey)
+}
+
+// GetStoresStat gets the stores of the cluster from pd
+func GetStoresStat(ctx context.Context) (*helper.StoresStat, error) {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return is.regionManager.GetStoresStat(ctx)
+}
+
+// ScatterRegions asks pd to scatter the regions
+func ScatterRegions(ctx context.Context, regionIDs []uint64) error {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return is.regionManager.ScatterRegions(ctx, regionIDs)
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package infosync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/parser/terror"
+	util2 "github.com/pingcap/tidb/util"
+)
+
+// ProcessListItem is a connection of a TiDB instance, which is listed by the status API `/processlist`.
+type ProcessListItem struct {
+	ConnectionID uint64    `json:"connection_id"`
+	User         string    `json:"user"`
+	Host         string    `json:"host"`
+	DB           string    `json:"db"`
+	Command      string    `json:"command"`
+	Info         string    `json:"info"`
+	StartTime    time.Time `json:"start_time"`
+	TxnStartTS   uint64    `json:"txn_start_ts"`
+	// DDLJobID is the ID of the DDL job which the connection is waiting for.
+	DDLJobID int64 `json:"ddl_job_id"`
+}
+
+// NewProcessListItem creates the ProcessListItem from the process info of the connection.
+func NewProcessListItem(pi *util2.ProcessInfo) *ProcessListItem {
+	item := &ProcessListItem{
+		ConnectionID: pi.ID,
+		User:         pi.User,
+		Host:         pi.Host,
+		DB:           pi.DB,
+		Command:      mysql.Command2Str[pi.Command],
+		Info:         pi.Info,
+		StartTime:    pi.Time,
+		TxnStartTS:   pi.CurTxnStartTS,
+	}
+	if pi.StmtCtx != nil {
+		item.DDLJobID = pi.StmtCtx.DDLJobID
+	}
+	return item
+}
+
+// GetSessionManager gets the session manager of the TiDB instance, it returns nil if there is no session manager.
+func GetSessionManager() util2.SessionManager {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return nil
+	}
+	return is.GetSessionManager()
+}
+
+// GetProcessList gets the connections of the TiDB instance. The connections of this instance are got from the
+// session manager directly, and the ones of the other instances are got by the status API `/processlist`.
+func GetProcessList(ctx context.Context, server *ServerInfo) ([]*ProcessListItem, error) {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return nil, err
+	}
+	if server.ID == is.info.ID {
+		sm := is.GetSessionManager()
+		if sm == nil {
+			return nil, nil
+		}
+		pl := sm.ShowProcessList()
+		items := make([]*ProcessListItem, 0, len(pl))
+		for _, pi := range pl {
+			items = append(items, NewProcessListItem(pi))
+		}
+		return items, nil
+	}
+
+	body, err := requestTiDBStatus(ctx, server, "/processlist", http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	var items []*ProcessListItem
+	if err = json.Unmarshal(body, &items); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return items, nil
+}
+
+// KillQuery kills the running query of the connection of the TiDB instance. The query of this instance is killed by
+// the session manager directly, and the one of the other instances is killed by the status API
+// `/processlist/{connID}/kill`.
+func KillQuery(ctx context.Context, server *ServerInfo, connID uint64) error {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return err
+	}
+	if server.ID == is.info.ID {
+		if sm := is.GetSessionManager(); sm != nil {
+			sm.Kill(connID, true)
+		}
+		return nil
+	}
+	_, err = requestTiDBStatus(ctx, server, fmt.Sprintf("/processlist/%d/kill", connID), http.MethodPost)
+	return err
+}
+
+func requestTiDBStatus(ctx context.Context, server *ServerInfo, route, method string) ([]byte, error) {
+	addr := fmt.Sprintf("%s:%d", server.IP, server.StatusPort)
+	req, err := http.NewRequestWithContext(ctx, method, util2.ComposeURL(addr, route), nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	resp, err := util2.InternalHTTPClient().Do(req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer func() {
+		terror.Log(resp.Body.Close())
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("request %s of the TiDB %s failed, status: %s, body: %s", route, addr, resp.Status, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package infosync
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/store/helper"
+	"github.com/pingcap/tidb/util/pdapi"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// RegionManager gets the regions and the stores from PD, and schedules the regions.
+type RegionManager interface {
+	// GetRegionsInfoByRange gets the regions in the range [startKey, endKey) of the encoded keys.
+	GetRegionsInfoByRange(ctx context.Context, startKey, endKey []byte) (*helper.RegionsInfo, error)
+	// GetStoresStat gets the stores of the cluster.
+	GetStoresStat(ctx context.Context) (*helper.StoresStat, error)
+	// ScatterRegions scatters the regions, PD creates the scatter operators and returns without waiting for them.
+	ScatterRegions(ctx context.Context, regionIDs []uint64) error
+}
+
+// PDRegionManager manages the regions with pd
+type PDRegionManager struct {
+	etcdCli *clientv3.Client
+}
+
+// GetRegionsInfoByRange gets the regions in the range from pd
+func (rm *PDRegionManager) GetRegionsInfoByRange(ctx context.Context, startKey, endKey []byte) (*helper.RegionsInfo, error) {
+	route := fmt.Sprintf("%s?key=%s&end_key=%s", pdapi.ScanRegions, url.QueryEscape(string(startKey)), url.QueryEscape(string(endKey)))
+	res, err := doRequest(ctx, "GetRegionsInfoByRange", rm.etcdCli.Endpoints(), route, "GET", nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	regionsInfo := helper.NewRegionsInfo()
+	if err = json.Unmarshal(res, regionsInfo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return regionsInfo, nil
+}
+
+// GetStoresStat gets the stores from pd
+func (rm *PDRegionManager) GetStoresStat(ctx context.Context) (*helper.StoresStat, error) {
+	res, err := doRequest(ctx, "GetStoresStat", rm.etcdCli.Endpoints(), pdapi.Stores, "GET", nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var stores helper.StoresStat
+	if err = json.Unmarshal(res, &stores); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &stores, nil
+}
+
+// ScatterRegions scatters the regions by pd
+func (rm *PDRegionManager) ScatterRegions(ctx context.Context, regionIDs []uint64) error {
+	body, err := json.Marshal(map[string]interface{}{"regions_id": regionIDs})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = doRequest(ctx, "ScatterRegions", rm.etcdCli.Endpoints(), path.Join(pdapi.Regions, "scatter"), "POST", bytes.NewReader(body))
+	return errors.Trace(err)
+}
+
+type mockRegionManager struct {
+	sync.Mutex
+	regions   []helper.RegionInfo
+	stores    helper.StoresStat
+	scattered []uint64
+}
+
+// GetRegionsInfoByRange gets the mock regions overlapping with the range
+func (mm *mockRegionManager) GetRegionsInfoByRange(ctx context.Context, startKey, endKey []byte) (*helper.RegionsInfo, error) {
+	mm.Lock()
+	defer mm.Unlock()
+
+	// The keys in the regions are the upper case hex strings of the encoded keys, which keep the order of the keys.
+	start, end := strings.ToUpper(hex.EncodeToString(startKey)), strings.ToUpper(hex.EncodeToString(endKey))
+	regionsInfo := helper.NewRegionsInfo()
+	for _, region := range mm.regions {
+		if (region.EndKey == "" || region.EndKey > start) && (end == "" || region.StartKey < end) {
+			regionsInfo.Regions = append(regionsInfo.Regions, region)
+		}
+	}
+	regionsInfo.Count = int64(len(regionsInfo.Regions))
+	return regionsInfo, nil
+}
+
+// GetStoresStat gets the mock stores
+func (mm *mockRegionManager) GetStoresStat(ctx context.Context) (*helper.StoresStat, error) {
+	mm.Lock()
+	defer mm.Unlock()
+
+	stores := mm.stores
+	return &stores, nil
+}
+
+// ScatterRegions records the scattered regions
+func (mm *mockRegionManager) ScatterRegions(ctx context.Context, regionIDs []uint64) error {
+	mm.Lock()
+	defer mm.Unlock()
+
+	mm.scattered = append(mm.scattered, regionIDs...)
+	return nil
+}
+
+// SetMockRegions can only be used in tests to set the regions and the stores returned by the mock PD, the recorded
+// scattered regions are reset.
+func SetMockRegions(regions []helper.RegionInfo, stores []helper.StoreStat) {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return
+	}
+	if m, ok := is.regionManager.(*mockRegionManager); ok {
+		m.Lock()
+		m.regions = regions
+		m.stores = helper.StoresStat{Count: len(stores), Stores: stores}
+		m.scattered = nil
+		m.Unlock()
+	}
+}
+
+// GetMockScatteredRegions can only be used in tests to get the regions scattered by the mock PD.
+func GetMockScatteredRegions() []uint64 {
+	is, err := getGlobalInfoSyncer()
+	if err != nil {
+		return nil
+	}
+	if m, ok := is.regionManager.(*mockRegionManager); ok {
+		m.Lock()
+		defer m.Unlock()
+		return append([]uint64(nil), m.scattered...)
+	}
+	return nil
+}
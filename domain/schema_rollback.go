@@ -0,0 +1,168 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"go.uber.org/zap"
+)
+
+// SchemaRollbackListener is notified after a flashback rewinds the schema, e.g. to invalidate the caches of the
+// external schema registries. oldVer and newVer are the schema versions before and after the flashback, and tso is
+// the timestamp flashed back to.
+type SchemaRollbackListener func(oldVer, newVer int64, tso uint64)
+
+// schemaRollbackNotifyTimeout is the max time the flashback statement waits for the listeners before returning.
+var schemaRollbackNotifyTimeout = 10 * time.Second
+
+type schemaRollbackListeners struct {
+	sync.RWMutex
+	listeners []SchemaRollbackListener
+}
+
+// RegisterSchemaRollbackListener registers the listener which is notified after a flashback statement executed on
+// this TiDB instance succeeds.
+func (do *Domain) RegisterSchemaRollbackListener(listener SchemaRollbackListener) {
+	do.schemaRollbackListeners.Lock()
+	defer do.schemaRollbackListeners.Unlock()
+	do.schemaRollbackListeners.listeners = append(do.schemaRollbackListeners.listeners, listener)
+}
+
+// SchemaRollbackEpoch4PC returns the number of the schema rollbacks notified on this TiDB instance. The cached plans
+// built in an older epoch are stale, and are rebuilt when they're hit the next time, so the re-optimizations after a
+// flashback are spread over the first uses of the plans.
+func (do *Domain) SchemaRollbackEpoch4PC() uint64 {
+	return do.schemaRollbackEpoch4PC.Load()
+}
+
+// NotifySchemaRollback reloads the infoschema and the stats, increases the SchemaRollbackEpoch4PC, and notifies the listeners of the schema rollback from oldVer. It's
+// called once by the flashback statement after the flashback job succeeds, so the listeners are notified once for a
+// flashback even if the job is retried by another DDL owner. The listeners are called concurrently, and their panics
+// are recovered. It returns when all the listeners return, or after schemaRollbackNotifyTimeout.
+func (do *Domain) NotifySchemaRollback(oldVer int64, tso uint64) {
+	if err := do.Reload(); err != nil {
+		logutil.BgLogger().Warn("reload the infoschema after the flashback failed", zap.Error(err))
+	}
+	newVer := do.InfoSchema().SchemaMetaVersion()
+	// The stats are reloaded before returning, so the statements after the flashback are optimized with the rewound
+	// stats, rather than with the ones cached before the flashback.
+	if statsHandle := do.StatsHandle(); statsHandle != nil && do.statsReloadNeeded.CAS(true, false) {
+		if err := statsHandle.ReloadStatsCache(do.InfoSchema()); err != nil {
+			logutil.BgLogger().Warn("reload the stats after the flashback failed", zap.Error(err))
+		}
+	}
+	do.schemaRollbackEpoch4PC.Inc()
+
+	do.schemaRollbackListeners.RLock()
+	listeners := do.schemaRollbackListeners.listeners
+	do.schemaRollbackListeners.RUnlock()
+	var wg util.WaitGroupWrapper
+	for _, listener := range listeners {
+		listener := listener
+		wg.Run(func() {
+			defer util.Recover(metrics.LabelDomain, "SchemaRollbackListener", nil, false)
+			listener(oldVer, newVer, tso)
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(schemaRollbackNotifyTimeout):
+		logutil.BgLogger().Warn("wait for the schema rollback listeners timeout", zap.Int64("oldVer", oldVer),
+			zap.Int64("newVer", newVer), zap.Uint64("tso", tso), zap.Duration("timeout", schemaRollbackNotifyTimeout))
+	}
+}
+
+// logSchemaRollback is the default schema rollback listener, which writes the schema rollback into
+// mysql.schema_rollback_log.
+func (do *Domain) logSchemaRollback(oldVer, newVer int64, tso uint64) {
+	sysSessionPool := do.SysSessionPool()
+	res, err := sysSessionPool.Get()
+	if err != nil {
+		logutil.BgLogger().Warn("log the schema rollback failed", zap.Error(err))
+		return
+	}
+	defer sysSessionPool.Put(res)
+	exec := res.(sqlexec.RestrictedSQLExecutor)
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnOthers)
+	_, _, err = exec.ExecRestrictedSQL(ctx, nil, "INSERT HIGH_PRIORITY INTO mysql.schema_rollback_log (old_schema_version, new_schema_version, flashback_ts) VALUES (%?, %?, %?)",
+		oldVer, newVer, tso)
+	if err != nil {
+		logutil.BgLogger().Warn("log the schema rollback failed", zap.Int64("oldVer", oldVer), zap.Int64("newVer", newVer),
+			zap.Uint64("tso", tso), zap.Error(err))
+	}
+}
+
+// NotifySchemaRollbackAfterJob notifies the listeners of the schema rollback from oldVer in the background after the
+// flashback job succeeds. It's called when the flashback statement returns before its job is done, e.g. the
+// statement is killed and detached from the job.
+func (do *Domain) NotifySchemaRollbackAfterJob(jobID, oldVer int64, tso uint64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	do.wg.Run(func() {
+		select {
+		case <-do.exit:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+	do.wg.Run(func() {
+		defer cancel()
+		defer util.Recover(metrics.LabelDomain, "NotifySchemaRollbackAfterJob", nil, false)
+		if _, err := ddl.WaitJobDone(ctx, do.store, jobID); err != nil {
+			logutil.BgLogger().Info("the flashback job isn't done successfully, skip notifying the schema rollback",
+				zap.Int64("jobID", jobID), zap.Error(err))
+			return
+		}
+		do.NotifySchemaRollback(oldVer, tso)
+	})
+}
+
+// checkCoprCacheEpoch invalidates the coprocessor cache of the store if the coprocessor cache epoch is bumped by a
+// flashback job since the last check, i.e. the cache items populated by the reads before ts are dropped. It's called
+// by Reload, so every TiDB instance drops the stale items within a schema lease after the flashback. The stats cache
+// is also reloaded by the next round of the loadStatsWorker, since the stats tables may be rewound, and the flashback
+// watermarks are reloaded the next time they're got.
+func (do *Domain) checkCoprCacheEpoch(ts uint64) {
+	epoch, err := meta.NewSnapshotMeta(do.store.GetSnapshot(kv.NewVersion(ts))).GetCoprCacheEpoch()
+	if err != nil {
+		logutil.BgLogger().Warn("get the coprocessor cache epoch failed", zap.Error(err))
+		return
+	}
+	if epoch == do.coprCacheEpoch {
+		return
+	}
+	if invalidator, ok := do.store.(kv.CoprCacheInvalidator); ok {
+		invalidator.InvalidateCoprCache(ts)
+	}
+	logutil.BgLogger().Info("invalidate the coprocessor cache", zap.Int64("oldEpoch", do.coprCacheEpoch),
+		zap.Int64("newEpoch", epoch), zap.Uint64("ts", ts))
+	do.coprCacheEpoch = epoch
+	do.statsReloadNeeded.Store(true)
+	do.flashbackWatermarks.invalidate()
+}
@@ -49,6 +49,9 @@ type SchemaValidator interface {
 	Update(leaseGrantTime uint64, oldSchemaVer, newSchemaVer int64, change *transaction.RelatedSchemaChange)
 	// Check is it valid for a transaction to use schemaVer and related tables, at timestamp txnTS.
 	Check(txnTS uint64, schemaVer int64, relatedPhysicalTableIDs []int64) (*transaction.RelatedSchemaChange, checkResult)
+	// IsRelatedTablesChanged returns whether the related tables are changed after schemaVer. It returns true if it
+	// can't be told, e.g. the history of the changes since schemaVer is discarded.
+	IsRelatedTablesChanged(schemaVer int64, relatedPhysicalTableIDs []int64) bool
 	// Stop stops checking the valid of transaction.
 	Stop()
 	// Restart restarts the schema validator after it is stopped.
@@ -264,6 +267,20 @@ func (s *schemaValidator) Check(txnTS uint64, schemaVer int64, relatedPhysicalTa
 	return nil, ResultSucc
 }
 
+// IsRelatedTablesChanged implements SchemaValidator.IsRelatedTablesChanged.
+func (s *schemaValidator) IsRelatedTablesChanged(schemaVer int64, relatedPhysicalTableIDs []int64) bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if !s.isStarted {
+		return true
+	}
+	if schemaVer >= s.latestSchemaVer {
+		return false
+	}
+	_, changed := s.isRelatedTablesChanged(schemaVer, relatedPhysicalTableIDs)
+	return changed
+}
+
 func (s *schemaValidator) enqueue(schemaVersion int64, change *transaction.RelatedSchemaChange) {
 	maxCnt := int(variable.GetMaxDeltaSchemaCount())
 	if maxCnt <= 0 {
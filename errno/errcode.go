@@ -1077,6 +1077,11 @@ const (
 	ErrPartitionColumnStatsMissing        = 8244
 	ErrColumnInChange                     = 8245
 	ErrDDLSetting                         = 8246
+	ErrStaleReadAcrossFlashback           = 8247
+	ErrFlashbackCommitConflict            = 8248
+	ErrSnapshotReadFlashbackRewriting     = 8249
+	ErrFlashbackTSTooRecent               = 8250
+	ErrFlashbackCollationMismatch         = 8251
 
 	// TiKV/PD/TiFlash errors.
 	ErrPDServerTimeout           = 9001
@@ -1092,4 +1097,5 @@ const (
 	ErrTiKVMaxTimestampNotSynced = 9011
 	ErrTiFlashServerTimeout      = 9012
 	ErrTiFlashServerBusy         = 9013
+	ErrGCTooEarlyForRead         = 9014
 )
@@ -1082,6 +1082,11 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrPlacementPolicyWithDirectOption: mysql.Message("Placement policy '%s' can't co-exist with direct placement options", nil),
 	ErrPlacementPolicyInUse:            mysql.Message("Placement policy '%-.192s' is still in use", nil),
 	ErrOptOnCacheTable:                 mysql.Message("'%s' is unsupported on cache tables.", nil),
+	ErrStaleReadAcrossFlashback:        mysql.Message("The read timestamp %d is inside the timeline erased by the flashback job %d, which flashed back from %d to %d", nil),
+	ErrFlashbackCommitConflict:         mysql.Message("The transaction started at %d before the flashback job %d finished at %d can't be committed on top of the data flashed back to %d, please retry the transaction", nil),
+	ErrSnapshotReadFlashbackRewriting:  mysql.Message("The snapshot read of the table %s is rejected since the flashback job %d is rewriting it, please retry after the job finishes", nil),
+	ErrFlashbackTSTooRecent:            mysql.Message("The flashback timestamp %s isn't at least %s earlier than the current time %s, there is nothing to flash back", nil),
+	ErrFlashbackCollationMismatch:      mysql.Message("The index keys of the tables %s are encoded in different collations at the flashback timestamp and now, can't do flashback", nil),
 
 	ErrColumnInChange: mysql.Message("column %s id %d does not exist, this column may have been updated by other DDL ran in parallel", nil),
 	// TiKV/PD errors.
@@ -1093,6 +1098,7 @@ var MySQLErrName = map[uint16]*mysql.ErrMessage{
 	ErrResolveLockTimeout:        mysql.Message("Resolve lock timeout", nil),
 	ErrRegionUnavailable:         mysql.Message("Region is unavailable", nil),
 	ErrGCTooEarly:                mysql.Message("GC life time is shorter than transaction duration, transaction starts at %v, GC safe point is %v", nil),
+	ErrGCTooEarlyForRead:         mysql.Message("Data at read timestamp %d (%s) may have been garbage collected, current GC safe point is %d (%s), please increase tidb_gc_life_time or read at a later timestamp", nil),
 	ErrWriteConflict:             mysql.Message("Write conflict, txnStartTS=%d, conflictStartTS=%d, conflictCommitTS=%d, key=%s", []int{3}),
 	ErrTiKVStoreLimit:            mysql.Message("Store token is up to the limit, store id = %d", nil),
 	ErrPrometheusAddrIsNotSet:    mysql.Message("Prometheus address is not set in PD and etcd", nil),
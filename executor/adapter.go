@@ -91,6 +91,10 @@ type recordSet struct {
 	stmt       *ExecStmt
 	lastErr    error
 	txnStartTS uint64
+	// params are the params of the statement whose record set is held by a cursor. The executors of the cached
+	// plans read the params from the session lazily, which are overwritten by the other statements executed between
+	// the fetches, so the record set keeps its own params and swaps them in while it's fetched.
+	params variable.PreparedParams
 }
 
 func (a *recordSet) Fields() []*ast.ResultField {
@@ -149,6 +153,14 @@ func (a *recordSet) Next(ctx context.Context, req *chunk.Chunk) (err error) {
 		logutil.Logger(ctx).Error("execute sql panic", zap.String("sql", a.stmt.GetTextToLog()), zap.Stack("stack"))
 	}()
 
+	if a.params != nil {
+		sessVars := a.stmt.Ctx.GetSessionVars()
+		originalParams := sessVars.PreparedParams
+		sessVars.PreparedParams = a.params
+		defer func() {
+			sessVars.PreparedParams = originalParams
+		}()
+	}
 	err = a.stmt.next(ctx, a.executor, req)
 	if err != nil {
 		a.lastErr = err
@@ -283,6 +295,8 @@ func (a *ExecStmt) PointGet(ctx context.Context) (*recordSet, error) {
 			// CachedPlan type is already checked in last step
 			pointGetPlan := a.PsStmt.PreparedAst.CachedPlan.(*plannercore.PointGetPlan)
 			exec.Init(pointGetPlan)
+			// The runtime stats are per execution, the cached executor can't carry them across the executions.
+			exec.resetRuntimeStats()
 			a.PsStmt.Executor = exec
 		}
 	}
@@ -523,11 +537,21 @@ func (a *ExecStmt) Exec(ctx context.Context) (_ sqlexec.RecordSet, err error) {
 		txnStartTS = txn.StartTS()
 	}
 
-	return &recordSet{
+	rs := &recordSet{
 		executor:   e,
 		stmt:       a,
 		txnStartTS: txnStartTS,
-	}, nil
+	}
+	if a.useCursor() && len(sctx.GetSessionVars().PreparedParams) > 0 {
+		rs.params = append(variable.PreparedParams(nil), sctx.GetSessionVars().PreparedParams...)
+	}
+	return rs, nil
+}
+
+// useCursor returns whether the record set of the statement is held by a server-side cursor.
+func (a *ExecStmt) useCursor() bool {
+	execStmt, ok := a.StmtNode.(*ast.ExecuteStmt)
+	return ok && execStmt.UseCursor
 }
 
 func (a *ExecStmt) handleNoDelay(ctx context.Context, e Executor, isPessimistic bool) (handled bool, rs sqlexec.RecordSet, err error) {
@@ -895,6 +919,7 @@ func (a *ExecStmt) buildExecutor() (Executor, error) {
 	}
 
 	b := newExecutorBuilder(ctx, a.InfoSchema, a.Ti)
+	b.forCursor = a.useCursor()
 	e := b.build(a.Plan)
 	if b.err != nil {
 		return nil, errors.Trace(b.err)
@@ -1149,7 +1174,7 @@ func (a *ExecStmt) observePhaseDurations(internal bool, commitDetails *util.Comm
 // 2. record summary statement.
 // 3. record execute duration metric.
 // 4. update the `PrevStmt` in session variable.
-// 5. reset `DurationParse` in session variable.
+// 5. reset `DurationParse` and the durations of the plan cache in session variable.
 func (a *ExecStmt) FinishExecuteStmt(txnTS uint64, err error, hasMoreResults bool) {
 	sessVars := a.Ctx.GetSessionVars()
 	execDetail := sessVars.StmtCtx.GetExecDetails()
@@ -1197,6 +1222,10 @@ func (a *ExecStmt) FinishExecuteStmt(txnTS uint64, err error, hasMoreResults boo
 	}
 	// Reset DurationParse due to the next statement may not need to be parsed (not a text protocol query).
 	sessVars.DurationParse = 0
+	// Reset the durations of the plan cache, the next statement may not go through it.
+	sessVars.DurationParameterize = 0
+	sessVars.DurationPlanCacheLookup = 0
+	sessVars.DurationOptimization = 0
 	// Clean the stale read flag when statement execution finish
 	sessVars.StmtCtx.IsStaleness = false
 
@@ -1226,7 +1255,7 @@ func (a *ExecStmt) LogSlowQuery(txnTS uint64, succ bool, hasMoreResults bool) {
 	stmtCtx := sessVars.StmtCtx
 	level := log.GetLevel()
 	cfg := config.GetGlobalConfig()
-	costTime := time.Since(sessVars.StartTime) + sessVars.DurationParse
+	costTime := time.Since(sessVars.StartTime) + sessVars.DurationParse + sessVars.DurationParameterize
 	threshold := time.Duration(atomic.LoadUint64(&cfg.Instance.SlowThreshold)) * time.Millisecond
 	enable := cfg.Instance.EnableSlowLog.Load()
 	// if the level is Debug, or trace is enabled, print slow logs anyway
@@ -1294,6 +1323,8 @@ func (a *ExecStmt) LogSlowQuery(txnTS uint64, succ bool, hasMoreResults bool) {
 		TimeCompile:       sessVars.DurationCompile,
 		TimeOptimize:      sessVars.DurationOptimization,
 		TimeWaitTS:        sessVars.DurationWaitTS,
+		TimeParameterize:  sessVars.DurationParameterize,
+		TimeCacheLookup:   sessVars.DurationPlanCacheLookup,
 		IndexNames:        indexNames,
 		StatsInfos:        statsInfos,
 		CopTasks:          copTaskInfo,
@@ -1306,8 +1337,9 @@ func (a *ExecStmt) LogSlowQuery(txnTS uint64, succ bool, hasMoreResults bool) {
 		BinaryPlan:        binaryPlan,
 		Prepared:          a.isPreparedStmt,
 		HasMoreResults:    hasMoreResults,
-		PlanFromCache:     sessVars.FoundInPlanCache,
+		PlanFromCache:     stmtCtx.FoundInPlanCache(),
 		PlanFromBinding:   sessVars.FoundInBinding,
+		PlanCacheStatus:   stmtCtx.GetPlanCacheStatus(),
 		RewriteInfo:       sessVars.RewritePhaseInfo,
 		KVTotal:           time.Duration(atomic.LoadInt64(&tikvExecDetail.WaitKVRespDuration)),
 		PDTotal:           time.Duration(atomic.LoadInt64(&tikvExecDetail.WaitPDRespDuration)),
@@ -1500,7 +1532,7 @@ func (a *ExecStmt) SummaryStmt(succ bool) {
 		stmtCtx.StmtType = ast.GetStmtLabel(a.StmtNode)
 	}
 	normalizedSQL, digest := stmtCtx.SQLDigest()
-	costTime := time.Since(sessVars.StartTime) + sessVars.DurationParse
+	costTime := time.Since(sessVars.StartTime) + sessVars.DurationParse + sessVars.DurationParameterize
 	charset, collation := sessVars.GetCharsetInfo()
 
 	var prevSQL, prevSQLDigest string
@@ -1592,13 +1624,17 @@ func (a *ExecStmt) SummaryStmt(succ bool) {
 		StartTime:           sessVars.StartTime,
 		IsInternal:          sessVars.InRestrictedSQL,
 		Succeed:             succ,
-		PlanInCache:         sessVars.FoundInPlanCache,
+		PlanInCache:         stmtCtx.FoundInPlanCache(),
+		PlanCacheStatus:     stmtCtx.GetPlanCacheStatus(),
 		PlanInBinding:       sessVars.FoundInBinding,
 		ExecRetryCount:      a.retryCount,
 		StmtExecDetails:     stmtDetail,
 		ResultRows:          resultRows,
 		TiKVExecDetails:     tikvExecDetail,
 		Prepared:            a.isPreparedStmt,
+		PlanCacheTemplateGen: func() string {
+			return plannercore.GetPlanCacheTemplate(a.Ctx, a.StmtNode)
+		},
 	}
 	if a.retryCount > 0 {
 		stmtExecInfo.ExecRetryTime = costTime - sessVars.DurationParse - sessVars.DurationCompile - time.Since(a.retryStartTime)
@@ -1610,16 +1646,47 @@ func (a *ExecStmt) SummaryStmt(succ bool) {
 func (a *ExecStmt) GetTextToLog() string {
 	var sql string
 	sessVars := a.Ctx.GetSessionVars()
+	if execStmt, ok := a.StmtNode.(*ast.ExecuteStmt); ok && execStmt.FromGeneralStmt {
+		if sql, ok = generalStmtTextToLog(a.Ctx, execStmt); ok {
+			return sql
+		}
+	}
 	if sessVars.EnableRedactLog {
 		sql, _ = sessVars.StmtCtx.SQLDigest()
 	} else if sensitiveStmt, ok := a.StmtNode.(ast.SensitiveStmtNode); ok {
 		sql = sensitiveStmt.SecureText()
+	} else if execStmt, ok := a.StmtNode.(*ast.ExecuteStmt); ok && execStmt.FromGeneralStmt && (len(execStmt.ParamTexts) > 0 || execStmt.GeneralSQL != "") {
+		// The real literals have been put back to the original SQL.
+		sql = sessVars.StmtCtx.OriginalSQL
 	} else {
 		sql = sessVars.StmtCtx.OriginalSQL + sessVars.PreparedParams.String()
 	}
 	return sql
 }
 
+// generalStmtTextToLog returns the original SQL of the statement converted from the general query, it's reconstructed
+// from the template and the params of the statement, and the params are redacted if the redact log is enabled. The
+// text sent by the client is used as is if it's kept and the params needn't be redacted.
+func generalStmtTextToLog(sctx sessionctx.Context, execStmt *ast.ExecuteStmt) (string, bool) {
+	redact := sctx.GetSessionVars().EnableRedactLog
+	if !redact && execStmt.GeneralSQL != "" {
+		return execStmt.GeneralSQL, true
+	}
+	cachedStmt, ok := execStmt.PrepStmt.(*plannercore.PlanCacheStmt)
+	if !ok {
+		return "", false
+	}
+	params, ok := execStmt.BinaryArgs.([]expression.Expression)
+	if !ok && !redact {
+		return "", false
+	}
+	sql, err := plannercore.RestoreSQLWithParams(sctx, cachedStmt.PreparedAst.Stmt, params, redact)
+	if err != nil {
+		return "", false
+	}
+	return sql, true
+}
+
 func (a *ExecStmt) observeStmtBeginForTopSQL(ctx context.Context) context.Context {
 	vars := a.Ctx.GetSessionVars()
 	sc := vars.StmtCtx
@@ -1673,7 +1740,7 @@ func (a *ExecStmt) observeStmtFinishedForTopSQL() {
 	}
 	if stats := a.Ctx.GetStmtStats(); stats != nil && topsqlstate.TopSQLEnabled() {
 		sqlDigest, planDigest := a.getSQLPlanDigest()
-		execDuration := time.Since(vars.StartTime) + vars.DurationParse
+		execDuration := time.Since(vars.StartTime) + vars.DurationParse + vars.DurationParameterize
 		stats.OnExecutionFinished(sqlDigest, planDigest, execDuration)
 	}
 }
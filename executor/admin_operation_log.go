@@ -0,0 +1,154 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pingcap/tidb/domain"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessiontxn/staleread"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/sqlexec"
+	"go.uber.org/zap"
+)
+
+const (
+	adminOperationPending = "pending"
+	adminOperationSucceed = "succeed"
+	adminOperationFailed  = "failed"
+
+	insertAdminOperationLogSQL = `INSERT HIGH_PRIORITY INTO mysql.admin_operation_log
+		(user, host, stmt_type, digest, query, target_ts, job_id, result, message) VALUES (%?, %?, %?, %?, %?, %?, %?, %?, %?)`
+)
+
+// adminOperationLogger records the destructive admin operations into mysql.admin_operation_log.
+// The statement types to be recorded are controlled by `tidb_admin_operation_log_stmts`.
+type adminOperationLogger struct {
+	sctx     sessionctx.Context
+	stmtType string
+	// targetTS is only set for FLASHBACK CLUSTER.
+	targetTS uint64
+}
+
+// newAdminOperationLogger returns nil if the statement doesn't need to be recorded.
+func newAdminOperationLogger(sctx sessionctx.Context, is infoschema.InfoSchema, stmt ast.StmtNode) *adminOperationLogger {
+	stmtTypes := variable.AdminOperationLogStmts.Load()
+	if stmtTypes == "" {
+		return nil
+	}
+	logger := &adminOperationLogger{sctx: sctx}
+	switch s := stmt.(type) {
+	case *ast.FlashBackClusterStmt:
+		logger.stmtType = variable.AdminOperationFlashbackCluster
+		logger.targetTS = s.FlashbackTSO
+		if logger.targetTS == 0 {
+			// The statement fails later if the timestamp is invalid, so we only record the valid ones.
+			ts, err := staleread.CalculateAsOfTsExpr(sctx, &s.AsOf)
+			if err != nil {
+				return nil
+			}
+			logger.targetTS = ts
+		}
+	case *ast.DropDatabaseStmt:
+		logger.stmtType = variable.AdminOperationDropDatabase
+	case *ast.TruncateTableStmt:
+		logger.stmtType = variable.AdminOperationTruncateTable
+		if getTableRowCount(sctx, is, s.Table) < variable.AdminOperationLogTruncateMinRows.Load() {
+			return nil
+		}
+	default:
+		return nil
+	}
+	for _, tp := range strings.Split(stmtTypes, ",") {
+		if tp == logger.stmtType {
+			return logger
+		}
+	}
+	return nil
+}
+
+// getTableRowCount gets the row count of the table from the statistics.
+func getTableRowCount(sctx sessionctx.Context, is infoschema.InfoSchema, tn *ast.TableName) uint64 {
+	schema := tn.Schema
+	if schema.L == "" {
+		schema = model.NewCIStr(sctx.GetSessionVars().CurrentDB)
+	}
+	tbl, err := is.TableByName(schema, tn.Name)
+	if err != nil {
+		return 0
+	}
+	statsHandle := domain.GetDomain(sctx).StatsHandle()
+	if statsHandle == nil {
+		return 0
+	}
+	tblInfo := tbl.Meta()
+	pi := tblInfo.GetPartitionInfo()
+	if pi == nil {
+		return uint64(statsHandle.GetTableStats(tblInfo).Count)
+	}
+	var count uint64
+	for _, def := range pi.Definitions {
+		count += uint64(statsHandle.GetPartitionStats(tblInfo, def.ID).Count)
+	}
+	return count
+}
+
+// logPending records that the operation is going to be executed.
+func (l *adminOperationLogger) logPending(ctx context.Context) error {
+	return l.log(ctx, 0, adminOperationPending, "")
+}
+
+// logResult records the terminal state of the operation.
+func (l *adminOperationLogger) logResult(ctx context.Context, jobID int64, execErr error) error {
+	if execErr != nil {
+		return l.log(ctx, jobID, adminOperationFailed, execErr.Error())
+	}
+	return l.log(ctx, jobID, adminOperationSucceed, "")
+}
+
+func (l *adminOperationLogger) log(ctx context.Context, jobID int64, result, message string) error {
+	vars := l.sctx.GetSessionVars()
+	var user, host string
+	if vars.User != nil {
+		user, host = vars.User.Username, vars.User.Hostname
+	}
+	_, digest := vars.StmtCtx.SQLDigest()
+	var targetTS, id interface{}
+	if l.targetTS != 0 {
+		targetTS = l.targetTS
+	}
+	if jobID != 0 {
+		id = jobID
+	}
+	exec := l.sctx.(sqlexec.RestrictedSQLExecutor)
+	_, _, err := exec.ExecRestrictedSQL(ctx, []sqlexec.OptionFuncAlias{sqlexec.ExecOptionIgnoreWarning}, insertAdminOperationLogSQL,
+		user, host, l.stmtType, digest.String(), vars.StmtCtx.OriginalSQL, targetTS, id, result, message)
+	if err == nil {
+		return nil
+	}
+	if variable.AdminOperationLogStrict.Load() {
+		return err
+	}
+	logutil.Logger(ctx).Warn("write admin operation log failed", zap.String("stmtType", l.stmtType),
+		zap.Int64("jobID", jobID), zap.String("result", result), zap.Error(err))
+	vars.StmtCtx.AppendWarning(err)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/parser/auth"
+	"github.com/pingcap/tidb/testkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminOperationLog(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	require.NoError(t, tk.Session().Auth(&auth.UserIdentity{Username: "root", Hostname: "%"}, nil, nil))
+	defer func() {
+		tk.MustExec("set @@global.tidb_admin_operation_log_stmts = ''")
+		tk.MustExec("set @@global.tidb_admin_operation_log_truncate_min_rows = default")
+		tk.MustExec("set @@global.tidb_admin_operation_log_strict = default")
+	}()
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("create database db1")
+	// Nothing is recorded by default.
+	tk.MustExec("truncate table t")
+	tk.MustExec("drop database db1")
+	tk.MustQuery("select count(*) from mysql.admin_operation_log").Check(testkit.Rows("0"))
+
+	tk.MustGetErrMsg("set @@global.tidb_admin_operation_log_stmts = 'drop_table'",
+		"[variable:1231]Variable 'tidb_admin_operation_log_stmts' can't be set to the value of 'drop_table'")
+	tk.MustExec("set @@global.tidb_admin_operation_log_stmts = 'DROP_DATABASE, truncate_table'")
+	tk.MustQuery("select @@global.tidb_admin_operation_log_stmts").Check(testkit.Rows("drop_database,truncate_table"))
+
+	tk.MustExec("create database db1")
+	tk.MustExec("drop database db1")
+	tk.MustGetErrCode("drop database db1", 1008)
+	tk.MustQuery("select user, host, stmt_type, query, target_ts, job_id is not null, result from mysql.admin_operation_log order by id").Check(testkit.Rows(
+		"root % drop_database drop database db1 <nil> 1 succeed",
+		"root % drop_database drop database db1 <nil> 0 failed",
+	))
+
+	// TRUNCATE TABLE is only recorded when the table has enough rows.
+	tk.MustExec("delete from mysql.admin_operation_log")
+	tk.MustExec("set @@global.tidb_admin_operation_log_truncate_min_rows = 100000")
+	tk.MustExec("truncate table t")
+	tk.MustQuery("select count(*) from mysql.admin_operation_log").Check(testkit.Rows("0"))
+	tk.MustExec("set @@global.tidb_admin_operation_log_truncate_min_rows = 0")
+	tk.MustExec("truncate table t")
+	tk.MustQuery("select stmt_type, query, result from mysql.admin_operation_log").Check(testkit.Rows("truncate_table truncate table t succeed"))
+
+	// The failure of writing the log only generates a warning if the strict mode is off.
+	tk.MustExec("delete from mysql.admin_operation_log")
+	tk.MustExec("rename table mysql.admin_operation_log to mysql.admin_operation_log_bak")
+	tk.MustExec("truncate table t")
+	require.Len(t, tk.Session().GetSessionVars().StmtCtx.GetWarnings(), 1)
+	tk.MustExec("set @@global.tidb_admin_operation_log_strict = on")
+	tk.MustGetErrCode("truncate table t", 1146)
+	tk.MustExec("rename table mysql.admin_operation_log_bak to mysql.admin_operation_log")
+	tk.MustExec("truncate table t")
+	tk.MustQuery("select stmt_type, result from mysql.admin_operation_log").Check(testkit.Rows("truncate_table succeed"))
+}
+
+func TestAdminOperationLogForFlashbackCluster(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	defer tk.MustExec("set @@global.tidb_admin_operation_log_stmts = ''")
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("set @@global.tidb_admin_operation_log_stmts = 'flashback_cluster'")
+	tk.MustExec("use test")
+	flashbackTime := time.Now().Add(-time.Second).Format("2006-01-02 15:04:05")
+	tk.MustExec("create table t (a int)")
+	// The job is cancelled because of the DDL after the flashback timestamp, both the pending
+	// record and the terminal record are written.
+	tk.MustContainErrMsg(fmt.Sprintf("flashback cluster as of timestamp '%s'", flashbackTime),
+		"schema version not same, have done ddl during [flashbackTS, now)")
+	tk.MustQuery("select stmt_type, target_ts is not null, job_id is not null, result from mysql.admin_operation_log order by id").Check(testkit.Rows(
+		"flashback_cluster 1 0 pending",
+		"flashback_cluster 1 1 failed",
+	))
+
+	tk.MustExec("delete from mysql.admin_operation_log")
+	time.Sleep(time.Second)
+	flashbackTime = time.Now().Add(-500 * time.Millisecond).Format("2006-01-02 15:04:05.000")
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", flashbackTime))
+	tk.MustQuery("select stmt_type, job_id is not null, result, message from mysql.admin_operation_log order by id").Check(testkit.Rows(
+		"flashback_cluster 0 pending ",
+		"flashback_cluster 1 succeed ",
+	))
+}
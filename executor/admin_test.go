@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tidb/config"
 	"github.com/pingcap/tidb/domain"
@@ -43,6 +44,7 @@ import (
 	"github.com/pingcap/tidb/util/logutil/consistency"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -1509,6 +1511,49 @@ func TestAdminCheckWithSnapshot(t *testing.T) {
 	tk.MustExec("drop table if exists admin_t_s")
 }
 
+func TestAdminCheckAsOfTimestamp(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	timeBeforeDrop, timeAfterDrop, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("use test")
+	tk.MustExec("create table admin_t_as_of (a int primary key, b int, key idx(b))")
+	tk.MustExec("insert into admin_t_as_of values (1, 1), (2, 2)")
+	// Make sure the as-of timestamp, which is rounded to milliseconds, is after the insertion.
+	time.Sleep(5 * time.Millisecond)
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{TxnScope: oracle.GlobalTxnScope})
+	require.NoError(t, err)
+	asOf := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+	time.Sleep(5 * time.Millisecond)
+
+	// Corrupt the index by dropping the index mutation of the insertion.
+	tk.MustExec("set @@tidb_enable_mutation_checker = 0")
+	tk.MustExec("set @@tidb_txn_assertion_level = 'OFF'")
+	require.NoError(t, failpoint.Enable("github.com/pingcap/tidb/table/tables/corruptMutations", `return("missingIndex")`))
+	tk.MustExec("insert into admin_t_as_of values (3, 3)")
+	require.NoError(t, failpoint.Disable("github.com/pingcap/tidb/table/tables/corruptMutations"))
+	require.Error(t, tk.ExecToErr("admin check table admin_t_as_of"))
+	require.Error(t, tk.ExecToErr("admin check index admin_t_as_of idx"))
+
+	// The data before the corruption is consistent.
+	tk.MustExec(fmt.Sprintf("admin check table admin_t_as_of as of timestamp '%s'", asOf))
+	tk.MustExec(fmt.Sprintf("admin check index admin_t_as_of idx as of timestamp '%s'", asOf))
+	// The schema is read at the timestamp too.
+	tk.MustGetErrCode("admin check table admin_t_as_of as of timestamp now() - interval 1 hour", mysql.ErrNoSuchTable)
+
+	// The as-of timestamp can't be earlier than the GC safe point.
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeAfterDrop))
+	tk.MustGetErrCode(fmt.Sprintf("admin check table admin_t_as_of as of timestamp '%s'", asOf), mysql.ErrGCTooEarlyForRead)
+	tk.MustGetErrCode(fmt.Sprintf("admin check index admin_t_as_of idx as of timestamp '%s'", asOf), mysql.ErrGCTooEarlyForRead)
+
+	// The as-of timestamp can't be used in a transaction.
+	tk.MustExec("begin")
+	require.Error(t, tk.ExecToErr(fmt.Sprintf("admin check table admin_t_as_of as of timestamp '%s'", asOf)))
+	tk.MustExec("rollback")
+}
+
 func TestAdminCheckTableFailed(t *testing.T) {
 	store, domain := testkit.CreateMockStoreAndDomain(t)
 
@@ -55,6 +55,7 @@ import (
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/table/temptable"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/chunk"
@@ -111,6 +112,12 @@ type executorBuilder struct {
 	// can return a correct value even if the session context has already been destroyed
 	forDataReaderBuilder bool
 	dataReaderTS         uint64
+
+	// forCursor indicates whether the executor is held by a server-side cursor, whose rows are fetched across the
+	// other statements on the connection. The executor mustn't share the state which is changed by the later
+	// executions of the cached plan, and the expressions should only be evaluated within the fetches, see
+	// recordSet.params.
+	forCursor bool
 }
 
 // CTEStorages stores resTbl and iterInTbl for CTEExec.
@@ -223,6 +230,10 @@ func (b *executorBuilder) build(p plannercore.Plan) Executor {
 		return b.buildShowDDLJobQueries(v)
 	case *plannercore.ShowDDLJobQueriesWithRange:
 		return b.buildShowDDLJobQueriesWithRange(v)
+	case *plannercore.ShowDDLJobArgs:
+		return b.buildShowDDLJobArgs(v)
+	case *plannercore.ShowDDLJobLog:
+		return b.buildShowDDLJobLog(v)
 	case *plannercore.ShowSlow:
 		return b.buildShowSlow(v)
 	case *plannercore.PhysicalShow:
@@ -401,6 +412,26 @@ func (b *executorBuilder) buildShowDDLJobQueriesWithRange(v *plannercore.ShowDDL
 	return e
 }
 
+func (b *executorBuilder) buildShowDDLJobArgs(v *plannercore.ShowDDLJobArgs) Executor {
+	e := &ShowDDLJobArgsExec{
+		ShowDDLJobQueriesExec: ShowDDLJobQueriesExec{
+			baseExecutor: newBaseExecutor(b.ctx, v.Schema(), v.ID()),
+			jobIDs:       v.JobIDs,
+		},
+	}
+	return e
+}
+
+func (b *executorBuilder) buildShowDDLJobLog(v *plannercore.ShowDDLJobLog) Executor {
+	e := &ShowDDLJobLogExec{
+		ShowDDLJobQueriesExec: ShowDDLJobQueriesExec{
+			baseExecutor: newBaseExecutor(b.ctx, v.Schema(), v.ID()),
+			jobIDs:       v.JobIDs,
+		},
+	}
+	return e
+}
+
 func (b *executorBuilder) buildShowSlow(v *plannercore.ShowSlow) Executor {
 	e := &ShowSlowExec{
 		baseExecutor: newBaseExecutor(b.ctx, v.Schema(), v.ID()),
@@ -472,6 +503,7 @@ func (b *executorBuilder) buildCheckTable(v *plannercore.CheckTable) Executor {
 		exitCh:       make(chan struct{}),
 		retCh:        make(chan error, len(readerExecs)),
 		checkIndex:   v.CheckIndex,
+		asOfTS:       v.AsOfTS,
 	}
 	return e
 }
@@ -625,6 +657,25 @@ func (b *executorBuilder) buildChecksumTable(v *plannercore.ChecksumTable) Execu
 		b.err = err
 		return nil
 	}
+	if v.AsOfTS != 0 {
+		e.asOfTS = v.AsOfTS
+		e.asOfTables = make(map[int64]*checksumContext)
+		for _, t := range v.Tables {
+			if pi := t.TableInfo.GetPartitionInfo(); pi != nil {
+				for i := range pi.Definitions {
+					def := &pi.Definitions[i]
+					e.tables[def.ID] = newPartitionChecksumContext(t.DBInfo, t.TableInfo, def, startTs)
+					e.asOfTables[def.ID] = newPartitionChecksumContext(t.DBInfo, t.TableInfo, def, v.AsOfTS)
+					e.physicalIDs = append(e.physicalIDs, def.ID)
+				}
+				continue
+			}
+			e.tables[t.TableInfo.ID] = newChecksumContext(t.DBInfo, t.TableInfo, startTs)
+			e.asOfTables[t.TableInfo.ID] = newChecksumContext(t.DBInfo, t.TableInfo, v.AsOfTS)
+			e.physicalIDs = append(e.physicalIDs, t.TableInfo.ID)
+		}
+		return e
+	}
 	for _, t := range v.Tables {
 		e.tables[t.TableInfo.ID] = newChecksumContext(t.DBInfo, t.TableInfo, startTs)
 	}
@@ -743,6 +794,8 @@ func (b *executorBuilder) buildExecute(v *plannercore.Execute) Executor {
 		stmt:         v.Stmt,
 		plan:         v.Plan,
 		outputNames:  v.OutputNames(),
+
+		runtimeSelectLimit: v.RuntimeSelectLimit,
 	}
 
 	failpoint.Inject("assertExecutePrepareStatementStalenessOption", func(val failpoint.Value) {
@@ -1561,6 +1614,12 @@ func (b *executorBuilder) buildProjection(v *plannercore.PhysicalProjection) Exe
 	if b.inUpdateStmt || b.inDeleteStmt || b.inInsertStmt || b.hasLock {
 		e.numWorkers = 0
 	}
+
+	// The parallel projection fetches and evaluates the rows ahead in the background, which may run between the
+	// fetches of the cursor, when the params of the session belong to the other statements.
+	if b.forCursor {
+		e.numWorkers = 0
+	}
 	return e
 }
 
@@ -1780,6 +1839,9 @@ func (b *executorBuilder) buildMemTable(v *plannercore.PhysicalMemTable) Executo
 			strings.ToLower(infoschema.TablePlacementPolicies),
 			strings.ToLower(infoschema.TableTrxSummary),
 			strings.ToLower(infoschema.TableVariablesInfo),
+			strings.ToLower(infoschema.TablePlanCacheEvictions),
+			strings.ToLower(infoschema.TableFlashbackReadiness),
+			strings.ToLower(infoschema.TableInternalSessionPool),
 			strings.ToLower(infoschema.ClusterTableTrxSummary):
 			return &MemTableReaderExec{
 				baseExecutor: newBaseExecutor(b.ctx, v.Schema(), v.ID()),
@@ -4762,6 +4824,13 @@ func (b *executorBuilder) buildBatchPointGet(plan *plannercore.BatchPointGetPlan
 	var capacity int
 	if plan.IndexInfo != nil && !isCommonHandleRead(plan.TblInfo, plan.IndexInfo) {
 		e.idxVals = plan.IndexValues
+		if b.forCursor {
+			// The index values of the cached plan are rebuilt in place by the later executions, see rebuildRange.
+			e.idxVals = make([][]types.Datum, 0, len(plan.IndexValues))
+			for _, values := range plan.IndexValues {
+				e.idxVals = append(e.idxVals, append([]types.Datum(nil), values...))
+			}
+		}
 		capacity = len(e.idxVals)
 	} else {
 		// `SELECT a FROM t WHERE a IN (1, 1, 2, 1, 2)` should not return duplicated rows
@@ -5025,9 +5094,49 @@ func (b *executorBuilder) validCanReadTemporaryOrCacheTable(tbl *model.TableInfo
 	if err != nil {
 		return err
 	}
+	if err = b.validCanSnapshotReadTable(tbl); err != nil {
+		return err
+	}
 	return b.validCanReadCacheTable(tbl)
 }
 
+// validCanSnapshotReadTable rejects the snapshot read of the table if it's being rewritten by a flashback cluster job,
+// since the read may see the torn data across the rewritten ranges. The error is retryable after the job finishes.
+func (b *executorBuilder) validCanSnapshotReadTable(tbl *model.TableInfo) error {
+	if b.ctx.GetSessionVars().SnapshotTS == 0 {
+		return nil
+	}
+	dom := domain.GetDomain(b.ctx)
+	if dom == nil {
+		return nil
+	}
+	rewriting := dom.FlashbackRewritingRanges()
+	if rewriting == nil {
+		return nil
+	}
+	inRanges := func(id int64) bool {
+		key := tablecodec.EncodeTablePrefix(id)
+		for _, r := range rewriting.Ranges {
+			if key.Cmp(r.StartKey) >= 0 && key.Cmp(r.EndKey) < 0 {
+				return true
+			}
+		}
+		return false
+	}
+	ids := []int64{tbl.ID}
+	if pi := tbl.GetPartitionInfo(); pi != nil {
+		for _, def := range pi.Definitions {
+			ids = append(ids, def.ID)
+		}
+	}
+	for _, id := range ids {
+		if inRanges(id) {
+			return ErrSnapshotReadFlashbackRewriting.GenWithStackByArgs(tbl.Name.O, rewriting.JobID)
+		}
+	}
+	return nil
+}
+
 func (b *executorBuilder) validCanReadCacheTable(tbl *model.TableInfo) error {
 	if tbl.TableCacheStatusType == model.TableCacheStatusDisable {
 		return nil
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/ranger"
 	"github.com/pingcap/tipb/go-tipb"
@@ -38,6 +39,17 @@ type ChecksumTableExec struct {
 
 	tables map[int64]*checksumContext
 	done   bool
+
+	// asOfTS is set by `admin checksum table ... as of timestamp ...`. In this case, tables and asOfTables
+	// are keyed by physical table ID, and physicalIDs keeps the order of the output rows.
+	asOfTS      uint64
+	asOfTables  map[int64]*checksumContext
+	physicalIDs []int64
+}
+
+// snapshotProtector is implemented by the session to hold a GC service safe point during snapshot reads.
+type snapshotProtector interface {
+	WithSnapshotProtection(ctx context.Context, snapshotTS uint64, fn func(ctx context.Context) error) error
 }
 
 // Open implements the Executor Open interface.
@@ -46,6 +58,19 @@ func (e *ChecksumTableExec) Open(ctx context.Context) error {
 		return err
 	}
 
+	if e.asOfTS == 0 {
+		return e.runChecksum(ctx)
+	}
+	if err := gcutil.ValidateSnapshot(e.ctx, e.asOfTS); err != nil {
+		return err
+	}
+	if protector, ok := e.ctx.(snapshotProtector); ok {
+		return protector.WithSnapshotProtection(ctx, e.asOfTS, e.runChecksum)
+	}
+	return e.runChecksum(ctx)
+}
+
+func (e *ChecksumTableExec) runChecksum(ctx context.Context) error {
 	concurrency, err := getChecksumTableConcurrency(e.ctx)
 	if err != nil {
 		return err
@@ -59,7 +84,7 @@ func (e *ChecksumTableExec) Open(ctx context.Context) error {
 	taskCh := make(chan *checksumTask, len(tasks))
 	resultCh := make(chan *checksumResult, len(tasks))
 	for i := 0; i < concurrency; i++ {
-		go e.checksumWorker(taskCh, resultCh)
+		go e.checksumWorker(ctx, taskCh, resultCh)
 	}
 
 	for _, task := range tasks {
@@ -89,6 +114,33 @@ func (e *ChecksumTableExec) Next(ctx context.Context, req *chunk.Chunk) error {
 	if e.done {
 		return nil
 	}
+	if e.asOfTS != 0 {
+		for _, id := range e.physicalIDs {
+			t, asOf := e.tables[id], e.asOfTables[id]
+			req.AppendString(0, t.DBInfo.Name.O)
+			req.AppendString(1, t.TableInfo.Name.O)
+			if t.PartitionDef != nil {
+				req.AppendString(2, t.PartitionDef.Name.O)
+			} else {
+				req.AppendNull(2)
+			}
+			req.AppendUint64(3, t.Response.Checksum)
+			req.AppendUint64(4, t.Response.TotalKvs)
+			req.AppendUint64(5, t.Response.TotalBytes)
+			req.AppendUint64(6, asOf.Response.Checksum)
+			req.AppendUint64(7, asOf.Response.TotalKvs)
+			req.AppendUint64(8, asOf.Response.TotalBytes)
+			match := t.Response.Checksum == asOf.Response.Checksum && t.Response.TotalKvs == asOf.Response.TotalKvs &&
+				t.Response.TotalBytes == asOf.Response.TotalBytes
+			if match {
+				req.AppendInt64(9, 1)
+			} else {
+				req.AppendInt64(9, 0)
+			}
+		}
+		e.done = true
+		return nil
+	}
 	for _, t := range e.tables {
 		req.AppendString(0, t.DBInfo.Name.O)
 		req.AppendString(1, t.TableInfo.Name.O)
@@ -108,7 +160,16 @@ func (e *ChecksumTableExec) buildTasks() ([]*checksumTask, error) {
 			return nil, err
 		}
 		for _, req := range reqs {
-			tasks = append(tasks, &checksumTask{id, req})
+			tasks = append(tasks, &checksumTask{TableID: id, Request: req})
+		}
+	}
+	for id, t := range e.asOfTables {
+		reqs, err := t.BuildRequests(e.ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			tasks = append(tasks, &checksumTask{TableID: id, Request: req, AsOf: true})
 		}
 	}
 	return tasks, nil
@@ -116,19 +177,22 @@ func (e *ChecksumTableExec) buildTasks() ([]*checksumTask, error) {
 
 func (e *ChecksumTableExec) handleResult(result *checksumResult) {
 	table := e.tables[result.TableID]
+	if result.AsOf {
+		table = e.asOfTables[result.TableID]
+	}
 	table.HandleResponse(result.Response)
 }
 
-func (e *ChecksumTableExec) checksumWorker(taskCh <-chan *checksumTask, resultCh chan<- *checksumResult) {
+func (e *ChecksumTableExec) checksumWorker(ctx context.Context, taskCh <-chan *checksumTask, resultCh chan<- *checksumResult) {
 	for task := range taskCh {
-		result := &checksumResult{TableID: task.TableID}
-		result.Response, result.Error = e.handleChecksumRequest(task.Request)
+		result := &checksumResult{TableID: task.TableID, AsOf: task.AsOf}
+		result.Response, result.Error = e.handleChecksumRequest(ctx, task.Request)
 		resultCh <- result
 	}
 }
 
-func (e *ChecksumTableExec) handleChecksumRequest(req *kv.Request) (resp *tipb.ChecksumResponse, err error) {
-	ctx := distsql.WithSQLKvExecCounterInterceptor(context.TODO(), e.ctx.GetSessionVars().StmtCtx)
+func (e *ChecksumTableExec) handleChecksumRequest(ctx context.Context, req *kv.Request) (resp *tipb.ChecksumResponse, err error) {
+	ctx = distsql.WithSQLKvExecCounterInterceptor(ctx, e.ctx.GetSessionVars().StmtCtx)
 	res, err := distsql.Checksum(ctx, e.ctx.GetClient(), req, e.ctx.GetSessionVars().KVVars)
 	if err != nil {
 		return nil, err
@@ -162,19 +226,23 @@ func (e *ChecksumTableExec) handleChecksumRequest(req *kv.Request) (resp *tipb.C
 type checksumTask struct {
 	TableID int64
 	Request *kv.Request
+	AsOf    bool
 }
 
 type checksumResult struct {
 	Error    error
 	TableID  int64
+	AsOf     bool
 	Response *tipb.ChecksumResponse
 }
 
 type checksumContext struct {
 	DBInfo    *model.DBInfo
 	TableInfo *model.TableInfo
-	StartTs   uint64
-	Response  *tipb.ChecksumResponse
+	// PartitionDef is set if only one partition of the table should be checksummed.
+	PartitionDef *model.PartitionDefinition
+	StartTs      uint64
+	Response     *tipb.ChecksumResponse
 }
 
 func newChecksumContext(db *model.DBInfo, table *model.TableInfo, startTs uint64) *checksumContext {
@@ -186,7 +254,21 @@ func newChecksumContext(db *model.DBInfo, table *model.TableInfo, startTs uint64
 	}
 }
 
+func newPartitionChecksumContext(db *model.DBInfo, table *model.TableInfo, def *model.PartitionDefinition, startTs uint64) *checksumContext {
+	c := newChecksumContext(db, table, startTs)
+	c.PartitionDef = def
+	return c
+}
+
 func (c *checksumContext) BuildRequests(ctx sessionctx.Context) ([]*kv.Request, error) {
+	if c.PartitionDef != nil {
+		reqs := make([]*kv.Request, 0, len(c.TableInfo.Indices)+1)
+		if err := c.appendRequest(ctx, c.PartitionDef.ID, &reqs); err != nil {
+			return nil, err
+		}
+		return reqs, nil
+	}
+
 	var partDefs []model.PartitionDefinition
 	if part := c.TableInfo.Partition; part != nil {
 		partDefs = part.Definitions
@@ -89,8 +89,12 @@ func (c *Compiler) Compile(ctx context.Context, stmtNode ast.StmtNode) (*ExecStm
 		if preparedObj, err = plannercore.GetPreparedStmt(execStmt, sessVars); err != nil {
 			return nil, err
 		}
-		if pointPlanShortPathOK, err = plannercore.IsPointPlanShortPathOK(c.Ctx, is, preparedObj); err != nil {
-			return nil, err
+		// The short path reuses the point get executor across the executions of the statement, which can't be held
+		// by a cursor.
+		if !execStmt.UseCursor {
+			if pointPlanShortPathOK, err = plannercore.IsPointPlanShortPathOK(c.Ctx, is, preparedObj); err != nil {
+				return nil, err
+			}
 		}
 	}
 	finalPlan, names, err := planner.Optimize(ctx, c.Ctx, stmtNode, is)
@@ -124,7 +128,7 @@ func (c *Compiler) Compile(ctx context.Context, stmtNode ast.StmtNode) (*ExecStm
 		Ti:            &TelemetryInfo{},
 	}
 	if pointPlanShortPathOK {
-		if ep, ok := stmt.Plan.(*plannercore.Execute); ok {
+		if ep, ok := stmt.Plan.(*plannercore.Execute); ok && !(ep.RuntimeSelectLimit && plannercore.NeedExtraLimit(c.Ctx, ep.Stmt)) {
 			if pointPlan, ok := ep.Plan.(*plannercore.PointGetPlan); ok {
 				stmtCtx.SetPlan(stmt.Plan)
 				stmtCtx.SetPlanDigest(preparedObj.NormalizedPlan, preparedObj.PlanDigest)
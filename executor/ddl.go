@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/config"
@@ -31,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/parser/terror"
 	"github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessiontxn"
 	"github.com/pingcap/tidb/sessiontxn/staleread"
@@ -141,6 +143,20 @@ func (e *DDLExec) Next(ctx context.Context, req *chunk.Chunk) (err error) {
 		e.ctx.GetSessionVars().StmtCtx.DDLJobID = 0
 	}()
 
+	if logger := newAdminOperationLogger(e.ctx, e.is, e.stmt); logger != nil {
+		// FLASHBACK CLUSTER is recorded before it starts, so it can be found even if the job is cancelled later.
+		if logger.stmtType == variable.AdminOperationFlashbackCluster {
+			if err = logger.logPending(ctx); err != nil {
+				return err
+			}
+		}
+		defer func() {
+			if logErr := logger.logResult(ctx, e.ctx.GetSessionVars().StmtCtx.DDLJobID, err); logErr != nil && err == nil {
+				err = logErr
+			}
+		}()
+	}
+
 	switch x := e.stmt.(type) {
 	case *ast.AlterDatabaseStmt:
 		err = e.executeAlterDatabase(x)
@@ -173,6 +189,8 @@ func (e *DDLExec) Next(ctx context.Context, req *chunk.Chunk) (err error) {
 		err = e.executeFlashbackTable(x)
 	case *ast.FlashBackClusterStmt:
 		err = e.executeFlashBackCluster(ctx, x)
+	case *ast.FlashBackToTimestampStmt:
+		err = e.executeFlashBackToTimestamp(x)
 	case *ast.RenameTableStmt:
 		err = e.executeRenameTable(x)
 	case *ast.TruncateTableStmt:
@@ -529,20 +547,65 @@ func (e *DDLExec) executeFlashBackCluster(ctx context.Context, s *ast.FlashBackC
 		return core.ErrSpecificAccessDenied.GenWithStackByArgs("SUPER")
 	}
 
-	tiFlashInfo, err := getTiFlashStores(e.ctx)
-	if err != nil {
+	if _, err := ddl.CheckFlashbackClusterStores(e.ctx); err != nil {
 		return err
 	}
-	if len(tiFlashInfo) != 0 {
-		return errors.Errorf("not support flash back cluster with TiFlash stores")
+
+	var err error
+	flashbackTS := s.FlashbackTSO
+	if flashbackTS == 0 {
+		if flashbackTS, err = staleread.CalculateAsOfTsExpr(e.ctx, &s.AsOf); err != nil {
+			return err
+		}
+	}
+
+	var timeout time.Duration
+	if s.Timeout != "" {
+		if timeout, err = time.ParseDuration(s.Timeout); err != nil || timeout <= 0 {
+			return errors.Errorf("invalid flashback cluster timeout '%s'", s.Timeout)
+		}
+	}
+	return flashbackCluster(e.ctx, flashbackTS, s.Force, s.KillConflictingQueries, timeout)
+}
+
+// flashbackCluster runs the flashback cluster job to flashbackTS and notifies the schema rollback listeners after it
+// succeeds. It's shared by FLASHBACK CLUSTER and ADMIN UNDO FLASHBACK.
+func flashbackCluster(sctx sessionctx.Context, flashbackTS uint64, force, killConflictingQueries bool, timeout time.Duration) error {
+	dom := domain.GetDomain(sctx)
+	oldVer := dom.InfoSchema().SchemaMetaVersion()
+	if err := dom.DDL().FlashbackCluster(sctx, flashbackTS, force, killConflictingQueries, timeout); err != nil {
+		notifySchemaRollbackAfterDetached(dom, err, oldVer, flashbackTS)
+		return err
 	}
+	dom.NotifySchemaRollback(oldVer, flashbackTS)
+	return nil
+}
 
+func (e *DDLExec) executeFlashBackToTimestamp(s *ast.FlashBackToTimestampStmt) error {
 	flashbackTS, err := staleread.CalculateAsOfTsExpr(e.ctx, &s.AsOf)
 	if err != nil {
 		return err
 	}
+	tables := make([]ast.Ident, 0, len(s.Tables))
+	for _, tn := range s.Tables {
+		tables = append(tables, ast.Ident{Schema: tn.Schema, Name: tn.Name})
+	}
+	dom := domain.GetDomain(e.ctx)
+	oldVer := dom.InfoSchema().SchemaMetaVersion()
+	if err = dom.DDL().FlashbackTables(e.ctx, tables, flashbackTS, s.Force); err != nil {
+		notifySchemaRollbackAfterDetached(dom, err, oldVer, flashbackTS)
+		return err
+	}
+	dom.NotifySchemaRollback(oldVer, flashbackTS)
+	return nil
+}
 
-	return domain.GetDomain(e.ctx).DDL().FlashbackCluster(e.ctx, flashbackTS)
+// notifySchemaRollbackAfterDetached notifies the schema rollback listeners after the flashback job is done if the
+// killed statement is detached from the job, i.e. it returns the FlashbackJobDetachedError.
+func notifySchemaRollbackAfterDetached(dom *domain.Domain, err error, oldVer int64, flashbackTS uint64) {
+	if detached, ok := errors.Cause(err).(*ddl.FlashbackJobDetachedError); ok {
+		dom.NotifySchemaRollbackAfterJob(detached.JobID, oldVer, flashbackTS)
+	}
 }
 
 func (e *DDLExec) executeFlashbackTable(s *ast.FlashBackTableStmt) error {
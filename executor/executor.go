@@ -16,6 +16,8 @@ package executor
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"runtime/pprof"
@@ -91,6 +93,8 @@ var (
 	_ Executor = &ShowDDLExec{}
 	_ Executor = &ShowDDLJobsExec{}
 	_ Executor = &ShowDDLJobQueriesExec{}
+	_ Executor = &ShowDDLJobArgsExec{}
+	_ Executor = &ShowDDLJobLogExec{}
 	_ Executor = &SortExec{}
 	_ Executor = &StreamAggExec{}
 	_ Executor = &TableDualExec{}
@@ -152,6 +156,10 @@ func init() {
 	action := &globalPanicOnExceed{}
 	GlobalMemoryUsageTracker = memory.NewGlobalTracker(memory.LabelForGlobalMemory, -1)
 	GlobalMemoryUsageTracker.SetActionOnExceed(action)
+	// The cached plans are evicted before the queries are killed when the server memory quota is exceeded.
+	GlobalMemoryUsageTracker.FallbackOldAndSetNewAction(plannercore.NewPlanCacheEvictOnExceed())
+	plannercore.GlobalPlanCacheMemTracker.AttachToGlobalTracker(GlobalMemoryUsageTracker)
+	ddl.FlashbackMemTracker.AttachToGlobalTracker(GlobalMemoryUsageTracker)
 	GlobalDiskUsageTracker = disk.NewGlobalTrcaker(memory.LabelForGlobalStorage, -1)
 	GlobalDiskUsageTracker.SetActionOnExceed(action)
 	GlobalAnalyzeMemoryTracker = memory.NewTracker(memory.LabelForGlobalAnalyzeMemory, -1)
@@ -559,7 +567,11 @@ func (e *DDLJobRetriever) appendJobToChunk(req *chunk.Chunk, job *model.Job, che
 	req.AppendInt64(0, job.ID)
 	req.AppendString(1, schemaName)
 	req.AppendString(2, tableName)
-	req.AppendString(3, job.Type.String())
+	jobType := job.Type.String()
+	if job.ReorgMeta != nil && job.ReorgMeta.RetryCount > 0 {
+		jobType += fmt.Sprintf(" /* retries: %d */", job.ReorgMeta.RetryCount)
+	}
+	req.AppendString(3, jobType)
 	req.AppendString(4, job.SchemaState.String())
 	req.AppendInt64(5, job.SchemaID)
 	req.AppendInt64(6, job.TableID)
@@ -594,6 +606,106 @@ func (e *DDLJobRetriever) appendJobToChunk(req *chunk.Chunk, job *model.Job, che
 	}
 }
 
+// appendJobRemainingToChunk appends the estimated remaining time of the job to the column, and NULLs for its
+// sub-jobs, so it's called after appendJobToChunk appends the job.
+func appendJobRemainingToChunk(req *chunk.Chunk, col int, job *model.Job) {
+	if remaining, ok := ddl.EstimateJobRemaining(job); ok {
+		req.AppendString(col, remaining.Round(time.Second).String())
+	} else {
+		req.AppendNull(col)
+	}
+	if job.Type == model.ActionMultiSchemaChange {
+		for range job.MultiSchemaInfo.SubJobs {
+			req.AppendNull(col)
+		}
+	}
+}
+
+// appendJobFlashbackCheckpointsToChunk appends the JSON of the checkpoints recorded by the running flashback job to
+// the column, and NULL for the other jobs, so it's called after appendJobToChunk appends the job.
+func appendJobFlashbackCheckpointsToChunk(ctx context.Context, req *chunk.Chunk, col int, sctx sessionctx.Context, job *model.Job) error {
+	if (job.Type != model.ActionFlashbackCluster && job.Type != model.ActionFlashbackTables) || job.IsFinished() || job.IsSynced() {
+		req.AppendNull(col)
+		if job.Type == model.ActionMultiSchemaChange {
+			for range job.MultiSchemaInfo.SubJobs {
+				req.AppendNull(col)
+			}
+		}
+		return nil
+	}
+	checkpoints, err := ddl.GetFlashbackCheckpoints(ctx, sctx, job.ID)
+	if err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		req.AppendNull(col)
+		return nil
+	}
+	type checkpointJSON struct {
+		RangeIndex  int    `json:"range_index"`
+		EndKey      string `json:"end_key"`
+		RegionCount int    `json:"region_count"`
+		UpdatedAt   string `json:"updated_at"`
+	}
+	display := make([]checkpointJSON, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		display = append(display, checkpointJSON{
+			RangeIndex:  cp.RangeIndex,
+			EndKey:      hex.EncodeToString(cp.EndKey),
+			RegionCount: cp.RegionCount,
+			UpdatedAt:   cp.UpdatedAt.Format(types.TimeFSPFormat),
+		})
+	}
+	b, err := json.Marshal(display)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var checkpointsJSON types.BinaryJSON
+	if err = checkpointsJSON.UnmarshalJSON(b); err != nil {
+		return errors.Trace(err)
+	}
+	req.AppendJSON(col, checkpointsJSON)
+	return nil
+}
+
+// appendJobStateDurationsToChunk appends the JSON of the time spent in each state by the finished job to the column,
+// and NULL for the running jobs, so it's called after appendJobToChunk appends the job.
+func appendJobStateDurationsToChunk(req *chunk.Chunk, col int, job *model.Job) error {
+	durations := job.StateDurations()
+	if (!job.IsFinished() && !job.IsSynced()) || len(durations) == 0 {
+		req.AppendNull(col)
+	} else {
+		type durationJSON struct {
+			State       string  `json:"state"`
+			SchemaState string  `json:"schema_state"`
+			Duration    float64 `json:"duration"`
+		}
+		display := make([]durationJSON, 0, len(durations))
+		for _, d := range durations {
+			display = append(display, durationJSON{
+				State:       d.State.String(),
+				SchemaState: d.SchemaState.String(),
+				Duration:    d.Duration.Seconds(),
+			})
+		}
+		b, err := json.Marshal(display)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		var durationsJSON types.BinaryJSON
+		if err = durationsJSON.UnmarshalJSON(b); err != nil {
+			return errors.Trace(err)
+		}
+		req.AppendJSON(col, durationsJSON)
+	}
+	if job.Type == model.ActionMultiSchemaChange {
+		for range job.MultiSchemaInfo.SubJobs {
+			req.AppendNull(col)
+		}
+	}
+	return nil
+}
+
 func ts2Time(timestamp uint64, loc *time.Location) types.Time {
 	duration := time.Duration(math.Pow10(9-types.DefaultFsp)) * time.Nanosecond
 	t := model.TSConvert2Time(timestamp)
@@ -675,6 +787,107 @@ func (e *ShowDDLJobQueriesExec) Next(ctx context.Context, req *chunk.Chunk) erro
 	return nil
 }
 
+// ShowDDLJobArgsExec represents a show DDL job args executor.
+// It searches the jobs in the same way as ShowDDLJobQueriesExec, and the args of the jobs are decoded
+// by the decoders registered in the ddl package.
+type ShowDDLJobArgsExec struct {
+	ShowDDLJobQueriesExec
+
+	argCursor int
+	jobArgs   []ddlJobArg
+}
+
+type ddlJobArg struct {
+	job *model.Job
+	ddl.JobArg
+}
+
+// Open implements the Executor Open interface.
+func (e *ShowDDLJobArgsExec) Open(ctx context.Context) error {
+	if err := e.ShowDDLJobQueriesExec.Open(ctx); err != nil {
+		return err
+	}
+	for _, id := range e.jobIDs {
+		for _, job := range e.jobs {
+			if id != job.ID {
+				continue
+			}
+			for _, arg := range ddl.DecodeJobArgs(job) {
+				e.jobArgs = append(e.jobArgs, ddlJobArg{job: job, JobArg: arg})
+			}
+		}
+	}
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowDDLJobArgsExec) Next(ctx context.Context, req *chunk.Chunk) error {
+	req.GrowAndReset(e.maxChunkSize)
+	if e.argCursor >= len(e.jobArgs) {
+		return nil
+	}
+	numCurBatch := mathutil.Min(req.Capacity(), len(e.jobArgs)-e.argCursor)
+	for _, arg := range e.jobArgs[e.argCursor : e.argCursor+numCurBatch] {
+		req.AppendInt64(0, arg.job.ID)
+		req.AppendString(1, arg.job.Type.String())
+		req.AppendString(2, arg.Name)
+		req.AppendString(3, arg.Value)
+	}
+	e.argCursor += numCurBatch
+	return nil
+}
+
+// ShowDDLJobLogExec represents a show DDL job log executor.
+// It searches the jobs in the same way as ShowDDLJobQueriesExec, and shows the logs recorded in the jobs.
+type ShowDDLJobLogExec struct {
+	ShowDDLJobQueriesExec
+
+	logCursor int
+	jobLogs   []ddlJobLog
+}
+
+type ddlJobLog struct {
+	job *model.Job
+	*model.JobLogEntry
+}
+
+// Open implements the Executor Open interface.
+func (e *ShowDDLJobLogExec) Open(ctx context.Context) error {
+	if err := e.ShowDDLJobQueriesExec.Open(ctx); err != nil {
+		return err
+	}
+	for _, id := range e.jobIDs {
+		for _, job := range e.jobs {
+			if id != job.ID {
+				continue
+			}
+			for _, entry := range job.Logs {
+				e.jobLogs = append(e.jobLogs, ddlJobLog{job: job, JobLogEntry: entry})
+			}
+		}
+	}
+	return nil
+}
+
+// Next implements the Executor Next interface.
+func (e *ShowDDLJobLogExec) Next(ctx context.Context, req *chunk.Chunk) error {
+	req.GrowAndReset(e.maxChunkSize)
+	if e.logCursor >= len(e.jobLogs) {
+		return nil
+	}
+	loc := e.ctx.GetSessionVars().Location()
+	numCurBatch := mathutil.Min(req.Capacity(), len(e.jobLogs)-e.logCursor)
+	for _, log := range e.jobLogs[e.logCursor : e.logCursor+numCurBatch] {
+		req.AppendInt64(0, log.job.ID)
+		req.AppendTime(1, types.NewTime(types.FromGoTime(log.Time.In(loc)), mysql.TypeDatetime, types.MaxFsp))
+		req.AppendString(2, log.Level)
+		req.AppendString(3, log.Msg)
+		req.AppendString(4, log.Fields)
+	}
+	e.logCursor += numCurBatch
+	return nil
+}
+
 // ShowDDLJobQueriesWithRangeExec represents a show DDL job queries with range executor.
 // The jobs id that is given by 'admin show ddl job queries' statement,
 // can be searched within a specified range in history jobs using offset and limit.
@@ -789,6 +1002,13 @@ func (e *ShowDDLJobsExec) Next(ctx context.Context, req *chunk.Chunk) error {
 		numCurBatch := mathutil.Min(req.Capacity(), len(e.runningJobs)-e.cursor)
 		for i := e.cursor; i < e.cursor+numCurBatch; i++ {
 			e.appendJobToChunk(req, e.runningJobs[i], nil)
+			appendJobRemainingToChunk(req, 12, e.runningJobs[i])
+			if err := appendJobFlashbackCheckpointsToChunk(ctx, req, 13, e.sess, e.runningJobs[i]); err != nil {
+				return err
+			}
+			if err := appendJobStateDurationsToChunk(req, 14, e.runningJobs[i]); err != nil {
+				return err
+			}
 		}
 		e.cursor += numCurBatch
 		count += numCurBatch
@@ -806,6 +1026,13 @@ func (e *ShowDDLJobsExec) Next(ctx context.Context, req *chunk.Chunk) error {
 		}
 		for _, job := range e.cacheJobs {
 			e.appendJobToChunk(req, job, nil)
+			appendJobRemainingToChunk(req, 12, job)
+			if err := appendJobFlashbackCheckpointsToChunk(ctx, req, 13, e.sess, job); err != nil {
+				return err
+			}
+			if err := appendJobStateDurationsToChunk(req, 14, job); err != nil {
+				return err
+			}
 		}
 		e.cursor += len(e.cacheJobs)
 	}
@@ -855,6 +1082,8 @@ type CheckTableExec struct {
 	exitCh     chan struct{}
 	retCh      chan error
 	checkIndex bool
+	// asOfTS is the timestamp of the snapshot checked by `admin check table/index ... as of timestamp ...`.
+	asOfTS uint64
 }
 
 // Open implements the Executor Open interface.
@@ -862,6 +1091,20 @@ func (e *CheckTableExec) Open(ctx context.Context) error {
 	if err := e.baseExecutor.Open(ctx); err != nil {
 		return err
 	}
+	if e.asOfTS != 0 {
+		// The data at the timestamp may have been collected by GC, which is validated the same way as flashback.
+		if err := ddl.ValidateFlashbackTS(ctx, e.ctx, e.asOfTS); err != nil {
+			return err
+		}
+		// Activate the stale read transaction, so the counts of CheckIndicesCount are read at the timestamp too.
+		txn, err := e.ctx.Txn(true)
+		if err != nil {
+			return err
+		}
+		if txn.StartTS() != e.asOfTS {
+			return errors.Errorf("the start ts %d of the transaction doesn't match the as of timestamp %d", txn.StartTS(), e.asOfTS)
+		}
+	}
 	for _, src := range e.srcs {
 		if err := src.Open(ctx); err != nil {
 			return errors.Trace(err)
@@ -1955,11 +2198,18 @@ func ResetContextOfStmt(ctx sessionctx.Context, s ast.StmtNode) (err error) {
 		action.SetLogHook(domain.GetDomain(ctx).ExpensiveQueryHandle().LogOnQueryExceedMemQuota)
 		sc.MemTracker.SetActionOnExceed(action)
 	}
+	var generalSQL string
 	if execStmt, ok := s.(*ast.ExecuteStmt); ok {
 		prepareStmt, err := plannercore.GetPreparedStmt(execStmt, vars)
 		if err != nil {
 			return err
 		}
+		if execStmt.FromGeneralStmt && execStmt.GeneralSQL != "" {
+			generalSQL = execStmt.GeneralSQL
+		} else if execStmt.FromGeneralStmt && len(execStmt.ParamTexts) > 0 {
+			// Show the general statement with its real literals rather than the parameterized one.
+			generalSQL = plannercore.RestoreParamTexts(prepareStmt.PreparedAst.Stmt.Text(), execStmt.ParamTexts)
+		}
 		s = prepareStmt.PreparedAst.Stmt
 		sc.InitSQLDigest(prepareStmt.NormalizedSQL, prepareStmt.SQLDigest)
 		// For `execute stmt` SQL, should reset the SQL digest with the prepare SQL digest.
@@ -1980,6 +2230,9 @@ func ResetContextOfStmt(ctx sessionctx.Context, s ast.StmtNode) (err error) {
 	}
 	// execute missed stmtID uses empty sql
 	sc.OriginalSQL = s.Text()
+	if generalSQL != "" {
+		sc.OriginalSQL = generalSQL
+	}
 	if explainStmt, ok := s.(*ast.ExplainStmt); ok {
 		sc.InExplainStmt = true
 		sc.IgnoreExplainIDSuffix = strings.ToLower(explainStmt.Format) == types.ExplainFormatBrief
@@ -2115,9 +2368,8 @@ func ResetContextOfStmt(ctx sessionctx.Context, s ast.StmtNode) (err error) {
 	errCount, warnCount := vars.StmtCtx.NumErrorWarnings()
 	vars.SysErrorCount = errCount
 	vars.SysWarningCount = warnCount
+	vars.PrevFoundInPlanCache = vars.StmtCtx.FoundInPlanCache()
 	vars.StmtCtx = sc
-	vars.PrevFoundInPlanCache = vars.FoundInPlanCache
-	vars.FoundInPlanCache = false
 	vars.ClearStmtVars()
 	vars.PrevFoundInBinding = vars.FoundInBinding
 	vars.FoundInBinding = false
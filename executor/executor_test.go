@@ -5543,7 +5543,7 @@ func TestAdmin(t *testing.T) {
 	err = r.Next(ctx, req)
 	require.NoError(t, err)
 	row = req.GetRow(0)
-	require.Equal(t, 12, row.Len())
+	require.Equal(t, 15, row.Len())
 	txn, err := store.Begin()
 	require.NoError(t, err)
 	historyJobs, err := ddl.GetLastNHistoryDDLJobs(meta.NewMeta(txn), ddl.DefNumHistoryJobs)
@@ -5559,7 +5559,7 @@ func TestAdmin(t *testing.T) {
 	err = r.Next(ctx, req)
 	require.NoError(t, err)
 	row = req.GetRow(0)
-	require.Equal(t, 12, row.Len())
+	require.Equal(t, 15, row.Len())
 	require.Equal(t, historyJobs[0].ID, row.GetInt64(0))
 	require.NoError(t, err)
 
@@ -1321,8 +1321,7 @@ func TestValidity4PlanCache(t *testing.T) {
 	tk.MustExec("use plan_cache;")
 	tk.MustExec("create table t(a int);")
 	tk.MustExec("insert into t values(1);")
-	tk.MustQuery("execute stmt;").Check(testkit.Rows())
-	tk.MustQuery("select @@last_plan_from_cache;").Check(testkit.Rows("0"))
+	// The plan of test.t survives the DDL on plan_cache.t.
 	tk.MustQuery("execute stmt;").Check(testkit.Rows())
 	tk.MustQuery("select @@last_plan_from_cache;").Check(testkit.Rows("1"))
 
@@ -1394,3 +1393,34 @@ func TestIssue28792(t *testing.T) {
 	r2 := tk.MustQuery("EXPLAIN SELECT t12.a, t12.b FROM t12 LEFT JOIN t97 use index () on t12.b = t97.b;").Rows()
 	require.Equal(t, r2, r1)
 }
+
+func TestPointGetRuntimeStatsPlanCache(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("set tidb_enable_prepared_plan_cache=ON")
+	tk.MustExec("use test")
+	tk.MustExec("set @@tidb_enable_collect_execution_info=1")
+	tk.MustExec("create table t(a int primary key, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk.MustExec("prepare stmt from 'select * from t where a = ?'")
+	tk.MustExec("set @a=1")
+
+	// The runtime stats of the executor reused by the plan cache are per execution, the later executions show the
+	// same stats as the first one.
+	var fresh []interface{}
+	for i := 0; i < 3; i++ {
+		tk.MustQuery("execute stmt using @a").Check(testkit.Rows("1 1"))
+		require.Equal(t, i > 0, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
+		tkProcess := tk.Session().ShowProcess()
+		tk.Session().SetSessionManager(&testutil.MockSessionManager{PS: []*util.ProcessInfo{tkProcess}})
+		rows := tk.MustQuery(fmt.Sprintf("explain for connection %d", tkProcess.ID)).Rows()
+		require.Len(t, rows, 1)
+		require.Regexp(t, "^Point_Get_", rows[0][0])
+		require.Equal(t, "1", rows[0][2])
+		require.Regexp(t, `loops:2,`, rows[0][5])
+		if i == 0 {
+			fresh = rows[0][:3]
+		}
+		require.Equal(t, fresh, rows[0][:3])
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/deadlock"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/ddl/label"
 	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/domain/infosync"
@@ -173,6 +174,12 @@ func (e *memtableRetriever) retrieve(ctx context.Context, sctx sessionctx.Contex
 			err = e.setDataForClusterTrxSummary(sctx)
 		case infoschema.TableVariablesInfo:
 			err = e.setDataForVariablesInfo(sctx)
+		case infoschema.TablePlanCacheEvictions:
+			e.setDataForPlanCacheEvictions(sctx)
+		case infoschema.TableFlashbackReadiness:
+			err = e.setDataForFlashbackReadiness(ctx, sctx)
+		case infoschema.TableInternalSessionPool:
+			e.setDataForInternalSessionPool(sctx)
 		}
 		if err != nil {
 			return nil, err
@@ -443,6 +450,101 @@ func (e *memtableRetriever) setDataForVariablesInfo(ctx sessionctx.Context) erro
 	return nil
 }
 
+// setDataForPlanCacheEvictions fills the latest evictions of the plan caches of the current session.
+func (e *memtableRetriever) setDataForPlanCacheEvictions(ctx sessionctx.Context) {
+	var rows [][]types.Datum
+	for _, isGeneralPlanCache := range []bool{false, true} {
+		cache, ok := ctx.GetPlanCache(isGeneralPlanCache).(*plannercore.LRUPlanCache)
+		if !ok {
+			continue
+		}
+		cacheType := "prepared"
+		if isGeneralPlanCache {
+			cacheType = "general"
+		}
+		for _, eviction := range cache.RecentEvictions() {
+			evictedTime := types.NewTime(types.FromGoTime(eviction.Time.In(ctx.GetSessionVars().Location())), mysql.TypeTimestamp, types.MaxFsp)
+			rows = append(rows, types.MakeDatums(
+				evictedTime,             // EVICTED_TIME
+				cacheType,               // CACHE_TYPE
+				string(eviction.Reason), // REASON
+				eviction.DB,             // DB
+				eviction.StmtText,       // STMT_TEXT
+			))
+		}
+	}
+	e.rows = rows
+}
+
+// setDataForFlashbackReadiness fills the prerequisite checks of flashing back the cluster to the timestamp set by
+// tidb_flashback_readiness_ts.
+func (e *memtableRetriever) setDataForFlashbackReadiness(ctx context.Context, sctx sessionctx.Context) error {
+	checker := privilege.GetPrivilegeManager(sctx)
+	if checker != nil && !checker.RequestVerification(sctx.GetSessionVars().ActiveRoles, "", "", "", mysql.SuperPriv) {
+		return plannercore.ErrSpecificAccessDenied.GenWithStackByArgs("SUPER")
+	}
+	flashbackTS := sctx.GetSessionVars().FlashbackReadinessTS
+	if flashbackTS == 0 {
+		// Don't fail the statements reading all the tables of information_schema.
+		sctx.GetSessionVars().StmtCtx.AppendWarning(errors.Errorf("set %s to the flashback timestamp before reading %s",
+			variable.TiDBFlashbackReadinessTS, strings.ToLower(infoschema.TableFlashbackReadiness)))
+		return nil
+	}
+	sysSessionPool := domain.GetDomain(sctx).SysSessionPool()
+	res, err := sysSessionPool.Get()
+	if err != nil {
+		return err
+	}
+	defer sysSessionPool.Put(res)
+	sysSess := res.(sessionctx.Context)
+	sysSess.GetSessionVars().InRestrictedSQL = true
+	ctx = kv.WithInternalSourceType(ctx, kv.InternalTxnOthers)
+	checks, err := ddl.CheckFlashbackClusterReadiness(ctx, sctx, sysSess, flashbackTS)
+	if err != nil {
+		return err
+	}
+	rows := make([][]types.Datum, 0, len(checks))
+	for _, check := range checks {
+		result := "FAIL"
+		if check.Passed {
+			result = "PASS"
+		}
+		rows = append(rows, types.MakeDatums(
+			flashbackTS,  // FLASHBACK_TS
+			check.Name,   // CHECK_NAME
+			result,       // RESULT
+			check.Detail, // DETAIL
+		))
+	}
+	e.rows = rows
+	return nil
+}
+
+// setDataForInternalSessionPool fills the holders of the sessions of the DDL session pool on this TiDB instance.
+func (e *memtableRetriever) setDataForInternalSessionPool(ctx sessionctx.Context) {
+	if !hasPriv(ctx, mysql.ProcessPriv) {
+		return
+	}
+	threshold := time.Duration(variable.DDLSessionLeakThreshold.Load()) * time.Second
+	now := time.Now()
+	holders := domain.GetDomain(ctx).DDL().SessionPoolHolders()
+	rows := make([][]types.Datum, 0, len(holders))
+	for _, holder := range holders {
+		held := now.Sub(holder.StartTime)
+		leaked := !holder.Resident && threshold > 0 && held >= threshold
+		startTime := types.NewTime(types.FromGoTime(holder.StartTime.In(ctx.GetSessionVars().Location())), mysql.TypeTimestamp, types.MaxFsp)
+		rows = append(rows, types.MakeDatums(
+			holder.JobID,    // JOB_ID
+			holder.Purpose,  // PURPOSE
+			holder.Resident, // RESIDENT
+			startTime,       // START_TIME
+			held.Seconds(),  // HOLD_SECONDS
+			leaked,          // LEAKED
+		))
+	}
+	e.rows = rows
+}
+
 func (e *memtableRetriever) setDataFromSchemata(ctx sessionctx.Context, schemas []*model.DBInfo) {
 	checker := privilege.GetPrivilegeManager(ctx)
 	rows := make([][]types.Datum, 0, len(schemas))
@@ -1379,6 +1481,10 @@ func (e *DDLJobsReaderExec) Next(ctx context.Context, req *chunk.Chunk) error {
 					req.AppendString(12, e.runningJobs[i].Query)
 				}
 			}
+			appendJobRemainingToChunk(req, 13, e.runningJobs[i])
+			if err := appendJobStateDurationsToChunk(req, 14, e.runningJobs[i]); err != nil {
+				return err
+			}
 		}
 		e.cursor += num
 		count += num
@@ -1399,6 +1505,10 @@ func (e *DDLJobsReaderExec) Next(ctx context.Context, req *chunk.Chunk) error {
 					req.AppendString(12, job.Query)
 				}
 			}
+			appendJobRemainingToChunk(req, 13, job)
+			if err := appendJobStateDurationsToChunk(req, 14, job); err != nil {
+				return err
+			}
 		}
 		e.cursor += len(e.cacheJobs)
 	}
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/testkit/testdata"
@@ -46,6 +47,8 @@ func TestMain(m *testing.M) {
 	pointGetSuiteData = testDataMap["point_get_suite"]
 
 	autoid.SetStep(5000)
+	// The tests flash back the cluster to the timestamps got just before.
+	ddl.FlashbackClusterMinLag = 0
 	config.UpdateGlobal(func(conf *config.Config) {
 		conf.Instance.SlowThreshold = 30000 // 30s
 		conf.TiKVClient.AsyncCommit.SafeWindow = 0
@@ -65,6 +65,10 @@ func (b *executorBuilder) buildPointGet(p *plannercore.PointGetPlan) Executor {
 	e.base().initCap = 1
 	e.base().maxChunkSize = 1
 	e.Init(p)
+	if b.forCursor {
+		// The index values of the cached plan are rebuilt in place by the later executions, see rebuildRange.
+		e.idxVals = append([]types.Datum(nil), e.idxVals...)
+	}
 
 	e.snapshot, err = b.getSnapshot()
 	if err != nil {
@@ -74,14 +78,7 @@ func (b *executorBuilder) buildPointGet(p *plannercore.PointGetPlan) Executor {
 	if b.ctx.GetSessionVars().GetReplicaRead() == kv.ReplicaReadClosestAdaptive {
 		e.snapshot.SetOption(kv.ReplicaReadAdjuster, newReplicaReadAdjuster(e.ctx, p.GetAvgRowSize()))
 	}
-	if e.runtimeStats != nil {
-		snapshotStats := &txnsnapshot.SnapshotRuntimeStats{}
-		e.stats = &runtimeStatsWithSnapshot{
-			SnapshotRuntimeStats: snapshotStats,
-		}
-		e.snapshot.SetOption(kv.CollectRuntimeStats, snapshotStats)
-		b.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl.RegisterStats(e.id, e.stats)
-	}
+	e.registerSnapshotStats()
 
 	if p.IndexInfo != nil {
 		sctx := b.ctx.GetSessionVars().StmtCtx
@@ -167,6 +164,30 @@ func (e *PointGetExecutor) Init(p *plannercore.PointGetPlan) {
 	e.buildVirtualColumnInfo()
 }
 
+// resetRuntimeStats registers new runtime stats of the executor reused by the plan cache to the collector of the
+// current statement by the plan ID. The stats registered by the previous executions are kept by their statements,
+// the executor must not record into them any more.
+func (e *PointGetExecutor) resetRuntimeStats() {
+	e.runtimeStats, e.stats = nil, nil
+	if coll := e.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl; coll != nil && e.id > 0 {
+		e.runtimeStats = &execdetails.BasicRuntimeStats{}
+		coll.RegisterStats(e.id, e.runtimeStats)
+	}
+	e.registerSnapshotStats()
+}
+
+func (e *PointGetExecutor) registerSnapshotStats() {
+	if e.runtimeStats == nil {
+		return
+	}
+	snapshotStats := &txnsnapshot.SnapshotRuntimeStats{}
+	e.stats = &runtimeStatsWithSnapshot{
+		SnapshotRuntimeStats: snapshotStats,
+	}
+	e.snapshot.SetOption(kv.CollectRuntimeStats, snapshotStats)
+	e.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl.RegisterStats(e.id, e.stats)
+}
+
 // buildVirtualColumnInfo saves virtual column indices and sort them in definition order
 func (e *PointGetExecutor) buildVirtualColumnInfo() {
 	e.virtualColumnIndex = buildVirtualColumnIndex(e.Schema(), e.columns)
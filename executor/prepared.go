@@ -117,7 +117,7 @@ func (e *PrepareExec) Next(ctx context.Context, req *chunk.Chunk) error {
 			return err
 		}
 	}
-	stmt, p, paramCnt, err := plannercore.GeneratePlanCacheStmtWithAST(ctx, e.ctx, stmt0)
+	stmt, p, paramCnt, err := plannercore.GeneratePlanCacheStmtWithAST(ctx, e.ctx, e.IsGeneralStmt, stmt0)
 	if err != nil {
 		return err
 	}
@@ -165,6 +165,8 @@ type ExecuteExec struct {
 	plan          plannercore.Plan
 	lowerPriority bool
 	outputNames   []*types.FieldName
+
+	runtimeSelectLimit bool
 }
 
 // Next implements the Executor Next interface.
@@ -180,6 +182,13 @@ func (e *ExecuteExec) Build(b *executorBuilder) error {
 		log.Warn("rebuild plan in EXECUTE statement failed", zap.String("labelName of PREPARE statement", e.name))
 		return errors.Trace(b.err)
 	}
+	if e.runtimeSelectLimit && plannercore.NeedExtraLimit(e.ctx, e.stmt) {
+		// The plan from the general plan cache doesn't contain the sql_select_limit, enforce it here.
+		stmtExec = &LimitExec{
+			baseExecutor: newBaseExecutor(e.ctx, stmtExec.Schema(), 0, stmtExec),
+			end:          e.ctx.GetSessionVars().SelectLimit,
+		}
+	}
 	e.stmtExec = stmtExec
 	if e.ctx.GetSessionVars().StmtCtx.Priority == mysql.NoPriority {
 		e.lowerPriority = needLowerPriority(e.plan)
@@ -15,6 +15,7 @@
 package executor_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -26,9 +27,13 @@ import (
 	"github.com/pingcap/tidb/parser/auth"
 	"github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessiontxn/staleread"
+	storeerr "github.com/pingcap/tidb/store/driver/error"
 	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/stretchr/testify/require"
+	"github.com/tikv/client-go/v2/oracle"
+	"github.com/tikv/client-go/v2/tikv"
 )
 
 func TestRecoverTable(t *testing.T) {
@@ -297,7 +302,7 @@ func TestRecoverClusterMeetError(t *testing.T) {
 	tk := testkit.NewTestKit(t, store)
 
 	// Get GC safe point error.
-	tk.MustContainErrMsg(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(30*time.Second)), "cannot set flashback timestamp to future time")
+	tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(30*time.Second)), errno.ErrFlashbackTSTooRecent)
 	tk.MustContainErrMsg(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(0-30*time.Second)), "can not get 'tikv_gc_safe_point'")
 
 	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
@@ -307,7 +312,7 @@ func TestRecoverClusterMeetError(t *testing.T) {
 	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
 
 	// out of GC safe point range.
-	tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(0-60*60*60*time.Second)), int(variable.ErrSnapshotTooOld.Code()))
+	tk.MustGetErrCode(fmt.Sprintf("flashback cluster as of timestamp '%s'", time.Now().Add(0-60*60*60*time.Second)), errno.ErrGCTooEarlyForRead)
 
 	// Flashback without super privilege.
 	tk.MustExec("CREATE USER 'testflashback'@'localhost';")
@@ -360,3 +365,159 @@ func MockGC(tk *testkit.TestKit) (string, string, string, func()) {
 	tk.MustExec("delete from mysql.tidb where variable_name in ( 'tikv_gc_safe_point','tikv_gc_enable' )")
 	return timeBeforeDrop, timeAfterDrop, safePointSQL, resetGC
 }
+
+func TestChecksumTableAsOfAfterFlashback(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int, key(b))")
+	tk.MustExec("create table tp (a int primary key, b int, key(b)) partition by hash(a) partitions 2")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk.MustExec("insert into tp values (1, 1), (2, 2)")
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	flashbackTime := oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05.000")
+
+	tk.MustExec("insert into t values (3, 3)")
+	tk.MustExec("update tp set b = 10 where a = 1")
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", flashbackTime))
+
+	// Mocktikv returns 1 for every table/index scan, so the checksums of a table with one index are 1^1 = 0.
+	tk.MustQuery(fmt.Sprintf("admin checksum table t, tp as of timestamp '%s'", flashbackTime)).Sort().Check(testkit.Rows(
+		"test t <nil> 0 2 2 0 2 2 1",
+		"test tp p0 0 2 2 0 2 2 1",
+		"test tp p1 0 2 2 0 2 2 1",
+	))
+
+	// The as of timestamp can't be a future time or be older than the GC safe point.
+	tk.MustContainErrMsg(fmt.Sprintf("admin checksum table t as of timestamp '%s'", time.Now().Add(30*time.Second).Format("2006-01-02 15:04:05")),
+		"cannot set read timestamp to a future time")
+	tk.MustGetErrCode(fmt.Sprintf("admin checksum table t as of timestamp '%s'", time.Now().Add(-60*60*60*time.Second).Format("2006-01-02 15:04:05")),
+		int(variable.ErrSnapshotTooOld.Code()))
+}
+
+func TestStatsReloadedAfterFlashback(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk.MustExec("analyze table t")
+	require.NoError(t, dom.StatsHandle().Update(dom.InfoSchema()))
+	ts, err := tk.Session().GetStore().GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	oldMeta := tk.MustQuery("select version, count from mysql.stats_meta").Rows()
+
+	tk.MustExec("insert into t values (3, 3), (4, 4), (5, 5)")
+	tk.MustExec("analyze table t")
+	require.NoError(t, dom.StatsHandle().Update(dom.InfoSchema()))
+	tk.MustQuery("explain format = 'brief' select * from t").Check(testkit.Rows(
+		"TableReader 5.00 root  data:TableFullScan",
+		"└─TableFullScan 5.00 cop[tikv] table:t keep order:false"))
+
+	// The rewound stats are older than the cached ones, they're reloaded after the flashback. Mocktikv doesn't rewind
+	// the data, so the stats meta is rewound by hand.
+	tk.MustExec(fmt.Sprintf("update mysql.stats_meta set version = %v, count = %v", oldMeta[0][0], oldMeta[0][1]))
+	require.NoError(t, dom.StatsHandle().Update(dom.InfoSchema()))
+	tk.MustQuery("explain format = 'brief' select * from t").Check(testkit.Rows(
+		"TableReader 5.00 root  data:TableFullScan",
+		"└─TableFullScan 5.00 cop[tikv] table:t keep order:false"))
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(ts)))
+	tk.MustQuery("explain format = 'brief' select * from t").Check(testkit.Rows(
+		"TableReader 2.00 root  data:TableFullScan",
+		"└─TableFullScan 2.00 cop[tikv] table:t keep order:false"))
+}
+
+func TestStaleReadAcrossFlashback(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	getTS := func() uint64 {
+		ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{TxnScope: oracle.GlobalTxnScope})
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+		return ts
+	}
+	flashbackTS := getTS()
+	tk.MustExec("insert into t values (1)")
+	erasedTS := getTS()
+	tk.MustExec(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(flashbackTS)))
+	tk.MustQuery(fmt.Sprintf("select table_id from mysql.flashback_watermark where target_ts <= %d and end_ts > %d", flashbackTS, erasedTS)).Check(testkit.Rows("0"))
+
+	asOfSQL := fmt.Sprintf("select * from t as of timestamp '%s'", oracle.GetTimeFromTS(erasedTS).Format("2006-01-02 15:04:05.000"))
+	// The default is WARN.
+	tk.MustQuery(asOfSQL)
+	require.Len(t, tk.Session().GetSessionVars().StmtCtx.GetWarnings(), 1)
+	require.True(t, staleread.ErrStaleReadAcrossFlashback.Equal(tk.Session().GetSessionVars().StmtCtx.GetWarnings()[0].Err))
+	tk.MustQuery(fmt.Sprintf("select * from t as of timestamp '%s'", oracle.GetTimeFromTS(flashbackTS).Format("2006-01-02 15:04:05.000")))
+	require.Len(t, tk.Session().GetSessionVars().StmtCtx.GetWarnings(), 0)
+
+	tk.MustExec("set @@tidb_stale_read_flashback_check = 'ON'")
+	tk.MustGetErrCode(asOfSQL, errno.ErrStaleReadAcrossFlashback)
+	tk.MustGetErrCode(fmt.Sprintf("set @@tidb_snapshot = '%d'", erasedTS), errno.ErrStaleReadAcrossFlashback)
+	tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", getTS()))
+	tk.MustExec("set @@tidb_snapshot = ''")
+
+	tk.MustExec("set @@tidb_stale_read_flashback_check = 'OFF'")
+	tk.MustQuery(asOfSQL)
+	require.Len(t, tk.Session().GetSessionVars().StmtCtx.GetWarnings(), 0)
+}
+
+func TestStaleReadGCTooEarly(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk2 := testkit.NewTestKit(t, store)
+
+	timeBeforeDrop, _, safePointSQL, resetGC := MockGC(tk)
+	defer resetGC()
+	tk.MustExec(fmt.Sprintf(safePointSQL, timeBeforeDrop))
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, index idx(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	readTS, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{TxnScope: oracle.GlobalTxnScope})
+	require.NoError(t, err)
+	tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", readTS))
+	tk.MustQuery("select * from t").Check(testkit.Rows("1 1", "2 2"))
+
+	// GC advances after the snapshot is set, the cached safe point of the store is behind the saved one.
+	tikvStore := store.(tikv.Storage)
+	safePoint := oracle.GoTimeToTS(oracle.GetTimeFromTS(readTS).Add(time.Minute))
+	tk2.MustExec(fmt.Sprintf(safePointSQL, oracle.GetTimeFromTS(safePoint).Format("20060102-15:04:05 -0700 MST")))
+	require.NoError(t, tikvStore.GetSafePointKV().Put(tikv.GcSavedSafePoint, fmt.Sprintf("%d", safePoint)))
+	tikvStore.UpdateSPCache(readTS+1, time.Now())
+	defer tikvStore.UpdateSPCache(0, time.Now())
+
+	checkErr := func(err error) {
+		require.True(t, storeerr.ErrGCTooEarlyForRead.Equal(err), "err %v", err)
+		require.Contains(t, err.Error(), fmt.Sprintf("current GC safe point is %d", safePoint))
+		require.Contains(t, err.Error(), "please increase tidb_gc_life_time")
+	}
+	err = tk.QueryToErr("select * from t")
+	checkErr(err)
+	require.Contains(t, err.Error(), fmt.Sprintf("read timestamp %d", readTS))
+	checkErr(tk.QueryToErr("select b from t use index(idx) where b > 0"))
+	tk.MustExec("set @@tidb_snapshot = ''")
+	checkErr(tk.QueryToErr(fmt.Sprintf("select * from t as of timestamp '%s'", oracle.GetTimeFromTS(readTS).Format("2006-01-02 15:04:05.000"))))
+
+	// Flashback validation reports the same error, the safe point is loaded from mysql.tidb in seconds.
+	err = tk.ExecToErr(fmt.Sprintf("flashback cluster as of timestamp '%s'", oracle.GetTimeFromTS(readTS).Format("2006-01-02 15:04:05")))
+	require.True(t, storeerr.ErrGCTooEarlyForRead.Equal(err), "err %v", err)
+	require.Contains(t, err.Error(), "please increase tidb_gc_life_time")
+}
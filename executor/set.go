@@ -29,6 +29,7 @@ import (
 	"github.com/pingcap/tidb/plugin"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessiontxn/staleread"
 	"github.com/pingcap/tidb/table/temptable"
 	"github.com/pingcap/tidb/util/chunk"
 	"github.com/pingcap/tidb/util/collate"
@@ -196,6 +197,9 @@ func (e *SetExecutor) setSysVariable(ctx context.Context, name string, v *expres
 			if err == nil {
 				err = gcutil.ValidateSnapshot(e.ctx, newSnapshotTS)
 			}
+			if err == nil {
+				err = staleread.CheckReadTSAcrossFlashback(e.ctx, newSnapshotTS)
+			}
 		}
 		if err != nil {
 			fallbackOldSnapshotTS()
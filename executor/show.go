@@ -882,7 +882,10 @@ func (e *ShowExec) fetchShowStatus() error {
 			}
 		}
 		switch v.Value.(type) {
-		case []interface{}, nil:
+		case nil:
+			e.appendRow([]interface{}{status, nil})
+			continue
+		case []interface{}:
 			v.Value = fmt.Sprintf("%v", v.Value)
 		}
 		value, err := types.ToString(v.Value)
@@ -27,6 +27,7 @@ import (
 	"github.com/ngaut/pools"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/config"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/distsql"
 	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/errno"
@@ -1677,7 +1678,7 @@ func (e *SimpleExec) executeDropStats(s *ast.DropStatsStmt) (err error) {
 func (e *SimpleExec) autoNewTxn() bool {
 	// Some statements cause an implicit commit
 	// See https://dev.mysql.com/doc/refman/5.7/en/implicit-commit.html
-	switch e.Statement.(type) {
+	switch s := e.Statement.(type) {
 	// Data definition language (DDL) statements that define or modify database objects.
 	// (handled in DDL package)
 	// Statements that implicitly use or modify tables in the mysql database.
@@ -1690,6 +1691,9 @@ func (e *SimpleExec) autoNewTxn() bool {
 	// Administrative statements. TODO: ANALYZE TABLE, CACHE INDEX, CHECK TABLE, FLUSH, LOAD INDEX INTO CACHE, OPTIMIZE TABLE, REPAIR TABLE, RESET (but not RESET PERSIST).
 	case *ast.FlushStmt:
 		return true
+	// ADMIN UNDO FLASHBACK runs a flashback cluster job like FLASHBACK CLUSTER.
+	case *ast.AdminStmt:
+		return s.Tp == ast.AdminUndoFlashback
 	}
 	return false
 }
@@ -1750,6 +1754,47 @@ func (e *SimpleExec) executeAdmin(s *ast.AdminStmt) error {
 		return e.executeAdminReloadStatistics(s)
 	case ast.AdminFlushPlanCache:
 		return e.executeAdminFlushPlanCache(s)
+	case ast.AdminCleanupFlashback:
+		return e.executeAdminCleanupFlashback()
+	case ast.AdminUndoFlashback:
+		return e.executeAdminUndoFlashback(s)
+	}
+	return nil
+}
+
+// executeAdminUndoFlashback flashes the cluster back to the start TS of the flashback cluster job, which reverts the
+// flashback. Like FLASHBACK CLUSTER ... FORCE, FORCE skips the read-only window check and rolls back the live
+// transactions in the ranges.
+func (e *SimpleExec) executeAdminUndoFlashback(s *ast.AdminStmt) error {
+	if _, err := ddl.CheckFlashbackClusterStores(e.ctx); err != nil {
+		return err
+	}
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	undoTS, err := ddl.GetFlashbackUndoTS(ctx, e.ctx.GetStore(), s.JobIDs[0], s.Force)
+	if err != nil {
+		return err
+	}
+	stmtCtx := e.ctx.GetSessionVars().StmtCtx
+	defer func() {
+		stmtCtx.IsDDLJobInQueue = false
+		stmtCtx.DDLJobID = 0
+	}()
+	return flashbackCluster(e.ctx, undoTS, s.Force, false, 0)
+}
+
+func (e *SimpleExec) executeAdminCleanupFlashback() error {
+	restrictedCtx, err := e.getSysSession()
+	if err != nil {
+		return err
+	}
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnDDL)
+	defer e.releaseSysSession(ctx, restrictedCtx)
+	jobID, err := ddl.CleanupOrphanFlashbackPDSchedule(ctx, restrictedCtx)
+	if err != nil {
+		return err
+	}
+	if jobID != 0 {
+		e.ctx.GetSessionVars().StmtCtx.AppendNote(errors.Errorf("the PD schedule saved by the orphaned flashback job %d is restored", jobID))
 	}
 	return nil
 }
@@ -1768,20 +1813,32 @@ func (e *SimpleExec) executeAdminFlushPlanCache(s *ast.AdminStmt) error {
 	if s.Tp != ast.AdminFlushPlanCache {
 		return errors.New("This AdminStmt is not ADMIN FLUSH PLAN_CACHE")
 	}
-	if s.StatementScope == ast.StatementScopeGlobal {
-		return errors.New("Do not support the 'admin flush global scope.'")
-	}
-	if !e.ctx.GetSessionVars().EnablePreparedPlanCache {
-		e.ctx.GetSessionVars().StmtCtx.AppendWarning(errors.New("The plan cache is disable. So there no need to flush the plan cache"))
+	vars := e.ctx.GetSessionVars()
+	if !vars.EnablePreparedPlanCache && !vars.EnableGeneralPlanCache {
+		vars.StmtCtx.AppendWarning(errors.New("The plan cache is disable. So there no need to flush the plan cache"))
 		return nil
 	}
-	now := types.NewTime(types.FromGoTime(time.Now().In(e.ctx.GetSessionVars().StmtCtx.TimeZone)), mysql.TypeTimestamp, 3)
-	e.ctx.GetSessionVars().LastUpdateTime4PC = now
-	e.ctx.GetPlanCache(false).DeleteAll()
-	if s.StatementScope == ast.StatementScopeInstance {
-		// Record the timestamp. When other sessions want to use the plan cache,
-		// it will check the timestamp first to decide whether the plan cache should be flushed.
-		domain.GetDomain(e.ctx).SetExpiredTimeStamp4PC(now)
+	now := types.NewTime(types.FromGoTime(time.Now().In(vars.StmtCtx.TimeZone)), mysql.TypeTimestamp, 3)
+	vars.LastUpdateTime4PC = now
+	evicted := 0
+	for _, isGeneralPlanCache := range []bool{false, true} {
+		if cache := e.ctx.GetPlanCache(isGeneralPlanCache); cache != nil {
+			evicted += cache.Size()
+			cache.DeleteAll()
+		}
 	}
+	dom := domain.GetDomain(e.ctx)
+	switch s.StatementScope {
+	case ast.StatementScopeInstance, ast.StatementScopeGlobal:
+		// Flush the plan caches of all the sessions on this instance. The plans cached in the prepared statements
+		// are flushed lazily, when other sessions use the plan cache, they check the timestamp first to decide
+		// whether the plan cache should be flushed.
+		evicted += dom.FlushPlanCache(now)
+		if s.StatementScope == ast.StatementScopeGlobal {
+			// Other TiDB instances flush their plan caches when they're notified.
+			dom.NotifyFlushPlanCache()
+		}
+	}
+	vars.StmtCtx.AddAffectedRows(uint64(evicted))
 	return nil
 }
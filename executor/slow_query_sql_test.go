@@ -281,3 +281,33 @@ func TestIssue37066(t *testing.T) {
 		}
 	}
 }
+
+func TestSlowLogPlanCacheTimes(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	f, err := os.CreateTemp("", "tidb-slow-*.log")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	defer config.RestoreFunc()()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Log.SlowQueryFile = f.Name()
+	})
+	require.NoError(t, logutil.InitLogger(config.GetGlobalConfig().Log.ToLogConfig()))
+
+	tk.MustExec(fmt.Sprintf("set @@tidb_slow_query_file='%v'", f.Name()))
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, index idx(a))")
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("set tidb_slow_log_threshold=0")
+	tk.MustQuery("select * from t where a > 1")
+	tk.MustQuery("select * from t where a > 2")
+	tk.MustExec("set tidb_slow_log_threshold=300")
+
+	// The first execution misses the plan cache and the second one hits it, both are parameterized and look up the
+	// plan cache, and the phases don't take longer than the statement.
+	tk.MustQuery("select plan_cache_status from `information_schema`.`slow_query` " +
+		"where query like 'select * from t where a >%' and parameterize_time > 0 and plan_cache_lookup_time > 0 " +
+		"and parse_time + parameterize_time + plan_cache_lookup_time + optimize_time <= query_time order by time").
+		Check(testkit.Rows("miss", "hit"))
+}
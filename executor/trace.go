@@ -240,13 +240,27 @@ func (e *TraceExec) executeChild(ctx context.Context, se sqlexec.SQLExecutor) {
 	// For audit log plugin to log the statement correctly.
 	// Should be logged as 'explain ...', instead of the executed SQL.
 	vars := e.ctx.GetSessionVars()
+	stmtNode := e.stmtNode
+	// The internal statements have their own cached plans, so the statement is run as a user one to trace the
+	// decisions of the plan cache it meets when it's run by the client, at the cost of the audit log.
+	restricted := !vars.TracePlanCache
+	if vars.TracePlanCache {
+		// Go through the general plan cache like the client does.
+		if p, ok := se.(interface {
+			Parameterize(ctx context.Context, originSQL string) (*ast.ExecuteStmt, bool)
+		}); ok && stmtNode.Text() != "" {
+			if execStmt, ok := p.Parameterize(ctx, stmtNode.Text()); ok {
+				stmtNode = execStmt
+			}
+		}
+	}
 	origin := vars.InRestrictedSQL
-	vars.InRestrictedSQL = true
+	vars.InRestrictedSQL = restricted
 	defer func() {
 		vars.InRestrictedSQL = origin
 	}()
 	ctx = kv.WithInternalSourceType(ctx, kv.InternalTxnTrace)
-	rs, err := se.ExecuteStmt(ctx, e.stmtNode)
+	rs, err := se.ExecuteStmt(ctx, stmtNode)
 	if err != nil {
 		var errCode uint16
 		if te, ok := err.(*terror.Error); ok {
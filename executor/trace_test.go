@@ -15,6 +15,8 @@
 package executor_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/pingcap/tidb/testkit"
@@ -84,3 +86,58 @@ func TestTracePlanStmt(t *testing.T) {
 	require.Len(t, rows[0], 1)
 	require.Regexp(t, ".*zip", rows[0][0])
 }
+
+func TestTracePlanCache(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t(a int, b int, key(a))")
+	tk.MustExec("set @@tidb_enable_general_plan_cache = 1")
+	// The messages are collected from the spans of the JSON output, in which they're base64 encoded.
+	type span struct {
+		Annotations []struct {
+			Key   string
+			Value []byte
+		}
+		Sub []*span
+	}
+	traceJSON := func(sql string) string {
+		var sb strings.Builder
+		for _, row := range tk.MustQuery(sql).Rows() {
+			sb.WriteString(row[0].(string))
+		}
+		var spans []*span
+		require.NoError(t, json.Unmarshal([]byte(sb.String()), &spans))
+		var msgs []string
+		for len(spans) > 0 {
+			s := spans[0]
+			spans = append(spans[1:], s.Sub...)
+			for _, a := range s.Annotations {
+				if a.Key == "Msg" {
+					msgs = append(msgs, string(a.Value))
+				}
+			}
+		}
+		return strings.Join(msgs, "\n")
+	}
+
+	// The decisions are traced only if tidb_trace_plan_cache is on.
+	tk.MustQuery("select * from t where a = 1")
+	require.NotContains(t, traceJSON("trace format='json' select * from t where a = 1"), "[plan-cache]")
+
+	tk.MustExec("set @@tidb_trace_plan_cache = 1")
+	out := traceJSON("trace format='json' select * from t where a = 2")
+	require.Contains(t, out, "[plan-cache] the statement is parameterized to the template")
+	require.Contains(t, out, "[plan-cache] cache key")
+	require.Contains(t, out, "[plan-cache] hit the cached plan")
+
+	// The param types differ from the cached plan, so it's optimized again, and the plan comparing the int column
+	// with the string isn't cached.
+	out = traceJSON("trace format='json' select * from t where a = '3'")
+	require.Contains(t, out, "[plan-cache] miss the cached plans")
+	require.Contains(t, out, "[plan-cache] optimize the statement")
+	require.Contains(t, out, "[plan-cache] don't cache the plan")
+
+	out = traceJSON("trace format='json' select /*+ ignore_plan_cache() */ * from t where a = 3")
+	require.Contains(t, out, "[plan-cache] the ignore_plan_cache hint skips the general plan cache")
+}
@@ -296,6 +296,9 @@ func TestInfoTables(t *testing.T) {
 		"DEADLOCKS",
 		"PLACEMENT_POLICIES",
 		"TRX_SUMMARY",
+		"PLAN_CACHE_EVICTIONS",
+		"FLASHBACK_READINESS",
+		"INTERNAL_SESSION_POOL",
 	}
 	for _, tbl := range infoTables {
 		tb, err1 := is.TableByName(util.InformationSchemaName, model.NewCIStr(tbl))
@@ -184,6 +184,12 @@ const (
 	TableTrxSummary = "TRX_SUMMARY"
 	// TableVariablesInfo is the string constant of variables_info table.
 	TableVariablesInfo = "VARIABLES_INFO"
+	// TablePlanCacheEvictions is the string constant of plan_cache_evictions table.
+	TablePlanCacheEvictions = "PLAN_CACHE_EVICTIONS"
+	// TableFlashbackReadiness is the string constant of flashback_readiness table.
+	TableFlashbackReadiness = "FLASHBACK_READINESS"
+	// TableInternalSessionPool is the string constant of internal_session_pool table.
+	TableInternalSessionPool = "INTERNAL_SESSION_POOL"
 )
 
 const (
@@ -285,6 +291,9 @@ var tableIDMap = map[string]int64{
 	TableTrxSummary:                      autoid.InformationSchemaDBID + 80,
 	ClusterTableTrxSummary:               autoid.InformationSchemaDBID + 81,
 	TableVariablesInfo:                   autoid.InformationSchemaDBID + 82,
+	TablePlanCacheEvictions:              autoid.InformationSchemaDBID + 83,
+	TableFlashbackReadiness:              autoid.InformationSchemaDBID + 84,
+	TableInternalSessionPool:             autoid.InformationSchemaDBID + 85,
 }
 
 // columnInfo represents the basic column information of all kinds of INFORMATION_SCHEMA tables
@@ -833,6 +842,8 @@ var slowQueryCols = []columnInfo{
 	{name: variable.SlowLogPreprocSubQueriesStr, tp: mysql.TypeLonglong, size: 20, flag: mysql.UnsignedFlag},
 	{name: variable.SlowLogPreProcSubQueryTimeStr, tp: mysql.TypeDouble, size: 22},
 	{name: variable.SlowLogOptimizeTimeStr, tp: mysql.TypeDouble, size: 22},
+	{name: variable.SlowLogParameterizeTimeStr, tp: mysql.TypeDouble, size: 22},
+	{name: variable.SlowLogPlanCacheLookupTimeStr, tp: mysql.TypeDouble, size: 22},
 	{name: variable.SlowLogWaitTSTimeStr, tp: mysql.TypeDouble, size: 22},
 	{name: execdetails.PreWriteTimeStr, tp: mysql.TypeDouble, size: 22},
 	{name: execdetails.WaitPrewriteBinlogTimeStr, tp: mysql.TypeDouble, size: 22},
@@ -886,6 +897,7 @@ var slowQueryCols = []columnInfo{
 	{name: variable.SlowLogIsWriteCacheTable, tp: mysql.TypeTiny, size: 1},
 	{name: variable.SlowLogPlanFromCache, tp: mysql.TypeTiny, size: 1},
 	{name: variable.SlowLogPlanFromBinding, tp: mysql.TypeTiny, size: 1},
+	{name: variable.SlowLogPlanCacheStatus, tp: mysql.TypeVarchar, size: 8},
 	{name: variable.SlowLogHasMoreResults, tp: mysql.TypeTiny, size: 1},
 	{name: variable.SlowLogPlan, tp: mysql.TypeLongBlob, size: types.UnspecifiedLength},
 	{name: variable.SlowLogPlanDigest, tp: mysql.TypeVarchar, size: 128},
@@ -1203,6 +1215,8 @@ var tableDDLJobsCols = []columnInfo{
 	{name: "END_TIME", tp: mysql.TypeDatetime, size: 19},
 	{name: "STATE", tp: mysql.TypeVarchar, size: 64},
 	{name: "QUERY", tp: mysql.TypeVarchar, size: 64},
+	{name: "ESTIMATED_REMAINING", tp: mysql.TypeVarchar, size: 64},
+	{name: "STATE_DURATIONS", tp: mysql.TypeJSON, size: 51},
 }
 
 var tableSequencesCols = []columnInfo{
@@ -1306,12 +1320,14 @@ var tableStatementsSummaryCols = []columnInfo{
 	{name: stmtsummary.LastSeenStr, tp: mysql.TypeTimestamp, size: 26, flag: mysql.NotNullFlag, comment: "The time these statements are seen for the last time"},
 	{name: stmtsummary.PlanInCacheStr, tp: mysql.TypeTiny, size: 1, flag: mysql.NotNullFlag, comment: "Whether the last statement hit plan cache"},
 	{name: stmtsummary.PlanCacheHitsStr, tp: mysql.TypeLonglong, size: 20, flag: mysql.NotNullFlag, comment: "The number of times these statements hit plan cache"},
+	{name: stmtsummary.PlanCacheStatusStr, tp: mysql.TypeVarchar, size: 8, flag: mysql.NotNullFlag, comment: "How the last statement got the plan from plan cache, one of none, hit, miss and skip"},
 	{name: stmtsummary.PlanInBindingStr, tp: mysql.TypeTiny, size: 1, flag: mysql.NotNullFlag, comment: "Whether the last statement is matched with the hints in the binding"},
 	{name: stmtsummary.QuerySampleTextStr, tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "Sampled original statement"},
 	{name: stmtsummary.PrevSampleTextStr, tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "The previous statement before commit"},
 	{name: stmtsummary.PlanDigestStr, tp: mysql.TypeVarchar, size: 64, comment: "Digest of its execution plan"},
 	{name: stmtsummary.PlanStr, tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "Sampled execution plan"},
 	{name: stmtsummary.BinaryPlan, tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "Sampled binary plan"},
+	{name: stmtsummary.PlanCacheTemplateStr, tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "Parameterized statement used as its plan cache key"},
 }
 
 var tableStorageStatsCols = []columnInfo{
@@ -1519,6 +1535,30 @@ var tableVariablesInfoCols = []columnInfo{
 	{name: "IS_NOOP", tp: mysql.TypeVarchar, size: 64, flag: mysql.NotNullFlag},
 }
 
+var tablePlanCacheEvictionsCols = []columnInfo{
+	{name: "EVICTED_TIME", tp: mysql.TypeTimestamp, decimal: 6, size: 26, comment: "The time when the plan is evicted"},
+	{name: "CACHE_TYPE", tp: mysql.TypeVarchar, size: 16, comment: "Whether the plan is from the prepared or the general plan cache"},
+	{name: "REASON", tp: mysql.TypeVarchar, size: 32, comment: "The reason why the plan is evicted or invalidated"},
+	{name: "DB", tp: mysql.TypeVarchar, size: 64, comment: "The schema the statement works on"},
+	{name: "STMT_TEXT", tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "The text of the statement"},
+}
+
+var tableFlashbackReadinessCols = []columnInfo{
+	{name: "FLASHBACK_TS", tp: mysql.TypeLonglong, size: 21, flag: mysql.UnsignedFlag, comment: "The timestamp set by tidb_flashback_readiness_ts"},
+	{name: "CHECK_NAME", tp: mysql.TypeVarchar, size: 64, comment: "The name of the prerequisite check of flashback cluster"},
+	{name: "RESULT", tp: mysql.TypeVarchar, size: 8, comment: "Whether the check passes, PASS or FAIL"},
+	{name: "DETAIL", tp: mysql.TypeBlob, size: types.UnspecifiedLength, comment: "The detail of the check"},
+}
+
+var tableInternalSessionPoolCols = []columnInfo{
+	{name: "JOB_ID", tp: mysql.TypeLonglong, size: 21, comment: "The ID of the DDL job holding the session, 0 if it isn't held by a job"},
+	{name: "PURPOSE", tp: mysql.TypeVarchar, size: 256, comment: "What the session is used for"},
+	{name: "RESIDENT", tp: mysql.TypeTiny, size: 1, comment: "Whether the session is held all the time by its holder, e.g. a DDL worker"},
+	{name: "START_TIME", tp: mysql.TypeTimestamp, decimal: 6, size: 26, comment: "The time when the session is got from the pool"},
+	{name: "HOLD_SECONDS", tp: mysql.TypeDouble, size: 22, comment: "The seconds the session is held"},
+	{name: "LEAKED", tp: mysql.TypeTiny, size: 1, comment: "Whether the session is held longer than tidb_ddl_session_leak_threshold"},
+}
+
 // GetShardingInfo returns a nil or description string for the sharding information of given TableInfo.
 // The returned description string may be:
 //   - "NOT_SHARDED": for tables that SHARD_ROW_ID_BITS is not specified.
@@ -1960,6 +2000,9 @@ var tableNameToColumns = map[string][]columnInfo{
 	TablePlacementPolicies:                  tablePlacementPoliciesCols,
 	TableTrxSummary:                         tableTrxSummaryCols,
 	TableVariablesInfo:                      tableVariablesInfoCols,
+	TablePlanCacheEvictions:                 tablePlanCacheEvictionsCols,
+	TableFlashbackReadiness:                 tableFlashbackReadinessCols,
+	TableInternalSessionPool:                tableInternalSessionPoolCols,
 }
 
 func createInfoSchemaTable(_ autoid.Allocators, meta *model.TableInfo) (table.Table, error) {
@@ -52,6 +52,9 @@ var (
 	// ErrWriteConflictInTiDB is the error when the commit meets an write conflict error when local latch is enabled.
 	ErrWriteConflictInTiDB = dbterror.ClassKV.NewStdErr(mysql.ErrWriteConflictInTiDB,
 		pmysql.Message(mysql.MySQLErrName[mysql.ErrWriteConflictInTiDB].Raw+" "+TxnRetryableMark, nil))
+	// ErrFlashbackCommitConflict is the error when the transaction starts before a flashback job finishes and commits
+	// after it.
+	ErrFlashbackCommitConflict = dbterror.ClassKV.NewStd(mysql.ErrFlashbackCommitConflict)
 	// ErrLockExpire is the error when the lock is expired.
 	ErrLockExpire = dbterror.ClassTiKV.NewStd(mysql.ErrLockExpire)
 	// ErrAssertionFailed is the error when an assertion fails.
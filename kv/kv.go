@@ -503,6 +503,12 @@ type StorageWithPD interface {
 	GetPDClient() pd.Client
 }
 
+// CoprCacheInvalidator is the kv store which can invalidate its coprocessor cache.
+type CoprCacheInvalidator interface {
+	// InvalidateCoprCache invalidates the coprocessor cache items populated by the reads before ts.
+	InvalidateCoprCache(ts uint64)
+}
+
 // FnKeyCmp is the function for iterator the keys
 type FnKeyCmp func(key Key) bool
 
@@ -522,6 +528,42 @@ type SplittableStore interface {
 	CheckRegionInScattering(regionID uint64) (bool, error)
 }
 
+// FlashbackableStore is the kv store which supports flashing back the data of the key ranges to a version.
+type FlashbackableStore interface {
+	// PrepareFlashbackToVersion checks the range [startKey, endKey) can be flashed back, e.g. no lock is left in it.
+	PrepareFlashbackToVersion(ctx context.Context, startKey, endKey []byte) error
+	// FlashbackToVersion rewrites the data in the range [startKey, endKey) to the data at version, the changes are
+	// written with startTS and commitTS. The returned stats are nil if the store doesn't count them, e.g. the TiKV of
+	// the older versions.
+	FlashbackToVersion(ctx context.Context, startKey, endKey []byte, version, startTS, commitTS uint64) (*FlashbackStats, error)
+}
+
+// FlashbackStats is the counters of the flashback of a key range returned by the store.
+type FlashbackStats struct {
+	// KeysProcessed is the number of the keys scanned in the range.
+	KeysProcessed uint64
+	// VersionsWritten is the number of the new versions written to rewind the keys.
+	VersionsWritten uint64
+}
+
+// KeyspaceCodec prepends and strips the keyspace prefix of the keys. When the cluster runs with a keyspace of the TiKV
+// API v2, the keys in TiKV are prefixed with the keyspace prefix.
+type KeyspaceCodec interface {
+	// KeyspaceAware tells whether the codec prepends the keyspace prefix, it's false for the API v1 codec.
+	KeyspaceAware() bool
+	// EncodeKey prepends the keyspace prefix to the key.
+	EncodeKey(key Key) Key
+	// DecodeKey strips the keyspace prefix from the key, it returns an error if the key isn't in the keyspace.
+	DecodeKey(key Key) (Key, error)
+}
+
+// KeyspaceStore is the kv store which runs with a keyspace. The keys are encoded by the transaction layer, but the
+// requests sent to TiKV directly, e.g. the flashback, must encode the keys by the codec.
+type KeyspaceStore interface {
+	// GetKeyspaceCodec returns the codec of the keyspace attached to the store.
+	GetKeyspaceCodec() KeyspaceCodec
+}
+
 // Priority value for transaction priority.
 const (
 	PriorityNormal = iota
@@ -79,6 +79,18 @@ var (
 	mDDLTableVersion    = []byte("DDLTableVersion")
 	mConcurrentDDL      = []byte("concurrentDDL")
 	mInFlashbackCluster = []byte("InFlashbackCluster")
+	// mLastFlashbackClusterTS is the marker of the last finished flashback cluster job, which is written in the final
+	// commit of the job. The replication and backup tools use it to find the flashback boundary.
+	mLastFlashbackClusterTS = []byte("LastFlashbackClusterTS")
+	// mFlashbackTablesBarrier blocks the DDL jobs on the tables being flashed back by the flashback tables job.
+	mFlashbackTablesBarrier = []byte("FlashbackTablesBarrier")
+	// mFlashbackPDSchedule records the PD schedule saved by the running flashback job.
+	mFlashbackPDSchedule = []byte("FlashbackPDSchedule")
+	// mCoprCacheEpoch is bumped in the final commit of the flashback jobs, the TiDB instances invalidate their
+	// coprocessor caches once they see a new epoch.
+	mCoprCacheEpoch = []byte("CoprCacheEpoch")
+	// mFlashbackRewritingRanges records the ranges of the stats tables being rewritten by the flashback cluster job.
+	mFlashbackRewritingRanges = []byte("FlashbackRewritingRanges")
 )
 
 const (
@@ -608,6 +620,138 @@ func (m *Meta) GetFlashbackClusterJobID() (int64, error) {
 	return int64(binary.BigEndian.Uint64(val)), nil
 }
 
+// SetLastFlashbackClusterTS sets the target TS of the last finished flashback cluster job.
+func (m *Meta) SetLastFlashbackClusterTS(ts uint64) error {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, ts)
+	return errors.Trace(m.txn.Set(mLastFlashbackClusterTS, b))
+}
+
+// GetLastFlashbackClusterTS returns the target TS of the last finished flashback cluster job, it returns 0 if no
+// flashback cluster job has finished.
+func (m *Meta) GetLastFlashbackClusterTS() (uint64, error) {
+	val, err := m.txn.Get(mLastFlashbackClusterTS)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(val) == 0 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
+
+// BumpCoprCacheEpoch increases the coprocessor cache epoch by 1.
+func (m *Meta) BumpCoprCacheEpoch() error {
+	_, err := m.txn.Inc(mCoprCacheEpoch, 1)
+	return errors.Trace(err)
+}
+
+// GetCoprCacheEpoch returns the coprocessor cache epoch, it returns 0 if it's never bumped.
+func (m *Meta) GetCoprCacheEpoch() (int64, error) {
+	epoch, err := m.txn.GetInt64(mCoprCacheEpoch)
+	return epoch, errors.Trace(err)
+}
+
+// FlashbackTablesBarrier is the barrier of the flashback tables job, the DDL jobs on the tables or dropping the
+// schemas of the tables can't run when it's held.
+type FlashbackTablesBarrier struct {
+	JobID     int64   `json:"job_id"`
+	SchemaIDs []int64 `json:"schema_ids"`
+	TableIDs  []int64 `json:"table_ids"`
+}
+
+// SetFlashbackTablesBarrier sets the barrier of the flashback tables job, the barrier is released if b is nil.
+func (m *Meta) SetFlashbackTablesBarrier(b *FlashbackTablesBarrier) error {
+	if b == nil {
+		return errors.Trace(m.txn.Clear(mFlashbackTablesBarrier))
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(m.txn.Set(mFlashbackTablesBarrier, data))
+}
+
+// GetFlashbackTablesBarrier returns the barrier of the flashback tables job, it returns nil if it's not held.
+func (m *Meta) GetFlashbackTablesBarrier() (*FlashbackTablesBarrier, error) {
+	val, err := m.txn.Get(mFlashbackTablesBarrier)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	b := &FlashbackTablesBarrier{}
+	return b, errors.Trace(json.Unmarshal(val, b))
+}
+
+// FlashbackPDSchedule is the PD schedule saved by the flashback job before closing it. It's kept until the job
+// finishes, so the PD schedule can be restored even if the job is lost.
+type FlashbackPDSchedule struct {
+	JobID      int64                  `json:"job_id"`
+	PDSchedule map[string]interface{} `json:"pd_schedule"`
+}
+
+// SetFlashbackPDSchedule records the PD schedule saved by the flashback job, the record is removed if s is nil.
+func (m *Meta) SetFlashbackPDSchedule(s *FlashbackPDSchedule) error {
+	if s == nil {
+		return errors.Trace(m.txn.Clear(mFlashbackPDSchedule))
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(m.txn.Set(mFlashbackPDSchedule, data))
+}
+
+// GetFlashbackPDSchedule returns the PD schedule saved by the flashback job, it returns nil if there is no record.
+func (m *Meta) GetFlashbackPDSchedule() (*FlashbackPDSchedule, error) {
+	val, err := m.txn.Get(mFlashbackPDSchedule)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	s := &FlashbackPDSchedule{}
+	return s, errors.Trace(json.Unmarshal(val, s))
+}
+
+// FlashbackRewritingRanges are the ranges of the stats tables being rewritten by the flashback cluster job. The
+// snapshot reads of them are rejected until the job finishes, since the ranges are rewritten one by one, and a read
+// across them may see the torn stats.
+type FlashbackRewritingRanges struct {
+	JobID  int64         `json:"job_id"`
+	Ranges []kv.KeyRange `json:"ranges"`
+}
+
+// SetFlashbackRewritingRanges records the ranges being rewritten by the flashback job, the record is removed if r is
+// nil.
+func (m *Meta) SetFlashbackRewritingRanges(r *FlashbackRewritingRanges) error {
+	if r == nil {
+		return errors.Trace(m.txn.Clear(mFlashbackRewritingRanges))
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(m.txn.Set(mFlashbackRewritingRanges, data))
+}
+
+// GetFlashbackRewritingRanges returns the ranges being rewritten by the flashback job, it returns nil if there is no
+// record.
+func (m *Meta) GetFlashbackRewritingRanges() (*FlashbackRewritingRanges, error) {
+	val, err := m.txn.Get(mFlashbackRewritingRanges)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	r := &FlashbackRewritingRanges{}
+	return r, errors.Trace(json.Unmarshal(val, r))
+}
+
 // SetConcurrentDDL set the concurrent DDL flag.
 func (m *Meta) SetConcurrentDDL(b bool) error {
 	var data []byte
@@ -814,6 +958,69 @@ func (m *Meta) ListDatabases() ([]*model.DBInfo, error) {
 	return dbs, nil
 }
 
+// TableMeta is the part of the table info decoded by IterTables, the columns, the indices and the other fields which
+// aren't needed to enumerate the physical tables are skipped.
+type TableMeta struct {
+	ID        int64       `json:"id"`
+	Name      model.CIStr `json:"name"`
+	View      *struct{}   `json:"view"`
+	Sequence  *struct{}   `json:"sequence"`
+	Partition *struct {
+		Definitions []struct {
+			ID int64 `json:"id"`
+		} `json:"definitions"`
+	} `json:"partition"`
+
+	raw []byte
+}
+
+// IsBaseTable checks whether the table is a base table, i.e. neither a view nor a sequence.
+func (t *TableMeta) IsBaseTable() bool {
+	return t.View == nil && t.Sequence == nil
+}
+
+// PhysicalIDs returns the physical IDs of the table, including the table ID and the partition IDs.
+func (t *TableMeta) PhysicalIDs() []int64 {
+	ids := []int64{t.ID}
+	if t.Partition != nil {
+		for _, def := range t.Partition.Definitions {
+			ids = append(ids, def.ID)
+		}
+	}
+	return ids
+}
+
+// TableInfo decodes the whole table info. It can only be called inside the IterTables callback.
+func (t *TableMeta) TableInfo() (*model.TableInfo, error) {
+	tblInfo := &model.TableInfo{}
+	err := json.Unmarshal(t.raw, tblInfo)
+	return tblInfo, errors.Trace(err)
+}
+
+// IterTables walks the tables of all the databases in the order of the meta keys, and calls fn for each table. Unlike
+// ListDatabases and ListTables, it doesn't materialize all the table infos, so the memory stays flat however many
+// tables there are. The TableMeta passed to fn is only valid until fn returns.
+func IterTables(m *Meta, fn func(dbInfo *model.DBInfo, tbl *TableMeta) error) error {
+	err := m.txn.IterateHash(mDBs, func(_ []byte, value []byte) error {
+		dbInfo := &model.DBInfo{}
+		if err := json.Unmarshal(value, dbInfo); err != nil {
+			return errors.Trace(err)
+		}
+		return m.txn.IterateHash(m.dbKey(dbInfo.ID), func(field []byte, value []byte) error {
+			// only handle table meta
+			if !bytes.HasPrefix(field, []byte(mTablePrefix)) {
+				return nil
+			}
+			tbl := &TableMeta{raw: value}
+			if err := json.Unmarshal(value, tbl); err != nil {
+				return errors.Trace(err)
+			}
+			return fn(dbInfo, tbl)
+		})
+	})
+	return errors.Trace(err)
+}
+
 // GetDatabase gets the database value with ID.
 func (m *Meta) GetDatabase(dbID int64) (*model.DBInfo, error) {
 	dbKey := m.dbKey(dbID)
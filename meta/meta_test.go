@@ -638,6 +638,124 @@ func TestAddIndexJob(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestIterTables(t *testing.T) {
+	store, err := mockstore.NewMockStore()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+
+	txn, err := store.Begin()
+	require.NoError(t, err)
+	m := meta.NewMeta(txn)
+	for _, dbInfo := range []*model.DBInfo{{ID: 1, Name: model.NewCIStr("a")}, {ID: 2, Name: model.NewCIStr("b")}} {
+		require.NoError(t, m.CreateDatabase(dbInfo))
+	}
+	require.NoError(t, m.CreateTableOrView(1, &model.TableInfo{ID: 11, Name: model.NewCIStr("t")}))
+	require.NoError(t, m.CreateTableOrView(1, &model.TableInfo{ID: 12, Name: model.NewCIStr("v"), View: &model.ViewInfo{}}))
+	require.NoError(t, m.CreateTableAndSetAutoID(2, &model.TableInfo{
+		ID:   21,
+		Name: model.NewCIStr("tp"),
+		Partition: &model.PartitionInfo{
+			Definitions: []model.PartitionDefinition{{ID: 22}, {ID: 23}},
+		},
+	}, 100, 0))
+	require.NoError(t, txn.Commit(context.Background()))
+
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	require.NoError(t, err)
+	snapMeta := meta.NewSnapshotMeta(store.GetSnapshot(ver))
+	var tables []string
+	err = meta.IterTables(snapMeta, func(dbInfo *model.DBInfo, tbl *meta.TableMeta) error {
+		tables = append(tables, fmt.Sprintf("%s.%s %v %v", dbInfo.Name, tbl.Name, tbl.IsBaseTable(), tbl.PhysicalIDs()))
+		if tbl.ID == 21 {
+			tblInfo, err := tbl.TableInfo()
+			require.NoError(t, err)
+			require.Len(t, tblInfo.Partition.Definitions, 2)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.t true [11]", "a.v false [12]", "b.tp true [21 22 23]"}, tables)
+
+	// The error returned by fn stops the iteration.
+	var count int
+	err = meta.IterTables(snapMeta, func(*model.DBInfo, *meta.TableMeta) error {
+		count++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, count)
+}
+
+// prepareBenchmarkTables creates n tables with 16 columns and 2 indices each in a database.
+func prepareBenchmarkTables(b *testing.B, store kv.Storage, n int) {
+	txn, err := store.Begin()
+	require.NoError(b, err)
+	m := meta.NewMeta(txn)
+	require.NoError(b, m.CreateDatabase(&model.DBInfo{ID: 1, Name: model.NewCIStr("test")}))
+	for i := 0; i < n; i++ {
+		// Split the transaction, or it's too large.
+		if i > 0 && i%1000 == 0 {
+			require.NoError(b, txn.Commit(context.Background()))
+			txn, err = store.Begin()
+			require.NoError(b, err)
+			m = meta.NewMeta(txn)
+		}
+		tblInfo := &model.TableInfo{ID: int64(i + 2), Name: model.NewCIStr(fmt.Sprintf("t%d", i))}
+		for j := 0; j < 16; j++ {
+			tblInfo.Columns = append(tblInfo.Columns, &model.ColumnInfo{ID: int64(j + 1), Name: model.NewCIStr(fmt.Sprintf("c%d", j))})
+		}
+		for j := 0; j < 2; j++ {
+			tblInfo.Indices = append(tblInfo.Indices, &model.IndexInfo{ID: int64(j + 1), Name: model.NewCIStr(fmt.Sprintf("idx%d", j))})
+		}
+		require.NoError(b, m.CreateTableOrView(1, tblInfo))
+	}
+	require.NoError(b, txn.Commit(context.Background()))
+}
+
+// BenchmarkIterTables compares IterTables with ListDatabases and ListTables, IterTables allocates much less since it
+// doesn't decode the columns and the indices, and its memory in use doesn't grow with the number of tables.
+func BenchmarkIterTables(b *testing.B) {
+	store, err := mockstore.NewMockStore()
+	require.NoError(b, err)
+	defer func() {
+		require.NoError(b, store.Close())
+	}()
+	const tableCount = 20000
+	prepareBenchmarkTables(b, store, tableCount)
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	require.NoError(b, err)
+	snapMeta := meta.NewSnapshotMeta(store.GetSnapshot(ver))
+
+	b.Run("IterTables", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var count int
+			err := meta.IterTables(snapMeta, func(*model.DBInfo, *meta.TableMeta) error {
+				count++
+				return nil
+			})
+			require.NoError(b, err)
+			require.Equal(b, tableCount, count)
+		}
+	})
+	b.Run("ListTables", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var count int
+			dbs, err := snapMeta.ListDatabases()
+			require.NoError(b, err)
+			for _, db := range dbs {
+				tables, err := snapMeta.ListTables(db.ID)
+				require.NoError(b, err)
+				count += len(tables)
+			}
+			require.Equal(b, tableCount, count)
+		}
+	})
+}
+
 func BenchmarkGenGlobalIDs(b *testing.B) {
 	store, err := mockstore.NewMockStore()
 	require.NoError(b, err)
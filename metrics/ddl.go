@@ -99,6 +99,7 @@ var (
 	CreateDDLInstance = "create_ddl_instance"
 	CreateDDL         = "create_ddl"
 	DDLOwner          = "owner"
+	StaleFlashbackJob = "stale_flashback_job"
 	DDLCounter        = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "tidb",
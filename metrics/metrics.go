@@ -134,6 +134,9 @@ func RegisterMetrics() {
 	prometheus.MustRegister(PanicCounter)
 	prometheus.MustRegister(PlanCacheCounter)
 	prometheus.MustRegister(PlanCacheMissCounter)
+	prometheus.MustRegister(PlanCacheEvictionCounter)
+	prometheus.MustRegister(PlanCacheParamPoolCounter)
+	prometheus.MustRegister(PlanCacheParamRestoreFailCounter)
 	prometheus.MustRegister(PseudoEstimation)
 	prometheus.MustRegister(PacketIOCounter)
 	prometheus.MustRegister(QueryDurationHistogram)
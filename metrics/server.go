@@ -144,6 +144,30 @@ var (
 			Help:      "Counter of plan cache miss.",
 		}, []string{LblType})
 
+	PlanCacheEvictionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_eviction_total",
+			Help:      "Counter of the cached plans evicted or invalidated.",
+		}, []string{LblReason})
+
+	PlanCacheParamPoolCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_param_pool_total",
+			Help:      "Counter of the pool operations of the parameterizer of the general plan cache.",
+		}, []string{LblType})
+
+	PlanCacheParamRestoreFailCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_param_restore_fail_total",
+			Help:      "Counter of the statements failed to be restored by the parameterizer of the general plan cache.",
+		}, []string{LblType})
+
 	ReadFromTableCacheCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "tidb",
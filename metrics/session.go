@@ -197,4 +197,5 @@ const (
 	LblHasLock     = "has_lock"
 	LblPhase       = "phase"
 	LblModule      = "module"
+	LblReason      = "reason"
 )
@@ -4037,14 +4037,38 @@ type FlashBackClusterStmt struct {
 	ddlNode
 
 	AsOf AsOfClause
+	// FlashbackTSO is the TSO to flashback to, it's only set by `FLASHBACK CLUSTER TO TSO ...`, and AsOf is empty
+	// then.
+	FlashbackTSO uint64
+	// Force means rolling back the live transactions holding the locks in the flashback ranges.
+	Force bool
+	// KillConflictingQueries means killing the running queries started before the flashback job in the cluster,
+	// instead of waiting for them.
+	KillConflictingQueries bool
+	// Timeout is the duration string, e.g. "10m", after which the flashback job is aborted. It's empty if there is
+	// no timeout.
+	Timeout string
 }
 
 // Restore implements Node interface
 func (n *FlashBackClusterStmt) Restore(ctx *format.RestoreCtx) error {
 	ctx.WriteKeyWord("FLASHBACK CLUSTER ")
-	if err := n.AsOf.Restore(ctx); err != nil {
+	if n.FlashbackTSO != 0 {
+		ctx.WriteKeyWord("TO TSO ")
+		ctx.WritePlainf("%d", n.FlashbackTSO)
+	} else if err := n.AsOf.Restore(ctx); err != nil {
 		return errors.Annotate(err, "An error occurred while splicing FlashBackClusterStmt.Asof")
 	}
+	if n.Force {
+		ctx.WriteKeyWord(" FORCE")
+	}
+	if n.KillConflictingQueries {
+		ctx.WriteKeyWord(" KILL CONFLICTING QUERIES")
+	}
+	if n.Timeout != "" {
+		ctx.WriteKeyWord(" TIMEOUT ")
+		ctx.WriteString(n.Timeout)
+	}
 	return nil
 }
 
@@ -4056,11 +4080,13 @@ func (n *FlashBackClusterStmt) Accept(v Visitor) (Node, bool) {
 	}
 
 	n = newNode.(*FlashBackClusterStmt)
-	node, ok := n.AsOf.Accept(v)
-	if !ok {
-		return n, false
+	if n.FlashbackTSO == 0 {
+		node, ok := n.AsOf.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf = *node.(*AsOfClause)
 	}
-	n.AsOf = *node.(*AsOfClause)
 	return v.Leave(n)
 }
 
@@ -4103,6 +4129,60 @@ func (n *FlashBackTableStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// FlashBackToTimestampStmt is a statement to restore the tables to the specified timestamp.
+type FlashBackToTimestampStmt struct {
+	ddlNode
+
+	Tables []*TableName
+	AsOf   AsOfClause
+	// Force means rolling back the live transactions holding the locks in the flashback ranges.
+	Force bool
+}
+
+// Restore implements Node interface.
+func (n *FlashBackToTimestampStmt) Restore(ctx *format.RestoreCtx) error {
+	ctx.WriteKeyWord("FLASHBACK TABLE ")
+	for i, table := range n.Tables {
+		if i != 0 {
+			ctx.WritePlain(", ")
+		}
+		if err := table.Restore(ctx); err != nil {
+			return errors.Annotatef(err, "An error occurred while splicing FlashBackToTimestampStmt.Tables[%d]", i)
+		}
+	}
+	ctx.WriteKeyWord(" TO TIMESTAMP ")
+	if err := n.AsOf.TsExpr.Restore(ctx); err != nil {
+		return errors.Annotate(err, "An error occurred while splicing FlashBackToTimestampStmt.AsOf")
+	}
+	if n.Force {
+		ctx.WriteKeyWord(" FORCE")
+	}
+	return nil
+}
+
+// Accept implements Node Accept interface.
+func (n *FlashBackToTimestampStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+
+	n = newNode.(*FlashBackToTimestampStmt)
+	for i, table := range n.Tables {
+		node, ok := table.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.Tables[i] = node.(*TableName)
+	}
+	node, ok := n.AsOf.Accept(v)
+	if !ok {
+		return n, false
+	}
+	n.AsOf = *node.(*AsOfClause)
+	return v.Leave(n)
+}
+
 type AttributesSpec struct {
 	node
 
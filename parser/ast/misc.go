@@ -512,6 +512,15 @@ type ExecuteStmt struct {
 	// FromGeneralStmt indicates whether this execute-stmt is converted from a general query.
 	// e.g. select * from t where a>2 --> execute 'select * from t where a>?' using 2
 	FromGeneralStmt bool
+	// ParamTexts are the original texts of the parameters if it's converted from a general query, they're used to
+	// show the real literals in messages and logs.
+	ParamTexts []string
+	// GeneralSQL is the original text of the general query if it's converted from one. The parameterized text is
+	// qualified by the current database, so the original text is shown in the statement summary and logs instead.
+	GeneralSQL string
+	// UseCursor indicates whether the result set is held by a server-side cursor. It's fetched by COM_STMT_FETCH
+	// after the statement returns, and the other statements may be executed on the connection between the fetches.
+	UseCursor bool
 }
 
 // Restore implements Node interface.
@@ -1723,6 +1732,9 @@ type CreateBindingStmt struct {
 	GlobalScope bool
 	OriginNode  StmtNode
 	HintedNode  StmtNode
+	// PlanCacheDigest is the plan digest of the cached plan the binding is created from by
+	// `CREATE BINDING FROM PLAN CACHE DIGEST`, OriginNode and HintedNode are nil in this case.
+	PlanCacheDigest string
 }
 
 func (n *CreateBindingStmt) Restore(ctx *format.RestoreCtx) error {
@@ -1732,6 +1744,11 @@ func (n *CreateBindingStmt) Restore(ctx *format.RestoreCtx) error {
 	} else {
 		ctx.WriteKeyWord("SESSION ")
 	}
+	if n.OriginNode == nil {
+		ctx.WriteKeyWord("BINDING FROM PLAN CACHE DIGEST ")
+		ctx.WriteString(n.PlanCacheDigest)
+		return nil
+	}
 	ctx.WriteKeyWord("BINDING FOR ")
 	if err := n.OriginNode.Restore(ctx); err != nil {
 		return errors.Trace(err)
@@ -1749,6 +1766,9 @@ func (n *CreateBindingStmt) Accept(v Visitor) (Node, bool) {
 		return v.Leave(newNode)
 	}
 	n = newNode.(*CreateBindingStmt)
+	if n.OriginNode == nil {
+		return v.Leave(n)
+	}
 	origNode, ok := n.OriginNode.Accept(v)
 	if !ok {
 		return n, false
@@ -2053,6 +2073,10 @@ const (
 	AdminResetTelemetryID
 	AdminReloadStatistics
 	AdminFlushPlanCache
+	AdminShowDDLJobArgs
+	AdminCleanupFlashback
+	AdminUndoFlashback
+	AdminShowDDLJobLog
 )
 
 // HandleRange represents a range where handle value >= Begin and < End.
@@ -2148,6 +2172,10 @@ type AdminStmt struct {
 	Where          ExprNode
 	StatementScope StatementScope
 	LimitSimple    LimitSimple
+	// AsOf is only used by `ADMIN CHECKSUM TABLE`, `ADMIN CHECK TABLE` and `ADMIN CHECK INDEX` now.
+	AsOf *AsOfClause
+	// Force is only used by `ADMIN UNDO FLASHBACK ... FORCE` now.
+	Force bool
 }
 
 // Restore implements Node interface.
@@ -2163,6 +2191,16 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 		}
 		return nil
 	}
+	restoreAsOf := func() error {
+		if n.AsOf == nil {
+			return nil
+		}
+		ctx.WritePlain(" ")
+		if err := n.AsOf.Restore(ctx); err != nil {
+			return errors.Annotate(err, "An error occurred while restore AdminStmt.AsOf")
+		}
+		return nil
+	}
 	restoreJobIDs := func() {
 		for i, v := range n.JobIDs {
 			if i != 0 {
@@ -2198,12 +2236,18 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 		if err := restoreTables(); err != nil {
 			return err
 		}
+		if err := restoreAsOf(); err != nil {
+			return err
+		}
 	case AdminCheckIndex:
 		ctx.WriteKeyWord("CHECK INDEX ")
 		if err := restoreTables(); err != nil {
 			return err
 		}
 		ctx.WritePlainf(" %s", n.Index)
+		if err := restoreAsOf(); err != nil {
+			return err
+		}
 	case AdminRecoverIndex:
 		ctx.WriteKeyWord("RECOVER INDEX ")
 		if err := restoreTables(); err != nil {
@@ -2236,6 +2280,9 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 		if err := restoreTables(); err != nil {
 			return err
 		}
+		if err := restoreAsOf(); err != nil {
+			return err
+		}
 	case AdminCancelDDLJobs:
 		ctx.WriteKeyWord("CANCEL DDL JOBS ")
 		restoreJobIDs()
@@ -2245,6 +2292,12 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 	case AdminShowDDLJobQueriesWithRange:
 		ctx.WriteKeyWord("SHOW DDL JOB QUERIES LIMIT ")
 		ctx.WritePlainf("%d, %d", n.LimitSimple.Offset, n.LimitSimple.Count)
+	case AdminShowDDLJobArgs:
+		ctx.WriteKeyWord("SHOW DDL JOB ARGS ")
+		restoreJobIDs()
+	case AdminShowDDLJobLog:
+		ctx.WriteKeyWord("SHOW DDL JOB LOG ")
+		restoreJobIDs()
 	case AdminShowSlow:
 		ctx.WriteKeyWord("SHOW SLOW ")
 		if err := n.ShowSlow.Restore(ctx); err != nil {
@@ -2296,6 +2349,14 @@ func (n *AdminStmt) Restore(ctx *format.RestoreCtx) error {
 		} else if n.StatementScope == StatementScopeGlobal {
 			ctx.WriteKeyWord("FLUSH GLOBAL PLAN_CACHE")
 		}
+	case AdminCleanupFlashback:
+		ctx.WriteKeyWord("CLEANUP FLASHBACK")
+	case AdminUndoFlashback:
+		ctx.WriteKeyWord("UNDO FLASHBACK ")
+		restoreJobIDs()
+		if n.Force {
+			ctx.WriteKeyWord(" FORCE")
+		}
 	default:
 		return errors.New("Unsupported AdminStmt type")
 	}
@@ -2326,6 +2387,14 @@ func (n *AdminStmt) Accept(v Visitor) (Node, bool) {
 		n.Where = node.(ExprNode)
 	}
 
+	if n.AsOf != nil {
+		node, ok := n.AsOf.Accept(v)
+		if !ok {
+			return n, false
+		}
+		n.AsOf = node.(*AsOfClause)
+	}
+
 	return v.Leave(n)
 }
 
@@ -3560,7 +3629,7 @@ func (n *TableOptimizerHint) Restore(ctx *format.RestoreCtx) error {
 	}
 	// Hints without args except query block.
 	switch n.HintName.L {
-	case "hash_agg", "stream_agg", "agg_to_cop", "read_consistent_replica", "no_index_merge", "qb_name", "ignore_plan_cache", "limit_to_cop", "straight_join", "merge":
+	case "hash_agg", "stream_agg", "agg_to_cop", "read_consistent_replica", "no_index_merge", "qb_name", "ignore_plan_cache", "use_plan_cache", "limit_to_cop", "straight_join", "merge":
 		ctx.WritePlain(")")
 		return nil
 	}
@@ -153,6 +153,7 @@ var tokenMap = map[string]int{
 	"ANY":                      any,
 	"APPROX_COUNT_DISTINCT":    approxCountDistinct,
 	"APPROX_PERCENTILE":        approxPercentile,
+	"ARGS":                     args,
 	"AS":                       as,
 	"ASC":                      asc,
 	"ASCII":                    ascii,
@@ -235,6 +236,7 @@ var tokenMap = map[string]int{
 	"COMPRESSED":               compressed,
 	"COMPRESSION":              compression,
 	"CONCURRENCY":              concurrency,
+	"CONFLICTING":              conflicting,
 	"CONFIG":                   config,
 	"CONNECTION":               connection,
 	"CONSISTENCY":              consistency,
@@ -288,6 +290,7 @@ var tokenMap = map[string]int{
 	"DEPTH":                    depth,
 	"DESC":                     desc,
 	"DESCRIBE":                 describe,
+	"DIGEST":                   digest,
 	"DIRECTORY":                directory,
 	"DISABLE":                  disable,
 	"DISABLED":                 disabled,
@@ -447,6 +450,7 @@ var tokenMap = map[string]int{
 	"LOCATION":                 location,
 	"LOCK":                     lock,
 	"LOCKED":                   locked,
+	"LOG":                      log,
 	"LOGS":                     logs,
 	"LONG":                     long,
 	"LONGBLOB":                 longblobType,
@@ -726,6 +730,7 @@ var tokenMap = map[string]int{
 	"TIFLASH":                  tiFlash,
 	"TIKV_IMPORTER":            tikvImporter,
 	"TIME":                     timeType,
+	"TIMEOUT":                  timeout,
 	"TIMESTAMP":                timestampType,
 	"TIMESTAMPADD":             timestampAdd,
 	"TIMESTAMPDIFF":            timestampDiff,
@@ -755,10 +760,12 @@ var tokenMap = map[string]int{
 	"TRUE":                     trueKwd,
 	"TRUNCATE":                 truncate,
 	"TRUE_CARD_COST":           trueCardCost,
+	"TSO":                      tso,
 	"TYPE":                     tp,
 	"UNBOUNDED":                unbounded,
 	"UNCOMMITTED":              uncommitted,
 	"UNDEFINED":                undefined,
+	"UNDO":                     undo,
 	"UNICODE":                  unicodeSym,
 	"UNION":                    union,
 	"UNIQUE":                   unique,
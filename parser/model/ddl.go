@@ -97,6 +97,7 @@ const (
 	ActionCreateTables                  ActionType = 60
 	ActionMultiSchemaChange             ActionType = 61
 	ActionFlashbackCluster              ActionType = 62
+	ActionFlashbackTables               ActionType = 63
 )
 
 var actionMap = map[ActionType]string{
@@ -158,6 +159,7 @@ var actionMap = map[ActionType]string{
 	ActionAlterTableStatsOptions:        "alter table statistics options",
 	ActionMultiSchemaChange:             "alter table multi-schema change",
 	ActionFlashbackCluster:              "flashback cluster",
+	ActionFlashbackTables:               "flashback tables",
 
 	// `ActionAlterTableAlterPartition` is removed and will never be used.
 	// Just left a tombstone here for compatibility.
@@ -222,6 +224,104 @@ type DDLReorgMeta struct {
 	Warnings      map[errors.ErrorID]*terror.Error `json:"warnings"`
 	WarningsCount map[errors.ErrorID]int64         `json:"warnings_count"`
 	Location      *TimeZoneLocation                `json:"location"`
+	// RetryCount is the number of retries consumed by the range operations of the job.
+	RetryCount int64 `json:"retry_count"`
+	// FlashbackBatchSize is the effective number of the regions flashed back by one request of the flashback job,
+	// it's smaller than the one in the args of the job if the requests have timed out.
+	FlashbackBatchSize int `json:"flashback_batch_size,omitempty"`
+	// FlashbackRanges is the counters of the key ranges flashed back by the flashback job, indexed by the ranges, it's
+	// nil for the range which isn't flashed back yet.
+	FlashbackRanges []*FlashbackRangeStats `json:"flashback_ranges,omitempty"`
+	// Progress is the progress of the reorg sampled by the DDL owner, it's used to estimate the remaining time.
+	Progress *ReorgProgress `json:"progress,omitempty"`
+}
+
+// FlashbackRangeStats is the counters of a key range flashed back by the flashback job, which are returned by TiKV.
+// They're nil if TiKV doesn't return them for any request of the range, e.g. the TiKV of the older versions.
+type FlashbackRangeStats struct {
+	KeysProcessed   *uint64 `json:"keys_processed"`
+	VersionsWritten *uint64 `json:"versions_written"`
+}
+
+// FlashbackTotals returns the total counters of the key ranges flashed back by the flashback job. They're nil if the
+// counters of any range are missing, or no range is flashed back.
+func (dm *DDLReorgMeta) FlashbackTotals() (keysProcessed, versionsWritten *uint64) {
+	var keys, versions uint64
+	counted := false
+	for _, r := range dm.FlashbackRanges {
+		if r == nil {
+			continue
+		}
+		if r.KeysProcessed == nil || r.VersionsWritten == nil {
+			return nil, nil
+		}
+		keys += *r.KeysProcessed
+		versions += *r.VersionsWritten
+		counted = true
+	}
+	if !counted {
+		return nil, nil
+	}
+	return &keys, &versions
+}
+
+const (
+	// reorgProgressWindowSize is the max number of the samples in the sliding window of the reorg progress.
+	reorgProgressWindowSize = 10
+	// reorgProgressMaxGap is the max interval between two samples in the sliding window, the window is reset if
+	// the reorg hasn't been sampled for a longer time, e.g. the job is stalled.
+	reorgProgressMaxGap = time.Minute
+)
+
+// ReorgProgressSample is the processed count of the reorg at a time.
+type ReorgProgressSample struct {
+	// Time is the unix time in milliseconds when the sample is taken.
+	Time  int64 `json:"time"`
+	Count int64 `json:"count"`
+}
+
+// ReorgProgress is a sliding window of the progress samples of the reorg.
+type ReorgProgress struct {
+	// Total is the estimated total count to process, 0 means unknown.
+	Total   int64                 `json:"total"`
+	Samples []ReorgProgressSample `json:"samples"`
+}
+
+// AddSample adds the processed count at the time into the sliding window. The window is reset if the count goes
+// backwards or the last sample is too old, so the stale rate isn't used to estimate the remaining time.
+func (p *ReorgProgress) AddSample(now time.Time, count, total int64) {
+	sample := ReorgProgressSample{Time: now.UnixMilli(), Count: count}
+	p.Total = total
+	if n := len(p.Samples); n > 0 {
+		last := p.Samples[n-1]
+		if count < last.Count || sample.Time <= last.Time || sample.Time-last.Time > reorgProgressMaxGap.Milliseconds() {
+			p.Samples = p.Samples[:0]
+		}
+	}
+	p.Samples = append(p.Samples, sample)
+	if len(p.Samples) > reorgProgressWindowSize {
+		p.Samples = append(p.Samples[:0], p.Samples[len(p.Samples)-reorgProgressWindowSize:]...)
+	}
+}
+
+// EstimateRemaining estimates the remaining time of the reorg at now by the rate in the sliding window. It returns
+// false if the remaining time is unknown, i.e. there are less than 2 samples, nothing is processed in the window,
+// or the total count is unknown or already exceeded.
+func (p *ReorgProgress) EstimateRemaining(now time.Time) (time.Duration, bool) {
+	if p == nil || len(p.Samples) < 2 {
+		return 0, false
+	}
+	first, last := p.Samples[0], p.Samples[len(p.Samples)-1]
+	if last.Count <= first.Count || p.Total <= last.Count {
+		return 0, false
+	}
+	rate := float64(last.Count-first.Count) / float64(last.Time-first.Time)
+	remaining := time.Duration(float64(p.Total-last.Count)/rate) * time.Millisecond
+	remaining -= now.Sub(time.UnixMilli(last.Time))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
 }
 
 // TimeZoneLocation represents a single time zone.
@@ -408,6 +508,96 @@ type Job struct {
 
 	// SeqNum is the total order in all DDLs, it's used to identify the order of DDL.
 	SeqNum uint64 `json:"seq_num"`
+
+	// Logs are the recent events of the job, at most MaxJobLogEntries events are kept, see AppendLog.
+	Logs []*JobLogEntry `json:"logs,omitempty"`
+
+	// StateTransitions are the times when the job moves to a new state, at most MaxJobStateTransitions transitions
+	// are kept, see RecordStateTransition.
+	StateTransitions []*JobStateTransition `json:"state_transitions,omitempty"`
+}
+
+// MaxJobLogEntries is the max number of the events kept in the log of a job, the oldest events are rotated out.
+const MaxJobLogEntries = 32
+
+// JobLogEntry is an event in the log of a job.
+type JobLogEntry struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+	Msg   string    `json:"msg"`
+	// Fields is the JSON object of the structured fields of the event.
+	Fields string `json:"fields,omitempty"`
+}
+
+// AppendLog appends the event to the log of the job. The oldest event is dropped if the log is full.
+func (job *Job) AppendLog(entry *JobLogEntry) {
+	job.Mu.Lock()
+	defer job.Mu.Unlock()
+	if len(job.Logs) >= MaxJobLogEntries {
+		n := copy(job.Logs, job.Logs[len(job.Logs)-MaxJobLogEntries+1:])
+		for i := n; i < len(job.Logs); i++ {
+			job.Logs[i] = nil
+		}
+		job.Logs = job.Logs[:n]
+	}
+	job.Logs = append(job.Logs, entry)
+}
+
+// MaxJobStateTransitions is the max number of the state transitions kept in a job, the oldest ones are rotated out.
+const MaxJobStateTransitions = 32
+
+// JobStateTransition is the time when the job moves to the state.
+type JobStateTransition struct {
+	Time        time.Time   `json:"time"`
+	State       JobState    `json:"state"`
+	SchemaState SchemaState `json:"schema_state"`
+}
+
+// RecordStateTransition records that the job moves to its current state at the time. It does nothing if the state
+// isn't changed since the last transition. The oldest transition is dropped if there are too many transitions.
+func (job *Job) RecordStateTransition(t time.Time) {
+	if n := len(job.StateTransitions); n > 0 {
+		last := job.StateTransitions[n-1]
+		if last.State == job.State && last.SchemaState == job.SchemaState {
+			return
+		}
+	}
+	if len(job.StateTransitions) >= MaxJobStateTransitions {
+		n := copy(job.StateTransitions, job.StateTransitions[len(job.StateTransitions)-MaxJobStateTransitions+1:])
+		for i := n; i < len(job.StateTransitions); i++ {
+			job.StateTransitions[i] = nil
+		}
+		job.StateTransitions = job.StateTransitions[:n]
+	}
+	job.StateTransitions = append(job.StateTransitions, &JobStateTransition{
+		Time:        t,
+		State:       job.State,
+		SchemaState: job.SchemaState,
+	})
+}
+
+// JobStateDuration is how long the job stays in a state.
+type JobStateDuration struct {
+	State       JobState
+	SchemaState SchemaState
+	Duration    time.Duration
+}
+
+// StateDurations returns how long the job stays in each recorded state, in the order of the transitions. The last
+// state is excluded, since it lasts until the next transition which isn't recorded yet.
+func (job *Job) StateDurations() []JobStateDuration {
+	if len(job.StateTransitions) < 2 {
+		return nil
+	}
+	durations := make([]JobStateDuration, 0, len(job.StateTransitions)-1)
+	for i, tr := range job.StateTransitions[:len(job.StateTransitions)-1] {
+		durations = append(durations, JobStateDuration{
+			State:       tr.State,
+			SchemaState: tr.SchemaState,
+			Duration:    job.StateTransitions[i+1].Time.Sub(tr.Time),
+		})
+	}
+	return durations
 }
 
 // FinishTableJob is called when a job is finished.
@@ -777,7 +967,7 @@ func (job *Job) IsRollbackable() bool {
 		return job.SchemaState == StateNone
 	case ActionMultiSchemaChange:
 		return job.MultiSchemaInfo.Revertible
-	case ActionFlashbackCluster:
+	case ActionFlashbackCluster, ActionFlashbackTables:
 		if job.SchemaState == StateWriteReorganization {
 			return false
 		}
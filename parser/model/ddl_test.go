@@ -15,6 +15,7 @@ package model_test
 
 import (
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/pingcap/tidb/parser/model"
@@ -49,5 +50,88 @@ func TestJobSize(t *testing.T) {
 - SubJob.ToProxyJob()
 `
 	job := model.Job{}
-	require.Equal(t, 288, int(unsafe.Sizeof(job)), msg)
+	require.Equal(t, 336, int(unsafe.Sizeof(job)), msg)
+}
+
+func TestReorgProgressEstimateRemaining(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	var p *model.ReorgProgress
+	_, ok := p.EstimateRemaining(start)
+	require.False(t, ok)
+
+	// 100 rows are processed per second.
+	p = &model.ReorgProgress{}
+	p.AddSample(start, 0, 10000)
+	_, ok = p.EstimateRemaining(start)
+	require.False(t, ok)
+	for i := 1; i <= 20; i++ {
+		p.AddSample(start.Add(time.Duration(i)*time.Second), int64(i*100), 10000)
+	}
+	require.Len(t, p.Samples, 10)
+	now := start.Add(20 * time.Second)
+	remaining, ok := p.EstimateRemaining(now)
+	require.True(t, ok)
+	require.Equal(t, 80*time.Second, remaining)
+	remaining, ok = p.EstimateRemaining(now.Add(30 * time.Second))
+	require.True(t, ok)
+	require.Equal(t, 50*time.Second, remaining)
+	remaining, ok = p.EstimateRemaining(now.Add(time.Hour))
+	require.True(t, ok)
+	require.Equal(t, time.Duration(0), remaining)
+
+	// The rate in the window is used, so the slowdown shows up once the old samples slide out.
+	for i := 21; i <= 30; i++ {
+		p.AddSample(start.Add(time.Duration(i)*time.Second), int64(2000+(i-20)*10), 10000)
+	}
+	remaining, ok = p.EstimateRemaining(start.Add(30 * time.Second))
+	require.True(t, ok)
+	require.Equal(t, 790*time.Second, remaining)
+
+	// The window is reset after a long gap.
+	p.AddSample(start.Add(30*time.Second+2*time.Minute), 2200, 10000)
+	require.Len(t, p.Samples, 1)
+	_, ok = p.EstimateRemaining(start.Add(30*time.Second + 2*time.Minute))
+	require.False(t, ok)
+
+	// The window is reset if the count goes backwards.
+	p.AddSample(start.Add(31*time.Second+2*time.Minute), 2300, 10000)
+	require.Len(t, p.Samples, 2)
+	p.AddSample(start.Add(32*time.Second+2*time.Minute), 100, 10000)
+	require.Len(t, p.Samples, 1)
+
+	// Nothing is processed in the window, or the total is unknown or exceeded.
+	p = &model.ReorgProgress{}
+	p.AddSample(start, 100, 10000)
+	p.AddSample(start.Add(time.Second), 100, 10000)
+	_, ok = p.EstimateRemaining(start.Add(time.Second))
+	require.False(t, ok)
+	p.AddSample(start.Add(2*time.Second), 200, 0)
+	_, ok = p.EstimateRemaining(start.Add(2 * time.Second))
+	require.False(t, ok)
+	p.AddSample(start.Add(3*time.Second), 300, 200)
+	_, ok = p.EstimateRemaining(start.Add(3 * time.Second))
+	require.False(t, ok)
+}
+
+func TestFlashbackTotals(t *testing.T) {
+	dm := &model.DDLReorgMeta{}
+	keys, versions := dm.FlashbackTotals()
+	require.Nil(t, keys)
+	require.Nil(t, versions)
+
+	counter := func(v uint64) *uint64 { return &v }
+	dm.FlashbackRanges = []*model.FlashbackRangeStats{
+		{KeysProcessed: counter(3), VersionsWritten: counter(2)},
+		nil,
+		{KeysProcessed: counter(4), VersionsWritten: counter(1)},
+	}
+	keys, versions = dm.FlashbackTotals()
+	require.Equal(t, uint64(7), *keys)
+	require.Equal(t, uint64(3), *versions)
+
+	// The totals are missing if the counters of any range are missing.
+	dm.FlashbackRanges[1] = &model.FlashbackRangeStats{}
+	keys, versions = dm.FlashbackTotals()
+	require.Nil(t, keys)
+	require.Nil(t, versions)
 }
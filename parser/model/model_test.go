@@ -496,6 +496,64 @@ func TestJobCodec(t *testing.T) {
 	require.Equal(t, int64(3), job.GetRowCount())
 }
 
+func TestJobAppendLog(t *testing.T) {
+	job := &Job{ID: 1, BinlogInfo: &HistoryInfo{}}
+	for i := 0; i < MaxJobLogEntries+3; i++ {
+		job.AppendLog(&JobLogEntry{Time: time.Unix(int64(i), 0), Level: "info", Msg: fmt.Sprintf("event %d", i)})
+	}
+	// The oldest events are rotated out.
+	require.Len(t, job.Logs, MaxJobLogEntries)
+	require.Equal(t, "event 3", job.Logs[0].Msg)
+	require.Equal(t, fmt.Sprintf("event %d", MaxJobLogEntries+2), job.Logs[MaxJobLogEntries-1].Msg)
+
+	b, err := job.Encode(false)
+	require.NoError(t, err)
+	newJob := &Job{}
+	require.NoError(t, newJob.Decode(b))
+	require.Len(t, newJob.Logs, MaxJobLogEntries)
+	require.Equal(t, job.Logs[0].Msg, newJob.Logs[0].Msg)
+	require.True(t, job.Logs[0].Time.Equal(newJob.Logs[0].Time))
+
+	// The jobs without the log are encoded as before.
+	b, err = (&Job{ID: 2, BinlogInfo: &HistoryInfo{}}).Encode(false)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "logs")
+}
+
+func TestJobStateDurations(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	job := &Job{ID: 1, BinlogInfo: &HistoryInfo{}}
+	require.Nil(t, job.StateDurations())
+	job.State = JobStateRunning
+	job.RecordStateTransition(start)
+	require.Nil(t, job.StateDurations())
+	// The unchanged state isn't recorded again.
+	job.RecordStateTransition(start.Add(time.Second))
+	require.Len(t, job.StateTransitions, 1)
+	job.SchemaState = StateWriteOnly
+	job.RecordStateTransition(start.Add(2 * time.Second))
+	job.State, job.SchemaState = JobStateSynced, StatePublic
+	job.RecordStateTransition(start.Add(5 * time.Second))
+	require.Equal(t, []JobStateDuration{
+		{State: JobStateRunning, SchemaState: StateNone, Duration: 2 * time.Second},
+		{State: JobStateRunning, SchemaState: StateWriteOnly, Duration: 3 * time.Second},
+	}, job.StateDurations())
+
+	b, err := job.Encode(false)
+	require.NoError(t, err)
+	newJob := &Job{}
+	require.NoError(t, newJob.Decode(b))
+	require.Equal(t, job.StateDurations(), newJob.StateDurations())
+
+	// The oldest transitions are rotated out.
+	for i := 0; i < MaxJobStateTransitions; i++ {
+		job.SchemaState = SchemaState(i%2 + 1)
+		job.RecordStateTransition(start.Add(time.Duration(i+6) * time.Second))
+	}
+	require.Len(t, job.StateTransitions, MaxJobStateTransitions)
+	require.True(t, job.StateTransitions[0].Time.Equal(start.Add(6*time.Second)))
+}
+
 func TestState(t *testing.T) {
 	schemaTbl := []SchemaState{
 		StateDeleteOnly,
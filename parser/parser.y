@@ -296,6 +296,7 @@ import (
 	algorithm             "ALGORITHM"
 	always                "ALWAYS"
 	any                   "ANY"
+	args                  "ARGS"
 	ascii                 "ASCII"
 	attributes            "ATTRIBUTES"
 	statsOptions          "STATS_OPTIONS"
@@ -347,6 +348,7 @@ import (
 	compressed            "COMPRESSED"
 	compression           "COMPRESSION"
 	concurrency           "CONCURRENCY"
+	conflicting           "CONFLICTING"
 	connection            "CONNECTION"
 	consistency           "CONSISTENCY"
 	consistent            "CONSISTENT"
@@ -370,6 +372,7 @@ import (
 	deallocate            "DEALLOCATE"
 	definer               "DEFINER"
 	delayKeyWrite         "DELAY_KEY_WRITE"
+	digest                "DIGEST"
 	directory             "DIRECTORY"
 	disable               "DISABLE"
 	disabled              "DISABLED"
@@ -444,6 +447,7 @@ import (
 	local                 "LOCAL"
 	locked                "LOCKED"
 	location              "LOCATION"
+	log                   "LOG"
 	logs                  "LOGS"
 	master                "MASTER"
 	max_idxnum            "MAX_IDXNUM"
@@ -601,6 +605,7 @@ import (
 	textType              "TEXT"
 	than                  "THAN"
 	tikvImporter          "TIKV_IMPORTER"
+	timeout               "TIMEOUT"
 	timestampType         "TIMESTAMP"
 	timeType              "TIME"
 	tp                    "TYPE"
@@ -609,9 +614,11 @@ import (
 	transaction           "TRANSACTION"
 	triggers              "TRIGGERS"
 	truncate              "TRUNCATE"
+	tso                   "TSO"
 	unbounded             "UNBOUNDED"
 	uncommitted           "UNCOMMITTED"
 	undefined             "UNDEFINED"
+	undo                  "UNDO"
 	unicodeSym            "UNICODE"
 	unknown               "UNKNOWN"
 	user                  "USER"
@@ -1030,6 +1037,7 @@ import (
 	FieldList                              "field expression list"
 	FlushOption                            "Flush option"
 	ForceOpt                               "Force opt"
+	FlashbackKillOpt                       "Flashback kill conflicting queries option"
 	InstanceOption                         "Instance option"
 	FulltextSearchModifierOpt              "Fulltext modifier"
 	PluginNameList                         "Plugin Name List"
@@ -1406,6 +1414,7 @@ import (
 	FieldAsName                     "Field alias name"
 	FieldAsNameOpt                  "Field alias name opt"
 	FieldTerminator                 "Field terminator"
+	FlashbackTimeoutOpt             "Flashback timeout option"
 	FlashbackToNewName              "Flashback to new name"
 	HashString                      "Hashed string"
 	LikeEscapeOpt                   "like escape option"
@@ -2570,23 +2579,59 @@ RecoverTableStmt:
  *  Flush Back Cluster Statement
  *
  *  Example:
+ *      FLASHBACK CLUSTER AS OF TIMESTAMP '2022-10-01 00:00:00'
+ *      FLASHBACK CLUSTER TO TSO 436709980869133058 FORCE
+ *      FLASHBACK CLUSTER AS OF TIMESTAMP '2022-10-01 00:00:00' KILL CONFLICTING QUERIES
  *
  *******************************************************************/
 FlashbackClusterStmt:
-	"FLASHBACK" "CLUSTER" asof "TIMESTAMP" stringLit
+	"FLASHBACK" "CLUSTER" asof "TIMESTAMP" stringLit ForceOpt FlashbackKillOpt FlashbackTimeoutOpt
 	{
 		$$ = &ast.FlashBackClusterStmt{
 			AsOf: ast.AsOfClause{
 				TsExpr: ast.NewValueExpr($5, "", ""),
 			},
+			Force:                  $6.(bool),
+			KillConflictingQueries: $7.(bool),
+			Timeout:                $8,
 		}
 	}
+|	"FLASHBACK" "CLUSTER" "TO" "TSO" LengthNum ForceOpt FlashbackKillOpt FlashbackTimeoutOpt
+	{
+		$$ = &ast.FlashBackClusterStmt{
+			FlashbackTSO:           $5.(uint64),
+			Force:                  $6.(bool),
+			KillConflictingQueries: $7.(bool),
+			Timeout:                $8,
+		}
+	}
+
+FlashbackKillOpt:
+	{
+		$$ = false
+	}
+|	"KILL" "CONFLICTING" "QUERIES"
+	{
+		$$ = true
+	}
+
+FlashbackTimeoutOpt:
+	{
+		$$ = ""
+	}
+|	"TIMEOUT" stringLit
+	{
+		$$ = $2
+	}
 
 /*******************************************************************
  *
  *  Flush Back Table Statement
  *
  *  Example:
+ *      FLASHBACK TABLE t TO t1
+ *      FLASHBACK TABLE t1, t2 TO TIMESTAMP '2022-10-01 00:00:00'
+ *      FLASHBACK TABLE t1, t2 TO TIMESTAMP '2022-10-01 00:00:00' FORCE
  *
  *******************************************************************/
 FlashbackTableStmt:
@@ -2597,6 +2642,26 @@ FlashbackTableStmt:
 			NewName: $4,
 		}
 	}
+|	"FLASHBACK" "TABLE" TableName "TO" "TIMESTAMP" stringLit ForceOpt
+	{
+		$$ = &ast.FlashBackToTimestampStmt{
+			Tables: []*ast.TableName{$3.(*ast.TableName)},
+			AsOf: ast.AsOfClause{
+				TsExpr: ast.NewValueExpr($6, "", ""),
+			},
+			Force: $7.(bool),
+		}
+	}
+|	"FLASHBACK" "TABLE" TableName ',' TableNameList "TO" "TIMESTAMP" stringLit ForceOpt
+	{
+		$$ = &ast.FlashBackToTimestampStmt{
+			Tables: append([]*ast.TableName{$3.(*ast.TableName)}, $5.([]*ast.TableName)...),
+			AsOf: ast.AsOfClause{
+				TsExpr: ast.NewValueExpr($8, "", ""),
+			},
+			Force: $9.(bool),
+		}
+	}
 
 FlashbackToNewName:
 	{
@@ -6005,6 +6070,7 @@ UnReservedKeyword:
 |	"AUTO_INCREMENT"
 |	"AFTER"
 |	"ALWAYS"
+|	"ARGS"
 |	"AVG"
 |	"BEGIN"
 |	"BIT"
@@ -6095,9 +6161,12 @@ UnReservedKeyword:
 |	"THAN"
 |	"TIME" %prec lowerThanStringLitToken
 |	"TIMESTAMP" %prec lowerThanStringLitToken
+|	"TIMEOUT"
+|	"CONFLICTING"
 |	"TRACE"
 |	"TRANSACTION"
 |	"TRUNCATE"
+|	"TSO"
 |	"UNBOUNDED"
 |	"UNKNOWN"
 |	"VALUE" %prec lowerThanValueKeyword
@@ -6198,6 +6267,7 @@ UnReservedKeyword:
 |	"MERGE"
 |	"TEMPTABLE"
 |	"UNDEFINED"
+|	"UNDO"
 |	"SECURITY"
 |	"CASCADED"
 |	"RECOVER"
@@ -6223,6 +6293,7 @@ UnReservedKeyword:
 |	"TRADITIONAL"
 |	"SQL_BUFFER_RESULT"
 |	"DIRECTORY"
+|	"DIGEST"
 |	"HISTOGRAM"
 |	"HISTORY"
 |	"LIST"
@@ -6268,6 +6339,7 @@ UnReservedKeyword:
 |	"REPLICA"
 |	"LOCATION"
 |	"LABELS"
+|	"LOG"
 |	"LOGS"
 |	"HOSTS"
 |	"AGAINST"
@@ -7231,6 +7303,7 @@ FunctionNameConflict:
 |	"INTERVAL"
 |	"FORMAT"
 |	"LEFT"
+|	"LOG"
 |	"MICROSECOND"
 |	"MINUTE"
 |	"MONTH"
@@ -10328,20 +10401,28 @@ AdminStmt:
 			Tables: []*ast.TableName{$3.(*ast.TableName)},
 		}
 	}
-|	"ADMIN" "CHECK" "TABLE" TableNameList
+|	"ADMIN" "CHECK" "TABLE" TableNameList AsOfClauseOpt
 	{
-		$$ = &ast.AdminStmt{
+		stmt := &ast.AdminStmt{
 			Tp:     ast.AdminCheckTable,
 			Tables: $4.([]*ast.TableName),
 		}
+		if $5 != nil {
+			stmt.AsOf = $5.(*ast.AsOfClause)
+		}
+		$$ = stmt
 	}
-|	"ADMIN" "CHECK" "INDEX" TableName Identifier
+|	"ADMIN" "CHECK" "INDEX" TableName Identifier AsOfClauseOpt
 	{
-		$$ = &ast.AdminStmt{
+		stmt := &ast.AdminStmt{
 			Tp:     ast.AdminCheckIndex,
 			Tables: []*ast.TableName{$4.(*ast.TableName)},
 			Index:  string($5),
 		}
+		if $6 != nil {
+			stmt.AsOf = $6.(*ast.AsOfClause)
+		}
+		$$ = stmt
 	}
 |	"ADMIN" "RECOVER" "INDEX" TableName Identifier
 	{
@@ -10368,12 +10449,16 @@ AdminStmt:
 			HandleRanges: $6.([]ast.HandleRange),
 		}
 	}
-|	"ADMIN" "CHECKSUM" "TABLE" TableNameList
+|	"ADMIN" "CHECKSUM" "TABLE" TableNameList AsOfClauseOpt
 	{
-		$$ = &ast.AdminStmt{
+		stmt := &ast.AdminStmt{
 			Tp:     ast.AdminChecksumTable,
 			Tables: $4.([]*ast.TableName),
 		}
+		if $5 != nil {
+			stmt.AsOf = $5.(*ast.AsOfClause)
+		}
+		$$ = stmt
 	}
 |	"ADMIN" "CANCEL" "DDL" "JOBS" NumList
 	{
@@ -10398,6 +10483,20 @@ AdminStmt:
 		ret.LimitSimple.Offset = $6.(*ast.LimitSimple).Offset
 		$$ = ret
 	}
+|	"ADMIN" "SHOW" "DDL" "JOB" "ARGS" NumList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:     ast.AdminShowDDLJobArgs,
+			JobIDs: $6.([]int64),
+		}
+	}
+|	"ADMIN" "SHOW" "DDL" "JOB" "LOG" NumList
+	{
+		$$ = &ast.AdminStmt{
+			Tp:     ast.AdminShowDDLJobLog,
+			JobIDs: $6.([]int64),
+		}
+	}
 |	"ADMIN" "SHOW" "SLOW" AdminShowSlow
 	{
 		$$ = &ast.AdminStmt{
@@ -10437,6 +10536,20 @@ AdminStmt:
 			Tables: $5.([]*ast.TableName),
 		}
 	}
+|	"ADMIN" "CLEANUP" "FLASHBACK"
+	{
+		$$ = &ast.AdminStmt{
+			Tp: ast.AdminCleanupFlashback,
+		}
+	}
+|	"ADMIN" "UNDO" "FLASHBACK" Int64Num ForceOpt
+	{
+		$$ = &ast.AdminStmt{
+			Tp:     ast.AdminUndoFlashback,
+			JobIDs: []int64{$4.(int64)},
+			Force:  $5.(bool),
+		}
+	}
 |	"ADMIN" "REPAIR" "TABLE" TableName CreateTableStmt
 	{
 		$$ = &ast.RepairTableStmt{
@@ -12972,6 +13085,7 @@ BindableStmt:
  *
  *  Example:
  *      CREATE GLOBAL BINDING FOR select Col1,Col2 from table USING select Col1,Col2 from table use index(Col1)
+ *      CREATE GLOBAL BINDING FROM PLAN CACHE DIGEST 'plan_digest'
  *******************************************************************/
 CreateBindingStmt:
 	"CREATE" GlobalScope "BINDING" "FOR" BindableStmt "USING" BindableStmt
@@ -12991,6 +13105,15 @@ CreateBindingStmt:
 			GlobalScope: $2.(bool),
 		}
 
+		$$ = x
+	}
+|	"CREATE" GlobalScope "BINDING" "FROM" "PLAN" "CACHE" "DIGEST" stringLit
+	{
+		x := &ast.CreateBindingStmt{
+			GlobalScope:     $2.(bool),
+			PlanCacheDigest: $8,
+		}
+
 		$$ = x
 	}
 
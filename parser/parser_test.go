@@ -798,11 +798,30 @@ func TestDMLStmt(t *testing.T) {
 		{"admin show ddl job queries limit 5, 10", true, "ADMIN SHOW DDL JOB QUERIES LIMIT 5, 10"},
 		{"admin show ddl job queries limit 3 offset 2", true, "ADMIN SHOW DDL JOB QUERIES LIMIT 2, 3"},
 		{"admin show ddl job queries limit 22 offset 0", true, "ADMIN SHOW DDL JOB QUERIES LIMIT 0, 22"},
+		{"admin show ddl job args 1", true, "ADMIN SHOW DDL JOB ARGS 1"},
+		{"admin show ddl job args 1, 2, 3", true, "ADMIN SHOW DDL JOB ARGS 1, 2, 3"},
+		{"admin show ddl job args", false, ""},
+		{"admin cleanup flashback", true, "ADMIN CLEANUP FLASHBACK"},
+		{"admin undo flashback 1", true, "ADMIN UNDO FLASHBACK 1"},
+		{"admin undo flashback 1 force", true, "ADMIN UNDO FLASHBACK 1 FORCE"},
+		{"admin undo flashback", false, ""},
+		{"admin undo flashback 1, 2", false, ""},
+		{"create table undo (tso int)", true, "CREATE TABLE `undo` (`tso` INT)"},
+		{"create table args (args int)", true, "CREATE TABLE `args` (`args` INT)"},
+		{"admin show ddl job log 1", true, "ADMIN SHOW DDL JOB LOG 1"},
+		{"admin show ddl job log 1, 2", true, "ADMIN SHOW DDL JOB LOG 1, 2"},
+		{"admin show ddl job log", false, ""},
+		{"create table log (log int)", true, "CREATE TABLE `log` (`log` INT)"},
+		{"select log(10), log(2, 8)", true, "SELECT LOG(10),LOG(2, 8)"},
 		{"admin show t1 next_row_id", true, "ADMIN SHOW `t1` NEXT_ROW_ID"},
 		{"admin check table t1, t2;", true, "ADMIN CHECK TABLE `t1`, `t2`"},
 		{"admin check index tableName idxName;", true, "ADMIN CHECK INDEX `tableName` idxName"},
+		{"admin check index tableName idxName as of timestamp '2022-10-01 00:00:00';", true, "ADMIN CHECK INDEX `tableName` idxName AS OF TIMESTAMP _UTF8MB4'2022-10-01 00:00:00'"},
+		{"admin check table t1, t2 as of timestamp now() - interval 1 minute;", true, "ADMIN CHECK TABLE `t1`, `t2` AS OF TIMESTAMP DATE_SUB(NOW(), INTERVAL 1 MINUTE)"},
 		{"admin check index tableName idxName (1, 2), (4, 5);", true, "ADMIN CHECK INDEX `tableName` idxName (1,2), (4,5)"},
 		{"admin checksum table t1, t2;", true, "ADMIN CHECKSUM TABLE `t1`, `t2`"},
+		{"admin checksum table t1, t2 as of timestamp '2022-10-01 00:00:00';", true, "ADMIN CHECKSUM TABLE `t1`, `t2` AS OF TIMESTAMP _UTF8MB4'2022-10-01 00:00:00'"},
+		{"admin checksum table t1 as of timestamp now() - interval 1 minute;", true, "ADMIN CHECKSUM TABLE `t1` AS OF TIMESTAMP DATE_SUB(NOW(), INTERVAL 1 MINUTE)"},
 		{"admin cancel ddl jobs 1", true, "ADMIN CANCEL DDL JOBS 1"},
 		{"admin cancel ddl jobs 1, 2", true, "ADMIN CANCEL DDL JOBS 1, 2"},
 		{"admin recover index t1 idx_a", true, "ADMIN RECOVER INDEX `t1` idx_a"},
@@ -3233,9 +3252,28 @@ func TestDDL(t *testing.T) {
 		// for flashback table.
 		{"flashback table t", true, "FLASHBACK TABLE `t`"},
 		{"flashback table t TO t1", true, "FLASHBACK TABLE `t` TO `t1`"},
+		{"flashback table t to timestamp '2021-05-26 16:45:26'", true, "FLASHBACK TABLE `t` TO TIMESTAMP '2021-05-26 16:45:26'"},
+		{"flashback table t1, test.t2, t3 to timestamp '2021-05-26 16:45:26'", true, "FLASHBACK TABLE `t1`, `test`.`t2`, `t3` TO TIMESTAMP '2021-05-26 16:45:26'"},
+		{"flashback table t1, t2 to timestamp '2021-05-26 16:45:26' force", true, "FLASHBACK TABLE `t1`, `t2` TO TIMESTAMP '2021-05-26 16:45:26' FORCE"},
+		{"flashback table t to timestamp", true, "FLASHBACK TABLE `t` TO `timestamp`"},
+		{"flashback table t1, t2", false, ""},
+		{"flashback table t1, t2 to t3", false, ""},
 
 		// for flashback cluster
 		{"flashback cluster as of timestamp '2021-05-26 16:45:26'", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26'"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' force", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26' FORCE"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' timeout '10m'", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26' TIMEOUT '10m'"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' force timeout '1h30m'", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26' FORCE TIMEOUT '1h30m'"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' timeout '10m' force", false, ""},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' timeout 10", false, ""},
+		{"flashback cluster to tso 436709980869133058", true, "FLASHBACK CLUSTER TO TSO 436709980869133058"},
+		{"flashback cluster to tso 436709980869133058 force timeout '10m'", true, "FLASHBACK CLUSTER TO TSO 436709980869133058 FORCE TIMEOUT '10m'"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' kill conflicting queries", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26' KILL CONFLICTING QUERIES"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' force kill conflicting queries timeout '10m'", true, "FLASHBACK CLUSTER AS OF TIMESTAMP '2021-05-26 16:45:26' FORCE KILL CONFLICTING QUERIES TIMEOUT '10m'"},
+		{"flashback cluster as of timestamp '2021-05-26 16:45:26' kill conflicting queries force", false, ""},
+		{"flashback cluster to tso 436709980869133058 kill conflicting queries", true, "FLASHBACK CLUSTER TO TSO 436709980869133058 KILL CONFLICTING QUERIES"},
+		{"flashback cluster to tso '436709980869133058'", false, ""},
+		{"create table timeout (timeout int)", true, "CREATE TABLE `timeout` (`timeout` INT)"},
 		{"flashback cluster as of timestamp TIDB_BOUNDED_STALENESS(DATE_SUB(NOW(), INTERVAL 3 SECOND), NOW())", false, ""},
 		{"flashback cluster as of timestamp DATE_SUB(NOW(), INTERVAL 3 SECOND)", false, ""},
 
@@ -4969,6 +5007,11 @@ func TestBinding(t *testing.T) {
 	table := []testCase{
 		{"create global binding for select * from t using select * from t use index(a)", true, "CREATE GLOBAL BINDING FOR SELECT * FROM `t` USING SELECT * FROM `t` USE INDEX (`a`)"},
 		{"create session binding for select * from t using select * from t use index(a)", true, "CREATE SESSION BINDING FOR SELECT * FROM `t` USING SELECT * FROM `t` USE INDEX (`a`)"},
+		{"create global binding from plan cache digest 'abc'", true, "CREATE GLOBAL BINDING FROM PLAN CACHE DIGEST 'abc'"},
+		{"create binding from plan cache digest 'abc'", true, "CREATE SESSION BINDING FROM PLAN CACHE DIGEST 'abc'"},
+		{"create binding from plan cache digest ''", true, "CREATE SESSION BINDING FROM PLAN CACHE DIGEST ''"},
+		{"create binding from plan cache digest abc", false, ""},
+		{"create table digest (digest int)", true, "CREATE TABLE `digest` (`digest` INT)"},
 		{"drop global binding for select * from t", true, "DROP GLOBAL BINDING FOR SELECT * FROM `t`"},
 		{"drop session binding for select * from t", true, "DROP SESSION BINDING FOR SELECT * FROM `t`"},
 		{"drop global binding for select * from t using select * from t use index(a)", true, "DROP GLOBAL BINDING FOR SELECT * FROM `t` USING SELECT * FROM `t` USE INDEX (`a`)"},
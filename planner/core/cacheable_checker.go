@@ -17,6 +17,7 @@ package core
 import (
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/sessionctx"
@@ -25,6 +26,28 @@ import (
 	"go.uber.org/zap"
 )
 
+// The reasons why a statement can't use the plan cache, which are returned by CacheableWithReason.
+const (
+	UncacheableStmtType        = "stmt_type"
+	UncacheableHint            = "ignore_plan_cache_hint"
+	UncacheableSubquery        = "subquery"
+	UncacheableSysVar          = "system_variable"
+	UncacheableUserVarAssign   = "user_variable_assignment"
+	UncacheableFunction        = "uncacheable_function"
+	UncacheableParamInOrderBy  = "param_in_order_by"
+	UncacheableParamInGroupBy  = "param_in_group_by"
+	UncacheableParamInLimit    = "param_in_limit"
+	UncacheableParamInFrame    = "param_in_window_frame"
+	UncacheablePartitionTable  = "partition_table"
+	UncacheableGeneratedColumn = "generated_column"
+	UncacheableTempTable       = "temporary_table"
+	UncacheableViewDepth       = "view_depth"
+	UncacheableView            = "invalid_view"
+)
+
+// maxViewDepth4PC is the max nesting depth of the views read by the statements whose plans can be cached.
+const maxViewDepth4PC = 8
+
 // Cacheable checks whether the input ast is cacheable with empty session context, which is mainly for testing.
 func Cacheable(node ast.Node, is infoschema.InfoSchema) bool {
 	return CacheableWithCtx(nil, node, is)
@@ -34,161 +57,201 @@ func Cacheable(node ast.Node, is infoschema.InfoSchema) bool {
 // Handle "ignore_plan_cache()" hint
 // If there are multiple hints, only one will take effect
 func CacheableWithCtx(sctx sessionctx.Context, node ast.Node, is infoschema.InfoSchema) bool {
-	_, isSelect := node.(*ast.SelectStmt)
-	_, isUpdate := node.(*ast.UpdateStmt)
-	_, isInsert := node.(*ast.InsertStmt)
-	_, isDelete := node.(*ast.DeleteStmt)
-	_, isSetOpr := node.(*ast.SetOprStmt)
-	if !(isSelect || isUpdate || isInsert || isDelete || isSetOpr) {
-		return false
+	cacheable, _ := cacheableWithReason(sctx, node, is)
+	return cacheable
+}
+
+// CacheableWithReason checks whether the plan of the statement can be cached, and returns the reason, which is one
+// of the Uncacheable* constants, if it can't. The AST is walked once, and the checks on the tables are skipped if
+// is is nil, so that the result only depends on the statement itself.
+func CacheableWithReason(stmt ast.StmtNode, is infoschema.InfoSchema) (bool, string) {
+	return cacheableWithReason(nil, stmt, is)
+}
+
+func cacheableWithReason(sctx sessionctx.Context, node ast.Node, is infoschema.InfoSchema) (bool, string) {
+	checker := checkCacheable(sctx, node, is, false)
+	return checker.cacheable, checker.reason
+}
+
+// checkCacheable walks the statement and returns the checker holding the result. The isGeneralStmt is whether the
+// statement is parameterized by the general plan cache, which caches the plans of more tables, see checkTable.
+func checkCacheable(sctx sessionctx.Context, node ast.Node, is infoschema.InfoSchema, isGeneralStmt bool) *cacheableChecker {
+	checker := &cacheableChecker{
+		sctx:          sctx,
+		cacheable:     true,
+		schema:        is,
+		isGeneralStmt: isGeneralStmt,
 	}
-	checker := cacheableChecker{
-		sctx:      sctx,
-		cacheable: true,
-		schema:    is,
+	switch node.(type) {
+	case *ast.SelectStmt, *ast.UpdateStmt, *ast.InsertStmt, *ast.DeleteStmt, *ast.SetOprStmt:
+	default:
+		checker.uncacheable(node, UncacheableStmtType)
+		return checker
 	}
-	node.Accept(&checker)
-	return checker.cacheable
+	node.Accept(checker)
+	return checker
 }
 
 // cacheableChecker checks whether a query's plan can be cached, querys that:
 //  1. have ExistsSubqueryExpr, or
-//  2. have VariableExpr
+//  2. have system variables or the assignments of user variables
 //
 // will not be cached currently.
 // NOTE: we can add more rules in the future.
 type cacheableChecker struct {
 	sctx      sessionctx.Context
 	cacheable bool
+	reason    string
 	schema    infoschema.InfoSchema
+
+	isGeneralStmt bool
+	// hasPartitionTable is whether the statement reads the partitioned tables, whose plans are only cached for the
+	// general statements in the dynamic prune mode.
+	hasPartitionTable bool
+	// hasView is whether the statement reads the views, the definitions of the views are checked as well.
+	hasView   bool
+	viewDepth int
+}
+
+// uncacheable marks the statement uncacheable for the reason, and stops the walk.
+func (checker *cacheableChecker) uncacheable(in ast.Node, reason string) (ast.Node, bool) {
+	checker.cacheable = false
+	checker.reason = reason
+	return in, true
 }
 
 // Enter implements Visitor interface.
 func (checker *cacheableChecker) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 	switch node := in.(type) {
 	case *ast.SelectStmt:
-		for _, hints := range node.TableHints {
-			if hints.HintName.L == HintIgnorePlanCache {
-				checker.cacheable = false
-				return in, true
-			}
+		if hasIgnorePlanCacheHint(node.TableHints) {
+			return checker.uncacheable(in, UncacheableHint)
 		}
 	case *ast.DeleteStmt:
-		for _, hints := range node.TableHints {
-			if hints.HintName.L == HintIgnorePlanCache {
-				checker.cacheable = false
-				return in, true
-			}
+		if hasIgnorePlanCacheHint(node.TableHints) {
+			return checker.uncacheable(in, UncacheableHint)
 		}
 	case *ast.UpdateStmt:
-		for _, hints := range node.TableHints {
-			if hints.HintName.L == HintIgnorePlanCache {
-				checker.cacheable = false
-				return in, true
-			}
+		if hasIgnorePlanCacheHint(node.TableHints) {
+			return checker.uncacheable(in, UncacheableHint)
 		}
-	case *ast.VariableExpr, *ast.ExistsSubqueryExpr, *ast.SubqueryExpr:
-		checker.cacheable = false
-		return in, true
+	case *ast.VariableExpr:
+		// The reads of user variables are evaluated on each execution of the cached plan, while the system
+		// variables are folded into the plan and the assignments have side effects.
+		if node.IsSystem {
+			return checker.uncacheable(in, UncacheableSysVar)
+		}
+		if node.Value != nil {
+			return checker.uncacheable(in, UncacheableUserVarAssign)
+		}
+	case *ast.ExistsSubqueryExpr, *ast.SubqueryExpr:
+		return checker.uncacheable(in, UncacheableSubquery)
 	case *ast.FuncCallExpr:
 		if _, found := expression.UnCacheableFunctions[node.FnName.L]; found {
-			checker.cacheable = false
-			return in, true
+			return checker.uncacheable(in, UncacheableFunction)
 		}
 	case *ast.OrderByClause:
 		for _, item := range node.Items {
 			if _, isParamMarker := item.Expr.(*driver.ParamMarkerExpr); isParamMarker {
-				checker.cacheable = false
-				return in, true
+				return checker.uncacheable(in, UncacheableParamInOrderBy)
 			}
 		}
 	case *ast.GroupByClause:
 		for _, item := range node.Items {
 			if _, isParamMarker := item.Expr.(*driver.ParamMarkerExpr); isParamMarker {
-				checker.cacheable = false
-				return in, true
+				return checker.uncacheable(in, UncacheableParamInGroupBy)
 			}
 		}
 	case *ast.Limit:
 		if node.Count != nil {
 			if _, isParamMarker := node.Count.(*driver.ParamMarkerExpr); isParamMarker {
-				checker.cacheable = false
-				return in, true
+				return checker.uncacheable(in, UncacheableParamInLimit)
 			}
 		}
 		if node.Offset != nil {
 			if _, isParamMarker := node.Offset.(*driver.ParamMarkerExpr); isParamMarker {
-				checker.cacheable = false
-				return in, true
+				return checker.uncacheable(in, UncacheableParamInLimit)
 			}
 		}
 	case *ast.FrameBound:
 		if _, ok := node.Expr.(*driver.ParamMarkerExpr); ok {
-			checker.cacheable = false
-			return in, true
+			return checker.uncacheable(in, UncacheableParamInFrame)
 		}
 	case *ast.TableName:
 		if checker.schema != nil {
-			if checker.isPartitionTable(node) {
-				// Temporary disable prepared plan cache until https://github.com/pingcap/tidb/issues/33031
-				// is fixed and additional tests with dynamic partition prune mode has been added.
-				/*
-					if checker.sctx != nil && checker.sctx.GetSessionVars().UseDynamicPartitionPrune() {
-						return in, false // dynamic-mode for partition tables can use plan-cache
-					}
-				*/
-				checker.cacheable = false
-				return in, true
-			}
-			if checker.hasGeneratedCol(node) {
-				checker.cacheable = false
-				return in, true
-			}
-			if checker.isTempTable(node) {
-				checker.cacheable = false
-				return in, true
+			if reason := checker.checkTable(node); reason != "" {
+				return checker.uncacheable(in, reason)
 			}
 		}
 	}
 	return in, false
 }
 
-func (checker *cacheableChecker) hasGeneratedCol(tn *ast.TableName) bool {
-	tb, err := checker.schema.TableByName(tn.Schema, tn.Name)
-	if err != nil {
-		logutil.BgLogger().Error("Error occur in checking cacheable", zap.Error(err))
-		return false
-	}
-	for _, col := range tb.Cols() {
-		if col.IsGenerated() {
+func hasIgnorePlanCacheHint(hints []*ast.TableOptimizerHint) bool {
+	for _, hint := range hints {
+		if hint.HintName.L == HintIgnorePlanCache {
 			return true
 		}
 	}
 	return false
 }
 
-func (checker *cacheableChecker) isTempTable(tn *ast.TableName) bool {
+// checkTable returns the reason why the plans reading the table can't be cached, or an empty string if they can.
+func (checker *cacheableChecker) checkTable(tn *ast.TableName) string {
 	tb, err := checker.schema.TableByName(tn.Schema, tn.Name)
 	if err != nil {
 		logutil.BgLogger().Error("Error occur in checking cacheable", zap.Error(err))
-		return false
+		return ""
+	}
+	if tb.Meta().IsView() {
+		return checker.checkView(tb.Meta())
+	}
+	if pi := tb.Meta().GetPartitionInfo(); pi != nil {
+		// Temporary disable prepared plan cache until https://github.com/pingcap/tidb/issues/33031
+		// is fixed and additional tests with dynamic partition prune mode has been added.
+		// The general statements reading the range partitioned tables are the exception, in the dynamic mode the
+		// partitions are pruned by the executors with the params of each execution rather than by the cached plan.
+		if !checker.isGeneralStmt || checker.sctx == nil || !checker.sctx.GetSessionVars().UseDynamicPartitionPrune() ||
+			pi.Type != model.PartitionTypeRange {
+			return UncacheablePartitionTable
+		}
+		checker.hasPartitionTable = true
+	}
+	for _, col := range tb.Cols() {
+		if col.IsGenerated() {
+			return UncacheableGeneratedColumn
+		}
 	}
 	if tb.Meta().TempTableType != model.TempTableNone {
-		return true
+		return UncacheableTempTable
 	}
-	return false
+	return ""
 }
 
-func (checker *cacheableChecker) isPartitionTable(tn *ast.TableName) bool {
-	tb, err := checker.schema.TableByName(tn.Schema, tn.Name)
+// checkView walks the definition of the view, the plans reading the view read the tables in it as well. The
+// recursive views are never built, they're stopped by the depth limit here.
+func (checker *cacheableChecker) checkView(tblInfo *model.TableInfo) string {
+	checker.hasView = true
+	if checker.viewDepth >= maxViewDepth4PC {
+		return UncacheableViewDepth
+	}
+	p := parser.New()
+	var charset, collation string
+	if checker.sctx != nil {
+		p.SetParserConfig(checker.sctx.GetSessionVars().BuildParserConfig())
+		charset, collation = checker.sctx.GetSessionVars().GetCharsetInfo()
+	}
+	selectNode, err := p.ParseOneStmt(tblInfo.View.SelectStmt, charset, collation)
 	if err != nil {
-		logutil.BgLogger().Error("Error occur in checking cacheable", zap.Error(err))
-		return false
+		return UncacheableView
 	}
-	if tb.Meta().GetPartitionInfo() != nil {
-		return true
+	checker.viewDepth++
+	selectNode.Accept(checker)
+	checker.viewDepth--
+	if !checker.cacheable {
+		return checker.reason
 	}
-	return false
+	return ""
 }
 
 // Leave implements Visitor interface.
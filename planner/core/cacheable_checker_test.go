@@ -15,11 +15,14 @@
 package core_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/auth"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/testkit"
@@ -244,3 +247,100 @@ func TestCacheable(t *testing.T) {
 	}
 	require.True(t, core.Cacheable(stmt, is))
 }
+
+func TestCacheableWithReason(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, key(a))")
+	tk.MustExec("create table tp (a int, b int) partition by hash(a) partitions 4")
+	tk.MustExec("create table tg (a int, b int as (a + 1), c int as (a + 2) stored)")
+	tk.MustExec("create global temporary table tt (a int) on commit delete rows")
+	// The nested views are checked by the privileges of their definers.
+	require.NoError(t, tk.Session().Auth(&auth.UserIdentity{Username: "root", Hostname: "%"}, nil, nil))
+	tk.MustExec("create view vj as select t1.a, t2.b from t t1 join t t2 on t1.a = t2.a")
+	tk.MustExec("create view vp as select a, b from tp")
+	tk.MustExec("create view vs as select a from t where a in (select a from t)")
+	// The views v0, v1, ..., v8 are nested, v0 reads t.
+	tk.MustExec("create view v0 as select a from t")
+	for i := 1; i <= 8; i++ {
+		tk.MustExec(fmt.Sprintf("create view v%d as select a from v%d", i, i-1))
+	}
+	is := tk.Session().GetInfoSchema().(infoschema.InfoSchema)
+
+	cases := []struct {
+		sql string
+		// reason is empty if the statement is cacheable.
+		reason string
+		// noSchemaReason is the reason if the tables aren't checked.
+		noSchemaReason string
+	}{
+		{"select * from test.t where a = ?", "", ""},
+		{"select * from test.t where a in (?, ?, ?)", "", ""},
+		{"select * from test.t where a like 'x%'", "", ""},
+		{"select * from test.t where b < now()", "", ""},
+		{"select a, count(*) from test.t group by a", "", ""},
+		{"select a from test.t group by ?", core.UncacheableParamInGroupBy, core.UncacheableParamInGroupBy},
+		{"select a from test.t order by ?", core.UncacheableParamInOrderBy, core.UncacheableParamInOrderBy},
+		{"select a from test.t limit 10", "", ""},
+		{"select a from test.t limit ?", core.UncacheableParamInLimit, core.UncacheableParamInLimit},
+		{"select a from test.t limit 1 offset ?", core.UncacheableParamInLimit, core.UncacheableParamInLimit},
+		{"select * from test.t where a = ? for update", "", ""},
+		{"select * from test.t where a = ? for update nowait", "", ""},
+		{"select * from test.t where a = ? lock in share mode", "", ""},
+		{"select a, row_number() over (order by b) from test.t", "", ""},
+		{"select sum(a) over (order by b rows between 1 preceding and current row) from test.t", "", ""},
+		{"select sum(a) over (order by b rows between ? preceding and current row) from test.t", core.UncacheableParamInFrame, core.UncacheableParamInFrame},
+		{"select * from test.t t1 join test.t t2 on t1.a = t2.b where t1.a = ?", "", ""},
+		{"select a from test.t union select b from test.t", "", ""},
+		{"(select a from test.t limit ?) union all (select b from test.t)", core.UncacheableParamInLimit, core.UncacheableParamInLimit},
+		{"select * from test.t where a in (select a from test.t)", core.UncacheableSubquery, core.UncacheableSubquery},
+		{"select * from test.t where exists (select 1 from test.t)", core.UncacheableSubquery, core.UncacheableSubquery},
+		{"select /*+ ignore_plan_cache() */ * from test.t", core.UncacheableHint, core.UncacheableHint},
+		{"select * from test.t where a = @@tidb_mem_quota_query", core.UncacheableSysVar, core.UncacheableSysVar},
+		{"select @a := a from test.t", core.UncacheableUserVarAssign, core.UncacheableUserVarAssign},
+		{"select * from test.t where a = @a", "", ""},
+		{"select * from test.vj where a = ?", "", ""},
+		{"select * from test.vp where a = ?", core.UncacheablePartitionTable, ""},
+		{"select * from test.vs", core.UncacheableSubquery, ""},
+		{"select * from test.v7 where a = ?", "", ""},
+		{"select * from test.v8 where a = ?", core.UncacheableViewDepth, ""},
+		{"select database() from test.t", core.UncacheableFunction, core.UncacheableFunction},
+		{"select * from test.tp where a = ?", core.UncacheablePartitionTable, ""},
+		{"select a from test.tg where a = ?", core.UncacheableGeneratedColumn, ""},
+		{"select * from test.tt", core.UncacheableTempTable, ""},
+		{"insert into test.t values (?, ?)", "", ""},
+		{"insert into test.tg (a) values (?)", core.UncacheableGeneratedColumn, ""},
+		{"insert into test.t select * from test.t where a > ?", "", ""},
+		{"update test.t set b = ? where a = ?", "", ""},
+		{"update /*+ ignore_plan_cache() */ test.t set b = 1", core.UncacheableHint, core.UncacheableHint},
+		{"update test.t set b = (select max(a) from test.t)", core.UncacheableSubquery, core.UncacheableSubquery},
+		{"delete from test.t where a = ?", "", ""},
+		{"delete /*+ ignore_plan_cache() */ from test.t where a = ?", core.UncacheableHint, core.UncacheableHint},
+		{"delete from test.t where a in (select a from test.t)", core.UncacheableSubquery, core.UncacheableSubquery},
+		{"show tables", core.UncacheableStmtType, core.UncacheableStmtType},
+		{"set @a = 1", core.UncacheableStmtType, core.UncacheableStmtType},
+		{"create table t1 (a int)", core.UncacheableStmtType, core.UncacheableStmtType},
+	}
+	p := parser.New()
+	for _, c := range cases {
+		stmt, err := p.ParseOneStmt(c.sql, "", "")
+		require.NoError(t, err, c.sql)
+		cacheable, reason := core.CacheableWithReason(stmt, is)
+		require.Equal(t, c.reason == "", cacheable, c.sql)
+		require.Equal(t, c.reason, reason, c.sql)
+		require.Equal(t, cacheable, core.Cacheable(stmt, is), c.sql)
+		cacheable, reason = core.CacheableWithReason(stmt, nil)
+		require.Equal(t, c.noSchemaReason == "", cacheable, c.sql)
+		require.Equal(t, c.noSchemaReason, reason, c.sql)
+	}
+
+	// The reason is warned when preparing the statement.
+	tk.MustExec("prepare st from 'select a from t limit ?'")
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 skip plan-cache: param_in_limit"))
+	tk.MustExec("prepare st from 'select a from t where a = ?'")
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	// The statements which can't be cached aren't parameterized for the general plan cache.
+	require.Nil(t, core.GetParamTemplate(tk.Session(), "select a from t where a = @@tidb_mem_quota_query"))
+	require.NotNil(t, core.GetParamTemplate(tk.Session(), "select a from t where a = 1"))
+}
@@ -44,6 +44,11 @@ import (
 
 var planCacheCounter = metrics.PlanCacheCounter.WithLabelValues("prepare")
 var planCacheMissCounter = metrics.PlanCacheMissCounter.WithLabelValues("cache_miss")
+var planCacheSkipSnapshotCounter = metrics.PlanCacheMissCounter.WithLabelValues("skip_tidb_snapshot")
+var planCacheSkipStaleTxnCounter = metrics.PlanCacheMissCounter.WithLabelValues("skip_stale_txn")
+var planCacheSkipStaleReadCounter = metrics.PlanCacheMissCounter.WithLabelValues("skip_stale_read")
+var planCacheSkipStatsAsOfCounter = metrics.PlanCacheMissCounter.WithLabelValues("skip_stats_as_of")
+var planCacheTxnMismatchCounter = metrics.PlanCacheMissCounter.WithLabelValues("txn_mismatch")
 
 // ShowDDL is for showing DDL information.
 type ShowDDL struct {
@@ -72,6 +77,20 @@ type ShowDDLJobQueriesWithRange struct {
 	Offset uint64
 }
 
+// ShowDDLJobArgs is for showing the decoded args of DDL jobs.
+type ShowDDLJobArgs struct {
+	baseSchemaProducer
+
+	JobIDs []int64
+}
+
+// ShowDDLJobLog is for showing the logs recorded in DDL jobs.
+type ShowDDLJobLog struct {
+	baseSchemaProducer
+
+	JobIDs []int64
+}
+
 // ShowNextRowID is for showing the next global row ID.
 type ShowNextRowID struct {
 	baseSchemaProducer
@@ -87,6 +106,8 @@ type CheckTable struct {
 	IndexInfos         []*model.IndexInfo
 	IndexLookUpReaders []*PhysicalIndexLookUpReader
 	CheckIndex         bool
+	// AsOfTS is set by `admin check table/index ... as of timestamp ...`, the check runs on the snapshot of AsOfTS.
+	AsOfTS uint64
 }
 
 // RecoverIndex is used for backfilling corrupted index data.
@@ -120,6 +141,9 @@ type ChecksumTable struct {
 	baseSchemaProducer
 
 	Tables []*ast.TableName
+	// AsOfTS is set by `admin checksum table ... as of timestamp ...`, the checksum is calculated
+	// both on the current snapshot and on the snapshot of AsOfTS.
+	AsOfTS uint64
 }
 
 // CancelDDLJobs represents a cancel DDL jobs plan.
@@ -189,6 +213,9 @@ type Execute struct {
 	PrepStmt *PlanCacheStmt
 	Stmt     ast.StmtNode
 	Plan     Plan
+	// RuntimeSelectLimit indicates the Plan is from the general plan cache, which is built without the
+	// sql_select_limit, so the executor should enforce it, see NeedExtraLimit.
+	RuntimeSelectLimit bool
 }
 
 // Check if result of GetVar expr is BinaryLiteral
@@ -1287,6 +1287,16 @@ func (er *expressionRewriter) useCache() bool {
 	return er.sctx.GetSessionVars().StmtCtx.UseCache
 }
 
+// getUserVarType returns the type of the user variable, it's a string type if the variable is never set.
+func getUserVarType(sessionVars *variable.SessionVars, name string) *types.FieldType {
+	tp, ok := sessionVars.GetUserVarType(name)
+	if !ok {
+		tp = types.NewFieldType(mysql.TypeVarString)
+		tp.SetFlen(mysql.MaxFieldVarCharLength)
+	}
+	return tp
+}
+
 func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 	stkLen := len(er.ctxStack)
 	name := strings.ToLower(v.Name)
@@ -1304,16 +1314,17 @@ func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 			sessionVars.SetUserVarType(name, tp)
 			return
 		}
-		tp, ok := sessionVars.GetUserVarType(name)
-		if !ok {
-			tp = types.NewFieldType(mysql.TypeVarString)
-			tp.SetFlen(mysql.MaxFieldVarCharLength)
-		}
+		tp := getUserVarType(sessionVars, name)
 		f, err := er.newFunction(ast.GetVar, tp, expression.DatumToConstant(types.NewStringDatum(name), mysql.TypeString, 0))
 		if err != nil {
 			er.err = err
 			return
 		}
+		if er.useCache() {
+			// The variable is read on each execution of the cached plan, but its type is fixed in the plan.
+			sessionVars.StmtCtx.NonDeterministicExprs4PC = append(sessionVars.StmtCtx.NonDeterministicExprs4PC, "@"+name)
+			sessionVars.StmtCtx.UserVars4PC = append(sessionVars.StmtCtx.UserVars4PC, name)
+		}
 		f.SetCoercibility(expression.CoercibilityImplicit)
 		er.ctxStackAppend(f, types.EmptyName)
 		return
@@ -1787,6 +1798,26 @@ func (er *expressionRewriter) wrapExpWithCast() (expr, lexp, rexp expression.Exp
 	return
 }
 
+// betweenEqualParams checks whether both bounds of the BETWEEN expression are params holding equal values when
+// building a plan for the plan cache, and records them in EqualParams4PC if so.
+func (er *expressionRewriter) betweenEqualParams(v *ast.BetweenExpr) bool {
+	if !er.useCache() {
+		return false
+	}
+	left, ok1 := v.Left.(*driver.ParamMarkerExpr)
+	right, ok2 := v.Right.(*driver.ParamMarkerExpr)
+	if !ok1 || !ok2 {
+		return false
+	}
+	sessVars := er.sctx.GetSessionVars()
+	if left.Order >= len(sessVars.PreparedParams) || right.Order >= len(sessVars.PreparedParams) ||
+		!paramsEqual(sessVars.StmtCtx, &sessVars.PreparedParams[left.Order], &sessVars.PreparedParams[right.Order]) {
+		return false
+	}
+	sessVars.StmtCtx.EqualParams4PC = append(sessVars.StmtCtx.EqualParams4PC, [2]int{left.Order, right.Order})
+	return true
+}
+
 func (er *expressionRewriter) betweenToExpression(v *ast.BetweenExpr) {
 	stkLen := len(er.ctxStack)
 	er.err = expression.CheckArgsNotMultiColumnRow(er.ctxStack[stkLen-3:]...)
@@ -1811,24 +1842,32 @@ func (er *expressionRewriter) betweenToExpression(v *ast.BetweenExpr) {
 	lexp = expression.BuildCastCollationFunction(er.sctx, lexp, coll, enumOrSetRealTypeIsStr)
 	rexp = expression.BuildCastCollationFunction(er.sctx, rexp, coll, enumOrSetRealTypeIsStr)
 
-	var l, r expression.Expression
-	l, er.err = expression.NewFunction(er.sctx, ast.GE, &v.Type, expr, lexp)
-	if er.err != nil {
-		return
-	}
-	r, er.err = expression.NewFunction(er.sctx, ast.LE, &v.Type, expr, rexp)
-	if er.err != nil {
-		return
-	}
-	function, err := er.newFunction(ast.LogicAnd, &v.Type, l, r)
-	if err != nil {
-		er.err = err
-		return
+	var function expression.Expression
+	if er.betweenEqualParams(v) {
+		// `a BETWEEN ? AND ?` is a point range if both bounds are equal. The plan is only reused when they're still
+		// equal, see EqualParams4PC.
+		function, er.err = er.newFunction(ast.EQ, &v.Type, expr, lexp)
+		if er.err != nil {
+			return
+		}
+	} else {
+		var l, r expression.Expression
+		l, er.err = expression.NewFunction(er.sctx, ast.GE, &v.Type, expr, lexp)
+		if er.err != nil {
+			return
+		}
+		r, er.err = expression.NewFunction(er.sctx, ast.LE, &v.Type, expr, rexp)
+		if er.err != nil {
+			return
+		}
+		function, er.err = er.newFunction(ast.LogicAnd, &v.Type, l, r)
+		if er.err != nil {
+			return
+		}
 	}
 	if v.Not {
-		function, err = er.newFunction(ast.UnaryNot, &v.Type, function)
-		if err != nil {
-			er.err = err
+		function, er.err = er.newFunction(ast.UnaryNot, &v.Type, function)
+		if er.err != nil {
 			return
 		}
 	}
@@ -1926,6 +1965,8 @@ func (er *expressionRewriter) funcCallToExpression(v *ast.FuncCallExpr) {
 			function, er.err = expression.NewFunctionBase(er.sctx, v.FnName.L, &v.Type, args...)
 			c := &expression.Constant{Value: types.NewDatum(nil), RetType: function.GetType().Clone(), DeferredExpr: function}
 			er.ctxStackAppend(c, types.EmptyName)
+			stmtCtx := er.sctx.GetSessionVars().StmtCtx
+			stmtCtx.NonDeterministicExprs4PC = append(stmtCtx.NonDeterministicExprs4PC, v.FnName.L+"()")
 		}
 	} else {
 		function, er.err = er.newFunction(v.FnName.L, &v.Type, args...)
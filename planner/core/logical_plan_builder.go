@@ -4157,7 +4157,12 @@ func getStatsTable(ctx sessionctx.Context, tblInfo *model.TableInfo, pid int64)
 	}
 
 	var statsTbl *statistics.Table
-	if pid == tblInfo.ID || ctx.GetSessionVars().UseDynamicPartitionPrune() {
+	if ctx.GetSessionVars().UseDynamicPartitionPrune() {
+		pid = tblInfo.ID
+	}
+	if ctx.GetSessionVars().OptimizerStatsAsOf > 0 {
+		statsTbl = getStatsTableAsOf(ctx, statsHandle, tblInfo, pid)
+	} else if pid == tblInfo.ID {
 		statsTbl = statsHandle.GetTableStats(tblInfo, handle.WithTableStatsByQuery())
 	} else {
 		statsTbl = statsHandle.GetPartitionStats(tblInfo, pid, handle.WithTableStatsByQuery())
@@ -4186,6 +4191,24 @@ func getStatsTable(ctx sessionctx.Context, tblInfo *model.TableInfo, pid int64)
 	return statsTbl
 }
 
+// getStatsTableAsOf gets the stats of the table as of tidb_optimizer_stats_as_of. The stats snapshot is loaded by the
+// first statement using it, and kept in the session until the variable is changed. The pseudo stats are used if the
+// snapshot can't be loaded.
+func getStatsTableAsOf(ctx sessionctx.Context, statsHandle *handle.Handle, tblInfo *model.TableInfo, pid int64) *statistics.Table {
+	sessVars := ctx.GetSessionVars()
+	snapshot, ok := sessVars.OptimizerStatsSnapshot.(*handle.StatsSnapshot)
+	if !ok || snapshot.TS() != sessVars.OptimizerStatsAsOf {
+		var err error
+		snapshot, err = statsHandle.LoadStatsSnapshot(sessVars.OptimizerStatsAsOf)
+		if err != nil {
+			sessVars.StmtCtx.AppendWarning(errors.Errorf("load the stats as of %d failed, use the pseudo stats: %v", sessVars.OptimizerStatsAsOf, err))
+			return statistics.PseudoTable(tblInfo)
+		}
+		sessVars.OptimizerStatsSnapshot = snapshot
+	}
+	return snapshot.GetPartitionStats(tblInfo, pid)
+}
+
 func (b *PlanBuilder) tryBuildCTE(ctx context.Context, tn *ast.TableName, asName *model.CIStr) (LogicalPlan, error) {
 	for i := len(b.outerCTEs) - 1; i >= 0; i-- {
 		cte := b.outerCTEs[i]
@@ -4876,6 +4899,7 @@ func (b *PlanBuilder) checkRecursiveView(dbName model.CIStr, tableName model.CIS
 func (b *PlanBuilder) BuildDataSourceFromView(ctx context.Context, dbName model.CIStr, tableInfo *model.TableInfo) (LogicalPlan, error) {
 	viewDepth := b.ctx.GetSessionVars().StmtCtx.ViewDepth
 	b.ctx.GetSessionVars().StmtCtx.ViewDepth++
+	b.ctx.GetSessionVars().StmtCtx.Views4PC = append(b.ctx.GetSessionVars().StmtCtx.Views4PC, tableInfo)
 	deferFunc, err := b.checkRecursiveView(dbName, tableInfo.Name)
 	if err != nil {
 		return nil, err
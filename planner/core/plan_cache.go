@@ -16,6 +16,8 @@ package core
 
 import (
 	"context"
+	"math"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/bindinfo"
@@ -29,7 +31,6 @@ import (
 	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
-	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessiontxn/staleread"
 	"github.com/pingcap/tidb/table/tables"
 	"github.com/pingcap/tidb/types"
@@ -39,6 +40,7 @@ import (
 	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/ranger"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
@@ -93,6 +95,13 @@ func planCachePreprocess(sctx sessionctx.Context, isGeneralPlanCache bool, is in
 			return ErrSchemaChanged.GenWithStack("Schema change caused error: %s", err.Error())
 		}
 		stmtAst.SchemaVersion = is.SchemaMetaVersion()
+		if stmt.ReadsView && vars.EnablePreparedPlanCache {
+			checker := checkCacheable(sctx, stmtAst.Stmt, is, stmt.isGeneralStmt)
+			stmtAst.UseCache, stmt.ReadsPartitionTable = checker.cacheable, checker.hasPartitionTable
+			if !stmtAst.UseCache {
+				vars.StmtCtx.AppendWarning(errors.Errorf("skip plan-cache: %s", checker.reason))
+			}
+		}
 	}
 
 	// step 4: handle expiration
@@ -116,15 +125,19 @@ func planCachePreprocess(sctx sessionctx.Context, isGeneralPlanCache bool, is in
 func GetPlanFromSessionPlanCache(ctx context.Context, sctx sessionctx.Context,
 	isGeneralPlanCache bool, is infoschema.InfoSchema, stmt *PlanCacheStmt,
 	params []expression.Expression) (plan Plan, names []*types.FieldName, err error) {
+	sessVars := sctx.GetSessionVars()
+	beginLookup := time.Now()
 	if err := planCachePreprocess(sctx, isGeneralPlanCache, is, stmt, params); err != nil {
 		return nil, nil, err
 	}
 
 	var cacheKey kvcache.Key
-	sessVars := sctx.GetSessionVars()
 	stmtCtx := sessVars.StmtCtx
 	stmtAst := stmt.PreparedAst
 	stmtCtx.UseCache = stmtAst.UseCache
+	if !stmtAst.UseCache {
+		TracePlanCache(ctx, sctx, "the statement isn't cacheable, optimize it without the plan cache")
+	}
 
 	var bindSQL string
 	var ignorePlanCache = false
@@ -141,31 +154,155 @@ func GetPlanFromSessionPlanCache(ctx context.Context, sctx sessionctx.Context,
 			// up-to-date schema version which can lead plan cache miss and thus, the plan will be rebuilt.
 			latestSchemaVersion = domain.GetDomain(sctx).InfoSchema().SchemaMetaVersion()
 		}
-		if cacheKey, err = NewPlanCacheKey(sctx.GetSessionVars(), stmt.StmtText,
-			stmt.StmtDB, stmtAst.SchemaVersion, latestSchemaVersion, bindSQL); err != nil {
+		if cacheKey, err = newStmtPlanCacheKey(sessVars, isGeneralPlanCache, stmt, latestSchemaVersion,
+			bindSQL); err != nil {
 			return nil, nil, err
 		}
+		if sessVars.TracePlanCache {
+			TracePlanCache(ctx, sctx, "cache key %s of the statement %q, schema version %d, latest schema version %d, binding %q",
+				planCacheKeyDigest(cacheKey), stmt.planCacheKeyText(isGeneralPlanCache), stmtAst.SchemaVersion,
+				latestSchemaVersion, bindSQL)
+		}
+		if ignorePlanCache {
+			TracePlanCache(ctx, sctx, "the binding ignores the plan cache")
+		}
+	}
+
+	if stmtAst.UseCache && !ignorePlanCache {
+		if reason, counter := staleReadSkipReason(sctx); reason != "" {
+			// Neither get nor put the plan, since the plans are built against the latest schema and statistics.
+			stmtCtx.AppendWarning(errors.Errorf("skip plan-cache: %s", reason))
+			TracePlanCache(ctx, sctx, "skip the plan cache: %s", reason)
+			counter.Inc()
+			stmtCtx.SkipPlanCache = true
+			ignorePlanCache = true
+		}
+	}
+
+	if stmtAst.UseCache && !ignorePlanCache && stmt.ReadsPartitionTable && !sessVars.UseDynamicPartitionPrune() {
+		// The cached plans rely on the executors to prune the partitions, which only happens in the dynamic mode.
+		stmtCtx.AppendWarning(errors.New("skip plan-cache: static partition prune mode"))
+		TracePlanCache(ctx, sctx, "skip the plan cache: static partition prune mode")
+		stmtCtx.SkipPlanCache = true
+		ignorePlanCache = true
 	}
 
 	paramNum, paramTypes := parseParamTypes(sctx, params)
 
 	if stmtAst.UseCache && stmtAst.CachedPlan != nil && !ignorePlanCache { // for point query plan
-		if plan, names, ok, err := getPointQueryPlan(stmtAst, sessVars, stmtCtx); ok {
+		if plan, names, ok, err := getPointQueryPlan(ctx, sctx, is, stmt); ok {
+			sessVars.DurationPlanCacheLookup = time.Since(beginLookup)
 			return plan, names, err
 		}
 	}
 
 	if stmtAst.UseCache && !ignorePlanCache { // for general plans
-		if plan, names, ok, err := getGeneralPlan(sctx, isGeneralPlanCache, cacheKey, bindSQL, is, stmt,
+		if plan, names, ok, err := getGeneralPlan(ctx, sctx, isGeneralPlanCache, cacheKey, bindSQL, is, stmt,
 			paramTypes); err != nil || ok {
+			sessVars.DurationPlanCacheLookup = time.Since(beginLookup)
 			return plan, names, err
 		}
+		if invalidateStalePlans(sctx, isGeneralPlanCache, is, cacheKey) {
+			// Some plans survive the schema version bump, try them again.
+			TracePlanCache(ctx, sctx, "the cached plans survive the schema changes, look them up again")
+			if plan, names, ok, err := getGeneralPlan(ctx, sctx, isGeneralPlanCache, cacheKey, bindSQL, is, stmt,
+				paramTypes); err != nil || ok {
+				sessVars.DurationPlanCacheLookup = time.Since(beginLookup)
+				return plan, names, err
+			}
+		}
 	}
 
+	// The optimization of the new plan is recorded in DurationOptimization.
+	sessVars.DurationPlanCacheLookup = time.Since(beginLookup)
 	return generateNewPlan(ctx, sctx, isGeneralPlanCache, is, stmt, ignorePlanCache, cacheKey,
 		latestSchemaVersion, paramNum, paramTypes, bindSQL)
 }
 
+// invalidateStalePlans evicts the plans of the statement which can't be hit anymore, since the schema version
+// or the binding in their keys is outdated. It's called on cache misses only, so it doesn't slow down the hits.
+// The plans whose dependent tables are untouched by the schema changes survive the schema version bump, they're
+// moved to cacheKey instead, and it returns true if there are such plans.
+func invalidateStalePlans(sctx sessionctx.Context, isGeneralPlanCache bool, is infoschema.InfoSchema, cacheKey kvcache.Key) bool {
+	cache, ok := sctx.GetPlanCache(isGeneralPlanCache).(*LRUPlanCache)
+	if !ok {
+		return false
+	}
+	current, ok := cacheKey.(*planCacheKey)
+	if !ok {
+		return false
+	}
+	sameStmt := func(key *planCacheKey) bool {
+		return key.connID == current.connID && key.database == current.database && key.stmtText == current.stmtText
+	}
+	staleSchema := func(key *planCacheKey) bool {
+		return key.schemaVersion < current.schemaVersion ||
+			(key.lastUpdatedSchemaVersion != 0 && key.lastUpdatedSchemaVersion < current.lastUpdatedSchemaVersion)
+	}
+	moved := cache.moveIf(current, func(key *planCacheKey, value *PlanCacheValue) bool {
+		return sameStmt(key) && staleSchema(key) && key.equalsIgnoringSchemaVersion(current) &&
+			planUntouchedSince(sctx, is, key.schemaVersion, value)
+	})
+	cache.deleteIf(func(key *planCacheKey, value *PlanCacheValue) (PlanCacheEvictReason, bool) {
+		if !sameStmt(key) {
+			return "", false
+		}
+		if staleSchema(key) {
+			for tblInfo := range value.TblInfo2UnionScan {
+				tbl, ok := is.TableByID(tblInfo.ID)
+				if !ok || tbl.Meta().UpdateTS != tblInfo.UpdateTS || !value.partitionsUnchanged(tbl.Meta()) {
+					return EvictReasonDDL, true
+				}
+			}
+			return EvictReasonSchemaVersion, true
+		}
+		if key.schemaVersion == current.schemaVersion && key.bindSQL != current.bindSQL {
+			return EvictReasonBinding, true
+		}
+		return "", false
+	})
+	return moved > 0
+}
+
+// planUntouchedSince checks whether the tables the plan depends on are untouched by the schema changes after
+// schemaVer, which are told by the schema diffs.
+func planUntouchedSince(sctx sessionctx.Context, is infoschema.InfoSchema, schemaVer int64, value *PlanCacheValue) bool {
+	ids := make([]int64, 0, len(value.DependentTables))
+	for id, updateTS := range value.DependentTables {
+		tbl, ok := is.TableByID(id)
+		// Some changes of the tables are skipped by the schema diffs, e.g. the TiFlash replica, which changes the
+		// access paths of the plan, so the update TS is checked as well.
+		if !ok || tbl.Meta().UpdateTS != updateTS {
+			return false
+		}
+		// The partition DDL may only be told by the diffs of the partitions, e.g. the dropped ones, which aren't in
+		// the current definitions, so the partitions are compared with the ones when the plan is built.
+		if !value.partitionsUnchanged(tbl.Meta()) {
+			return false
+		}
+		ids = append(ids, id)
+		ids = append(ids, value.DependentPartitions[id]...)
+	}
+	return !domain.GetDomain(sctx).SchemaValidator.IsRelatedTablesChanged(schemaVer, ids)
+}
+
+// staleReadSkipReason returns the reason to skip the plan cache if the statement reads a stale snapshot, or is
+// planned with the historical stats, the returned counter records the skipping.
+func staleReadSkipReason(sctx sessionctx.Context) (string, prometheus.Counter) {
+	vars := sctx.GetSessionVars()
+	switch {
+	case vars.SnapshotTS != 0:
+		return "tidb_snapshot is set", planCacheSkipSnapshotCounter
+	case vars.TxnCtx != nil && vars.TxnCtx.IsStaleness:
+		return "in a stale read transaction", planCacheSkipStaleTxnCounter
+	case staleread.IsStmtStaleness(sctx):
+		return "stale read statement", planCacheSkipStaleReadCounter
+	case vars.OptimizerStatsAsOf != 0:
+		return "tidb_optimizer_stats_as_of is set", planCacheSkipStatsAsOfCounter
+	}
+	return "", nil
+}
+
 // parseParamTypes get parameters' types in PREPARE statement
 func parseParamTypes(sctx sessionctx.Context, params []expression.Expression) (paramNum int, paramTypes []*types.FieldType) {
 	paramNum = len(params)
@@ -186,31 +323,39 @@ func parseParamTypes(sctx sessionctx.Context, params []expression.Expression) (p
 	return
 }
 
-func getPointQueryPlan(stmt *ast.Prepared, sessVars *variable.SessionVars, stmtCtx *stmtctx.StatementContext) (Plan,
+func getPointQueryPlan(ctx context.Context, sctx sessionctx.Context, is infoschema.InfoSchema, stmt *PlanCacheStmt) (Plan,
 	[]*types.FieldName, bool, error) {
+	sessVars := sctx.GetSessionVars()
+	stmtCtx := sessVars.StmtCtx
 	// short path for point-get plans
 	// Rewriting the expression in the select.where condition  will convert its
 	// type from "paramMarker" to "Constant".When Point Select queries are executed,
 	// the expression in the where condition will not be evaluated,
 	// so you don't need to consider whether prepared.useCache is enabled.
-	plan := stmt.CachedPlan.(Plan)
-	names := stmt.CachedNames.(types.NameSlice)
+	if stmt.PointPlanTxnAssumption != stmt.txnAssumption(sessVars) {
+		// The point plan is kept for the executions in its transaction context, others go to the general plans.
+		TracePlanCache(ctx, sctx, "the cached point plan is built in another transaction context")
+		return nil, nil, false, nil
+	}
+	if err := checkCachedPlanPriv(ctx, sctx, stmt, is, &stmt.PointPlanPrivFingerprint); err != nil {
+		TracePlanCache(ctx, sctx, "check the privileges failed: %v", err)
+		return nil, nil, true, err
+	}
+	plan := stmt.PreparedAst.CachedPlan.(Plan)
+	names := stmt.PreparedAst.CachedNames.(types.NameSlice)
 	err := RebuildPlan4CachedPlan(plan)
 	if err != nil {
 		logutil.BgLogger().Debug("rebuild range failed", zap.Error(err))
+		TracePlanCache(ctx, sctx, "rebuild the ranges of the cached point plan failed: %v", err)
 		return nil, nil, false, nil
 	}
-	if metrics.ResettablePlanCacheCounterFortTest {
-		metrics.PlanCacheCounter.WithLabelValues("prepare").Inc()
-	} else {
-		planCacheCounter.Inc()
-	}
-	sessVars.FoundInPlanCache = true
+	TracePlanCache(ctx, sctx, "hit the cached point plan")
+	setPlanCacheStatus(sctx, stmtctx.PlanCacheHit)
 	stmtCtx.PointExec = true
 	return plan, names, true, nil
 }
 
-func getGeneralPlan(sctx sessionctx.Context, isGeneralPlanCache bool, cacheKey kvcache.Key, bindSQL string,
+func getGeneralPlan(ctx context.Context, sctx sessionctx.Context, isGeneralPlanCache bool, cacheKey kvcache.Key, bindSQL string,
 	is infoschema.InfoSchema, stmt *PlanCacheStmt, paramTypes []*types.FieldType) (Plan,
 	[]*types.FieldName, bool, error) {
 	sessVars := sctx.GetSessionVars()
@@ -218,36 +363,61 @@ func getGeneralPlan(sctx sessionctx.Context, isGeneralPlanCache bool, cacheKey k
 
 	candidate, exist := sctx.GetPlanCache(isGeneralPlanCache).Get(cacheKey, paramTypes)
 	if !exist {
+		TracePlanCache(ctx, sctx, "miss the cached plans of the param types %v", paramTypes)
 		return nil, nil, false, nil
 	}
 	cachedVal := candidate.(*PlanCacheValue)
-	if err := CheckPreparedPriv(sctx, stmt, is); err != nil {
+	TracePlanCache(ctx, sctx, "found the cached plan %s, check it", cachedVal.PlanDigest)
+	if err := checkCachedPlanPriv(ctx, sctx, stmt, is, &cachedVal.PrivFingerprint); err != nil {
+		TracePlanCache(ctx, sctx, "check the privileges failed: %v", err)
 		return nil, nil, false, err
 	}
+	if cachedVal.SchemaRollbackEpoch != domain.GetDomain(sctx).SchemaRollbackEpoch4PC() {
+		// The schema is rolled back by a flashback after the plan is built. The plans are rebuilt lazily when
+		// they're hit, instead of all at once, and the rebuilt one overwrites the cached one.
+		TracePlanCache(ctx, sctx, "the schema is rolled back after the cached plan is built")
+		return nil, nil, false, nil
+	}
+	if cachedVal.TxnAssumption != stmt.txnAssumption(sessVars) {
+		// The plan is rebuilt in the current transaction context and overwrites the cached one.
+		planCacheTxnMismatchCounter.Inc()
+		TracePlanCache(ctx, sctx, "the cached plan is built in another transaction context")
+		return nil, nil, false, nil
+	}
+	if !cachedVal.outputSchemaUnchanged(is) {
+		// The plan is rebuilt and overwrites the cached one.
+		stmtCtx.AppendWarning(errors.Errorf("skip plan-cache: the output schema of the cached plan mismatches the statement"))
+		TracePlanCache(ctx, sctx, "the output schema of the cached plan mismatches the statement")
+		return nil, nil, false, nil
+	}
+	if !cachedVal.userVarTypesUnchanged(sessVars) || !cachedVal.equalParamsHold(sessVars) {
+		// The plan is rebuilt and overwrites the cached one.
+		TracePlanCache(ctx, sctx, "the user variables or the params the cached plan relies on are changed")
+		return nil, nil, false, nil
+	}
 	for tblInfo, unionScan := range cachedVal.TblInfo2UnionScan {
 		if !unionScan && tableHasDirtyContent(sctx, tblInfo) {
 			// TODO we can inject UnionScan into cached plan to avoid invalidating it, though
 			// rebuilding the filters in UnionScan is pretty trivial.
 			sctx.GetPlanCache(isGeneralPlanCache).Delete(cacheKey)
+			TracePlanCache(ctx, sctx, "the table %s is dirty in the transaction but the cached plan has no union scan",
+				tblInfo.Name.O)
 			return nil, nil, false, nil
 		}
 	}
 	err := RebuildPlan4CachedPlan(cachedVal.Plan)
 	if err != nil {
 		logutil.BgLogger().Debug("rebuild range failed", zap.Error(err))
+		TracePlanCache(ctx, sctx, "rebuild the ranges of the cached plan failed: %v", err)
 		return nil, nil, false, nil
 	}
-	sessVars.FoundInPlanCache = true
+	TracePlanCache(ctx, sctx, "hit the cached plan %s", cachedVal.PlanDigest)
+	setPlanCacheStatus(sctx, stmtctx.PlanCacheHit)
 	if len(bindSQL) > 0 {
 		// When the `len(bindSQL) > 0`, it means we use the binding.
 		// So we need to record this.
 		sessVars.FoundInBinding = true
 	}
-	if metrics.ResettablePlanCacheCounterFortTest {
-		metrics.PlanCacheCounter.WithLabelValues("prepare").Inc()
-	} else {
-		planCacheCounter.Inc()
-	}
 	stmtCtx.SetPlanDigest(stmt.NormalizedPlan, stmt.PlanDigest)
 	return cachedVal.Plan, cachedVal.OutPutNames, true, nil
 }
@@ -261,11 +431,31 @@ func generateNewPlan(ctx context.Context, sctx sessionctx.Context, isGeneralPlan
 	sessVars := sctx.GetSessionVars()
 	stmtCtx := sessVars.StmtCtx
 
-	planCacheMissCounter.Inc()
+	if stmtAst.UseCache && !ignorePlanCache {
+		setPlanCacheStatus(sctx, stmtctx.PlanCacheMiss)
+	} else {
+		setPlanCacheStatus(sctx, stmtctx.PlanCacheSkip)
+	}
+	TracePlanCache(ctx, sctx, "optimize the statement")
+	// Get the epoch before optimizing, in case the schema is rolled back meanwhile.
+	schemaRollbackEpoch := domain.GetDomain(sctx).SchemaRollbackEpoch4PC()
+	if isGeneralPlanCache {
+		// The plans in the general plan cache don't contain the sql_select_limit, it's enforced by the executor
+		// instead, see Execute.RuntimeSelectLimit.
+		originalSelectLimit := sessVars.SelectLimit
+		sessVars.SelectLimit = math.MaxUint64
+		defer func() {
+			sessVars.SelectLimit = originalSelectLimit
+		}()
+	}
 	p, names, err := OptimizeAstNode(ctx, sctx, stmtAst.Stmt, is)
 	if err != nil {
 		return nil, nil, err
 	}
+	// The partition of the point get is located by the params when building the plan, it can't be reused.
+	if stmt.ReadsPartitionTable && containPartitionPointGet(p) {
+		stmtCtx.SkipPlanCache = true
+	}
 	err = tryCachePointPlan(ctx, sctx, stmt, is, p)
 	if err != nil {
 		return nil, nil, err
@@ -279,22 +469,48 @@ func generateNewPlan(ctx context.Context, sctx sessionctx.Context, isGeneralPlan
 		// rebuild key to exclude kv.TiFlash when stmt is not read only
 		if _, isolationReadContainTiFlash := sessVars.IsolationReadEngines[kv.TiFlash]; isolationReadContainTiFlash && !IsReadOnly(stmtAst.Stmt, sessVars) {
 			delete(sessVars.IsolationReadEngines, kv.TiFlash)
-			if cacheKey, err = NewPlanCacheKey(sessVars, stmt.StmtText, stmt.StmtDB,
-				stmtAst.SchemaVersion, latestSchemaVersion, bindSQL); err != nil {
+			if cacheKey, err = newStmtPlanCacheKey(sessVars, isGeneralPlanCache, stmt, latestSchemaVersion,
+				bindSQL); err != nil {
 				return nil, nil, err
 			}
 			sessVars.IsolationReadEngines[kv.TiFlash] = struct{}{}
 		}
 		cached := NewPlanCacheValue(p, names, stmtCtx.TblInfo2UnionScan, paramTypes)
+		cached.setNonDeterministicExprs(sessVars)
+		cached.setEqualParams(sessVars)
+		cached.setDependentTables(stmtAst.Stmt, stmtCtx.Views4PC)
+		cached.SchemaRollbackEpoch = schemaRollbackEpoch
+		cached.TxnAssumption = stmt.txnAssumption(sessVars)
+		cached.PrivFingerprint = newPlanCachePrivFingerprint(sctx)
 		stmt.NormalizedPlan, stmt.PlanDigest = NormalizePlan(p)
+		cached.PlanDigest = stmt.PlanDigest.String()
 		stmtCtx.SetPlan(p)
 		stmtCtx.SetPlanDigest(stmt.NormalizedPlan, stmt.PlanDigest)
 		sctx.GetPlanCache(isGeneralPlanCache).Put(cacheKey, cached, paramTypes)
+		TracePlanCache(ctx, sctx, "cache the plan %s", cached.PlanDigest)
+	} else if stmtAst.UseCache && !ignorePlanCache {
+		TracePlanCache(ctx, sctx, "don't cache the plan, it can't be reused by the other params")
 	}
-	sessVars.FoundInPlanCache = false
 	return p, names, err
 }
 
+// setPlanCacheStatus records how the plan of the statement is got from the plan cache and counts it. It's the only
+// place where the planner updates the status, and it's called once per execution.
+func setPlanCacheStatus(sctx sessionctx.Context, status stmtctx.PlanCacheStatus) {
+	sctx.GetSessionVars().StmtCtx.SetPlanCacheStatus(status)
+	switch status {
+	case stmtctx.PlanCacheHit:
+		if metrics.ResettablePlanCacheCounterFortTest {
+			metrics.PlanCacheCounter.WithLabelValues("prepare").Inc()
+		} else {
+			planCacheCounter.Inc()
+		}
+	case stmtctx.PlanCacheMiss, stmtctx.PlanCacheSkip:
+		// The skips are counted as the misses, since the plans are built by the optimizer as well.
+		planCacheMissCounter.Inc()
+	}
+}
+
 // RebuildPlan4CachedPlan will rebuild this plan under current user parameters.
 func RebuildPlan4CachedPlan(p Plan) error {
 	sc := p.SCtx().GetSessionVars().StmtCtx
@@ -587,6 +803,22 @@ func buildRangeForIndexScan(sctx sessionctx.Context, is *PhysicalIndexScan) (err
 	return
 }
 
+// checkCachedPlanPriv checks the privileges of the statement again before its cached plan is reused if the privilege
+// fingerprint fp changes since they're checked last time, and updates fp after they're checked. The table locks are
+// always checked.
+func checkCachedPlanPriv(ctx context.Context, sctx sessionctx.Context, stmt *PlanCacheStmt, is infoschema.InfoSchema,
+	fp *planCachePrivFingerprint) error {
+	if fp.unchanged(sctx) {
+		return CheckTableLock(sctx, is, stmt.VisitInfos)
+	}
+	TracePlanCache(ctx, sctx, "the privileges may be changed since the cached plan is checked, check them again")
+	if err := CheckPreparedPriv(sctx, stmt, is); err != nil {
+		return err
+	}
+	*fp = newPlanCachePrivFingerprint(sctx)
+	return nil
+}
+
 // CheckPreparedPriv checks the privilege of the prepared statement
 func CheckPreparedPriv(sctx sessionctx.Context, stmt *PlanCacheStmt, is infoschema.InfoSchema) error {
 	if pm := privilege.GetPrivilegeManager(sctx); pm != nil {
@@ -625,6 +857,8 @@ func tryCachePointPlan(_ context.Context, sctx sessionctx.Context,
 		// just cache point plan now
 		stmtAst.CachedPlan = p
 		stmtAst.CachedNames = names
+		stmt.PointPlanTxnAssumption = stmt.txnAssumption(sctx.GetSessionVars())
+		stmt.PointPlanPrivFingerprint = newPlanCachePrivFingerprint(sctx)
 		stmt.NormalizedPlan, stmt.PlanDigest = NormalizePlan(p)
 		sctx.GetSessionVars().StmtCtx.SetPlan(p)
 		sctx.GetSessionVars().StmtCtx.SetPlanDigest(stmt.NormalizedPlan, stmt.PlanDigest)
@@ -648,6 +882,29 @@ func containTableDual(p Plan) bool {
 	return childContainTableDual
 }
 
+// containPartitionPointGet checks whether the plan reads a partitioned table by the point get or the batch point get.
+func containPartitionPointGet(p Plan) bool {
+	switch x := p.(type) {
+	case *PointGetPlan:
+		return x.PartitionInfo != nil
+	case *BatchPointGetPlan:
+		return x.TblInfo.GetPartitionInfo() != nil
+	case *Update:
+		return x.SelectPlan != nil && containPartitionPointGet(x.SelectPlan)
+	case *Delete:
+		return x.SelectPlan != nil && containPartitionPointGet(x.SelectPlan)
+	case *Insert:
+		return x.SelectPlan != nil && containPartitionPointGet(x.SelectPlan)
+	case PhysicalPlan:
+		for _, child := range x.Children() {
+			if containPartitionPointGet(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetBindSQL4PlanCache used to get the bindSQL for plan cache to build the plan cache key.
 func GetBindSQL4PlanCache(sctx sessionctx.Context, stmt *PlanCacheStmt) (string, bool) {
 	useBinding := sctx.GetSessionVars().UsePlanBaselines
@@ -687,11 +944,14 @@ func GetBindSQL4PlanCache(sctx sessionctx.Context, stmt *PlanCacheStmt) (string,
 // IsPointGetWithPKOrUniqueKeyByAutoCommit
 func IsPointPlanShortPathOK(sctx sessionctx.Context, is infoschema.InfoSchema, stmt *PlanCacheStmt) (bool, error) {
 	stmtAst := stmt.PreparedAst
-	if stmtAst.CachedPlan == nil || staleread.IsStmtStaleness(sctx) {
+	if stmtAst.CachedPlan == nil {
+		return false, nil
+	}
+	if reason, _ := staleReadSkipReason(sctx); reason != "" {
 		return false, nil
 	}
 	// check auto commit
-	if !IsAutoCommitTxn(sctx) {
+	if !IsAutoCommitTxn(sctx) || stmt.PointPlanTxnAssumption != stmt.txnAssumption(sctx.GetSessionVars()) {
 		return false, nil
 	}
 	if stmtAst.SchemaVersion != is.SchemaMetaVersion() {
@@ -699,6 +959,10 @@ func IsPointPlanShortPathOK(sctx sessionctx.Context, is infoschema.InfoSchema, s
 		stmt.ColumnInfos = nil
 		return false, nil
 	}
+	if !stmt.PointPlanPrivFingerprint.unchanged(sctx) {
+		// The privileges are checked again in the normal path.
+		return false, nil
+	}
 	// maybe we'd better check cached plan type here, current
 	// only point select/update will be cached, see "getPhysicalPlan" func
 	var ok bool
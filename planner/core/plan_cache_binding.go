@@ -0,0 +1,194 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/bindinfo"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/sessionctx"
+	utilhint "github.com/pingcap/tidb/util/hint"
+	utilparser "github.com/pingcap/tidb/util/parser"
+)
+
+// cachedPlanForBinding is a cached plan found by its plan digest, which a binding is created from.
+type cachedPlanForBinding struct {
+	// stmtText is the parameterized statement of the plan, e.g. `select * from t where a = ?`.
+	stmtText string
+	db       string
+	hints    []*ast.TableOptimizerHint
+}
+
+// findByPlanDigest finds the cached plan whose plan digest is planDigest. The hints are generated under the lock, so
+// that the plan isn't evicted meanwhile.
+func (l *LRUPlanCache) findByPlanDigest(planDigest string) (*cachedPlanForBinding, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for element := l.lruList.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*planCacheEntry)
+		key, ok := entry.PlanKey.(*planCacheKey)
+		if !ok {
+			continue
+		}
+		value, ok := entry.PlanValue.(*PlanCacheValue)
+		if !ok || value.PlanDigest == "" || value.PlanDigest != planDigest {
+			continue
+		}
+		return &cachedPlanForBinding{stmtText: key.stmtText, db: key.database, hints: genBindingHints(value.Plan)}, true
+	}
+	return nil, false
+}
+
+// findCachedPlanByDigest finds the cached plan whose plan digest is planDigest in the plan caches of the session,
+// then in the ones of the other sessions of the instance.
+func findCachedPlanByDigest(sctx sessionctx.Context, planDigest string) (*cachedPlanForBinding, bool) {
+	own := make(map[*LRUPlanCache]struct{}, 2)
+	for _, isGeneralPlanCache := range []bool{true, false} {
+		if cache, ok := sctx.GetPlanCache(isGeneralPlanCache).(*LRUPlanCache); ok {
+			if cached, ok := cache.findByPlanDigest(planDigest); ok {
+				return cached, true
+			}
+			own[cache] = struct{}{}
+		}
+	}
+
+	planCacheRegistry.Lock()
+	caches := make([]*LRUPlanCache, 0, len(planCacheRegistry.caches))
+	for l := range planCacheRegistry.caches {
+		if _, ok := own[l]; !ok {
+			caches = append(caches, l)
+		}
+	}
+	planCacheRegistry.Unlock()
+	for _, l := range caches {
+		if cached, ok := l.findByPlanDigest(planDigest); ok {
+			return cached, true
+		}
+	}
+	return nil, false
+}
+
+// genBindingHints generates the hints reproducing the plan. Unlike GenHintsFromPhysicalPlan, the access paths of
+// the point gets are hinted too, since they're exactly what the binding freezes.
+func genBindingHints(p Plan) []*ast.TableOptimizerHint {
+	hints := GenHintsFromPhysicalPlan(p)
+	nodeType := utilhint.TypeSelect
+	var selectPlan PhysicalPlan
+	switch x := p.(type) {
+	case *Update:
+		selectPlan, nodeType = x.SelectPlan, utilhint.TypeUpdate
+	case *Delete:
+		selectPlan, nodeType = x.SelectPlan, utilhint.TypeDelete
+	case *Insert:
+		selectPlan = x.SelectPlan
+	case PhysicalPlan:
+		selectPlan = x
+	}
+	return genPointGetHints(selectPlan, nodeType, hints)
+}
+
+func genPointGetHints(p PhysicalPlan, nodeType utilhint.NodeType, res []*ast.TableOptimizerHint) []*ast.TableOptimizerHint {
+	if p == nil {
+		return res
+	}
+	for _, child := range p.Children() {
+		res = genPointGetHints(child, nodeType, res)
+	}
+	var dbName string
+	var tblInfo *model.TableInfo
+	var idxInfo *model.IndexInfo
+	switch x := p.(type) {
+	case *PointGetPlan:
+		dbName, tblInfo, idxInfo = x.dbName, x.TblInfo, x.IndexInfo
+	case *BatchPointGetPlan:
+		dbName, tblInfo, idxInfo = x.dbName, x.TblInfo, x.IndexInfo
+	default:
+		return res
+	}
+	blockOffset := p.SelectBlockOffset()
+	if blockOffset == 0 && nodeType == utilhint.TypeSelect {
+		// The point get built by the fast plan is in the only select block.
+		blockOffset = 1
+	}
+	qbName, err := utilhint.GenerateQBName(nodeType, blockOffset)
+	if err != nil {
+		return res
+	}
+	tblName := tblInfo.Name
+	if names := p.OutputNames(); len(names) > 0 && names[0].TblName.L != "" {
+		// The alias of the table is named by the hint if there is one.
+		tblName = names[0].TblName
+	}
+	// The handle is read by the primary key.
+	idxName := model.NewCIStr(mysql.PrimaryKeyName)
+	if idxInfo != nil {
+		idxName = idxInfo.Name
+	}
+	return append(res, &ast.TableOptimizerHint{
+		QBName:   qbName,
+		HintName: model.NewCIStr(HintUseIndex),
+		Tables:   []ast.HintTable{{DBName: model.NewCIStr(dbName), TableName: tblName}},
+		Indexes:  []model.CIStr{idxName},
+	})
+}
+
+// buildCreateBindPlanFromPlanCache builds the plan of `CREATE BINDING FROM PLAN CACHE DIGEST`, which creates the
+// binding from the parameterized statement and the plan of a cached plan.
+func (b *PlanBuilder) buildCreateBindPlanFromPlanCache(v *ast.CreateBindingStmt) (Plan, error) {
+	cached, ok := findCachedPlanByDigest(b.ctx, v.PlanCacheDigest)
+	if !ok {
+		return nil, errors.Errorf("can't find the plan digest '%s' in the plan cache", v.PlanCacheDigest)
+	}
+	if len(cached.hints) == 0 {
+		return nil, errors.Errorf("the plan of the plan digest '%s' can't be reproduced by hints", v.PlanCacheDigest)
+	}
+	charSet, collation := b.ctx.GetSessionVars().GetCharsetInfo()
+	stmt, err := parser.New().ParseOneStmt(cached.stmtText, charSet, collation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	switch bindableStmtType(stmt) {
+	case TypeInvalid:
+		return nil, errors.Errorf("create binding doesn't support this type of query")
+	case TypeInsert:
+		if stmt.(*ast.InsertStmt).Select == nil {
+			return nil, errors.Errorf("create binding only supports INSERT / REPLACE INTO SELECT")
+		}
+	}
+	// The hints of the statement are replaced by the ones generated from the plan.
+	utilhint.BindHint(stmt, &utilhint.HintsSet{})
+	normdOrigSQL := parser.Normalize(utilparser.RestoreWithDefaultDB(stmt, cached.db, cached.stmtText))
+	bindSQL := bindinfo.GenerateBindSQL(context.TODO(), stmt, utilhint.RestoreOptimizerHints(cached.hints), true, cached.db)
+	if bindSQL == "" {
+		return nil, errors.Errorf("the plan of the plan digest '%s' can't be reproduced by hints", v.PlanCacheDigest)
+	}
+	p := &SQLBindPlan{
+		SQLBindOp:    OpSQLBindCreate,
+		NormdOrigSQL: normdOrigSQL,
+		BindSQL:      bindSQL,
+		IsGlobal:     v.GlobalScope,
+		BindStmt:     stmt,
+		Db:           cached.db,
+		Charset:      charSet,
+		Collation:    collation,
+	}
+	b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "", nil)
+	return p, nil
+}
@@ -16,19 +16,56 @@ package core
 import (
 	"container/list"
 	"sync"
+	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/metrics"
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/memory"
+	"go.uber.org/zap"
 )
 
+// PlanCacheEvictReason is the reason why a cached plan is evicted or invalidated.
+type PlanCacheEvictReason string
+
+const (
+	// EvictReasonLRU means the plan is evicted since the cache is full or the memory quota is exceeded.
+	EvictReasonLRU PlanCacheEvictReason = "lru"
+	// EvictReasonDDL means a table the plan depends on is changed by DDL.
+	EvictReasonDDL PlanCacheEvictReason = "ddl"
+	// EvictReasonSchemaVersion means the schema version is bumped, and it can't be told whether the tables the plan
+	// depends on are touched, e.g. the history of the schema changes is discarded.
+	EvictReasonSchemaVersion PlanCacheEvictReason = "schema_version"
+	// EvictReasonBinding means the binding of the statement is changed.
+	EvictReasonBinding PlanCacheEvictReason = "binding_change"
+	// EvictReasonFlush means the plan is flushed by `ADMIN FLUSH PLAN_CACHE`.
+	EvictReasonFlush PlanCacheEvictReason = "flush"
+	// EvictReasonDirtyTable means the plan can't read the tables modified by the current transaction.
+	EvictReasonDirtyTable PlanCacheEvictReason = "dirty_table"
+	// EvictReasonMemoryPressure means the plan is evicted since the server memory quota is exceeded.
+	EvictReasonMemoryPressure PlanCacheEvictReason = "memory_pressure"
+)
+
+// planCacheEvictionHistorySize is the number of the latest evictions kept by a plan cache.
+const planCacheEvictionHistorySize = 100
+
+// PlanCacheEviction records an eviction of the plan cache.
+type PlanCacheEviction struct {
+	Time     time.Time
+	Reason   PlanCacheEvictReason
+	DB       string
+	StmtText string
+}
+
 // planCacheEntry wraps Key and Value. It's the value of list.Element.
 type planCacheEntry struct {
 	PlanKey   kvcache.Key
 	PlanValue kvcache.Value
+	// memUsage is the estimated memory usage of the entry consumed from the memTracker of the cache.
+	memUsage int64
 }
 
 // LRUPlanCache is a dedicated least recently used cache, Only used for plan cache.
@@ -49,6 +86,13 @@ type LRUPlanCache struct {
 	// 0 indicates no quota
 	quota uint64
 	guard float64
+
+	// history is a ring of the latest evictions, historyNext is the position of the next eviction.
+	history     []PlanCacheEviction
+	historyNext int
+
+	// memTracker tracks the estimated memory usage of the cached plans, it's attached to GlobalPlanCacheMemTracker.
+	memTracker *memory.Tracker
 }
 
 // NewLRUPlanCache creates a PCLRUCache object, whose capacity is "capacity".
@@ -59,7 +103,7 @@ func NewLRUPlanCache(capacity uint, guard float64, quota uint64,
 		capacity = 100
 		logutil.BgLogger().Info("capacity of LRU cache is less than 1, will use default value(100) init cache")
 	}
-	return &LRUPlanCache{
+	l := &LRUPlanCache{
 		capacity:       capacity,
 		size:           0,
 		buckets:        make(map[string]map[*list.Element]struct{}, 1), //Generally one query has one plan
@@ -67,7 +111,11 @@ func NewLRUPlanCache(capacity uint, guard float64, quota uint64,
 		pickFromBucket: pickFromBucket,
 		quota:          quota,
 		guard:          guard,
+		memTracker:     memory.NewTracker(memory.LabelForPlanCache, -1),
 	}
+	l.memTracker.AttachTo(GlobalPlanCacheMemTracker)
+	registerPlanCache(l)
+	return l
 }
 
 // Get tries to find the corresponding value according to the given key.
@@ -87,16 +135,27 @@ func (l *LRUPlanCache) Get(key kvcache.Key, paramTypes []*types.FieldType) (valu
 
 // Put puts the (key, value) pair into the LRU Cache.
 func (l *LRUPlanCache) Put(key kvcache.Key, value kvcache.Value, paramTypes []*types.FieldType) {
+	memUsage := planCacheEntryMemUsage(key, value)
 	l.lock.Lock()
-	defer l.lock.Unlock()
+	consumed := l.put(key, value, paramTypes, memUsage)
+	l.lock.Unlock()
+	// The memory is consumed without holding the lock, since the plans of the cache may be evicted by
+	// PlanCacheEvictOnExceed if the global memory quota is exceeded.
+	l.memTracker.Consume(consumed)
+}
 
+// put puts the (key, value) pair into the cache, and returns the memory to be consumed, the caller must hold the
+// lock.
+func (l *LRUPlanCache) put(key kvcache.Key, value kvcache.Value, paramTypes []*types.FieldType, memUsage int64) int64 {
 	hash := string(key.Hash())
 	bucket, bucketExist := l.buckets[hash]
 	if bucketExist {
 		if element, exist := l.pickFromBucket(bucket, paramTypes); exist {
-			element.Value.(*planCacheEntry).PlanValue = value
+			entry := element.Value.(*planCacheEntry)
+			consumed := memUsage - entry.memUsage
+			entry.PlanValue, entry.memUsage = value, memUsage
 			l.lruList.MoveToFront(element)
-			return
+			return consumed
 		}
 	} else {
 		l.buckets[hash] = make(map[*list.Element]struct{}, 1)
@@ -105,14 +164,16 @@ func (l *LRUPlanCache) Put(key kvcache.Key, value kvcache.Value, paramTypes []*t
 	newCacheEntry := &planCacheEntry{
 		PlanKey:   key,
 		PlanValue: value,
+		memUsage:  memUsage,
 	}
 	element := l.lruList.PushFront(newCacheEntry)
 	l.buckets[hash][element] = struct{}{}
 	l.size++
 	if l.size > l.capacity {
-		l.removeOldest()
+		l.removeOldest(EvictReasonLRU)
 	}
 	l.memoryControl()
+	return memUsage
 }
 
 // Delete deletes the multi-values from the LRU Cache.
@@ -126,8 +187,12 @@ func (l *LRUPlanCache) Delete(key kvcache.Key) {
 		for element := range bucket {
 			l.lruList.Remove(element)
 			l.size--
+			l.memTracker.Consume(-element.Value.(*planCacheEntry).memUsage)
+			l.recordEviction(element.Value.(*planCacheEntry).PlanKey, EvictReasonDirtyTable)
 		}
 		delete(l.buckets, string(hash))
+		logutil.BgLogger().Debug("[plan-cache] evict plans", zap.String("reason", string(EvictReasonDirtyTable)),
+			zap.Int("count", len(bucket)))
 	}
 }
 
@@ -136,11 +201,103 @@ func (l *LRUPlanCache) DeleteAll() {
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
+	evicted := 0
 	for lru := l.lruList.Back(); lru != nil; lru = l.lruList.Back() {
 		l.lruList.Remove(lru)
 		l.size--
+		l.memTracker.Consume(-lru.Value.(*planCacheEntry).memUsage)
+		l.recordEviction(lru.Value.(*planCacheEntry).PlanKey, EvictReasonFlush)
+		evicted++
 	}
 	l.buckets = make(map[string]map[*list.Element]struct{}, 1)
+	if evicted > 0 {
+		logutil.BgLogger().Debug("[plan-cache] evict plans", zap.String("reason", string(EvictReasonFlush)),
+			zap.Int("count", evicted))
+	}
+}
+
+// deleteIf deletes the plans chosen by the filter, which also returns the reason of the eviction.
+func (l *LRUPlanCache) deleteIf(filter func(key *planCacheKey, value *PlanCacheValue) (PlanCacheEvictReason, bool)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for element := l.lruList.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*planCacheEntry)
+		key, ok1 := entry.PlanKey.(*planCacheKey)
+		value, ok2 := entry.PlanValue.(*PlanCacheValue)
+		if ok1 && ok2 {
+			if reason, ok := filter(key, value); ok {
+				l.lruList.Remove(element)
+				l.removeFromBucket(element)
+				l.size--
+				l.memTracker.Consume(-entry.memUsage)
+				l.recordEviction(key, reason)
+				logutil.BgLogger().Debug("[plan-cache] evict plans", zap.String("reason", string(reason)),
+					zap.Int("count", 1))
+			}
+		}
+		element = next
+	}
+}
+
+// moveIf moves the plans chosen by the filter to the key, and returns the number of the moved plans. A plan isn't
+// moved if the key already has a plan of the same param types.
+func (l *LRUPlanCache) moveIf(key kvcache.Key, filter func(key *planCacheKey, value *PlanCacheValue) bool) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	hash := string(key.Hash())
+	moved := 0
+	for element := l.lruList.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*planCacheEntry)
+		k, ok1 := entry.PlanKey.(*planCacheKey)
+		v, ok2 := entry.PlanValue.(*PlanCacheValue)
+		if !ok1 || !ok2 || string(k.Hash()) == hash || !filter(k, v) {
+			continue
+		}
+		bucket, bucketExist := l.buckets[hash]
+		if !bucketExist {
+			bucket = make(map[*list.Element]struct{}, 1)
+			l.buckets[hash] = bucket
+		} else if _, exist := l.pickFromBucket(bucket, v.ParamTypes); exist {
+			continue
+		}
+		l.removeFromBucket(element)
+		memUsage := planCacheEntryMemUsage(key, v)
+		l.memTracker.Consume(memUsage - entry.memUsage)
+		entry.PlanKey, entry.memUsage = key, memUsage
+		bucket[element] = struct{}{}
+		moved++
+	}
+	return moved
+}
+
+// RecentEvictions returns the latest evictions of the cache, from the oldest to the newest.
+func (l *LRUPlanCache) RecentEvictions() []PlanCacheEviction {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	evictions := make([]PlanCacheEviction, 0, len(l.history))
+	if len(l.history) == planCacheEvictionHistorySize {
+		evictions = append(evictions, l.history[l.historyNext:]...)
+	}
+	return append(evictions, l.history[:l.historyNext]...)
+}
+
+// recordEviction records the eviction in the history and the metrics, the caller must hold the lock.
+func (l *LRUPlanCache) recordEviction(key kvcache.Key, reason PlanCacheEvictReason) {
+	eviction := PlanCacheEviction{Time: time.Now(), Reason: reason}
+	if k, ok := key.(*planCacheKey); ok {
+		eviction.DB, eviction.StmtText = k.database, k.stmtText
+	}
+	if len(l.history) < planCacheEvictionHistorySize {
+		l.history = append(l.history, eviction)
+	} else {
+		l.history[l.historyNext] = eviction
+	}
+	l.historyNext = (l.historyNext + 1) % planCacheEvictionHistorySize
+	metrics.PlanCacheEvictionCounter.WithLabelValues(string(reason)).Inc()
 }
 
 // Size gets the current cache size.
@@ -161,13 +318,47 @@ func (l *LRUPlanCache) SetCapacity(capacity uint) error {
 	}
 	l.capacity = capacity
 	for l.size > l.capacity {
-		l.removeOldest()
+		l.removeOldest(EvictReasonLRU)
 	}
 	return nil
 }
 
+// MemoryUsage returns the estimated memory usage of the cached plans.
+func (l *LRUPlanCache) MemoryUsage() int64 {
+	return l.memTracker.BytesConsumed()
+}
+
+// Close deletes all elements from the cache without recording the evictions, and detaches the cache from
+// GlobalPlanCacheMemTracker. It's called when the session is closed.
+func (l *LRUPlanCache) Close() {
+	unregisterPlanCache(l)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.lruList.Init()
+	l.buckets = make(map[string]map[*list.Element]struct{}, 1)
+	l.size = 0
+	l.memTracker.Consume(-l.memTracker.BytesConsumed())
+	l.memTracker.Detach()
+}
+
+// tryRemoveOldest removes the oldest element from the cache if the cache isn't empty and isn't being used by others,
+// and returns whether it's removed.
+func (l *LRUPlanCache) tryRemoveOldest(reason PlanCacheEvictReason) bool {
+	if !l.lock.TryLock() {
+		return false
+	}
+	defer l.lock.Unlock()
+
+	if l.size == 0 {
+		return false
+	}
+	l.removeOldest(reason)
+	return true
+}
+
 // removeOldest removes the oldest element from the cache.
-func (l *LRUPlanCache) removeOldest() {
+func (l *LRUPlanCache) removeOldest(reason PlanCacheEvictReason) {
 	lru := l.lruList.Back()
 	if l.onEvict != nil {
 		l.onEvict(lru.Value.(*planCacheEntry).PlanKey, lru.Value.(*planCacheEntry).PlanValue)
@@ -176,6 +367,10 @@ func (l *LRUPlanCache) removeOldest() {
 	l.lruList.Remove(lru)
 	l.removeFromBucket(lru)
 	l.size--
+	l.memTracker.Consume(-lru.Value.(*planCacheEntry).memUsage)
+	l.recordEviction(lru.Value.(*planCacheEntry).PlanKey, reason)
+	logutil.BgLogger().Debug("[plan-cache] evict plans", zap.String("reason", string(reason)),
+		zap.Int("count", 1))
 }
 
 // removeFromBucket remove element from bucket
@@ -192,7 +387,7 @@ func (l *LRUPlanCache) memoryControl() {
 
 	memUsed, _ := memory.InstanceMemUsed()
 	for memUsed > uint64(float64(l.quota)*(1.0-l.guard)) {
-		l.removeOldest()
+		l.removeOldest(EvictReasonLRU)
 		memUsed, _ = memory.InstanceMemUsed()
 	}
 }
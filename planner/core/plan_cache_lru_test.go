@@ -319,3 +319,57 @@ func TestLRUPCSetCapacity(t *testing.T) {
 	err = lru.SetCapacity(0)
 	require.Error(t, err, "capacity of LRU cache should be at least 1")
 }
+
+func TestLRUPCMemoryTracking(t *testing.T) {
+	globalConsumed := GlobalPlanCacheMemTracker.BytesConsumed()
+	lru := NewLRUPlanCache(3, 0, 0, pickFromBucket)
+	pTypes := []*types.FieldType{types.NewFieldType(mysql.TypeLonglong)}
+	keys := make([]*mockCacheKey, 5)
+	vals := make([]*fakePlan, 5)
+	for i := range keys {
+		keys[i] = newMockHashKey(int64(i))
+		vals[i] = &fakePlan{plan: int64(i), tps: pTypes}
+	}
+	entryMemUsage := planCacheEntryMemUsage(keys[0], vals[0])
+	require.Greater(t, entryMemUsage, int64(0))
+
+	// The memory is released when the plans are evicted by LRU.
+	for i := range keys {
+		lru.Put(keys[i], vals[i], pTypes)
+	}
+	require.Equal(t, 3*entryMemUsage, lru.MemoryUsage())
+	require.Equal(t, globalConsumed+3*entryMemUsage, GlobalPlanCacheMemTracker.BytesConsumed())
+	// Replacing the plan doesn't consume more memory.
+	lru.Put(keys[4], &fakePlan{plan: 5, tps: pTypes}, pTypes)
+	require.Equal(t, 3*entryMemUsage, lru.MemoryUsage())
+	lru.Delete(keys[4])
+	require.Equal(t, 2*entryMemUsage, lru.MemoryUsage())
+
+	// The oldest plans are evicted until the condition is satisfied.
+	evicted := evictPlanCachesUntil(func() bool {
+		return lru.MemoryUsage() <= entryMemUsage
+	})
+	require.GreaterOrEqual(t, evicted, 1)
+	require.Equal(t, 1, lru.Size())
+	require.Equal(t, entryMemUsage, lru.MemoryUsage())
+	_, ok := lru.Get(keys[3], pTypes)
+	require.True(t, ok)
+	evictions := lru.RecentEvictions()
+	require.Equal(t, EvictReasonMemoryPressure, evictions[len(evictions)-1].Reason)
+
+	lru.DeleteAll()
+	require.Equal(t, int64(0), lru.MemoryUsage())
+	// The plans of the other caches may be evicted as well.
+	globalConsumed = GlobalPlanCacheMemTracker.BytesConsumed()
+	lru.Put(keys[0], vals[0], pTypes)
+	require.Equal(t, globalConsumed+entryMemUsage, GlobalPlanCacheMemTracker.BytesConsumed())
+
+	// The closed cache isn't tracked or evicted any more.
+	lru.Close()
+	require.Equal(t, globalConsumed, GlobalPlanCacheMemTracker.BytesConsumed())
+	require.Equal(t, 0, lru.Size())
+	planCacheRegistry.Lock()
+	_, ok = planCacheRegistry.caches[lru]
+	planCacheRegistry.Unlock()
+	require.False(t, ok)
+}
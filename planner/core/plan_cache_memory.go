@@ -0,0 +1,167 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"container/list"
+	"sync"
+	"unsafe"
+
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/kvcache"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/memory"
+	"go.uber.org/zap"
+)
+
+// GlobalPlanCacheMemTracker tracks the memory of all the plan caches of the instance. It's attached to the global
+// memory tracker, so the plan caches are evicted when the server memory quota is exceeded, see PlanCacheEvictOnExceed.
+var GlobalPlanCacheMemTracker = memory.NewTracker(memory.LabelForGlobalPlanCache, -1)
+
+// The estimated sizes of the parts of a cached plan.
+const (
+	planCacheEntrySize  = int64(unsafe.Sizeof(planCacheEntry{})) + int64(unsafe.Sizeof(list.Element{}))
+	planCacheKeySize    = int64(unsafe.Sizeof(planCacheKey{}))
+	planCacheValueSize  = int64(unsafe.Sizeof(PlanCacheValue{}))
+	planCacheColumnSize = int64(unsafe.Sizeof(expression.Column{}))
+	planCacheTypeSize   = int64(unsafe.Sizeof(types.FieldType{}))
+	// planCacheNodeSize is the estimated size of a plan node besides its schema, e.g. the conditions and the ranges.
+	planCacheNodeSize = 1024
+)
+
+// planCacheEntryMemUsage estimates the memory usage of a cached plan.
+func planCacheEntryMemUsage(key kvcache.Key, value kvcache.Value) int64 {
+	size := planCacheEntrySize + int64(len(key.Hash()))
+	if k, ok := key.(*planCacheKey); ok {
+		size += planCacheKeySize + int64(len(k.database)+len(k.stmtText)+len(k.bindSQL))
+	}
+	if v, ok := value.(*PlanCacheValue); ok {
+		size += planCacheValueSize + int64(len(v.ParamTypes)+len(v.UserVarTypes))*planCacheTypeSize
+		size += planMemUsage(v.Plan)
+	}
+	return size
+}
+
+// planMemUsage estimates the memory usage of the plan tree by its nodes and the columns of their schemas.
+func planMemUsage(p Plan) int64 {
+	if p == nil {
+		return 0
+	}
+	size := int64(planCacheNodeSize)
+	if schema := p.Schema(); schema != nil {
+		size += int64(len(schema.Columns)) * planCacheColumnSize
+	}
+	switch x := p.(type) {
+	case PhysicalPlan:
+		for _, child := range x.Children() {
+			size += planMemUsage(child)
+		}
+	case *Insert:
+		size += planMemUsage(x.SelectPlan)
+	case *Update:
+		size += planMemUsage(x.SelectPlan)
+	case *Delete:
+		size += planMemUsage(x.SelectPlan)
+	}
+	return size
+}
+
+// planCacheRegistry keeps the plan caches of the instance, which are evicted by PlanCacheEvictOnExceed.
+var planCacheRegistry = struct {
+	sync.Mutex
+	caches map[*LRUPlanCache]struct{}
+}{caches: make(map[*LRUPlanCache]struct{})}
+
+func registerPlanCache(l *LRUPlanCache) {
+	planCacheRegistry.Lock()
+	defer planCacheRegistry.Unlock()
+	planCacheRegistry.caches[l] = struct{}{}
+}
+
+func unregisterPlanCache(l *LRUPlanCache) {
+	planCacheRegistry.Lock()
+	defer planCacheRegistry.Unlock()
+	delete(planCacheRegistry.caches, l)
+}
+
+// PlanCacheEvictOnExceed evicts the cached plans when the memory usage exceeds the quota of the tracker, e.g. the
+// global memory tracker. The least recently used plans of all the plan caches are evicted in turn until the memory
+// usage is below the quota, and the fallback action, e.g. killing the query, is only taken if it's still exceeded
+// after all the plans are evicted.
+type PlanCacheEvictOnExceed struct {
+	memory.BaseOOMAction
+	mutex sync.Mutex
+}
+
+// NewPlanCacheEvictOnExceed creates a PlanCacheEvictOnExceed.
+func NewPlanCacheEvictOnExceed() *PlanCacheEvictOnExceed {
+	return &PlanCacheEvictOnExceed{}
+}
+
+// SetLogHook implements the memory.ActionOnExceed interface.
+func (*PlanCacheEvictOnExceed) SetLogHook(func(uint64)) {}
+
+// GetPriority implements the memory.ActionOnExceed interface. The plans are evicted before the queries are killed.
+func (*PlanCacheEvictOnExceed) GetPriority() int64 {
+	return memory.DefSpillPriority
+}
+
+// Action implements the memory.ActionOnExceed interface.
+func (a *PlanCacheEvictOnExceed) Action(t *memory.Tracker) {
+	a.mutex.Lock()
+	evicted := evictPlanCachesUntil(func() bool {
+		return t.BytesConsumed() < t.GetBytesLimit()
+	})
+	a.mutex.Unlock()
+	if evicted > 0 {
+		logutil.BgLogger().Info("[plan-cache] evict plans under memory pressure", zap.Int("count", evicted),
+			zap.Int64("consumed", t.BytesConsumed()), zap.Int64("quota", t.GetBytesLimit()))
+	}
+	if t.BytesConsumed() >= t.GetBytesLimit() {
+		if fallback := a.GetFallback(); fallback != nil {
+			fallback.Action(t)
+		}
+	}
+}
+
+// evictPlanCachesUntil evicts the least recently used plans of the plan caches in turn until done returns true or
+// the caches are empty, and returns the number of the evicted plans. The caches being used by others are skipped.
+func evictPlanCachesUntil(done func() bool) int {
+	planCacheRegistry.Lock()
+	caches := make([]*LRUPlanCache, 0, len(planCacheRegistry.caches))
+	for l := range planCacheRegistry.caches {
+		caches = append(caches, l)
+	}
+	planCacheRegistry.Unlock()
+
+	evicted := 0
+	for !done() {
+		evictedInRound := 0
+		for _, l := range caches {
+			if done() {
+				break
+			}
+			if l.tryRemoveOldest(EvictReasonMemoryPressure) {
+				evictedInRound++
+			}
+		}
+		if evictedInRound == 0 {
+			break
+		}
+		evicted += evictedInRound
+	}
+	return evicted
+}
@@ -15,46 +15,238 @@
 package core
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/types"
 	driver "github.com/pingcap/tidb/types/parser_driver"
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/collate"
+	"github.com/pingcap/tidb/util/israce"
+	atomic2 "go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// maxPooledParamsCap is the max capacity of the params slices kept by the pooled objects.
+	// The larger ones are dropped on Reset, so that an occasional huge statement doesn't pin its memory in the pool.
+	maxPooledParamsCap = 256
+	// maxPooledBufCap is the max capacity of the buffers kept by the pooled restore contexts.
+	maxPooledBufCap = 16 * 1024
 )
 
 var (
 	paramReplacerPool = sync.Pool{New: func() interface{} {
+		paramPoolNewCounter.Inc()
 		pr := new(paramReplacer)
 		pr.Reset()
 		return pr
 	}}
 	paramRestorerPool = sync.Pool{New: func() interface{} {
+		paramPoolNewCounter.Inc()
 		pr := new(paramRestorer)
 		pr.Reset()
 		return pr
 	}}
 	paramCtxPool = sync.Pool{New: func() interface{} {
-		buf := new(strings.Builder)
-		buf.Reset()
-		restoreCtx := format.NewRestoreCtx(format.DefaultRestoreFlags, buf)
-		return restoreCtx
+		paramPoolNewCounter.Inc()
+		buf := new(bytes.Buffer)
+		return &paramCtx{buf: buf, restoreCtx: format.NewRestoreCtx(format.DefaultRestoreFlags, buf)}
 	}}
+
+	// EnableParamPoolMetrics indicates whether to record the metrics of the pools used by the parameterizer.
+	// It's a debug flag, since the metrics are updated on every parameterization.
+	EnableParamPoolMetrics = atomic2.NewBool(false)
+
+	paramPoolGetCounter  = paramPoolMetricsCounter{metrics.PlanCacheParamPoolCounter.WithLabelValues("get")}
+	paramPoolNewCounter  = paramPoolMetricsCounter{metrics.PlanCacheParamPoolCounter.WithLabelValues("new")}
+	paramPoolDropCounter = paramPoolMetricsCounter{metrics.PlanCacheParamPoolCounter.WithLabelValues("drop")}
 )
 
+// paramPoolMetricsCounter is a counter which only works when EnableParamPoolMetrics is set.
+type paramPoolMetricsCounter struct {
+	counter interface{ Inc() }
+}
+
+func (c paramPoolMetricsCounter) Inc() {
+	if EnableParamPoolMetrics.Load() {
+		c.counter.Inc()
+	}
+}
+
+// pooledObject records whether the object has been taken from the pool, it's used to detect the double Put
+// in the race-enabled tests.
+type pooledObject struct {
+	inUse bool
+}
+
+func (o *pooledObject) take() {
+	paramPoolGetCounter.Inc()
+	o.inUse = true
+}
+
+func (o *pooledObject) release() {
+	if israce.RaceEnabled && !o.inUse {
+		panic("the object is put back to the pool twice")
+	}
+	o.inUse = false
+}
+
+func getParamReplacer() *paramReplacer {
+	pr := paramReplacerPool.Get().(*paramReplacer)
+	pr.take()
+	return pr
+}
+
+func putParamReplacer(pr *paramReplacer) {
+	pr.release()
+	pr.Reset()
+	paramReplacerPool.Put(pr)
+}
+
+func getParamRestorer() *paramRestorer {
+	pr := paramRestorerPool.Get().(*paramRestorer)
+	pr.take()
+	return pr
+}
+
+func putParamRestorer(pr *paramRestorer) {
+	pr.release()
+	pr.Reset()
+	paramRestorerPool.Put(pr)
+}
+
+// paramCtx is the pooled restore context, which restores the statement into buf.
+type paramCtx struct {
+	pooledObject
+	buf        *bytes.Buffer
+	restoreCtx *format.RestoreCtx
+}
+
+// Reset resets the context, the buffer is dropped if it's oversized.
+func (pc *paramCtx) Reset() {
+	if pc.buf.Cap() > maxPooledBufCap {
+		paramPoolDropCounter.Inc()
+		pc.buf = new(bytes.Buffer)
+		pc.restoreCtx.In = pc.buf
+	} else {
+		pc.buf.Reset()
+	}
+	pc.restoreCtx.DefaultDB = ""
+	pc.restoreCtx.CTENames = nil
+}
+
+func getParamCtx() *paramCtx {
+	pc := paramCtxPool.Get().(*paramCtx)
+	pc.take()
+	return pc
+}
+
+func putParamCtx(pc *paramCtx) {
+	pc.release()
+	pc.Reset()
+	paramCtxPool.Put(pc)
+}
+
+// structuralFuncArgs records the arguments of the functions which are structural, such as the JSON paths and the
+// format strings. They decide the shape of the plan or the result, e.g. a different JSON path may be able to use
+// another index on the virtual column, so the literals in these positions are kept in the parameterized statement.
+// To add a function, map its name to the checker of the structural argument indexes.
+var structuralFuncArgs = map[string]func(idx int) bool{
+	ast.JSONExtract:      argsFrom(1),
+	ast.JSONContains:     argsAt(2),
+	ast.JSONContainsPath: argsFrom(1),
+	ast.JSONKeys:         argsAt(1),
+	ast.JSONLength:       argsAt(1),
+	ast.JSONRemove:       argsFrom(1),
+	ast.JSONSet:          oddArgs,
+	ast.JSONInsert:       oddArgs,
+	ast.JSONReplace:      oddArgs,
+	ast.JSONSearch:       func(idx int) bool { return idx == 1 || idx >= 3 },
+	ast.DateFormat:       argsAt(1),
+	ast.TimeFormat:       argsAt(1),
+	ast.StrToDate:        argsAt(1),
+}
+
+// argsAt returns the checker of the arguments at the indexes.
+func argsAt(indexes ...int) func(idx int) bool {
+	return func(idx int) bool {
+		for _, i := range indexes {
+			if i == idx {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// argsFrom returns the checker of the arguments whose indexes are not less than start.
+func argsFrom(start int) func(idx int) bool {
+	return func(idx int) bool { return idx >= start }
+}
+
+func oddArgs(idx int) bool {
+	return idx%2 == 1
+}
+
+// temporalLiterals maps the functions of the temporal literals to their keywords, e.g. DATE '2023-01-01'. The literals
+// are parameterized as a whole, so that the params keep their temporal types rather than being strings.
+var temporalLiterals = map[string]string{
+	ast.DateLiteral:      "DATE ",
+	ast.TimeLiteral:      "TIME ",
+	ast.TimestampLiteral: "TIMESTAMP ",
+}
+
 type paramReplacer struct {
+	pooledObject
+	sctx   sessionctx.Context
 	params []*driver.ValueExpr
+	// texts are the original texts of the params, they're used to show the real literals in messages and logs.
+	texts []string
 }
 
 func (pr *paramReplacer) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 	switch n := in.(type) {
 	case *driver.ValueExpr:
 		pr.params = append(pr.params, n)
+		pr.texts = append(pr.texts, paramText(n))
 		// offset is used as order in general plan cache.
 		param := ast.NewParamMarkerExpr(len(pr.params) - 1)
 		return param, true
+	case *ast.VariableExpr:
+		// Keep the variables in the template, they're evaluated on each execution rather than being parameters.
+		return in, true
+	case *ast.FuncCallExpr:
+		if keyword, ok := temporalLiterals[n.FnName.L]; ok {
+			return pr.replaceTemporalLiteral(n, keyword), true
+		}
+		isStructural, ok := structuralFuncArgs[n.FnName.L]
+		if !ok {
+			return in, false
+		}
+		for i, arg := range n.Args {
+			if _, isValue := arg.(*driver.ValueExpr); isValue && isStructural(i) {
+				continue
+			}
+			node, _ := arg.Accept(pr)
+			n.Args[i] = node.(ast.ExprNode)
+		}
+		return in, true
 	}
 	return in, false
 }
@@ -63,30 +255,224 @@ func (pr *paramReplacer) Leave(in ast.Node) (out ast.Node, ok bool) {
 	return in, true
 }
 
-func (pr *paramReplacer) Reset() { pr.params = nil }
+// replaceTemporalLiteral replaces the temporal literal with a param holding its value, e.g. DATE '2023-01-01' is
+// replaced by a DATE param rather than a DATE function of a string param. The type of the param tells the date params
+// from the string params when checking the cached plans, and a compared column is never compared with a string which
+// was a date. The literal is kept if it's invalid, so the error is reported when executing the statement.
+func (pr *paramReplacer) replaceTemporalLiteral(n *ast.FuncCallExpr, keyword string) ast.Node {
+	arg, ok := n.Args[0].(*driver.ValueExpr)
+	if !ok || pr.sctx == nil {
+		return n
+	}
+	sc := pr.sctx.GetSessionVars().StmtCtx
+	warnCnt := int(sc.WarningCount())
+	f, err := expression.NewFunction(pr.sctx, n.FnName.L, types.NewFieldType(mysql.TypeUnspecified),
+		&expression.Constant{Value: arg.Datum, RetType: &arg.Type})
+	var val types.Datum
+	if err == nil {
+		val, err = f.Eval(chunk.Row{})
+	}
+	if err != nil || int(sc.WarningCount()) != warnCnt || val.IsNull() {
+		sc.TruncateWarnings(warnCnt)
+		return n
+	}
+	param := ast.NewValueExpr(val.GetValue(), "", "").(*driver.ValueExpr)
+	pr.params = append(pr.params, param)
+	pr.texts = append(pr.texts, keyword+paramText(arg))
+	return ast.NewParamMarkerExpr(len(pr.params) - 1)
+}
+
+// Reset resets the replacer. The params slice is kept for reuse unless it's oversized, and the references to the
+// parameters are cleared so that they can be garbage collected.
+func (pr *paramReplacer) Reset() {
+	pr.sctx = nil
+	if cap(pr.params) > maxPooledParamsCap || cap(pr.texts) > maxPooledParamsCap {
+		paramPoolDropCounter.Inc()
+		pr.params, pr.texts = nil, nil
+		return
+	}
+	for i := range pr.params {
+		pr.params[i] = nil
+	}
+	for i := range pr.texts {
+		pr.texts[i] = ""
+	}
+	pr.params, pr.texts = pr.params[:0], pr.texts[:0]
+}
+
+// paramText returns the original text of the literal. The text is restored from the literal if the parser doesn't
+// keep it, e.g. `'abc'` and `1.5`.
+func paramText(n *driver.ValueExpr) string {
+	if text := n.Text(); text != "" {
+		return text
+	}
+	var sb strings.Builder
+	if err := n.Restore(format.NewRestoreCtx(format.RestoreStringSingleQuotes|format.RestoreStringWithoutCharset, &sb)); err != nil {
+		return "?"
+	}
+	return sb.String()
+}
+
+// RestoreParamTexts puts the original texts of the params back to the parameterized SQL, so that the messages and logs
+// can show the SQL with the real literals. The paramSQL is returned as is if the params don't match the markers.
+func RestoreParamTexts(paramSQL string, paramTexts []string) string {
+	if len(paramTexts) == 0 {
+		return paramSQL
+	}
+	var (
+		sb    strings.Builder
+		quote byte
+		idx   int
+	)
+	sb.Grow(len(paramSQL))
+	for i := 0; i < len(paramSQL); i++ {
+		c := paramSQL[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' && i+1 < len(paramSQL) {
+				sb.WriteByte(c)
+				i++
+				c = paramSQL[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			if idx >= len(paramTexts) {
+				return paramSQL
+			}
+			sb.WriteString(paramTexts[idx])
+			idx++
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	if idx != len(paramTexts) {
+		return paramSQL
+	}
+	return sb.String()
+}
 
 // ParameterizeAST parameterizes this StmtNode.
-// e.g. `select * from t where a<10 and b<23` --> `select * from t where a<? and b<?`, [10, 23].
+// e.g. `select * from t where a<10 and b<23` --> `select * from test.t where a<? and b<?`, [10, 23].
+// The paramTexts are the original texts of the params, see RestoreParamTexts.
 // NOTICE: this function may modify the input stmt.
-func ParameterizeAST(sctx sessionctx.Context, stmt ast.StmtNode) (paramSQL string, params []*driver.ValueExpr, err error) {
-	pr := paramReplacerPool.Get().(*paramReplacer)
-	pCtx := paramCtxPool.Get().(*format.RestoreCtx)
+func ParameterizeAST(sctx sessionctx.Context, stmt ast.StmtNode) (paramSQL string, params []*driver.ValueExpr,
+	paramTexts []string, err error) {
+	pr := getParamReplacer()
+	pCtx := getParamCtx()
 	defer func() {
-		pr.Reset()
-		paramReplacerPool.Put(pr)
-		pCtx.In.(*strings.Builder).Reset()
-		paramCtxPool.Put(pCtx)
+		putParamReplacer(pr)
+		putParamCtx(pCtx)
 	}()
+	pr.sctx = sctx
 	stmt.Accept(pr)
-	if err := stmt.Restore(pCtx); err != nil {
-		err = RestoreASTWithParams(sctx, stmt, pr.params)
-		return "", nil, err
+	// The names of the tables are qualified by the current database, so the same text run in different databases
+	// gets different templates, and never shares the cached statement.
+	pCtx.restoreCtx.DefaultDB = sctx.GetSessionVars().CurrentDB
+	if restoreErr := stmt.Restore(pCtx.restoreCtx); restoreErr != nil {
+		// The node type is labeled without the package, e.g. SelectStmt.
+		nodeType := fmt.Sprintf("%T", stmt)
+		nodeType = nodeType[strings.LastIndexByte(nodeType, '.')+1:]
+		metrics.PlanCacheParamRestoreFailCounter.WithLabelValues(nodeType).Inc()
+		err = fmt.Errorf("failed to restore the parameterized %s: %w", nodeType, restoreErr)
+		// The params are put back, so the statement can still be executed without the plan cache.
+		if paramsErr := RestoreASTWithParams(sctx, stmt, pr.params); paramsErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to restore the params of the %s: %w", nodeType, paramsErr))
+		}
+		return "", nil, nil, err
 	}
-	paramSQL, params = pCtx.In.(*strings.Builder).String(), pr.params
+	// The slices of the replacer are reused, so copy the params out.
+	paramSQL, params, paramTexts = pCtx.buf.String(), make([]*driver.ValueExpr, len(pr.params)), make([]string, len(pr.texts))
+	copy(params, pr.params)
+	copy(paramTexts, pr.texts)
 	return
 }
 
+// EqualParamGroups reports the orders of the params holding equal values, e.g. [[0, 2], [1, 3]] for `a BETWEEN 1 AND 2
+// OR a BETWEEN 1 AND 2`. Each group has at least two params, and the NULL params are never equal. It only analyzes the
+// params, the params are still independent when executing the statement.
+func EqualParamGroups(sc *stmtctx.StatementContext, params []types.Datum) [][]int {
+	if len(params) < 2 {
+		return nil
+	}
+	groupOf := make(map[string]int, len(params))
+	var groups [][]int
+	for i := range params {
+		if params[i].IsNull() {
+			continue
+		}
+		key, err := codec.EncodeValue(sc, []byte{params[i].Kind()}, params[i])
+		if err != nil {
+			continue
+		}
+		if g, ok := groupOf[string(key)]; ok {
+			groups[g] = append(groups[g], i)
+			continue
+		}
+		groupOf[string(key)] = len(groups)
+		groups = append(groups, []int{i})
+	}
+	result := groups[:0]
+	for _, g := range groups {
+		if len(g) > 1 {
+			result = append(result, g)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// paramsEqual checks whether the two params hold the same values, they must be in the same kind.
+func paramsEqual(sc *stmtctx.StatementContext, a, b *types.Datum) bool {
+	if a.IsNull() || b.IsNull() || a.Kind() != b.Kind() {
+		return false
+	}
+	cmp, err := a.Compare(sc, b, collate.GetBinaryCollator())
+	return err == nil && cmp == 0
+}
+
+// paramListPattern matches the parameter lists of IN expressions, e.g. `IN (?,?,?)`.
+var paramListPattern = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(\s*,\s*\?)*\s*\)`)
+
+// GetPlanCacheTemplate returns the parameterized text of the statement, which is the key of the statement in the
+// general plan cache. The parameter lists of IN expressions are collapsed to `IN (...)`, so that IN lists of
+// different lengths share the same template. It returns an empty string if the statement can't be parameterized,
+// e.g. its plan can't be cached.
+func GetPlanCacheTemplate(sctx sessionctx.Context, stmt ast.StmtNode) string {
+	switch x := stmt.(type) {
+	case *ast.ExecuteStmt:
+		if !x.FromGeneralStmt {
+			return ""
+		}
+		// The statement has been parameterized by the general plan cache.
+		cachedStmt, ok := x.PrepStmt.(*PlanCacheStmt)
+		if !ok {
+			return ""
+		}
+		return paramListPattern.ReplaceAllString(cachedStmt.StmtText, "IN (...)")
+	case *ast.SelectStmt, *ast.SetOprStmt, *ast.InsertStmt, *ast.UpdateStmt, *ast.DeleteStmt:
+	default:
+		return ""
+	}
+	// Parameterize the restored text rather than the statement, the statement may be used by other goroutines. The
+	// text is restored from the statement, so that the names of the tables are qualified by the preprocessor.
+	var sb strings.Builder
+	if err := stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return ""
+	}
+	t := GetParamTemplate(sctx, sb.String())
+	if t == nil {
+		return ""
+	}
+	return paramListPattern.ReplaceAllString(t.ParamSQL(), "IN (...)")
+}
+
 type paramRestorer struct {
+	pooledObject
 	params []*driver.ValueExpr
 	err    error
 }
@@ -95,18 +481,41 @@ func (pr *paramRestorer) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 	switch n := in.(type) {
 	case *driver.ParamMarkerExpr:
 		if n.Offset >= len(pr.params) {
+			// Keep the node, the nil node can't be accepted by its parent.
 			pr.err = errors.New("failed to restore ast.Node")
-			return nil, true
+			return in, true
 		}
 		// offset is used as order in general plan cache.
-		return pr.params[n.Offset], true
+		return restoredParam(pr.params[n.Offset]), true
 	}
 	if pr.err != nil {
-		return nil, true
+		return in, true
 	}
 	return in, false
 }
 
+// restoredParam returns the node to restore the param, the temporal params are restored as the temporal literals,
+// see paramReplacer.replaceTemporalLiteral.
+func restoredParam(param *driver.ValueExpr) ast.ExprNode {
+	var fnName string
+	switch param.Kind() {
+	case types.KindMysqlTime:
+		fnName = ast.TimestampLiteral
+		if param.GetMysqlTime().Type() == mysql.TypeDate {
+			fnName = ast.DateLiteral
+		}
+	case types.KindMysqlDuration:
+		fnName = ast.TimeLiteral
+	default:
+		return param
+	}
+	str, err := param.ToString()
+	if err != nil {
+		return param
+	}
+	return &ast.FuncCallExpr{FnName: model.NewCIStr(fnName), Args: []ast.ExprNode{ast.NewValueExpr(str, "", "")}}
+}
+
 func (pr *paramRestorer) Leave(in ast.Node) (out ast.Node, ok bool) {
 	return in, true
 }
@@ -118,12 +527,66 @@ func (pr *paramRestorer) Reset() {
 // RestoreASTWithParams restore this parameterized AST with specific parameters.
 // e.g. `select * from t where a<? and b<?`, [10, 23] --> `select * from t where a<10 and b<23`.
 func RestoreASTWithParams(_ sessionctx.Context, stmt ast.StmtNode, params []*driver.ValueExpr) error {
-	pr := paramRestorerPool.Get().(*paramRestorer)
-	defer func() {
-		pr.Reset()
-		paramRestorerPool.Put(pr)
-	}()
+	pr := getParamRestorer()
+	defer putParamRestorer(pr)
 	pr.params = params
 	stmt.Accept(pr)
 	return pr.err
 }
+
+// sqlWithParamsRestoreFlags are the flags to restore the original SQL of a parameterized statement, the SQL can be
+// replayed to reproduce the statement.
+const sqlWithParamsRestoreFlags = format.DefaultRestoreFlags | format.RestoreStringWithoutDefaultCharset
+
+// RestoreSQLWithParams reconstructs the original SQL of the parameterized statement with its params, e.g.
+// `SELECT * FROM test.t WHERE a<?`, [10] --> `SELECT * FROM test.t WHERE a<10`, it's used to show the general queries
+// in the slow log and the statement summary. The params are redacted, i.e. left as the param markers, if redact is
+// set. The statement isn't modified, since the parameterized AST is shared by the executions, the params are
+// restored into a private copy parsed from its text.
+func RestoreSQLWithParams(sctx sessionctx.Context, stmt ast.StmtNode, params []expression.Expression, redact bool) (string, error) {
+	var sb strings.Builder
+	if err := stmt.Restore(format.NewRestoreCtx(sqlWithParamsRestoreFlags, &sb)); err != nil {
+		return "", err
+	}
+	if redact || len(params) == 0 {
+		return sb.String(), nil
+	}
+	valueExprs := make([]*driver.ValueExpr, 0, len(params))
+	for _, param := range params {
+		c, ok := param.(*expression.Constant)
+		if !ok {
+			return "", errors.New("failed to restore the SQL with the non-constant params")
+		}
+		v := &driver.ValueExpr{Datum: *c.Value.Clone()}
+		v.SetType(c.RetType)
+		valueExprs = append(valueExprs, v)
+	}
+
+	vars := sctx.GetSessionVars()
+	p := parser.New()
+	p.SetSQLMode(vars.SQLMode)
+	p.SetParserConfig(vars.BuildParserConfig())
+	chs, coll := vars.GetCharsetInfo()
+	privateStmt, err := p.ParseOneStmt(sb.String(), chs, coll)
+	if err != nil {
+		return "", err
+	}
+	// The param markers parsed from the text are numbered by their positions, renumber them by their orders, which
+	// RestoreASTWithParams uses, see ParameterizeAST.
+	var extractor paramMarkerExtractor
+	privateStmt.Accept(&extractor)
+	slices.SortFunc(extractor.markers, func(i, j ast.ParamMarkerExpr) bool {
+		return i.(*driver.ParamMarkerExpr).Offset < j.(*driver.ParamMarkerExpr).Offset
+	})
+	for i, marker := range extractor.markers {
+		marker.(*driver.ParamMarkerExpr).Offset = i
+	}
+	if err = RestoreASTWithParams(sctx, privateStmt, valueExprs); err != nil {
+		return "", err
+	}
+	sb.Reset()
+	if err = privateStmt.Restore(format.NewRestoreCtx(sqlWithParamsRestoreFlags, &sb)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/sessionctx"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/kvcache"
+	"github.com/pingcap/tidb/util/logutil"
+	"github.com/pingcap/tidb/util/stringutil"
+	"go.uber.org/zap"
+)
+
+// paramTemplateCacheCapacity is the capacity of the per-session cache of the param templates.
+const paramTemplateCacheCapacity = 64
+
+// paramTemplateCacheKey is used to get the param template cache from a ctx.
+const paramTemplateCacheKey = stringutil.StringerStr("paramTemplateCacheKey")
+
+// ParamTemplate is the result of parameterizing a statement text. It's built from a private AST parsed from the
+// text, and it's shared by all the callers parameterizing the same text in the session, so it's immutable after
+// being built and the callers never see a half-parameterized AST.
+type ParamTemplate struct {
+	paramSQL string
+	// stmt is the parameterized AST, whose literals have been replaced by the param markers.
+	stmt   ast.StmtNode
+	params []*driver.ValueExpr
+	texts  []string
+}
+
+// ParamSQL returns the parameterized SQL, whose tables are qualified by the current database, e.g.
+// SELECT * FROM `test`.`t` WHERE `a`<?.
+func (t *ParamTemplate) ParamSQL() string {
+	return t.paramSQL
+}
+
+// Stmt returns the parameterized AST. It's shared by the callers and must not be modified.
+func (t *ParamTemplate) Stmt() ast.StmtNode {
+	return t.stmt
+}
+
+// ParamTexts returns the original texts of the params, see RestoreParamTexts.
+func (t *ParamTemplate) ParamTexts() []string {
+	texts := make([]string, len(t.texts))
+	copy(texts, t.texts)
+	return texts
+}
+
+// NewParams returns the params of the template. The params are copied, so the callers own them.
+func (t *ParamTemplate) NewParams() []expression.Expression {
+	params := make([]expression.Expression, 0, len(t.params))
+	for _, p := range t.params {
+		params = append(params, &expression.Constant{Value: *p.Datum.Clone(), RetType: p.Type.Clone()})
+	}
+	return params
+}
+
+type paramTemplateKey string
+
+func (k paramTemplateKey) Hash() []byte {
+	return hack.Slice(string(k))
+}
+
+// paramTemplateCache caches the param templates of a session keyed by the statement texts. The statements
+// which can't be parameterized are cached as nil templates, so they're not parsed again.
+type paramTemplateCache struct {
+	mu  sync.Mutex
+	lru *kvcache.SimpleLRUCache
+}
+
+func (c *paramTemplateCache) get(key paramTemplateKey) (*ParamTemplate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*ParamTemplate), true
+}
+
+func (c *paramTemplateCache) put(key paramTemplateKey, t *ParamTemplate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Put(key, t)
+}
+
+// paramTemplateCacheInitMu makes sure that only one param template cache is created for a session.
+var paramTemplateCacheInitMu sync.Mutex
+
+func getParamTemplateCache(sctx sessionctx.Context) *paramTemplateCache {
+	if v := sctx.Value(paramTemplateCacheKey); v != nil {
+		return v.(*paramTemplateCache)
+	}
+	paramTemplateCacheInitMu.Lock()
+	defer paramTemplateCacheInitMu.Unlock()
+	if v := sctx.Value(paramTemplateCacheKey); v != nil {
+		return v.(*paramTemplateCache)
+	}
+	c := &paramTemplateCache{lru: kvcache.NewSimpleLRUCache(paramTemplateCacheCapacity, 0, 0)}
+	sctx.SetValue(paramTemplateCacheKey, c)
+	return c
+}
+
+// GetParamTemplate returns the param template of the statement text from the cache of the session, and builds it
+// on a cache miss. It returns nil if the text can't be parameterized, e.g. it isn't a single DML statement, or its
+// plan can't be cached.
+// It's safe to call it concurrently on the same session.
+func GetParamTemplate(sctx sessionctx.Context, sql string) *ParamTemplate {
+	vars := sctx.GetSessionVars()
+	chs, coll := vars.GetCharsetInfo()
+	// The SQL mode and the charset affect how the text is parsed, and the current database qualifies the tables.
+	var sb strings.Builder
+	sb.Grow(len(sql) + len(chs) + len(coll) + len(vars.CurrentDB) + 24)
+	sb.WriteString(strconv.FormatUint(uint64(vars.SQLMode), 10))
+	sb.WriteByte(0)
+	sb.WriteString(chs)
+	sb.WriteByte(0)
+	sb.WriteString(coll)
+	sb.WriteByte(0)
+	sb.WriteString(vars.CurrentDB)
+	sb.WriteByte(0)
+	sb.WriteString(sql)
+	key := paramTemplateKey(sb.String())
+
+	c := getParamTemplateCache(sctx)
+	if t, ok := c.get(key); ok {
+		return t
+	}
+	// Build the template out of the lock, the callers racing on the same text build equivalent templates.
+	t := buildParamTemplate(sctx, sql)
+	c.put(key, t)
+	return t
+}
+
+func buildParamTemplate(sctx sessionctx.Context, sql string) *ParamTemplate {
+	vars := sctx.GetSessionVars()
+	p := parser.New()
+	p.SetSQLMode(vars.SQLMode)
+	p.SetParserConfig(vars.BuildParserConfig())
+	stmts, _, err := p.ParseSQL(sql, vars.GetParseParams()...)
+	if err != nil || len(stmts) != 1 {
+		return nil
+	}
+	// The plan of the statement can't be cached, don't parameterize it in vain. The tables aren't checked, since
+	// the template is kept across the schema changes.
+	if cacheable, _ := CacheableWithReason(stmts[0], nil); !cacheable {
+		return nil
+	}
+	paramSQL, params, texts, err := ParameterizeAST(sctx, stmts[0])
+	if err != nil {
+		// The cacheable statements are expected to be restored, the failure is likely a bug of the restorer.
+		logutil.BgLogger().Warn("[plan-cache] parameterize the statement failed", zap.Error(err))
+		return nil
+	}
+	return &ParamTemplate{paramSQL: paramSQL, stmt: stmts[0], params: params, texts: texts}
+}
@@ -15,12 +15,22 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/pingcap/tidb/metrics"
 	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+	"github.com/pingcap/tidb/util/israce"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 )
 
 func TestParameterize(t *testing.T) {
@@ -33,35 +43,73 @@ func TestParameterize(t *testing.T) {
 	}{
 		{
 			"select * from t where a<10",
-			"SELECT * FROM `t` WHERE `a`<?",
+			"SELECT * FROM `test`.`t` WHERE `a`<?",
 			[]interface{}{int64(10)},
 			"SELECT * FROM `t` WHERE `a`<10",
 		},
 		{
 			"select * from t",
-			"SELECT * FROM `t`",
+			"SELECT * FROM `test`.`t`",
 			[]interface{}{},
 			"SELECT * FROM `t`",
 		},
 		{
 			"select * from t where a<10 and b<20 and c=30 and d>40",
-			"SELECT * FROM `t` WHERE `a`<? AND `b`<? AND `c`=? AND `d`>?",
+			"SELECT * FROM `test`.`t` WHERE `a`<? AND `b`<? AND `c`=? AND `d`>?",
 			[]interface{}{int64(10), int64(20), int64(30), int64(40)},
 			"SELECT * FROM `t` WHERE `a`<10 AND `b`<20 AND `c`=30 AND `d`>40",
 		},
 		{
 			"select * from t where a='a' and b='bbbbbbbbbbbbbbbbbbbbbbbb'",
-			"SELECT * FROM `t` WHERE `a`=? AND `b`=?",
+			"SELECT * FROM `test`.`t` WHERE `a`=? AND `b`=?",
 			[]interface{}{"a", "bbbbbbbbbbbbbbbbbbbbbbbb"},
 			"SELECT * FROM `t` WHERE `a`=_UTF8MB4'a' AND `b`=_UTF8MB4'bbbbbbbbbbbbbbbbbbbbbbbb'",
 		},
+		{
+			"select /*+ use_plan_cache() */ * from t where a<10",
+			"SELECT /*+ USE_PLAN_CACHE()*/ * FROM `test`.`t` WHERE `a`<?",
+			[]interface{}{int64(10)},
+			"SELECT /*+ USE_PLAN_CACHE()*/ * FROM `t` WHERE `a`<10",
+		},
+		// The structural arguments of the functions are kept.
+		{
+			"select json_extract(j, '$.user.id', '$.name') from t where json_extract(j, '$.user.id')=10",
+			"SELECT JSON_EXTRACT(`j`, _UTF8MB4'$.user.id', _UTF8MB4'$.name') FROM `test`.`t` WHERE JSON_EXTRACT(`j`, _UTF8MB4'$.user.id')=?",
+			[]interface{}{int64(10)},
+			"SELECT JSON_EXTRACT(`j`, _UTF8MB4'$.user.id', _UTF8MB4'$.name') FROM `t` WHERE JSON_EXTRACT(`j`, _UTF8MB4'$.user.id')=10",
+		},
+		{
+			"select * from t where j->'$.a'=1",
+			"SELECT * FROM `test`.`t` WHERE JSON_EXTRACT(`j`, _UTF8MB4'$.a')=?",
+			[]interface{}{int64(1)},
+			"SELECT * FROM `t` WHERE JSON_EXTRACT(`j`, _UTF8MB4'$.a')=1",
+		},
+		{
+			"select * from t where json_contains(j, '1', '$.a') and json_contains(j, '2')",
+			"SELECT * FROM `test`.`t` WHERE JSON_CONTAINS(`j`, ?, _UTF8MB4'$.a') AND JSON_CONTAINS(`j`, ?)",
+			[]interface{}{"1", "2"},
+			"SELECT * FROM `t` WHERE JSON_CONTAINS(`j`, _UTF8MB4'1', _UTF8MB4'$.a') AND JSON_CONTAINS(`j`, _UTF8MB4'2')",
+		},
+		{
+			"select date_format(d, '%Y-%m') from t where date_format(d, '%Y') > '2020'",
+			"SELECT DATE_FORMAT(`d`, _UTF8MB4'%Y-%m') FROM `test`.`t` WHERE DATE_FORMAT(`d`, _UTF8MB4'%Y')>?",
+			[]interface{}{"2020"},
+			"SELECT DATE_FORMAT(`d`, _UTF8MB4'%Y-%m') FROM `t` WHERE DATE_FORMAT(`d`, _UTF8MB4'%Y')>_UTF8MB4'2020'",
+		},
+		{
+			// The non-literal structural arguments are still visited.
+			"select * from t where json_extract(j, concat('$.', 'a'))=1",
+			"SELECT * FROM `test`.`t` WHERE JSON_EXTRACT(`j`, CONCAT(?, ?))=?",
+			[]interface{}{"$.", "a", int64(1)},
+			"SELECT * FROM `t` WHERE JSON_EXTRACT(`j`, CONCAT(_UTF8MB4'$.', _UTF8MB4'a'))=1",
+		},
 		// TODO: more test cases
 	}
 
 	for _, c := range cases {
 		stmt, err := parser.New().ParseOneStmt(c.sql, "", "")
 		require.Nil(t, err)
-		paramSQL, params, err := ParameterizeAST(sctx, stmt)
+		paramSQL, params, _, err := ParameterizeAST(sctx, stmt)
 		require.Nil(t, err)
 		require.Equal(t, c.paramSQL, paramSQL)
 		require.Equal(t, len(c.params), len(params))
@@ -77,3 +125,249 @@ func TestParameterize(t *testing.T) {
 		require.Equal(t, c.restoreSQL, buf.String())
 	}
 }
+
+func TestStructuralFuncArgs(t *testing.T) {
+	isStructural := structuralFuncArgs[ast.JSONExtract]
+	require.False(t, isStructural(0))
+	require.True(t, isStructural(1))
+	require.True(t, isStructural(5))
+	isStructural = structuralFuncArgs[ast.JSONContains]
+	require.False(t, isStructural(1))
+	require.True(t, isStructural(2))
+	isStructural = structuralFuncArgs[ast.JSONSet]
+	require.True(t, isStructural(1))
+	require.False(t, isStructural(2))
+	require.True(t, isStructural(3))
+	isStructural = structuralFuncArgs[ast.DateFormat]
+	require.False(t, isStructural(0))
+	require.True(t, isStructural(1))
+}
+
+func TestParameterizeTemporalLiterals(t *testing.T) {
+	sctx := MockContext()
+	stmt, err := parser.New().ParseOneStmt("select * from t where a<date '2023-01-01' and b>timestamp '2023-01-01 10:00:00' and c=time '10:00:00' and d<'2023-01-01'", "", "")
+	require.NoError(t, err)
+	paramSQL, params, texts, err := ParameterizeAST(sctx, stmt)
+	require.NoError(t, err)
+	// The literals are parameterized as a whole, the params keep their temporal types.
+	require.Equal(t, "SELECT * FROM `test`.`t` WHERE `a`<? AND `b`>? AND `c`=? AND `d`<?", paramSQL)
+	require.Equal(t, []string{"DATE '2023-01-01'", "TIMESTAMP '2023-01-01 10:00:00'", "TIME '10:00:00'", "'2023-01-01'"}, texts)
+	require.Len(t, params, 4)
+	require.Equal(t, mysql.TypeDate, params[0].Type.GetType())
+	require.Equal(t, mysql.TypeDatetime, params[1].Type.GetType())
+	require.Equal(t, mysql.TypeDuration, params[2].Type.GetType())
+	require.Equal(t, mysql.TypeVarString, params[3].Type.GetType())
+
+	require.NoError(t, RestoreASTWithParams(sctx, stmt, params))
+	var buf strings.Builder
+	require.NoError(t, stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags|format.RestoreStringWithoutCharset, &buf)))
+	require.Equal(t, "SELECT * FROM `t` WHERE `a`<DATE '2023-01-01' AND `b`>TIMESTAMP '2023-01-01 10:00:00' AND `c`=TIME '10:00:00' AND `d`<'2023-01-01'", buf.String())
+
+	// The invalid literals are kept, so the errors are reported when executing the statements.
+	stmt, err = parser.New().ParseOneStmt("select * from t where a<date '2023-13-01'", "", "")
+	require.NoError(t, err)
+	paramSQL, params, _, err = ParameterizeAST(sctx, stmt)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM `test`.`t` WHERE `a`<DATE '2023-13-01'", paramSQL)
+	require.Len(t, params, 0)
+	require.Len(t, sctx.GetSessionVars().StmtCtx.GetWarnings(), 0)
+}
+
+func TestRestoreParamTexts(t *testing.T) {
+	sctx := MockContext()
+	stmt, err := parser.New().ParseOneStmt("select * from t where a<10 and b='x?' and c>1.5 and `d?`=-3", "", "")
+	require.NoError(t, err)
+	paramSQL, _, paramTexts, err := ParameterizeAST(sctx, stmt)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM `test`.`t` WHERE `a`<? AND `b`=? AND `c`>? AND `d?`=-?", paramSQL)
+	require.Equal(t, []string{"10", "'x?'", "1.5", "3"}, paramTexts)
+	require.Equal(t, "SELECT * FROM `test`.`t` WHERE `a`<10 AND `b`='x?' AND `c`>1.5 AND `d?`=-3", RestoreParamTexts(paramSQL, paramTexts))
+
+	// The markers in the quoted strings are skipped.
+	require.Equal(t, `select '?', "\"?", 1`, RestoreParamTexts(`select '?', "\"?", ?`, []string{"1"}))
+	// The paramSQL is kept if the params don't match the markers.
+	require.Equal(t, "select ?, ?", RestoreParamTexts("select ?, ?", []string{"1"}))
+	require.Equal(t, "select ?", RestoreParamTexts("select ?", []string{"1", "2"}))
+	require.Equal(t, "select ?", RestoreParamTexts("select ?", nil))
+}
+
+// restoreFailStmt is a statement which fails to be restored, e.g. it contains an unsupported node.
+type restoreFailStmt struct {
+	*ast.SelectStmt
+	err error
+}
+
+func (s *restoreFailStmt) Restore(*format.RestoreCtx) error {
+	return s.err
+}
+
+func TestParameterizeRestoreFail(t *testing.T) {
+	sctx := MockContext()
+	errRestore := errors.New("mock restore error")
+	failCount := func() float64 {
+		pb := &dto.Metric{}
+		require.NoError(t, metrics.PlanCacheParamRestoreFailCounter.WithLabelValues("restoreFailStmt").Write(pb))
+		return pb.GetCounter().GetValue()
+	}
+	count := failCount()
+
+	// The params are put back into the statement.
+	stmt, err := parser.New().ParseOneStmt("select * from t where a<10", "", "")
+	require.NoError(t, err)
+	_, _, _, err = ParameterizeAST(sctx, &restoreFailStmt{SelectStmt: stmt.(*ast.SelectStmt), err: errRestore})
+	require.ErrorIs(t, err, errRestore)
+	require.EqualError(t, err, "failed to restore the parameterized restoreFailStmt: mock restore error")
+	require.Len(t, multierr.Errors(err), 1)
+	var sb strings.Builder
+	require.NoError(t, stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)))
+	require.Equal(t, "SELECT * FROM `t` WHERE `a`<10", sb.String())
+	require.Equal(t, count+1, failCount())
+
+	// Both the failures are returned if the params can't be put back either, the param marker in the statement isn't
+	// replaced by the parameterizer, so it has no param to restore.
+	stmt, err = parser.New().ParseOneStmt("select * from t where a<?", "", "")
+	require.NoError(t, err)
+	_, _, _, err = ParameterizeAST(sctx, &restoreFailStmt{SelectStmt: stmt.(*ast.SelectStmt), err: errRestore})
+	errs := multierr.Errors(err)
+	require.Len(t, errs, 2)
+	require.ErrorIs(t, errs[0], errRestore)
+	require.EqualError(t, errs[1], "failed to restore the params of the restoreFailStmt: failed to restore ast.Node")
+	require.ErrorIs(t, err, errRestore)
+	require.Equal(t, count+2, failCount())
+}
+
+func TestEqualParamGroups(t *testing.T) {
+	sc := MockContext().GetSessionVars().StmtCtx
+	require.Nil(t, EqualParamGroups(sc, nil))
+	require.Nil(t, EqualParamGroups(sc, types.MakeDatums(1, 2, "1")))
+	require.Equal(t, [][]int{{0, 2}, {1, 3}}, EqualParamGroups(sc, types.MakeDatums(100, "a", 100, "a", 200)))
+	// The NULL params are never equal.
+	require.Nil(t, EqualParamGroups(sc, types.MakeDatums(nil, nil)))
+	require.Equal(t, [][]int{{1, 2}}, EqualParamGroups(sc, types.MakeDatums(1.5, int64(2), int64(2), 2.0)))
+}
+
+func TestParamPoolReset(t *testing.T) {
+	pr := getParamReplacer()
+	for i := 0; i < 10; i++ {
+		pr.params = append(pr.params, &driver.ValueExpr{})
+	}
+	params := pr.params
+	pr.Reset()
+	// The small slice is kept, but it doesn't reference the parameters anymore.
+	require.Len(t, pr.params, 0)
+	require.Equal(t, cap(params), cap(pr.params))
+	for _, param := range params {
+		require.Nil(t, param)
+	}
+	pr.params = make([]*driver.ValueExpr, maxPooledParamsCap+1)
+	pr.Reset()
+	require.Nil(t, pr.params)
+	putParamReplacer(pr)
+
+	pc := getParamCtx()
+	pc.buf.Grow(maxPooledBufCap + 1)
+	pc.buf.WriteString("select 1")
+	pc.Reset()
+	require.Equal(t, 0, pc.buf.Cap())
+	require.Equal(t, pc.buf, pc.restoreCtx.In)
+	pc.buf.WriteString("select 1")
+	pc.Reset()
+	require.Equal(t, 0, pc.buf.Len())
+	putParamCtx(pc)
+
+	// The params returned by ParameterizeAST aren't affected by the reuse of the replacer.
+	sctx := MockContext()
+	stmt, err := parser.New().ParseOneStmt("select * from t where a<10 and b<20", "", "")
+	require.NoError(t, err)
+	_, params, _, err = ParameterizeAST(sctx, stmt)
+	require.NoError(t, err)
+	stmt, err = parser.New().ParseOneStmt("select * from t where a<30", "", "")
+	require.NoError(t, err)
+	_, _, _, err = ParameterizeAST(sctx, stmt)
+	require.NoError(t, err)
+	require.Len(t, params, 2)
+	require.Equal(t, int64(10), params[0].Datum.GetValue())
+	require.Equal(t, int64(20), params[1].Datum.GetValue())
+}
+
+func TestParamPoolDoublePut(t *testing.T) {
+	if !israce.RaceEnabled {
+		t.Skip("the double Put is only detected when race is enabled")
+	}
+	pr := getParamReplacer()
+	putParamReplacer(pr)
+	require.Panics(t, func() { putParamReplacer(pr) })
+	pc := getParamCtx()
+	putParamCtx(pc)
+	require.Panics(t, func() { putParamCtx(pc) })
+}
+
+// inListSQL returns a statement with an IN list of n items.
+func inListSQL(n int) string {
+	var sb strings.Builder
+	sb.WriteString("select * from t where a in (")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%d", i)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func benchmarkParameterize(b *testing.B, n int) {
+	stmt, err := parser.New().ParseOneStmt(inListSQL(n), "", "")
+	require.NoError(b, err)
+	sctx := MockContext()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, params, _, err := ParameterizeAST(sctx, stmt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := RestoreASTWithParams(sctx, stmt, params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParameterizeSmall(b *testing.B) {
+	benchmarkParameterize(b, 4)
+}
+
+func BenchmarkParameterizeMedium(b *testing.B) {
+	benchmarkParameterize(b, 128)
+}
+
+func BenchmarkParameterizeHuge(b *testing.B) {
+	benchmarkParameterize(b, 10000)
+}
+
+// BenchmarkParameterizeMixed parameterizes an occasional huge statement among the small ones, the pooled objects
+// shouldn't keep the memory of the huge one.
+func BenchmarkParameterizeMixed(b *testing.B) {
+	small, err := parser.New().ParseOneStmt(inListSQL(4), "", "")
+	require.NoError(b, err)
+	huge, err := parser.New().ParseOneStmt(inListSQL(10000), "", "")
+	require.NoError(b, err)
+	sctx := MockContext()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stmt := small
+		if i%1000 == 0 {
+			stmt = huge
+		}
+		_, params, _, err := ParameterizeAST(sctx, stmt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := RestoreASTWithParams(sctx, stmt, params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
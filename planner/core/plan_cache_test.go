@@ -15,16 +15,30 @@
 package core_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/metrics"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/auth"
+	"github.com/pingcap/tidb/parser/format"
 	"github.com/pingcap/tidb/parser/mysql"
 	plannercore "github.com/pingcap/tidb/planner/core"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/testutil"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,17 +46,23 @@ type mockParameterizer struct {
 	action string
 }
 
-func (mp *mockParameterizer) Parameterize(originSQL string) (paramSQL string, params []expression.Expression, ok bool, err error) {
+func (mp *mockParameterizer) Parameterize(originSQL string) (paramSQL string, params []expression.Expression,
+	paramTexts []string, ok bool, err error) {
 	switch mp.action {
 	case "error":
-		return "", nil, false, errors.New("error")
+		return "", nil, nil, false, errors.New("error")
 	case "not_support":
-		return "", nil, false, nil
+		return "", nil, nil, false, nil
 	}
-	// only support SQL like 'select * from t where col {op} {int} and ...'
+	// only support SQL like 'select [/*+ hints */] * from t where col {op} {int|'string'} and ...'
 	prefix := "select * from t where "
+	if strings.HasPrefix(originSQL, "select /*+ ") {
+		if end := strings.Index(originSQL, " */ "); end > 0 {
+			prefix = originSQL[:end+len(" */ ")] + "* from t where "
+		}
+	}
 	if !strings.HasPrefix(originSQL, prefix) {
-		return "", nil, false, nil
+		return "", nil, nil, false, nil
 	}
 	buf := make([]byte, 0, 32)
 	buf = append(buf, prefix...)
@@ -52,19 +72,25 @@ func (mp *mockParameterizer) Parameterize(originSQL string) (paramSQL string, pa
 		}
 		tmp := strings.Split(strings.TrimSpace(condStr), " ")
 		if len(tmp) != 3 { // col {op} {val}
-			return "", nil, false, nil
+			return "", nil, nil, false, nil
 		}
 		buf = append(buf, tmp[0]...)
 		buf = append(buf, tmp[1]...)
 		buf = append(buf, '?')
+		paramTexts = append(paramTexts, tmp[2])
 
+		if len(tmp[2]) >= 2 && tmp[2][0] == '\'' && tmp[2][len(tmp[2])-1] == '\'' {
+			strParam := tmp[2][1 : len(tmp[2])-1]
+			params = append(params, &expression.Constant{Value: types.NewDatum(strParam), RetType: types.NewFieldType(mysql.TypeVarString)})
+			continue
+		}
 		intParam, err := strconv.Atoi(tmp[2])
 		if err != nil {
-			return "", nil, false, nil
+			return "", nil, nil, false, nil
 		}
 		params = append(params, &expression.Constant{Value: types.NewDatum(intParam), RetType: types.NewFieldType(mysql.TypeLong)})
 	}
-	return string(buf), params, true, nil
+	return string(buf), params, paramTexts, true, nil
 }
 
 func TestGeneralPlanCacheParameterizer(t *testing.T) {
@@ -97,6 +123,1008 @@ func TestGeneralPlanCacheParameterizer(t *testing.T) {
 	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
 }
 
+func TestGeneralPlanCacheParamTexts(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+
+	mp := new(mockParameterizer)
+	tk.Session().SetValue(plannercore.ParameterizerKey, mp)
+
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert into t values (1), (2)")
+	tk.MustQuery("select * from t where a > '1x'").Check(testkit.Rows("2"))
+	// The statement is shown with the real literal rather than the parameterized one.
+	require.Equal(t, "select * from t where a > '1x'", tk.Session().GetSessionVars().StmtCtx.OriginalSQL)
+	warnings := tk.MustQuery("show warnings").Rows()
+	require.NotEmpty(t, warnings)
+	require.Equal(t, "Truncated incorrect DOUBLE value: '1x'", warnings[0][2])
+
+	tk.MustQuery("select * from t where a > '0y'").Sort().Check(testkit.Rows("1", "2"))
+	require.Equal(t, "select * from t where a > '0y'", tk.Session().GetSessionVars().StmtCtx.OriginalSQL)
+	warnings = tk.MustQuery("show warnings").Rows()
+	require.NotEmpty(t, warnings)
+	require.Equal(t, "Truncated incorrect DOUBLE value: '0y'", warnings[0][2])
+}
+
+func TestGeneralPlanCacheHints(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+
+	mp := new(mockParameterizer)
+	tk.Session().SetValue(plannercore.ParameterizerKey, mp)
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert into t values (1), (2), (3)")
+	for _, c := range []struct {
+		enabled bool
+		hint    string
+		hit     string
+	}{
+		{false, "", "0"},
+		{true, "", "1"},
+		{false, "/*+ ignore_plan_cache() */ ", "0"},
+		{true, "/*+ ignore_plan_cache() */ ", "0"},
+		{false, "/*+ use_plan_cache() */ ", "1"},
+		{true, "/*+ use_plan_cache() */ ", "1"},
+		{true, "/*+ USE_PLAN_CACHE(), IGNORE_PLAN_CACHE() */ ", "0"},
+	} {
+		tk.MustExec(fmt.Sprintf("set tidb_enable_general_plan_cache=%v", c.enabled))
+		tk.MustQuery(fmt.Sprintf("select %s* from t where a > 1", c.hint)).Sort().Check(testkit.Rows("2", "3"))
+		tk.MustQuery(fmt.Sprintf("select %s* from t where a > 2", c.hint)).Check(testkit.Rows("3"))
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(c.hit))
+	}
+
+	require.Equal(t, plannercore.NoPlanCacheHint, plannercore.GetPlanCacheHint("select * from t where a = '/*+ use_plan_cache() */'"))
+	require.Equal(t, plannercore.UsePlanCacheHint, plannercore.GetPlanCacheHint("select /*+ use_index(t, a) */ /*+ Use_Plan_Cache( ) */ * from t"))
+	require.Equal(t, plannercore.IgnorePlanCacheHint, plannercore.GetPlanCacheHint("select /*+ use_plan_cache() */ * from t union select /*+ ignore_plan_cache() */ * from t"))
+}
+
+func TestGeneralPlanCacheSkipInternalSQL(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+
+	mp := new(mockParameterizer)
+	tk.Session().SetValue(plannercore.ParameterizerKey, mp)
+
+	// Enable it globally, so the internal sessions used by DDL enable it too.
+	tk.MustExec("set global tidb_enable_general_plan_cache=1")
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert into t values (1), (2), (3)")
+
+	ctx := context.Background()
+	_, ok := tk.Session().Parameterize(ctx, "select * from t where a > 1")
+	require.True(t, ok)
+	tk.Session().GetSessionVars().InRestrictedSQL = true
+	_, ok = tk.Session().Parameterize(ctx, "select * from t where a > 1")
+	tk.Session().GetSessionVars().InRestrictedSQL = false
+	require.False(t, ok)
+
+	// The expression indexes whose definitions differ only in a literal are both backfilled correctly.
+	tk.MustExec("alter table t add index i1((a + 1))")
+	tk.MustExec("alter table t add index i2((a + 2))")
+	tk.MustExec("admin check table t")
+	tk.MustQuery("select a + 1 from t use index(i1) where a + 1 > 2").Sort().Check(testkit.Rows("3", "4"))
+	tk.MustQuery("select a + 2 from t use index(i2) where a + 2 > 2").Sort().Check(testkit.Rows("3", "4", "5"))
+	tk.MustQuery("select * from t where a > 1").Sort().Check(testkit.Rows("2", "3"))
+}
+
+func TestGeneralPlanCacheSkipStaleRead(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+
+	mp := new(mockParameterizer)
+	tk.Session().SetValue(plannercore.ParameterizerKey, mp)
+
+	// For mocktikv, safe point is not initialized, we manually insert it for snapshot to use.
+	timeSafe := time.Now().Add(-48 * 60 * 60 * time.Second).Format("20060102-15:04:05 -0700 MST")
+	tk.MustExec(fmt.Sprintf(`INSERT HIGH_PRIORITY INTO mysql.tidb VALUES ('tikv_gc_safe_point', '%[1]s', '')
+			       ON DUPLICATE KEY
+			       UPDATE variable_value = '%[1]s'`, timeSafe))
+
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+	// Record the tso before the index exists.
+	tk.MustExec("begin")
+	tso := tk.Session().GetSessionVars().TxnCtx.StartTS
+	tk.MustExec("rollback")
+	tk.MustExec("alter table t add index ia(a)")
+	tk.MustExec("insert into t values (4, 4)")
+
+	tk.MustQuery("select * from t where a > 1").Sort().Check(testkit.Rows("2 2", "3 3", "4 4"))
+	tk.MustQuery("select * from t where a > 2").Sort().Check(testkit.Rows("3 3", "4 4"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", tso))
+	tk.MustQuery("select * from t where a > 1").Sort().Check(testkit.Rows("2 2", "3 3"))
+	warnings := tk.Session().GetSessionVars().StmtCtx.GetWarnings()
+	require.Len(t, warnings, 1)
+	require.EqualError(t, warnings[0].Err, "skip plan-cache: tidb_snapshot is set")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select * from t where a > 2").Sort().Check(testkit.Rows("3 3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+
+	// The cached plan is still served after the snapshot is cleared.
+	tk.MustExec("set @@tidb_snapshot = ''")
+	tk.MustQuery("select * from t where a > 2").Sort().Check(testkit.Rows("3 3", "4 4"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestGeneralPlanCacheParamTemplate(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b varchar(10))")
+	tk.MustExec("insert into t values (1, 'x'), (2, 'y'), (3, 'z')")
+
+	tk.MustQuery("select a from t where a > 1 and b != 'y'").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select a from t where a > 0 and b != 'z'").Sort().Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	sql := "select a from t where a > 1 and b != 'y'"
+	t1 := plannercore.GetParamTemplate(tk.Session(), sql)
+	require.NotNil(t, t1)
+	require.Equal(t, "SELECT `a` FROM `test`.`t` WHERE `a`>? AND `b`!=?", t1.ParamSQL())
+	require.Equal(t, []string{"1", "'y'"}, t1.ParamTexts())
+	// The template is shared by the same text, and the params are copied out of it.
+	require.True(t, t1 == plannercore.GetParamTemplate(tk.Session(), sql))
+	params := t1.NewParams()
+	params[0].(*expression.Constant).Value.SetInt64(100)
+	require.Equal(t, int64(1), t1.NewParams()[0].(*expression.Constant).Value.GetInt64())
+	// The statements which can't be parameterized.
+	require.Nil(t, plannercore.GetParamTemplate(tk.Session(), "set @a = 1"))
+	require.Nil(t, plannercore.GetParamTemplate(tk.Session(), "select 1; select 2"))
+	require.Nil(t, plannercore.GetParamTemplate(tk.Session(), "select * frm t"))
+}
+
+func TestGeneralPlanCacheParamTemplateAcrossDBs(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("create database db1")
+	tk.MustExec("create database db2")
+	tk.MustExec("create table db1.t (a int)")
+	tk.MustExec("create table db2.t (a int, b int)")
+	tk.MustExec("insert into db1.t values (1), (2)")
+	tk.MustExec("insert into db2.t values (1, 10), (2, 20), (3, 30)")
+
+	sql := "select * from t where a > 1"
+	tk.MustExec("use db1")
+	t1 := plannercore.GetParamTemplate(tk.Session(), sql)
+	require.NotNil(t, t1)
+	require.Equal(t, "SELECT * FROM `db1`.`t` WHERE `a`>?", t1.ParamSQL())
+	tk.MustQuery(sql).Check(testkit.Rows("2"))
+	tk.MustQuery(sql).Check(testkit.Rows("2"))
+	// The original text is kept for the statement summary and logs.
+	require.Equal(t, sql, tk.Session().GetSessionVars().StmtCtx.OriginalSQL)
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The same text gets a different template in another database, and never reuses the plan of db1.t.
+	tk.MustExec("use db2")
+	t2 := plannercore.GetParamTemplate(tk.Session(), sql)
+	require.NotNil(t, t2)
+	require.Equal(t, "SELECT * FROM `db2`.`t` WHERE `a`>?", t2.ParamSQL())
+	tk.MustQuery(sql).Sort().Check(testkit.Rows("2 20", "3 30"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery(sql).Sort().Check(testkit.Rows("2 20", "3 30"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The qualified tables are kept as they are.
+	require.Equal(t, "SELECT * FROM `db1`.`t` WHERE `a`>?", plannercore.GetParamTemplate(tk.Session(), "select * from db1.t where a > 1").ParamSQL())
+	tk.MustExec("use db1")
+	tk.MustQuery(sql).Check(testkit.Rows("2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestGeneralPlanCacheRuntimeSelectLimit(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3), (4, 4)")
+
+	// The plan is shared by the different sql_select_limit, which is enforced at runtime.
+	tk.MustExec("set @@sql_select_limit = 1")
+	tk.MustQuery("select a from t where b > 1 order by a").Check(testkit.Rows("2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	cacheSize := tk.Session().GetPlanCache(true).Size()
+	tk.MustExec("set @@sql_select_limit = 2")
+	tk.MustQuery("select a from t where b > 0 order by a").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("set @@sql_select_limit = default")
+	tk.MustQuery("select a from t where b > 0 order by a").Check(testkit.Rows("1", "2", "3", "4"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("set @@sql_select_limit = 0")
+	tk.MustQuery("select a from t where b > 0 order by a").Check(testkit.Rows())
+	// The sql_select_limit truncates `select @@last_plan_from_cache` as well.
+	require.True(t, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
+	require.Equal(t, cacheSize, tk.Session().GetPlanCache(true).Size())
+
+	// The limit of the statement itself takes precedence over the sql_select_limit.
+	tk.MustExec("set @@sql_select_limit = 1")
+	tk.MustQuery("select a from t where b > 1 order by a limit 2").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select a from t where b > 0 order by a limit 2").Check(testkit.Rows("1", "2"))
+	// The point plans are limited too.
+	tk.MustExec("set @@sql_select_limit = 0")
+	tk.MustQuery("select b from t where a = 1").Check(testkit.Rows())
+	tk.MustExec("set @@sql_select_limit = default")
+	tk.MustQuery("select b from t where a = 2").Check(testkit.Rows("2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("set @@sql_select_limit = 0")
+	tk.MustQuery("select b from t where a = 3").Check(testkit.Rows())
+	require.True(t, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
+
+	// The max_execution_time isn't a part of the key either, it's enforced by the killer as usual.
+	tk.MustExec("set @@sql_select_limit = default")
+	tk.MustQuery("select a from t where b > 3").Check(testkit.Rows("4"))
+	tk.MustExec("set @@max_execution_time = 1000")
+	tk.MustQuery("select a from t where b > 2 order by a").Check(testkit.Rows("3", "4"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("set @@max_execution_time = 0")
+
+	// Another session with a different sql_select_limit gets its own truncated results.
+	tk2 := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk2.MustExec("set tidb_enable_general_plan_cache=1")
+	tk2.MustExec("use test")
+	tk2.MustExec("set @@sql_select_limit = 3")
+	tk2.MustQuery("select a from t where b > 0 order by a").Check(testkit.Rows("1", "2", "3"))
+	tk2.MustQuery("select a from t where b > 1 order by a").Check(testkit.Rows("2", "3", "4"))
+	tk2.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustQuery("select a from t where b > 0 order by a").Check(testkit.Rows("1", "2", "3", "4"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestGeneralPlanCacheMultiStatements(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	defer tk.Session().Close()
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("set tidb_multi_statement_mode='ON'")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert into t values (1, 0), (2, 0), (3, 0)")
+
+	// Each statement is parameterized independently, the templates never concatenate.
+	stmts, _, err := parser.New().ParseSQL("select a from t where b > 1; update t set b = 10 where a = 2")
+	require.NoError(t, err)
+	paramSQLs := make([]string, 0, len(stmts))
+	for _, stmt := range stmts {
+		paramSQL, _, _, err := plannercore.ParameterizeAST(tk.Session(), stmt)
+		require.NoError(t, err)
+		paramSQLs = append(paramSQLs, paramSQL)
+	}
+	require.Equal(t, []string{"SELECT `a` FROM `test`.`t` WHERE `b`>?", "UPDATE `test`.`t` SET `b`=? WHERE `a`=?"}, paramSQLs)
+
+	// The statements of the same template share the cached plan in the batch.
+	tk.Session().GetPlanCache(true).DeleteAll()
+	tk.MustExec("update t set b = 1 where a = 1; update t set b = 2 where a = 2")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	require.Equal(t, 1, tk.Session().GetPlanCache(true).Size())
+	tk.MustQuery("select * from t").Sort().Check(testkit.Rows("1 1", "2 2", "3 0"))
+	// The statements of different templates get their own cached plans, and hit them as single statements.
+	tk.Session().GetPlanCache(true).DeleteAll()
+	tk.MustExec("update t set b = 3 where a = 3; delete from t where a = 10")
+	require.Equal(t, 2, tk.Session().GetPlanCache(true).Size())
+	tk.MustExec("delete from t where a = 11")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("update t set b = 4 where a = 3")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustQuery("select * from t").Sort().Check(testkit.Rows("1 1", "2 2", "3 4"))
+
+	// The statement which can't be parameterized doesn't affect the cached plan of the statement before it.
+	tk.Session().GetPlanCache(true).DeleteAll()
+	tk.MustExec("update t set b = 5 where a = 1; update t set b = 6 where a = (select max(a) from t)")
+	require.Equal(t, 1, tk.Session().GetPlanCache(true).Size())
+	tk.MustQuery("select * from t").Sort().Check(testkit.Rows("1 5", "2 2", "3 6"))
+	tk.MustExec("update t set b = 7 where a = 2")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustQuery("select * from t").Sort().Check(testkit.Rows("1 5", "2 7", "3 6"))
+
+	// The statements are parameterized after the statements before them are executed.
+	tk.MustExec("create database test2")
+	tk.MustExec("create table test2.t (a int, b int)")
+	tk.MustExec("insert into test2.t values (1, 0)")
+	tk.MustExec("use test2; update t set b = 8 where a = 1; use test")
+	tk.MustQuery("select * from test2.t").Check(testkit.Rows("1 8"))
+	tk.MustQuery("select * from t where a = 1").Check(testkit.Rows("1 5"))
+}
+
+func TestRestoreSQLWithParams(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	sqls := []string{
+		"select * from t where a > 1 and b = 'x'",
+		"select a, b from t where a in (1, 2, 3) and b like 'a\\\\%' order by a limit 10",
+		"select * from t where a between -1 and 1.5 or b is null or c = 0x1a",
+		"select json_extract(j, '$.a') from t where a = 1e3 and b != 'it''s'",
+		"select count(*) from t t1 join t t2 on t1.a = t2.a where t1.b > 'y' group by t1.c having count(*) > 2",
+		"update t set a = 10, b = concat(b, 'z') where c < 5",
+		"delete from t where a = 1 and b = _latin1'x'",
+		"insert into t select * from t where a > 100",
+	}
+	flags := format.DefaultRestoreFlags | format.RestoreStringWithoutDefaultCharset
+	for _, sql := range sqls {
+		template := plannercore.GetParamTemplate(tk.Session(), sql)
+		require.NotNil(t, template, sql)
+		// The reconstructed SQL is the same as the original one restored.
+		stmt, err := parser.New().ParseOneStmt(sql, "", "")
+		require.NoError(t, err)
+		var sb strings.Builder
+		restoreCtx := format.NewRestoreCtx(flags, &sb)
+		restoreCtx.DefaultDB = "test"
+		require.NoError(t, stmt.Restore(restoreCtx))
+		// The template is parsed from the parameterized SQL like the cached statement.
+		templateStmt, err := parser.New().ParseOneStmt(template.ParamSQL(), "", "")
+		require.NoError(t, err)
+		restored, err := plannercore.RestoreSQLWithParams(tk.Session(), templateStmt, template.NewParams(), false)
+		require.NoError(t, err, sql)
+		require.Equal(t, sb.String(), restored, sql)
+
+		// The params are redacted.
+		redacted, err := plannercore.RestoreSQLWithParams(tk.Session(), templateStmt, template.NewParams(), true)
+		require.NoError(t, err, sql)
+		require.Equal(t, strings.Count(template.ParamSQL(), "?"), strings.Count(redacted, "?"), sql)
+		require.NotContains(t, redacted, "'x'", sql)
+		// The template isn't modified.
+		restoredAgain, err := plannercore.RestoreSQLWithParams(tk.Session(), templateStmt, template.NewParams(), false)
+		require.NoError(t, err)
+		require.Equal(t, restored, restoredAgain)
+	}
+}
+
+func TestGeneralPlanCacheSQLInStmtSummary(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	// Close the session to release its plan cache, which is evicted under the global memory pressure.
+	defer tk.Session().Close()
+	require.NoError(t, tk.Session().Auth(&auth.UserIdentity{Username: "root", Hostname: "%"}, nil, nil))
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b varchar(10))")
+	tk.MustExec("insert into t values (1, 'x'), (2, 'y')")
+	sampleText := func() [][]interface{} {
+		return tk.MustQuery("select query_sample_text from information_schema.statements_summary where digest_text like 'select `a` from `test` . `t` where%'").Rows()
+	}
+
+	tk.MustExec("set global tidb_enable_stmt_summary = 0")
+	tk.MustExec("set global tidb_enable_stmt_summary = 1")
+	tk.MustQuery("select a from t where a > 1 and b != 'x'").Check(testkit.Rows("2"))
+	require.Equal(t, [][]interface{}{{"select a from t where a > 1 and b != 'x'"}}, sampleText())
+
+	// The literals are redacted.
+	tk.MustExec("set global tidb_enable_stmt_summary = 0")
+	tk.MustExec("set global tidb_enable_stmt_summary = 1")
+	tk.MustExec("set tidb_redact_log = 1")
+	tk.MustQuery("select a from t where a > 0 and b != 'y'").Check(testkit.Rows("1"))
+	tk.MustExec("set tidb_redact_log = 0")
+	require.Equal(t, [][]interface{}{{"SELECT `a` FROM `test`.`t` WHERE `a`>? AND `b`!=?"}}, sampleText())
+}
+
+func TestSharedPlanCacheTemplate(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, key(a))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("prepare stmt from 'select b from t where a > ?'")
+	cacheSizes := func() (int, int) {
+		return tk.Session().GetPlanCache(true).Size(), tk.Session().GetPlanCache(false).Size()
+	}
+	clearCaches := func() {
+		tk.Session().GetPlanCache(true).DeleteAll()
+		tk.Session().GetPlanCache(false).DeleteAll()
+	}
+
+	// The prepared and the general statements are cached separately by default.
+	clearCaches()
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute stmt using @a").Sort().Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select b from t where a > 2").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	general, prepared := cacheSizes()
+	require.Equal(t, 1, general)
+	require.Equal(t, 1, prepared)
+
+	tk.MustExec("set tidb_enable_shared_plan_cache_template=1")
+	clearCaches()
+	planDigest := func() string {
+		_, digest := tk.Session().GetSessionVars().StmtCtx.GetPlanDigest()
+		return digest.String()
+	}
+	for i := 0; i < 3; i++ {
+		tk.MustExec("set @a = 1")
+		tk.MustQuery("execute stmt using @a").Sort().Check(testkit.Rows("2", "3"))
+		prepPlanDigest := planDigest()
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(map[bool]string{true: "0", false: "1"}[i == 0]))
+		tk.MustQuery("select b from t where a > 2").Check(testkit.Rows("3"))
+		// The plans are consistent.
+		require.Equal(t, prepPlanDigest, planDigest())
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	}
+	// The plan is shared by both of them.
+	general, prepared = cacheSizes()
+	require.Equal(t, 1, general)
+	require.Equal(t, 0, prepared)
+
+	// The params of different types are told apart, the types of the params of the prepared statement are known at
+	// prepare time, and the ones of the general statement are inferred from the literals.
+	tk.MustExec("set @a = '1'")
+	tk.MustQuery("execute stmt using @a").Sort().Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select b from t where a > 2").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The statements of different shapes aren't shared.
+	tk.MustQuery("select b from t where a > 2 and b > 0").Check(testkit.Rows("3"))
+	general, prepared = cacheSizes()
+	require.Equal(t, 2, general)
+	require.Equal(t, 0, prepared)
+
+	// Deallocating the statement doesn't evict the shared plans.
+	tk.MustExec("deallocate prepare stmt")
+	tk.MustQuery("select b from t where a > 2").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestGeneralPlanCachePointGet(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	defer tk.Session().Close()
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int, unique key(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+
+	for _, c := range []struct {
+		sql      string
+		operator string
+		// pointExec indicates whether the cached plan is executed by the point get short path of the session.
+		pointExec bool
+	}{
+		{"select * from t where a = %d", "Point_Get_", true},
+		{"select * from t where b = %d", "Point_Get_", false},
+		{"select * from t where a in (%d, 5)", "Batch_Point_Get_", false},
+		{"select a from t where b in (%d, 5)", "Batch_Point_Get_", false},
+	} {
+		for i := 1; i <= 3; i++ {
+			sql := fmt.Sprintf(c.sql, i)
+			tk.MustExec("set tidb_enable_general_plan_cache=0")
+			expected := tk.MustQuery(sql).Rows()
+			tk.MustExec("set tidb_enable_general_plan_cache=1")
+			tk.MustQuery(sql).Check(expected)
+			tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(map[bool]string{true: "0", false: "1"}[i == 1]))
+
+			// The cached plan keeps the point get operator, and it's shown in the process list like the uncached one.
+			tk.MustQuery(sql)
+			require.True(t, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
+			require.Equal(t, c.pointExec, tk.Session().GetSessionVars().StmtCtx.PointExec)
+			tkProcess := tk.Session().ShowProcess()
+			tk.Session().SetSessionManager(&testutil.MockSessionManager{PS: []*util.ProcessInfo{tkProcess}})
+			rows := tk.MustQuery(fmt.Sprintf("explain for connection %d", tkProcess.ID)).Rows()
+			require.Len(t, rows, 1, sql)
+			require.Regexp(t, "^"+c.operator, rows[0][0], sql)
+		}
+	}
+}
+
+func TestGeneralPlanCacheRangeColumnsPartition(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	defer tk.Session().Close()
+	tk.MustExec("use test")
+	tk.MustExec("set tidb_partition_prune_mode='dynamic'")
+	tk.MustExec(`create table t (id int, dt date) partition by range columns(dt) (
+		partition p0 values less than ('2022-01-01'),
+		partition p1 values less than ('2023-01-01'),
+		partition p2 values less than (maxvalue))`)
+	tk.MustExec("insert into t values (1, '2021-06-01'), (2, '2022-06-01'), (3, '2023-06-01')")
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+
+	// The string and the date literals share the template, but not the plans, since the params are in different types.
+	// The dates go from the first partition to all the partitions, the rows are missed if the partitions pruned for the
+	// first date are reused.
+	for _, sql := range []string{
+		"select id from t where dt < '%s'",
+		"select id from t where dt < date '%s'",
+	} {
+		for i, c := range []struct {
+			dt   string
+			rows []string
+		}{
+			{"2021-01-01", nil},
+			{"2022-01-01", []string{"1"}},
+			{"2023-01-01", []string{"1", "2"}},
+			{"2024-01-01", []string{"1", "2", "3"}},
+		} {
+			tk.MustQuery(fmt.Sprintf(sql, c.dt)).Sort().Check(testkit.Rows(c.rows...))
+			tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(map[bool]string{true: "0", false: "1"}[i == 0]))
+		}
+	}
+	tk.MustQuery("select id from t where dt between date '2022-01-01' and date '2022-12-31'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where dt between date '2023-01-01' and date '2023-12-31'").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The partitions are pruned by the cached plans in the static mode, the plans are never used in it.
+	tk.MustExec("set tidb_partition_prune_mode='static'")
+	tk.MustQuery("select id from t where dt < date '2023-01-01'").Sort().Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select id from t where dt < date '2022-01-01'").Check(testkit.Rows("1"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("set tidb_partition_prune_mode='dynamic'")
+	tk.MustQuery("select id from t where dt < date '2024-01-01'").Sort().Check(testkit.Rows("1", "2", "3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The partitions of the point gets are located when building the plans, the plans aren't cached.
+	tk.MustExec("create table t2 (id int primary key) partition by range (id) (partition p0 values less than (10), partition p1 values less than (20))")
+	tk.MustExec("insert into t2 values (1), (11)")
+	for _, id := range []string{"1", "11", "1"} {
+		tk.MustQuery("select id from t2 where id = " + id).Check(testkit.Rows(id))
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	}
+}
+
+func TestGeneralPlanCacheView(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	defer tk.Session().Close()
+	require.NoError(t, tk.Session().Auth(&auth.UserIdentity{Username: "root", Hostname: "%"}, nil, nil))
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int, b int)")
+	tk.MustExec("create table t2 (a int, c int)")
+	tk.MustExec("insert into t1 values (1, 10), (2, 20)")
+	tk.MustExec("insert into t2 values (1, 100), (2, 200)")
+	tk.MustExec("create view v as select t1.a, t1.b, t2.c from t1 join t2 on t1.a = t2.a")
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustQuery("select b, c from v where a = 1").Check(testkit.Rows("10 100"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select b, c from v where a = 2").Check(testkit.Rows("20 200"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// TiDB alters the views by replacing them, the plans built with the old definition are invalidated.
+	tk.MustExec("create or replace view v as select t1.a, t1.b, t2.c + 1 as c from t1 join t2 on t1.a = t2.a")
+	tk.MustQuery("select b, c from v where a = 1").Check(testkit.Rows("10 101"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustQuery("select b, c from v where a = 2").Check(testkit.Rows("20 201"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// So are the ones built with the old definitions of the nested views.
+	tk.MustExec("create view vv as select a, c from v")
+	tk.MustQuery("select c from vv where a = 1").Check(testkit.Rows("101"))
+	tk.MustQuery("select c from vv where a = 2").Check(testkit.Rows("201"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("create or replace view v as select t1.a, t1.b, t2.c + 2 as c from t1 join t2 on t1.a = t2.a")
+	tk.MustQuery("select c from vv where a = 1").Check(testkit.Rows("102"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+
+	// The views redefined over the tables whose plans can't be cached are checked again.
+	tk.MustExec("create table tp (a int, c int) partition by hash(a) partitions 2")
+	tk.MustExec("insert into tp values (1, 1000)")
+	tk.MustExec("prepare st from 'select c from vv where a = ?'")
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("102"))
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("102"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("create or replace view v as select a, c from tp")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("1000"))
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 skip plan-cache: partition_table"))
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("1000"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+}
+
+func TestParameterizeConcurrently(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	stmt, err := parser.New().ParseOneStmt("select * from t where a > 1 and b = 'x'", "", "")
+	require.NoError(t, err)
+
+	sqls := []string{
+		"select * from t where a > 1 and b = 'x'",
+		"select * from t where a in (1, 2, 3)",
+		"update t set a = 10 where b < 5",
+	}
+	paramSQLs := []string{
+		"SELECT * FROM `test`.`t` WHERE `a`>? AND `b`=?",
+		"SELECT * FROM `test`.`t` WHERE `a` IN (?,?,?)",
+		"UPDATE `test`.`t` SET `a`=? WHERE `b`<?",
+	}
+	restoredSQLs := []string{
+		"SELECT * FROM `test`.`t` WHERE `a`>1 AND `b`='x'",
+		"SELECT * FROM `test`.`t` WHERE `a` IN (1,2,3)",
+		"UPDATE `test`.`t` SET `a`=10 WHERE `b`<5",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				k := (i + j) % len(sqls)
+				paramSQL, params, paramTexts, ok, err := plannercore.Parameterize(tk.Session(), sqls[k])
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, paramSQLs[k], paramSQL)
+				require.Equal(t, len(params), len(paramTexts))
+				require.Equal(t, restoredSQLs[k], plannercore.RestoreParamTexts(paramSQL, paramTexts))
+				// The statement being executed is never modified.
+				require.Equal(t, "SELECT * FROM `test`.`t` WHERE `a`>? AND `b`=?", plannercore.GetPlanCacheTemplate(tk.Session(), stmt))
+			}
+		}(i)
+	}
+	wg.Wait()
+	var sb strings.Builder
+	require.NoError(t, stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)))
+	require.Equal(t, "SELECT * FROM `t` WHERE `a`>1 AND `b`=_UTF8MB4'x'", sb.String())
+}
+
+func TestPlanCacheEvictionReasons(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	metrics.PlanCacheEvictionCounter.Reset()
+	evictions := func(reason plannercore.PlanCacheEvictReason) float64 {
+		pb := &dto.Metric{}
+		require.NoError(t, metrics.PlanCacheEvictionCounter.WithLabelValues(string(reason)).Write(pb))
+		return pb.GetCounter().GetValue()
+	}
+
+	tk.MustExec("set tidb_prepared_plan_cache_size=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, key(a))")
+	tk.MustExec("prepare st from 'select a from t where a = ?'")
+	tk.MustExec("set @a = 1")
+	tk.MustExec("execute st using @a")
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// DDL on other tables doesn't touch the plan.
+	tk.MustExec("create table t1 (a int)")
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	require.Equal(t, float64(0), evictions(plannercore.EvictReasonSchemaVersion))
+
+	// It can't be told whether the plan is touched without the history of the schema changes.
+	dom.SchemaValidator.Reset()
+	tk.MustExec("create table t2 (a int)")
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	require.Equal(t, float64(1), evictions(plannercore.EvictReasonSchemaVersion))
+	require.Equal(t, float64(0), evictions(plannercore.EvictReasonDDL))
+
+	tk.MustExec("alter table t add column c int")
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	require.Equal(t, float64(1), evictions(plannercore.EvictReasonDDL))
+
+	tk.MustExec("create session binding for select a from t where a = 1 using select a from t use index(a) where a = 1")
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	require.Equal(t, float64(1), evictions(plannercore.EvictReasonBinding))
+
+	tk.MustExec("admin flush session plan_cache")
+	require.Equal(t, float64(1), evictions(plannercore.EvictReasonFlush))
+
+	tk.MustExec("execute st using @a")
+	tk.MustExec("prepare st1 from 'select b from t where a = ?'")
+	tk.MustExec("execute st1 using @a")
+	require.Equal(t, float64(1), evictions(plannercore.EvictReasonLRU))
+
+	tk.MustQuery("select reason, db, stmt_text from information_schema.plan_cache_evictions where cache_type = 'prepared'").Check(testkit.Rows(
+		"schema_version test select a from t where a = ?",
+		"ddl test select a from t where a = ?",
+		"binding_change test select a from t where a = ?",
+		"flush test select a from t where a = ?",
+		"lru test select a from t where a = ?",
+	))
+	// The evictions are recorded per session.
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustQuery("select count(*) from information_schema.plan_cache_evictions").Check(testkit.Rows("0"))
+}
+
+func TestPlanCacheEvictedUnderMemoryPressure(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, key(a))")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk.MustExec("set @a = 1")
+	cache := tk.Session().GetPlanCache(false).(*plannercore.LRUPlanCache)
+	const stmts = 20
+	for i := 0; i < stmts; i++ {
+		tk.MustExec(fmt.Sprintf("prepare st%d from 'select b from t where a = ? and b > %d'", i, i))
+		tk.MustExec(fmt.Sprintf("execute st%d using @a", i))
+	}
+	require.Equal(t, stmts, cache.Size())
+
+	// The server memory quota is exceeded mainly by the plan cache, the plans are evicted instead of killing the query.
+	limit := executor.GlobalMemoryUsageTracker.BytesConsumed() - cache.MemoryUsage()/2
+	executor.GlobalMemoryUsageTracker.SetBytesLimit(limit)
+	defer executor.GlobalMemoryUsageTracker.SetBytesLimit(-1)
+	tk.MustExec("prepare st from 'select a from t where a = ?'")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("1"))
+	require.Less(t, executor.GlobalMemoryUsageTracker.BytesConsumed(), limit)
+	require.Less(t, cache.Size(), stmts)
+	require.Greater(t, cache.Size(), 0)
+	rows := tk.MustQuery("select count(*) from information_schema.plan_cache_evictions where reason = 'memory_pressure'").Rows()
+	evicted, err := strconv.Atoi(rows[0][0].(string))
+	require.NoError(t, err)
+	require.Greater(t, evicted, 0)
+	// The least recently used plans are evicted.
+	tk.MustExec("execute st0 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("execute st using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestPlanCacheSurviveSchemaVersionBump(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int, b int, key(a))")
+	tk.MustExec("create table t2 (a int, b int, key(a))")
+	tk.MustExec("prepare st1 from 'select b from t1 where a > ?'")
+	tk.MustExec("prepare st2 from 'select t2.b from t1 join t2 on t1.a = t2.a where t2.a > ?'")
+	tk.MustExec("prepare st3 from 'insert into t2 values (?, ?)'")
+	tk.MustExec("set @a = 1, @b = 2")
+	mustHit := func(hit bool) {
+		tk.MustExec("execute st1 using @a")
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+		expected := "0"
+		if hit {
+			expected = "1"
+		}
+		tk.MustExec("execute st2 using @a")
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(expected))
+		tk.MustExec("execute st3 using @a, @b")
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(expected))
+	}
+	for _, sql := range []string{"st1", "st2"} {
+		tk.MustExec("execute " + sql + " using @a")
+	}
+	tk.MustExec("execute st3 using @a, @b")
+	mustHit(true)
+
+	// The plans of t1 survive the DDL on other tables, the plans of t2 are invalidated.
+	tk.MustExec("create table t3 (a int)")
+	mustHit(true)
+	tk.MustExec("alter table t2 add index b(b)")
+	mustHit(false)
+	mustHit(true)
+
+	// After a schema rollback, the plans are rebuilt on their first uses, rather than purged at once.
+	evicted := tk.MustQuery("select count(*) from information_schema.plan_cache_evictions").Rows()
+	dom.NotifySchemaRollback(dom.InfoSchema().SchemaMetaVersion(), 0)
+	tk.MustExec("execute st1 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("execute st1 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("execute st2 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("execute st2 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustQuery("select count(*) from information_schema.plan_cache_evictions").Check(evicted)
+}
+
+func TestPlanCacheNonDeterministicExprs(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b datetime)")
+	tk.MustExec("insert into t values (1, '2022-01-01 00:00:00'), (2, '2022-01-02 00:00:00'), (3, '2022-01-03 00:00:00')")
+
+	// The user variables are read on each execution instead of being folded into the plan.
+	tk.MustExec("prepare st from 'select a from t where a = @uv'")
+	tk.MustExec("set @uv = 1")
+	tk.MustQuery("execute st").Check(testkit.Rows("1"))
+	tk.MustExec("set @uv = 2")
+	tk.MustQuery("execute st").Check(testkit.Rows("2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	// The plan is rebuilt once the type of the variable changes.
+	tk.MustExec("set @uv = '3'")
+	tk.MustQuery("execute st").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("set @uv = '1'")
+	tk.MustQuery("execute st").Check(testkit.Rows("1"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The assignments of user variables and the reads of system variables are never cached.
+	tk.MustExec("prepare st from 'select a, @uv := a from t where a = 1'")
+	tk.MustExec("execute st")
+	tk.MustExec("execute st")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("prepare st from 'select a from t where a = @@tidb_max_chunk_size'")
+	tk.MustExec("execute st")
+	tk.MustExec("execute st")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+
+	// `now()` is evaluated at execution time, the mocked time is changed by @@timestamp.
+	tk.MustExec("prepare st from 'select a from t where b > now() - interval 1 day'")
+	tk.MustExec("set @@timestamp = unix_timestamp('2022-01-02 12:00:00')")
+	tk.MustQuery("execute st").Check(testkit.Rows("2", "3"))
+	tk.MustExec("set @@timestamp = unix_timestamp('2022-01-03 12:00:00')")
+	tk.MustQuery("execute st").Check(testkit.Rows("3"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+}
+
+func TestPlanCacheOutputSchemaChanged(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert into t values (1, 2)")
+	tk.MustExec("prepare st from 'select * from t'")
+	tk.MustQuery("execute st").Check(testkit.Rows("1 2"))
+	tk.MustQuery("execute st").Check(testkit.Rows("1 2"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	fieldNames := func() []string {
+		rs, err := tk.Exec("execute st")
+		require.NoError(t, err)
+		names := make([]string, 0, len(rs.Fields()))
+		for _, field := range rs.Fields() {
+			names = append(names, field.Column.Name.L)
+		}
+		require.NoError(t, rs.Close())
+		return names
+	}
+	tk.MustExec("alter table t rename column a to c")
+	require.Equal(t, []string{"c", "b"}, fieldNames())
+	tk.MustQuery("execute st").Check(testkit.Rows("1 2"))
+	tk.MustExec("alter table t modify column b int first")
+	require.Equal(t, []string{"b", "c"}, fieldNames())
+	tk.MustQuery("execute st").Check(testkit.Rows("2 1"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestPlanCacheKeyWithOptimizerVars(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t1 (a int, b int)")
+	tk.MustExec("create table t2 (a int, b int)")
+	tk.MustExec("insert into t1 values (1, 1), (2, 2)")
+	tk.MustExec("insert into t2 values (1, 1), (1, 2)")
+	tk.MustExec("prepare st from 'select /*+ hash_join(t1, t2) */ sum(t1.b) from t1 join t2 on t1.a = t2.a where t1.a > ?'")
+	tk.MustExec("set @a = 0")
+
+	plan := func() string {
+		execute, ok := tk.Session().ShowProcess().Plan.(*plannercore.Execute)
+		require.True(t, ok)
+		return plannercore.ToString(execute.Plan)
+	}
+	tk.MustExec("set tidb_opt_agg_push_down = 0")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("2"))
+	withoutPushDown := plan()
+	tk.MustExec("set tidb_opt_agg_push_down = 1")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("2"))
+	withPushDown := plan()
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	require.NotEqual(t, withoutPushDown, withPushDown)
+
+	// The plans built under both settings are kept in the cache.
+	require.Equal(t, 2, tk.Session().GetPlanCache(false).Size())
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("2"))
+	require.Equal(t, withPushDown, plan())
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+	tk.MustExec("set tidb_opt_agg_push_down = 0")
+	tk.MustQuery("execute st using @a").Check(testkit.Rows("2"))
+	require.Equal(t, withoutPushDown, plan())
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestPlanCacheBetweenEqualParams(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int, b int, unique key(a))")
+	tk.MustExec("insert into t values (100, 1), (150, 2), (200, 3)")
+	tk.MustExec("prepare st from 'select * from t where a between ? and ?'")
+
+	plan := func() string {
+		execute, ok := tk.Session().ShowProcess().Plan.(*plannercore.Execute)
+		require.True(t, ok)
+		return plannercore.ToString(execute.Plan)
+	}
+	// BETWEEN with equal bounds is built as a point range.
+	tk.MustExec("set @a = 100, @b = 100")
+	tk.MustQuery("execute st using @a, @b").Check(testkit.Rows("100 1"))
+	require.True(t, strings.HasPrefix(plan(), "PointGet"))
+	tk.MustQuery("execute st using @a, @b").Check(testkit.Rows("100 1"))
+	require.True(t, strings.HasPrefix(plan(), "PointGet"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The point plan can't be reused once the bounds differ, it falls back to the range plan.
+	tk.MustExec("set @b = 200")
+	tk.MustQuery("execute st using @a, @b").Check(testkit.Rows("100 1", "150 2", "200 3"))
+	require.True(t, strings.HasPrefix(plan(), "IndexLookUp"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+	tk.MustExec("set @a = 150")
+	tk.MustQuery("execute st using @a, @b").Check(testkit.Rows("150 2", "200 3"))
+	require.True(t, strings.HasPrefix(plan(), "IndexLookUp"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestPlanCacheTxnMode(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (id int primary key, a int, v int, key(a))")
+	tk.MustExec("insert into t values (1, 1, 0), (2, 2, 0)")
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec("use test")
+	tk.MustExec("prepare st1 from 'select * from t where id = ? for update'")
+	tk.MustExec("prepare st2 from 'select * from t where a = ? for update'")
+	tk.MustExec("prepare st3 from 'update t set v = v + 1 where id = ?'")
+	tk.MustExec("set @a = 1")
+	mismatches := func() float64 {
+		pb := &dto.Metric{}
+		require.NoError(t, metrics.PlanCacheMissCounter.WithLabelValues("txn_mismatch").Write(pb))
+		return pb.GetCounter().GetValue()
+	}
+	// mustLock checks whether the row is locked by the transaction of tk.
+	mustLock := func(locked bool) {
+		tk2.MustExec("begin pessimistic")
+		err := tk2.ExecToErr("select * from t where id = 1 for update nowait")
+		if locked {
+			require.ErrorContains(t, err, "lock")
+		} else {
+			require.NoError(t, err)
+		}
+		tk2.MustExec("rollback")
+	}
+
+	cases := []struct {
+		isolation string
+		begin     string
+		locked    bool
+	}{
+		{"REPEATABLE-READ", "", false},
+		{"REPEATABLE-READ", "begin optimistic", false},
+		{"REPEATABLE-READ", "begin pessimistic", true},
+		{"READ-COMMITTED", "begin pessimistic", true},
+		{"REPEATABLE-READ", "begin pessimistic", true},
+		{"REPEATABLE-READ", "begin optimistic", false},
+	}
+	for i, c := range cases {
+		tk.MustExec(fmt.Sprintf("set transaction_isolation = '%s'", c.isolation))
+		for _, st := range []string{"st1", "st2", "st3"} {
+			before := mismatches()
+			// The plan is rebuilt once the transaction context changes, and reused after that.
+			for j, hit := range []string{"0", "1"} {
+				if c.begin != "" {
+					tk.MustExec(c.begin)
+				}
+				tk.MustExec("execute " + st + " using @a")
+				if i > 0 || j > 0 {
+					tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(hit))
+				}
+				if c.begin != "" {
+					mustLock(c.locked)
+					tk.MustExec("rollback")
+				}
+			}
+			if i > 0 {
+				require.Equal(t, before+1, mismatches())
+			}
+		}
+	}
+
+	// The point plan built in the autocommit transaction is kept, it's reused once back to autocommit.
+	tk.MustExec("execute st1 using @a")
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
 func TestInitLRUWithSystemVar(t *testing.T) {
 	store := testkit.CreateMockStore(t)
 	tk := testkit.NewTestKit(t, store)
@@ -107,3 +1135,204 @@ func TestInitLRUWithSystemVar(t *testing.T) {
 	lru := plannercore.NewLRUPlanCache(uint(sessionVar.PreparedPlanCacheSize), 0, 0, plannercore.PickPlanFromBucket)
 	require.NotNil(t, lru)
 }
+
+func TestCreateBindingFromPlanCache(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	tk.MustExec("set tidb_enable_general_plan_cache=1")
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int, c int, key ib(b), key ic(c))")
+	tk.MustExec("insert into t values (1, 1, 1), (2, 2, 2), (3, 3, 3)")
+	queryDigest := func(sql string, rows ...string) string {
+		tk.MustQuery(sql).Check(testkit.Rows(rows...))
+		_, digest := tk.Session().GetSessionVars().StmtCtx.GetPlanDigest()
+		require.NotNil(t, digest)
+		return digest.String()
+	}
+
+	// The binding is created from the cached point get template.
+	digest := queryDigest("select * from t where a = 1", "1 1 1")
+	tk.MustExec(fmt.Sprintf("create binding from plan cache digest '%s'", digest))
+	rows := tk.MustQuery("show session bindings").Rows()
+	require.Len(t, rows, 1)
+	require.Equal(t, "select * from `test` . `t` where `a` = ?", rows[0][0])
+	require.Equal(t, "SELECT /*+ use_index(@`sel_1` `test`.`t` `primary`)*/ * FROM `test`.`t` WHERE `a` = ?", rows[0][1])
+	tk.MustExec("admin flush session plan_cache")
+	require.Equal(t, digest, queryDigest("select * from t where a = 2", "2 2 2"))
+
+	// The binding is followed by the executions after the plan cache is flushed.
+	digest = queryDigest("select a from t where b = 1 and c = 1", "1")
+	tk.MustExec(fmt.Sprintf("create binding from plan cache digest '%s'", digest))
+	rows = tk.MustQuery("show session bindings").Sort().Rows()
+	require.Len(t, rows, 2)
+	require.Equal(t, "select `a` from `test` . `t` where `b` = ? and `c` = ?", rows[1][0])
+	tk.MustExec("admin flush session plan_cache")
+	require.Equal(t, digest, queryDigest("select a from t where b = 2 and c = 2", "2"))
+	tk.MustQuery("select @@last_plan_from_binding").Check(testkit.Rows("1"))
+	require.Equal(t, digest, queryDigest("select a from t where b = 3 and c = 3", "3"))
+	tk.MustQuery("select @@last_plan_from_cache, @@last_plan_from_binding").Check(testkit.Rows("1 1"))
+
+	// The plans cached by the other sessions of the instance can be found too.
+	digest = queryDigest("select b from t where c = 1", "1")
+	tk2 := testkit.NewTestKit(t, store)
+	tk2.MustExec(fmt.Sprintf("create global binding from plan cache digest '%s'", digest))
+	rows = tk2.MustQuery("show global bindings").Rows()
+	require.Len(t, rows, 1)
+	require.Equal(t, "select `b` from `test` . `t` where `c` = ?", rows[0][0])
+	tk2.MustExec("drop global binding for select b from test.t where c = 1")
+
+	// The errors of the digest which isn't cached and the plan which can't be reproduced by hints.
+	tk.MustContainErrMsg("create binding from plan cache digest 'abc'", "can't find the plan digest 'abc' in the plan cache")
+	tk.MustContainErrMsg("create binding from plan cache digest ''", "can't find the plan digest '' in the plan cache")
+	tk.MustExec("prepare st from 'select 1'")
+	tk.MustExec("execute st")
+	_, dualDigest := tk.Session().GetSessionVars().StmtCtx.GetPlanDigest()
+	tk.MustContainErrMsg(fmt.Sprintf("create binding from plan cache digest '%s'", dualDigest.String()), "can't be reproduced by hints")
+}
+
+func TestPlanCacheStatusConcurrentSessions(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3)")
+	timeSafe := time.Now().Add(-48 * 60 * 60 * time.Second).Format("20060102-15:04:05 -0700 MST")
+	tk.MustExec(fmt.Sprintf(`INSERT HIGH_PRIORITY INTO mysql.tidb VALUES ('tikv_gc_safe_point', '%[1]s', '')
+			       ON DUPLICATE KEY
+			       UPDATE variable_value = '%[1]s'`, timeSafe))
+	ver, err := store.CurrentVersion(kv.GlobalTxnScope)
+	require.NoError(t, err)
+
+	// The sessions run the cached statements concurrently, the status of each execution is only decided by its own
+	// session, the stale read sessions skip the plan cache.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		stale := i%2 == 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk := testkit.NewTestKit(t, store)
+			tk.MustExec("use test")
+			if stale {
+				tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", ver.Ver))
+			}
+			tk.MustExec("prepare stmt from 'select b from t where a > ?'")
+			for j := 0; j < 20; j++ {
+				tk.MustExec(fmt.Sprintf("set @a = %d", j%3))
+				tk.MustQuery("execute stmt using @a")
+				expected := stmtctx.PlanCacheHit
+				if stale {
+					expected = stmtctx.PlanCacheSkip
+				} else if j == 0 {
+					expected = stmtctx.PlanCacheMiss
+				}
+				require.Equal(t, expected, tk.Session().GetSessionVars().StmtCtx.GetPlanCacheStatus())
+				tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(map[bool]string{true: "1", false: "0"}[expected == stmtctx.PlanCacheHit]))
+				require.Equal(t, stmtctx.PlanCacheNotUsed, tk.Session().GetSessionVars().StmtCtx.GetPlanCacheStatus())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The status of the last execution is shown in the statements summary.
+	require.NoError(t, tk.Session().Auth(&auth.UserIdentity{Username: "root", Hostname: "%"}, nil, nil))
+	tk.MustExec("prepare stmt from 'select a from t where b = ?'")
+	tk.MustExec("set @b = 1")
+	tk.MustQuery("execute stmt using @b")
+	tk.MustQuery("select plan_cache_status from information_schema.statements_summary where digest_text = 'select `a` from `t` where `b` = ?'").Check(testkit.Rows("miss"))
+	tk.MustQuery("execute stmt using @b")
+	tk.MustQuery("select plan_cache_status, plan_cache_hits from information_schema.statements_summary where digest_text = 'select `a` from `t` where `b` = ?'").Check(testkit.Rows("hit 1"))
+}
+
+func TestPlanCacheRecheckPrivileges(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t (a int primary key, b int)")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk.MustExec("create user 'u1'@'%'")
+	tk.MustExec("grant select on test.t to 'u1'@'%'")
+	tk.MustExec("create role 'r1'")
+	tk.MustExec("grant select on test.t to 'r1'")
+	tk.MustExec("grant 'r1' to 'u1'@'%'")
+
+	tk1 := testkit.NewTestKit(t, store)
+	require.NoError(t, tk1.Session().Auth(&auth.UserIdentity{Username: "u1", Hostname: "%"}, nil, nil))
+	tk1.MustExec("use test")
+	tk1.MustExec("prepare stmt1 from 'select b from t where a = ?'")
+	tk1.MustExec("prepare stmt2 from 'select b from t where a > ?'")
+	tk1.MustExec("set @a = 1")
+	for i := 0; i < 2; i++ {
+		tk1.MustQuery("execute stmt1 using @a").Check(testkit.Rows("1"))
+		tk1.MustQuery("execute stmt2 using @a").Check(testkit.Rows("2"))
+	}
+	tk1.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The privileges granted by the active role are enough to reuse the cached plans.
+	tk.MustExec("revoke select on test.t from 'u1'@'%'")
+	tk1.MustExec("set role 'r1'")
+	tk1.MustQuery("execute stmt1 using @a").Check(testkit.Rows("1"))
+	tk1.MustQuery("execute stmt2 using @a").Check(testkit.Rows("2"))
+	tk1.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The cached plans can't be reused once the privileges are gone.
+	tk1.MustExec("set role none")
+	tk1.MustGetErrCode("execute stmt1 using @a", mysql.ErrTableaccessDenied)
+	tk1.MustGetErrCode("execute stmt2 using @a", mysql.ErrTableaccessDenied)
+	tk1.MustExec("set role 'r1'")
+	tk.MustExec("revoke select on test.t from 'r1'")
+	tk1.MustGetErrCode("execute stmt1 using @a", mysql.ErrTableaccessDenied)
+	tk1.MustGetErrCode("execute stmt2 using @a", mysql.ErrTableaccessDenied)
+
+	// The plans are reused again after the privileges are granted back.
+	tk.MustExec("grant select on test.t to 'u1'@'%'")
+	tk1.MustQuery("execute stmt1 using @a").Check(testkit.Rows("1"))
+	tk1.MustQuery("execute stmt2 using @a").Check(testkit.Rows("2"))
+	tk1.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+}
+
+func TestPlanCachePartitionDDL(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec(`create table employees (id int, hired date, store_id int, key(store_id)) partition by range (year(hired)) (
+		partition p0 values less than (1991),
+		partition p1 values less than (1996),
+		partition p2 values less than (2001),
+		partition p3 values less than (2006))`)
+	tk.MustExec("create table t (a int, key(a))")
+	tk.MustExec("insert into employees values (1, '1990-01-01', 1), (2, '1995-01-01', 1), (3, '2000-01-01', 1), (4, '2005-01-01', 1)")
+	tkGeneral := testkit.NewTestKitWithGeneralPlanCache(t, store)
+	defer tkGeneral.Session().Close()
+	tkGeneral.MustExec("use test")
+	tkGeneral.MustExec("set tidb_partition_prune_mode='dynamic'")
+	tkGeneral.MustExec("set tidb_enable_general_plan_cache=1")
+	mustQuery := func(hit bool, rows ...string) {
+		tkGeneral.MustQuery("select id from employees where store_id = 1").Sort().Check(testkit.Rows(rows...))
+		tkGeneral.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(map[bool]string{true: "1", false: "0"}[hit]))
+	}
+	mustQuery(false, "1", "2", "3", "4")
+	mustQuery(true, "1", "2", "3", "4")
+	tkGeneral.MustQuery("select a from t where a > 1").Check(testkit.Rows())
+	tkGeneral.MustQuery("select a from t where a > 1").Check(testkit.Rows())
+	tkGeneral.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// The plans reading the table are rebuilt after the partitions are added, the rows in the new partition are
+	// returned, the plans of the other tables survive.
+	tk.MustExec("alter table employees add partition (partition p4 values less than (2011))")
+	tk.MustExec("insert into employees values (5, '2010-01-01', 1)")
+	mustQuery(false, "1", "2", "3", "4", "5")
+	mustQuery(true, "1", "2", "3", "4", "5")
+	tkGeneral.MustQuery("select a from t where a > 1").Check(testkit.Rows())
+	tkGeneral.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+
+	// So are they after the partitions are dropped or truncated.
+	tk.MustExec("alter table employees drop partition p0")
+	mustQuery(false, "2", "3", "4", "5")
+	mustQuery(true, "2", "3", "4", "5")
+	tk.MustExec("alter table employees truncate partition p1")
+	mustQuery(false, "3", "4", "5")
+	mustQuery(true, "3", "4", "5")
+	tk.MustExec("insert into employees values (6, '1994-01-01', 1)")
+	mustQuery(true, "3", "4", "5", "6")
+}
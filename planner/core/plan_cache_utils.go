@@ -15,18 +15,26 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"hash/fnv"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/auth"
+	"github.com/pingcap/tidb/parser/format"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/types"
@@ -35,6 +43,7 @@ import (
 	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/hint"
 	"github.com/pingcap/tidb/util/kvcache"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/stringutil"
 	atomic2 "go.uber.org/atomic"
 	"golang.org/x/exp/slices"
@@ -63,8 +72,10 @@ func (e *paramMarkerExtractor) Leave(in ast.Node) (ast.Node, bool) {
 	return in, true
 }
 
-// GeneratePlanCacheStmtWithAST generates the PlanCacheStmt structure for this AST.
-func GeneratePlanCacheStmtWithAST(ctx context.Context, sctx sessionctx.Context, stmt ast.StmtNode) (*PlanCacheStmt, Plan, int, error) {
+// GeneratePlanCacheStmtWithAST generates the PlanCacheStmt structure for this AST, the isGeneralStmt is whether the
+// statement is parameterized by the general plan cache.
+func GeneratePlanCacheStmtWithAST(ctx context.Context, sctx sessionctx.Context, isGeneralStmt bool,
+	stmt ast.StmtNode) (*PlanCacheStmt, Plan, int, error) {
 	vars := sctx.GetSessionVars()
 	var extractor paramMarkerExtractor
 	stmt.Accept(&extractor)
@@ -113,17 +124,32 @@ func GeneratePlanCacheStmtWithAST(ctx context.Context, sctx sessionctx.Context,
 	var (
 		normalizedSQL4PC, digest4PC string
 		selectStmtNode              ast.StmtNode
+		readsPartitionTable         bool
+		readsView                   bool
 	)
 	if !vars.EnablePreparedPlanCache {
 		prepared.UseCache = false
 	} else {
-		prepared.UseCache = CacheableWithCtx(sctx, stmt, ret.InfoSchema)
+		checker := checkCacheable(sctx, stmt, ret.InfoSchema, isGeneralStmt)
+		prepared.UseCache, readsPartitionTable, readsView = checker.cacheable, checker.hasPartitionTable, checker.hasView
+		if !prepared.UseCache {
+			vars.StmtCtx.AppendWarning(errors.Errorf("skip plan-cache: %s", checker.reason))
+		}
 		selectStmtNode, normalizedSQL4PC, digest4PC, err = ExtractSelectAndNormalizeDigest(stmt, vars.CurrentDB)
 		if err != nil || selectStmtNode == nil {
 			normalizedSQL4PC = ""
 			digest4PC = ""
 		}
 	}
+	// The tables of the preprocessed statement are qualified, so the restored text is the same for the prepared and
+	// the general statements of the same shape.
+	var paramSQL string
+	if prepared.UseCache {
+		var sb strings.Builder
+		if err := stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err == nil {
+			paramSQL = sb.String()
+		}
+	}
 
 	// We try to build the real statement of preparedStmt.
 	for i := range prepared.Params {
@@ -143,6 +169,7 @@ func GeneratePlanCacheStmtWithAST(ctx context.Context, sctx sessionctx.Context,
 		PreparedAst:         prepared,
 		StmtDB:              vars.CurrentDB,
 		StmtText:            stmt.Text(),
+		ParamSQL:            paramSQL,
 		VisitInfos:          destBuilder.GetVisitInfo(),
 		NormalizedSQL:       normalizedSQL,
 		SQLDigest:           digest,
@@ -150,6 +177,10 @@ func GeneratePlanCacheStmtWithAST(ctx context.Context, sctx sessionctx.Context,
 		SnapshotTSEvaluator: ret.SnapshotTSEvaluator,
 		NormalizedSQL4PC:    normalizedSQL4PC,
 		SQLDigest4PC:        digest4PC,
+		ReadOnly:            ast.IsReadOnly(stmt),
+		ReadsPartitionTable: readsPartitionTable,
+		ReadsView:           readsView,
+		isGeneralStmt:       isGeneralStmt,
 	}
 	if err = CheckPreparedPriv(sctx, preparedObj, ret.InfoSchema); err != nil {
 		return nil, nil, 0, err
@@ -195,6 +226,8 @@ type planCacheKey struct {
 	inRestrictedSQL          bool
 	restrictedReadOnly       bool
 	TiDBSuperReadOnly        bool
+	// optimizerVarsHash is the hash of the optimizer variables of the session, e.g. `tidb_opt_agg_push_down`.
+	optimizerVarsHash uint64
 
 	hash []byte
 }
@@ -204,7 +237,7 @@ func (key *planCacheKey) Hash() []byte {
 	if len(key.hash) == 0 {
 		var (
 			dbBytes    = hack.Slice(key.database)
-			bufferSize = len(dbBytes) + 8*6 + 3*8
+			bufferSize = len(dbBytes) + 8*7 + 3*8
 		)
 		if key.hash == nil {
 			key.hash = make([]byte, 0, bufferSize)
@@ -230,6 +263,7 @@ func (key *planCacheKey) Hash() []byte {
 		key.hash = append(key.hash, hack.Slice(strconv.FormatBool(key.inRestrictedSQL))...)
 		key.hash = append(key.hash, hack.Slice(strconv.FormatBool(key.restrictedReadOnly))...)
 		key.hash = append(key.hash, hack.Slice(strconv.FormatBool(key.TiDBSuperReadOnly))...)
+		key.hash = codec.EncodeUint(key.hash, key.optimizerVarsHash)
 	}
 	return key.hash
 }
@@ -250,6 +284,13 @@ func SetPstmtIDSchemaVersion(key kvcache.Key, stmtText string, schemaVersion int
 	psStmtKey.hash = psStmtKey.hash[:0]
 }
 
+// equalsIgnoringSchemaVersion checks whether the keys are equal except the schema versions.
+func (key *planCacheKey) equalsIgnoringSchemaVersion(other *planCacheKey) bool {
+	k := *key
+	k.schemaVersion, k.lastUpdatedSchemaVersion, k.hash = other.schemaVersion, other.lastUpdatedSchemaVersion, nil
+	return bytes.Equal(k.Hash(), other.Hash())
+}
+
 // NewPlanCacheKey creates a new planCacheKey object.
 // Note: lastUpdatedSchemaVersion will only be set in the case of rc or for update read in order to
 // differentiate the cache key. In other cases, it will be 0.
@@ -282,6 +323,7 @@ func NewPlanCacheKey(sessionVars *variable.SessionVars, stmtText, stmtDB string,
 		inRestrictedSQL:          sessionVars.InRestrictedSQL,
 		restrictedReadOnly:       variable.RestrictedReadOnly.Load(),
 		TiDBSuperReadOnly:        variable.VarTiDBSuperReadOnly.Load(),
+		optimizerVarsHash:        sessionVars.OptimizerVarsHash4PC(),
 	}
 	for k, v := range sessionVars.IsolationReadEngines {
 		key.isolationReadEngines[k] = v
@@ -289,6 +331,21 @@ func NewPlanCacheKey(sessionVars *variable.SessionVars, stmtText, stmtDB string,
 	return key, nil
 }
 
+// newStmtPlanCacheKey creates the key of the statement in the plan cache. The sql_select_limit isn't a part of the
+// keys in the general plan cache, since it's enforced at runtime, so the plans are shared among the different limits.
+func newStmtPlanCacheKey(sessVars *variable.SessionVars, isGeneralPlanCache bool, stmt *PlanCacheStmt,
+	latestSchemaVersion int64, bindSQL string) (kvcache.Key, error) {
+	key, err := NewPlanCacheKey(sessVars, stmt.planCacheKeyText(isGeneralPlanCache), stmt.StmtDB,
+		stmt.PreparedAst.SchemaVersion, latestSchemaVersion, bindSQL)
+	if err != nil {
+		return nil, err
+	}
+	if isGeneralPlanCache {
+		key.(*planCacheKey).selectLimit = math.MaxUint64
+	}
+	return key, nil
+}
+
 // FieldSlice is the slice of the types.FieldType
 type FieldSlice []*types.FieldType
 
@@ -327,12 +384,201 @@ type PlanCacheValue struct {
 	OutPutNames       []*types.FieldName
 	TblInfo2UnionScan map[*model.TableInfo]bool
 	ParamTypes        FieldSlice
+	// NonDeterministicExprs lists the non-deterministic expressions of the plan, which are re-evaluated on each
+	// execution instead of being folded into the plan, e.g. `now()` and `@a`.
+	NonDeterministicExprs []string
+	// UserVars and UserVarTypes are the user variables read by the plan and their types when the plan is built.
+	// The plan can't be reused once the types change, since the types are fixed in the plan.
+	UserVars     []string
+	UserVarTypes FieldSlice
+	// EqualParamGroups are the orders of the params holding equal values when the plan is built, see EqualParamGroups.
+	EqualParamGroups [][]int
+	// EqualParams are the pairs of the params which are assumed to be equal by the plan, the plan can't be reused
+	// once they're not equal anymore.
+	EqualParams [][2]int
+	// SchemaHash is the hash of the columns of the tables read by the plan when the plan is built. The output
+	// fields of the plan are resolved from these columns, so the plan can't be reused once the hash changes.
+	SchemaHash uint64
+	// DependentTables are the update TS of the tables the plan depends on when the plan is built, i.e. the tables
+	// read by the plan and the ones named in the statement, e.g. the views and the target tables of DML. The plan
+	// survives the schema version bumps which don't touch these tables.
+	DependentTables map[int64]uint64
+	// DependentPartitions are the IDs of the partitions of the partitioned tables in DependentTables when the plan is
+	// built. The plan can't survive the partition DDL, e.g. ADD and DROP PARTITION, since the partitions it reads are
+	// resolved when it's built, so they're checked as well as the update TS of the tables.
+	DependentPartitions map[int64][]int64
+	// SchemaRollbackEpoch is the Domain.SchemaRollbackEpoch4PC when the plan is built.
+	SchemaRollbackEpoch uint64
+	// TxnAssumption is the transaction context the plan is built in, the plan can't be reused in a different one.
+	TxnAssumption planCacheTxnAssumption
+	// PrivFingerprint is the privilege fingerprint when the privileges of the plan are checked last time.
+	PrivFingerprint planCachePrivFingerprint
+	// PlanDigest is the digest of the normalized plan, by which the plan is found by `CREATE BINDING FROM PLAN CACHE`.
+	PlanDigest string
+}
+
+// planCacheTxnAssumption is the transaction context assumed by a plan. The plan decides by it whether to lock the
+// rows it reads, e.g. the rows of `select for update` and the DML in pessimistic transactions, and whether to read
+// the latest data, e.g. in the RC isolation, so reusing it in another context may skip the locks or read stale data.
+type planCacheTxnAssumption struct {
+	inTxn         bool
+	pessimistic   bool
+	readCommitted bool
+}
+
+// txnAssumption returns the transaction context of the current execution of the statement. The read-only
+// statements neither lock the rows nor read for update, so their plans assume nothing.
+func (stmt *PlanCacheStmt) txnAssumption(sessVars *variable.SessionVars) planCacheTxnAssumption {
+	if stmt.ReadOnly {
+		return planCacheTxnAssumption{}
+	}
+	return planCacheTxnAssumption{
+		inTxn:         !sessVars.IsAutocommit() || sessVars.InTxn(),
+		pessimistic:   sessVars.TxnCtx != nil && sessVars.TxnCtx.IsPessimistic,
+		readCommitted: sessVars.IsIsolation(ast.ReadCommitted),
+	}
+}
+
+// planCachePrivFingerprint is what the privileges checked for a cached plan depend on. The privileges are checked
+// again when the plan is hit if the fingerprint changes, e.g. the active roles are changed by SET ROLE or the
+// privileges are reloaded after REVOKE and FLUSH PRIVILEGES.
+type planCachePrivFingerprint struct {
+	user        auth.UserIdentity
+	activeRoles []auth.RoleIdentity
+	version     uint64
+}
+
+// newPlanCachePrivFingerprint returns the privilege fingerprint of the current session.
+func newPlanCachePrivFingerprint(sctx sessionctx.Context) planCachePrivFingerprint {
+	sessVars := sctx.GetSessionVars()
+	var fp planCachePrivFingerprint
+	if sessVars.User != nil {
+		fp.user = *sessVars.User
+	}
+	fp.activeRoles = make([]auth.RoleIdentity, 0, len(sessVars.ActiveRoles))
+	for _, role := range sessVars.ActiveRoles {
+		fp.activeRoles = append(fp.activeRoles, *role)
+	}
+	if pm := privilege.GetPrivilegeManager(sctx); pm != nil {
+		fp.version = pm.Version()
+	}
+	return fp
+}
+
+// unchanged checks whether the privilege fingerprint of the current session is still fp.
+func (fp *planCachePrivFingerprint) unchanged(sctx sessionctx.Context) bool {
+	sessVars := sctx.GetSessionVars()
+	if sessVars.User == nil || *sessVars.User != fp.user || len(sessVars.ActiveRoles) != len(fp.activeRoles) {
+		return false
+	}
+	for i, role := range sessVars.ActiveRoles {
+		if *role != fp.activeRoles[i] {
+			return false
+		}
+	}
+	pm := privilege.GetPrivilegeManager(sctx)
+	return pm == nil || pm.Version() == fp.version
 }
 
 func (v *PlanCacheValue) varTypesUnchanged(txtVarTps []*types.FieldType) bool {
 	return v.ParamTypes.CheckTypesCompatibility4PC(txtVarTps)
 }
 
+// userVarTypesUnchanged checks whether the types of the user variables read by the plan are unchanged.
+func (v *PlanCacheValue) userVarTypesUnchanged(sessVars *variable.SessionVars) bool {
+	if len(v.UserVars) == 0 {
+		return true
+	}
+	tps := make([]*types.FieldType, 0, len(v.UserVars))
+	for _, name := range v.UserVars {
+		tps = append(tps, getUserVarType(sessVars, name))
+	}
+	return v.UserVarTypes.CheckTypesCompatibility4PC(tps)
+}
+
+// equalParamsHold checks whether the params assumed to be equal by the plan are still equal.
+func (v *PlanCacheValue) equalParamsHold(sessVars *variable.SessionVars) bool {
+	for _, pair := range v.EqualParams {
+		if pair[0] >= len(sessVars.PreparedParams) || pair[1] >= len(sessVars.PreparedParams) ||
+			!paramsEqual(sessVars.StmtCtx, &sessVars.PreparedParams[pair[0]], &sessVars.PreparedParams[pair[1]]) {
+			return false
+		}
+	}
+	return true
+}
+
+// setEqualParams records the params holding equal values and the ones assumed to be equal by the plan.
+func (v *PlanCacheValue) setEqualParams(sessVars *variable.SessionVars) {
+	v.EqualParamGroups = EqualParamGroups(sessVars.StmtCtx, sessVars.PreparedParams)
+	v.EqualParams = append([][2]int(nil), sessVars.StmtCtx.EqualParams4PC...)
+}
+
+// setNonDeterministicExprs records the non-deterministic expressions collected when building the plan.
+func (v *PlanCacheValue) setNonDeterministicExprs(sessVars *variable.SessionVars) {
+	stmtCtx := sessVars.StmtCtx
+	v.NonDeterministicExprs = append([]string(nil), stmtCtx.NonDeterministicExprs4PC...)
+	v.UserVars = append([]string(nil), stmtCtx.UserVars4PC...)
+	v.UserVarTypes = make(FieldSlice, 0, len(v.UserVars))
+	for _, name := range v.UserVars {
+		v.UserVarTypes = append(v.UserVarTypes, getUserVarType(sessVars, name).Clone())
+	}
+}
+
+// setDependentTables records the tables the plan of the statement depends on, the views are the ones expanded when
+// building the plan, including the nested ones.
+func (v *PlanCacheValue) setDependentTables(stmt ast.StmtNode, views []*model.TableInfo) {
+	collector := &tableInfoCollector{tblInfos: make(map[int64]*model.TableInfo)}
+	stmt.Accept(collector)
+	for tblInfo := range v.TblInfo2UnionScan {
+		collector.tblInfos[tblInfo.ID] = tblInfo
+	}
+	for _, tblInfo := range views {
+		collector.tblInfos[tblInfo.ID] = tblInfo
+	}
+	v.DependentTables = make(map[int64]uint64, len(collector.tblInfos))
+	v.DependentPartitions = make(map[int64][]int64)
+	for id, tblInfo := range collector.tblInfos {
+		v.DependentTables[id] = tblInfo.UpdateTS
+		if pids := partitionIDs(tblInfo); len(pids) > 0 {
+			v.DependentPartitions[id] = pids
+		}
+	}
+}
+
+// partitionsUnchanged checks whether the partitions of the table are still the ones when the plan is built.
+func (v *PlanCacheValue) partitionsUnchanged(tblInfo *model.TableInfo) bool {
+	return slices.Equal(partitionIDs(tblInfo), v.DependentPartitions[tblInfo.ID])
+}
+
+// partitionIDs returns the IDs of the partitions of the table in the order of their definitions.
+func partitionIDs(tblInfo *model.TableInfo) []int64 {
+	pi := tblInfo.GetPartitionInfo()
+	if pi == nil {
+		return nil
+	}
+	ids := make([]int64, 0, len(pi.Definitions))
+	for _, def := range pi.Definitions {
+		ids = append(ids, def.ID)
+	}
+	return ids
+}
+
+// tableInfoCollector collects the tables named in the statement, which are resolved by the preprocessor.
+type tableInfoCollector struct {
+	tblInfos map[int64]*model.TableInfo
+}
+
+func (c *tableInfoCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok && tn.TableInfo != nil {
+		c.tblInfos[tn.TableInfo.ID] = tn.TableInfo
+	}
+	return in, false
+}
+
+func (c *tableInfoCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
 // NewPlanCacheValue creates a SQLCacheValue.
 func NewPlanCacheValue(plan Plan, names []*types.FieldName, srcMap map[*model.TableInfo]bool,
 	paramTypes []*types.FieldType) *PlanCacheValue {
@@ -349,7 +595,50 @@ func NewPlanCacheValue(plan Plan, names []*types.FieldName, srcMap map[*model.Ta
 		OutPutNames:       names,
 		TblInfo2UnionScan: dstMap,
 		ParamTypes:        userParamTypes,
+		SchemaHash:        tableColumnsHash(dstMap, nil),
+	}
+}
+
+// outputSchemaUnchanged checks whether the output fields of the cached plan still match the ones resolved from the
+// current schema, in case the columns are renamed or reordered while the plan is kept in the cache.
+func (v *PlanCacheValue) outputSchemaUnchanged(is infoschema.InfoSchema) bool {
+	// The names of the DML plans are the columns of the target tables rather than the output fields.
+	if p, ok := v.Plan.(PhysicalPlan); ok && p.Schema().Len() != len(v.OutPutNames) {
+		return false
 	}
+	return tableColumnsHash(v.TblInfo2UnionScan, is) == v.SchemaHash
+}
+
+// tableColumnsHash hashes the names and types of the columns of the tables. The latest definitions of the tables
+// in is are used if is isn't nil.
+func tableColumnsHash(tblInfos map[*model.TableInfo]bool, is infoschema.InfoSchema) uint64 {
+	tbls := make([]*model.TableInfo, 0, len(tblInfos))
+	for tblInfo := range tblInfos {
+		tbls = append(tbls, tblInfo)
+	}
+	slices.SortFunc(tbls, func(i, j *model.TableInfo) bool {
+		return i.ID < j.ID
+	})
+	h := fnv.New64a()
+	for _, tblInfo := range tbls {
+		if is != nil {
+			tbl, ok := is.TableByID(tblInfo.ID)
+			if !ok {
+				// The table is dropped, the hash can't match.
+				return 0
+			}
+			tblInfo = tbl.Meta()
+		}
+		h.Write([]byte(strconv.FormatInt(tblInfo.ID, 10)))
+		for _, col := range tblInfo.Columns {
+			h.Write([]byte{0})
+			h.Write(hack.Slice(col.Name.L))
+			h.Write([]byte{0})
+			h.Write(hack.Slice(col.FieldType.String()))
+		}
+		h.Write([]byte{1})
+	}
+	return h.Sum64()
 }
 
 // PlanCacheStmt store prepared ast from PrepareExec and other related fields
@@ -374,6 +663,36 @@ type PlanCacheStmt struct {
 	//  NormalizedSQL4PC: select * from `test` . `t` where `a` > ? and `b` < ? --> schema name is added,
 	//  StmtText: select * from t where a>1 and b <? --> just format the original query;
 	StmtText string
+	// ParamSQL is the text restored from the preprocessed statement, e.g. SELECT * FROM `test`.`t` WHERE `a`>?, it's
+	// the statement text in the keys of the plans in the general plan cache, so the general statements and the
+	// prepared statements of the same shape share the plans if tidb_enable_shared_plan_cache_template is ON. It's empty
+	// if the plans of the statement can't be cached.
+	ParamSQL string
+
+	// ReadOnly is whether the statement is read-only, see ast.IsReadOnly.
+	ReadOnly bool
+	// ReadsPartitionTable is whether the statement reads the partitioned tables. Their cached plans don't hold the
+	// pruned partitions, which are pruned by the executors with the params of each execution in the dynamic prune
+	// mode, so the plans are only used in the dynamic mode.
+	ReadsPartitionTable bool
+	// ReadsView is whether the statement reads the views. The views may be redefined over the tables whose plans
+	// can't be cached, so the statement is checked again once the schema changes.
+	ReadsView bool
+	// isGeneralStmt is whether the statement is parameterized by the general plan cache, see checkCacheable.
+	isGeneralStmt bool
+	// PointPlanTxnAssumption is the transaction context the PreparedAst.CachedPlan is built in.
+	PointPlanTxnAssumption planCacheTxnAssumption
+	// PointPlanPrivFingerprint is the privilege fingerprint when the privileges of the PreparedAst.CachedPlan are
+	// checked last time.
+	PointPlanPrivFingerprint planCachePrivFingerprint
+}
+
+// planCacheKeyText returns the statement text in the keys of the plans of the statement.
+func (s *PlanCacheStmt) planCacheKeyText(isGeneralPlanCache bool) string {
+	if isGeneralPlanCache && s.ParamSQL != "" {
+		return s.ParamSQL
+	}
+	return s.StmtText
 }
 
 // GetPreparedStmt extract the prepared statement from the execute statement.
@@ -396,17 +715,90 @@ func GetPreparedStmt(stmt *ast.ExecuteStmt, vars *variable.SessionVars) (*PlanCa
 // e.g. 'select * from t where a>23' --> 'select * from t where a>?' + 23
 type Parameterizer interface {
 	// Parameterize this specific sql, ok indicates whether this sql is supported.
-	Parameterize(originSQL string) (paramSQL string, params []expression.Expression, ok bool, err error)
+	// The paramTexts are the original texts of the params, they can be nil if they're unknown.
+	Parameterize(originSQL string) (paramSQL string, params []expression.Expression, paramTexts []string, ok bool, err error)
 }
 
 // ParameterizerKey is used to get a parameterizer from a ctx, only for test.
 const ParameterizerKey = stringutil.StringerStr("parameterizerKey")
 
+// PlanCacheHint is the plan cache hint of a statement.
+type PlanCacheHint int
+
+const (
+	// NoPlanCacheHint means there is no plan cache hint.
+	NoPlanCacheHint PlanCacheHint = iota
+	// IgnorePlanCacheHint means the statement has the `IGNORE_PLAN_CACHE()` hint.
+	IgnorePlanCacheHint
+	// UsePlanCacheHint means the statement has the `USE_PLAN_CACHE()` hint.
+	UsePlanCacheHint
+)
+
+var planCacheHintPattern = regexp.MustCompile(`(?i)\b(ignore_plan_cache|use_plan_cache)\s*\(\s*\)`)
+
+// GetPlanCacheHint finds the plan cache hint of the sql without parsing it, so the general plan cache can skip the
+// statement before parameterizing it. `IGNORE_PLAN_CACHE()` takes precedence if both hints are used.
+func GetPlanCacheHint(sql string) PlanCacheHint {
+	hint := NoPlanCacheHint
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(sql[i:], "/*+"):
+			end := strings.Index(sql[i+3:], "*/")
+			if end < 0 {
+				return hint
+			}
+			for _, match := range planCacheHintPattern.FindAllStringSubmatch(sql[i+3:i+3+end], -1) {
+				if strings.EqualFold(match[1], HintIgnorePlanCache) {
+					return IgnorePlanCacheHint
+				}
+				hint = UsePlanCacheHint
+			}
+			i += 3 + end + 1
+		}
+	}
+	return hint
+}
+
 // Parameterize parameterizes this sql, used by general plan cache.
-func Parameterize(sctx sessionctx.Context, originSQL string) (paramSQL string, params []expression.Expression, ok bool, err error) {
+func Parameterize(sctx sessionctx.Context, originSQL string) (paramSQL string, params []expression.Expression,
+	paramTexts []string, ok bool, err error) {
+	if sctx.GetSessionVars().InRestrictedSQL {
+		// Never parameterize the internal SQL, the constants in it may be copied from the schema objects, e.g. the
+		// expressions of the expression indexes, and sharing a cached plan among them produces wrong results.
+		return "", nil, nil, false, nil
+	}
 	if v := sctx.Value(ParameterizerKey); v != nil { // for test
 		return v.(Parameterizer).Parameterize(originSQL)
 	}
-	// TODO: implement it
-	return "", nil, false, nil
+	// The template is shared by the callers of the same text, so the params are copied out of it.
+	t := GetParamTemplate(sctx, originSQL)
+	if t == nil {
+		return "", nil, nil, false, nil
+	}
+	return t.ParamSQL(), t.NewParams(), t.ParamTexts(), true, nil
+}
+
+// TracePlanCache records a decision of the plan cache into the trace span of ctx if tidb_trace_plan_cache is on,
+// so the decisions show up in the TRACE output of the statement. It never affects the decisions themselves.
+func TracePlanCache(ctx context.Context, sctx sessionctx.Context, format string, args ...interface{}) {
+	if sctx.GetSessionVars().TracePlanCache {
+		logutil.Eventf(ctx, "[plan-cache] "+format, args...)
+	}
+}
+
+// planCacheKeyDigest returns a short digest of the plan cache key to tell the keys apart in the trace.
+func planCacheKeyDigest(key kvcache.Key) string {
+	h := fnv.New64a()
+	_, _ = h.Write(key.Hash())
+	return strconv.FormatUint(h.Sum64(), 16)
 }
@@ -18,6 +18,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/stretchr/testify/require"
@@ -49,5 +51,37 @@ func TestCacheKey(t *testing.T) {
 	if err != nil {
 		t.Fail()
 	}
-	require.Equal(t, []byte{0x74, 0x65, 0x73, 0x74, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x20, 0x31, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x74, 0x69, 0x64, 0x62, 0x74, 0x69, 0x6b, 0x76, 0x74, 0x69, 0x66, 0x6c, 0x61, 0x73, 0x68, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x66, 0x61, 0x6c, 0x73, 0x65}, key.Hash())
+	require.Equal(t, []byte{0x74, 0x65, 0x73, 0x74, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x20, 0x31, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x80, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x74, 0x69, 0x64, 0x62, 0x74, 0x69, 0x6b, 0x76, 0x74, 0x69, 0x66, 0x6c, 0x61, 0x73, 0x68, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x66, 0x61, 0x6c, 0x73, 0x65, 0x66, 0x61, 0x6c, 0x73, 0x65, 0xcb, 0xf2, 0x9c, 0xe4, 0x84, 0x22, 0x23, 0x25}, key.Hash())
+}
+
+func TestPlanCacheValuePartitionsUnchanged(t *testing.T) {
+	tblInfo := &model.TableInfo{ID: 1, Partition: &model.PartitionInfo{
+		Type:        model.PartitionTypeRange,
+		Enable:      true,
+		Definitions: []model.PartitionDefinition{{ID: 2}, {ID: 3}},
+	}}
+	v := &PlanCacheValue{TblInfo2UnionScan: map[*model.TableInfo]bool{tblInfo: false}}
+	v.setDependentTables(&ast.SelectStmt{}, nil)
+	require.Equal(t, []int64{2, 3}, v.DependentPartitions[1])
+	require.True(t, v.partitionsUnchanged(tblInfo.Clone()))
+
+	// The partitions are added, dropped or replaced, e.g. by TRUNCATE and EXCHANGE PARTITION.
+	for _, defs := range [][]model.PartitionDefinition{
+		{{ID: 2}, {ID: 3}, {ID: 4}},
+		{{ID: 3}},
+		{{ID: 2}, {ID: 5}},
+	} {
+		newTblInfo := tblInfo.Clone()
+		newTblInfo.Partition.Definitions = defs
+		require.False(t, v.partitionsUnchanged(newTblInfo))
+	}
+	newTblInfo := tblInfo.Clone()
+	newTblInfo.Partition = nil
+	require.False(t, v.partitionsUnchanged(newTblInfo))
+
+	// The partitions of the non-partitioned tables are never changed.
+	v = &PlanCacheValue{TblInfo2UnionScan: map[*model.TableInfo]bool{newTblInfo: false}}
+	v.setDependentTables(&ast.SelectStmt{}, nil)
+	require.Empty(t, v.DependentPartitions)
+	require.True(t, v.partitionsUnchanged(newTblInfo))
 }
@@ -45,6 +45,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessiontxn"
 	"github.com/pingcap/tidb/sessiontxn/staleread"
 	"github.com/pingcap/tidb/statistics"
 	"github.com/pingcap/tidb/table"
@@ -977,6 +978,9 @@ func checkHintedSQL(sql, charset, collation, db string) error {
 }
 
 func (b *PlanBuilder) buildCreateBindPlan(v *ast.CreateBindingStmt) (Plan, error) {
+	if v.OriginNode == nil {
+		return b.buildCreateBindPlanFromPlanCache(v)
+	}
 	charSet, collation := b.ctx.GetSessionVars().GetCharsetInfo()
 
 	// Because we use HintedNode.Restore instead of HintedNode.Text, so we need do some check here
@@ -1407,7 +1411,19 @@ func (b *PlanBuilder) buildAdmin(ctx context.Context, as *ast.AdminStmt) (Plan,
 		ret = p
 	case ast.AdminChecksumTable:
 		p := &ChecksumTable{Tables: as.Tables}
-		p.setSchemaAndNames(buildChecksumTableSchema())
+		if as.AsOf != nil {
+			asOfTS, err := staleread.CalculateAsOfTsExpr(b.ctx, as.AsOf)
+			if err != nil {
+				return nil, err
+			}
+			if err = sessionctx.ValidateStaleReadTS(ctx, b.ctx, asOfTS); err != nil {
+				return nil, err
+			}
+			p.AsOfTS = asOfTS
+			p.setSchemaAndNames(buildChecksumTableAsOfSchema())
+		} else {
+			p.setSchemaAndNames(buildChecksumTableSchema())
+		}
 		ret = p
 	case ast.AdminShowNextRowID:
 		p := &ShowNextRowID{TableName: as.Tables[0]}
@@ -1451,6 +1467,14 @@ func (b *PlanBuilder) buildAdmin(ctx context.Context, as *ast.AdminStmt) (Plan,
 		p := &ShowDDLJobQueriesWithRange{Limit: as.LimitSimple.Count, Offset: as.LimitSimple.Offset}
 		p.setSchemaAndNames(buildShowDDLJobQueriesWithRangeFields())
 		ret = p
+	case ast.AdminShowDDLJobArgs:
+		p := &ShowDDLJobArgs{JobIDs: as.JobIDs}
+		p.setSchemaAndNames(buildShowDDLJobArgsFields())
+		ret = p
+	case ast.AdminShowDDLJobLog:
+		p := &ShowDDLJobLog{JobIDs: as.JobIDs}
+		p.setSchemaAndNames(buildShowDDLJobLogFields())
+		ret = p
 	case ast.AdminShowSlow:
 		p := &ShowSlow{ShowSlow: as.ShowSlow}
 		p.setSchemaAndNames(buildShowSlowSchema())
@@ -1486,6 +1510,8 @@ func (b *PlanBuilder) buildAdmin(ctx context.Context, as *ast.AdminStmt) (Plan,
 		return &Simple{Statement: as}, nil
 	case ast.AdminFlushPlanCache:
 		return &Simple{Statement: as}, nil
+	case ast.AdminCleanupFlashback, ast.AdminUndoFlashback:
+		ret = &Simple{Statement: as}
 	default:
 		return nil, ErrUnsupportedType.GenWithStack("Unsupported ast.AdminStmt(%T) for buildAdmin", as)
 	}
@@ -1733,6 +1759,14 @@ func (b *PlanBuilder) buildAdminCheckTable(ctx context.Context, as *ast.AdminStm
 		DBName: tblName.Schema.O,
 		Table:  tbl,
 	}
+	if as.AsOf != nil {
+		// The statement is a stale read of the snapshot at the timestamp, see preprocessor.
+		asOfTS, err := sessiontxn.GetTxnManager(b.ctx).GetStmtReadTS()
+		if err != nil {
+			return nil, err
+		}
+		p.AsOfTS = asOfTS
+	}
 	var readerPlans []Plan
 	var indexInfos []*model.IndexInfo
 	var err error
@@ -2813,7 +2847,7 @@ func buildCleanupIndexFields() (*expression.Schema, types.NameSlice) {
 }
 
 func buildShowDDLJobsFields() (*expression.Schema, types.NameSlice) {
-	schema := newColumnsWithNames(12)
+	schema := newColumnsWithNames(15)
 	schema.Append(buildColumnWithName("", "JOB_ID", mysql.TypeLonglong, 4))
 	schema.Append(buildColumnWithName("", "DB_NAME", mysql.TypeVarchar, 64))
 	schema.Append(buildColumnWithName("", "TABLE_NAME", mysql.TypeVarchar, 64))
@@ -2826,6 +2860,9 @@ func buildShowDDLJobsFields() (*expression.Schema, types.NameSlice) {
 	schema.Append(buildColumnWithName("", "START_TIME", mysql.TypeDatetime, 19))
 	schema.Append(buildColumnWithName("", "END_TIME", mysql.TypeDatetime, 19))
 	schema.Append(buildColumnWithName("", "STATE", mysql.TypeVarchar, 64))
+	schema.Append(buildColumnWithName("", "ESTIMATED_REMAINING", mysql.TypeVarchar, 64))
+	schema.Append(buildColumnWithName("", "FLASHBACK_CHECKPOINTS", mysql.TypeJSON, 51))
+	schema.Append(buildColumnWithName("", "STATE_DURATIONS", mysql.TypeJSON, 51))
 	return schema.col2Schema(), schema.names
 }
 
@@ -2867,6 +2904,25 @@ func buildShowDDLJobQueriesWithRangeFields() (*expression.Schema, types.NameSlic
 	return schema.col2Schema(), schema.names
 }
 
+func buildShowDDLJobArgsFields() (*expression.Schema, types.NameSlice) {
+	schema := newColumnsWithNames(4)
+	schema.Append(buildColumnWithName("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumnWithName("", "JOB_TYPE", mysql.TypeVarchar, 64))
+	schema.Append(buildColumnWithName("", "ARG_NAME", mysql.TypeVarchar, 64))
+	schema.Append(buildColumnWithName("", "ARG_VALUE", mysql.TypeVarchar, 256))
+	return schema.col2Schema(), schema.names
+}
+
+func buildShowDDLJobLogFields() (*expression.Schema, types.NameSlice) {
+	schema := newColumnsWithNames(5)
+	schema.Append(buildColumnWithName("", "JOB_ID", mysql.TypeLonglong, 4))
+	schema.Append(buildColumnWithName("", "TIME", mysql.TypeDatetime, 26))
+	schema.Append(buildColumnWithName("", "LEVEL", mysql.TypeVarchar, 16))
+	schema.Append(buildColumnWithName("", "MESSAGE", mysql.TypeVarchar, 256))
+	schema.Append(buildColumnWithName("", "FIELDS", mysql.TypeVarchar, 1024))
+	return schema.col2Schema(), schema.names
+}
+
 func buildShowSlowSchema() (*expression.Schema, types.NameSlice) {
 	longlongSize, _ := mysql.GetDefaultFieldLengthAndDecimal(mysql.TypeLonglong)
 	tinySize, _ := mysql.GetDefaultFieldLengthAndDecimal(mysql.TypeTiny)
@@ -3226,6 +3282,11 @@ func (b *PlanBuilder) buildSimple(ctx context.Context, node ast.StmtNode) (Plan,
 			// consume read ts here
 			b.ctx.GetSessionVars().TxnReadTS.UseTxnReadTS()
 		}
+		if p.StaleTxnStartTS > 0 {
+			if err := staleread.CheckReadTSAcrossFlashback(b.ctx, p.StaleTxnStartTS); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return p, nil
 }
@@ -4475,7 +4536,7 @@ func (b *PlanBuilder) buildDDL(ctx context.Context, node ast.DDLNode) (Plan, err
 		}
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.InsertPriv, v.TableToTables[0].NewTable.Schema.L,
 			v.TableToTables[0].NewTable.Name.L, "", authErr)
-	case *ast.RecoverTableStmt, *ast.FlashBackTableStmt:
+	case *ast.RecoverTableStmt, *ast.FlashBackTableStmt, *ast.FlashBackToTimestampStmt:
 		// Recover table command can only be executed by administrator.
 		b.visitInfo = appendVisitInfo(b.visitInfo, mysql.SuperPriv, "", "", "", nil)
 	case *ast.LockTablesStmt, *ast.UnlockTablesStmt:
@@ -4909,6 +4970,21 @@ func buildChecksumTableSchema() (*expression.Schema, []*types.FieldName) {
 	return schema.col2Schema(), schema.names
 }
 
+func buildChecksumTableAsOfSchema() (*expression.Schema, []*types.FieldName) {
+	schema := newColumnsWithNames(10)
+	schema.Append(buildColumnWithName("", "Db_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumnWithName("", "Table_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumnWithName("", "Partition_name", mysql.TypeVarchar, 128))
+	schema.Append(buildColumnWithName("", "Checksum_crc64_xor", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "Total_kvs", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "Total_bytes", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "As_of_checksum_crc64_xor", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "As_of_total_kvs", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "As_of_total_bytes", mysql.TypeLonglong, 22))
+	schema.Append(buildColumnWithName("", "Match", mysql.TypeTiny, 1))
+	return schema.col2Schema(), schema.names
+}
+
 // adjustOverlongViewColname adjusts the overlong outputNames of a view to
 // `new_exp_$off` where `$off` is the offset of the output column, $off starts from 1.
 // There is still some MySQL compatible problems.
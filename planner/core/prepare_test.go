@@ -36,6 +36,7 @@ import (
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/testkit"
+	"github.com/pingcap/tidb/testkit/testutil"
 	"github.com/pingcap/tidb/util/hint"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -213,7 +214,73 @@ func TestRandomFlushPlanCache(t *testing.T) {
 	}
 
 	err = tk.ExecToErr("admin flush global plan_cache;")
-	require.EqualError(t, err, "Do not support the 'admin flush global scope.'")
+	require.NoError(t, err)
+}
+
+// planCacheSessionManager is a session manager which flushes the plan caches of the testkit sessions.
+type planCacheSessionManager struct {
+	testutil.MockSessionManager
+	sessions []session.Session
+}
+
+func (sm *planCacheSessionManager) FlushPlanCache() int {
+	evicted := 0
+	for _, se := range sm.sessions {
+		evicted += se.FlushPlanCache()
+	}
+	return evicted
+}
+
+func TestFlushPlanCacheWithScopes(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk2 := testkit.NewTestKit(t, store)
+	dom.InfoSyncer().SetSessionManager(&planCacheSessionManager{sessions: []session.Session{tk.Session(), tk2.Session()}})
+
+	tk.MustExec("use test")
+	tk.MustExec("create table t(id int, a int, key(a))")
+	tk.MustExec("insert into t values (1, 1), (2, 2)")
+	tk2.MustExec("use test")
+	for _, tk := range []*testkit.TestKit{tk, tk2} {
+		tk.MustExec("set tidb_enable_prepared_plan_cache=1")
+		tk.MustExec("prepare stmt1 from 'select * from t where a > ?'")
+		tk.MustExec("prepare stmt2 from 'select * from t where id < ?'")
+		tk.MustExec("set @p = 1")
+	}
+	warmUp := func(tk *testkit.TestKit, stmts ...string) {
+		for _, stmt := range stmts {
+			tk.MustQuery(stmt + " using @p")
+			tk.MustQuery(stmt + " using @p")
+			tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
+		}
+	}
+	checkCached := func(tk *testkit.TestKit, cached string) {
+		tk.MustQuery("execute stmt1 using @p").Check(testkit.Rows("2 2"))
+		tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows(cached))
+	}
+
+	// The session scope only flushes the current session.
+	warmUp(tk, "execute stmt1", "execute stmt2")
+	warmUp(tk2, "execute stmt1")
+	tk.MustExec("admin flush session plan_cache")
+	require.Equal(t, uint64(2), tk.Session().AffectedRows())
+	checkCached(tk, "0")
+	checkCached(tk2, "1")
+
+	// The instance scope flushes all the sessions on this instance.
+	warmUp(tk, "execute stmt1", "execute stmt2")
+	tk.MustExec("admin flush instance plan_cache")
+	require.Equal(t, uint64(3), tk.Session().AffectedRows())
+	checkCached(tk, "0")
+	checkCached(tk2, "0")
+
+	// The global scope flushes this instance and notifies the others.
+	warmUp(tk, "execute stmt1", "execute stmt2")
+	warmUp(tk2, "execute stmt1")
+	tk2.MustExec("admin flush global plan_cache")
+	require.Equal(t, uint64(3), tk2.Session().AffectedRows())
+	checkCached(tk, "0")
+	checkCached(tk2, "0")
 }
 
 func TestFlushPlanCache(t *testing.T) {
@@ -299,8 +366,7 @@ func TestFlushPlanCache(t *testing.T) {
 	tk.MustExec("execute stmt3;")
 	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
 
-	err := tk.ExecToErr("admin flush global plan_cache;")
-	require.EqualError(t, err, "Do not support the 'admin flush global scope.'")
+	tk.MustExec("admin flush global plan_cache;")
 }
 
 func TestFlushPlanCacheWithoutPCEnable(t *testing.T) {
@@ -389,8 +455,8 @@ func TestFlushPlanCacheWithoutPCEnable(t *testing.T) {
 	tk.MustExec("execute stmt3;")
 	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
 
-	err := tk.ExecToErr("admin flush global plan_cache;")
-	require.EqualError(t, err, "Do not support the 'admin flush global scope.'")
+	tk.MustExec("admin flush global plan_cache;")
+	tk.MustQuery("show warnings;").Check(testkit.Rows("Warning 1105 The plan cache is disable. So there no need to flush the plan cache"))
 }
 
 func TestPrepareCache(t *testing.T) {
@@ -1921,6 +1987,13 @@ func TestPlanCacheSnapshot(t *testing.T) {
 	tk.MustExec(fmt.Sprintf("set @@tidb_snapshot = '%d'", tso))
 	tk.MustQuery("select * from t where id = 1").Check(testkit.Rows("1"))
 	tk.MustQuery("execute stmt using @p").Check(testkit.Rows("1"))
+	// The plan cache is skipped when reading the snapshot.
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 skip plan-cache: tidb_snapshot is set"))
+	tk.MustQuery("execute stmt using @p").Check(testkit.Rows("1"))
+	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("0"))
+
+	tk.MustExec("set @@tidb_snapshot = ''")
+	tk.MustQuery("execute stmt using @p").Check(testkit.Rows("1", "1"))
 	tk.MustQuery("select @@last_plan_from_cache").Check(testkit.Rows("1"))
 }
 
@@ -102,6 +102,22 @@ func TryAddExtraLimit(ctx sessionctx.Context, node ast.StmtNode) ast.StmtNode {
 	return node
 }
 
+// NeedExtraLimit checks whether TryAddExtraLimit adds an extra limit for the statement.
+func NeedExtraLimit(ctx sessionctx.Context, node ast.StmtNode) bool {
+	if ctx.GetSessionVars().SelectLimit == math.MaxUint64 || ctx.GetSessionVars().InRestrictedSQL {
+		return false
+	}
+	switch x := node.(type) {
+	case *ast.ExplainStmt:
+		return NeedExtraLimit(ctx, x.Stmt)
+	case *ast.SelectStmt:
+		return x.Limit == nil && x.SelectIntoOpt == nil
+	case *ast.SetOprStmt:
+		return x.Limit == nil
+	}
+	return false
+}
+
 // Preprocess resolves table names of the node, and checks some statements' validation.
 // preprocessReturn used to extract the infoschema for the tableName and the timestamp from the asof clause.
 func Preprocess(ctx sessionctx.Context, node ast.Node, preprocessOpt ...PreprocessOpt) error {
@@ -187,6 +203,15 @@ type preprocessor struct {
 func (p *preprocessor) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 	switch node := in.(type) {
 	case *ast.AdminStmt:
+		if node.AsOf != nil && (node.Tp == ast.AdminCheckTable || node.Tp == ast.AdminCheckIndex) {
+			// The whole statement is a stale read of the snapshot at the timestamp, including the table meta.
+			if p.err = p.staleReadProcessor.OnSelectTable(&ast.TableName{AsOf: node.AsOf}); p.err != nil {
+				return in, true
+			}
+			if p.err = p.updateStateFromStaleReadProcessor(); p.err != nil {
+				return in, true
+			}
+		}
 		p.checkAdminCheckTableGrammar(node)
 	case *ast.DeleteStmt:
 		p.stmtTp = TypeDelete
@@ -249,6 +274,10 @@ func (p *preprocessor) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
 		p.checkNonUniqTableAlias(node)
 	case *ast.CreateBindingStmt:
 		p.stmtTp = TypeCreate
+		if node.OriginNode == nil {
+			// The statement is read from the plan cache, and is checked when the plan is built.
+			return in, true
+		}
 		EraseLastSemicolon(node.OriginNode)
 		EraseLastSemicolon(node.HintedNode)
 		p.checkBindGrammar(node.OriginNode, node.HintedNode, p.ctx.GetSessionVars().CurrentDB)
@@ -259,7 +259,7 @@ func allowInReadOnlyMode(sctx sessionctx.Context, node ast.Node) (bool, error) {
 		return true, nil
 	}
 
-	switch node.(type) {
+	switch x := node.(type) {
 	// allow change variables (otherwise can't unset read-only mode)
 	case *ast.SetStmt,
 		// allow analyze table
@@ -281,6 +281,11 @@ func allowInReadOnlyMode(sctx sessionctx.Context, node ast.Node) (bool, error) {
 			return false, txn.Rollback()
 		}
 		return true, nil
+	case *ast.AdminStmt:
+		// allow undoing the flashback in the read-only window after it
+		if x.Tp == ast.AdminUndoFlashback {
+			return true, nil
+		}
 	}
 
 	vars := sctx.GetSessionVars()
@@ -372,13 +377,17 @@ func OptimizeExecStmt(ctx context.Context, sctx sessionctx.Context,
 	if !ok {
 		return nil, nil, errors.Errorf("invalid result plan type, should be Execute")
 	}
-	plan, names, err := core.GetPlanFromSessionPlanCache(ctx, sctx, execAst.FromGeneralStmt, is, exec.PrepStmt, exec.Params)
+	// The prepared statements share the plans in the general plan cache with the general statements if it's enabled.
+	isGeneralPlanCache := execAst.FromGeneralStmt ||
+		(sctx.GetSessionVars().EnableSharedPlanCacheTemplate && exec.PrepStmt.ParamSQL != "")
+	plan, names, err := core.GetPlanFromSessionPlanCache(ctx, sctx, isGeneralPlanCache, is, exec.PrepStmt, exec.Params)
 	if err != nil {
 		return nil, nil, err
 	}
 	exec.Plan = plan
 	exec.SetOutputNames(names)
 	exec.Stmt = exec.PrepStmt.PreparedAst.Stmt
+	exec.RuntimeSelectLimit = isGeneralPlanCache
 	return exec, names, nil
 }
 
@@ -93,6 +93,10 @@ type Manager interface {
 
 	// Get the authentication plugin for a user
 	GetAuthPlugin(user, host string) (string, error)
+
+	// Version returns the version of the privileges, which is changed once they're reloaded, e.g. by GRANT, REVOKE
+	// and FLUSH PRIVILEGES.
+	Version() uint64
 }
 
 const key keyType = 0
@@ -1552,6 +1552,8 @@ func (p *MySQLPrivilege) getAllRoles(user, host string) []*auth.RoleIdentity {
 // Handle wraps MySQLPrivilege providing thread safe access.
 type Handle struct {
 	priv atomic.Value
+	// version is bumped every time the privileges are reloaded.
+	version uint64
 }
 
 // NewHandle returns a Handle.
@@ -1573,5 +1575,11 @@ func (h *Handle) Update(ctx sessionctx.Context) error {
 	}
 
 	h.priv.Store(&priv)
+	atomic.AddUint64(&h.version, 1)
 	return nil
 }
+
+// Version returns the version of the privileges, which is changed once they're reloaded.
+func (h *Handle) Version() uint64 {
+	return atomic.LoadUint64(&h.version)
+}
@@ -1858,6 +1858,13 @@ func (cc *clientConn) handleQuery(ctx context.Context, sql string) (err error) {
 			// Save the point plan in Session, so we don't need to build the point plan again.
 			cc.ctx.SetValue(plannercore.PointPlanKey, plannercore.PointPlanVal{Plan: pointPlans[i]})
 		}
+		if len(stmts) > 1 && (len(pointPlans) == 0 || pointPlans[i] == nil) {
+			// The statements of the multi-statement query are parameterized by their own texts one by one, right
+			// before being executed, since the statements before may change the current database or the variables.
+			if execStmt, ok := cc.ctx.Parameterize(ctx, stmt.Text()); ok {
+				stmt = execStmt
+			}
+		}
 		retryable, err = cc.handleStmt(ctx, stmt, parserWarns, i == len(stmts)-1)
 		if err != nil {
 			action, txnErr := sessiontxn.GetTxnManager(&cc.ctx).OnStmtErrorForNextAction(sessiontxn.StmtErrAfterQuery, err)
@@ -246,6 +246,7 @@ func (cc *clientConn) executePreparedStmtAndWriteResult(ctx context.Context, stm
 	execStmt := &ast.ExecuteStmt{
 		BinaryArgs: args,
 		PrepStmt:   prepStmt,
+		UseCursor:  useCursor,
 	}
 	rs, err := (&cc.ctx).ExecuteStmt(ctx, execStmt)
 	if err != nil {
@@ -317,6 +318,10 @@ func (cc *clientConn) handleStmtFetch(ctx context.Context, data []byte) (err err
 		return errors.Annotate(mysql.NewErr(mysql.ErrUnknownStmtHandler,
 			strconv.FormatUint(uint64(stmtID), 10), "stmt_fetch_rs"), cc.preparedStmt2String(stmtID))
 	}
+	// The result set is closed once all the rows are sent, the cursor doesn't exist any more.
+	if rs.IsClosed() {
+		return errors.Annotate(mysql.NewErr(mysql.ErrStmtHasNoOpenCursor, stmtID), cc.preparedStmt2String(stmtID))
+	}
 
 	_, err = cc.writeResultset(ctx, rs, true, cc.ctx.Status()|mysql.ServerStatusCursorExists, int(fetchSize))
 	if err != nil {
@@ -15,13 +15,21 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
 	"testing"
 
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/parser/terror"
 	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/testkit"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/arena"
+	"github.com/pingcap/tidb/util/chunk"
 	"github.com/stretchr/testify/require"
 )
 
@@ -251,3 +259,114 @@ func TestParseStmtFetchCmd(t *testing.T) {
 		require.Equal(t, tc.err, err)
 	}
 }
+
+func TestCursorFetchCachedPlan(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	out := new(bytes.Buffer)
+	cc := &clientConn{
+		alloc:      arena.NewAllocator(1024),
+		chunkAlloc: chunk.NewAllocator(),
+		capability: mysql.ClientProtocol41,
+		pkt: &packetIO{
+			bufWriter: bufio.NewWriter(out),
+		},
+	}
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	cc.setCtx(&TiDBContext{Session: tk.Session(), stmts: make(map[int]*TiDBStatement)})
+	tk.MustExec("create table t (a bigint primary key, b bigint, unique key(b))")
+	tk.MustExec("insert into t values (1, 1), (2, 2), (3, 3), (4, 4), (5, 5), (6, 6), (7, 7), (8, 8)")
+	tk.MustExec("set tidb_enable_general_plan_cache = 1")
+	tk.MustExec("set tidb_enable_shared_plan_cache_template = 1")
+	ctx := context.Background()
+
+	executeWithCursor := func(stmtID uint32, params ...int64) {
+		data := binary.LittleEndian.AppendUint32(nil, stmtID)
+		data = append(data, mysql.CursorTypeReadOnly)
+		// the iteration count
+		data = binary.LittleEndian.AppendUint32(data, 1)
+		// the null bitmap and the new params bound flag
+		data = append(data, make([]byte, (len(params)+7)/8)...)
+		data = append(data, 1)
+		for range params {
+			data = append(data, mysql.TypeLonglong, 0)
+		}
+		for _, param := range params {
+			data = binary.LittleEndian.AppendUint64(data, uint64(param))
+		}
+		require.NoError(t, cc.handleStmtExecute(ctx, data))
+		// The plans are shared with the general statements of the same templates.
+		require.Equal(t, stmtctx.PlanCacheHit, tk.Session().GetSessionVars().StmtCtx.GetPlanCacheStatus())
+	}
+	fetch := func(stmtID uint32, fetchSize uint32) (rows []int64, cursorExists bool) {
+		out.Reset()
+		data := binary.LittleEndian.AppendUint32(nil, stmtID)
+		data = binary.LittleEndian.AppendUint32(data, fetchSize)
+		require.NoError(t, cc.handleStmtFetch(ctx, data))
+		for buf := out.Bytes(); len(buf) > 0; {
+			length := int(buf[0]) | int(buf[1])<<8 | int(buf[2])<<16
+			payload := buf[4 : 4+length]
+			buf = buf[4+length:]
+			switch payload[0] {
+			case mysql.OKHeader:
+				// The binary row of a single bigint column, whose null bitmap takes 1 byte.
+				rows = append(rows, int64(binary.LittleEndian.Uint64(payload[2:])))
+			case mysql.EOFHeader:
+				cursorExists = binary.LittleEndian.Uint16(payload[3:])&mysql.ServerStatusCursorExists > 0
+			}
+		}
+		return rows, cursorExists
+	}
+	// The general statements of the same templates rebuild the shared plans with their own params, and the other
+	// statements overwrite the params of the session.
+	interleave := func() {
+		for _, sql := range []string{
+			"select a from t where b > 6 order by a",
+			"select a from t where b = 7",
+			"select a from t where b in (1, 8) order by a",
+			"select a from t where b < 2",
+		} {
+			require.NoError(t, cc.handleQuery(ctx, sql))
+		}
+	}
+	interleave()
+	require.NoError(t, cc.handleStmtPrepare(ctx, "select a from t where b > ? order by a"))
+	require.NoError(t, cc.handleStmtPrepare(ctx, "select a from t where b = ?"))
+	require.NoError(t, cc.handleStmtPrepare(ctx, "select a from t where b in (?, ?) order by a"))
+	executeWithCursor(1, 2)
+	executeWithCursor(2, 3)
+	executeWithCursor(3, 4, 5)
+
+	interleave()
+	rows, cursorExists := fetch(1, 2)
+	require.Equal(t, []int64{3, 4}, rows)
+	require.True(t, cursorExists)
+	interleave()
+	rows, cursorExists = fetch(2, 2)
+	require.Equal(t, []int64{3}, rows)
+	require.True(t, cursorExists)
+	interleave()
+	rows, cursorExists = fetch(3, 2)
+	require.Equal(t, []int64{4, 5}, rows)
+	require.True(t, cursorExists)
+	interleave()
+	rows, cursorExists = fetch(1, 10)
+	require.Equal(t, []int64{5, 6, 7, 8}, rows)
+	require.True(t, cursorExists)
+
+	// The cursors are closed after all the rows are sent, and can't be fetched any more.
+	for _, stmtID := range []uint32{1, 2, 3} {
+		rows, cursorExists = fetch(stmtID, 10)
+		require.Empty(t, rows)
+		require.False(t, cursorExists)
+		err := cc.handleStmtFetch(ctx, binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, stmtID), 10))
+		require.ErrorContains(t, err, fmt.Sprintf("The statement (%d) has no open cursor", stmtID))
+	}
+
+	// The cursor can be opened again on the statement.
+	executeWithCursor(1, 6)
+	interleave()
+	rows, cursorExists = fetch(1, 10)
+	require.Equal(t, []int64{7, 8}, rows)
+	require.True(t, cursorExists)
+}
@@ -447,7 +447,9 @@ func (trs *tidbResultSet) Columns() []*ColumnInfo {
 	if trs.preparedStmt != nil {
 		ps := trs.preparedStmt
 		if colInfos, ok := ps.ColumnInfos.([]*ColumnInfo); ok {
-			trs.columns = colInfos
+			// The cached column infos are shared by all the result sets of the statement, which may be held by
+			// cursors, so every result set keeps its own copy.
+			trs.columns = append([]*ColumnInfo(nil), colInfos...)
 		}
 	}
 	if trs.columns == nil {
@@ -69,6 +69,7 @@ import (
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/tikv/client-go/v2/tikv"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 )
 
 const (
@@ -370,6 +371,11 @@ type ddlResignOwnerHandler struct {
 	store kv.Storage
 }
 
+// processListHandler is the handler for listing the connections and killing the queries of this TiDB instance.
+type processListHandler struct {
+	sm util.SessionManager
+}
+
 type serverInfoHandler struct {
 	*tikvHandlerTool
 }
@@ -1337,6 +1343,42 @@ func (h ddlResignOwnerHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 	writeData(w, "success!")
 }
 
+// ServeHTTP handles request of listing the connections, or killing the query of the connection.
+func (h processListHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	connIDStr, ok := params["connID"]
+	if !ok {
+		pl := h.sm.ShowProcessList()
+		items := make([]*infosync.ProcessListItem, 0, len(pl))
+		for _, pi := range pl {
+			items = append(items, infosync.NewProcessListItem(pi))
+		}
+		slices.SortFunc(items, func(i, j *infosync.ProcessListItem) bool {
+			return i.ConnectionID < j.ConnectionID
+		})
+		writeData(w, items)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		writeError(w, errors.Errorf("This api only support POST method"))
+		return
+	}
+	connID, err := strconv.ParseUint(connIDStr, 10, 64)
+	if err != nil {
+		writeError(w, errors.Errorf("invalid connection ID '%s'", connIDStr))
+		return
+	}
+	if _, ok := h.sm.GetProcessInfo(connID); !ok {
+		writeError(w, errors.Errorf("connection %d not found", connID))
+		return
+	}
+	h.sm.Kill(connID, true)
+	logutil.BgLogger().Info("kill the query by the status API", zap.Uint64("connID", connID),
+		zap.String("remoteAddr", req.RemoteAddr))
+	writeData(w, "success!")
+}
+
 func (h tableHandler) getPDAddr() ([]string, error) {
 	etcd, ok := h.Store.(kv.EtcdBackend)
 	if !ok {
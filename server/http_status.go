@@ -221,6 +221,10 @@ func (s *Server) startHTTPServer() {
 	router.Handle("/ddl/history", ddlHistoryJobHandler{tikvHandlerTool}).Name("DDL_History")
 	router.Handle("/ddl/owner/resign", ddlResignOwnerHandler{tikvHandlerTool.Store.(kv.Storage)}).Name("DDL_Owner_Resign")
 
+	// HTTP path for listing the connections and killing the queries of this TiDB instance.
+	router.Handle("/processlist", processListHandler{s}).Name("ProcessList")
+	router.Handle("/processlist/{connID}/kill", processListHandler{s})
+
 	// HTTP path for get the TiDB config
 	router.Handle("/config", fn.Wrap(func() (*config.Config, error) {
 		return config.GetGlobalConfig(), nil
@@ -829,6 +829,24 @@ func (s *Server) DeleteInternalSession(se interface{}) {
 	s.sessionMapMutex.Unlock()
 }
 
+// FlushPlanCache implements SessionManager interface.
+func (s *Server) FlushPlanCache() int {
+	s.rwlock.RLock()
+	sessions := make([]session.Session, 0, len(s.clients))
+	for _, client := range s.clients {
+		if client.ctx.Session != nil {
+			sessions = append(sessions, client.ctx.Session)
+		}
+	}
+	s.rwlock.RUnlock()
+
+	evicted := 0
+	for _, se := range sessions {
+		evicted += se.FlushPlanCache()
+	}
+	return evicted
+}
+
 // GetInternalSessionStartTSList implements SessionManager interface.
 func (s *Server) GetInternalSessionStartTSList() []uint64 {
 	s.sessionMapMutex.Lock()
@@ -339,6 +339,38 @@ func BenchmarkPreparedPointGet(b *testing.B) {
 	b.StopTimer()
 }
 
+func BenchmarkGeneralPlanCachePointGet(b *testing.B) {
+	ctx := context.Background()
+	se, do, st := prepareBenchSession()
+	defer func() {
+		se.Close()
+		do.Close()
+		st.Close()
+	}()
+	mustExecute(se, "create table t (pk int primary key)")
+	mustExecute(se, "insert t values (61),(62),(63),(64)")
+	mustExecute(se, "set tidb_enable_general_plan_cache = 1")
+
+	alloc := chunk.NewAllocator()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		execStmt, ok := se.Parameterize(ctx, "select * from t where pk = 64")
+		if !ok {
+			b.Fatal("the point get isn't parameterized")
+		}
+		rs, err := se.ExecuteStmt(ctx, execStmt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = drainRecordSet(ctx, se.(*session), rs, alloc)
+		if err != nil {
+			b.Fatal(err)
+		}
+		alloc.Reset()
+	}
+	b.StopTimer()
+}
+
 func BenchmarkStringIndexLookup(b *testing.B) {
 	ctx := context.Background()
 	se, do, st := prepareBenchSession()
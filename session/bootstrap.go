@@ -424,6 +424,55 @@ const (
 	CreateAdvisoryLocks = `CREATE TABLE IF NOT EXISTS mysql.advisory_locks (
 		lock_name VARCHAR(64) NOT NULL PRIMARY KEY
 	);`
+	// CreateAdminOperationLog stores the records of destructive admin operations, such as FLASHBACK CLUSTER.
+	CreateAdminOperationLog = `CREATE TABLE IF NOT EXISTS mysql.admin_operation_log (
+		id BIGINT(64) UNSIGNED NOT NULL AUTO_INCREMENT,
+		create_time TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		user VARCHAR(32) NOT NULL DEFAULT '',
+		host VARCHAR(255) NOT NULL DEFAULT '',
+		stmt_type VARCHAR(64) NOT NULL,
+		digest VARCHAR(64) NOT NULL DEFAULT '',
+		query TEXT NOT NULL,
+		target_ts BIGINT(64) UNSIGNED comment 'the target TSO of FLASHBACK CLUSTER',
+		job_id BIGINT(64),
+		result ENUM('pending', 'succeed', 'failed') NOT NULL,
+		message TEXT,
+		PRIMARY KEY (id),
+		KEY (create_time)
+	);`
+	// CreateSchemaRollbackLog stores the schema rollbacks of the flashback statements, written by the default schema
+	// rollback listener of the domain.
+	CreateSchemaRollbackLog = `CREATE TABLE IF NOT EXISTS mysql.schema_rollback_log (
+		id BIGINT(64) UNSIGNED NOT NULL AUTO_INCREMENT,
+		create_time TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		old_schema_version BIGINT(64) NOT NULL,
+		new_schema_version BIGINT(64) NOT NULL,
+		flashback_ts BIGINT(64) UNSIGNED NOT NULL,
+		PRIMARY KEY (id),
+		KEY (create_time)
+	);`
+	// CreateFlashbackCheckpoint stores the checkpoints of the running flashback jobs, the jobs resume from them after
+	// the step is retried. They're purged when the job finishes, see ddl.CleanupOrphanFlashbackCheckpoints for the
+	// ones left by the lost jobs.
+	CreateFlashbackCheckpoint = `CREATE TABLE IF NOT EXISTS mysql.tidb_flashback_checkpoint (
+		job_id BIGINT(64) NOT NULL,
+		range_index BIGINT(64) NOT NULL comment 'the index of the key range flashed back by the job',
+		end_key VARBINARY(3072) NOT NULL comment 'the key before which the range is flashed back',
+		region_count BIGINT(64) NOT NULL comment 'the number of the regions flashed back in the range',
+		updated_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+		PRIMARY KEY (job_id, range_index),
+		KEY (updated_at)
+	);`
+	// CreateFlashbackWatermark stores the timeline intervals erased by the finished flashback jobs, the stale reads
+	// inside them are checked according to tidb_stale_read_flashback_check.
+	CreateFlashbackWatermark = `CREATE TABLE IF NOT EXISTS mysql.flashback_watermark (
+		job_id BIGINT(64) NOT NULL,
+		table_id BIGINT(64) NOT NULL DEFAULT 0 comment '0 if the whole cluster is flashed back',
+		target_ts BIGINT(64) UNSIGNED NOT NULL comment 'the timestamp flashed back to',
+		end_ts BIGINT(64) UNSIGNED NOT NULL comment 'the timestamp of the final commit of the flashback job',
+		create_time TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		PRIMARY KEY (job_id, table_id)
+	);`
 )
 
 // bootstrap initiates system DB for a store.
@@ -627,11 +676,19 @@ const (
 	version92 = 92
 	// version93 converts oom-use-tmp-storage to a sysvar
 	version93 = 93
+	// version94 adds the table mysql.admin_operation_log
+	version94 = 94
+	// version95 adds the table mysql.schema_rollback_log
+	version95 = 95
+	// version96 adds the table mysql.tidb_flashback_checkpoint
+	version96 = 96
+	// version97 adds the table mysql.flashback_watermark
+	version97 = 97
 )
 
 // currentBootstrapVersion is defined as a variable, so we can modify its value for testing.
 // please make sure this is the largest version
-var currentBootstrapVersion int64 = version93
+var currentBootstrapVersion int64 = version97
 
 // DDL owner key's expired time is ManagerSessionTTL seconds, we should wait the time and give more time to have a chance to finish it.
 var internalSQLTimeout = owner.ManagerSessionTTL + 15
@@ -730,6 +787,10 @@ var (
 		upgradeToVer90,
 		upgradeToVer91,
 		upgradeToVer93,
+		upgradeToVer94,
+		upgradeToVer95,
+		upgradeToVer96,
+		upgradeToVer97,
 	}
 )
 
@@ -1918,6 +1979,34 @@ func upgradeToVer93(s Session, ver int64) {
 	importConfigOption(s, "oom-use-tmp-storage", variable.TiDBEnableTmpStorageOnOOM, valStr)
 }
 
+func upgradeToVer94(s Session, ver int64) {
+	if ver >= version94 {
+		return
+	}
+	doReentrantDDL(s, CreateAdminOperationLog)
+}
+
+func upgradeToVer95(s Session, ver int64) {
+	if ver >= version95 {
+		return
+	}
+	doReentrantDDL(s, CreateSchemaRollbackLog)
+}
+
+func upgradeToVer96(s Session, ver int64) {
+	if ver >= version96 {
+		return
+	}
+	doReentrantDDL(s, CreateFlashbackCheckpoint)
+}
+
+func upgradeToVer97(s Session, ver int64) {
+	if ver >= version97 {
+		return
+	}
+	doReentrantDDL(s, CreateFlashbackWatermark)
+}
+
 func writeOOMAction(s Session) {
 	comment := "oom-action is `log` by default in v3.0.x, `cancel` by default in v4.0.11+"
 	mustExecute(s, `INSERT HIGH_PRIORITY INTO %n.%n VALUES (%?, %?, %?) ON DUPLICATE KEY UPDATE VARIABLE_VALUE= %?`,
@@ -2012,6 +2101,14 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateAnalyzeJobs)
 	// Create advisory_locks table.
 	mustExecute(s, CreateAdvisoryLocks)
+	// Create admin_operation_log table.
+	mustExecute(s, CreateAdminOperationLog)
+	// Create schema_rollback_log table.
+	mustExecute(s, CreateSchemaRollbackLog)
+	// Create tidb_flashback_checkpoint table.
+	mustExecute(s, CreateFlashbackCheckpoint)
+	// Create flashback_watermark table.
+	mustExecute(s, CreateFlashbackWatermark)
 }
 
 // inTestSuite checks if we are bootstrapping in the context of tests.
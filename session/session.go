@@ -87,6 +87,7 @@ import (
 	"github.com/pingcap/tidb/util/collate"
 	"github.com/pingcap/tidb/util/dbterror"
 	"github.com/pingcap/tidb/util/execdetails"
+	"github.com/pingcap/tidb/util/gcutil"
 	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/logutil/consistency"
@@ -104,6 +105,7 @@ import (
 	"github.com/tikv/client-go/v2/oracle"
 	tikvutil "github.com/tikv/client-go/v2/util"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 )
 
 var (
@@ -153,6 +155,9 @@ type Session interface {
 	Parameterize(ctx context.Context, originSQL string) (*ast.ExecuteStmt, bool)
 	// ExecuteInternal is a helper around ParseWithParams() and ExecuteStmt(). It is not allowed to execute multiple statements.
 	ExecuteInternal(context.Context, string, ...interface{}) (sqlexec.RecordSet, error)
+	// WithSnapshotProtection runs fn while holding a GC service safe point at snapshotTS, so snapshot reads at
+	// snapshotTS inside fn won't fail because of an advancing GC safe point.
+	WithSnapshotProtection(ctx context.Context, snapshotTS uint64, fn func(ctx context.Context) error) error
 	String() string // String is used to debug.
 	CommitTxn(context.Context) error
 	RollbackTxn(context.Context)
@@ -160,6 +165,8 @@ type Session interface {
 	PrepareStmt(sql string) (stmtID uint32, paramCount int, fields []*ast.ResultField, err error)
 	// CacheGeneralStmt parses the sql, generates the corresponding PlanCacheStmt and cache it.
 	CacheGeneralStmt(sql string) (interface{}, error)
+	// FlushPlanCache flushes the prepared and general plan caches, and returns the number of evicted entries.
+	FlushPlanCache() int
 	// ExecutePreparedStmt executes a prepared statement.
 	// Deprecated: please use ExecuteStmt, this function is left for testing only.
 	// TODO: remove ExecutePreparedStmt.
@@ -234,6 +241,8 @@ type session struct {
 
 	store kv.Storage
 
+	// planCacheMu protects the lazy construction of the plan caches, because they may be flushed by other sessions.
+	planCacheMu       sync.Mutex
 	preparedPlanCache sessionctx.PlanCache
 	generalPlanCache  sessionctx.PlanCache
 
@@ -435,10 +444,11 @@ func (s *session) SetCollation(coID int) error {
 }
 
 func (s *session) GetPlanCache(isGeneralPlanCache bool) sessionctx.PlanCache {
+	s.planCacheMu.Lock()
+	defer s.planCacheMu.Unlock()
 	if isGeneralPlanCache { // use the general plan cache
-		if !s.GetSessionVars().EnableGeneralPlanCache {
-			return nil
-		}
+		// It's not checked against tidb_enable_general_plan_cache here, since the `USE_PLAN_CACHE()` hint enables the
+		// general plan cache for a statement even if it's disabled in the session, see Parameterize.
 		if s.generalPlanCache == nil { // lazy construction
 			s.generalPlanCache = plannercore.NewLRUPlanCache(uint(s.GetSessionVars().GeneralPlanCacheSize),
 				variable.PreparedPlanCacheMemoryGuardRatio.Load(), plannercore.PreparedPlanCacheMaxMemory.Load(),
@@ -459,6 +469,34 @@ func (s *session) GetPlanCache(isGeneralPlanCache bool) sessionctx.PlanCache {
 	return s.preparedPlanCache
 }
 
+// FlushPlanCache flushes both the prepared and the general plan caches of the session, and returns the number of
+// evicted entries. It's safe to be called by other sessions.
+func (s *session) FlushPlanCache() int {
+	s.planCacheMu.Lock()
+	defer s.planCacheMu.Unlock()
+	evicted := 0
+	for _, cache := range []sessionctx.PlanCache{s.preparedPlanCache, s.generalPlanCache} {
+		if cache != nil {
+			evicted += cache.Size()
+			cache.DeleteAll()
+		}
+	}
+	return evicted
+}
+
+// closePlanCache releases the plan caches of the session, so their memory isn't tracked by the global memory
+// tracker any more.
+func (s *session) closePlanCache() {
+	s.planCacheMu.Lock()
+	defer s.planCacheMu.Unlock()
+	for _, cache := range []sessionctx.PlanCache{s.preparedPlanCache, s.generalPlanCache} {
+		if cache != nil {
+			cache.Close()
+		}
+	}
+	s.preparedPlanCache, s.generalPlanCache = nil, nil
+}
+
 func (s *session) SetSessionManager(sm util.SessionManager) {
 	s.sessionManager = sm
 }
@@ -642,6 +680,11 @@ func (s *session) doCommit(ctx context.Context) error {
 		}
 		physicalTableIDs = append(physicalTableIDs, id)
 	}
+	startTS := s.txn.StartTS()
+	if err = s.checkFlashbackCommitConflict(startTS, physicalTableIDs); err != nil {
+		s.RollbackTxn(ctx)
+		return err
+	}
 	// Set this option for 2 phase commit to validate schema lease.
 	s.txn.SetOption(kv.SchemaChecker, domain.NewSchemaChecker(domain.GetDomain(s), s.GetInfoSchema().SchemaMetaVersion(), physicalTableIDs))
 	s.txn.SetOption(kv.InfoSchema, s.sessionVars.TxnCtx.InfoSchema)
@@ -685,9 +728,41 @@ func (s *session) doCommit(ctx context.Context) error {
 	if err != nil {
 		err = s.handleAssertionFailure(ctx, err)
 	}
+	if kv.ErrWriteConflict.Equal(err) {
+		// The keys may be rewritten by a flashback job which is seen after the check above.
+		if conflictErr := s.checkFlashbackCommitConflict(startTS, physicalTableIDs); conflictErr != nil {
+			err = conflictErr
+		}
+	}
 	return err
 }
 
+// checkFlashbackCommitConflict returns ErrFlashbackCommitConflict if the transaction starts before a flashback job
+// on the tables it writes finishes, i.e. it starts before the read-only window of the flashback and commits after it.
+// Its writes would be put on top of the rewound data, so it's rejected as if the flashback installed a min commit TS
+// barrier, instead of leaving the "future" data or failing with a generic write conflict. The commit isn't blocked if
+// the flashback watermarks can't be loaded.
+func (s *session) checkFlashbackCommitConflict(startTS uint64, physicalTableIDs []int64) error {
+	dom := domain.GetDomain(s)
+	if dom == nil {
+		return nil
+	}
+	watermarks, err := dom.GetFlashbackWatermarks()
+	if err != nil {
+		logutil.BgLogger().Warn("get the flashback watermarks failed, skip checking the commit", zap.Uint64("startTS", startTS), zap.Error(err))
+		return nil
+	}
+	for _, w := range watermarks {
+		if startTS >= w.EndTS {
+			continue
+		}
+		if w.TableID == 0 || slices.Contains(physicalTableIDs, w.TableID) {
+			return kv.ErrFlashbackCommitConflict.FastGenByArgs(startTS, w.JobID, w.EndTS, w.TargetTS)
+		}
+	}
+	return nil
+}
+
 type cachedTableRenewLease struct {
 	tables map[int64]interface{}
 	lease  []uint64 // Lease for each visited cached tables.
@@ -1500,6 +1575,8 @@ func (s *session) SetProcessInfo(sql string, t time.Time, command byte, maxExecu
 	if s.sessionVars.User != nil {
 		pi.User = s.sessionVars.User.Username
 		pi.Host = s.sessionVars.User.Hostname
+	} else if s.sessionVars.SysProcessName != "" {
+		pi.User = s.sessionVars.SysProcessName
 	}
 	s.processInfo.Store(&pi)
 }
@@ -1577,21 +1654,36 @@ func (s *session) Execute(ctx context.Context, sql string) (recordSets []sqlexec
 
 // Parameterize Parameterizes this sql.
 func (s *session) Parameterize(ctx context.Context, originSQL string) (exec *ast.ExecuteStmt, ok bool) {
-	if !s.GetSessionVars().EnableGeneralPlanCache {
+	switch plannercore.GetPlanCacheHint(originSQL) {
+	case plannercore.IgnorePlanCacheHint:
+		plannercore.TracePlanCache(ctx, s, "the %s hint skips the general plan cache", plannercore.HintIgnorePlanCache)
 		return nil, false
+	case plannercore.UsePlanCacheHint:
+	default:
+		if !s.GetSessionVars().EnableGeneralPlanCache {
+			return nil, false
+		}
 	}
-	paramSQL, params, ok, err := plannercore.Parameterize(s, originSQL)
+	beginParameterize := time.Now()
+	paramSQL, params, paramTexts, ok, err := plannercore.Parameterize(s, originSQL)
+	s.sessionVars.DurationParameterize = time.Since(beginParameterize)
 	if !ok || err != nil {
+		plannercore.TracePlanCache(ctx, s, "the statement isn't parameterized, it isn't cacheable")
 		return nil, false
 	}
+	plannercore.TracePlanCache(ctx, s, "the statement is parameterized to the template %q with %d params",
+		paramSQL, len(params))
 	cachedStmt, err := s.CacheGeneralStmt(paramSQL)
 	if err != nil {
+		plannercore.TracePlanCache(ctx, s, "cache the template failed: %v", err)
 		return nil, false
 	}
 	return &ast.ExecuteStmt{
 		BinaryArgs:      params,
 		PrepStmt:        cachedStmt,
 		FromGeneralStmt: true,
+		ParamTexts:      paramTexts,
+		GeneralSQL:      originSQL,
 	}, true
 }
 
@@ -2051,8 +2143,9 @@ func (s *session) ExecuteStmt(ctx context.Context, stmtNode ast.StmtNode) (sqlex
 	}
 	s.currentPlan = stmt.Plan
 	if execStmt, ok := stmtNode.(*ast.ExecuteStmt); ok {
-		if execStmt.Name == "" {
+		if execStmt.Name == "" && !execStmt.FromGeneralStmt {
 			// for exec-stmt on bin-protocol, ignore the plan detail in `show process` to gain performance benefits.
+			// The statements served by the general plan cache are text queries, they show the plan as uncached.
 			s.currentPlan = nil
 		}
 	}
@@ -2308,6 +2401,16 @@ func (s *session) CacheGeneralStmt(sql string) (interface{}, error) {
 	return prepareExec.Stmt, nil
 }
 
+// WithSnapshotProtection implements the Session interface.
+func (s *session) WithSnapshotProtection(ctx context.Context, snapshotTS uint64, fn func(ctx context.Context) error) error {
+	store, ok := s.store.(kv.StorageWithPD)
+	if !ok || store.GetPDClient() == nil {
+		return fn(ctx)
+	}
+	serviceID := fmt.Sprintf("tidb-snapshot-%d-%d", s.sessionVars.ConnectionID, snapshotTS)
+	return gcutil.WithSnapshotProtection(ctx, store.GetPDClient(), serviceID, snapshotTS, fn)
+}
+
 // PrepareStmt is used for executing prepare statement in binary protocol
 func (s *session) PrepareStmt(sql string) (stmtID uint32, paramCount int, fields []*ast.ResultField, err error) {
 	if s.sessionVars.TxnCtx.InfoSchema == nil {
@@ -2439,6 +2542,7 @@ func (s *session) Close() {
 	if s.stmtStats != nil {
 		s.stmtStats.SetFinished()
 	}
+	s.closePlanCache()
 	s.ClearDiskFullOpt()
 }
 
@@ -2695,7 +2799,7 @@ func BootstrapSession(store kv.Storage) (*domain.Domain, error) {
 	}
 
 	concurrency := int(config.GetGlobalConfig().Performance.StatsLoadConcurrency)
-	ses, err := createSessions(store, 7+concurrency)
+	ses, err := createSessions(store, 8+concurrency)
 	if err != nil {
 		return nil, err
 	}
@@ -2777,6 +2881,8 @@ func BootstrapSession(store kv.Storage) (*domain.Domain, error) {
 
 	dom.DumpFileGcCheckerLoop()
 	dom.LoadSigningCertLoop()
+	dom.FlushPlanCacheLoop()
+	dom.CleanupOrphanFlashbackLoop(ses[7+concurrency])
 
 	if raw, ok := store.(kv.EtcdBackend); ok {
 		err = raw.StartGCWorker()
@@ -59,6 +59,8 @@ type PlanCache interface {
 	DeleteAll()
 	Size() int
 	SetCapacity(capacity uint) error
+	// Close releases the cache, it's called when the session is closed.
+	Close()
 }
 
 // Context is an interface for transaction and executive args environment.
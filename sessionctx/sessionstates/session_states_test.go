@@ -394,7 +394,7 @@ func TestSessionCtx(t *testing.T) {
 		{
 			// check FoundInPlanCache
 			setFunc: func(tk *testkit.TestKit) any {
-				require.False(t, tk.Session().GetSessionVars().FoundInPlanCache)
+				require.False(t, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
 				return nil
 			},
 			checkFunc: func(tk *testkit.TestKit, param any) {
@@ -407,7 +407,7 @@ func TestSessionCtx(t *testing.T) {
 				tk.MustExec("prepare stmt from 'select * from test.t1'")
 				tk.MustQuery("execute stmt")
 				tk.MustQuery("execute stmt")
-				require.True(t, tk.Session().GetSessionVars().FoundInPlanCache)
+				require.True(t, tk.Session().GetSessionVars().StmtCtx.FoundInPlanCache())
 				return nil
 			},
 			checkFunc: func(tk *testkit.TestKit, param any) {
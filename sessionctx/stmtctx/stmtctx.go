@@ -239,6 +239,17 @@ type StatementContext struct {
 	TblInfo2UnionScan     map[*model.TableInfo]bool
 	TaskID                uint64 // unique ID for an execution of a statement
 	TaskMapBakTS          uint64 // counter for
+	// NonDeterministicExprs4PC records the non-deterministic expressions of the plan, e.g. `now()` and the reads of
+	// user variables. They're not folded into the plan, and are re-evaluated when the plan is reused by the plan cache.
+	NonDeterministicExprs4PC []string
+	// UserVars4PC records the names of the user variables read by the plan.
+	UserVars4PC []string
+	// EqualParams4PC records the pairs of the params, by their orders, which are assumed to be equal by the plan.
+	// e.g. `a BETWEEN ? AND ?` is built as `a = ?` if both bounds are equal.
+	EqualParams4PC [][2]int
+	// Views4PC records the views expanded by the plan, including the nested ones. Their definitions are folded into
+	// the plan, so the plan can't be reused once they're redefined.
+	Views4PC []*model.TableInfo
 
 	// stmtCache is used to store some statement-related values.
 	// add mutex to protect stmtCache concurrent access
@@ -308,6 +319,10 @@ type StatementContext struct {
 	// RCCheckTS indicates the current read-consistency read select statement will use `RCCheckTS` path.
 	RCCheckTS bool
 
+	// planCacheStatus tells how the plan of the statement is got from the plan cache, see PlanCacheStatus. It's set
+	// once per execution by SetPlanCacheStatus, and may be read concurrently by the async executors.
+	planCacheStatus atomic2.Int32
+
 	// IsSQLRegistered uses to indicate whether the SQL has been registered for TopSQL.
 	IsSQLRegistered atomic2.Bool
 	// IsSQLAndPlanRegistered uses to indicate whether the SQL and plan has been registered for TopSQL.
@@ -321,6 +336,35 @@ type StatementContext struct {
 	ColRefFromUpdatePlan []int64
 }
 
+// PlanCacheStatus tells how the plan of the statement is got from the plan cache.
+type PlanCacheStatus int32
+
+const (
+	// PlanCacheNotUsed means the statement doesn't go through the plan cache, e.g. it isn't prepared.
+	PlanCacheNotUsed PlanCacheStatus = iota
+	// PlanCacheHit means the plan is got from the plan cache.
+	PlanCacheHit
+	// PlanCacheMiss means the plan isn't found in the plan cache, it's built by the optimizer.
+	PlanCacheMiss
+	// PlanCacheSkip means the statement goes through the plan cache, but it's uncacheable in this execution, e.g.
+	// it's a stale read, so the plan is built by the optimizer without looking up the plan cache.
+	PlanCacheSkip
+)
+
+// String implements the fmt.Stringer interface.
+func (s PlanCacheStatus) String() string {
+	switch s {
+	case PlanCacheHit:
+		return "hit"
+	case PlanCacheMiss:
+		return "miss"
+	case PlanCacheSkip:
+		return "skip"
+	default:
+		return "none"
+	}
+}
+
 // StmtHints are SessionVars related sql hints.
 type StmtHints struct {
 	// Hint Information
@@ -470,6 +514,22 @@ func (sc *StatementContext) SetPlanDigest(normalized string, planDigest *parser.
 	}
 }
 
+// SetPlanCacheStatus sets how the plan of the statement is got from the plan cache. It's called once per execution,
+// the later calls are ignored, so the status can't be overwritten by the plans built for the internal retries.
+func (sc *StatementContext) SetPlanCacheStatus(status PlanCacheStatus) {
+	sc.planCacheStatus.CAS(int32(PlanCacheNotUsed), int32(status))
+}
+
+// GetPlanCacheStatus gets how the plan of the statement is got from the plan cache.
+func (sc *StatementContext) GetPlanCacheStatus() PlanCacheStatus {
+	return PlanCacheStatus(sc.planCacheStatus.Load())
+}
+
+// FoundInPlanCache checks whether the plan of the statement is got from the plan cache.
+func (sc *StatementContext) FoundInPlanCache() bool {
+	return sc.GetPlanCacheStatus() == PlanCacheHit
+}
+
 // GetEncodedPlan gets the encoded plan, it is used to avoid repeated encode.
 func (sc *StatementContext) GetEncodedPlan() string {
 	return sc.encodedPlan
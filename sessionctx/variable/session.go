@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/rand"
 	"net"
@@ -591,6 +592,10 @@ type SessionVars struct {
 	}
 	// systems variables, don't modify it directly, use GetSystemVar/SetSystemVar method.
 	systems map[string]string
+	// optimizerVarsHash4PC is the hash of the optimizer variables in systems, it's recomputed lazily after any of
+	// them changes, see OptimizerVarsHash4PC.
+	optimizerVarsHash4PC      uint64
+	optimizerVarsHash4PCValid bool
 	// stmtVars variables are temporarily set by SET_VAR hint
 	// It only take effect for the duration of a single statement
 	stmtVars map[string]string
@@ -681,6 +686,16 @@ type SessionVars struct {
 	// SnapshotTS is used for reading history data. For simplicity, SnapshotTS only supports distsql request.
 	SnapshotTS uint64
 
+	// FlashbackReadinessTS is the timestamp checked by information_schema.flashback_readiness.
+	FlashbackReadinessTS uint64
+
+	// OptimizerStatsAsOf is the timestamp of the stats used by the optimizer, 0 means the latest stats are used.
+	OptimizerStatsAsOf uint64
+
+	// OptimizerStatsSnapshot is the stats snapshot as of OptimizerStatsAsOf loaded by the optimizer, it's kept across
+	// the statements until OptimizerStatsAsOf is changed.
+	OptimizerStatsSnapshot interface{}
+
 	// TxnReadTS is used for staleness transaction, it provides next staleness transaction startTS.
 	TxnReadTS *TxnReadTS
 
@@ -720,6 +735,16 @@ type SessionVars struct {
 	// MultiStatementMode permits incorrect client library usage. Not recommended to be turned on.
 	MultiStatementMode int
 
+	// FlashbackBatchSize is the max number of the regions flashed back by one request of a FLASHBACK job.
+	FlashbackBatchSize int
+
+	// StaleReadFlashbackCheck indicates how a stale read inside the timeline erased by a flashback is handled, it's
+	// one of OffInt, WarnInt and OnInt.
+	StaleReadFlashbackCheck int
+
+	// FlashbackScatterRegions indicates whether FLASHBACK CLUSTER scatters the regions of the heavy flashback ranges.
+	FlashbackScatterRegions bool
+
 	// AllowWriteRowID variable is currently not recommended to be turned on.
 	AllowWriteRowID bool
 
@@ -945,6 +970,10 @@ type SessionVars struct {
 	// Killed is a flag to indicate that this query is killed.
 	Killed uint32
 
+	// SysProcessName is shown as the user of the internal session in the process list when the session
+	// is tracked as a system process, e.g. the internal session of a DDL job.
+	SysProcessName string
+
 	// ConnectionInfo indicates current connection info used by current session.
 	ConnectionInfo *ConnectionInfo
 
@@ -957,6 +986,10 @@ type SessionVars struct {
 	// DurationParse is the duration of parsing SQL string to AST of the last query.
 	DurationParse time.Duration
 
+	// DurationParameterize is the duration of parameterizing the last query for the general plan cache, the query
+	// isn't parsed if it's parameterized.
+	DurationParameterize time.Duration
+
 	// DurationCompile is the duration of compiling AST to execution plan of the last query.
 	DurationCompile time.Duration
 
@@ -966,6 +999,10 @@ type SessionVars struct {
 	// DurationOptimization is the duration of optimizing a query.
 	DurationOptimization time.Duration
 
+	// DurationPlanCacheLookup is the duration of looking up the plan cache for a query, the optimization on the miss
+	// isn't included.
+	DurationPlanCacheLookup time.Duration
+
 	// DurationWaitTS is the duration of waiting for a snapshot TS
 	DurationWaitTS time.Duration
 
@@ -1034,9 +1071,8 @@ type SessionVars struct {
 	// see https://dev.mysql.com/doc/refman/8.0/en/window-function-optimization.html for more details.
 	WindowingUseHighPrecision bool
 
-	// FoundInPlanCache indicates whether this statement was found in plan cache.
-	FoundInPlanCache bool
-	// PrevFoundInPlanCache indicates whether the last statement was found in plan cache.
+	// PrevFoundInPlanCache indicates whether the last statement was found in plan cache. Whether the current statement
+	// is found in plan cache is told by StmtCtx.FoundInPlanCache.
 	PrevFoundInPlanCache bool
 
 	// FoundInBinding indicates whether the execution plan is matched with the hints in the binding.
@@ -1231,6 +1267,14 @@ type SessionVars struct {
 	// GeneralPlanCacheSize controls the size of general plan cache.
 	GeneralPlanCacheSize uint64
 
+	// EnableSharedPlanCacheTemplate indicates whether the prepared statements share the cached plans with the general
+	// statements of the same shape, i.e. the plans are cached in the general plan cache by the parameterized texts.
+	EnableSharedPlanCacheTemplate bool
+
+	// TracePlanCache indicates whether to record the decisions of the plan cache, e.g. the template, the cache key,
+	// the hit or miss and the checks of the cached plans, into the TRACE output of the statements.
+	TracePlanCache bool
+
 	// ConstraintCheckInPlacePessimistic controls whether to skip the locking of some keys in pessimistic transactions.
 	// Postpone the conflict check and constraint check to prewrite or later pessimistic locking requests.
 	ConstraintCheckInPlacePessimistic bool
@@ -1446,6 +1490,7 @@ func NewSessionVars() *SessionVars {
 		StrictSQLMode:                 true,
 		AutoIncrementIncrement:        DefAutoIncrementIncrement,
 		AutoIncrementOffset:           DefAutoIncrementOffset,
+		FlashbackBatchSize:            DefTiDBFlashbackBatchSize,
 		Status:                        mysql.ServerStatusAutocommit,
 		StmtCtx:                       new(stmtctx.StatementContext),
 		AllowAggPushDown:              false,
@@ -1494,7 +1539,6 @@ func NewSessionVars() *SessionVars {
 		SequenceState:                 NewSequenceState(),
 		WindowingUseHighPrecision:     true,
 		PrevFoundInPlanCache:          DefTiDBFoundInPlanCache,
-		FoundInPlanCache:              DefTiDBFoundInPlanCache,
 		PrevFoundInBinding:            DefTiDBFoundInBinding,
 		FoundInBinding:                DefTiDBFoundInBinding,
 		SelectLimit:                   math.MaxUint64,
@@ -1961,6 +2005,9 @@ func (s *SessionVars) GetSessionOrGlobalSystemVar(name string) (string, error) {
 			} else {
 				s.systems[sv.Name] = sv.Value // no global scope, use default
 			}
+			if isOptimizerVar4PC(sv.Name) {
+				s.optimizerVarsHash4PCValid = false
+			}
 		}
 		return sv.GetSessionFromHook(s)
 	}
@@ -2029,6 +2076,43 @@ func (s *SessionVars) SetSystemVar(name string, val string) error {
 	return sv.SetSessionFromHook(s, val)
 }
 
+// isOptimizerVar4PC checks whether the variable affects the plans built by the optimizer, so the plans built under
+// different values of it can't be shared by the plan cache.
+func isOptimizerVar4PC(name string) bool {
+	if strings.HasPrefix(name, "tidb_opt_") {
+		return true
+	}
+	switch name {
+	case TiDBCostModelVersion, TiDBAllowMPPExecution, TiDBEnforceMPPExecution, TiDBAllowBatchCop:
+		return true
+	}
+	return false
+}
+
+// OptimizerVarsHash4PC returns the hash of the session values of the optimizer variables, it's a part of the plan
+// cache key. The hash is only recomputed after the variables are changed, rather than on every lookup.
+func (s *SessionVars) OptimizerVarsHash4PC() uint64 {
+	if s.optimizerVarsHash4PCValid {
+		return s.optimizerVarsHash4PC
+	}
+	names := make([]string, 0, 64)
+	for name := range s.systems {
+		if isOptimizerVar4PC(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	h := fnv.New64a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{'='})
+		h.Write([]byte(s.systems[name]))
+		h.Write([]byte{0})
+	}
+	s.optimizerVarsHash4PC, s.optimizerVarsHash4PCValid = h.Sum64(), true
+	return s.optimizerVarsHash4PC
+}
+
 // SetSystemVarWithoutValidation sets the value of a system variable for session scope.
 // Deprecated: Values are NOT normalized or Validated.
 func (s *SessionVars) SetSystemVarWithoutValidation(name string, val string) error {
@@ -2161,10 +2245,12 @@ func (s *SessionVars) DecodeSessionStates(ctx context.Context, sessionStates *se
 	if sessionStates.MPPStoreLastFailTime != nil {
 		s.MPPStoreLastFailTime = sessionStates.MPPStoreLastFailTime
 	}
-	s.FoundInPlanCache = sessionStates.FoundInPlanCache
 	s.FoundInBinding = sessionStates.FoundInBinding
 
 	// Decode StatementContext.
+	if sessionStates.FoundInPlanCache {
+		s.StmtCtx.SetPlanCacheStatus(stmtctx.PlanCacheHit)
+	}
 	s.StmtCtx.SetAffectedRows(uint64(sessionStates.LastAffectedRows))
 	s.StmtCtx.PrevLastInsertID = sessionStates.LastInsertID
 	s.StmtCtx.SetWarnings(sessionStates.Warnings)
@@ -2450,6 +2536,10 @@ const (
 	SlowLogRewriteTimeStr = "Rewrite_time"
 	// SlowLogOptimizeTimeStr is the optimization time.
 	SlowLogOptimizeTimeStr = "Optimize_time"
+	// SlowLogParameterizeTimeStr is the time of parameterizing the sql for the general plan cache.
+	SlowLogParameterizeTimeStr = "Parameterize_time"
+	// SlowLogPlanCacheLookupTimeStr is the time of looking up the plan cache.
+	SlowLogPlanCacheLookupTimeStr = "Plan_cache_lookup_time"
 	// SlowLogWaitTSTimeStr is the time of waiting TS.
 	SlowLogWaitTSTimeStr = "Wait_TS"
 	// SlowLogPreprocSubQueriesStr is the number of pre-processed sub-queries.
@@ -2498,6 +2588,8 @@ const (
 	SlowLogPlanFromCache = "Plan_from_cache"
 	// SlowLogPlanFromBinding is used to indicate whether this plan is matched with the hints in the binding.
 	SlowLogPlanFromBinding = "Plan_from_binding"
+	// SlowLogPlanCacheStatus is how the plan is got from the plan cache, it's one of none, hit, miss and skip.
+	SlowLogPlanCacheStatus = "Plan_cache_status"
 	// SlowLogHasMoreResults is used to indicate whether this sql has more following results.
 	SlowLogHasMoreResults = "Has_more_results"
 	// SlowLogSucc is used to indicate whether this sql execute successfully.
@@ -2557,6 +2649,8 @@ type SlowQueryLogItems struct {
 	TimeCompile       time.Duration
 	TimeOptimize      time.Duration
 	TimeWaitTS        time.Duration
+	TimeParameterize  time.Duration
+	TimeCacheLookup   time.Duration
 	IndexNames        string
 	StatsInfos        map[string]uint64
 	CopTasks          *stmtctx.CopTasksDetails
@@ -2567,6 +2661,7 @@ type SlowQueryLogItems struct {
 	Prepared          bool
 	PlanFromCache     bool
 	PlanFromBinding   bool
+	PlanCacheStatus   stmtctx.PlanCacheStatus
 	HasMoreResults    bool
 	PrevStmt          string
 	Plan              string
@@ -2647,6 +2742,11 @@ func (s *SessionVars) SlowLogFormat(logItems *SlowQueryLogItems) string {
 
 	writeSlowLogItem(&buf, SlowLogOptimizeTimeStr, strconv.FormatFloat(logItems.TimeOptimize.Seconds(), 'f', -1, 64))
 	writeSlowLogItem(&buf, SlowLogWaitTSTimeStr, strconv.FormatFloat(logItems.TimeWaitTS.Seconds(), 'f', -1, 64))
+	if logItems.TimeParameterize > 0 || logItems.TimeCacheLookup > 0 {
+		buf.WriteString(SlowLogRowPrefixStr + fmt.Sprintf("%v%v%v %v%v%v\n",
+			SlowLogParameterizeTimeStr, SlowLogSpaceMarkStr, strconv.FormatFloat(logItems.TimeParameterize.Seconds(), 'f', -1, 64),
+			SlowLogPlanCacheLookupTimeStr, SlowLogSpaceMarkStr, strconv.FormatFloat(logItems.TimeCacheLookup.Seconds(), 'f', -1, 64)))
+	}
 
 	if execDetailStr := logItems.ExecDetail.String(); len(execDetailStr) > 0 {
 		buf.WriteString(SlowLogRowPrefixStr + execDetailStr + "\n")
@@ -2744,6 +2844,7 @@ func (s *SessionVars) SlowLogFormat(logItems *SlowQueryLogItems) string {
 	writeSlowLogItem(&buf, SlowLogPrepared, strconv.FormatBool(logItems.Prepared))
 	writeSlowLogItem(&buf, SlowLogPlanFromCache, strconv.FormatBool(logItems.PlanFromCache))
 	writeSlowLogItem(&buf, SlowLogPlanFromBinding, strconv.FormatBool(logItems.PlanFromBinding))
+	writeSlowLogItem(&buf, SlowLogPlanCacheStatus, logItems.PlanCacheStatus.String())
 	writeSlowLogItem(&buf, SlowLogHasMoreResults, strconv.FormatBool(logItems.HasMoreResults))
 	writeSlowLogItem(&buf, SlowLogKVTotal, strconv.FormatFloat(logItems.KVTotal.Seconds(), 'f', -1, 64))
 	writeSlowLogItem(&buf, SlowLogPDTotal, strconv.FormatFloat(logItems.PDTotal.Seconds(), 'f', -1, 64))
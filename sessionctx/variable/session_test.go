@@ -229,6 +229,7 @@ func TestSlowLogFormat(t *testing.T) {
 # Prepared: true
 # Plan_from_cache: true
 # Plan_from_binding: true
+# Plan_cache_status: hit
 # Has_more_results: true
 # KV_total: 10
 # PD_total: 11
@@ -259,6 +260,7 @@ func TestSlowLogFormat(t *testing.T) {
 		Prepared:          true,
 		PlanFromCache:     true,
 		PlanFromBinding:   true,
+		PlanCacheStatus:   stmtctx.PlanCacheHit,
 		HasMoreResults:    true,
 		KVTotal:           10 * time.Second,
 		PDTotal:           11 * time.Second,
@@ -408,3 +410,19 @@ func TestGeneralPlanCacheStmt(t *testing.T) {
 	require.NotNil(t, sessVars.GetGeneralPlanCacheStmt(sql1))
 	require.NotNil(t, sessVars.GetGeneralPlanCacheStmt(sql2))
 }
+
+func TestOptimizerVarsHash4PC(t *testing.T) {
+	sessVars := variable.NewSessionVars()
+	hash := sessVars.OptimizerVarsHash4PC()
+	require.Equal(t, hash, sessVars.OptimizerVarsHash4PC())
+
+	// The variables unrelated to the optimizer don't change the hash.
+	require.NoError(t, sessVars.SetSystemVar(variable.TiDBMemQuotaQuery, "1024"))
+	require.Equal(t, hash, sessVars.OptimizerVarsHash4PC())
+
+	require.NoError(t, sessVars.SetSystemVar(variable.TiDBOptAggPushDown, "ON"))
+	pushDownHash := sessVars.OptimizerVarsHash4PC()
+	require.NotEqual(t, hash, pushDownHash)
+	require.NoError(t, sessVars.SetSystemVar(variable.TiDBCostModelVersion, "2"))
+	require.NotEqual(t, pushDownHash, sessVars.OptimizerVarsHash4PC())
+}
@@ -159,6 +159,31 @@ var defaultSysVars = []*SysVar{
 		}
 		return nil
 	}},
+	{Scope: ScopeSession, Name: TiDBFlashbackReadinessTS, Value: "", SetSession: func(s *SessionVars, val string) error {
+		if val == "" {
+			s.FlashbackReadinessTS = 0
+			return nil
+		}
+		ts, err := parseTSFromNumberOrTime(s, val)
+		if err != nil {
+			return err
+		}
+		s.FlashbackReadinessTS = ts
+		return nil
+	}},
+	{Scope: ScopeSession, Name: TiDBOptimizerStatsAsOf, Value: "", SetSession: func(s *SessionVars, val string) error {
+		s.OptimizerStatsSnapshot = nil
+		if val == "" {
+			s.OptimizerStatsAsOf = 0
+			return nil
+		}
+		ts, err := parseTSFromNumberOrTime(s, val)
+		if err != nil {
+			return err
+		}
+		s.OptimizerStatsAsOf = ts
+		return nil
+	}},
 	{Scope: ScopeSession, Name: TiDBOptProjectionPushDown, Value: BoolToOnOff(config.GetGlobalConfig().Performance.ProjectionPushDown), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
 		s.AllowProjectionPushDown = TiDBOptOn(val)
 		return nil
@@ -550,6 +575,10 @@ var defaultSysVars = []*SysVar{
 		SetGlobal: func(s *SessionVars, val string) error {
 			return stmtsummary.StmtSummaryByDigestMap.SetMaxSQLLength(TidbOptInt(val, DefTiDBStmtSummaryMaxSQLLength))
 		}},
+	{Scope: ScopeGlobal, Name: TiDBStmtSummaryGroupByPlanTemplate, Value: BoolToOnOff(DefTiDBStmtSummaryGroupByPlanTemplate), Type: TypeBool,
+		SetGlobal: func(s *SessionVars, val string) error {
+			return stmtsummary.StmtSummaryByDigestMap.SetGroupByPlanTemplate(TiDBOptOn(val))
+		}},
 	{Scope: ScopeGlobal, Name: TiDBCapturePlanBaseline, Value: DefTiDBCapturePlanBaseline, Type: TypeBool, AllowEmptyAll: true},
 	{Scope: ScopeGlobal, Name: TiDBEvolvePlanTaskMaxTime, Value: strconv.Itoa(DefTiDBEvolvePlanTaskMaxTime), Type: TypeInt, MinValue: -1, MaxValue: math.MaxInt64},
 	{Scope: ScopeGlobal, Name: TiDBEvolvePlanTaskStartTime, Value: DefTiDBEvolvePlanTaskStartTime, Type: TypeTime},
@@ -810,6 +839,14 @@ var defaultSysVars = []*SysVar{
 		}
 		return err
 	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBEnableSharedPlanCacheTemplate, Value: BoolToOnOff(DefTiDBEnableSharedPlanCacheTemplate), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
+		s.EnableSharedPlanCacheTemplate = TiDBOptOn(val)
+		return nil
+	}},
+	{Scope: ScopeSession, Name: TiDBTracePlanCache, Value: BoolToOnOff(DefTiDBTracePlanCache), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
+		s.TracePlanCache = TiDBOptOn(val)
+		return nil
+	}},
 	{Scope: ScopeGlobal, Name: TiDBMemOOMAction, Value: DefTiDBMemOOMAction, PossibleValues: []string{"CANCEL", "LOG"}, Type: TypeEnum,
 		GetGlobal: func(s *SessionVars) (string, error) {
 			return OOMAction.Load(), nil
@@ -1609,6 +1646,18 @@ var defaultSysVars = []*SysVar{
 		s.MultiStatementMode = TiDBOptOnOffWarn(val)
 		return nil
 	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBFlashbackBatchSize, Value: strconv.Itoa(DefTiDBFlashbackBatchSize), Type: TypeInt, MinValue: 1, MaxValue: 10240, SetSession: func(s *SessionVars, val string) error {
+		s.FlashbackBatchSize = int(TidbOptInt64(val, DefTiDBFlashbackBatchSize))
+		return nil
+	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBStaleReadFlashbackCheck, Value: DefTiDBStaleReadFlashbackCheck, Type: TypeEnum, PossibleValues: []string{Off, On, Warn}, SetSession: func(s *SessionVars, val string) error {
+		s.StaleReadFlashbackCheck = TiDBOptOnOffWarn(val)
+		return nil
+	}},
+	{Scope: ScopeGlobal | ScopeSession, Name: TiDBFlashbackScatterRegions, Value: BoolToOnOff(DefTiDBFlashbackScatterRegions), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
+		s.FlashbackScatterRegions = TiDBOptOn(val)
+		return nil
+	}},
 	{Scope: ScopeGlobal | ScopeSession, Name: TiDBEnableExchangePartition, Value: BoolToOnOff(DefTiDBEnableExchangePartition), Type: TypeBool, SetSession: func(s *SessionVars, val string) error {
 		s.TiDBEnableExchangePartition = TiDBOptOn(val)
 		return nil
@@ -1795,6 +1844,56 @@ var defaultSysVars = []*SysVar{
 		s.EnableTiFlashReadForWriteStmt = TiDBOptOn(val)
 		return nil
 	}},
+	{Scope: ScopeGlobal, Name: TiDBAdminOperationLogStmts, Value: DefTiDBAdminOperationLogStmts, Type: TypeStr, AllowEmpty: true,
+		Validation: func(vars *SessionVars, normalizedValue string, originalValue string, scope ScopeFlag) (string, error) {
+			if normalizedValue == "" {
+				return normalizedValue, nil
+			}
+			stmtTypes := strings.Split(strings.ToLower(normalizedValue), ",")
+			for i, tp := range stmtTypes {
+				tp = strings.TrimSpace(tp)
+				switch tp {
+				case AdminOperationFlashbackCluster, AdminOperationDropDatabase, AdminOperationTruncateTable:
+				default:
+					return normalizedValue, ErrWrongValueForVar.GenWithStackByArgs(TiDBAdminOperationLogStmts, originalValue)
+				}
+				stmtTypes[i] = tp
+			}
+			return strings.Join(stmtTypes, ","), nil
+		}, SetGlobal: func(s *SessionVars, val string) error {
+			AdminOperationLogStmts.Store(val)
+			return nil
+		}, GetGlobal: func(s *SessionVars) (string, error) {
+			return AdminOperationLogStmts.Load(), nil
+		}},
+	{Scope: ScopeGlobal, Name: TiDBAdminOperationLogTruncateMinRows, Value: strconv.Itoa(DefTiDBAdminOperationLogTruncateMinRows), Type: TypeUnsigned, MinValue: 0, MaxValue: math.MaxInt64,
+		SetGlobal: func(s *SessionVars, val string) error {
+			AdminOperationLogTruncateMinRows.Store(uint64(TidbOptInt64(val, DefTiDBAdminOperationLogTruncateMinRows)))
+			return nil
+		}, GetGlobal: func(s *SessionVars) (string, error) {
+			return strconv.FormatUint(AdminOperationLogTruncateMinRows.Load(), 10), nil
+		}},
+	{Scope: ScopeGlobal, Name: TiDBAdminOperationLogStrict, Value: BoolToOnOff(DefTiDBAdminOperationLogStrict), Type: TypeBool,
+		SetGlobal: func(s *SessionVars, val string) error {
+			AdminOperationLogStrict.Store(TiDBOptOn(val))
+			return nil
+		}, GetGlobal: func(s *SessionVars) (string, error) {
+			return BoolToOnOff(AdminOperationLogStrict.Load()), nil
+		}},
+	{Scope: ScopeGlobal, Name: TiDBDDLSessionLeakThreshold, Value: strconv.Itoa(DefTiDBDDLSessionLeakThreshold), Type: TypeUnsigned, MinValue: 0, MaxValue: math.MaxInt32,
+		SetGlobal: func(s *SessionVars, val string) error {
+			DDLSessionLeakThreshold.Store(TidbOptInt64(val, DefTiDBDDLSessionLeakThreshold))
+			return nil
+		}, GetGlobal: func(s *SessionVars) (string, error) {
+			return strconv.FormatInt(DDLSessionLeakThreshold.Load(), 10), nil
+		}},
+	{Scope: ScopeGlobal, Name: TiDBDDLRecycleLeakedSession, Value: BoolToOnOff(DefTiDBDDLRecycleLeakedSession), Type: TypeBool,
+		SetGlobal: func(s *SessionVars, val string) error {
+			DDLRecycleLeakedSession.Store(TiDBOptOn(val))
+			return nil
+		}, GetGlobal: func(s *SessionVars) (string, error) {
+			return BoolToOnOff(DDLRecycleLeakedSession.Load()), nil
+		}},
 }
 
 // FeedbackProbability points to the FeedbackProbability in statistics package.
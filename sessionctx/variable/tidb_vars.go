@@ -40,6 +40,14 @@ const (
 	// The value can be a datetime string like '2017-11-11 20:20:20' or a tso string. When this variable is set, the session reads history data of that time.
 	TiDBSnapshot = "tidb_snapshot"
 
+	// TiDBFlashbackReadinessTS is the timestamp checked by information_schema.flashback_readiness, the default value
+	// is empty string. The value can be a datetime string like '2017-11-11 20:20:20' or a tso string.
+	TiDBFlashbackReadinessTS = "tidb_flashback_readiness_ts"
+
+	// TiDBOptimizerStatsAsOf makes the optimizer use the stats as of the timestamp instead of the latest ones, the
+	// default value is empty string. The value can be a datetime string like '2017-11-11 20:20:20' or a tso string.
+	TiDBOptimizerStatsAsOf = "tidb_optimizer_stats_as_of"
+
 	// TiDBOptAggPushDown is used to enable/disable the optimizer rule of aggregation push down.
 	TiDBOptAggPushDown = "tidb_opt_agg_push_down"
 
@@ -534,6 +542,9 @@ const (
 	// TiDBStmtSummaryMaxSQLLength indicates the max length of displayed normalized sql and sample sql.
 	TiDBStmtSummaryMaxSQLLength = "tidb_stmt_summary_max_sql_length"
 
+	// TiDBStmtSummaryGroupByPlanTemplate indicates whether to merge the statement summaries sharing the same plan cache template.
+	TiDBStmtSummaryGroupByPlanTemplate = "tidb_stmt_summary_group_by_plan_template"
+
 	// TiDBCapturePlanBaseline indicates whether the capture of plan baselines is enabled.
 	TiDBCapturePlanBaseline = "tidb_capture_plan_baselines"
 
@@ -733,6 +744,11 @@ const (
 	TiDBEnableGeneralPlanCache = "tidb_enable_general_plan_cache"
 	// TiDBGeneralPlanCacheSize controls the size of general plan cache.
 	TiDBGeneralPlanCacheSize = "tidb_general_plan_cache_size"
+	// TiDBEnableSharedPlanCacheTemplate indicates whether the prepared statements share the cached plans with the
+	// general statements of the same shape.
+	TiDBEnableSharedPlanCacheTemplate = "tidb_enable_shared_plan_cache_template"
+	// TiDBTracePlanCache indicates whether to record the decisions of the plan cache into the trace of the statements.
+	TiDBTracePlanCache = "tidb_trace_plan_cache"
 
 	// TiDBConstraintCheckInPlacePessimistic controls whether to skip certain kinds of pessimistic locks.
 	TiDBConstraintCheckInPlacePessimistic = "tidb_constraint_check_in_place_pessimistic"
@@ -806,6 +822,27 @@ const (
 	TiDBDDLEnableFastReorg = "tidb_ddl_enable_fast_reorg"
 	// TiDBDDLDiskQuota used to set disk quota for lightning add index.
 	TiDBDDLDiskQuota = "tidb_ddl_disk_quota"
+	// TiDBAdminOperationLogStmts indicates which kinds of destructive statements are recorded into mysql.admin_operation_log.
+	// It's a comma separated list of `flashback_cluster`, `drop_database` and `truncate_table`.
+	TiDBAdminOperationLogStmts = "tidb_admin_operation_log_stmts"
+	// TiDBAdminOperationLogTruncateMinRows indicates the minimum row count of the table for TRUNCATE TABLE to be recorded.
+	TiDBAdminOperationLogTruncateMinRows = "tidb_admin_operation_log_truncate_min_rows"
+	// TiDBAdminOperationLogStrict indicates whether a failure of writing mysql.admin_operation_log fails the statement.
+	TiDBAdminOperationLogStrict = "tidb_admin_operation_log_strict"
+	// TiDBFlashbackBatchSize is the max number of the regions flashed back by one request of a FLASHBACK job. It's
+	// saved in the args of the job when it's submitted, and halved by the job when the requests time out.
+	TiDBFlashbackBatchSize = "tidb_flashback_batch_size"
+	// TiDBDDLSessionLeakThreshold is the seconds after which a session held by a DDL job, e.g. flashback, is reported
+	// as leaked. 0 disables the leak detection.
+	TiDBDDLSessionLeakThreshold = "tidb_ddl_session_leak_threshold"
+	// TiDBDDLRecycleLeakedSession indicates whether the leaked sessions of the DDL are returned to the session pool.
+	TiDBDDLRecycleLeakedSession = "tidb_ddl_recycle_leaked_session"
+	// TiDBStaleReadFlashbackCheck indicates how a stale read inside the timeline erased by a flashback is handled.
+	// OFF allows it, WARN allows it with a warning, and ON rejects it.
+	TiDBStaleReadFlashbackCheck = "tidb_stale_read_flashback_check"
+	// TiDBFlashbackScatterRegions indicates whether FLASHBACK CLUSTER scatters the regions of the heavy flashback
+	// ranges before the PD schedule is closed, so that the rewrite traffic of the flashback isn't on a few stores.
+	TiDBFlashbackScatterRegions = "tidb_flashback_scatter_regions"
 )
 
 // TiDB intentional limits
@@ -974,6 +1011,7 @@ const (
 	DefTiDBStmtSummaryHistorySize                  = 24
 	DefTiDBStmtSummaryMaxStmtCount                 = 3000
 	DefTiDBStmtSummaryMaxSQLLength                 = 4096
+	DefTiDBStmtSummaryGroupByPlanTemplate          = false
 	DefTiDBCapturePlanBaseline                     = Off
 	DefTiDBEnableIndexMerge                        = true
 	DefEnableLegacyInstanceScope                   = true
@@ -1026,7 +1064,17 @@ const (
 	DefExecutorConcurrency                         = 5
 	DefTiDBEnableGeneralPlanCache                  = false
 	DefTiDBGeneralPlanCacheSize                    = 100
+	DefTiDBEnableSharedPlanCacheTemplate           = false
+	DefTiDBTracePlanCache                          = false
 	DefTiDBEnableTiFlashReadForWriteStmt           = false
+	DefTiDBAdminOperationLogStmts                  = ""
+	DefTiDBAdminOperationLogTruncateMinRows        = 0
+	DefTiDBAdminOperationLogStrict                 = false
+	DefTiDBFlashbackBatchSize                      = 64
+	DefTiDBDDLSessionLeakThreshold                 = 600
+	DefTiDBDDLRecycleLeakedSession                 = false
+	DefTiDBStaleReadFlashbackCheck                 = Warn
+	DefTiDBFlashbackScatterRegions                 = false
 	// MaxDDLReorgBatchSize is exported for testing.
 	MaxDDLReorgBatchSize                     int32  = 10240
 	MinDDLReorgBatchSize                     int32  = 32
@@ -1078,6 +1126,23 @@ var (
 	EnableFastReorg = atomic.NewBool(DefTiDBEnableFastReorg)
 	// DDLDiskQuota is the temporary variable for set disk quota for lightning
 	DDLDiskQuota = atomic.NewInt64(DefTiDBDDLDiskQuota)
+	// AdminOperationLogStmts is the comma separated statement types recorded into mysql.admin_operation_log.
+	AdminOperationLogStmts = atomic.NewString(DefTiDBAdminOperationLogStmts)
+	// AdminOperationLogTruncateMinRows is the minimum row count of a table for TRUNCATE TABLE to be recorded.
+	AdminOperationLogTruncateMinRows = atomic.NewUint64(DefTiDBAdminOperationLogTruncateMinRows)
+	// AdminOperationLogStrict indicates whether a failure of writing mysql.admin_operation_log fails the statement.
+	AdminOperationLogStrict = atomic.NewBool(DefTiDBAdminOperationLogStrict)
+	// DDLSessionLeakThreshold is the seconds after which a session held by a DDL job is reported as leaked.
+	DDLSessionLeakThreshold = atomic.NewInt64(DefTiDBDDLSessionLeakThreshold)
+	// DDLRecycleLeakedSession indicates whether the leaked sessions of the DDL are returned to the session pool.
+	DDLRecycleLeakedSession = atomic.NewBool(DefTiDBDDLRecycleLeakedSession)
+)
+
+// The statement types which can be recorded into mysql.admin_operation_log.
+const (
+	AdminOperationFlashbackCluster = "flashback_cluster"
+	AdminOperationDropDatabase     = "drop_database"
+	AdminOperationTruncateTable    = "truncate_table"
 )
 
 var (
@@ -202,6 +202,9 @@ func (sv *SysVar) SetSessionFromHook(s *SessionVars, val string) error {
 		}
 	}
 	s.systems[sv.Name] = val
+	if isOptimizerVar4PC(sv.Name) {
+		s.optimizerVarsHash4PCValid = false
+	}
 
 	// Call the Set function on all the aliases for this sysVar
 	// Skipping the validation function, and not calling aliases of
@@ -217,6 +220,9 @@ func (sv *SysVar) SetSessionFromHook(s *SessionVars, val string) error {
 				}
 			}
 			s.systems[aliasSv.Name] = val
+			if isOptimizerVar4PC(aliasSv.Name) {
+				s.optimizerVarsHash4PCValid = false
+			}
 		}
 	}
 	return nil
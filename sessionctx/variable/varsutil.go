@@ -28,7 +28,6 @@ import (
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tidb/util/collate"
 	"github.com/pingcap/tidb/util/timeutil"
-	"github.com/tikv/client-go/v2/oracle"
 	"golang.org/x/exp/slices"
 )
 
@@ -377,16 +376,27 @@ func setSnapshotTS(s *SessionVars, sVal string) error {
 		return nil
 	}
 
-	t, err := types.ParseTime(s.StmtCtx, sVal, mysql.TypeTimestamp, types.MaxFsp)
+	ts, err := parseTSFromNumberOrTime(s, sVal)
 	if err != nil {
 		return err
 	}
-
-	t1, err := t.GoTime(s.Location())
-	s.SnapshotTS = oracle.GoTimeToTS(t1)
+	s.SnapshotTS = ts
 	// tx_read_ts should be mutual exclusive with tidb_snapshot
 	s.TxnReadTS = NewTxnReadTS(0)
-	return err
+	return nil
+}
+
+// parseTSFromNumberOrTime parses the value which is a tso string or a datetime string in the session time zone.
+func parseTSFromNumberOrTime(s *SessionVars, sVal string) (uint64, error) {
+	if tso, err := strconv.ParseUint(sVal, 10, 64); err == nil {
+		return tso, nil
+	}
+
+	t, err := types.ParseTime(s.StmtCtx, sVal, mysql.TypeTimestamp, types.MaxFsp)
+	if err != nil {
+		return 0, err
+	}
+	return t.ToTSO(s.Location())
 }
 
 func setTxnReadTS(s *SessionVars, sVal string) error {
@@ -399,11 +409,11 @@ func setTxnReadTS(s *SessionVars, sVal string) error {
 	if err != nil {
 		return err
 	}
-	t1, err := t.GoTime(s.Location())
+	ts, err := t.ToTSO(s.Location())
 	if err != nil {
 		return err
 	}
-	s.TxnReadTS = NewTxnReadTS(oracle.GoTimeToTS(t1))
+	s.TxnReadTS = NewTxnReadTS(ts)
 	// tx_read_ts should be mutual exclusive with tidb_snapshot
 	s.SnapshotTS = 0
 	s.SnapshotInfoschema = nil
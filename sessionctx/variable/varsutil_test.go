@@ -83,7 +83,7 @@ func TestNewSessionVars(t *testing.T) {
 	require.Equal(t, DefOptWriteRowID, vars.AllowWriteRowID)
 	require.Equal(t, DefTiDBOptJoinReorderThreshold, vars.TiDBOptJoinReorderThreshold)
 	require.Equal(t, DefTiDBUseFastAnalyze, vars.EnableFastAnalyze)
-	require.Equal(t, DefTiDBFoundInPlanCache, vars.FoundInPlanCache)
+	require.Equal(t, DefTiDBFoundInPlanCache, vars.PrevFoundInPlanCache)
 	require.Equal(t, DefTiDBFoundInBinding, vars.FoundInBinding)
 	require.Equal(t, DefTiDBAllowAutoRandExplicitInsert, vars.AllowAutoRandExplicitInsert)
 	require.Equal(t, int64(DefTiDBShardAllocateStep), vars.ShardAllocateStep)
@@ -21,4 +21,6 @@ import (
 
 var (
 	errAsOf = dbterror.ClassOptimizer.NewStd(mysql.ErrAsOf)
+	// ErrStaleReadAcrossFlashback is returned if the stale read is inside the timeline erased by a flashback.
+	ErrStaleReadAcrossFlashback = dbterror.ClassOptimizer.NewStd(mysql.ErrStaleReadAcrossFlashback)
 )
@@ -93,6 +93,9 @@ func (p *baseProcessor) setAsNonStaleRead() error {
 }
 
 func (p *baseProcessor) setEvaluatedTS(ts uint64) (err error) {
+	if err = CheckReadTSAcrossFlashback(p.sctx, ts); err != nil {
+		return err
+	}
 	is, err := GetSessionSnapshotInfoSchema(p.sctx, ts)
 	if err != nil {
 		return err
@@ -108,6 +111,9 @@ func (p *baseProcessor) setEvaluatedEvaluator(evaluator StalenessTSEvaluator) er
 	if err != nil {
 		return err
 	}
+	if err = CheckReadTSAcrossFlashback(p.sctx, ts); err != nil {
+		return err
+	}
 
 	is, err := GetSessionSnapshotInfoSchema(p.sctx, ts)
 	if err != nil {
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/sessiontxn/staleread"
 	"github.com/pingcap/tidb/table/temptable"
 	"github.com/pingcap/tidb/testkit"
@@ -338,3 +339,51 @@ func createProcessor(t *testing.T, se sessionctx.Context) staleread.Processor {
 	require.Nil(t, processor.GetStalenessInfoSchema())
 	return processor
 }
+
+func TestAsOfTimestampSameAsSnapshotVariables(t *testing.T) {
+	store := testkit.CreateMockStore(t)
+	tk := testkit.NewTestKit(t, store)
+	sessVars := tk.Session().GetSessionVars()
+	p := parser.New()
+	calculateAsOf := func(sql string) (uint64, error) {
+		stmt, err := p.ParseOneStmt(sql, "", "")
+		require.NoError(t, err)
+		return staleread.CalculateAsOfTsExpr(tk.Session(), &stmt.(*ast.FlashBackClusterStmt).AsOf)
+	}
+
+	for _, tz := range []string{"UTC", "Asia/Shanghai", "America/New_York"} {
+		tk.MustExec(fmt.Sprintf("set @@time_zone = '%s'", tz))
+		for _, dt := range []string{
+			"2023-01-02 03:04:05",
+			"2023-01-02 03:04:05.123456",
+			"2023-01-02 03:04:05.1239",
+			// The ambiguous time when the daylight saving time ends in America/New_York.
+			"2022-11-06 01:30:00.5",
+		} {
+			asOfTS, err := calculateAsOf(fmt.Sprintf("flashback cluster as of timestamp '%s'", dt))
+			require.NoError(t, err)
+			require.NoError(t, sessVars.SetSystemVar(variable.TiDBSnapshot, dt))
+			require.Equal(t, asOfTS, sessVars.SnapshotTS, "%s %s", tz, dt)
+			require.NoError(t, sessVars.SetSystemVar(variable.TiDBSnapshot, ""))
+			require.NoError(t, sessVars.SetSystemVar(variable.TiDBTxnReadTS, dt))
+			require.Equal(t, asOfTS, sessVars.TxnReadTS.PeakTxnReadTS(), "%s %s", tz, dt)
+			require.NoError(t, sessVars.SetSystemVar(variable.TiDBTxnReadTS, ""))
+			require.NoError(t, sessVars.SetSystemVar(variable.TiDBFlashbackReadinessTS, dt))
+			require.Equal(t, asOfTS, sessVars.FlashbackReadinessTS, "%s %s", tz, dt)
+		}
+	}
+
+	// The explicit time zone is given by CONVERT_TZ, and the microseconds are truncated to the millisecond.
+	tk.MustExec("set @@time_zone = 'Asia/Shanghai'")
+	stmt, err := p.ParseOneStmt("select * from t as of timestamp convert_tz('2023-01-02 03:04:05.123999', 'UTC', 'Asia/Shanghai')", "", "")
+	require.NoError(t, err)
+	tn := stmt.(*ast.SelectStmt).From.TableRefs.Left.(*ast.TableSource).Source.(*ast.TableName)
+	asOfTS, err := staleread.CalculateAsOfTsExpr(tk.Session(), tn.AsOf)
+	require.NoError(t, err)
+	require.Equal(t, oracle.GoTimeToTS(time.Date(2023, 1, 2, 3, 4, 5, 123000000, time.UTC)), asOfTS)
+	// The time skipped when the daylight saving time starts is rejected by all of them.
+	tk.MustExec("set @@time_zone = 'America/New_York'")
+	_, err = calculateAsOf("flashback cluster as of timestamp '2022-03-13 02:30:00'")
+	require.Error(t, err)
+	require.Error(t, sessVars.SetSystemVar(variable.TiDBSnapshot, "2022-03-13 02:30:00"))
+}
@@ -17,12 +17,16 @@ package staleread
 import (
 	"time"
 
+	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/tikv/client-go/v2/oracle"
+	"go.uber.org/zap"
 )
 
 // CalculateAsOfTsExpr calculates the TsExpr of AsOfClause to get a StartTS.
@@ -37,17 +41,13 @@ func CalculateAsOfTsExpr(sctx sessionctx.Context, asOfClause *ast.AsOfClause) (u
 	}
 
 	toTypeTimestamp := types.NewFieldType(mysql.TypeTimestamp)
-	// We need at least the millionsecond here, so set fsp to 3.
-	toTypeTimestamp.SetDecimal(3)
+	// Keep the microseconds, so the time is truncated to the millisecond of TSO in the same way as tidb_snapshot.
+	toTypeTimestamp.SetDecimal(types.MaxFsp)
 	tsTimestamp, err := tsVal.ConvertTo(sctx.GetSessionVars().StmtCtx, toTypeTimestamp)
 	if err != nil {
 		return 0, err
 	}
-	tsTime, err := tsTimestamp.GetMysqlTime().GoTime(sctx.GetSessionVars().Location())
-	if err != nil {
-		return 0, err
-	}
-	return oracle.GoTimeToTS(tsTime), nil
+	return tsTimestamp.GetMysqlTime().ToTSO(sctx.GetSessionVars().Location())
 }
 
 // CalculateTsWithReadStaleness calculates the TsExpr for readStaleness duration
@@ -65,3 +65,34 @@ func CalculateTsWithReadStaleness(sctx sessionctx.Context, readStaleness time.Du
 func IsStmtStaleness(sctx sessionctx.Context) bool {
 	return sctx.GetSessionVars().StmtCtx.IsStaleness
 }
+
+// CheckReadTSAcrossFlashback checks whether the stale read at ts is inside the timeline erased by a flashback, i.e.
+// it reads the data which doesn't exist in the rewound timeline. It's rejected or warned according to
+// tidb_stale_read_flashback_check. The read isn't blocked if the flashback watermarks can't be loaded.
+func CheckReadTSAcrossFlashback(sctx sessionctx.Context, ts uint64) error {
+	sessVars := sctx.GetSessionVars()
+	if sessVars.StaleReadFlashbackCheck == variable.OffInt {
+		return nil
+	}
+	dom := domain.GetDomain(sctx)
+	if dom == nil {
+		return nil
+	}
+	watermarks, err := dom.GetFlashbackWatermarks()
+	if err != nil {
+		logutil.BgLogger().Warn("get the flashback watermarks failed, skip checking the stale read", zap.Uint64("ts", ts), zap.Error(err))
+		return nil
+	}
+	for _, w := range watermarks {
+		if w.TableID != 0 || !w.Contains(ts) {
+			continue
+		}
+		err = ErrStaleReadAcrossFlashback.FastGenByArgs(ts, w.JobID, w.TargetTS, w.EndTS)
+		if sessVars.StaleReadFlashbackCheck == variable.OnInt {
+			return err
+		}
+		sessVars.StmtCtx.AppendWarning(err)
+		return nil
+	}
+	return nil
+}
@@ -372,6 +372,25 @@ func (h *Handle) Update(is infoschema.InfoSchema, opts ...TableStatsOpt) error {
 	return nil
 }
 
+// ReloadStatsCache drops all the cached stats and reloads them from the storage. It's used after the stats tables are
+// rewound by a flashback, since the rewound rows aren't loaded by Update if their versions are older than the ones
+// in the cache.
+func (h *Handle) ReloadStatsCache(is infoschema.InfoSchema) error {
+	h.statsCache.Lock()
+	oldCache := h.statsCache.Load().(statsCache)
+	healthyChange := &statsHealthyChange{}
+	for _, tbl := range oldCache.Values() {
+		if healthy, ok := tbl.GetStatsHealthy(); ok {
+			healthyChange.drop(healthy)
+		}
+	}
+	h.statsCache.memTracker.Consume(-oldCache.Cost())
+	h.statsCache.Store(newStatsCache())
+	h.statsCache.Unlock()
+	healthyChange.apply()
+	return h.Update(is)
+}
+
 // UpdateSessionVar updates the necessary session variables for the stats reader.
 func (h *Handle) UpdateSessionVar() error {
 	h.mu.Lock()
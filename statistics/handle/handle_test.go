@@ -16,6 +16,7 @@ package handle_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"strconv"
@@ -3392,3 +3393,63 @@ func TestUninitializedStatsStatus(t *testing.T) {
 	tk.MustExec("set @@tidb_enable_pseudo_for_outdated_stats = false")
 	checkStatsPseudo()
 }
+
+func TestOptimizerStatsAsOf(t *testing.T) {
+	store, dom := testkit.CreateMockStoreAndDomain(t)
+	tk := testkit.NewTestKit(t, store)
+	tk.MustExec("use test")
+	tk.MustExec("create table t(a int, b int, index ia(a))")
+	h := dom.StatsHandle()
+	insertRows := func(n int, gen func(i int) string) {
+		values := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			values = append(values, gen(i))
+		}
+		tk.MustExec("insert into t values " + strings.Join(values, ","))
+	}
+	insertRows(100, func(i int) string { return fmt.Sprintf("(%d,%d)", i, i) })
+	tk.MustExec("analyze table t")
+	require.NoError(t, h.Update(dom.InfoSchema()))
+	ts, err := store.GetOracle().GetTimestamp(context.Background(), &oracle.Option{})
+	require.NoError(t, err)
+	oldMeta := tk.MustQuery("select version, count from mysql.stats_meta").Rows()
+
+	// Most of the rows are a = 1 now, the table scan is cheaper than the index lookup.
+	insertRows(1000, func(i int) string { return fmt.Sprintf("(1,%d)", i) })
+	tk.MustExec("analyze table t")
+	require.NoError(t, h.Update(dom.InfoSchema()))
+	hasOperator := func(operator string) bool {
+		for _, row := range tk.MustQuery("explain format = 'brief' select * from t where a = 1").Rows() {
+			if strings.Contains(row[0].(string), operator) {
+				return true
+			}
+		}
+		return false
+	}
+	require.True(t, hasOperator("TableFullScan"))
+	tk.MustQuery("explain format = 'brief' select * from t").Check(testkit.Rows(
+		"TableReader 1100.00 root  data:TableFullScan",
+		"└─TableFullScan 1100.00 cop[tikv] table:t keep order:false"))
+
+	// The plans are built with the stats before the change.
+	tk.MustExec(fmt.Sprintf("set @@tidb_optimizer_stats_as_of = %d", ts))
+	require.True(t, hasOperator("IndexLookUp"))
+	tk.MustQuery("explain format = 'brief' select * from t").Check(testkit.Rows(
+		"TableReader 100.00 root  data:TableFullScan",
+		"└─TableFullScan 100.00 cop[tikv] table:t keep order:false"))
+	tk.MustExec("prepare stmt from 'select * from t where a = ?'")
+	tk.MustExec("set @a = 1")
+	tk.MustExec("execute stmt using @a")
+	tk.MustQuery("show warnings").Check(testkit.Rows("Warning 1105 skip plan-cache: tidb_optimizer_stats_as_of is set"))
+	tk.MustExec("set @@tidb_optimizer_stats_as_of = ''")
+	require.True(t, hasOperator("TableFullScan"))
+
+	// The rewound stats are loaded by ReloadStatsCache, even if their versions are older than the cached ones.
+	tk.MustExec(fmt.Sprintf("update mysql.stats_meta set version = %v, count = %v", oldMeta[0][0], oldMeta[0][1]))
+	require.NoError(t, h.Update(dom.InfoSchema()))
+	tbl, err := dom.InfoSchema().TableByName(model.NewCIStr("test"), model.NewCIStr("t"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1100), h.GetTableStats(tbl.Meta()).Count)
+	require.NoError(t, h.ReloadStatsCache(dom.InfoSchema()))
+	require.Equal(t, int64(100), h.GetTableStats(tbl.Meta()).Count)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handle
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/statistics"
+	"github.com/pingcap/tidb/util/logutil"
+	"go.uber.org/zap"
+)
+
+// statsSnapshotMeta is a row of mysql.stats_meta in the stats snapshot.
+type statsSnapshotMeta struct {
+	version     uint64
+	modifyCount int64
+	count       int64
+}
+
+// StatsSnapshot is a stats cache detached from the Handle, which holds the stats as of a TSO, e.g. to explain the
+// plans with the stats before a flashback. The stats are read by the snapshot reads of the stats tables at the TSO,
+// and aren't updated afterwards. The stats of a table are loaded when they're got the first time.
+type StatsSnapshot struct {
+	h  *Handle
+	ts uint64
+	// metas are the rows of mysql.stats_meta as of ts, keyed by the physical IDs.
+	metas map[int64]statsSnapshotMeta
+
+	mu struct {
+		sync.Mutex
+		tables map[int64]*statistics.Table
+	}
+}
+
+// LoadStatsSnapshot loads the snapshot of the stats as of ts. It fails if the stats tables can't be read at ts, e.g.
+// ts is before the GC safe point.
+func (h *Handle) LoadStatsSnapshot(ts uint64) (*StatsSnapshot, error) {
+	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnStats)
+	rows, _, err := h.execRestrictedSQLWithSnapshot(ctx, "select table_id, version, modify_count, count from mysql.stats_meta", ts)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := &StatsSnapshot{
+		h:     h,
+		ts:    ts,
+		metas: make(map[int64]statsSnapshotMeta, len(rows)),
+	}
+	for _, row := range rows {
+		s.metas[row.GetInt64(0)] = statsSnapshotMeta{
+			version:     row.GetUint64(1),
+			modifyCount: row.GetInt64(2),
+			count:       row.GetInt64(3),
+		}
+	}
+	s.mu.tables = make(map[int64]*statistics.Table)
+	return s, nil
+}
+
+// TS returns the TSO of the stats snapshot.
+func (s *StatsSnapshot) TS() uint64 {
+	return s.ts
+}
+
+// GetTableStats gets the stats of the table from the snapshot.
+func (s *StatsSnapshot) GetTableStats(tblInfo *model.TableInfo) *statistics.Table {
+	return s.GetPartitionStats(tblInfo, tblInfo.ID)
+}
+
+// GetPartitionStats gets the stats of the partition from the snapshot. The pseudo stats are returned if the
+// partition has no stats as of the TSO, or its stats can't be loaded.
+func (s *StatsSnapshot) GetPartitionStats(tblInfo *model.TableInfo, pid int64) *statistics.Table {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tbl, ok := s.mu.tables[pid]; ok {
+		return tbl
+	}
+	tbl := s.loadPartitionStats(tblInfo, pid)
+	if tbl == nil {
+		tbl = statistics.PseudoTable(tblInfo)
+		tbl.PhysicalID = pid
+	}
+	s.mu.tables[pid] = tbl
+	return tbl
+}
+
+func (s *StatsSnapshot) loadPartitionStats(tblInfo *model.TableInfo, pid int64) *statistics.Table {
+	meta, ok := s.metas[pid]
+	if !ok {
+		return nil
+	}
+	// All the histograms are loaded, because the snapshot isn't maintained by the stats loading of the Handle.
+	tbl, err := s.h.TableStatsFromStorage(tblInfo, pid, true, s.ts)
+	if err != nil {
+		logutil.BgLogger().Warn("[stats] load the table stats of the snapshot failed", zap.String("table", tblInfo.Name.O),
+			zap.Int64("physicalID", pid), zap.Uint64("snapshot", s.ts), zap.Error(err))
+		return nil
+	}
+	if tbl == nil {
+		return nil
+	}
+	tbl.Version = meta.version
+	tbl.Count = meta.count
+	tbl.ModifyCount = meta.modifyCount
+	tbl.TblInfoUpdateTS = tblInfo.UpdateTS
+	return tbl
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -33,6 +34,9 @@ type coprCache struct {
 	admissionMaxRanges      int
 	admissionMaxSize        int
 	admissionMinProcessTime time.Duration
+	// minTS is the min timestamp of the valid items, the ones populated by the reads before it are stale, e.g. the
+	// data is rewritten by a flashback after they're populated. It's accessed atomically.
+	minTS uint64
 }
 
 type coprCacheValue struct {
@@ -170,9 +174,27 @@ func (c *coprCache) Get(key []byte) *coprCacheValue {
 	if !bytes.Equal(typedValue.Key, key) {
 		return nil
 	}
+	if typedValue.TimeStamp < atomic.LoadUint64(&c.minTS) {
+		return nil
+	}
 	return typedValue
 }
 
+// Invalidate invalidates the cache items populated by the reads before ts. TiKV only checks whether the data
+// version of the region matches the cached one, which isn't always bumped when the data is rewritten, e.g. by a
+// flashback, so such items are dropped here instead.
+func (c *coprCache) Invalidate(ts uint64) {
+	if c == nil {
+		return
+	}
+	for {
+		minTS := atomic.LoadUint64(&c.minTS)
+		if ts <= minTS || atomic.CompareAndSwapUint64(&c.minTS, minTS, ts) {
+			return
+		}
+	}
+}
+
 // CheckRequestAdmission checks whether a response item is worth caching.
 func (c *coprCache) CheckRequestAdmission(ranges int) bool {
 	if c == nil {
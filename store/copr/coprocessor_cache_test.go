@@ -208,3 +208,27 @@ func TestIssue24118(t *testing.T) {
 	_, err := newCoprCache(&config.CoprocessorCache{AdmissionMinProcessMs: 5, AdmissionMaxResultMB: 1, CapacityMB: -1})
 	require.EqualError(t, err, "Capacity must be > 0 to enable the cache")
 }
+
+func TestInvalidate(t *testing.T) {
+	cache, err := newCoprCache(&config.CoprocessorCache{AdmissionMinProcessMs: 5, AdmissionMaxResultMB: 1, CapacityMB: 1})
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	defer cache.cache.Close()
+
+	require.True(t, cache.Set([]byte("foo"), &coprCacheValue{Data: []byte("bar"), TimeStamp: 0x123}))
+	require.True(t, cache.Set([]byte("foo2"), &coprCacheValue{Data: []byte("bar2"), TimeStamp: 0x125}))
+	time.Sleep(time.Millisecond * 50)
+
+	// The items populated before the ts are dropped.
+	cache.Invalidate(0x124)
+	require.Nil(t, cache.Get([]byte("foo")))
+	require.NotNil(t, cache.Get([]byte("foo2")))
+	// The ts never goes back.
+	cache.Invalidate(0x100)
+	require.Nil(t, cache.Get([]byte("foo")))
+	cache.Invalidate(0x126)
+	require.Nil(t, cache.Get([]byte("foo2")))
+
+	var disabled *coprCache
+	disabled.Invalidate(0x123)
+}
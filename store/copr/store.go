@@ -16,7 +16,9 @@ package copr
 
 import (
 	"context"
+	stderrs "errors"
 	"math/rand"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -24,9 +26,12 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/driver/backoff"
 	derr "github.com/pingcap/tidb/store/driver/error"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/tikv/client-go/v2/config"
+	tikverr "github.com/tikv/client-go/v2/error"
 	"github.com/tikv/client-go/v2/tikv"
 	"github.com/tikv/client-go/v2/tikvrpc"
+	"go.uber.org/zap"
 )
 
 type kvStore struct {
@@ -41,9 +46,28 @@ func (s *kvStore) GetRegionCache() *RegionCache {
 // CheckVisibility checks if it is safe to read using given ts.
 func (s *kvStore) CheckVisibility(startTime uint64) error {
 	err := s.store.CheckVisibility(startTime)
+	var gcTooEarly *tikverr.ErrGCTooEarly
+	if stderrs.As(err, &gcTooEarly) {
+		return s.enrichGCTooEarlyErr(startTime, err)
+	}
 	return derr.ToTiDBErr(err)
 }
 
+// enrichGCTooEarlyErr converts the GC too early error into ErrGCTooEarlyForRead. The cached safe point may
+// fall behind when GC advances after the statement starts, so the latest one is fetched from the safe point kv.
+func (s *kvStore) enrichGCTooEarlyErr(readTS uint64, err error) error {
+	spStr, e := s.store.GetSafePointKV().Get(tikv.GcSavedSafePoint)
+	if e != nil || spStr == "" {
+		logutil.BgLogger().Warn("[copr] load GC safe point failed", zap.Uint64("readTS", readTS), zap.Error(e))
+		return derr.ToTiDBErr(err)
+	}
+	safePoint, e := strconv.ParseUint(spStr, 10, 64)
+	if e != nil || safePoint <= readTS {
+		return derr.ToTiDBErr(err)
+	}
+	return derr.NewErrGCTooEarlyForRead(readTS, safePoint)
+}
+
 // GetTiKVClient gets the client instance.
 func (s *kvStore) GetTiKVClient() tikv.Client {
 	client := s.store.GetTiKVClient()
@@ -98,6 +122,11 @@ func (s *Store) Close() {
 	}
 }
 
+// InvalidateCoprCache invalidates the coprocessor cache items populated by the reads before ts.
+func (s *Store) InvalidateCoprCache(ts uint64) {
+	s.coprCache.Invalidate(ts)
+}
+
 func (s *Store) nextReplicaReadSeed() uint32 {
 	return atomic.AddUint32(&s.replicaReadSeed, 1)
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/pingcap/tidb/parser/terror"
 	"github.com/pingcap/tidb/util/dbterror"
 	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/oracle"
 )
 
 // tikv error instance
@@ -34,6 +35,8 @@ var (
 	ErrTiFlashServerTimeout = dbterror.ClassTiKV.NewStd(errno.ErrTiFlashServerTimeout)
 	// ErrGCTooEarly is the error that GC life time is shorter than transaction duration
 	ErrGCTooEarly = dbterror.ClassTiKV.NewStd(errno.ErrGCTooEarly)
+	// ErrGCTooEarlyForRead is the error that the read timestamp is older than the current GC safe point.
+	ErrGCTooEarlyForRead = dbterror.ClassTiKV.NewStd(errno.ErrGCTooEarlyForRead)
 	// ErrTiKVStaleCommand is the error that the command is stale in tikv.
 	ErrTiKVStaleCommand = dbterror.ClassTiKV.NewStd(errno.ErrTiKVStaleCommand)
 	// ErrQueryInterrupted is the error when the query is interrupted.
@@ -64,6 +67,12 @@ var (
 	_ = dbterror.ClassTiKV.NewStd(errno.ErrDivisionByZero)
 )
 
+// NewErrGCTooEarlyForRead returns an ErrGCTooEarlyForRead which carries both the read timestamp and the
+// GC safe point in TSO and in time, so users can tell how far the read falls behind the GC safe point.
+func NewErrGCTooEarlyForRead(readTS, gcSafePoint uint64) error {
+	return ErrGCTooEarlyForRead.GenWithStackByArgs(readTS, oracle.GetTimeFromTS(readTS), gcSafePoint, oracle.GetTimeFromTS(gcSafePoint))
+}
+
 // ToTiDBErr checks and converts a tikv error to a tidb error.
 func ToTiDBErr(err error) error {
 	if err == nil {
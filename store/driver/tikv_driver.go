@@ -279,6 +279,11 @@ func (s *tikvStore) GetMPPClient() kv.MPPClient {
 	return s.coprStore.GetMPPClient()
 }
 
+// InvalidateCoprCache invalidates the coprocessor cache items populated by the reads before ts.
+func (s *tikvStore) InvalidateCoprCache(ts uint64) {
+	s.coprStore.InvalidateCoprCache(ts)
+}
+
 // Close and unregister the store.
 func (s *tikvStore) Close() error {
 	mc.Lock()
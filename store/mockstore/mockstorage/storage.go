@@ -48,6 +48,24 @@ func NewMockStorage(tikvStore *tikv.KVStore) (kv.Storage, error) {
 	}, nil
 }
 
+// flashbackableMockStorage is the mockStorage whose mock TiKV supports the flashback.
+type flashbackableMockStorage struct {
+	*mockStorage
+	kv.FlashbackableStore
+}
+
+// NewMockStorageWithFlashback wraps tikv.KVStore as kv.Storage, the data of the storage is flashed back by flashback.
+func NewMockStorageWithFlashback(tikvStore *tikv.KVStore, flashback kv.FlashbackableStore) (kv.Storage, error) {
+	store, err := NewMockStorage(tikvStore)
+	if err != nil {
+		return nil, err
+	}
+	return &flashbackableMockStorage{
+		mockStorage:        store.(*mockStorage),
+		FlashbackableStore: flashback,
+	}, nil
+}
+
 func (s *mockStorage) EtcdAddrs() ([]string, error) {
 	return nil, nil
 }
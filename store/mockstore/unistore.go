@@ -37,5 +37,5 @@ func newUnistore(opts *mockOptions) (kv.Storage, error) {
 	if err != nil {
 		return nil, err
 	}
-	return mockstorage.NewMockStorage(kvstore)
+	return mockstorage.NewMockStorageWithFlashback(kvstore, client)
 }
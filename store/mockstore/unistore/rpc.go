@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/mpp"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/terror"
 	us "github.com/pingcap/tidb/store/mockstore/unistore/tikv"
 	"github.com/pingcap/tidb/util/codec"
@@ -402,6 +403,29 @@ func (c *RPCClient) handleDebugGetRegionProperties(ctx context.Context, req *deb
 		}}}, nil
 }
 
+// PrepareFlashbackToVersion implements the kv.FlashbackableStore interface.
+func (c *RPCClient) PrepareFlashbackToVersion(ctx context.Context, startKey, endKey []byte) error {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return context.Canceled
+	}
+	return c.usSvr.PrepareFlashbackToVersion(startKey, endKey)
+}
+
+// FlashbackToVersion implements the kv.FlashbackableStore interface.
+func (c *RPCClient) FlashbackToVersion(ctx context.Context, startKey, endKey []byte, version, startTS, commitTS uint64) (*kv.FlashbackStats, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return nil, context.Canceled
+	}
+	stats, err := c.usSvr.FlashbackToVersion(startKey, endKey, version, startTS, commitTS)
+	// Mock the TiKV of the older versions which doesn't return the counters.
+	failpoint.Inject("mockFlashbackStatsMissing", func(val failpoint.Value) {
+		if val.(bool) {
+			stats = nil
+		}
+	})
+	return stats, err
+}
+
 // Close closes RPCClient and cleanup temporal resources.
 func (c *RPCClient) Close() error {
 	atomic.StoreInt32(&c.closed, 1)
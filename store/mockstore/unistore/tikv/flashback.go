@@ -0,0 +1,122 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tikv
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/store/mockstore/unistore/tikv/dbreader"
+	"github.com/pingcap/tidb/store/mockstore/unistore/tikv/kverrors"
+	"github.com/pingcap/tidb/store/mockstore/unistore/tikv/mvcc"
+)
+
+// PrepareFlashback checks there is no lock left in the range [startKey, endKey), so the range can be flashed back.
+func (store *MVCCStore) PrepareFlashback(startKey, endKey []byte) error {
+	endKey = flashbackEndKey(endKey)
+	it := store.lockStore.NewIterator()
+	for it.Seek(startKey); it.Valid(); it.Next() {
+		if exceedEndKey(it.Key(), endKey) {
+			break
+		}
+		lock := mvcc.DecodeLock(it.Value())
+		return kverrors.BuildLockErr(safeCopy(it.Key()), &lock)
+	}
+	return nil
+}
+
+// FlashbackToVersion rewrites the data in the range [startKey, endKey) to the data at version. The keys whose latest
+// values differ from the values at version are written as a new version with startTS and commitTS, the keys which
+// don't exist at version are deleted. The older versions are kept, so the data before the flashback can still be read
+// by the snapshots. It returns the number of the keys processed and the versions written.
+func (store *MVCCStore) FlashbackToVersion(startKey, endKey []byte, version, startTS, commitTS uint64) (*kv.FlashbackStats, error) {
+	if err := store.PrepareFlashback(startKey, endKey); err != nil {
+		return nil, err
+	}
+	endKey = flashbackEndKey(endKey)
+	latest, err := store.scanFlashbackRange(startKey, endKey, maxSystemTS)
+	if err != nil {
+		return nil, err
+	}
+	old, err := store.scanFlashbackRange(startKey, endKey, version)
+	if err != nil {
+		return nil, err
+	}
+
+	store.updateLatestTS(commitTS)
+	batch := store.dbWriter.NewWriteBatch(startTS, commitTS, &kvrpcpb.Context{})
+	stats := &kv.FlashbackStats{}
+	put := func(pair *kvrpcpb.KvPair) {
+		batch.Commit(pair.Key, &mvcc.Lock{LockHdr: mvcc.LockHdr{StartTS: startTS, Op: uint8(kvrpcpb.Op_Put)}, Value: pair.Value})
+		stats.VersionsWritten++
+	}
+	del := func(pair *kvrpcpb.KvPair) {
+		batch.Commit(pair.Key, &mvcc.Lock{LockHdr: mvcc.LockHdr{StartTS: startTS, Op: uint8(kvrpcpb.Op_Del)}})
+		stats.VersionsWritten++
+	}
+	// Both of the pairs are sorted by the keys, merge them.
+	i, j := 0, 0
+	for i < len(latest.pairs) || j < len(old.pairs) {
+		stats.KeysProcessed++
+		switch {
+		case j == len(old.pairs):
+			del(latest.pairs[i])
+			i++
+		case i == len(latest.pairs):
+			put(old.pairs[j])
+			j++
+		default:
+			cmp := bytes.Compare(latest.pairs[i].Key, old.pairs[j].Key)
+			if cmp < 0 {
+				del(latest.pairs[i])
+				i++
+			} else if cmp > 0 {
+				put(old.pairs[j])
+				j++
+			} else {
+				if !bytes.Equal(latest.pairs[i].Value, old.pairs[j].Value) {
+					put(old.pairs[j])
+				}
+				i++
+				j++
+			}
+		}
+	}
+	if err = store.dbWriter.Write(batch); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// scanFlashbackRange returns the pairs in the range [startKey, endKey) at the version.
+func (store *MVCCStore) scanFlashbackRange(startKey, endKey []byte, version uint64) (*kvScanProcessor, error) {
+	reader := dbreader.NewDBReader(startKey, endKey, store.db.NewTransaction(false))
+	defer reader.Close()
+	proc := &kvScanProcessor{}
+	if err := reader.Scan(startKey, endKey, math.MaxInt, version, proc); err != nil {
+		return nil, err
+	}
+	return proc, nil
+}
+
+// flashbackEndKey returns the end key of the range to flash back, the internal keys are never flashed back.
+func flashbackEndKey(endKey []byte) []byte {
+	if len(endKey) == 0 || bytes.Compare(endKey, InternalKeyPrefix) > 0 {
+		return InternalKeyPrefix
+	}
+	return endKey
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/kvproto/pkg/kvrpcpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/mockstore/unistore/config"
 	"github.com/pingcap/tidb/store/mockstore/unistore/lockstore"
 	"github.com/pingcap/tidb/store/mockstore/unistore/tikv/kverrors"
@@ -1872,3 +1873,40 @@ func TestRcReadCheckTS(t *testing.T) {
 	conflictErr = getConflictErr(scanRes)
 	require.NotNil(t, conflictErr)
 }
+
+func TestFlashbackToVersion(t *testing.T) {
+	store := NewTestStore("TestFlashbackToVersion", "TestFlashbackToVersion", t)
+	k1, k2, k3 := []byte("tk1"), []byte("tk2"), []byte("tk3")
+
+	// k1 and k2 exist at the version 20, k2 is changed, k1 is deleted and k3 is inserted after it.
+	MustPrewritePut(k1, k1, []byte("v1"), 10, store)
+	MustCommit(k1, 10, 11, store)
+	MustPrewritePut(k2, k2, []byte("v2"), 12, store)
+	MustCommit(k2, 12, 13, store)
+	MustPrewritePut(k2, k2, []byte("v2-new"), 30, store)
+	MustCommit(k2, 30, 31, store)
+	MustPrewriteDelete(k1, k1, 32, store)
+	MustCommit(k1, 32, 33, store)
+	MustPrewritePut(k3, k3, []byte("v3"), 34, store)
+	MustCommit(k3, 34, 35, store)
+
+	// The range with locks can't be flashed back.
+	MustPrewritePut(k3, k3, []byte("v3-new"), 40, store)
+	require.Error(t, store.MvccStore.PrepareFlashback([]byte("t"), []byte("u")))
+	_, err := store.MvccStore.FlashbackToVersion([]byte("t"), []byte("u"), 20, 50, 51)
+	require.Error(t, err)
+	MustRollbackKey(k3, 40, store)
+	require.NoError(t, store.MvccStore.PrepareFlashback([]byte("t"), []byte("u")))
+
+	stats, err := store.MvccStore.FlashbackToVersion([]byte("t"), []byte("u"), 20, 50, 51)
+	require.NoError(t, err)
+	// All the 3 keys are rewound.
+	require.Equal(t, &kv.FlashbackStats{KeysProcessed: 3, VersionsWritten: 3}, stats)
+	MustGetVal(k1, []byte("v1"), 52, store)
+	MustGetVal(k2, []byte("v2"), 52, store)
+	MustGetNone(k3, 52, store)
+	// The versions before the flashback are kept.
+	MustGetNone(k1, 50, store)
+	MustGetVal(k2, []byte("v2-new"), 50, store)
+	MustGetVal(k3, []byte("v3"), 50, store)
+}
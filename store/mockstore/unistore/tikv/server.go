@@ -506,6 +506,24 @@ func (svr *Server) KvDeleteRange(ctx context.Context, req *kvrpcpb.DeleteRangeRe
 	return &kvrpcpb.DeleteRangeResponse{}, nil
 }
 
+// PrepareFlashbackToVersion checks the range [startKey, endKey) can be flashed back. The kvproto of this version has
+// no flashback RPC, so it's called by the mock store directly.
+func (svr *Server) PrepareFlashbackToVersion(startKey, endKey []byte) error {
+	if atomic.LoadInt32(&svr.stopped) > 0 {
+		return kverrors.ErrRetryable("server is closed")
+	}
+	return svr.mvccStore.PrepareFlashback(startKey, endKey)
+}
+
+// FlashbackToVersion flashes back the range [startKey, endKey) to the version. The kvproto of this version has no
+// flashback RPC, so it's called by the mock store directly.
+func (svr *Server) FlashbackToVersion(startKey, endKey []byte, version, startTS, commitTS uint64) (*kv.FlashbackStats, error) {
+	if atomic.LoadInt32(&svr.stopped) > 0 {
+		return nil, kverrors.ErrRetryable("server is closed")
+	}
+	return svr.mvccStore.FlashbackToVersion(startKey, endKey, version, startTS, commitTS)
+}
+
 // RawKV commands.
 
 // RawGet implements implements the tikvpb.TikvServer interface.
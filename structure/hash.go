@@ -208,6 +208,12 @@ func (t *TxStructure) HClear(key []byte) error {
 	return nil
 }
 
+// IterateHash iterates the fields and values in a hash in the order of the fields. The field and value passed to fn
+// are only valid until fn returns.
+func (t *TxStructure) IterateHash(key []byte, fn func(field []byte, value []byte) error) error {
+	return t.iterateHash(key, fn)
+}
+
 func (t *TxStructure) iterateHash(key []byte, fn func(k []byte, v []byte) error) error {
 	dataPrefix := t.hashDataKeyPrefix(key)
 	it, err := t.reader.Iter(dataPrefix, dataPrefix.PrefixNext())
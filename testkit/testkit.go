@@ -25,9 +25,11 @@ import (
 	"time"
 
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/terror"
 	"github.com/pingcap/tidb/session"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -268,6 +270,12 @@ func (tk *TestKit) ExecWithContext(ctx context.Context, sql string, args ...inte
 		parserWarns := warns[len(prevWarns):]
 		var rs0 sqlexec.RecordSet
 		for i, stmt := range stmts {
+			if tk.useGeneralPlanCache && len(stmts) > 1 {
+				// Parameterize the statements of the multi-statement query one by one, like the server does.
+				if execStmt, ok := tk.session.Parameterize(ctx, stmt.Text()); ok {
+					stmt = execStmt
+				}
+			}
 			var rs sqlexec.RecordSet
 			var err error
 			if s, ok := stmt.(*ast.NonTransactionalDeleteStmt); ok {
@@ -337,6 +345,15 @@ func (tk *TestKit) RefreshConnectionID() {
 	}
 }
 
+// MustWaitDDLJob waits for the DDL job to be done, asserts it succeeds, and returns the history job.
+func (tk *TestKit) MustWaitDDLJob(jobID int64) *model.Job {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	job, err := ddl.WaitJobDone(ctx, tk.store, jobID)
+	tk.require.NoError(err, "job id: %d", jobID)
+	return job
+}
+
 // MustGetErrCode executes a sql statement and assert it's error code.
 func (tk *TestKit) MustGetErrCode(sql string, errCode int) {
 	_, err := tk.Exec(sql)
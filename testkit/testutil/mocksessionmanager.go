@@ -79,3 +79,8 @@ func (*MockSessionManager) DeleteInternalSession(interface{}) {}
 func (*MockSessionManager) GetInternalSessionStartTSList() []uint64 {
 	return nil
 }
+
+// FlushPlanCache implements the SessionManager.FlushPlanCache interface.
+func (*MockSessionManager) FlushPlanCache() int {
+	return 0
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/mathutil"
 	"github.com/pingcap/tidb/util/parser"
+	"github.com/tikv/client-go/v2/oracle"
 )
 
 // Time format without fractional seconds precision.
@@ -371,6 +372,33 @@ func (t *Time) ConvertTimeZone(from, to *gotime.Location) error {
 	return nil
 }
 
+// ToTSO converts the time in loc to the TSO. It's shared by FLASHBACK, AS OF TIMESTAMP, tidb_snapshot and
+// tx_read_ts, so the same time string resolves to the same TSO in all of them. The rules are:
+//  1. The physical part of the TSO is in milliseconds. The microseconds are truncated instead of rounded, so the TSO
+//     is never after the time.
+//  2. If the time is ambiguous in loc, e.g. 01:30 happens twice when the daylight saving time ends, the earlier
+//     instant is used.
+//  3. If the time doesn't exist in loc, e.g. 02:30 is skipped when the daylight saving time starts, an error is
+//     returned.
+//  4. The leap seconds like 23:59:60 are rejected when the time is parsed, the Unix time of TSO has no leap seconds.
+func (t Time) ToTSO(loc *gotime.Location) (uint64, error) {
+	tm, err := t.GoTime(loc)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	// gotime.Date doesn't guarantee which instant is returned for an ambiguous time, so try the zone offsets around
+	// the time, and use the earliest instant having the same wall clock.
+	wall := gotime.Date(t.Year(), gotime.Month(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Microsecond()*1000, gotime.UTC)
+	for _, probe := range []gotime.Duration{-12 * gotime.Hour, 12 * gotime.Hour} {
+		_, offset := tm.Add(probe).Zone()
+		candidate := wall.Add(-gotime.Duration(offset) * gotime.Second).In(loc)
+		if candidate.Before(tm) && FromGoTime(candidate) == FromGoTime(wall) {
+			tm = candidate
+		}
+	}
+	return oracle.GoTimeToTS(tm), nil
+}
+
 func (t Time) String() string {
 	if t.Type() == mysql.TypeDate {
 		// We control the format, so no error would occur.
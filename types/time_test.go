@@ -2317,3 +2317,60 @@ func BenchmarkStrToDate(b *testing.B) {
 	benchmarkStrToDate(b, "strToDate %r ddMMyyyy", sc, "04:13:56 AM 13/05/2019", "%r %d/%c/%Y")
 	benchmarkStrToDate(b, "strToDate %T ddMMyyyy", sc, " 4:13:56 13/05/2019", "%T %d/%c/%Y")
 }
+
+func TestTimeToTSO(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	sc := &stmtctx.StatementContext{TimeZone: time.UTC}
+	tests := []struct {
+		input  string
+		loc    *time.Location
+		expect string
+	}{
+		// The microseconds are truncated to the millisecond.
+		{"2023-01-02 03:04:05.123999", shanghai, "2023-01-01T19:04:05.123Z"},
+		{"2023-01-02 03:04:05.1239", time.UTC, "2023-01-02T03:04:05.123Z"},
+		// The digits after the microsecond are rounded when parsing.
+		{"2023-01-02 03:04:05.9999999", time.UTC, "2023-01-02T03:04:06Z"},
+		// The earlier instant is used for the ambiguous time.
+		{"2022-11-06 01:30:00", newYork, "2022-11-06T05:30:00Z"},
+		{"2022-11-06 00:59:59.999", newYork, "2022-11-06T04:59:59.999Z"},
+		{"2022-11-06 02:00:00", newYork, "2022-11-06T07:00:00Z"},
+		{"2022-10-30 01:30:00", london, "2022-10-30T00:30:00Z"},
+		// The time after the daylight saving time starts.
+		{"2022-03-13 03:00:00", newYork, "2022-03-13T07:00:00Z"},
+		{"2022-03-27 02:00:00", london, "2022-03-27T01:00:00Z"},
+	}
+	for _, tt := range tests {
+		v, err := types.ParseTime(sc, tt.input, mysql.TypeTimestamp, types.MaxFsp)
+		require.NoError(t, err)
+		ts, err := v.ToTSO(tt.loc)
+		require.NoError(t, err, tt.input)
+		expect, err := time.Parse(time.RFC3339Nano, tt.expect)
+		require.NoError(t, err)
+		require.Equal(t, expect.UnixMilli(), int64(ts>>18), tt.input)
+		require.Zero(t, ts&(1<<18-1), tt.input)
+	}
+
+	// The time skipped when the daylight saving time starts doesn't exist.
+	for _, tt := range []struct {
+		input string
+		loc   *time.Location
+	}{
+		{"2022-03-13 02:30:00", newYork},
+		{"2022-03-27 01:30:00", london},
+	} {
+		v, err := types.ParseTime(sc, tt.input, mysql.TypeTimestamp, types.MaxFsp)
+		require.NoError(t, err)
+		_, err = v.ToTSO(tt.loc)
+		require.Error(t, err, tt.input)
+	}
+
+	// The leap seconds are rejected.
+	_, err = types.ParseTime(sc, "2016-12-31 23:59:60", mysql.TypeTimestamp, types.MaxFsp)
+	require.Error(t, err)
+}
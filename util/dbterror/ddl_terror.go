@@ -393,6 +393,12 @@ var (
 	// ErrColumnInChange indicates there is modification on the column in parallel.
 	ErrColumnInChange = ClassDDL.NewStd(mysql.ErrColumnInChange)
 
+	// ErrFlashbackTSTooRecent returns when the flashback cluster timestamp is in the future or too close to now.
+	ErrFlashbackTSTooRecent = ClassDDL.NewStd(mysql.ErrFlashbackTSTooRecent)
+	// ErrFlashbackCollationMismatch returns when the index keys of the tables are encoded in different collations at
+	// the flashback timestamp and now.
+	ErrFlashbackCollationMismatch = ClassDDL.NewStd(mysql.ErrFlashbackCollationMismatch)
+
 	// ErrAlterTiFlashModeForTableWithoutTiFlashReplica returns when set tiflash mode on table whose tiflash_replica is null or tiflash_replica_count = 0
 	ErrAlterTiFlashModeForTableWithoutTiFlashReplica = ClassDDL.NewStdErr(0, parser_mysql.Message("TiFlash mode will take effect after at least one TiFlash replica is set for the table", nil))
 
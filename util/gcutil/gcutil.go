@@ -16,21 +16,37 @@ package gcutil
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/logutil"
 	"github.com/pingcap/tidb/util/sqlexec"
 	"github.com/tikv/client-go/v2/oracle"
 	"github.com/tikv/client-go/v2/util"
+	"go.uber.org/zap"
 )
 
 const (
 	selectVariableValueSQL = `SELECT HIGH_PRIORITY variable_value FROM mysql.tidb WHERE variable_name=%?`
 )
 
+var (
+	// SnapshotProtectionTTL is the TTL in seconds of the service safe point registered by WithSnapshotProtection.
+	SnapshotProtectionTTL int64 = 5 * 60
+	// SnapshotProtectionRefreshInterval is the interval to refresh the service safe point registered by WithSnapshotProtection.
+	SnapshotProtectionRefreshInterval = time.Minute
+)
+
+// ServiceSafePointManager is the part of pd.Client used to register service GC safe points.
+type ServiceSafePointManager interface {
+	UpdateServiceGCSafePoint(ctx context.Context, serviceID string, ttl int64, safePoint uint64) (uint64, error)
+}
+
 // CheckGCEnable is use to check whether GC is enable.
 func CheckGCEnable(ctx sessionctx.Context) (enable bool, err error) {
 	val, err := ctx.GetSessionVars().GlobalVarsAccessor.GetGlobalSysVar(variable.TiDBGCEnable)
@@ -72,20 +88,78 @@ func ValidateSnapshotWithGCSafePoint(snapshotTS, safePointTS uint64) error {
 
 // GetGCSafePoint loads GC safe point time from mysql.tidb.
 func GetGCSafePoint(sctx sessionctx.Context) (uint64, error) {
+	ts, ok, err := GetGCSafePointIfExists(sctx)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errors.New("can not get 'tikv_gc_safe_point'")
+	}
+	return ts, nil
+}
+
+// GetGCSafePointIfExists loads GC safe point time from mysql.tidb. It returns false if the safe point hasn't been
+// saved, e.g. GC has never run in the cluster.
+func GetGCSafePointIfExists(sctx sessionctx.Context) (uint64, bool, error) {
 	exec := sctx.(sqlexec.RestrictedSQLExecutor)
 	ctx := kv.WithInternalSourceType(context.Background(), kv.InternalTxnGC)
 	rows, _, err := exec.ExecRestrictedSQL(ctx, nil, selectVariableValueSQL, "tikv_gc_safe_point")
 	if err != nil {
-		return 0, errors.Trace(err)
+		return 0, false, errors.Trace(err)
 	}
-	if len(rows) != 1 {
-		return 0, errors.New("can not get 'tikv_gc_safe_point'")
+	if len(rows) != 1 || rows[0].IsNull(0) || rows[0].GetString(0) == "" {
+		return 0, false, nil
 	}
 	safePointString := rows[0].GetString(0)
 	safePointTime, err := util.CompatibleParseGCTime(safePointString)
 	if err != nil {
-		return 0, errors.Trace(err)
+		return 0, false, errors.Trace(err)
 	}
 	ts := oracle.GoTimeToTS(safePointTime)
-	return ts, nil
+	return ts, true, nil
+}
+
+// WithSnapshotProtection registers a service GC safe point at snapshotTS, runs fn, and keeps the safe point alive
+// until fn returns. The service safe point is refreshed every SnapshotProtectionRefreshInterval, and it is removed
+// once fn returns or ctx is cancelled, so long snapshot reads won't be broken by an advancing GC safe point.
+func WithSnapshotProtection(ctx context.Context, mgr ServiceSafePointManager, serviceID string, snapshotTS uint64,
+	fn func(ctx context.Context) error) error {
+	minSafePoint, err := mgr.UpdateServiceGCSafePoint(ctx, serviceID, SnapshotProtectionTTL, snapshotTS)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The service safe point can't be registered if the GC safe point has already exceeded snapshotTS.
+	if minSafePoint > snapshotTS {
+		return variable.ErrSnapshotTooOld.GenWithStackByArgs(model.TSConvert2Time(minSafePoint).String())
+	}
+
+	protectCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(SnapshotProtectionRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-protectCtx.Done():
+				// Use a new context here since protectCtx has been done.
+				if _, err := mgr.UpdateServiceGCSafePoint(context.Background(), serviceID, 0, snapshotTS); err != nil {
+					logutil.BgLogger().Warn("remove service safe point failed",
+						zap.String("serviceID", serviceID), zap.Uint64("snapshotTS", snapshotTS), zap.Error(err))
+				}
+				return
+			case <-ticker.C:
+				if _, err := mgr.UpdateServiceGCSafePoint(protectCtx, serviceID, SnapshotProtectionTTL, snapshotTS); err != nil {
+					logutil.BgLogger().Warn("refresh service safe point failed",
+						zap.String("serviceID", serviceID), zap.Uint64("snapshotTS", snapshotTS), zap.Error(err))
+				}
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	return fn(protectCtx)
 }
@@ -0,0 +1,110 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSafePointManager struct {
+	sync.Mutex
+	gcSafePoint  uint64
+	safePoints   map[string]uint64
+	refreshCount int
+}
+
+func newMockSafePointManager() *mockSafePointManager {
+	return &mockSafePointManager{safePoints: make(map[string]uint64)}
+}
+
+func (m *mockSafePointManager) UpdateServiceGCSafePoint(_ context.Context, serviceID string, ttl int64, safePoint uint64) (uint64, error) {
+	m.Lock()
+	defer m.Unlock()
+	if ttl == 0 {
+		delete(m.safePoints, serviceID)
+		return m.gcSafePoint, nil
+	}
+	if safePoint < m.gcSafePoint {
+		return m.gcSafePoint, nil
+	}
+	if _, ok := m.safePoints[serviceID]; ok {
+		m.refreshCount++
+	}
+	m.safePoints[serviceID] = safePoint
+	return safePoint, nil
+}
+
+func (m *mockSafePointManager) get(serviceID string) (uint64, bool) {
+	m.Lock()
+	defer m.Unlock()
+	ts, ok := m.safePoints[serviceID]
+	return ts, ok
+}
+
+func (m *mockSafePointManager) getRefreshCount() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.refreshCount
+}
+
+func TestWithSnapshotProtection(t *testing.T) {
+	originInterval := SnapshotProtectionRefreshInterval
+	SnapshotProtectionRefreshInterval = 10 * time.Millisecond
+	defer func() {
+		SnapshotProtectionRefreshInterval = originInterval
+	}()
+
+	mgr := newMockSafePointManager()
+	err := WithSnapshotProtection(context.Background(), mgr, "test", 100, func(ctx context.Context) error {
+		ts, ok := mgr.get("test")
+		require.True(t, ok)
+		require.Equal(t, uint64(100), ts)
+		require.Eventually(t, func() bool {
+			return mgr.getRefreshCount() >= 2
+		}, 5*time.Second, 10*time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	_, ok := mgr.get("test")
+	require.False(t, ok)
+
+	// The service safe point is removed once the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	err = WithSnapshotProtection(ctx, mgr, "test", 100, func(ctx context.Context) error {
+		cancel()
+		require.Eventually(t, func() bool {
+			_, ok := mgr.get("test")
+			return !ok
+		}, 5*time.Second, 10*time.Millisecond)
+		return ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The snapshot has been GCed.
+	mgr.gcSafePoint = 200
+	called := false
+	err = WithSnapshotProtection(context.Background(), mgr, "test", 100, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	require.True(t, variable.ErrSnapshotTooOld.Equal(err))
+	require.False(t, called)
+}
@@ -705,6 +705,12 @@ const (
 	LabelForAnalyzeMemory int = -24
 	// LabelForGlobalAnalyzeMemory represents the label of the global memory of all analyze jobs
 	LabelForGlobalAnalyzeMemory int = -25
+	// LabelForGlobalPlanCache represents the label of the global memory of all the plan caches
+	LabelForGlobalPlanCache int = -26
+	// LabelForPlanCache represents the label of the memory of each plan cache
+	LabelForPlanCache int = -27
+	// LabelForFlashback represents the label of the memory of the flashback jobs
+	LabelForFlashback int = -28
 )
 
 // MetricsTypes is used to get label for metrics
@@ -180,6 +180,8 @@ type SessionManager interface {
 	DeleteInternalSession(se interface{})
 	// GetInternalSessionStartTSList gets all startTS of every transactions running in the current internal sessions.
 	GetInternalSessionStartTSList() []uint64
+	// FlushPlanCache flushes the plan caches of all the sessions, and returns the number of evicted entries.
+	FlushPlanCache() int
 }
 
 // GlobalConnID is the global connection ID, providing UNIQUE connection IDs across the whole TiDB cluster.
@@ -279,6 +281,9 @@ func ParseGlobalConnID(id uint64) (g GlobalConnID, isTruncated bool, err error)
 const (
 	reservedLocalConns  = 200
 	reservedConnAnalyze = 1
+	// reservedConnDDLJob is the first reserved local connID for the internal sessions of DDL jobs,
+	// the range is [reservedConnDDLJob, reservedLocalConns).
+	reservedConnDDLJob = 100
 )
 
 // GetAutoAnalyzeProcID returns processID for auto analyze
@@ -287,3 +292,9 @@ func GetAutoAnalyzeProcID(serverIDGetter func() uint64) uint64 {
 	globalConnID := NewGlobalConnIDWithGetter(serverIDGetter, true)
 	return globalConnID.makeID(reservedConnAnalyze)
 }
+
+// GetDDLJobProcID returns processID for the internal session of the DDL job.
+func GetDDLJobProcID(serverIDGetter func() uint64, jobID int64) uint64 {
+	globalConnID := NewGlobalConnIDWithGetter(serverIDGetter, true)
+	return globalConnID.makeID(reservedConnDDLJob + uint64(jobID)%(reservedLocalConns-reservedConnDDLJob))
+}
@@ -304,12 +304,14 @@ const (
 	LastSeenStr                       = "LAST_SEEN"
 	PlanInCacheStr                    = "PLAN_IN_CACHE"
 	PlanCacheHitsStr                  = "PLAN_CACHE_HITS"
+	PlanCacheStatusStr                = "PLAN_CACHE_STATUS"
 	PlanInBindingStr                  = "PLAN_IN_BINDING"
 	QuerySampleTextStr                = "QUERY_SAMPLE_TEXT"
 	PrevSampleTextStr                 = "PREV_SAMPLE_TEXT"
 	PlanDigestStr                     = "PLAN_DIGEST"
 	PlanStr                           = "PLAN"
 	BinaryPlan                        = "BINARY_PLAN"
+	PlanCacheTemplateStr              = "PLAN_CACHE_TEMPLATE"
 )
 
 type columnValueFactory func(reader *stmtSummaryReader, ssElement *stmtSummaryByDigestElement, ssbd *stmtSummaryByDigest) interface{}
@@ -597,6 +599,9 @@ var columnValueFactoryMap = map[string]columnValueFactory{
 	PlanCacheHitsStr: func(_ *stmtSummaryReader, ssElement *stmtSummaryByDigestElement, _ *stmtSummaryByDigest) interface{} {
 		return ssElement.planCacheHits
 	},
+	PlanCacheStatusStr: func(_ *stmtSummaryReader, ssElement *stmtSummaryByDigestElement, _ *stmtSummaryByDigest) interface{} {
+		return ssElement.planCacheStatus.String()
+	},
 	PlanInBindingStr: func(_ *stmtSummaryReader, ssElement *stmtSummaryByDigestElement, _ *stmtSummaryByDigest) interface{} {
 		return ssElement.planInBinding
 	},
@@ -620,4 +625,7 @@ var columnValueFactoryMap = map[string]columnValueFactory{
 	BinaryPlan: func(_ *stmtSummaryReader, ssElement *stmtSummaryByDigestElement, _ *stmtSummaryByDigest) interface{} {
 		return ssElement.sampleBinaryPlan
 	},
+	PlanCacheTemplateStr: func(_ *stmtSummaryReader, _ *stmtSummaryByDigestElement, ssbd *stmtSummaryByDigest) interface{} {
+		return convertEmptyToNil(ssbd.planCacheTemplate)
+	},
 }
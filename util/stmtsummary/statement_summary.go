@@ -45,6 +45,8 @@ type stmtSummaryByDigestKey struct {
 	prevDigest string
 	// The digest of the plan of this SQL.
 	planDigest string
+	// The plan cache template of this SQL, it's only set when the summaries are grouped by the templates.
+	planCacheTemplate string
 	// `hash` is the hash value of this object.
 	hash []byte
 }
@@ -54,11 +56,12 @@ type stmtSummaryByDigestKey struct {
 // `prevSQL` is included in the key To distinguish different transactions.
 func (key *stmtSummaryByDigestKey) Hash() []byte {
 	if len(key.hash) == 0 {
-		key.hash = make([]byte, 0, len(key.schemaName)+len(key.digest)+len(key.prevDigest)+len(key.planDigest))
+		key.hash = make([]byte, 0, len(key.schemaName)+len(key.digest)+len(key.prevDigest)+len(key.planDigest)+len(key.planCacheTemplate))
 		key.hash = append(key.hash, hack.Slice(key.digest)...)
 		key.hash = append(key.hash, hack.Slice(key.schemaName)...)
 		key.hash = append(key.hash, hack.Slice(key.prevDigest)...)
 		key.hash = append(key.hash, hack.Slice(key.planDigest)...)
+		key.hash = append(key.hash, hack.Slice(key.planCacheTemplate)...)
 	}
 	return key.hash
 }
@@ -78,6 +81,8 @@ type stmtSummaryByDigestMap struct {
 	optRefreshInterval     *atomic2.Int64
 	optHistorySize         *atomic2.Int32
 	optMaxSQLLength        *atomic2.Int32
+	// optGroupByPlanTemplate indicates whether to merge the summaries sharing the same plan cache template.
+	optGroupByPlanTemplate *atomic2.Bool
 
 	// other stores summary of evicted data.
 	other *stmtSummaryByDigestEvicted
@@ -103,6 +108,8 @@ type stmtSummaryByDigest struct {
 	normalizedSQL string
 	tableNames    string
 	isInternal    bool
+	// planCacheTemplate is the parameterized text of the statement, it's capped and interned.
+	planCacheTemplate string
 }
 
 // stmtSummaryByDigestElement is the summary for each type of statements in current interval.
@@ -202,9 +209,10 @@ type stmtSummaryByDigestElement struct {
 	// The last time this type of SQL executes.
 	lastSeen time.Time
 	// plan cache
-	planInCache   bool
-	planCacheHits int64
-	planInBinding bool
+	planInCache     bool
+	planCacheHits   int64
+	planCacheStatus stmtctx.PlanCacheStatus
+	planInBinding   bool
 	// pessimistic execution retry information.
 	execRetryCount uint
 	execRetryTime  time.Duration
@@ -237,6 +245,7 @@ type StmtExecInfo struct {
 	IsInternal          bool
 	Succeed             bool
 	PlanInCache         bool
+	PlanCacheStatus     stmtctx.PlanCacheStatus
 	PlanInBinding       bool
 	ExecRetryCount      uint
 	ExecRetryTime       time.Duration
@@ -244,6 +253,54 @@ type StmtExecInfo struct {
 	ResultRows      int64
 	TiKVExecDetails util.ExecDetails
 	Prepared        bool
+	// PlanCacheTemplateGen generates the plan cache template of the statement. It's called lazily, since
+	// parameterizing the statement is not cheap.
+	PlanCacheTemplateGen func() string
+
+	planCacheTemplate          string
+	planCacheTemplateGenerated bool
+}
+
+// getPlanCacheTemplate returns the capped and interned plan cache template of the statement.
+func (sei *StmtExecInfo) getPlanCacheTemplate() string {
+	if !sei.planCacheTemplateGenerated {
+		sei.planCacheTemplateGenerated = true
+		if sei.PlanCacheTemplateGen != nil {
+			if template := sei.PlanCacheTemplateGen(); len(template) > 0 {
+				sei.planCacheTemplate = planCacheTemplates.intern(formatSQL(template))
+			}
+		}
+	}
+	return sei.planCacheTemplate
+}
+
+// planCacheTemplateInterner interns the plan cache templates, so that the summaries of the statements sharing
+// a template, e.g. the ones with different plans, share the string.
+type planCacheTemplateInterner struct {
+	sync.Mutex
+	templates map[string]string
+}
+
+var planCacheTemplates = &planCacheTemplateInterner{templates: make(map[string]string)}
+
+func (i *planCacheTemplateInterner) intern(template string) string {
+	i.Lock()
+	defer i.Unlock()
+	if interned, ok := i.templates[template]; ok {
+		return interned
+	}
+	// The templates are only referenced by the summaries, so the number of them is bounded by the max statement count.
+	if len(i.templates) >= StmtSummaryByDigestMap.maxStmtCount() {
+		i.templates = make(map[string]string)
+	}
+	i.templates[template] = template
+	return template
+}
+
+func (i *planCacheTemplateInterner) clear() {
+	i.Lock()
+	defer i.Unlock()
+	i.templates = make(map[string]string)
 }
 
 // newStmtSummaryByDigestMap creates an empty stmtSummaryByDigestMap.
@@ -265,6 +322,7 @@ func newStmtSummaryByDigestMap() *stmtSummaryByDigestMap {
 		optRefreshInterval:     atomic2.NewInt64(1800),
 		optHistorySize:         atomic2.NewInt32(24),
 		optMaxSQLLength:        atomic2.NewInt32(4096),
+		optGroupByPlanTemplate: atomic2.NewBool(false),
 		other:                  ssbde,
 	}
 	newSsMap.summaryMap.SetOnEvict(func(k kvcache.Key, v kvcache.Value) {
@@ -300,6 +358,12 @@ func (ssMap *stmtSummaryByDigestMap) AddStatement(sei *StmtExecInfo) {
 		prevDigest: sei.PrevSQLDigest,
 		planDigest: sei.PlanDigest,
 	}
+	if ssMap.GroupByPlanTemplate() {
+		// The statements sharing the template are merged even if their digests or plans are different.
+		if template := sei.getPlanCacheTemplate(); len(template) > 0 {
+			key.digest, key.planDigest, key.planCacheTemplate = "", "", template
+		}
+	}
 	// Calculate hash value in advance, to reduce the time holding the lock.
 	key.Hash()
 
@@ -349,6 +413,7 @@ func (ssMap *stmtSummaryByDigestMap) Clear() {
 	ssMap.summaryMap.DeleteAll()
 	ssMap.other.Clear()
 	ssMap.beginTimeForCurInterval = 0
+	planCacheTemplates.clear()
 }
 
 // clearInternal removes all statement summaries which are internal summaries.
@@ -498,6 +563,17 @@ func (ssMap *stmtSummaryByDigestMap) maxSQLLength() int {
 	return int(ssMap.optMaxSQLLength.Load())
 }
 
+// SetGroupByPlanTemplate sets whether to merge the summaries sharing the same plan cache template.
+func (ssMap *stmtSummaryByDigestMap) SetGroupByPlanTemplate(value bool) error {
+	ssMap.optGroupByPlanTemplate.Store(value)
+	return nil
+}
+
+// GroupByPlanTemplate returns whether the summaries sharing the same plan cache template are merged.
+func (ssMap *stmtSummaryByDigestMap) GroupByPlanTemplate() bool {
+	return ssMap.optGroupByPlanTemplate.Load()
+}
+
 // newStmtSummaryByDigest creates a stmtSummaryByDigest from StmtExecInfo.
 func (ssbd *stmtSummaryByDigest) init(sei *StmtExecInfo, _ int64, _ int64, _ int) {
 	// Use "," to separate table names to support FIND_IN_SET.
@@ -527,6 +603,7 @@ func (ssbd *stmtSummaryByDigest) init(sei *StmtExecInfo, _ int64, _ int64, _ int
 	ssbd.stmtType = sei.StmtCtx.StmtType
 	ssbd.normalizedSQL = formatSQL(sei.NormalizedSQL)
 	ssbd.tableNames = tableNames
+	ssbd.planCacheTemplate = sei.getPlanCacheTemplate()
 	ssbd.history = list.New()
 	ssbd.initialized = true
 }
@@ -813,6 +890,7 @@ func (ssElement *stmtSummaryByDigestElement) add(sei *StmtExecInfo, intervalSeco
 	} else {
 		ssElement.planInCache = false
 	}
+	ssElement.planCacheStatus = sei.PlanCacheStatus
 
 	// SPM
 	if sei.PlanInBinding {